@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/standardbeagle/agnt/internal/certs"
+
+	"github.com/spf13/cobra"
+)
+
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Show the local development CA and how to trust it",
+	Long: `Show the local certificate authority agnt uses for HTTPS proxies and
+HTTPS LAN shares, with instructions for trusting it on this machine, in
+browsers, and on phones and tablets.
+
+The CA is created on first use and stored in the agnt config directory.`,
+	Run: runCA,
+}
+
+var (
+	caPathOnly bool
+	caPEM      bool
+)
+
+func init() {
+	caCmd.Flags().BoolVar(&caPathOnly, "path", false, "Print only the CA certificate path")
+	caCmd.Flags().BoolVar(&caPEM, "pem", false, "Print the CA certificate in PEM format")
+
+	rootCmd.AddCommand(caCmd)
+}
+
+func runCA(cmd *cobra.Command, args []string) {
+	ca, err := certs.LoadOrCreate("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case caPathOnly:
+		fmt.Println(ca.CertPath())
+	case caPEM:
+		os.Stdout.Write(ca.CertPEM())
+	default:
+		fmt.Print(certs.InstallInstructions(ca.CertPath(), runtime.GOOS))
+	}
+}
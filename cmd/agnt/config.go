@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/daemon"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the .agnt.kdl project configuration",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check [dir|file]",
+	Short: "Check .agnt.kdl for errors",
+	Long: `Check an .agnt.kdl file for syntax errors, unknown keys, values of the
+wrong type and invalid references, such as a proxy linked to a script that
+neither the config nor the project defines.
+
+Without an argument, the .agnt.kdl of the current directory (or the nearest
+parent) is checked. Issues are printed as file:line:column. The exit status
+is 1 when there are errors.
+
+The daemon reloads .agnt.kdl when it changes, but only if it has no errors.
+
+Examples:
+  agnt config check
+  agnt config check ./web
+  agnt config check --json`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigCheck,
+}
+
+var configCheckJSON bool
+
+func init() {
+	configCheckCmd.Flags().BoolVar(&configCheckJSON, "json", false, "Print the issues as JSON")
+
+	configCmd.AddCommand(configCheckCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) {
+	target := "."
+	if len(args) > 0 {
+		target = args[0]
+	}
+
+	// A directory is searched like the daemon does; a file is checked as is
+	path, projectPath := "", target
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		path, projectPath = target, filepath.Dir(target)
+	} else if path = config.FindAgntConfigFile(target); path == "" {
+		fmt.Fprintf(os.Stderr, "No %s found in %s or its parents\n", config.AgntConfigFileName, target)
+		os.Exit(1)
+	}
+
+	issues, err := config.CheckAgntConfigFile(path, daemon.ConfigCheckOptions(projectPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Show paths relative to the working directory, like compilers do
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			path = rel
+			for i := range issues {
+				issues[i].File = rel
+			}
+		}
+	}
+
+	if configCheckJSON {
+		if issues == nil {
+			issues = []config.Issue{}
+		}
+		data, _ := json.MarshalIndent(map[string]interface{}{"path": path, "issues": issues}, "", "  ")
+		fmt.Println(string(data))
+	} else if len(issues) == 0 {
+		fmt.Printf("%s: no issues\n", path)
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+
+	if config.HasErrors(issues) {
+		os.Exit(1)
+	}
+}
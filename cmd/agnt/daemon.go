@@ -6,10 +6,12 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/standardbeagle/agnt/internal/daemon"
+	"github.com/standardbeagle/agnt/pkg/api"
 	"github.com/standardbeagle/go-cli-server/process"
 
 	"github.com/spf13/cobra"
@@ -54,12 +56,31 @@ var daemonInfoCmd = &cobra.Command{
 	Run:   runDaemonInfo,
 }
 
+var daemonAuditCmd = &cobra.Command{
+	Use:   "audit [project-dir]",
+	Short: "Export the audit log of a project as JSONL",
+	Long: `Print the actions MCP clients performed through agnt in a project, one JSON
+object per line, oldest first. The project defaults to the current directory.
+
+Examples:
+  agnt daemon audit > audit.jsonl
+  agnt daemon audit --since 24h --tool proxy
+  agnt daemon audit ~/src/app --status error`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runDaemonAudit,
+}
+
 func init() {
+	daemonAuditCmd.Flags().String("since", "", "Only entries since a duration ago (e.g. 24h) or an RFC3339 time")
+	daemonAuditCmd.Flags().String("tool", "", "Only entries of this tool")
+	daemonAuditCmd.Flags().String("status", "", "Only entries with this result: ok or error")
+
 	daemonCmd.AddCommand(daemonStartCmd)
 	daemonCmd.AddCommand(daemonStopCmd)
 	daemonCmd.AddCommand(daemonRestartCmd)
 	daemonCmd.AddCommand(daemonStatusCmd)
 	daemonCmd.AddCommand(daemonInfoCmd)
+	daemonCmd.AddCommand(daemonAuditCmd)
 }
 
 func getSocketPath(cmd *cobra.Command) string {
@@ -215,3 +236,45 @@ func runDaemonInfo(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+func runDaemonAudit(cmd *cobra.Command, args []string) {
+	socketPath := getSocketPath(cmd)
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	projectPath, err := filepath.Abs(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid project directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	q := api.AuditQuery{ProjectPath: projectPath, Limit: -1}
+	q.Tool, _ = cmd.Flags().GetString("tool")
+	q.Status, _ = cmd.Flags().GetString("status")
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		q.Since, err = daemon.ParseSince(since, time.Now())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	client := daemon.NewClient(daemon.WithSocketPath(socketPath))
+	if err := client.Connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Daemon is not running: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	result, err := client.AuditQuery(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to query audit log: %v\n", err)
+		os.Exit(1)
+	}
+	if err := daemon.WriteAuditJSONL(os.Stdout, result.Entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write audit log: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/standardbeagle/agnt/internal/aichannel"
 	"github.com/standardbeagle/agnt/internal/daemon"
 	"github.com/standardbeagle/agnt/internal/debug"
 )
@@ -37,15 +38,21 @@ var rootCmd = &cobra.Command{
 
 var debugMode bool
 var debugLogFile string
+var llmMock bool
+var llmMockFixtures string
+var llmMockRecord string
 
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().String("socket", "", "Socket path for daemon communication")
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enable debug logging (also: AGNT_DEBUG=1)")
 	rootCmd.PersistentFlags().StringVar(&debugLogFile, "debug-log", "", "Write debug logs to file (in ~/.cache/agnt/logs/)")
+	rootCmd.PersistentFlags().BoolVar(&llmMock, "llm-mock", false, "Serve LLM requests from a local mock server with built-in responses")
+	rootCmd.PersistentFlags().StringVar(&llmMockFixtures, "llm-mock-fixtures", "", "Serve LLM requests from a local mock server with the fixtures in a directory")
+	rootCmd.PersistentFlags().StringVar(&llmMockRecord, "llm-mock-record", "", "Append requests received by the LLM mock server to a JSONL file")
 
 	// Initialize debug mode from flags before command execution
-	cobra.OnInitialize(initDebug)
+	cobra.OnInitialize(initDebug, initLLMMock)
 
 	// Add subcommands
 	rootCmd.AddCommand(mcpCmd)
@@ -78,6 +85,44 @@ func initDebug() {
 	}
 }
 
+// initLLMMock starts the local LLM mock server when --llm-mock or
+// --llm-mock-fixtures is set. API providers in this process talk to the mock
+// instead of the network; daemons it auto-starts are not affected, since
+// they outlive the server. Run `agnt daemon start --llm-mock` to mock a
+// daemon's providers.
+func initLLMMock() {
+	if !llmMock && llmMockFixtures == "" {
+		return
+	}
+
+	var fixtures []aichannel.MockFixture
+	if llmMockFixtures != "" {
+		var err error
+		fixtures, err = aichannel.LoadMockFixtures(llmMockFixtures)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --llm-mock-fixtures: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	mock := aichannel.NewMockServer(fixtures...)
+	if llmMockRecord != "" {
+		f, err := os.OpenFile(llmMockRecord, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --llm-mock-record: %v\n", err)
+			os.Exit(1)
+		}
+		mock.SetRecorder(f)
+	}
+	if err := mock.Start("127.0.0.1:0"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --llm-mock: %v\n", err)
+		os.Exit(1)
+	}
+
+	aichannel.SetMockURL(mock.URL())
+	debug.Log("main", "LLM mock server listening on %s (%d fixtures)", mock.URL(), len(fixtures))
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -340,6 +340,7 @@ func runWithPTY(ctx context.Context, args []string, socketPath string, sessionCo
 		inputRouter.SetOutputFetcher(outputFetcher)
 		daemonConnector := overlay.NewDaemonConnector(daemonConn)
 		inputRouter.SetDaemonConnector(daemonConnector)
+		inputRouter.SetProxySharer(overlay.NewDaemonProxySharer(daemonConn))
 
 		// Set up summarizer - detect first available AI agent
 		if agent := detectAIAgent(); agent != "" {
@@ -350,6 +350,7 @@ func runWithConPTY(ctx context.Context, args []string, socketPath string, sessio
 		inputRouter.SetOutputFetcher(outputFetcher)
 		daemonConnector := overlay.NewDaemonConnector(daemonConn)
 		inputRouter.SetDaemonConnector(daemonConnector)
+		inputRouter.SetProxySharer(overlay.NewDaemonProxySharer(daemonConn))
 
 		// Set up summarizer using shared connection
 		if agent := detectAIAgent(); agent != "" {
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -34,18 +36,39 @@ var mcpCmd = &cobra.Command{
 	Long: `Run as an MCP (Model Context Protocol) server for AI coding assistants.
 
 This is the primary mode for integration with Claude Code, Claude Desktop, and other MCP clients.
-Uses a background daemon for persistent state across connections.`,
+Uses a background daemon for persistent state across connections.
+
+With --daemon tcp://host:port, uses the daemon of another machine, e.g. the host
+of a devcontainer, WSL distribution or VM, over TLS. The daemon must enable its
+remote listener in config.kdl. Use --path-map to map directories of this machine
+to the daemon's, e.g. --path-map /workspace=/home/me/project.`,
 	Run: runMCP,
 }
 
 var (
 	serveLegacy bool
 	mcpNoAttach bool
+
+	mcpDaemon          string
+	mcpDaemonTokenFile string
+	mcpDaemonCA        string
+	mcpDaemonCert      string
+	mcpDaemonKey       string
+	mcpPathMap         []string
 )
 
+// daemonTokenEnv holds the token of a remote daemon when no token file is given.
+const daemonTokenEnv = "AGNT_DAEMON_TOKEN"
+
 func init() {
 	serveCmd.Flags().BoolVar(&serveLegacy, "legacy", false, "Run in legacy mode (no daemon)")
 	mcpCmd.Flags().BoolVar(&mcpNoAttach, "no-attach", false, "Don't auto-attach to existing session (operate globally)")
+	mcpCmd.Flags().StringVar(&mcpDaemon, "daemon", "", "Remote daemon address (tcp://host:port)")
+	mcpCmd.Flags().StringVar(&mcpDaemonTokenFile, "daemon-token-file", "", "File with the remote daemon's token (default: $"+daemonTokenEnv+")")
+	mcpCmd.Flags().StringVar(&mcpDaemonCA, "daemon-ca", "", "CA certificate to verify the remote daemon with, e.g. a copy of its agnt CA")
+	mcpCmd.Flags().StringVar(&mcpDaemonCert, "daemon-cert", "", "Client certificate for the remote daemon")
+	mcpCmd.Flags().StringVar(&mcpDaemonKey, "daemon-key", "", "Client certificate key for the remote daemon")
+	mcpCmd.Flags().StringArrayVar(&mcpPathMap, "path-map", nil, "Map a local directory to the remote daemon's (local=daemon, repeatable)")
 }
 
 func runServe(cmd *cobra.Command, args []string) {
@@ -57,7 +80,7 @@ func runServe(cmd *cobra.Command, args []string) {
 	if serveLegacy {
 		runLegacyServer()
 	} else {
-		runDaemonClient(socketPath)
+		runDaemonClient(socketPath, nil)
 	}
 }
 
@@ -67,11 +90,59 @@ func runMCP(cmd *cobra.Command, args []string) {
 		socketPath = daemon.DefaultSocketPath()
 	}
 
-	runDaemonClient(socketPath, mcpNoAttach)
+	remote, err := remoteDaemonConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	runDaemonClient(socketPath, remote, mcpNoAttach)
+}
+
+// remoteDaemonConfig returns the remote daemon of the mcp flags, or nil if
+// --daemon is not set.
+func remoteDaemonConfig() (*daemon.RemoteDialConfig, error) {
+	if mcpDaemon == "" {
+		if len(mcpPathMap) > 0 {
+			return nil, fmt.Errorf("--path-map requires --daemon")
+		}
+		return nil, nil
+	}
+	addr, ok, err := daemon.ParseRemoteAddr(mcpDaemon)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("--daemon must be %shost:port, got %q", daemon.RemoteScheme, mcpDaemon)
+	}
+
+	remote := &daemon.RemoteDialConfig{
+		Addr:     addr,
+		Token:    os.Getenv(daemonTokenEnv),
+		CAFile:   mcpDaemonCA,
+		CertFile: mcpDaemonCert,
+		KeyFile:  mcpDaemonKey,
+	}
+	if mcpDaemonTokenFile != "" {
+		data, err := os.ReadFile(mcpDaemonTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("--daemon-token-file: %w", err)
+		}
+		remote.Token = strings.TrimSpace(string(data))
+	}
+	for _, s := range mcpPathMap {
+		m, err := daemon.ParsePathMapping(s)
+		if err != nil {
+			return nil, fmt.Errorf("--path-map: %w", err)
+		}
+		remote.PathMap = append(remote.PathMap, m)
+	}
+	return remote, nil
 }
 
 // runDaemonClient runs the MCP server that communicates with the daemon.
-func runDaemonClient(socketPath string, noAttach ...bool) {
+// A non-nil remote connects to that daemon instead of the local one.
+func runDaemonClient(socketPath string, remote *daemon.RemoteDialConfig, noAttach ...bool) {
 	// Create root context with signal cancellation
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT,
@@ -88,6 +159,7 @@ func runDaemonClient(socketPath string, noAttach ...bool) {
 	}
 
 	dt := tools.NewDaemonTools(config, appVersion)
+	dt.SetRemote(remote)
 	defer dt.Close()
 
 	// Disable auto-attach if requested
@@ -127,6 +199,9 @@ Available tools:
 	tools.RegisterDaemonManagementTool(server, dt)
 	tools.RegisterTunnelTool(server, dt)
 
+	// Record the tool calls that change something in the project's audit log
+	server.AddReceivingMiddleware(tools.AuditMiddleware(dt))
+
 	// Register snapshot tools (visual regression testing)
 	snapshotManager, err := snapshot.NewManager("", 0.01) // Default path and 1% threshold
 	if err != nil {
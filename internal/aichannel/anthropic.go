@@ -36,6 +36,15 @@ func NewAnthropicProvider(config ProviderConfig) *AnthropicProvider {
 	if apiKey == "" {
 		apiKey = os.Getenv("CLAUDE_KEY")
 	}
+	if mockURL := MockURL(); mockURL != "" {
+		if apiKey == "" {
+			apiKey = mockAPIKey
+			config.APIKey = apiKey
+		}
+		if config.BaseURL == "" {
+			config.BaseURL = mockURL + "/"
+		}
+	}
 
 	// Set defaults
 	if config.Model == "" {
@@ -130,6 +139,58 @@ func (p *AnthropicProvider) CompleteWithContext(ctx context.Context, systemPromp
 	}, nil
 }
 
+// StreamWithContext is like CompleteWithContext but reports text deltas to onChunk as they arrive.
+func (p *AnthropicProvider) StreamWithContext(ctx context.Context, systemPrompt, userPrompt, inputContext string, onChunk func(string)) (*Response, error) {
+	if !p.IsConfigured() {
+		return nil, ErrNoAPIKey
+	}
+
+	userContent := userPrompt
+	if inputContext != "" {
+		userContent = fmt.Sprintf("<context>\n%s\n</context>\n\n%s", inputContext, userPrompt)
+	}
+
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(p.config.Model),
+		MaxTokens: int64(p.config.MaxTokens),
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(userContent)),
+		},
+	}
+	if systemPrompt != "" {
+		params.System = []anthropic.TextBlockParam{
+			{Text: systemPrompt},
+		}
+	}
+
+	stream := p.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	var resultText strings.Builder
+	var messageID string
+	for stream.Next() {
+		switch ev := stream.Current().AsAny().(type) {
+		case anthropic.MessageStartEvent:
+			messageID = ev.Message.ID
+		case anthropic.ContentBlockDeltaEvent:
+			if delta, ok := ev.Delta.AsAny().(anthropic.TextDelta); ok {
+				resultText.WriteString(delta.Text)
+				if onChunk != nil {
+					onChunk(delta.Text)
+				}
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderError, err)
+	}
+
+	return &Response{
+		Result:    strings.TrimSpace(resultText.String()),
+		SessionID: messageID,
+	}, nil
+}
+
 // Model returns the configured model name.
 func (p *AnthropicProvider) Model() string {
 	return p.config.Model
@@ -94,6 +94,9 @@ type Config struct {
 	// MaxTokens limits API response length (default 1024).
 	MaxTokens int `json:"max_tokens,omitempty"`
 
+	// BaseURL overrides the API endpoint for API mode (e.g. a local mock server).
+	BaseURL string `json:"base_url,omitempty"`
+
 	// SystemPrompt provides context/instructions for API-based completions.
 	SystemPrompt string `json:"system_prompt,omitempty"`
 }
@@ -121,6 +124,10 @@ func NewWithConfig(config Config) *Channel {
 func (c *Channel) Configure(config Config) {
 	// Apply defaults based on agent type
 	config = applyDefaults(config)
+	// A mock LLM server replaces agent CLIs so flows run without network
+	if MockURL() != "" {
+		config.UseAPI = true
+	}
 	c.config = config
 	c.configured = true
 
@@ -149,6 +156,7 @@ func (c *Channel) createProvider() Provider {
 		APIKey:    c.config.APIKey,
 		Model:     c.config.Model,
 		MaxTokens: c.config.MaxTokens,
+		BaseURL:   c.config.BaseURL,
 	})
 	if err != nil {
 		// Log error but return nil - IsAvailable will handle this
@@ -117,6 +117,8 @@ type LangChainConfig struct {
 	Model string
 	// MaxTokens limits response length
 	MaxTokens int
+	// BaseURL overrides the provider endpoint (OpenAI-compatible and Anthropic only)
+	BaseURL string
 }
 
 // NewLangChainProvider creates a new LangChain-based provider.
@@ -137,6 +139,24 @@ func NewLangChainProvider(config LangChainConfig) (*LangChainProvider, error) {
 		}
 	}
 
+	// Route everything to the mock server when one is configured
+	baseURL := config.BaseURL
+	if mockURL := MockURL(); mockURL != "" {
+		if apiKey == "" {
+			apiKey = mockAPIKey
+		}
+		if baseURL == "" {
+			baseURL = mockURL + "/v1"
+		}
+		// The mock only speaks the OpenAI and Anthropic wire formats
+		if config.Provider != ProviderAnthropic {
+			info.IsOpenAICompatible = true
+		}
+	}
+	if baseURL == "" {
+		baseURL = info.BaseURL
+	}
+
 	if apiKey == "" {
 		return nil, fmt.Errorf("%w: no API key found for %s (tried: %v)", ErrNoAPIKey, config.Provider, info.EnvKeys)
 	}
@@ -156,17 +176,21 @@ func NewLangChainProvider(config LangChainConfig) (*LangChainProvider, error) {
 			openai.WithToken(apiKey),
 			openai.WithModel(model),
 		}
-		if info.BaseURL != "" {
-			opts = append(opts, openai.WithBaseURL(info.BaseURL))
+		if baseURL != "" {
+			opts = append(opts, openai.WithBaseURL(baseURL))
 		}
 		llm, err = openai.New(opts...)
 	} else {
 		switch config.Provider {
 		case ProviderAnthropic:
-			llm, err = anthropic.New(
+			opts := []anthropic.Option{
 				anthropic.WithToken(apiKey),
 				anthropic.WithModel(model),
-			)
+			}
+			if baseURL != "" {
+				opts = append(opts, anthropic.WithBaseURL(baseURL))
+			}
+			llm, err = anthropic.New(opts...)
 		case ProviderGoogle:
 			llm, err = googleai.New(
 				context.Background(),
@@ -242,6 +266,36 @@ func (p *LangChainProvider) CompleteWithContext(ctx context.Context, systemPromp
 	}, nil
 }
 
+// StreamWithContext is like CompleteWithContext but reports text deltas to onChunk as they arrive.
+func (p *LangChainProvider) StreamWithContext(ctx context.Context, systemPrompt, userPrompt, inputContext string, onChunk func(string)) (*Response, error) {
+	var messages []llms.MessageContent
+	if systemPrompt != "" {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeSystem, systemPrompt))
+	}
+	userContent := userPrompt
+	if inputContext != "" {
+		userContent = fmt.Sprintf("<context>\n%s\n</context>\n\n%s", inputContext, userPrompt)
+	}
+	messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, userContent))
+
+	resp, err := p.llm.GenerateContent(ctx, messages, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		if onChunk != nil && len(chunk) > 0 {
+			onChunk(string(chunk))
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderError, err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: no response choices", ErrProviderError)
+	}
+
+	return &Response{
+		Result: resp.Choices[0].Content,
+	}, nil
+}
+
 // Model returns the configured model name.
 func (p *LangChainProvider) Model() string {
 	return p.model
@@ -253,6 +307,9 @@ func GetAPIKeyForProvider(provider LLMProvider) string {
 	if !ok {
 		return ""
 	}
+	if MockURL() != "" {
+		return mockAPIKey
+	}
 	for _, envKey := range info.EnvKeys {
 		if key := os.Getenv(envKey); key != "" {
 			return key
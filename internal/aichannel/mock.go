@@ -0,0 +1,561 @@
+package aichannel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MockURLEnv is the environment variable that points API providers at a mock LLM server.
+// When set, every provider is considered configured and requests go to this URL.
+const MockURLEnv = "AGNT_LLM_MOCK_URL"
+
+// mockAPIKey is the placeholder API key used when the mock server is active.
+const mockAPIKey = "agnt-mock-key"
+
+// defaultMockResponse is returned when no fixture matches a request.
+const defaultMockResponse = "This is a mock response from the agnt LLM mock server."
+
+// mockURL is the mock server set by SetMockURL; it takes precedence over MockURLEnv.
+var mockURL atomic.Pointer[string]
+
+// SetMockURL points API providers in this process at a mock LLM server.
+// Unlike setting MockURLEnv, it is not inherited by child processes such as
+// auto-started daemons, which would outlive the server. An empty url falls
+// back to the environment.
+func SetMockURL(url string) {
+	if url == "" {
+		mockURL.Store(nil)
+		return
+	}
+	mockURL.Store(&url)
+}
+
+// MockURL returns the mock server URL set by SetMockURL or the environment,
+// or "" if not set.
+func MockURL() string {
+	if url := mockURL.Load(); url != nil {
+		return strings.TrimRight(*url, "/")
+	}
+	return strings.TrimRight(os.Getenv(MockURLEnv), "/")
+}
+
+// MockFixture is a scripted response served by MockServer.
+type MockFixture struct {
+	// Name identifies the fixture (defaults to the fixture file name)
+	Name string `json:"name,omitempty"`
+
+	// Match is a substring matched against the last user message.
+	// An empty Match matches every request.
+	Match string `json:"match,omitempty"`
+
+	// Model restricts the fixture to requests for this model
+	Model string `json:"model,omitempty"`
+
+	// Response is the completion text returned to the client
+	Response string `json:"response"`
+
+	// Chunks overrides how Response is split into streaming deltas
+	Chunks []string `json:"chunks,omitempty"`
+
+	// Status is the HTTP status to return (default 200)
+	Status int `json:"status,omitempty"`
+
+	// Error is the error message returned with a non-2xx Status
+	Error string `json:"error,omitempty"`
+
+	// DelayMS delays the response (or each streaming chunk) in milliseconds
+	DelayMS int `json:"delay_ms,omitempty"`
+}
+
+// MockMessage is a single chat message extracted from a recorded request.
+type MockMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// MockRequest records a request received by MockServer.
+type MockRequest struct {
+	// Format is the wire format: "openai" or "anthropic"
+	Format string `json:"format"`
+
+	Path     string        `json:"path"`
+	Model    string        `json:"model"`
+	System   string        `json:"system,omitempty"`
+	Messages []MockMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Time     time.Time     `json:"time"`
+
+	// Fixture is the name of the fixture that answered the request
+	Fixture string `json:"fixture,omitempty"`
+
+	// Body is the raw request body
+	Body json.RawMessage `json:"body"`
+}
+
+// LastUserMessage returns the content of the last user message.
+func (r MockRequest) LastUserMessage() string {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if r.Messages[i].Role == "user" {
+			return r.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// MockServer is a local LLM server speaking the OpenAI chat-completions and
+// Anthropic messages wire formats, including SSE streaming.
+// It serves scripted responses from fixtures and records every request.
+//
+// MockServer implements http.Handler, so tests can wrap it with httptest.NewServer,
+// or call Start to listen on a local address.
+type MockServer struct {
+	mu       sync.Mutex
+	fixtures []MockFixture
+	requests []MockRequest
+	recorder io.Writer
+	seq      atomic.Int64
+
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewMockServer creates a mock LLM server with the given fixtures.
+// Fixtures are matched in order; the first match wins.
+func NewMockServer(fixtures ...MockFixture) *MockServer {
+	return &MockServer{fixtures: fixtures}
+}
+
+// LoadMockFixtures reads fixtures from a directory, sorted by file name.
+// A .json file holds one fixture object or an array of fixtures.
+// Any other file is a fixture whose Response is the file content and whose
+// Match is empty, so it answers every request not matched earlier.
+func LoadMockFixtures(dir string) ([]MockFixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var fixtures []MockFixture
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture %s: %w", name, err)
+		}
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+
+		if filepath.Ext(name) != ".json" {
+			fixtures = append(fixtures, MockFixture{Name: base, Response: string(data)})
+			continue
+		}
+
+		trimmed := strings.TrimSpace(string(data))
+		if strings.HasPrefix(trimmed, "[") {
+			var list []MockFixture
+			if err := json.Unmarshal(data, &list); err != nil {
+				return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+			}
+			for i := range list {
+				if list[i].Name == "" {
+					list[i].Name = fmt.Sprintf("%s[%d]", base, i)
+				}
+			}
+			fixtures = append(fixtures, list...)
+			continue
+		}
+
+		var f MockFixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+		if f.Name == "" {
+			f.Name = base
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// AddFixture appends a fixture.
+func (s *MockServer) AddFixture(f MockFixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = append(s.fixtures, f)
+}
+
+// SetRecorder writes each recorded request as a JSON line to w.
+func (s *MockServer) SetRecorder(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorder = w
+}
+
+// Requests returns a copy of all recorded requests.
+func (s *MockServer) Requests() []MockRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]MockRequest, len(s.requests))
+	copy(out, s.requests)
+	return out
+}
+
+// Reset clears recorded requests.
+func (s *MockServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// Start listens on addr (e.g. "127.0.0.1:0") and serves in the background.
+func (s *MockServer) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.listener = ln
+	s.server = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go s.server.Serve(ln)
+	return nil
+}
+
+// URL returns the base URL of a started server.
+func (s *MockServer) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close stops a started server.
+func (s *MockServer) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// ServeHTTP dispatches OpenAI and Anthropic endpoints by path suffix,
+// so both "/v1/chat/completions" and "/chat/completions" work.
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		s.handleOpenAI(w, r.URL.Path, body)
+	case strings.HasSuffix(r.URL.Path, "/messages"):
+		s.handleAnthropic(w, r.URL.Path, body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// record stores the request and returns the matching fixture.
+func (s *MockServer) record(req MockRequest) MockFixture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fixture := MockFixture{Name: "default", Response: defaultMockResponse}
+	last := req.LastUserMessage()
+	for _, f := range s.fixtures {
+		if f.Model != "" && f.Model != req.Model {
+			continue
+		}
+		if f.Match == "" || strings.Contains(last, f.Match) {
+			fixture = f
+			break
+		}
+	}
+
+	req.Fixture = fixture.Name
+	req.Time = time.Now()
+	s.requests = append(s.requests, req)
+	if s.recorder != nil {
+		if line, err := json.Marshal(req); err == nil {
+			s.recorder.Write(append(line, '\n'))
+		}
+	}
+	return fixture
+}
+
+// chunks splits the fixture response into streaming deltas.
+func (f MockFixture) chunks() []string {
+	if len(f.Chunks) > 0 {
+		return f.Chunks
+	}
+	var out []string
+	rest := f.Response
+	for rest != "" {
+		i := strings.IndexAny(rest, " \n")
+		if i < 0 {
+			out = append(out, rest)
+			break
+		}
+		out = append(out, rest[:i+1])
+		rest = rest[i+1:]
+	}
+	return out
+}
+
+func (f MockFixture) delay() {
+	if f.DelayMS > 0 {
+		time.Sleep(time.Duration(f.DelayMS) * time.Millisecond)
+	}
+}
+
+// approxTokens estimates a token count for usage fields.
+func approxTokens(s string) int {
+	return len(strings.Fields(s))
+}
+
+// mockContent flattens string or content-block message content to text.
+func mockContent(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &blocks); err == nil {
+		var b strings.Builder
+		for _, block := range blocks {
+			if block.Type == "text" {
+				b.WriteString(block.Text)
+			}
+		}
+		return b.String()
+	}
+	return ""
+}
+
+type mockWireRequest struct {
+	Model    string          `json:"model"`
+	System   json.RawMessage `json:"system"`
+	Stream   bool            `json:"stream"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+}
+
+func parseMockRequest(format, path string, body []byte) (MockRequest, error) {
+	var wire mockWireRequest
+	if err := json.Unmarshal(body, &wire); err != nil {
+		return MockRequest{}, err
+	}
+	req := MockRequest{
+		Format: format,
+		Path:   path,
+		Model:  wire.Model,
+		Stream: wire.Stream,
+		Body:   json.RawMessage(body),
+	}
+	if len(wire.System) > 0 {
+		req.System = mockContent(wire.System)
+	}
+	for _, m := range wire.Messages {
+		content := mockContent(m.Content)
+		// OpenAI carries the system prompt as a message
+		if m.Role == "system" {
+			req.System = content
+			continue
+		}
+		req.Messages = append(req.Messages, MockMessage{Role: m.Role, Content: content})
+	}
+	return req, nil
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// startSSE prepares an event-stream response.
+func startSSE(w http.ResponseWriter) http.Flusher {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return flusher
+}
+
+func writeSSE(w io.Writer, flusher http.Flusher, event string, v interface{}) {
+	data, _ := json.Marshal(v)
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// handleOpenAI serves the OpenAI chat-completions format.
+func (s *MockServer) handleOpenAI(w http.ResponseWriter, path string, body []byte) {
+	req, err := parseMockRequest("openai", path, body)
+	if err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": map[string]string{"message": err.Error(), "type": "invalid_request_error"},
+		})
+		return
+	}
+	fixture := s.record(req)
+	id := fmt.Sprintf("chatcmpl-mock-%d", s.seq.Add(1))
+	created := time.Now().Unix()
+
+	if fixture.Status >= 400 {
+		fixture.delay()
+		writeMockJSON(w, fixture.Status, map[string]interface{}{
+			"error": map[string]string{"message": fixture.Error, "type": "mock_error"},
+		})
+		return
+	}
+
+	if !req.Stream {
+		fixture.delay()
+		promptTokens := approxTokens(req.System + req.LastUserMessage())
+		completionTokens := approxTokens(fixture.Response)
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": fixture.Response},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]int{
+				"prompt_tokens":     promptTokens,
+				"completion_tokens": completionTokens,
+				"total_tokens":      promptTokens + completionTokens,
+			},
+		})
+		return
+	}
+
+	flusher := startSSE(w)
+	chunk := func(delta map[string]string, finish interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finish}},
+		}
+	}
+	writeSSE(w, flusher, "", chunk(map[string]string{"role": "assistant", "content": ""}, nil))
+	for _, c := range fixture.chunks() {
+		fixture.delay()
+		writeSSE(w, flusher, "", chunk(map[string]string{"content": c}, nil))
+	}
+	writeSSE(w, flusher, "", chunk(map[string]string{}, "stop"))
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// handleAnthropic serves the Anthropic messages format.
+func (s *MockServer) handleAnthropic(w http.ResponseWriter, path string, body []byte) {
+	req, err := parseMockRequest("anthropic", path, body)
+	if err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"type":  "error",
+			"error": map[string]string{"type": "invalid_request_error", "message": err.Error()},
+		})
+		return
+	}
+	fixture := s.record(req)
+	id := fmt.Sprintf("msg_mock_%d", s.seq.Add(1))
+
+	if fixture.Status >= 400 {
+		fixture.delay()
+		writeMockJSON(w, fixture.Status, map[string]interface{}{
+			"type":  "error",
+			"error": map[string]string{"type": "api_error", "message": fixture.Error},
+		})
+		return
+	}
+
+	inputTokens := approxTokens(req.System + req.LastUserMessage())
+	outputTokens := approxTokens(fixture.Response)
+
+	if !req.Stream {
+		fixture.delay()
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"id":            id,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         req.Model,
+			"content":       []map[string]string{{"type": "text", "text": fixture.Response}},
+			"stop_reason":   "end_turn",
+			"stop_sequence": nil,
+			"usage":         map[string]int{"input_tokens": inputTokens, "output_tokens": outputTokens},
+		})
+		return
+	}
+
+	flusher := startSSE(w)
+	writeSSE(w, flusher, "message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":            id,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         req.Model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]int{"input_tokens": inputTokens, "output_tokens": 0},
+		},
+	})
+	writeSSE(w, flusher, "content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         0,
+		"content_block": map[string]string{"type": "text", "text": ""},
+	})
+	for _, c := range fixture.chunks() {
+		fixture.delay()
+		writeSSE(w, flusher, "content_block_delta", map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": 0,
+			"delta": map[string]string{"type": "text_delta", "text": c},
+		})
+	}
+	writeSSE(w, flusher, "content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": 0})
+	writeSSE(w, flusher, "message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": "end_turn", "stop_sequence": nil},
+		"usage": map[string]int{"output_tokens": outputTokens},
+	})
+	writeSSE(w, flusher, "message_stop", map[string]string{"type": "message_stop"})
+}
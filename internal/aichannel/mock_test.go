@@ -0,0 +1,184 @@
+package aichannel
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestMock(t *testing.T, fixtures ...MockFixture) (*MockServer, *httptest.Server) {
+	t.Helper()
+	mock := NewMockServer(fixtures...)
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	return mock, srv
+}
+
+func TestMockServer_OpenAIProvider(t *testing.T) {
+	mock, srv := newTestMock(t,
+		MockFixture{Name: "audit", Match: "audit", Response: `{"summary":"ok"}`},
+		MockFixture{Name: "fallback", Response: "fallback"},
+	)
+	t.Setenv(MockURLEnv, srv.URL)
+
+	provider, err := NewLangChainProvider(LangChainConfig{Provider: ProviderOpenAI})
+	if err != nil {
+		t.Fatalf("NewLangChainProvider: %v", err)
+	}
+
+	resp, err := provider.Complete(context.Background(), "be terse", "summarize this audit")
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Result != `{"summary":"ok"}` {
+		t.Errorf("Result = %q", resp.Result)
+	}
+
+	reqs := mock.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("recorded %d requests, want 1", len(reqs))
+	}
+	if reqs[0].Format != "openai" || reqs[0].System != "be terse" || reqs[0].Fixture != "audit" {
+		t.Errorf("recorded request = %+v", reqs[0])
+	}
+}
+
+func TestMockServer_AnthropicProvider(t *testing.T) {
+	mock, srv := newTestMock(t, MockFixture{Name: "hello", Response: "hello from mock"})
+	t.Setenv(MockURLEnv, srv.URL)
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("CLAUDE_KEY", "")
+
+	provider := NewAnthropicProvider(ProviderConfig{})
+	if !provider.IsConfigured() {
+		t.Fatal("provider should be configured in mock mode")
+	}
+
+	resp, err := provider.Complete(context.Background(), "system", "hi")
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Result != "hello from mock" {
+		t.Errorf("Result = %q", resp.Result)
+	}
+	if reqs := mock.Requests(); len(reqs) != 1 || reqs[0].Format != "anthropic" || reqs[0].System != "system" {
+		t.Errorf("recorded requests = %+v", reqs)
+	}
+}
+
+func TestMockServer_ChannelForcesAPIMode(t *testing.T) {
+	_, srv := newTestMock(t, MockFixture{Response: "channel ok"})
+	t.Setenv(MockURLEnv, srv.URL)
+
+	ch := NewWithConfig(Config{Agent: AgentClaude})
+	if !ch.IsAPIMode() || !ch.IsAvailable() {
+		t.Fatal("channel should use the mock API provider")
+	}
+	out, err := ch.Send(context.Background(), "prompt", "")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if out != "channel ok" {
+		t.Errorf("Send = %q", out)
+	}
+}
+
+func TestMockServer_Streaming(t *testing.T) {
+	_, srv := newTestMock(t, MockFixture{Chunks: []string{"a", "b"}, Response: "ab"})
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/v1/chat/completions", []string{`"content":"a"`, `"content":"b"`, "data: [DONE]"}},
+		{"/v1/messages", []string{"event: message_start", `"text":"a"`, `"text":"b"`, "event: message_stop"}},
+	}
+	for _, tt := range tests {
+		body := `{"model":"m","stream":true,"messages":[{"role":"user","content":"x"}]}`
+		resp, err := http.Post(srv.URL+tt.path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", tt.path, err)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("%s Content-Type = %q", tt.path, ct)
+		}
+		var lines []string
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		resp.Body.Close()
+		all := strings.Join(lines, "\n")
+		for _, w := range tt.want {
+			if !strings.Contains(all, w) {
+				t.Errorf("%s stream missing %q:\n%s", tt.path, w, all)
+			}
+		}
+	}
+}
+
+func TestMockServer_ErrorFixture(t *testing.T) {
+	_, srv := newTestMock(t, MockFixture{Status: http.StatusTooManyRequests, Error: "rate limited"})
+	t.Setenv(MockURLEnv, srv.URL)
+
+	provider, err := NewLangChainProvider(LangChainConfig{Provider: ProviderOpenAI})
+	if err != nil {
+		t.Fatalf("NewLangChainProvider: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), "", "x"); err == nil {
+		t.Error("expected error from error fixture")
+	}
+}
+
+func TestLoadMockFixtures(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"01-audit.json":  `{"match":"audit","response":"audit result"}`,
+		"02-many.json":   `[{"match":"a","response":"A"},{"name":"bee","match":"b","response":"B"}]`,
+		"99-default.txt": "default text",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixtures, err := LoadMockFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadMockFixtures: %v", err)
+	}
+	var names []string
+	for _, f := range fixtures {
+		names = append(names, f.Name)
+	}
+	want := "01-audit,02-many[0],bee,99-default"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("fixture names = %s, want %s", got, want)
+	}
+	if fixtures[3].Response != "default text" || fixtures[3].Match != "" {
+		t.Errorf("text fixture = %+v", fixtures[3])
+	}
+}
+
+func TestSetMockURL(t *testing.T) {
+	t.Setenv(MockURLEnv, "http://127.0.0.1:1/")
+	t.Cleanup(func() { SetMockURL("") })
+
+	SetMockURL("http://127.0.0.1:2/")
+	if got := MockURL(); got != "http://127.0.0.1:2" {
+		t.Errorf("MockURL() = %q, want the URL set in process", got)
+	}
+	if os.Getenv(MockURLEnv) != "http://127.0.0.1:1/" {
+		t.Error("SetMockURL should not change the environment inherited by child processes")
+	}
+
+	SetMockURL("")
+	if got := MockURL(); got != "http://127.0.0.1:1" {
+		t.Errorf("MockURL() = %q, want the environment URL", got)
+	}
+}
@@ -113,6 +113,7 @@ type StreamMessage struct {
 type ContentBlock struct {
 	Type string `json:"type"`
 	Text string `json:"text,omitempty"`
+	Name string `json:"name,omitempty"` // tool name for tool_use blocks
 }
 
 // ParseResponse parses the raw output from an AI agent based on the output format.
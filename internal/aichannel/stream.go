@@ -0,0 +1,304 @@
+package aichannel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// StreamEventType identifies the kind of streaming event.
+// The values match the event types understood by the browser ResponseStreamPanel.
+type StreamEventType string
+
+const (
+	StreamEventStart  StreamEventType = "start"
+	StreamEventChunk  StreamEventType = "chunk"
+	StreamEventSystem StreamEventType = "system"
+	StreamEventEnd    StreamEventType = "end"
+	StreamEventError  StreamEventType = "error"
+)
+
+// StreamEvent is a single event emitted while a response is being generated.
+type StreamEvent struct {
+	Type StreamEventType `json:"type"`
+
+	// Text is the text delta for chunk events, or the message for system/error events
+	Text string `json:"text,omitempty"`
+
+	// Response is the final response, set on end events
+	Response *Response `json:"response,omitempty"`
+}
+
+// StreamHandler receives streaming events. It is called from the streaming goroutine
+// and must not block for long.
+type StreamHandler func(StreamEvent)
+
+// StreamingProvider is a Provider that can deliver completions incrementally.
+type StreamingProvider interface {
+	Provider
+
+	// StreamWithContext is like CompleteWithContext but calls onChunk for each text delta.
+	StreamWithContext(ctx context.Context, systemPrompt, userPrompt, inputContext string, onChunk func(string)) (*Response, error)
+}
+
+// SupportsStreaming returns true if the channel delivers responses incrementally
+// (provider SSE or CLI stream-json). Other agents still work with Stream but
+// deliver their whole response as a single chunk.
+func (c *Channel) SupportsStreaming() bool {
+	if c.config.UseAPI {
+		_, ok := c.provider.(StreamingProvider)
+		return ok
+	}
+	return c.config.Agent == AgentClaude
+}
+
+// Stream sends a prompt and reports the response through handler as it is generated.
+// The handler always receives a start event followed by zero or more chunk/system
+// events and exactly one end or error event. Cancelling ctx aborts the request.
+func (c *Channel) Stream(ctx context.Context, prompt string, inputContext string, handler StreamHandler) (*Response, error) {
+	if handler == nil {
+		handler = func(StreamEvent) {}
+	}
+	if !c.configured {
+		handler(StreamEvent{Type: StreamEventError, Text: ErrNotConfigured.Error()})
+		return nil, ErrNotConfigured
+	}
+
+	handler(StreamEvent{Type: StreamEventStart})
+	onChunk := func(text string) {
+		if text != "" {
+			handler(StreamEvent{Type: StreamEventChunk, Text: text})
+		}
+	}
+
+	var resp *Response
+	var err error
+	switch {
+	case c.config.UseAPI:
+		resp, err = c.streamWithAPI(ctx, prompt, inputContext, onChunk)
+	case c.config.Agent == AgentClaude:
+		resp, err = c.streamWithClaudeCLI(ctx, prompt, inputContext, handler)
+	default:
+		// No incremental output available - deliver the whole response at once
+		var out string
+		out, err = c.Send(ctx, prompt, inputContext)
+		if err == nil {
+			resp = &Response{Result: out}
+			onChunk(out)
+		}
+	}
+
+	if err == nil && resp != nil && resp.IsError {
+		err = fmt.Errorf("%w: %s", ErrAgentError, resp.Result)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		handler(StreamEvent{Type: StreamEventError, Text: err.Error()})
+		return nil, err
+	}
+
+	handler(StreamEvent{Type: StreamEventEnd, Response: resp})
+	return resp, nil
+}
+
+// streamWithAPI streams through the provider, falling back to a single chunk
+// for providers without streaming support.
+func (c *Channel) streamWithAPI(ctx context.Context, prompt, inputContext string, onChunk func(string)) (*Response, error) {
+	if c.provider == nil {
+		return nil, fmt.Errorf("%w: provider not configured", ErrNotAvailable)
+	}
+	if !c.provider.IsConfigured() {
+		return nil, ErrNoAPIKey
+	}
+
+	if sp, ok := c.provider.(StreamingProvider); ok {
+		return sp.StreamWithContext(ctx, c.config.SystemPrompt, prompt, inputContext, onChunk)
+	}
+
+	resp, err := c.provider.CompleteWithContext(ctx, c.config.SystemPrompt, prompt, inputContext)
+	if err != nil {
+		return nil, err
+	}
+	onChunk(resp.Result)
+	return resp, nil
+}
+
+// streamWithClaudeCLI runs Claude Code with --output-format stream-json and
+// converts each JSONL message into stream events.
+func (c *Channel) streamWithClaudeCLI(ctx context.Context, prompt, inputContext string, handler StreamHandler) (*Response, error) {
+	if !c.IsAvailable() {
+		return nil, fmt.Errorf("%w: %s not found in PATH", ErrNotAvailable, c.config.Command)
+	}
+
+	config := c.config
+	config.OutputFormat = string(OutputFormatStreamJSON)
+	// Claude Code requires --verbose for stream-json in print mode
+	config.Args = append(append([]string{}, config.Args...), "--verbose")
+
+	execCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	adapter := &ClaudeAdapter{}
+	cmd, err := adapter.BuildCommand(execCtx, config, prompt, inputContext, config.SystemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build command: %w", err)
+	}
+
+	return runStreamJSON(execCtx, cmd, adapter.RequiresPTY(), handler)
+}
+
+// runStreamJSON starts cmd and decodes its stream-json output line by line.
+func runStreamJSON(ctx context.Context, cmd *exec.Cmd, usePTY bool, handler StreamHandler) (*Response, error) {
+	var out io.Reader
+	var closer io.Closer
+
+	if usePTY {
+		ptmx, err := pty.Start(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start PTY: %w", err)
+		}
+		out, closer = ptmx, ptmx
+	} else {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start command: %w", err)
+		}
+		out = stdout
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	decoder := NewStreamJSONDecoder(handler)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(out)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			decoder.DecodeLine(stripANSI(scanner.Text()))
+		}
+	}()
+
+	var waitErr error
+	if closer == nil {
+		// Read to EOF before Wait, which closes the pipe and would drop
+		// unread output such as the final result line
+		<-done
+		waitErr = cmd.Wait()
+	} else {
+		// PTY reads return EIO once the child has exited and its output is
+		// drained; give the reader a moment to finish, then unblock it
+		waitErr = cmd.Wait()
+		select {
+		case <-done:
+		case <-time.After(100 * time.Millisecond):
+		}
+		closer.Close()
+		<-done
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, ErrTimeout
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if waitErr != nil {
+		return nil, fmt.Errorf("command failed: %w", waitErr)
+	}
+	return decoder.Response()
+}
+
+// StreamJSONDecoder incrementally decodes Claude Code stream-json output,
+// emitting assistant text as chunk events and tool activity as system events.
+type StreamJSONDecoder struct {
+	handler   StreamHandler
+	response  *Response
+	content   strings.Builder
+	sessionID string
+}
+
+// NewStreamJSONDecoder creates a decoder that reports events to handler.
+func NewStreamJSONDecoder(handler StreamHandler) *StreamJSONDecoder {
+	if handler == nil {
+		handler = func(StreamEvent) {}
+	}
+	return &StreamJSONDecoder{handler: handler}
+}
+
+// DecodeLine processes one line of stream-json output. Malformed lines are ignored.
+func (d *StreamJSONDecoder) DecodeLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	var msg StreamJSONMessage
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		return
+	}
+
+	switch msg.Type {
+	case "init", "system":
+		if msg.SessionID != "" {
+			d.sessionID = msg.SessionID
+		}
+
+	case "assistant":
+		if msg.Message == nil {
+			return
+		}
+		for _, block := range msg.Message.Content {
+			switch block.Type {
+			case "text":
+				d.content.WriteString(block.Text)
+				d.handler(StreamEvent{Type: StreamEventChunk, Text: block.Text})
+			case "tool_use":
+				d.handler(StreamEvent{Type: StreamEventSystem, Text: "Using tool " + block.Name})
+			}
+		}
+
+	case "result":
+		d.response = &Response{
+			Result:        msg.Result,
+			SessionID:     msg.SessionID,
+			TotalCostUSD:  msg.TotalCostUSD,
+			DurationMS:    msg.DurationMS,
+			DurationAPIMS: msg.DurationAPIMS,
+			NumTurns:      msg.NumTurns,
+			IsError:       msg.IsError,
+			Subtype:       msg.Subtype,
+		}
+		if d.response.SessionID == "" {
+			d.response.SessionID = d.sessionID
+		}
+	}
+}
+
+// Response returns the final response, falling back to the accumulated
+// assistant text when no result message was seen.
+func (d *StreamJSONDecoder) Response() (*Response, error) {
+	if d.response != nil {
+		return d.response, nil
+	}
+	if d.content.Len() > 0 {
+		return &Response{
+			Result:    strings.TrimSpace(d.content.String()),
+			SessionID: d.sessionID,
+		}, nil
+	}
+	return nil, fmt.Errorf("no result found in stream-json response")
+}
@@ -0,0 +1,145 @@
+package aichannel
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func collectEvents(events *[]StreamEvent) StreamHandler {
+	return func(ev StreamEvent) {
+		*events = append(*events, ev)
+	}
+}
+
+func TestChannel_StreamAPI(t *testing.T) {
+	_, srv := newTestMock(t, MockFixture{Chunks: []string{"Hello", ", ", "world"}, Response: "Hello, world"})
+	t.Setenv(MockURLEnv, srv.URL)
+
+	ch := NewWithConfig(Config{Agent: AgentClaude})
+	if !ch.SupportsStreaming() {
+		t.Fatal("mock API channel should support streaming")
+	}
+
+	var events []StreamEvent
+	resp, err := ch.Stream(context.Background(), "hi", "", collectEvents(&events))
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if resp.Result != "Hello, world" {
+		t.Errorf("Result = %q", resp.Result)
+	}
+
+	if len(events) < 3 {
+		t.Fatalf("got %d events, want start, chunks and end", len(events))
+	}
+	if events[0].Type != StreamEventStart || events[len(events)-1].Type != StreamEventEnd {
+		t.Errorf("events should be bracketed by start/end: %+v", events)
+	}
+	var text strings.Builder
+	chunks := 0
+	for _, ev := range events {
+		if ev.Type == StreamEventChunk {
+			chunks++
+			text.WriteString(ev.Text)
+		}
+	}
+	if chunks < 2 || text.String() != "Hello, world" {
+		t.Errorf("chunks = %d, text = %q", chunks, text.String())
+	}
+}
+
+func TestAnthropicProvider_Stream(t *testing.T) {
+	_, srv := newTestMock(t, MockFixture{Chunks: []string{"a", "b", "c"}, Response: "abc"})
+	t.Setenv(MockURLEnv, srv.URL)
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("CLAUDE_KEY", "")
+
+	var chunks []string
+	resp, err := NewAnthropicProvider(ProviderConfig{}).StreamWithContext(context.Background(), "", "x", "", func(s string) {
+		chunks = append(chunks, s)
+	})
+	if err != nil {
+		t.Fatalf("StreamWithContext: %v", err)
+	}
+	if strings.Join(chunks, "|") != "a|b|c" || resp.Result != "abc" {
+		t.Errorf("chunks = %v, result = %q", chunks, resp.Result)
+	}
+}
+
+func TestChannel_StreamNotConfigured(t *testing.T) {
+	var events []StreamEvent
+	if _, err := (&Channel{}).Stream(context.Background(), "x", "", collectEvents(&events)); err != ErrNotConfigured {
+		t.Errorf("err = %v, want ErrNotConfigured", err)
+	}
+	if len(events) != 1 || events[0].Type != StreamEventError {
+		t.Errorf("events = %+v, want a single error", events)
+	}
+}
+
+func TestStreamJSONDecoder(t *testing.T) {
+	var events []StreamEvent
+	d := NewStreamJSONDecoder(collectEvents(&events))
+	for _, line := range []string{
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Looking"}]}}`,
+		`not json`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Read"},{"type":"text","text":" done"}]}}`,
+		`{"type":"result","subtype":"success","result":"Looking done","num_turns":2}`,
+	} {
+		d.DecodeLine(line)
+	}
+
+	var types []string
+	for _, ev := range events {
+		types = append(types, string(ev.Type)+":"+ev.Text)
+	}
+	want := "chunk:Looking,system:Using tool Read,chunk: done"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+
+	resp, err := d.Response()
+	if err != nil {
+		t.Fatalf("Response: %v", err)
+	}
+	if resp.Result != "Looking done" || resp.SessionID != "s1" || resp.NumTurns != 2 {
+		t.Errorf("Response = %+v", resp)
+	}
+}
+
+func TestStreamJSONDecoder_NoResult(t *testing.T) {
+	d := NewStreamJSONDecoder(nil)
+	if _, err := d.Response(); err == nil {
+		t.Error("expected error with no output")
+	}
+	d.DecodeLine(`{"type":"assistant","message":{"content":[{"type":"text","text":"partial "}]}}`)
+	if resp, err := d.Response(); err != nil || resp.Result != "partial" {
+		t.Errorf("Response = %+v, %v", resp, err)
+	}
+}
+
+func TestRunStreamJSON_ReadsFinalResult(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	script := `for i in $(seq 1 200); do echo '{"type":"assistant","message":{"content":[{"type":"text","text":"x"}]}}'; done; ` +
+		`echo '{"type":"result","subtype":"success","result":"all done"}'`
+
+	for _, usePTY := range []bool{false, true} {
+		cmd := exec.Command("sh", "-c", script)
+		chunks := 0
+		resp, err := runStreamJSON(context.Background(), cmd, usePTY, func(ev StreamEvent) {
+			if ev.Type == StreamEventChunk {
+				chunks++
+			}
+		})
+		if err != nil {
+			t.Fatalf("pty=%v: %v", usePTY, err)
+		}
+		if resp.Result != "all done" || chunks != 200 {
+			t.Errorf("pty=%v: result = %q after %d chunks", usePTY, resp.Result, chunks)
+		}
+	}
+}
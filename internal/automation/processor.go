@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os/exec"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -45,6 +46,10 @@ type ProcessorConfig struct {
 
 	// TimeoutSecs is the timeout for each task
 	TimeoutSecs int
+
+	// OfflineFallback processes audit tasks with the rule-based processor
+	// when the agent CLI is not available instead of failing.
+	OfflineFallback bool
 }
 
 // ProcessorStats tracks processor statistics.
@@ -60,10 +65,11 @@ type ProcessorStats struct {
 // DefaultConfig returns config optimized for automation tasks.
 func DefaultConfig() ProcessorConfig {
 	return ProcessorConfig{
-		Model:        "haiku", // Fast and cheap for processing
-		MaxBudgetUSD: 0.01,    // $0.01 limit per task
-		MaxTurns:     3,       // Usually 1-2 turns needed
-		TimeoutSecs:  30,      // 30 second timeout
+		Model:           "haiku", // Fast and cheap for processing
+		MaxBudgetUSD:    0.01,    // $0.01 limit per task
+		MaxTurns:        3,       // Usually 1-2 turns needed
+		TimeoutSecs:     30,      // 30 second timeout
+		OfflineFallback: true,    // Rule-based audit processing without an agent
 		DisallowedTools: []string{ // No file/bash access for processing
 			"Bash", "Write", "Edit", "Read",
 		},
@@ -111,6 +117,10 @@ func (p *Processor) Process(ctx context.Context, task Task) (*Result, error) {
 
 	startTime := time.Now()
 
+	if task.Type == TaskTypeAuditProcess && (task.Options.Offline || (p.config.OfflineFallback && !AgentAvailable())) {
+		return p.processOffline(task, startTime), nil
+	}
+
 	// Get the system prompt for this task type
 	systemPrompt := p.prompts.Get(task.Type)
 	if systemPrompt == "" {
@@ -189,6 +199,36 @@ func (p *Processor) Process(ctx context.Context, task Task) (*Result, error) {
 	return result, nil
 }
 
+// processOffline runs an audit task through the rule-based processor.
+func (p *Processor) processOffline(task Task, startTime time.Time) *Result {
+	atomic.AddInt64(&p.stats.TasksProcessed, 1)
+
+	input, err := toAuditInput(task.Input)
+	if err != nil {
+		atomic.AddInt64(&p.stats.TasksFailed, 1)
+		return &Result{
+			Type:     task.Type,
+			Error:    err,
+			Duration: time.Since(startTime),
+			Offline:  true,
+		}
+	}
+
+	atomic.AddInt64(&p.stats.TasksSucceeded, 1)
+	return &Result{
+		Type:     task.Type,
+		Output:   ProcessAuditOffline(input),
+		Duration: time.Since(startTime),
+		Offline:  true,
+	}
+}
+
+// AgentAvailable reports whether the claude CLI used for agent processing is installed.
+func AgentAvailable() bool {
+	_, err := exec.LookPath("claude")
+	return err == nil
+}
+
 // ProcessBatch runs multiple tasks concurrently.
 func (p *Processor) ProcessBatch(ctx context.Context, tasks []Task) ([]*Result, error) {
 	if p.closed.Load() {
@@ -0,0 +1,742 @@
+package automation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/textutil"
+)
+
+// Rule-based audit processing.
+//
+// ProcessAuditOffline converts raw audit output into AuditProcessOutput without
+// an LLM. It is used when no agent CLI is available and serves as a
+// deterministic baseline for the LLM path in tests.
+
+// severityRank orders severities for sorting (lower sorts first).
+var severityRank = map[string]int{
+	"error":   0,
+	"warning": 1,
+	"info":    2,
+}
+
+// axeImpactSeverity maps axe-core impact levels to output severities.
+var axeImpactSeverity = map[string]string{
+	"critical": "error",
+	"serious":  "error",
+	"moderate": "warning",
+	"minor":    "info",
+}
+
+// axeImpactScore maps axe-core impact levels to 1-10 impact scores.
+var axeImpactScore = map[string]int{
+	"critical": 10,
+	"serious":  8,
+	"moderate": 5,
+	"minor":    2,
+}
+
+// severityPenalty is the score deduction per issue, matching auditProcessPrompt.
+var severityPenalty = map[string]int{
+	"error":   10,
+	"warning": 5,
+	"info":    1,
+}
+
+// wcagCriteria maps WCAG success criterion numbers to their names.
+var wcagCriteria = map[string]string{
+	"1.1.1":  "Non-text Content",
+	"1.2.2":  "Captions (Prerecorded)",
+	"1.3.1":  "Info and Relationships",
+	"1.3.5":  "Identify Input Purpose",
+	"1.4.1":  "Use of Color",
+	"1.4.3":  "Contrast (Minimum)",
+	"1.4.4":  "Resize Text",
+	"1.4.6":  "Contrast (Enhanced)",
+	"1.4.12": "Text Spacing",
+	"2.1.1":  "Keyboard",
+	"2.1.2":  "No Keyboard Trap",
+	"2.2.1":  "Timing Adjustable",
+	"2.4.1":  "Bypass Blocks",
+	"2.4.2":  "Page Titled",
+	"2.4.3":  "Focus Order",
+	"2.4.4":  "Link Purpose (In Context)",
+	"2.4.7":  "Focus Visible",
+	"2.5.3":  "Label in Name",
+	"3.1.1":  "Language of Page",
+	"3.1.2":  "Language of Parts",
+	"3.3.2":  "Labels or Instructions",
+	"4.1.1":  "Parsing",
+	"4.1.2":  "Name, Role, Value",
+}
+
+// ruleWCAG maps common axe rule IDs to WCAG criteria when tags are absent.
+var ruleWCAG = map[string]string{
+	"image-alt":                  "1.1.1",
+	"input-image-alt":            "1.1.1",
+	"area-alt":                   "1.1.1",
+	"svg-img-alt":                "1.1.1",
+	"missing-alt":                "1.1.1",
+	"button-name":                "4.1.2",
+	"input-button-name":          "4.1.2",
+	"link-name":                  "2.4.4",
+	"label":                      "1.3.1",
+	"missing-label":              "1.3.1",
+	"form-field-multiple-labels": "3.3.2",
+	"color-contrast":             "1.4.3",
+	"color-contrast-enhanced":    "1.4.6",
+	"heading-order":              "1.3.1",
+	"heading-skip":               "1.3.1",
+	"list":                       "1.3.1",
+	"listitem":                   "1.3.1",
+	"bypass":                     "2.4.1",
+	"document-title":             "2.4.2",
+	"html-has-lang":              "3.1.1",
+	"html-lang-valid":            "3.1.1",
+	"valid-lang":                 "3.1.2",
+	"landmark-one-main":          "1.3.1",
+	"region":                     "1.3.1",
+	"aria-required-attr":         "4.1.2",
+	"aria-valid-attr":            "4.1.2",
+	"aria-valid-attr-value":      "4.1.2",
+	"aria-hidden-focus":          "4.1.2",
+	"aria-allowed-attr":          "4.1.2",
+	"duplicate-id":               "4.1.1",
+	"duplicate-id-aria":          "4.1.1",
+	"tabindex":                   "2.4.3",
+	"focus-order-semantics":      "2.4.3",
+	"meta-viewport":              "1.4.4",
+	"video-caption":              "1.2.2",
+	"autocomplete-valid":         "1.3.5",
+}
+
+// ruleFixes maps rule IDs to fix instructions.
+var ruleFixes = map[string]string{
+	"image-alt":          `Add alt="[description]" attribute to this image`,
+	"missing-alt":        `Add alt="[description]" attribute to this image`,
+	"button-name":        "Add text content, aria-label, or title to this button",
+	"input-button-name":  "Add a value or aria-label to this input button",
+	"link-name":          "Add descriptive text or aria-label to this link",
+	"label":              "Add a <label> element or aria-label to this input",
+	"missing-label":      "Add a <label> element or aria-label to this input",
+	"color-contrast":     "Increase text/background contrast ratio to at least 4.5:1 (3:1 for large text)",
+	"heading-order":      "Fix heading level order (no skipped levels)",
+	"heading-skip":       "Fix heading level order (no skipped levels)",
+	"bypass":             "Add a skip link at the top of the page",
+	"document-title":     "Add a descriptive <title> element",
+	"html-has-lang":      "Add lang attribute to <html> element",
+	"landmark-one-main":  `Add role="main" or <main> landmark`,
+	"region":             "Wrap content in appropriate landmark regions",
+	"aria-required-attr": "Add required ARIA attributes for this role",
+	"aria-valid-attr":    "Fix invalid ARIA attribute names",
+	"aria-hidden-focus":  "Remove focusable elements from aria-hidden containers",
+	"duplicate-id":       "Make element IDs unique",
+	"tabindex":           `Use tabindex="0" or "-1" only, avoid positive values`,
+	"meta-viewport":      "Remove user-scalable=no and maximum-scale from the viewport meta tag",
+}
+
+// ruleGroups assigns rule IDs to correlation groups.
+var ruleGroups = map[string]string{
+	"image-alt":          "Missing text alternatives",
+	"missing-alt":        "Missing text alternatives",
+	"input-image-alt":    "Missing text alternatives",
+	"area-alt":           "Missing text alternatives",
+	"svg-img-alt":        "Missing text alternatives",
+	"button-name":        "Unnamed controls",
+	"input-button-name":  "Unnamed controls",
+	"link-name":          "Unnamed controls",
+	"label":              "Unlabeled form fields",
+	"missing-label":      "Unlabeled form fields",
+	"heading-order":      "Document structure",
+	"heading-skip":       "Document structure",
+	"landmark-one-main":  "Document structure",
+	"region":             "Document structure",
+	"bypass":             "Document structure",
+	"aria-required-attr": "ARIA misuse",
+	"aria-valid-attr":    "ARIA misuse",
+	"aria-hidden-focus":  "ARIA misuse",
+	"aria-allowed-attr":  "ARIA misuse",
+}
+
+// groupFixes provides a common fix for each correlation group.
+var groupFixes = map[string]string{
+	"Missing text alternatives": "Give every meaningful image an alt attribute; use alt=\"\" for decorative images",
+	"Unnamed controls":          "Ensure every button and link has visible text or an aria-label",
+	"Unlabeled form fields":     "Associate a <label> with every form field",
+	"Document structure":        "Use a single <main>, landmark regions and sequential heading levels",
+	"ARIA misuse":               "Prefer native elements; validate ARIA roles and attributes against the spec",
+	"Low contrast":              "Adjust the shared color palette to meet contrast ratios",
+}
+
+const maxElementLen = 100
+
+// ProcessAuditOffline converts raw audit data into AuditProcessOutput using
+// severity tables, selector grouping and WCAG criterion mapping.
+//
+// It understands raw axe-core results (violations/incomplete/passes), the
+// AI-optimized axe format (raw.issuesByType) and the action-oriented format
+// produced by the browser audits (fixable/informational).
+func ProcessAuditOffline(input AuditProcessInput) *AuditProcessOutput {
+	b := &auditBuilder{seen: make(map[string]bool), ids: make(map[string]int)}
+	raw := input.RawData
+
+	b.collectAxe(raw)
+	if nested, ok := raw["raw"].(map[string]interface{}); ok {
+		b.collectIssuesByType(nested)
+	}
+	b.collectIssuesByType(raw)
+	b.collectActionOriented(raw)
+
+	out := &AuditProcessOutput{
+		CheckedAt:     stringField(raw, "checkedAt", "checked_at", "timestamp"),
+		ChecksRun:     b.checks,
+		Fixable:       b.fixable,
+		Informational: b.info,
+	}
+	if out.CheckedAt == "" {
+		out.CheckedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	if out.ChecksRun == nil {
+		out.ChecksRun = []string{}
+	}
+	if out.Fixable == nil {
+		out.Fixable = []FixableIssue{}
+	}
+	if out.Informational == nil {
+		out.Informational = []InformationalIssue{}
+	}
+
+	sort.SliceStable(out.Fixable, func(i, j int) bool {
+		a, c := out.Fixable[i], out.Fixable[j]
+		if severityRank[a.Severity] != severityRank[c.Severity] {
+			return severityRank[a.Severity] < severityRank[c.Severity]
+		}
+		return a.Impact > c.Impact
+	})
+
+	score := 100
+	for _, f := range out.Fixable {
+		out.Stats.countSeverity(f.Severity)
+		score -= severityPenalty[f.Severity]
+	}
+	for _, inf := range out.Informational {
+		out.Stats.countSeverity(inf.Severity)
+	}
+	if score < 0 {
+		score = 0
+	}
+	out.Score = score
+	out.Grade = gradeFor(score)
+	out.Stats.Fixable = len(out.Fixable)
+	out.Stats.Informational = len(out.Informational)
+	out.CorrelatedGroups = correlate(out.Fixable)
+	out.Actions = buildActions(out.Fixable, out.CorrelatedGroups)
+	out.Summary = buildSummary(input, out)
+
+	return out
+}
+
+// auditBuilder accumulates issues from the different raw formats.
+type auditBuilder struct {
+	fixable []FixableIssue
+	info    []InformationalIssue
+	checks  []string
+	seen    map[string]bool // dedup key: type|selector
+	ids     map[string]int  // per-type ID counters
+}
+
+func (b *auditBuilder) nextID(prefix string) string {
+	b.ids[prefix]++
+	return fmt.Sprintf("%s-%d", prefix, b.ids[prefix])
+}
+
+func (b *auditBuilder) addCheck(id string) {
+	for _, c := range b.checks {
+		if c == id {
+			return
+		}
+	}
+	b.checks = append(b.checks, id)
+}
+
+func (b *auditBuilder) addFixable(issue FixableIssue) {
+	key := issue.Type + "|" + issue.Selector
+	if b.seen[key] {
+		return
+	}
+	b.seen[key] = true
+	if issue.ID == "" {
+		issue.ID = b.nextID(issue.Type)
+	}
+	if issue.Fix == "" {
+		issue.Fix = fixFor(issue.Type)
+	}
+	if issue.Standard == "" {
+		issue.Standard = standardFor(issue.Type, nil)
+	}
+	if issue.Impact <= 0 {
+		issue.Impact = 5
+	}
+	if issue.Impact > 10 {
+		issue.Impact = 10
+	}
+	issue.Element = truncate(issue.Element, maxElementLen)
+	b.fixable = append(b.fixable, issue)
+	b.addCheck(issue.Type)
+}
+
+func (b *auditBuilder) addInfo(issue InformationalIssue) {
+	if issue.ID == "" {
+		issue.ID = b.nextID(issue.Type)
+	}
+	if issue.Severity == "" {
+		issue.Severity = "info"
+	}
+	b.info = append(b.info, issue)
+}
+
+// collectAxe handles raw axe-core results.
+func (b *auditBuilder) collectAxe(raw map[string]interface{}) {
+	for _, v := range sliceField(raw, "violations") {
+		violation, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ruleID := stringField(violation, "id")
+		impact := stringField(violation, "impact")
+		tags := stringSlice(violation["tags"])
+		for _, n := range sliceField(violation, "nodes") {
+			node, ok := n.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			b.addFixable(FixableIssue{
+				Type:     ruleID,
+				Severity: severityFor(impact, ""),
+				Impact:   axeImpactScore[impact],
+				Selector: strings.Join(stringSlice(node["target"]), ", "),
+				Element:  stringField(node, "html"),
+				Message:  stringField(violation, "help", "description"),
+				Fix:      fixFor(ruleID),
+				Standard: standardFor(ruleID, tags),
+			})
+		}
+	}
+	for _, v := range sliceField(raw, "incomplete") {
+		inc, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ruleID := stringField(inc, "id")
+		b.addInfo(InformationalIssue{
+			Type:     ruleID + "-needs-review",
+			Message:  stringField(inc, "help", "description") + " (needs manual review)",
+			Severity: "info",
+			Context: map[string]interface{}{
+				"nodeCount": len(sliceField(inc, "nodes")),
+			},
+		})
+		b.addCheck(ruleID)
+	}
+	passes := sliceField(raw, "passes")
+	if len(passes) > 0 {
+		var ids []string
+		for _, p := range passes {
+			if pm, ok := p.(map[string]interface{}); ok {
+				id := stringField(pm, "id")
+				ids = append(ids, id)
+				b.addCheck(id)
+			}
+		}
+		b.addInfo(InformationalIssue{
+			Type:    "checks-passed",
+			Message: fmt.Sprintf("%d checks passed", len(passes)),
+			Context: map[string]interface{}{"passedRules": ids},
+		})
+	}
+}
+
+// collectIssuesByType handles the AI-optimized axe format.
+func (b *auditBuilder) collectIssuesByType(raw map[string]interface{}) {
+	byType, ok := raw["issuesByType"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	// Sort rule IDs so output is deterministic regardless of map order.
+	ruleIDs := make([]string, 0, len(byType))
+	for id := range byType {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+
+	for _, ruleID := range ruleIDs {
+		group, ok := byType[ruleID].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		impact := stringField(group, "impact")
+		wcag := stringField(group, "wcag")
+		examples := sliceField(group, "examples")
+		for _, e := range examples {
+			ex, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			b.addFixable(FixableIssue{
+				Type:     ruleID,
+				Severity: severityFor(impact, ""),
+				Impact:   axeImpactScore[impact],
+				Selector: stringField(ex, "selector"),
+				Element:  stringField(ex, "html"),
+				Message:  stringField(group, "message"),
+				Fix:      stringField(group, "fix"),
+				Standard: formatStandard(wcag),
+			})
+		}
+		if count := intField(group, "count"); count > len(examples) {
+			b.addInfo(InformationalIssue{
+				Type:    ruleID + "-more",
+				Message: fmt.Sprintf("%d more %s issues not shown", count-len(examples), ruleID),
+				Context: map[string]interface{}{"total": count},
+			})
+		}
+		b.addCheck(ruleID)
+	}
+	for _, r := range sliceField(raw, "incompleteRules") {
+		inc, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		b.addInfo(InformationalIssue{
+			Type:    stringField(inc, "id") + "-needs-review",
+			Message: stringField(inc, "message") + " (needs manual review)",
+			Context: map[string]interface{}{"nodeCount": intField(inc, "count")},
+		})
+	}
+}
+
+// collectActionOriented handles fixable/informational/issues arrays.
+func (b *auditBuilder) collectActionOriented(raw map[string]interface{}) {
+	items := append(sliceField(raw, "fixable"), sliceField(raw, "issues")...)
+	for _, it := range items {
+		issue, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		issueType := stringField(issue, "type", "ruleId", "id")
+		selector := stringField(issue, "selector")
+		if selector == "" {
+			// Without a selector the issue cannot be targeted.
+			b.addInfo(InformationalIssue{
+				Type:     issueType,
+				Severity: severityFor(stringField(issue, "impact"), stringField(issue, "severity")),
+				Message:  stringField(issue, "message"),
+			})
+			continue
+		}
+		impact := intField(issue, "impact")
+		if impact == 0 {
+			impact = axeImpactScore[stringField(issue, "impact")]
+		}
+		b.addFixable(FixableIssue{
+			Type:     issueType,
+			Severity: severityFor(stringField(issue, "impact"), stringField(issue, "severity")),
+			Impact:   impact,
+			Selector: selector,
+			Element:  stringField(issue, "element", "html"),
+			Message:  stringField(issue, "message"),
+			Fix:      stringField(issue, "fix"),
+			Standard: formatStandard(stringField(issue, "wcag", "standard")),
+		})
+	}
+	for _, it := range sliceField(raw, "informational") {
+		issue, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ctx, _ := issue["context"].(map[string]interface{})
+		b.addInfo(InformationalIssue{
+			Type:     stringField(issue, "type"),
+			Severity: stringField(issue, "severity"),
+			Message:  stringField(issue, "message"),
+			Context:  ctx,
+		})
+	}
+	for _, c := range stringSlice(raw["checksRun"]) {
+		b.addCheck(c)
+	}
+}
+
+// correlate groups fixable issues by rule family and shared selector.
+func correlate(issues []FixableIssue) []CorrelatedGroup {
+	byGroup := make(map[string][]string)
+	var order []string
+	add := func(name, id string) {
+		if _, ok := byGroup[name]; !ok {
+			order = append(order, name)
+		}
+		byGroup[name] = append(byGroup[name], id)
+	}
+
+	for _, issue := range issues {
+		name := ruleGroups[issue.Type]
+		if name == "" && strings.Contains(issue.Type, "contrast") {
+			name = "Low contrast"
+		}
+		if name != "" {
+			add(name, issue.ID)
+		}
+	}
+
+	// Multiple distinct issues on the same element usually share one fix.
+	bySelector := make(map[string][]FixableIssue)
+	var selectors []string
+	for _, issue := range issues {
+		if _, ok := bySelector[issue.Selector]; !ok {
+			selectors = append(selectors, issue.Selector)
+		}
+		bySelector[issue.Selector] = append(bySelector[issue.Selector], issue)
+	}
+
+	var groups []CorrelatedGroup
+	for _, name := range order {
+		ids := byGroup[name]
+		if len(ids) < 2 {
+			continue
+		}
+		groups = append(groups, CorrelatedGroup{
+			Name:        name,
+			IssueIDs:    ids,
+			Description: fmt.Sprintf("%d issues share the same root cause", len(ids)),
+			CommonFix:   groupFixes[name],
+		})
+	}
+	for _, sel := range selectors {
+		same := bySelector[sel]
+		if len(same) < 2 || sel == "" {
+			continue
+		}
+		ids := make([]string, len(same))
+		for i, s := range same {
+			ids[i] = s.ID
+		}
+		groups = append(groups, CorrelatedGroup{
+			Name:        "Element " + sel,
+			IssueIDs:    ids,
+			Description: fmt.Sprintf("%d issues affect the same element", len(ids)),
+		})
+	}
+	return groups
+}
+
+// buildActions creates up to 5 prioritized action items.
+func buildActions(issues []FixableIssue, groups []CorrelatedGroup) []string {
+	actions := []string{}
+	covered := make(map[string]bool)
+	for _, g := range groups {
+		if g.CommonFix == "" {
+			continue
+		}
+		actions = append(actions, fmt.Sprintf("%s (%d issues)", g.CommonFix, len(g.IssueIDs)))
+		for _, id := range g.IssueIDs {
+			covered[id] = true
+		}
+		if len(actions) == 5 {
+			return actions
+		}
+	}
+	for _, issue := range issues {
+		if covered[issue.ID] {
+			continue
+		}
+		covered[issue.ID] = true
+		actions = append(actions, fmt.Sprintf("%s (%s)", issue.Fix, issue.Selector))
+		if len(actions) == 5 {
+			break
+		}
+	}
+	return actions
+}
+
+func buildSummary(input AuditProcessInput, out *AuditProcessOutput) string {
+	auditType := input.AuditType
+	if auditType == "" {
+		auditType = "audit"
+	}
+	if len(out.Fixable) == 0 {
+		return fmt.Sprintf("No fixable %s issues found (score %d, grade %s).", auditType, out.Score, out.Grade)
+	}
+	top := out.Fixable[0]
+	return fmt.Sprintf("Found %d fixable %s issues (%d errors, %d warnings); most impactful: %s. Score %d, grade %s.",
+		len(out.Fixable), auditType, out.Stats.Errors, out.Stats.Warnings, top.Message, out.Score, out.Grade)
+}
+
+func (s *AuditStats) countSeverity(severity string) {
+	switch severity {
+	case "error":
+		s.Errors++
+	case "warning":
+		s.Warnings++
+	default:
+		s.Info++
+	}
+}
+
+func gradeFor(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// severityFor resolves a severity from an axe impact or an explicit severity.
+func severityFor(impact, severity string) string {
+	if s, ok := axeImpactSeverity[impact]; ok {
+		return s
+	}
+	switch severity {
+	case "critical", "error":
+		return "error"
+	case "warning":
+		return "warning"
+	case "":
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func fixFor(ruleID string) string {
+	if fix, ok := ruleFixes[ruleID]; ok {
+		return fix
+	}
+	return "Review and fix this issue"
+}
+
+// standardFor derives the WCAG reference from axe tags (e.g. "wcag143") or the rule table.
+func standardFor(ruleID string, tags []string) string {
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "wcag") || len(tag) < 7 {
+			continue
+		}
+		digits := tag[4:]
+		if strings.Trim(digits, "0123456789") != "" {
+			continue // e.g. wcag2aa
+		}
+		criterion := digits[:1] + "." + digits[1:2] + "." + digits[2:]
+		return formatStandard(criterion)
+	}
+	return formatStandard(ruleWCAG[ruleID])
+}
+
+// formatStandard renders a WCAG criterion number as "WCAG 1.4.3 Contrast (Minimum)".
+func formatStandard(criterion string) string {
+	criterion = strings.TrimSpace(strings.TrimPrefix(criterion, "WCAG"))
+	if criterion == "" {
+		return ""
+	}
+	if name, ok := wcagCriteria[criterion]; ok {
+		return "WCAG " + criterion + " " + name
+	}
+	return "WCAG " + criterion
+}
+
+// truncate cuts s to at most max bytes, ending in "...", without splitting
+// a rune.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return textutil.Truncate(s, max-3) + "..."
+}
+
+// stringField returns the first non-empty string value among keys.
+func stringField(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func intField(m map[string]interface{}, key string) int {
+	switch v := m[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	}
+	return 0
+}
+
+func sliceField(m map[string]interface{}, key string) []interface{} {
+	s, _ := m[key].([]interface{})
+	return s
+}
+
+func stringSlice(v interface{}) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []interface{}:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// toAuditInput normalizes a task input into AuditProcessInput.
+// Inputs may be an AuditProcessInput, a pointer to one, or a generic map
+// (as received over the hub protocol). A map without raw_data is treated as
+// the raw data itself.
+func toAuditInput(v interface{}) (AuditProcessInput, error) {
+	switch in := v.(type) {
+	case AuditProcessInput:
+		return in, nil
+	case *AuditProcessInput:
+		if in == nil {
+			return AuditProcessInput{}, fmt.Errorf("nil audit input")
+		}
+		return *in, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return AuditProcessInput{}, fmt.Errorf("failed to marshal audit input: %w", err)
+	}
+	var input AuditProcessInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return AuditProcessInput{}, fmt.Errorf("invalid audit input: %w", err)
+	}
+	if input.RawData == nil {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return AuditProcessInput{}, fmt.Errorf("invalid audit input: %w", err)
+		}
+		input.RawData = raw
+		if input.AuditType == "" {
+			input.AuditType = stringField(raw, "audit", "auditType")
+		}
+	}
+	return input, nil
+}
@@ -0,0 +1,187 @@
+package automation
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+const axeFixture = `{
+  "violations": [
+    {
+      "id": "image-alt",
+      "impact": "critical",
+      "help": "Images must have alternate text",
+      "tags": ["cat.text-alternatives", "wcag2a", "wcag111"],
+      "nodes": [
+        {"target": ["#hero > img"], "html": "<img src=\"hero.png\">"},
+        {"target": [".logo"], "html": "<img class=\"logo\" src=\"logo.png\">"}
+      ]
+    },
+    {
+      "id": "color-contrast",
+      "impact": "serious",
+      "help": "Elements must have sufficient color contrast",
+      "tags": ["wcag2aa", "wcag143"],
+      "nodes": [{"target": [".muted"], "html": "<p class=\"muted\">x</p>"}]
+    },
+    {
+      "id": "region",
+      "impact": "moderate",
+      "help": "All page content should be contained by landmarks",
+      "tags": ["best-practice"],
+      "nodes": [{"target": [".logo"], "html": "<img class=\"logo\">"}]
+    }
+  ],
+  "incomplete": [
+    {"id": "color-contrast", "help": "Elements must have sufficient color contrast", "nodes": [{}, {}]}
+  ],
+  "passes": [{"id": "document-title"}, {"id": "html-has-lang"}]
+}`
+
+func loadRaw(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+	return raw
+}
+
+func TestProcessAuditOffline_Axe(t *testing.T) {
+	out := ProcessAuditOffline(AuditProcessInput{
+		AuditType: "accessibility",
+		RawData:   loadRaw(t, axeFixture),
+	})
+
+	if len(out.Fixable) != 4 {
+		t.Fatalf("Fixable = %d, want 4", len(out.Fixable))
+	}
+	first := out.Fixable[0]
+	if first.Type != "image-alt" || first.Severity != "error" || first.Impact != 10 {
+		t.Errorf("first issue = %+v, want critical image-alt", first)
+	}
+	if first.Standard != "WCAG 1.1.1 Non-text Content" {
+		t.Errorf("Standard = %q", first.Standard)
+	}
+	if out.Fixable[3].Severity != "warning" {
+		t.Errorf("moderate impact should sort last as warning, got %+v", out.Fixable[3])
+	}
+
+	wantStats := AuditStats{Errors: 3, Warnings: 1, Info: 2, Fixable: 4, Informational: 2}
+	if out.Stats != wantStats {
+		t.Errorf("Stats = %+v, want %+v", out.Stats, wantStats)
+	}
+	// 100 - 3*10 - 1*5
+	if out.Score != 65 || out.Grade != "D" {
+		t.Errorf("Score/Grade = %d/%s, want 65/D", out.Score, out.Grade)
+	}
+
+	var names []string
+	for _, g := range out.CorrelatedGroups {
+		names = append(names, g.Name)
+	}
+	want := []string{"Missing text alternatives", "Element .logo"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("CorrelatedGroups = %v, want %v", names, want)
+	}
+	if len(out.Actions) == 0 || len(out.Actions) > 5 {
+		t.Errorf("Actions = %v, want 1-5 entries", out.Actions)
+	}
+}
+
+func TestProcessAuditOffline_Deterministic(t *testing.T) {
+	raw := loadRaw(t, `{
+		"checkedAt": "2025-01-01T00:00:00Z",
+		"raw": {"issuesByType": {
+			"link-name": {"impact": "serious", "message": "Links must have discernible text", "wcag": "2.4.4", "count": 3,
+				"examples": [{"selector": "a.icon", "html": "<a class=\"icon\">"}]},
+			"button-name": {"impact": "critical", "message": "Buttons must have discernible text", "wcag": "4.1.2", "count": 1,
+				"examples": [{"selector": "button.close", "html": "<button>"}]}
+		}}
+	}`)
+
+	a, _ := json.Marshal(ProcessAuditOffline(AuditProcessInput{RawData: raw}))
+	b, _ := json.Marshal(ProcessAuditOffline(AuditProcessInput{RawData: raw}))
+	if string(a) != string(b) {
+		t.Errorf("output is not deterministic:\n%s\n%s", a, b)
+	}
+
+	out := ProcessAuditOffline(AuditProcessInput{RawData: raw})
+	if len(out.Fixable) != 2 || out.Fixable[0].Type != "button-name" {
+		t.Errorf("Fixable = %+v", out.Fixable)
+	}
+	if len(out.Informational) != 1 || out.Informational[0].Type != "link-name-more" {
+		t.Errorf("Informational = %+v", out.Informational)
+	}
+	if len(out.CorrelatedGroups) != 1 || out.CorrelatedGroups[0].Name != "Unnamed controls" {
+		t.Errorf("CorrelatedGroups = %+v", out.CorrelatedGroups)
+	}
+}
+
+func TestProcessAuditOffline_ActionOriented(t *testing.T) {
+	raw := loadRaw(t, `{
+		"fixable": [
+			{"type": "missing-alt", "severity": "error", "impact": 9, "selector": "img", "message": "Image missing alt", "wcag": "1.1.1"},
+			{"type": "missing-alt", "severity": "error", "impact": 9, "selector": "img", "message": "duplicate"},
+			{"type": "viewport", "severity": "warning", "message": "no selector"}
+		],
+		"informational": [{"type": "checks-passed", "severity": "info", "message": "5 checks passed"}]
+	}`)
+
+	out := ProcessAuditOffline(AuditProcessInput{RawData: raw})
+	if len(out.Fixable) != 1 {
+		t.Errorf("duplicate type+selector should be merged, got %d", len(out.Fixable))
+	}
+	if len(out.Informational) != 2 {
+		t.Errorf("issue without selector should be informational, got %+v", out.Informational)
+	}
+}
+
+func TestToAuditInput(t *testing.T) {
+	in, err := toAuditInput(map[string]interface{}{
+		"audit_type": "accessibility",
+		"raw_data":   map[string]interface{}{"violations": []interface{}{}},
+	})
+	if err != nil || in.AuditType != "accessibility" || in.RawData == nil {
+		t.Errorf("toAuditInput(wrapped) = %+v, %v", in, err)
+	}
+
+	in, err = toAuditInput(map[string]interface{}{"audit": "accessibility", "violations": []interface{}{}})
+	if err != nil || in.AuditType != "accessibility" || in.RawData["violations"] == nil {
+		t.Errorf("toAuditInput(bare) = %+v, %v", in, err)
+	}
+}
+
+func TestStandardFor(t *testing.T) {
+	tests := []struct {
+		rule string
+		tags []string
+		want string
+	}{
+		{"color-contrast", []string{"wcag2aa", "wcag143"}, "WCAG 1.4.3 Contrast (Minimum)"},
+		{"x", []string{"wcag1412"}, "WCAG 1.4.12 Text Spacing"},
+		{"bypass", nil, "WCAG 2.4.1 Bypass Blocks"},
+		{"unknown", []string{"best-practice"}, ""},
+	}
+	for _, tt := range tests {
+		if got := standardFor(tt.rule, tt.tags); got != tt.want {
+			t.Errorf("standardFor(%q, %v) = %q, want %q", tt.rule, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("<div>", 10); got != "<div>" {
+		t.Errorf("truncate(short) = %q", got)
+	}
+	if got := truncate("<div class=\"x\">", 10); got != "<div cl..." {
+		t.Errorf("truncate = %q", got)
+	}
+	// "é" is two bytes; the cut at 7 falls inside it
+	got := truncate("<p>héééé</p>", 10)
+	if !utf8.ValidString(got) || len(got) > 10 || got != "<p>hé..." {
+		t.Errorf("truncate(multi-byte) = %q", got)
+	}
+}
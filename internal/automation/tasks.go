@@ -47,6 +47,9 @@ type TaskOptions struct {
 
 	// Temperature controls randomness (0.0-1.0, lower = more deterministic)
 	Temperature float64
+
+	// Offline forces rule-based processing for task types that support it
+	Offline bool
 }
 
 // Result represents the processed output.
@@ -68,6 +71,9 @@ type Result struct {
 
 	// Error contains any processing error
 	Error error
+
+	// Offline is true when the result came from the rule-based processor
+	Offline bool
 }
 
 // AuditProcessInput is input for audit processing tasks.
@@ -0,0 +1,299 @@
+// Package certs provides a local development certificate authority for agnt.
+//
+// The CA is generated once and stored in the agnt config directory. Leaf
+// certificates for localhost and LAN addresses are issued from it on demand,
+// so devices that trust the CA can reach agnt proxies over HTTPS.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CACertFile is the file name of the CA certificate in the CA directory.
+	CACertFile = "ca.pem"
+	// CAKeyFile is the file name of the CA private key in the CA directory.
+	CAKeyFile = "ca-key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 397 * 24 * time.Hour // Apple rejects leaf certs valid for longer
+)
+
+// DefaultDir returns the default CA directory ($XDG_CONFIG_HOME/agnt/ca).
+func DefaultDir() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configDir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configDir, "agnt", "ca")
+}
+
+// CA is a local certificate authority that issues leaf certificates.
+type CA struct {
+	dir  string
+	cert *x509.Certificate
+	key  crypto.Signer
+	pem  []byte
+
+	mu     sync.Mutex
+	leaves map[string]*tls.Certificate
+}
+
+// LoadOrCreate loads the CA from dir, generating a new one if none exists.
+// An empty dir uses DefaultDir.
+func LoadOrCreate(dir string) (*CA, error) {
+	if dir == "" {
+		dir = DefaultDir()
+		if dir == "" {
+			return nil, fmt.Errorf("cannot determine CA directory")
+		}
+	}
+
+	ca, err := load(dir)
+	if err == nil {
+		return ca, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return create(dir)
+}
+
+// load reads an existing CA certificate and key.
+func load(dir string) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CACertFile))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA in %s: %w", dir, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("CA key in %s cannot sign", dir)
+	}
+	if time.Now().After(cert.NotAfter) {
+		return nil, fmt.Errorf("CA in %s expired on %s", dir, cert.NotAfter.Format(time.DateOnly))
+	}
+
+	return newCA(dir, cert, key, certPEM), nil
+}
+
+// create generates a new CA and writes it to dir.
+func create(dir string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	name := "agnt development CA"
+	if hostname != "" {
+		name += " (" + hostname + ")"
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"agnt"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal CA key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create CA dir: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, CAKeyFile), keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("write CA key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, CACertFile), certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("write CA certificate: %w", err)
+	}
+
+	return newCA(dir, cert, key, certPEM), nil
+}
+
+func newCA(dir string, cert *x509.Certificate, key crypto.Signer, certPEM []byte) *CA {
+	return &CA{
+		dir:    dir,
+		cert:   cert,
+		key:    key,
+		pem:    certPEM,
+		leaves: make(map[string]*tls.Certificate),
+	}
+}
+
+// Dir returns the directory the CA is stored in.
+func (ca *CA) Dir() string {
+	return ca.dir
+}
+
+// CertPath returns the path of the CA certificate file.
+func (ca *CA) CertPath() string {
+	return filepath.Join(ca.dir, CACertFile)
+}
+
+// CertPEM returns the PEM-encoded CA certificate.
+func (ca *CA) CertPEM() []byte {
+	return ca.pem
+}
+
+// Certificate returns the parsed CA certificate.
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// Leaf returns a certificate valid for the given host names and IP addresses.
+// Certificates are cached per host set and reissued shortly before expiry.
+func (ca *CA) Leaf(hosts ...string) (*tls.Certificate, error) {
+	hosts = normalizeHosts(hosts)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("at least one host is required")
+	}
+	cacheKey := strings.Join(hosts, ",")
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if leaf, ok := ca.leaves[cacheKey]; ok && time.Until(leaf.Leaf.NotAfter) > 24*time.Hour {
+		return leaf, nil
+	}
+
+	leaf, err := ca.issue(hosts)
+	if err != nil {
+		return nil, err
+	}
+	ca.leaves[cacheKey] = leaf
+	return leaf, nil
+}
+
+// issue creates a new leaf certificate signed by the CA.
+func (ca *CA) issue(hosts []string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate leaf key: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: hosts[0], Organization: []string{"agnt"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("create leaf certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse leaf certificate: %w", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// TLSConfig returns a server TLS configuration using a leaf for hosts.
+func (ca *CA) TLSConfig(hosts ...string) (*tls.Config, error) {
+	leaf, err := ca.Leaf(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{*leaf},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// normalizeHosts lowercases, strips ports and brackets, and deduplicates hosts.
+func normalizeHosts(hosts []string) []string {
+	seen := make(map[string]bool, len(hosts))
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if host, _, err := net.SplitHostPort(h); err == nil {
+			h = host
+		}
+		h = strings.Trim(h, "[]")
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		out = append(out, h)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func randomSerial() *big.Int {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
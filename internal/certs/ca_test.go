@@ -0,0 +1,119 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOrCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+
+	ca, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreate failed: %v", err)
+	}
+	if !ca.Certificate().IsCA {
+		t.Error("expected CA certificate")
+	}
+	if ca.CertPath() != filepath.Join(dir, CACertFile) {
+		t.Errorf("unexpected cert path %s", ca.CertPath())
+	}
+
+	info, err := os.Stat(filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		t.Fatalf("CA key not written: %v", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		t.Errorf("CA key should be private, got %v", info.Mode().Perm())
+	}
+
+	// Loading again reuses the stored CA
+	again, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !again.Certificate().Equal(ca.Certificate()) {
+		t.Error("expected the same CA after reload")
+	}
+}
+
+func TestLoadOrCreate_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, CACertFile), []byte("not a cert"), 0644)
+	os.WriteFile(filepath.Join(dir, CAKeyFile), []byte("not a key"), 0600)
+
+	if _, err := LoadOrCreate(dir); err == nil {
+		t.Error("expected error for corrupt CA")
+	}
+}
+
+func TestLeaf(t *testing.T) {
+	ca, err := LoadOrCreate(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadOrCreate failed: %v", err)
+	}
+
+	leaf, err := ca.Leaf("localhost", "192.168.1.20", "LOCALHOST:8443")
+	if err != nil {
+		t.Fatalf("Leaf failed: %v", err)
+	}
+	if len(leaf.Leaf.DNSNames) != 1 || leaf.Leaf.DNSNames[0] != "localhost" {
+		t.Errorf("unexpected DNS names: %v", leaf.Leaf.DNSNames)
+	}
+	if len(leaf.Leaf.IPAddresses) != 1 || !leaf.Leaf.IPAddresses[0].Equal(net.ParseIP("192.168.1.20")) {
+		t.Errorf("unexpected IPs: %v", leaf.Leaf.IPAddresses)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+	if _, err := leaf.Leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "192.168.1.20"}); err != nil {
+		t.Errorf("leaf does not verify against CA: %v", err)
+	}
+
+	cached, _ := ca.Leaf("192.168.1.20", "localhost")
+	if cached != leaf {
+		t.Error("expected cached leaf for the same host set")
+	}
+
+	if _, err := ca.Leaf(); err == nil {
+		t.Error("expected error without hosts")
+	}
+}
+
+func TestTLSConfig_Handshake(t *testing.T) {
+	ca, err := LoadOrCreate(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadOrCreate failed: %v", err)
+	}
+	cfg, err := ca.TLSConfig("127.0.0.1")
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.CertPEM())
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,41 @@
+package certs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InstallInstructions returns how to trust the CA certificate at certPath on
+// the given operating system (a runtime.GOOS value), plus how to install it
+// on phones and tablets.
+func InstallInstructions(certPath, goos string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "agnt development CA: %s\n\n", certPath)
+
+	switch goos {
+	case "darwin":
+		b.WriteString("Trust it in the macOS keychain (Safari, Chrome, Edge):\n")
+		fmt.Fprintf(&b, "  sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %q\n", certPath)
+	case "windows":
+		b.WriteString("Trust it for the current user (Edge, Chrome):\n")
+		fmt.Fprintf(&b, "  certutil -addstore -user Root %q\n", certPath)
+	default:
+		b.WriteString("Trust it system-wide (curl, Go, Node with --use-system-ca):\n")
+		b.WriteString("  Debian/Ubuntu:\n")
+		fmt.Fprintf(&b, "    sudo cp %q /usr/local/share/ca-certificates/agnt-ca.crt && sudo update-ca-certificates\n", certPath)
+		b.WriteString("  Fedora/RHEL/Arch:\n")
+		fmt.Fprintf(&b, "    sudo trust anchor --store %q\n", certPath)
+		b.WriteString("Chrome and Chromium use their own store:\n")
+		fmt.Fprintf(&b, "  certutil -d sql:$HOME/.pki/nssdb -A -t C,, -n \"agnt development CA\" -i %q\n", certPath)
+	}
+
+	b.WriteString("\nFirefox: Settings → Privacy & Security → Certificates → View Certificates → Authorities → Import.\n")
+	b.WriteString("Node.js: export NODE_EXTRA_CA_CERTS=" + certPath + "\n")
+	b.WriteString("\nPhones and tablets: share the proxy with HTTPS and open the CA link (/__devtool_ca.pem).\n")
+	b.WriteString("  iOS: install the profile in Settings → General → VPN & Device Management, then enable it in\n")
+	b.WriteString("       Settings → General → About → Certificate Trust Settings.\n")
+	b.WriteString("  Android: Settings → Security → Encryption & credentials → Install a certificate → CA certificate.\n")
+
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package certs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInstallInstructions(t *testing.T) {
+	tests := []struct {
+		goos string
+		want string
+	}{
+		{"darwin", "security add-trusted-cert"},
+		{"windows", "certutil -addstore -user Root"},
+		{"linux", "update-ca-certificates"},
+	}
+
+	for _, tt := range tests {
+		got := InstallInstructions("/home/dev/.config/agnt/ca/ca.pem", tt.goos)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: expected %q in instructions:\n%s", tt.goos, tt.want, got)
+		}
+		if !strings.Contains(got, `"/home/dev/.config/agnt/ca/ca.pem"`) {
+			t.Errorf("%s: expected quoted CA path in instructions", tt.goos)
+		}
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/standardbeagle/agnt/internal/project"
+
 	kdl "github.com/sblinch/kdl-go"
 )
 
@@ -30,6 +32,15 @@ type AgntConfig struct {
 
 	// Toast notification settings
 	Toast *ToastConfig `kdl:"toast"`
+
+	// Language overrides the detected project language
+	Language string `kdl:"language"`
+
+	// PackageManager overrides the detected package manager (Node.js)
+	PackageManager string `kdl:"package-manager"`
+
+	// Commands add to or override the commands of the project's language
+	Commands map[string]*KDLCommand `kdl:"commands"`
 }
 
 // ScriptConfig defines a script to run.
@@ -65,6 +76,156 @@ type ProxyConfig struct {
 	// Legacy fields (deprecated)
 	// Target is the explicit target URL (use URL instead)
 	Target string `kdl:"target"`
+
+	// HTTPS also serves the proxy over HTTPS, with a certificate from the
+	// local agnt CA (see "agnt ca" for installing it)
+	HTTPS bool `kdl:"https"`
+	// HTTPSPort is the HTTPS listen port (default: stable port per target)
+	HTTPSPort int `kdl:"https-port"`
+
+	// Routes send matching paths to other upstreams, checked in order.
+	// Unmatched requests go to the proxy target.
+	Routes []*RouteConfig `kdl:"route,multiple"`
+
+	// BindAddress is the listen address: "127.0.0.1" (default) or "0.0.0.0"
+	// to accept connections from other devices
+	BindAddress string `kdl:"bind-address"`
+	// PublicURL is the external URL of the proxy (e.g., a fixed tunnel domain)
+	PublicURL string `kdl:"public-url"`
+	// VerifyTLS verifies upstream certificates (default: accept self-signed)
+	VerifyTLS bool `kdl:"verify-tls"`
+
+	// Tunnel exposes the proxy through a tunnel provider
+	Tunnel *TunnelConfig `kdl:"tunnel"`
+	// Chaos rules are active as soon as the proxy starts
+	Chaos *ChaosConfig `kdl:"chaos"`
+	// Inject controls the instrumentation injected into HTML pages
+	Inject *InjectConfig `kdl:"inject"`
+}
+
+// RouteConfig sends requests under a path to a different upstream:
+//
+//	route "/api" target="http://localhost:8080" strip-prefix=true
+//	route name="assets" pattern="^/static/" target="http://localhost:9000"
+type RouteConfig struct {
+	// Path is the path prefix to match (e.g., "/api")
+	Path string `kdl:",arg"`
+	// Name labels the route in logs and chaos rules
+	Name string `kdl:"name"`
+	// Pattern is a regular expression matched against the path (instead of Path)
+	Pattern string `kdl:"pattern"`
+	// Target is the upstream URL
+	Target string `kdl:"target"`
+	// StripPrefix removes the matched prefix before forwarding
+	StripPrefix bool `kdl:"strip-prefix"`
+	// HostHeader overrides the Host header sent upstream
+	HostHeader string `kdl:"host-header"`
+}
+
+// TunnelConfig starts a tunnel alongside a proxy:
+//
+//	tunnel {
+//	    provider "cloudflared"
+//	}
+type TunnelConfig struct {
+	// Provider is "cloudflared", "ngrok", "tailscale", "ssh" or "custom"
+	Provider string `kdl:"provider"`
+	// Command is the full command for the "custom" provider
+	Command string `kdl:"command"`
+	// Args are additional arguments for the tunnel command
+	Args []string `kdl:"args"`
+	// AuthToken is the ngrok authentication token
+	AuthToken string `kdl:"auth-token"`
+	// Region is the tunnel region
+	Region string `kdl:"region"`
+	// SSHHost is the host for the ssh provider (default: localhost.run)
+	SSHHost string `kdl:"ssh-host"`
+}
+
+// ChaosConfig sets up failure injection when a proxy starts. Rules are added
+// to the preset's rules:
+//
+//	chaos {
+//	    preset "mobile-3g"
+//	    rule "http_error" url-pattern="/api/checkout" probability=0.2 error-codes="502,503"
+//	}
+type ChaosConfig struct {
+	// Preset is a built-in rule set (e.g., "mobile-3g", "flaky-api")
+	Preset string `kdl:"preset"`
+	// Enabled turns the rules on at start (default: true)
+	Enabled *bool `kdl:"enabled"`
+	// Seed makes random failures reproducible
+	Seed int64 `kdl:"seed"`
+	// GlobalOdds is the chance (0.0-1.0) that chaos applies to a request at all
+	GlobalOdds float64 `kdl:"global-odds"`
+	// Rules are individual chaos rules
+	Rules []*ChaosRuleConfig `kdl:"rule,multiple"`
+}
+
+// ChaosRuleConfig is a single chaos rule. The argument is the chaos type
+// (e.g., "latency", "http_error", "truncate").
+type ChaosRuleConfig struct {
+	Type string `kdl:",arg"`
+	Name string `kdl:"name"`
+	// Matching: URL regex, comma-separated methods, route name or upstream,
+	// GraphQL operation regex and chance per request (default 1.0)
+	URLPattern  string  `kdl:"url-pattern"`
+	Methods     string  `kdl:"methods"`
+	Upstream    string  `kdl:"upstream"`
+	Operation   string  `kdl:"operation"`
+	Probability float64 `kdl:"probability"`
+	// Latency
+	MinLatencyMs int `kdl:"min-latency-ms"`
+	MaxLatencyMs int `kdl:"max-latency-ms"`
+	JitterMs     int `kdl:"jitter-ms"`
+	// Errors: comma-separated status codes and message
+	ErrorCodes   string `kdl:"error-codes"`
+	ErrorMessage string `kdl:"error-message"`
+	// Slow drip, connection drops and truncation
+	BytesPerMs       int     `kdl:"bytes-per-ms"`
+	DropAfterPercent float64 `kdl:"drop-after-percent"`
+	TruncatePercent  float64 `kdl:"truncate-percent"`
+}
+
+// InjectConfig controls the instrumentation injected into HTML pages. Every
+// feature is on unless set to false:
+//
+//	inject {
+//	    indicator false
+//	}
+type InjectConfig struct {
+	// Enabled false injects nothing; traffic is still logged
+	Enabled *bool `kdl:"enabled"`
+	// Indicator is the floating bug indicator
+	Indicator *bool `kdl:"indicator"`
+	// Console captures console.warn and console.error
+	Console *bool `kdl:"console"`
+	// Interactions tracks clicks, keys and form input
+	Interactions *bool `kdl:"interactions"`
+	// Mutations tracks DOM mutations
+	Mutations *bool `kdl:"mutations"`
+	// Vitals measures Web Vitals and long tasks
+	Vitals *bool `kdl:"vitals"`
+}
+
+// Off returns the names of the features switched off.
+func (c *InjectConfig) Off() []string {
+	var off []string
+	for _, f := range []struct {
+		name string
+		on   *bool
+	}{
+		{"indicator", c.Indicator},
+		{"console", c.Console},
+		{"interactions", c.Interactions},
+		{"mutations", c.Mutations},
+		{"vitals", c.Vitals},
+	} {
+		if f.on != nil && !*f.on {
+			off = append(off, f.name)
+		}
+	}
+	return off
 }
 
 // HooksConfig defines hook behavior.
@@ -169,7 +330,7 @@ func ParseAgntConfig(data string) (*AgntConfig, error) {
 	// Try kdl-go first
 	if err := kdl.Unmarshal([]byte(data), cfg); err == nil {
 		// Check if we got anything useful
-		if len(cfg.Scripts) > 0 || len(cfg.Proxies) > 0 {
+		if len(cfg.Scripts) > 0 || len(cfg.Proxies) > 0 || cfg.Language != "" || len(cfg.Commands) > 0 {
 			log.Printf("[DEBUG] ParseAgntConfig: kdl-go parsed %d scripts, %d proxies", len(cfg.Scripts), len(cfg.Proxies))
 			return cfg, nil
 		}
@@ -197,6 +358,7 @@ func parseAgntConfigSimple(data string) (*AgntConfig, error) {
 	var currentBlock string
 	var currentProxy *ProxyConfig
 	var currentProxyName string
+	var currentSubBlock string // tunnel, chaos or inject inside a proxy block
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -208,6 +370,10 @@ func parseAgntConfigSimple(data string) (*AgntConfig, error) {
 
 		// Block start
 		if strings.HasSuffix(line, "{") {
+			if currentBlock == "proxy" && currentProxy != nil {
+				currentSubBlock = strings.Fields(line)[0]
+				continue
+			}
 			if strings.HasPrefix(line, "scripts") {
 				currentBlock = "scripts"
 			} else if strings.HasPrefix(line, "proxy") {
@@ -229,6 +395,10 @@ func parseAgntConfigSimple(data string) (*AgntConfig, error) {
 
 		// Block end
 		if line == "}" {
+			if currentSubBlock != "" {
+				currentSubBlock = ""
+				continue
+			}
 			if currentBlock == "proxy" && currentProxy != nil && currentProxyName != "" {
 				cfg.Proxies[currentProxyName] = currentProxy
 				currentProxy = nil
@@ -244,7 +414,9 @@ func parseAgntConfigSimple(data string) (*AgntConfig, error) {
 			parseScriptLine(line, cfg)
 
 		case "proxy":
-			if currentProxy != nil {
+			if currentProxy != nil && currentSubBlock != "" {
+				parseProxyBlockProperty(currentSubBlock, line, currentProxy)
+			} else if currentProxy != nil {
 				parseProxyProperty(line, currentProxy)
 			}
 
@@ -300,6 +472,11 @@ func parseScriptLine(line string, cfg *AgntConfig) {
 
 // parseProxyProperty parses a property line inside a proxy block.
 func parseProxyProperty(line string, proxy *ProxyConfig) {
+	if strings.HasPrefix(line, "route ") {
+		proxy.Routes = append(proxy.Routes, parseRouteLine(line))
+		return
+	}
+
 	// Match: property "value" or property value
 	stringRe := regexp.MustCompile(`^(\S+)\s+"([^"]+)"`)
 	intRe := regexp.MustCompile(`^(\S+)\s+(\d+)`)
@@ -314,6 +491,10 @@ func parseProxyProperty(line string, proxy *ProxyConfig) {
 			proxy.URL = matches[2]
 		case "host":
 			proxy.Host = matches[2]
+		case "bind-address":
+			proxy.BindAddress = matches[2]
+		case "public-url":
+			proxy.PublicURL = matches[2]
 		}
 		return
 	}
@@ -326,16 +507,182 @@ func parseProxyProperty(line string, proxy *ProxyConfig) {
 			proxy.Port = val
 		case "max-log-size":
 			proxy.MaxLogSize = val
+		case "https-port":
+			proxy.HTTPSPort = val
 		}
 		return
 	}
 
+	if strings.HasPrefix(line, "https ") {
+		proxy.HTTPS = strings.Contains(line, "true")
+		return
+	}
+
+	if strings.HasPrefix(line, "verify-tls ") {
+		proxy.VerifyTLS = strings.Contains(line, "true")
+		return
+	}
+
 	// Boolean properties (handle both "autostart" and "auto-start")
 	if strings.Contains(line, "autostart") || strings.Contains(line, "auto-start") {
 		proxy.Autostart = strings.Contains(line, "true")
 	}
 }
 
+// parseRouteLine parses a single-line route like:
+// route "/api" target="http://localhost:8080" strip-prefix=true
+func parseRouteLine(line string) *RouteConfig {
+	route := &RouteConfig{}
+	if matches := regexp.MustCompile(`^route\s+"([^"]+)"`).FindStringSubmatch(line); len(matches) > 1 {
+		route.Path = matches[1]
+	}
+
+	propRe := regexp.MustCompile(`([\w-]+)=(?:"([^"]*)"|(\S+))`)
+	for _, m := range propRe.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if value == "" {
+			value = m[3]
+		}
+		switch m[1] {
+		case "name":
+			route.Name = value
+		case "pattern":
+			route.Pattern = value
+		case "target":
+			route.Target = value
+		case "strip-prefix":
+			route.StripPrefix = value == "true"
+		case "host-header":
+			route.HostHeader = value
+		}
+	}
+	return route
+}
+
+// parseProxyBlockProperty parses a property line inside a tunnel, chaos or
+// inject block of a proxy.
+func parseProxyBlockProperty(block, line string, proxy *ProxyConfig) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return
+	}
+	name := fields[0]
+	value := strings.Trim(fields[1], "\"")
+
+	switch block {
+	case "tunnel":
+		if proxy.Tunnel == nil {
+			proxy.Tunnel = &TunnelConfig{}
+		}
+		t := proxy.Tunnel
+		switch name {
+		case "provider":
+			t.Provider = value
+		case "command":
+			t.Command = value
+		case "args":
+			for _, m := range regexp.MustCompile(`"([^"]*)"`).FindAllStringSubmatch(line, -1) {
+				t.Args = append(t.Args, m[1])
+			}
+		case "auth-token":
+			t.AuthToken = value
+		case "region":
+			t.Region = value
+		case "ssh-host":
+			t.SSHHost = value
+		}
+
+	case "chaos":
+		if proxy.Chaos == nil {
+			proxy.Chaos = &ChaosConfig{}
+		}
+		c := proxy.Chaos
+		switch name {
+		case "preset":
+			c.Preset = value
+		case "enabled":
+			enabled := value == "true"
+			c.Enabled = &enabled
+		case "seed":
+			c.Seed, _ = strconv.ParseInt(value, 10, 64)
+		case "global-odds":
+			c.GlobalOdds, _ = strconv.ParseFloat(value, 64)
+		case "rule":
+			c.Rules = append(c.Rules, parseChaosRuleLine(line))
+		}
+
+	case "inject":
+		if proxy.Inject == nil {
+			proxy.Inject = &InjectConfig{}
+		}
+		on := value == "true"
+		switch name {
+		case "enabled":
+			proxy.Inject.Enabled = &on
+		case "indicator":
+			proxy.Inject.Indicator = &on
+		case "console":
+			proxy.Inject.Console = &on
+		case "interactions":
+			proxy.Inject.Interactions = &on
+		case "mutations":
+			proxy.Inject.Mutations = &on
+		case "vitals":
+			proxy.Inject.Vitals = &on
+		}
+	}
+}
+
+// parseChaosRuleLine parses a single-line chaos rule like:
+// rule "latency" url-pattern="/api" min-latency-ms=200 max-latency-ms=800
+func parseChaosRuleLine(line string) *ChaosRuleConfig {
+	rule := &ChaosRuleConfig{}
+	if matches := regexp.MustCompile(`^rule\s+"([^"]+)"`).FindStringSubmatch(line); len(matches) > 1 {
+		rule.Type = matches[1]
+	}
+
+	propRe := regexp.MustCompile(`([\w-]+)=(?:"([^"]*)"|(\S+))`)
+	for _, m := range propRe.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if value == "" {
+			value = m[3]
+		}
+		intValue, _ := strconv.Atoi(value)
+		floatValue, _ := strconv.ParseFloat(value, 64)
+		switch m[1] {
+		case "name":
+			rule.Name = value
+		case "url-pattern":
+			rule.URLPattern = value
+		case "methods":
+			rule.Methods = value
+		case "upstream":
+			rule.Upstream = value
+		case "operation":
+			rule.Operation = value
+		case "probability":
+			rule.Probability = floatValue
+		case "min-latency-ms":
+			rule.MinLatencyMs = intValue
+		case "max-latency-ms":
+			rule.MaxLatencyMs = intValue
+		case "jitter-ms":
+			rule.JitterMs = intValue
+		case "error-codes":
+			rule.ErrorCodes = value
+		case "error-message":
+			rule.ErrorMessage = value
+		case "bytes-per-ms":
+			rule.BytesPerMs = intValue
+		case "drop-after-percent":
+			rule.DropAfterPercent = floatValue
+		case "truncate-percent":
+			rule.TruncatePercent = floatValue
+		}
+	}
+	return rule
+}
+
 // GetAutostartScripts returns scripts configured for autostart.
 func (c *AgntConfig) GetAutostartScripts() map[string]*ScriptConfig {
 	result := make(map[string]*ScriptConfig)
@@ -358,6 +705,22 @@ func (c *AgntConfig) GetAutostartProxies() map[string]*ProxyConfig {
 	return result
 }
 
+// ProjectConfig returns the language, package manager and commands set in
+// the config.
+func (c *AgntConfig) ProjectConfig() *ProjectConfig {
+	cfg := &ProjectConfig{
+		Language:       c.Language,
+		PackageManager: c.PackageManager,
+		Commands:       make(map[string]CommandConfig),
+	}
+	for name, kdlCmd := range c.Commands {
+		if kdlCmd != nil {
+			cfg.Commands[name] = kdlCmd.toCommandConfig(project.SourceProject)
+		}
+	}
+	return cfg
+}
+
 // WriteDefaultAgntConfig writes a default configuration file with documentation.
 func WriteDefaultAgntConfig(path string) error {
 	defaultKDL := `// Agnt Configuration
@@ -403,8 +766,46 @@ proxies {
     //     autostart true
     //     max-log-size 2000
     // }
+
+    // Example: HTTPS for secure cookies, service workers and OAuth redirects
+    // secure {
+    //     target "http://localhost:3000"
+    //     https true
+    // }
+
+    // Example: one origin for a frontend and its backends
+    // app {
+    //     target "http://localhost:3000"
+    //     route "/api" target="http://localhost:8080" strip-prefix=true
+    //     route "/auth" target="http://localhost:9000" host-header="auth.local"
+    // }
+
+    // Example: proxy for a script's URL, shared through a tunnel with
+    // failures injected from the start
+    // mobile {
+    //     script "dev"
+    //     tunnel {
+    //         provider "cloudflared"
+    //     }
+    //     chaos {
+    //         preset "mobile-3g"
+    //         rule "http_error" url-pattern="^/api/" probability=0.1 error-codes="503"
+    //     }
+    //     inject {
+    //         indicator false
+    //     }
+    // }
 }
 
+// Project commands (override the detected language's, shown by detect)
+// language "go"
+// commands {
+//     test {
+//         cmd "gotestsum"
+//         args "--" "./..."
+//     }
+// }
+
 // Hook configuration for notifications
 hooks {
     // What to do when Claude responds
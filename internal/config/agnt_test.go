@@ -231,3 +231,159 @@ func TestFindAgntConfigFile(t *testing.T) {
 	found = FindAgntConfigFile("/nonexistent/path")
 	assert.Equal(t, "", found)
 }
+
+func TestParseAgntConfigWithRoutes(t *testing.T) {
+	input := `proxies {
+    app {
+        target "http://localhost:3000"
+        route "/api" target="http://localhost:8080" strip-prefix=true
+        route name="assets" pattern="^/static/" target="http://localhost:9000" host-header="cdn.local"
+    }
+}`
+
+	cfg, err := ParseAgntConfig(input)
+	require.NoError(t, err)
+
+	app, ok := cfg.Proxies["app"]
+	require.True(t, ok, "should have 'app' proxy")
+	require.Len(t, app.Routes, 2)
+
+	assert.Equal(t, "/api", app.Routes[0].Path)
+	assert.Equal(t, "http://localhost:8080", app.Routes[0].Target)
+	assert.True(t, app.Routes[0].StripPrefix)
+
+	assert.Equal(t, "assets", app.Routes[1].Name)
+	assert.Equal(t, "^/static/", app.Routes[1].Pattern)
+	assert.Equal(t, "cdn.local", app.Routes[1].HostHeader)
+	assert.False(t, app.Routes[1].StripPrefix)
+}
+
+func TestParseRouteLine(t *testing.T) {
+	route := parseRouteLine(`route "/api" target="http://localhost:8080" strip-prefix=true host-header="api.local"`)
+	assert.Equal(t, &RouteConfig{
+		Path:        "/api",
+		Target:      "http://localhost:8080",
+		StripPrefix: true,
+		HostHeader:  "api.local",
+	}, route)
+
+	// Simple-format proxy blocks accept single-line routes
+	cfg, err := parseAgntConfigSimple("proxy \"dev\" {\n    target \"http://localhost:3000\"\n    route \"/api\" target=\"http://localhost:8080\"\n}\n")
+	require.NoError(t, err)
+	require.Len(t, cfg.Proxies["dev"].Routes, 1)
+	assert.Equal(t, "http://localhost:3000", cfg.Proxies["dev"].Target)
+	assert.Equal(t, "/api", cfg.Proxies["dev"].Routes[0].Path)
+}
+
+func TestParseAgntConfigWithHTTPS(t *testing.T) {
+	input := `proxies {
+    secure {
+        target "http://localhost:3000"
+        https true
+        https-port 8443
+    }
+}`
+
+	cfg, err := ParseAgntConfig(input)
+	require.NoError(t, err)
+
+	secure, ok := cfg.Proxies["secure"]
+	require.True(t, ok, "should have 'secure' proxy")
+	assert.True(t, secure.HTTPS)
+	assert.Equal(t, 8443, secure.HTTPSPort)
+
+	// Simple-format proxy blocks accept the same properties
+	simple, err := parseAgntConfigSimple("proxy \"dev\" {\n    port 3000\n    https true\n    https-port 8443\n}\n")
+	require.NoError(t, err)
+	assert.True(t, simple.Proxies["dev"].HTTPS)
+	assert.Equal(t, 8443, simple.Proxies["dev"].HTTPSPort)
+}
+
+func TestParseAgntConfigWithProxyOptions(t *testing.T) {
+	input := `proxies {
+    app {
+        script "dev"
+        bind-address "0.0.0.0"
+        public-url "https://dev.example.com"
+        verify-tls true
+        tunnel {
+            provider "cloudflared"
+            args "--no-autoupdate"
+        }
+        chaos {
+            preset "mobile-3g"
+            seed 42
+            rule "latency" url-pattern="^/api/" min-latency-ms=200 max-latency-ms=800
+            rule "http_error" methods="POST" probability=0.2 error-codes="502,503"
+        }
+        inject {
+            indicator false
+            vitals true
+        }
+    }
+}`
+
+	cfg, err := ParseAgntConfig(input)
+	require.NoError(t, err)
+
+	app, ok := cfg.Proxies["app"]
+	require.True(t, ok, "should have 'app' proxy")
+	assert.Equal(t, "0.0.0.0", app.BindAddress)
+	assert.Equal(t, "https://dev.example.com", app.PublicURL)
+	assert.True(t, app.VerifyTLS)
+
+	require.NotNil(t, app.Tunnel)
+	assert.Equal(t, "cloudflared", app.Tunnel.Provider)
+	assert.Equal(t, []string{"--no-autoupdate"}, app.Tunnel.Args)
+
+	require.NotNil(t, app.Chaos)
+	assert.Equal(t, "mobile-3g", app.Chaos.Preset)
+	assert.Equal(t, int64(42), app.Chaos.Seed)
+	assert.Nil(t, app.Chaos.Enabled)
+	require.Len(t, app.Chaos.Rules, 2)
+	assert.Equal(t, "latency", app.Chaos.Rules[0].Type)
+	assert.Equal(t, "^/api/", app.Chaos.Rules[0].URLPattern)
+	assert.Equal(t, 800, app.Chaos.Rules[0].MaxLatencyMs)
+	assert.Equal(t, "http_error", app.Chaos.Rules[1].Type)
+	assert.Equal(t, 0.2, app.Chaos.Rules[1].Probability)
+	assert.Equal(t, "502,503", app.Chaos.Rules[1].ErrorCodes)
+
+	require.NotNil(t, app.Inject)
+	assert.Nil(t, app.Inject.Enabled)
+	assert.Equal(t, []string{"indicator"}, app.Inject.Off())
+
+	// Simple-format proxy blocks accept the same options
+	simple, err := parseAgntConfigSimple(`proxy "dev" {
+    port 3000
+    bind-address "0.0.0.0"
+    verify-tls true
+    tunnel {
+        provider "ngrok"
+    }
+    chaos {
+        preset "flaky-api"
+        rule "latency" min-latency-ms=100
+    }
+    inject {
+        enabled false
+    }
+    https true
+}
+`)
+	require.NoError(t, err)
+	dev := simple.Proxies["dev"]
+	require.NotNil(t, dev)
+	assert.Equal(t, 3000, dev.Port)
+	assert.Equal(t, "0.0.0.0", dev.BindAddress)
+	assert.True(t, dev.VerifyTLS)
+	assert.True(t, dev.HTTPS, "properties after nested blocks belong to the proxy")
+	require.NotNil(t, dev.Tunnel)
+	assert.Equal(t, "ngrok", dev.Tunnel.Provider)
+	require.NotNil(t, dev.Chaos)
+	assert.Equal(t, "flaky-api", dev.Chaos.Preset)
+	require.Len(t, dev.Chaos.Rules, 1)
+	assert.Equal(t, 100, dev.Chaos.Rules[0].MinLatencyMs)
+	require.NotNil(t, dev.Inject)
+	require.NotNil(t, dev.Inject.Enabled)
+	assert.False(t, *dev.Inject.Enabled)
+}
@@ -0,0 +1,1018 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	kdl "github.com/sblinch/kdl-go"
+	"github.com/sblinch/kdl-go/document"
+)
+
+// Issue severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found in an .agnt.kdl file. Line and Column are
+// 1-based; they are 0 when the position is unknown.
+type Issue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// String formats the issue like a compiler diagnostic:
+// ".agnt.kdl:12:5: error: unknown key "prot" in proxy "app"".
+func (i Issue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc += ":" + strconv.Itoa(i.Line)
+		if i.Column > 0 {
+			loc += ":" + strconv.Itoa(i.Column)
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, i.Severity, i.Message)
+}
+
+// HasErrors reports whether any of the issues is an error.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOptions provides the project context references are checked against.
+type CheckOptions struct {
+	// Scripts are the project's own scripts (e.g., package.json scripts)
+	// that a proxy may link to besides the scripts in the config
+	Scripts []string
+	// ChaosPresets are the known chaos presets; nil skips the check
+	ChaosPresets []string
+	// Languages are the languages of the global config; nil skips the check
+	Languages []string
+}
+
+// CheckAgntConfigFile checks an .agnt.kdl file. See CheckAgntConfig.
+func CheckAgntConfigFile(path string, opts CheckOptions) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return CheckAgntConfig(path, data, opts), nil
+}
+
+// CheckAgntConfig checks .agnt.kdl data for syntax errors, unknown keys,
+// values of the wrong type and invalid references, such as a proxy linked
+// to a script that doesn't exist. Unlike ParseAgntConfig, which falls back
+// to a lenient parser, it reports everything that would be ignored. Issues
+// are sorted by position; file is only used to label them.
+func CheckAgntConfig(file string, data []byte, opts CheckOptions) []Issue {
+	c := &configChecker{file: file, src: data}
+
+	doc, err := kdl.Parse(bytes.NewReader(data))
+	if err != nil {
+		c.syntaxError(err)
+		return c.issues
+	}
+
+	c.locate(doc.Nodes)
+
+	// Check the nodes, keeping what is valid for decoding; proxy "name"
+	// nodes become proxies entries
+	valid := document.New()
+	var proxies *document.Node
+	var entries []*document.Node
+	for _, n := range doc.Nodes {
+		if !c.checkTopLevel(n) {
+			continue
+		}
+		switch n.Name.ValueString() {
+		case "proxy":
+			n.SetName(n.Arguments[0].ValueString())
+			n.Arguments = nil
+			entries = append(entries, n)
+			continue
+		case "proxies":
+			proxies = n
+		}
+		valid.AddNode(n)
+	}
+	if len(entries) > 0 {
+		if proxies == nil {
+			proxies = document.NewNode()
+			proxies.SetName("proxies")
+			valid.AddNode(proxies)
+		}
+		proxies.Children = append(proxies.Children, entries...)
+	}
+
+	if cfg, err := decodeAgntConfig(valid); err == nil {
+		c.checkReferences(cfg, opts)
+	}
+	return c.sorted()
+}
+
+// decodeAgntConfig decodes a parsed document, which kdl-go can only do from
+// source.
+func decodeAgntConfig(doc *document.Document) (*AgntConfig, error) {
+	var buf bytes.Buffer
+	if err := kdl.Generate(doc, &buf); err != nil {
+		return nil, err
+	}
+	cfg := DefaultAgntConfig()
+	if err := kdl.Unmarshal(buf.Bytes(), cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configChecker collects the issues of one file.
+type configChecker struct {
+	file   string
+	src    []byte
+	issues []Issue
+
+	// Source offset of each node; missing when the positions of the
+	// scanned source didn't line up with the parsed document
+	offsets map[*document.Node]int
+	// Nodes of the top-level keys, scripts ("script dev") and proxies
+	// ("proxy app"), for reporting reference problems
+	nodes map[string]*document.Node
+}
+
+func (c *configChecker) sorted() []Issue {
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		a, b := c.issues[i], c.issues[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.issues
+}
+
+func (c *configChecker) add(severity string, offset int, format string, args ...interface{}) {
+	issue := Issue{File: c.file, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if offset >= 0 {
+		issue.Line, issue.Column = c.position(offset)
+	}
+	c.issues = append(c.issues, issue)
+}
+
+func (c *configChecker) errorAt(n *document.Node, format string, args ...interface{}) {
+	c.add(SeverityError, c.nodeOffset(n), format, args...)
+}
+
+func (c *configChecker) warnAt(n *document.Node, format string, args ...interface{}) {
+	c.add(SeverityWarning, c.nodeOffset(n), format, args...)
+}
+
+// syntaxErrorRE matches the position kdl-go adds to parse errors.
+var syntaxErrorRE = regexp.MustCompile(`^(?:parse|scan) failed: (.*?) at line (\d+), column (\d+)`)
+
+func (c *configChecker) syntaxError(err error) {
+	issue := Issue{File: c.file, Severity: SeverityError, Message: err.Error()}
+	if m := syntaxErrorRE.FindStringSubmatch(err.Error()); m != nil {
+		issue.Message = "syntax error: " + m[1]
+		// kdl-go counts lines from 0, and has no position at the end of input
+		line, _ := strconv.Atoi(m[2])
+		issue.Line = line + 1
+		issue.Column, _ = strconv.Atoi(m[3])
+		if strings.Contains(m[1], "unexpected EOF") {
+			issue.Line, issue.Column = bytes.Count(bytes.TrimRight(c.src, "\n"), []byte("\n"))+1, 0
+		}
+	} else if i := strings.IndexByte(issue.Message, '\n'); i >= 0 {
+		issue.Message = issue.Message[:i]
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// Schema
+
+// kdlField is a field of a config struct, by its KDL name.
+type kdlField struct {
+	typ      reflect.Type
+	multiple bool
+}
+
+// configAliases are keys the lenient parser accepts besides the kdl tags.
+var configAliases = map[reflect.Type]map[string]string{
+	reflect.TypeOf(ScriptConfig{}): {"auto-start": "autostart"},
+	reflect.TypeOf(ProxyConfig{}):  {"auto-start": "autostart", "target-url": "target", "fallback-port": "port"},
+}
+
+// kdlFields returns the fields of a struct type by KDL name, and the type of
+// its argument field, if any.
+func kdlFields(t reflect.Type) (map[string]kdlField, reflect.Type) {
+	fields := make(map[string]kdlField)
+	var arg reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("kdl")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			if opts == "arg" {
+				arg = f.Type
+			}
+			continue
+		}
+		fields[name] = kdlField{typ: f.Type, multiple: opts == "multiple"}
+	}
+	for alias, name := range configAliases[t] {
+		fields[alias] = fields[name]
+	}
+	return fields, arg
+}
+
+func fieldNames(fields map[string]kdlField) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// checkTopLevel checks a top-level node. It returns false when the node is
+// invalid; nodes that are kept have their invalid parts removed and aliases
+// renamed, so the document can be decoded for the reference checks.
+func (c *configChecker) checkTopLevel(n *document.Node) bool {
+	name := n.Name.ValueString()
+	// proxy "name" { ... } is the lenient parser's form of a proxies entry
+	if name == "proxy" {
+		if len(n.Arguments) == 0 || n.Arguments[0].ValueString() == "" {
+			c.errorAt(n, "proxy needs a name: proxy \"name\" { ... }")
+			return false
+		}
+		proxyName := n.Arguments[0].ValueString()
+		c.record("proxy "+proxyName, n)
+		return c.checkStructBody(n, reflect.TypeOf(ProxyConfig{}), fmt.Sprintf("proxy %q", proxyName), true)
+	}
+
+	c.record(name, n)
+	fields, _ := kdlFields(reflect.TypeOf(AgntConfig{}))
+	field, ok := fields[name]
+	if !ok {
+		c.unknownKey(c.nodeOffset(n), name, "", append(fieldNames(fields), "proxy"))
+		return false
+	}
+	return c.checkField(n, field, name)
+}
+
+// checkField checks a node holding the value of a field.
+func (c *configChecker) checkField(n *document.Node, field kdlField, where string) bool {
+	t := deref(field.typ)
+	switch t.Kind() {
+	case reflect.Struct:
+		return c.checkStruct(n, t, where)
+
+	case reflect.Map:
+		if len(n.Arguments) > 0 {
+			c.errorAt(n, "%s takes no arguments", where)
+			return false
+		}
+		vt := deref(t.Elem())
+		keep := make(map[string]string)
+		for _, key := range sortedProps(n) {
+			v, _ := n.Properties.Get(key)
+			if vt.Kind() == reflect.Struct {
+				c.add(SeverityError, c.propOffset(n, key), "%s entry %q must be a block", where, key)
+			} else if msg := checkValueType(v, vt); msg != "" {
+				c.add(SeverityError, c.propOffset(n, key), "%s: %s %s", where, key, msg)
+			} else {
+				keep[key] = key
+			}
+		}
+		keepProps(n, keep)
+		n.Children = filterNodes(n.Children, func(child *document.Node) bool {
+			key := child.Name.ValueString()
+			if vt.Kind() != reflect.Struct {
+				return c.checkScalar(child, vt, where+" "+key)
+			}
+			kind := entryKind(where)
+			c.record(kind+" "+key, child)
+			return c.checkStruct(child, vt, fmt.Sprintf("%s %q", kind, key))
+		})
+		return true
+
+	case reflect.Slice:
+		et := deref(t.Elem())
+		if field.multiple && et.Kind() == reflect.Struct {
+			return c.checkStruct(n, et, where)
+		}
+		if len(n.Children) > 0 || n.Properties.Len() > 0 {
+			c.errorAt(n, "%s takes a list of values", where)
+			return false
+		}
+		for _, v := range n.Arguments {
+			if msg := checkValueType(v, et); msg != "" {
+				c.errorAt(n, "%s %s", where, msg)
+				return false
+			}
+		}
+		return true
+
+	default:
+		return c.checkScalar(n, t, where)
+	}
+}
+
+// entryKind names an entry of a top-level map: a script or a proxy.
+func entryKind(where string) string {
+	switch where {
+	case "scripts":
+		return "script"
+	case "proxies":
+		return "proxy"
+	case "commands":
+		return "command"
+	}
+	return where
+}
+
+// checkScalar checks a node holding a single value.
+func (c *configChecker) checkScalar(n *document.Node, t reflect.Type, where string) bool {
+	switch {
+	case len(n.Children) > 0 || n.Properties.Len() > 0:
+		c.errorAt(n, "%s takes a single value", where)
+	case len(n.Arguments) == 0:
+		c.errorAt(n, "%s needs a value", where)
+	case len(n.Arguments) > 1:
+		c.errorAt(n, "%s takes one value, got %d", where, len(n.Arguments))
+	default:
+		msg := checkValueType(n.Arguments[0], t)
+		if msg == "" {
+			return true
+		}
+		c.errorAt(n, "%s %s", where, msg)
+	}
+	return false
+}
+
+// checkStruct checks a node decoded into a struct: its argument, properties
+// and children.
+func (c *configChecker) checkStruct(n *document.Node, t reflect.Type, where string) bool {
+	return c.checkStructBody(n, t, where, false)
+}
+
+// checkStructBody checks a struct node; named nodes have the entry name as
+// their first argument.
+func (c *configChecker) checkStructBody(n *document.Node, t reflect.Type, where string, named bool) bool {
+	fields, arg := kdlFields(t)
+	aliases := configAliases[t]
+
+	args := n.Arguments
+	if named {
+		args = args[1:]
+	}
+	switch {
+	case arg == nil && len(args) > 0:
+		c.errorAt(n, "%s takes no arguments", where)
+		return false
+	case arg != nil && len(args) > 1:
+		c.errorAt(n, "%s takes one argument, got %d", where, len(args))
+		return false
+	case arg != nil && len(args) == 1:
+		if msg := checkValueType(args[0], deref(arg)); msg != "" {
+			c.errorAt(n, "%s argument %s", where, msg)
+			return false
+		}
+	}
+
+	keep := make(map[string]string)
+	for _, key := range sortedProps(n) {
+		offset := c.propOffset(n, key)
+		field, ok := fields[key]
+		if !ok {
+			c.unknownKey(offset, key, where, fieldNames(fields))
+			continue
+		}
+		v, _ := n.Properties.Get(key)
+		ft := deref(field.typ)
+		if ft.Kind() == reflect.Slice {
+			ft = deref(ft.Elem())
+		}
+		if ft.Kind() == reflect.Struct || ft.Kind() == reflect.Map {
+			c.add(SeverityError, offset, "%s in %s must be a block", key, where)
+		} else if msg := checkValueType(v, ft); msg != "" {
+			c.add(SeverityError, offset, "%s in %s %s", key, where, msg)
+		} else if canonical, ok := aliases[key]; ok {
+			keep[key] = canonical
+		} else {
+			keep[key] = key
+		}
+	}
+	keepProps(n, keep)
+
+	n.Children = filterNodes(n.Children, func(child *document.Node) bool {
+		key := child.Name.ValueString()
+		field, ok := fields[key]
+		if !ok {
+			c.unknownKey(c.nodeOffset(child), key, where, fieldNames(fields))
+			return false
+		}
+		if canonical, ok := aliases[key]; ok {
+			child.SetName(canonical)
+		}
+		return c.checkField(child, field, key+" in "+where)
+	})
+	return true
+}
+
+// keepProps keeps the properties of a node listed in keep, renamed to the
+// name they map to.
+func keepProps(n *document.Node, keep map[string]string) {
+	props := n.Properties.Unordered()
+	if len(keep) == len(props) && !renamed(keep) {
+		return
+	}
+	n.Properties = document.Properties{}
+	for _, key := range sortedProps(&document.Node{Properties: props}) {
+		if name, ok := keep[key]; ok {
+			n.AddPropertyValue(name, props[key], "")
+		}
+	}
+}
+
+func renamed(names map[string]string) bool {
+	for from, to := range names {
+		if from != to {
+			return true
+		}
+	}
+	return false
+}
+
+func filterNodes(nodes []*document.Node, keep func(*document.Node) bool) []*document.Node {
+	var out []*document.Node
+	for _, n := range nodes {
+		if keep(n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (c *configChecker) unknownKey(offset int, key, where string, known []string) {
+	msg := fmt.Sprintf("unknown key %q", key)
+	if where != "" {
+		msg += " in " + where
+	}
+	if s := suggest(key, known); s != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", s)
+	}
+	c.add(SeverityError, offset, "%s", msg)
+}
+
+func sortedProps(n *document.Node) []string {
+	props := n.Properties.Unordered()
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkValueType returns why a value can't be decoded into t, or "". Strings
+// accept any value, like kdl-go does; numbers and booleans only accept
+// values kdl-go wouldn't silently turn into something else.
+func checkValueType(v *document.Value, t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		if v.Value == nil {
+			return "expects a string, got null"
+		}
+	case reflect.Bool:
+		if _, ok := v.Value.(bool); !ok {
+			return "expects true or false, got " + v.String()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch x := v.Value.(type) {
+		case int64:
+			return ""
+		case float64:
+			if x == math.Trunc(x) {
+				return ""
+			}
+		case string:
+			if _, err := strconv.ParseInt(x, 10, 64); err == nil {
+				return ""
+			}
+		}
+		return "expects an integer, got " + v.String()
+	case reflect.Float32, reflect.Float64:
+		switch x := v.Value.(type) {
+		case int64, float64:
+			return ""
+		case string:
+			if _, err := strconv.ParseFloat(x, 64); err == nil {
+				return ""
+			}
+		}
+		return "expects a number, got " + v.String()
+	}
+	return ""
+}
+
+// suggest returns the candidate closest to name, if it is a likely typo.
+func suggest(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// References
+
+func (c *configChecker) record(key string, n *document.Node) {
+	if c.nodes == nil {
+		c.nodes = make(map[string]*document.Node)
+	}
+	c.nodes[key] = n
+}
+
+// proxyKeyNode returns the node of a key in a proxy block, or the proxy
+// node itself.
+func (c *configChecker) proxyKeyNode(proxy, key string) *document.Node {
+	return childNamed(c.nodes["proxy "+proxy], key)
+}
+
+// checkReferences checks what the schema can't: names referring to other
+// parts of the config or the project, and values with a format.
+func (c *configChecker) checkReferences(cfg *AgntConfig, opts CheckOptions) {
+	scripts := make([]string, 0, len(cfg.Scripts)+len(opts.Scripts))
+	for name := range cfg.Scripts {
+		scripts = append(scripts, name)
+	}
+	scripts = append(scripts, opts.Scripts...)
+
+	for _, name := range sortedKeys(cfg.Scripts) {
+		script := cfg.Scripts[name]
+		if script != nil && script.Run != "" && script.Command != "" {
+			c.warnAt(c.nodes["script "+name], "script %q sets both run and command; run is used", name)
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.Proxies) {
+		p := cfg.Proxies[name]
+		if p == nil {
+			continue
+		}
+		where := fmt.Sprintf("proxy %q", name)
+
+		if p.Script != "" && !contains(scripts, p.Script) {
+			msg := fmt.Sprintf("%s links to unknown script %q", where, p.Script)
+			if s := suggest(p.Script, scripts); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			c.errorAt(c.proxyKeyNode(name, "script"), "%s", msg)
+		}
+		if p.Script == "" && p.URL == "" && p.Port == 0 && p.Target == "" {
+			c.warnAt(c.proxyKeyNode(name, ""), "%s has no target: set script, url or port", where)
+		}
+		for _, key := range []string{"url", "target", "public-url"} {
+			value := map[string]string{"url": p.URL, "target": p.Target, "public-url": p.PublicURL}[key]
+			if value != "" && !isHTTPURL(value) {
+				c.errorAt(c.proxyKeyNode(name, key), "%s %s %q is not an http(s) URL", where, key, value)
+			}
+		}
+
+		for i, route := range p.Routes {
+			if route == nil {
+				continue
+			}
+			n := c.nthChild(name, "route", i)
+			switch {
+			case route.Target == "":
+				c.errorAt(n, "route in %s has no target", where)
+			case !isHTTPURL(route.Target):
+				c.errorAt(n, "route in %s: target %q is not an http(s) URL", where, route.Target)
+			}
+			if route.Path == "" && route.Pattern == "" {
+				c.errorAt(n, "route in %s needs a path or pattern", where)
+			}
+			if route.Pattern != "" {
+				if _, err := regexp.Compile(route.Pattern); err != nil {
+					c.errorAt(n, "route in %s: invalid pattern: %v", where, err)
+				}
+			}
+		}
+
+		if p.Chaos != nil {
+			c.checkChaos(name, p.Chaos, opts)
+		}
+	}
+
+	if cfg.Language != "" && opts.Languages != nil && !contains(opts.Languages, cfg.Language) {
+		msg := fmt.Sprintf("unknown language %q", cfg.Language)
+		if s := suggest(cfg.Language, opts.Languages); s != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+		c.errorAt(c.nodes["language"], "%s: define it under languages in the global config", msg)
+	}
+	switch cfg.PackageManager {
+	case "", "npm", "pnpm", "yarn", "bun":
+	default:
+		c.errorAt(c.nodes["package-manager"], "package-manager %q must be npm, pnpm, yarn or bun", cfg.PackageManager)
+	}
+	for _, name := range sortedKeys(cfg.Commands) {
+		if cmd := cfg.Commands[name]; cmd != nil && cmd.Command == "" {
+			c.errorAt(c.nodes["command "+name], "command %q needs cmd", name)
+		}
+	}
+
+	if cfg.Toast != nil && cfg.Toast.Position != "" {
+		switch cfg.Toast.Position {
+		case "top-right", "top-left", "bottom-right", "bottom-left":
+		default:
+			c.errorAt(childNamed(c.nodes["toast"], "position"), "toast position %q must be top-right, top-left, bottom-right or bottom-left", cfg.Toast.Position)
+		}
+	}
+}
+
+func (c *configChecker) checkChaos(proxy string, chaos *ChaosConfig, opts CheckOptions) {
+	where := fmt.Sprintf("chaos in proxy %q", proxy)
+	chaosNode := c.proxyKeyNode(proxy, "chaos")
+
+	if chaos.Preset != "" && opts.ChaosPresets != nil && !contains(opts.ChaosPresets, chaos.Preset) {
+		msg := fmt.Sprintf("unknown preset %q in %s", chaos.Preset, where)
+		if s := suggest(chaos.Preset, opts.ChaosPresets); s != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+		c.errorAt(childNamed(chaosNode, "preset"), "%s", msg)
+	}
+
+	rules := childrenNamed(chaosNode, "rule")
+	for i, rule := range chaos.Rules {
+		if rule == nil {
+			continue
+		}
+		var n *document.Node
+		if i < len(rules) {
+			n = rules[i]
+		}
+		if rule.Type == "" {
+			c.errorAt(n, "rule in %s needs a chaos type", where)
+		}
+		for _, code := range splitComma(rule.ErrorCodes) {
+			if v, err := strconv.Atoi(code); err != nil || v < 100 || v > 599 {
+				c.errorAt(n, "rule in %s: invalid error code %q", where, code)
+			}
+		}
+		if rule.URLPattern != "" {
+			if _, err := regexp.Compile(rule.URLPattern); err != nil {
+				c.errorAt(n, "rule in %s: invalid url-pattern: %v", where, err)
+			}
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			c.errorAt(n, "rule in %s: probability must be between 0 and 1", where)
+		}
+	}
+}
+
+// nthChild returns the i-th child named key of a proxy block.
+func (c *configChecker) nthChild(proxy, key string, i int) *document.Node {
+	n := c.nodes["proxy "+proxy]
+	if children := childrenNamed(n, key); i < len(children) {
+		return children[i]
+	}
+	return n
+}
+
+func childNamed(n *document.Node, name string) *document.Node {
+	if children := childrenNamed(n, name); len(children) > 0 {
+		return children[0]
+	}
+	return n
+}
+
+func childrenNamed(n *document.Node, name string) []*document.Node {
+	if n == nil {
+		return nil
+	}
+	var out []*document.Node
+	for _, child := range n.Children {
+		if child.Name.ValueString() == name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func splitComma(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Positions
+//
+// kdl-go doesn't keep source positions, so nodes are located by scanning the
+// source for where each node starts, in the same order as the parsed
+// document.
+
+// scannedNode is where a node starts in the source.
+type scannedNode struct {
+	offset int
+	name   string // Bare node name; "" for quoted or annotated names
+}
+
+// locate records the offset of every node of the document.
+func (c *configChecker) locate(nodes []*document.Node) {
+	scanned := scanNodes(c.src)
+	offsets := make(map[*document.Node]int)
+	i := 0
+	var walk func(nodes []*document.Node) bool
+	walk = func(nodes []*document.Node) bool {
+		for _, n := range nodes {
+			if i >= len(scanned) {
+				return false
+			}
+			if s := scanned[i]; s.name != "" && s.name != n.Name.ValueString() {
+				return false
+			}
+			offsets[n] = scanned[i].offset
+			i++
+			if !walk(n.Children) {
+				return false
+			}
+		}
+		return true
+	}
+	if walk(nodes) && i == len(scanned) {
+		c.offsets = offsets
+	}
+}
+
+func (c *configChecker) nodeOffset(n *document.Node) int {
+	if n == nil {
+		return -1
+	}
+	if offset, ok := c.offsets[n]; ok {
+		return offset
+	}
+	return -1
+}
+
+// propOffset returns the offset of a property of a node, or of the node.
+func (c *configChecker) propOffset(n *document.Node, key string) int {
+	start := c.nodeOffset(n)
+	if start < 0 {
+		return -1
+	}
+	line := c.src[start:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	for from := 0; ; {
+		i := bytes.Index(line[from:], []byte(key+"="))
+		if i < 0 {
+			return start
+		}
+		i += from
+		if i > 0 && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return start + i
+		}
+		from = i + 1
+	}
+}
+
+// position converts a byte offset to a 1-based line and column (in runes).
+func (c *configChecker) position(offset int) (line, column int) {
+	before := c.src[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// scanNodes returns the start of every node in KDL source, depth first,
+// skipping nodes and blocks commented out with /-.
+func scanNodes(src []byte) []scannedNode {
+	var out []scannedNode
+	depth := 0
+	skipDepth := -1    // Depth of a node or block commented out with /-
+	slashdash := false // /- applies to the next node, value or block
+	atStart := true    // The next token starts a node
+	continued := false // A \ continues the node on the next line
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		var next byte
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+
+		switch {
+		case c == '\n':
+			if continued {
+				continued = false
+			} else {
+				atStart = true
+			}
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			continue
+		case c == '/' && next == '/':
+			for i+1 < len(src) && src[i+1] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && next == '*':
+			i = skipBlockComment(src, i) - 1
+			continue
+		case c == '/' && next == '-':
+			slashdash = true
+			i++
+			continue
+		case c == '\\':
+			continued = true
+			continue
+		case c == ';':
+			atStart = true
+			continue
+		case c == '{':
+			if slashdash {
+				skipDepth = depth
+				slashdash = false
+			}
+			depth++
+			atStart = true
+			continue
+		case c == '}':
+			depth--
+			atStart = true
+			continue
+		}
+
+		end := skipToken(src, i)
+		if atStart {
+			atStart = false
+			switch {
+			case skipDepth >= 0 && depth > skipDepth:
+				// Inside a commented-out node
+			case slashdash:
+				skipDepth = depth
+				slashdash = false
+			default:
+				skipDepth = -1
+				name := ""
+				if c != '"' && c != '(' && !isRawStringStart(src, i) {
+					name = string(src[i:end])
+				}
+				out = append(out, scannedNode{offset: i, name: name})
+			}
+		} else {
+			slashdash = false
+		}
+		i = end - 1
+	}
+	return out
+}
+
+// skipBlockComment returns the offset after a (nestable) /* */ comment.
+func skipBlockComment(src []byte, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch {
+		case src[i] == '/' && i+1 < len(src) && src[i+1] == '*':
+			depth++
+			i += 2
+		case src[i] == '*' && i+1 < len(src) && src[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+func isRawStringStart(src []byte, i int) bool {
+	return src[i] == 'r' && i+1 < len(src) && (src[i+1] == '"' || src[i+1] == '#')
+}
+
+// skipToken returns the offset after the string, raw string, type
+// annotation or bare token starting at i.
+func skipToken(src []byte, i int) int {
+	switch {
+	case src[i] == '"':
+		for j := i + 1; j < len(src); j++ {
+			switch src[j] {
+			case '\\':
+				j++
+			case '"':
+				return j + 1
+			}
+		}
+		return len(src)
+	case isRawStringStart(src, i):
+		j := i + 1
+		hashes := 0
+		for j < len(src) && src[j] == '#' {
+			hashes++
+			j++
+		}
+		if j >= len(src) {
+			return len(src)
+		}
+		closing := append([]byte{'"'}, bytes.Repeat([]byte{'#'}, hashes)...)
+		if end := bytes.Index(src[j+1:], closing); end >= 0 {
+			return j + 1 + end + len(closing)
+		}
+		return len(src)
+	case src[i] == '(':
+		if end := bytes.IndexByte(src[i:], ')'); end >= 0 {
+			return i + end + 1
+		}
+		return len(src)
+	}
+	j := i
+	for j < len(src) && !strings.ContainsRune(" \t\r\n\f\v;{}()\"\\/=", rune(src[j])) {
+		j++
+	}
+	if j == i {
+		j++ // A lone delimiter, e.g. the = of a property
+	}
+	return j
+}
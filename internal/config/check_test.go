@@ -0,0 +1,238 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAgntConfig_Valid(t *testing.T) {
+	input := `// Scripts
+scripts {
+    dev {
+        run "npm run dev"
+        autostart true
+        env {
+            PORT "3000"
+        }
+    }
+}
+
+proxies {
+    app {
+        script "dev"
+        https true
+        route "/api" target="http://localhost:8080" strip-prefix=true
+        chaos {
+            preset "mobile-3g"
+            rule "http_error" probability=0.2 error-codes="502,503"
+        }
+        /- inject {
+            indicator false
+        }
+    }
+    api {
+        url "http://localhost:8080"
+    }
+}
+
+toast {
+    position "top-left"
+}
+`
+	issues := CheckAgntConfig(".agnt.kdl", []byte(input), CheckOptions{ChaosPresets: []string{"mobile-3g"}})
+	assert.Empty(t, issues)
+
+	// The lenient format is still valid
+	simple := `scripts {
+    dev auto-start=true
+}
+
+proxy "dev" {
+    target-url "http://localhost:3847"
+}
+`
+	assert.Empty(t, CheckAgntConfig(".agnt.kdl", []byte(simple), CheckOptions{}))
+}
+
+func TestCheckAgntConfig_Issues(t *testing.T) {
+	input := `scripts {
+    dev {
+        run "npm run dev"
+        autostrat true
+    }
+}
+
+proxies {
+    app {
+        script "web"
+        port "abc"
+        route "/api" target="http://localhost:8080" strip-prefx=true
+        chaos {
+            preset "mobile-4g"
+        }
+    }
+    empty {
+        https 1
+    }
+}
+`
+	issues := CheckAgntConfig(".agnt.kdl", []byte(input), CheckOptions{
+		Scripts:      []string{"build"},
+		ChaosPresets: []string{"mobile-3g", "flaky-api"},
+	})
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	assert.Equal(t, []string{
+		`.agnt.kdl:4:9: error: unknown key "autostrat" in script "dev" (did you mean "autostart"?)`,
+		`.agnt.kdl:10:9: error: proxy "app" links to unknown script "web" (did you mean "dev"?)`,
+		`.agnt.kdl:11:9: error: port in proxy "app" expects an integer, got "abc"`,
+		`.agnt.kdl:12:53: error: unknown key "strip-prefx" in route in proxy "app" (did you mean "strip-prefix"?)`,
+		`.agnt.kdl:14:13: error: unknown preset "mobile-4g" in chaos in proxy "app" (did you mean "mobile-3g"?)`,
+		`.agnt.kdl:17:5: warning: proxy "empty" has no target: set script, url or port`,
+		`.agnt.kdl:18:9: error: https in proxy "empty" expects true or false, got 1`,
+	}, got)
+	assert.True(t, HasErrors(issues))
+
+	// Project scripts satisfy references
+	issues = CheckAgntConfig(".agnt.kdl", []byte("proxies {\n    app {\n        script \"start\"\n    }\n}\n"), CheckOptions{Scripts: []string{"start"}})
+	assert.Empty(t, issues)
+}
+
+func TestCheckAgntConfig_Commands(t *testing.T) {
+	input := `language "rsut"
+package-manager "pip"
+commands {
+    test {
+        cmd "gotestsum"
+        args "--" "./..."
+    }
+    lint {
+        args "run"
+    }
+    fmt {
+        cmd "gofmt"
+        timeot 10
+    }
+}
+`
+	issues := CheckAgntConfig(".agnt.kdl", []byte(input), CheckOptions{Languages: []string{"go", "rust"}})
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	assert.Equal(t, []string{
+		`.agnt.kdl:1:1: error: unknown language "rsut" (did you mean "rust"?): define it under languages in the global config`,
+		`.agnt.kdl:2:1: error: package-manager "pip" must be npm, pnpm, yarn or bun`,
+		`.agnt.kdl:8:5: error: command "lint" needs cmd`,
+		`.agnt.kdl:13:9: error: unknown key "timeot" in command "fmt" (did you mean "timeout"?)`,
+	}, got)
+
+	// Languages are only checked when known
+	assert.Empty(t, CheckAgntConfig(".agnt.kdl", []byte("language \"zig\"\n"), CheckOptions{}))
+}
+
+func TestCheckAgntConfig_SyntaxError(t *testing.T) {
+	issues := CheckAgntConfig("cfg.kdl", []byte("scripts {\n    dev {\n        run \"x\"\n    }\n"), CheckOptions{})
+	require.Len(t, issues, 1)
+	assert.Equal(t, SeverityError, issues[0].Severity)
+	assert.Equal(t, "syntax error: unexpected EOF in state stateChildren", issues[0].Message)
+	assert.Equal(t, 4, issues[0].Line)
+
+	issues = CheckAgntConfig("cfg.kdl", []byte("scripts {\ndev }\n"), CheckOptions{})
+	require.Len(t, issues, 1)
+	assert.Equal(t, "cfg.kdl:2:5: error: syntax error: unexpected BraceClose in state stateNodeParams", issues[0].String())
+}
+
+func TestCheckAgntConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AgntConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte("toast {\n    duration 1.5\n    position \"middle\"\n}\nhooks\nunknown 1\n"), 0644))
+
+	issues, err := CheckAgntConfigFile(path, CheckOptions{})
+	require.NoError(t, err)
+	require.Len(t, issues, 3)
+	assert.Equal(t, Issue{File: path, Line: 2, Column: 5, Severity: SeverityError, Message: "duration in toast expects an integer, got 1.5"}, issues[0])
+	assert.Equal(t, 3, issues[1].Line)
+	assert.Contains(t, issues[1].Message, `toast position "middle"`)
+	assert.Equal(t, `unknown key "unknown"`, issues[2].Message)
+
+	_, err = CheckAgntConfigFile(filepath.Join(t.TempDir(), "missing.kdl"), CheckOptions{})
+	assert.Error(t, err)
+}
+
+func TestScanNodes(t *testing.T) {
+	src := `a "x" /* b { } */ {
+    r#"raw } {"# c=1
+    /- skipped {
+        child
+    }
+    "quoted"; d
+}
+e \
+  f
+`
+	var names []string
+	for _, n := range scanNodes([]byte(src)) {
+		names = append(names, n.name)
+	}
+	assert.Equal(t, []string{"a", "", "", "d", "e"}, names)
+}
+
+func TestCheckAgntConfig_DefaultFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AgntConfigFileName)
+	require.NoError(t, WriteDefaultAgntConfig(path))
+
+	issues, err := CheckAgntConfigFile(path, CheckOptions{Scripts: []string{"dev"}})
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+}
+
+func TestDiffAgntConfig(t *testing.T) {
+	prev, err := ParseAgntConfig(`scripts {
+    dev {
+        run "npm run dev"
+    }
+    test {
+        run "npm test"
+    }
+}
+proxies {
+    app {
+        script "dev"
+    }
+}
+`)
+	require.NoError(t, err)
+	next, err := ParseAgntConfig(`scripts {
+    dev {
+        run "npm run dev -- --port 4000"
+    }
+    lint {
+        run "npm run lint"
+    }
+}
+proxies {
+    app {
+        script "dev"
+    }
+}
+`)
+	require.NoError(t, err)
+
+	changes := DiffAgntConfig(prev, next)
+	assert.Equal(t, EntryChanges{Added: []string{"lint"}, Changed: []string{"dev"}, Removed: []string{"test"}}, changes.Scripts)
+	assert.Equal(t, EntryChanges{}, changes.Proxies)
+	assert.False(t, changes.Empty())
+	assert.True(t, DiffAgntConfig(next, next).Empty())
+
+	changes = DiffAgntConfig(nil, next)
+	assert.Equal(t, []string{"dev", "lint"}, changes.Scripts.Added)
+	assert.Equal(t, []string{"app"}, changes.Proxies.Added)
+}
@@ -16,6 +16,68 @@ type Config struct {
 
 	// Languages holds language-specific configurations.
 	Languages map[string]LanguageConfig `json:"languages"`
+
+	// Remote is the daemon's TCP listener for clients on other machines,
+	// containers and VMs.
+	Remote RemoteConfig `json:"remote"`
+
+	// Metrics is the daemon's Prometheus/OpenMetrics endpoint.
+	Metrics MetricsConfig `json:"metrics"`
+
+	// Speech is the speech-to-text backend of browser voice input.
+	Speech SpeechConfig `json:"speech"`
+}
+
+// SpeechConfig selects the speech-to-text backend of the proxies' voice
+// input. Empty fields fall back to the AGNT_SPEECH_* environment variables.
+type SpeechConfig struct {
+	// Provider is deepgram (default), openai or whisper.
+	Provider string `json:"provider,omitempty"`
+	// URL is the provider's endpoint; empty uses its default.
+	URL      string `json:"url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Language string `json:"language,omitempty"`
+	// InterimInterval is how often providers without streaming transcribe
+	// speech in progress (0: 2s, negative: off).
+	InterimInterval time.Duration `json:"interim_interval,omitempty"`
+}
+
+// IsSet reports whether the speech block sets anything.
+func (s SpeechConfig) IsSet() bool {
+	return s != SpeechConfig{}
+}
+
+// MetricsConfig configures the daemon's metrics endpoint. It is disabled
+// unless Listen is set, and only listens on loopback addresses.
+type MetricsConfig struct {
+	// Listen is the address to serve /metrics on, e.g. "127.0.0.1:9464".
+	Listen string `json:"listen,omitempty"`
+}
+
+// Enabled reports whether the metrics endpoint is configured.
+func (m MetricsConfig) Enabled() bool {
+	return m.Listen != ""
+}
+
+// RemoteConfig configures the daemon's TCP listener. It is disabled unless
+// Listen is set, and requires a token, client certificates or both.
+type RemoteConfig struct {
+	// Listen is the TCP address to listen on, e.g. "0.0.0.0:7433".
+	Listen string `json:"listen,omitempty"`
+	// CertFile and KeyFile are the server certificate; empty uses one
+	// issued by the local agnt CA.
+	CertFile string `json:"cert,omitempty"`
+	KeyFile  string `json:"key,omitempty"`
+	// ClientCAFile requires client certificates signed by this CA.
+	ClientCAFile string `json:"client_ca,omitempty"`
+	// TokenFile holds the token clients authenticate with.
+	TokenFile string `json:"token_file,omitempty"`
+}
+
+// Enabled reports whether the TCP listener is configured.
+func (r RemoteConfig) Enabled() bool {
+	return r.Listen != ""
 }
 
 // Settings holds global configuration settings.
@@ -54,6 +116,9 @@ type CommandConfig struct {
 	Persistent bool `json:"persistent,omitempty"`
 	// Env holds environment variables to set.
 	Env map[string]string `json:"env,omitempty"`
+	// Source is where the command is defined, one of the project.Source
+	// constants.
+	Source string `json:"source,omitempty"`
 }
 
 // DefaultConfig returns the default configuration.
@@ -78,7 +143,7 @@ func DefaultConfig() *Config {
 				Commands:             nodeCommandsToConfig(project.DefaultNodeCommands("npm")),
 			},
 			"python": {
-				Markers:  []string{"pyproject.toml", "setup.py", "requirements.txt"},
+				Markers:  []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"},
 				Priority: 80,
 				Commands: pythonCommandsToConfig(project.DefaultPythonCommands()),
 			},
@@ -95,6 +160,8 @@ func (c *CommandConfig) ToCommandDef(name string) project.CommandDef {
 		Args:        c.Args,
 		Timeout:     c.Timeout,
 		Persistent:  c.Persistent,
+		Env:         c.Env,
+		Source:      c.Source,
 	}
 }
 
@@ -108,6 +175,7 @@ func goCommandsToConfig(cmds []project.CommandDef) map[string]CommandConfig {
 			Description: cmd.Description,
 			Timeout:     cmd.Timeout,
 			Persistent:  cmd.Persistent,
+			Source:      project.SourceBuiltin,
 		}
 	}
 	return result
@@ -138,23 +206,35 @@ func (c *Config) GetLanguageCommands(lang string) []project.CommandDef {
 }
 
 // MergeProjectConfig merges per-project config with global config.
+// The project's commands are added to its language, which is created if
+// the global config doesn't define it.
 func (c *Config) MergeProjectConfig(projConfig *ProjectConfig) *Config {
 	if projConfig == nil {
 		return c
 	}
 
-	// Create a copy to avoid modifying the original
+	// Create a copy to avoid modifying the original, including the maps
 	merged := *c
+	merged.Languages = make(map[string]LanguageConfig, len(c.Languages))
+	for name, langConfig := range c.Languages {
+		commands := make(map[string]CommandConfig, len(langConfig.Commands))
+		for cmdName, cmd := range langConfig.Commands {
+			commands[cmdName] = cmd
+		}
+		langConfig.Commands = commands
+		merged.Languages[name] = langConfig
+	}
 
 	// Override language-specific commands if project specifies them
 	if projConfig.Language != "" {
-		if langConfig, ok := merged.Languages[projConfig.Language]; ok {
-			// Merge commands
-			for name, cmd := range projConfig.Commands {
-				langConfig.Commands[name] = cmd
-			}
-			merged.Languages[projConfig.Language] = langConfig
+		langConfig := merged.Languages[projConfig.Language]
+		if langConfig.Commands == nil {
+			langConfig.Commands = make(map[string]CommandConfig)
+		}
+		for name, cmd := range projConfig.Commands {
+			langConfig.Commands[name] = cmd
 		}
+		merged.Languages[projConfig.Language] = langConfig
 	}
 
 	return &merged
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/standardbeagle/agnt/internal/project"
+)
+
+// DetectProject detects the project at path using the configured languages
+// and the project's .agnt.kdl:
+//
+//   - the language is the one set in .agnt.kdl, or else the one with the
+//     highest priority that has a marker file at path;
+//   - the package manager set in .agnt.kdl replaces the detected one;
+//   - the commands are the built-in ones of the language, overridden and
+//     extended by the commands of the global config and then by those of
+//     .agnt.kdl.
+//
+// Each command records where it is defined in Source. Problems in .agnt.kdl
+// are left to CheckAgntConfig; a file that can't be read is ignored.
+func (c *Config) DetectProject(path string) (*project.Project, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	projConfig, _ := LoadProjectConfig(absPath)
+	if projConfig == nil {
+		projConfig = &ProjectConfig{}
+	}
+
+	lang := projConfig.Language
+	if lang == "" {
+		lang = c.detectLanguage(absPath)
+	}
+
+	proj, err := project.DetectAs(absPath, project.ProjectType(lang))
+	if err != nil {
+		return nil, err
+	}
+	if projConfig.PackageManager != "" && proj.Type == project.ProjectNode {
+		proj.PackageManager = projConfig.PackageManager
+		proj.Commands = project.DefaultNodeCommands(projConfig.PackageManager)
+	}
+	for i := range proj.Commands {
+		if proj.Commands[i].Source == "" {
+			proj.Commands[i].Source = project.SourceBuiltin
+		}
+	}
+
+	// The project's commands are merged into its language, so a project
+	// command replaces a global one of the same name; without a language
+	// they extend the unknown project
+	lang = string(proj.Type)
+	merged := c.MergeProjectConfig(&ProjectConfig{Language: lang, Commands: projConfig.Commands})
+	commands := merged.Languages[lang].Commands
+	for _, name := range sortedKeys(commands) {
+		cmd := commands[name]
+		// Built-in commands come from detection, which knows the package
+		// manager; commands without an executable can't be run
+		if cmd.Source == project.SourceBuiltin || cmd.Command == "" {
+			continue
+		}
+		def := cmd.ToCommandDef(name)
+		if existing := project.GetCommandByName(proj, name); existing != nil {
+			if def.Description == "" {
+				def.Description = existing.Description
+			}
+			*existing = def
+			continue
+		}
+		proj.Commands = append(proj.Commands, def)
+	}
+
+	return proj, nil
+}
+
+// detectLanguage returns the language with the highest priority that has a
+// marker file at path, or "" if none does.
+func (c *Config) detectLanguage(path string) string {
+	names := make([]string, 0, len(c.Languages))
+	for name := range c.Languages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := c.Languages[names[i]].Priority, c.Languages[names[j]].Priority
+		if pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		for _, marker := range c.Languages[name].Markers {
+			if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// LanguageNames returns the names of the configured languages, sorted.
+func (c *Config) LanguageNames() []string {
+	return sortedKeys(c.Languages)
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/project"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestParseKDLConfig_Languages(t *testing.T) {
+	cfg, err := ParseKDLConfig(`languages {
+    go {
+        commands {
+            test {
+                cmd "gotestsum"
+                args "--" "./..."
+            }
+        }
+    }
+    rust {
+        markers "Cargo.toml"
+        priority 95
+        commands {
+            test {
+                cmd "cargo"
+                args "test"
+                description "Run cargo tests"
+            }
+        }
+    }
+}
+`)
+	require.NoError(t, err)
+
+	// Unset fields keep the defaults
+	assert.Equal(t, []string{"go.mod"}, cfg.Languages["go"].Markers)
+	assert.Equal(t, 100, cfg.Languages["go"].Priority)
+	assert.Equal(t, CommandConfig{Command: "gotestsum", Args: []string{"--", "./..."}, Source: project.SourceGlobal}, cfg.Languages["go"].Commands["test"])
+	assert.Equal(t, project.SourceBuiltin, cfg.Languages["go"].Commands["build"].Source)
+
+	rust := cfg.Languages["rust"]
+	assert.Equal(t, []string{"Cargo.toml"}, rust.Markers)
+	assert.Equal(t, 95, rust.Priority)
+	assert.Equal(t, "Run cargo tests", rust.Commands["test"].Description)
+	assert.Equal(t, []string{"go", "node", "python", "rust"}, cfg.LanguageNames())
+}
+
+func TestWriteDefaultConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agnt", GlobalConfigFile)
+	require.NoError(t, WriteDefaultConfig(path))
+
+	cfg, err := LoadConfigFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "go", cfg.Languages["go"].Commands["test"].Command)
+	assert.Equal(t, project.SourceGlobal, cfg.Languages["go"].Commands["test"].Source)
+	assert.Equal(t, 80, cfg.Languages["python"].Priority)
+}
+
+func TestMergeProjectConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	merged := cfg.MergeProjectConfig(&ProjectConfig{
+		Language: "go",
+		Commands: map[string]CommandConfig{"test": {Command: "gotestsum", Source: project.SourceProject}},
+	})
+	assert.Equal(t, "gotestsum", merged.Languages["go"].Commands["test"].Command)
+	// The original is unchanged
+	assert.Equal(t, "go", cfg.Languages["go"].Commands["test"].Command)
+
+	merged = cfg.MergeProjectConfig(&ProjectConfig{
+		Language: "zig",
+		Commands: map[string]CommandConfig{"build": {Command: "zig", Args: []string{"build"}}},
+	})
+	assert.Equal(t, "zig", merged.Languages["zig"].Commands["build"].Command)
+	assert.NotContains(t, cfg.Languages, "zig")
+}
+
+func TestDetectProject_BuiltIn(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": "module example.com/app\n"})
+
+	proj, err := DefaultConfig().DetectProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, project.ProjectGo, proj.Type)
+	assert.Equal(t, "app", proj.Name)
+
+	want, err := project.Detect(dir)
+	require.NoError(t, err)
+	require.Len(t, proj.Commands, len(want.Commands))
+	for i, cmd := range proj.Commands {
+		assert.Equal(t, project.SourceBuiltin, cmd.Source)
+		cmd.Source = ""
+		assert.Equal(t, want.Commands[i], cmd)
+	}
+}
+
+func TestDetectProject_Overrides(t *testing.T) {
+	cfg, err := ParseKDLConfig(`languages {
+    go {
+        commands {
+            test {
+                cmd "gotestsum"
+                args "--" "./..."
+            }
+            bench {
+                cmd "go"
+                args "test" "-bench=." "./..."
+            }
+        }
+    }
+}
+`)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/app\n",
+		AgntConfigFileName: `scripts {
+    dev {
+        run "go run ."
+    }
+}
+
+commands {
+    bench {
+        cmd "go"
+        args "test" "-bench=." "-benchmem" "./..."
+        env {
+            GOFLAGS "-count=1"
+        }
+    }
+    e2e {
+        cmd "./scripts/e2e.sh"
+    }
+}
+`,
+	})
+
+	proj, err := cfg.DetectProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, project.ProjectGo, proj.Type)
+
+	test := project.GetCommandByName(proj, "test")
+	require.NotNil(t, test)
+	assert.Equal(t, project.CommandDef{
+		Name:        "test",
+		Description: "Run Go tests",
+		Command:     "gotestsum",
+		Args:        []string{"--", "./..."},
+		Source:      project.SourceGlobal,
+	}, *test)
+
+	bench := project.GetCommandByName(proj, "bench")
+	require.NotNil(t, bench)
+	assert.Equal(t, project.SourceProject, bench.Source)
+	assert.Equal(t, []string{"test", "-bench=.", "-benchmem", "./..."}, bench.Args)
+	assert.Equal(t, map[string]string{"GOFLAGS": "-count=1"}, bench.Env)
+
+	assert.Equal(t, project.SourceProject, project.GetCommandByName(proj, "e2e").Source)
+	assert.Equal(t, project.SourceBuiltin, project.GetCommandByName(proj, "build").Source)
+
+	// New commands come after the built-in ones
+	names := project.GetCommandNames(proj)
+	assert.Equal(t, []string{"bench", "e2e"}, names[len(names)-2:])
+}
+
+func TestDetectProject_NewLanguage(t *testing.T) {
+	cfg, err := ParseKDLConfig(`languages {
+    rust {
+        markers "Cargo.toml"
+        priority 95
+        commands {
+            test {
+                cmd "cargo"
+                args "test"
+            }
+        }
+    }
+}
+`)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Cargo.toml": "[package]\n", "package.json": `{"name": "web"}`})
+
+	// rust outranks node
+	proj, err := cfg.DetectProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, project.ProjectType("rust"), proj.Type)
+	require.Len(t, proj.Commands, 1)
+	assert.Equal(t, project.CommandDef{Name: "test", Command: "cargo", Args: []string{"test"}, Source: project.SourceGlobal}, proj.Commands[0])
+
+	// The project can pick the language and package manager
+	writeFiles(t, dir, map[string]string{AgntConfigFileName: "language \"node\"\npackage-manager \"pnpm\"\n"})
+	proj, err = cfg.DetectProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, project.ProjectNode, proj.Type)
+	assert.Equal(t, "pnpm", proj.PackageManager)
+	assert.Equal(t, "pnpm", project.GetCommandByName(proj, "test").Command)
+
+	// Without a matching language, project commands still apply
+	empty := t.TempDir()
+	writeFiles(t, empty, map[string]string{AgntConfigFileName: "commands {\n    check {\n        cmd \"make\"\n        args \"check\"\n    }\n}\n"})
+	proj, err = cfg.DetectProject(empty)
+	require.NoError(t, err)
+	assert.Equal(t, project.ProjectUnknown, proj.Type)
+	assert.Equal(t, []string{"check"}, project.GetCommandNames(proj))
+
+	_, err = cfg.DetectProject(filepath.Join(dir, "Cargo.toml"))
+	assert.ErrorIs(t, err, os.ErrInvalid)
+}
+
+func TestParseKDLConfig_Remote(t *testing.T) {
+	cfg, err := ParseKDLConfig(`remote {
+    listen "0.0.0.0:7433"
+    token-file "/etc/agnt/token"
+    client-ca "/etc/agnt/clients.pem"
+}
+`)
+	require.NoError(t, err)
+	assert.True(t, cfg.Remote.Enabled())
+	assert.Equal(t, "0.0.0.0:7433", cfg.Remote.Listen)
+	assert.Equal(t, "/etc/agnt/token", cfg.Remote.TokenFile)
+	assert.Equal(t, "/etc/agnt/clients.pem", cfg.Remote.ClientCAFile)
+	assert.Empty(t, cfg.Remote.CertFile)
+
+	assert.False(t, DefaultConfig().Remote.Enabled())
+}
+
+func TestParseKDLConfig_Metrics(t *testing.T) {
+	cfg, err := ParseKDLConfig(`metrics {
+    listen "127.0.0.1:9464"
+}
+`)
+	require.NoError(t, err)
+	assert.True(t, cfg.Metrics.Enabled())
+	assert.Equal(t, "127.0.0.1:9464", cfg.Metrics.Listen)
+
+	assert.False(t, DefaultConfig().Metrics.Enabled())
+}
+
+func TestParseKDLConfig_Speech(t *testing.T) {
+	cfg, err := ParseKDLConfig(`speech {
+    provider "whisper"
+    url "http://127.0.0.1:8080"
+    language "de"
+    interim-interval 1500
+}
+`)
+	require.NoError(t, err)
+	assert.True(t, cfg.Speech.IsSet())
+	assert.Equal(t, "whisper", cfg.Speech.Provider)
+	assert.Equal(t, "http://127.0.0.1:8080", cfg.Speech.URL)
+	assert.Equal(t, "de", cfg.Speech.Language)
+	assert.Equal(t, 1500*time.Millisecond, cfg.Speech.InterimInterval)
+	assert.Empty(t, cfg.Speech.APIKey)
+
+	assert.False(t, DefaultConfig().Speech.IsSet())
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// EntryChanges lists the names of entries added, changed and removed
+// between two configs, each sorted.
+type EntryChanges struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+// ConfigChanges is the difference between the scripts and proxies of two
+// configs.
+type ConfigChanges struct {
+	Scripts EntryChanges
+	Proxies EntryChanges
+}
+
+// Empty reports whether nothing changed.
+func (c ConfigChanges) Empty() bool {
+	return c.Scripts.empty() && c.Proxies.empty()
+}
+
+func (e EntryChanges) empty() bool {
+	return len(e.Added) == 0 && len(e.Changed) == 0 && len(e.Removed) == 0
+}
+
+// DiffAgntConfig compares the scripts and proxies of two configs. Either
+// may be nil.
+func DiffAgntConfig(prev, next *AgntConfig) ConfigChanges {
+	if prev == nil {
+		prev = &AgntConfig{}
+	}
+	if next == nil {
+		next = &AgntConfig{}
+	}
+	return ConfigChanges{
+		Scripts: diffEntries(prev.Scripts, next.Scripts),
+		Proxies: diffEntries(prev.Proxies, next.Proxies),
+	}
+}
+
+func diffEntries[V any](prev, next map[string]V) EntryChanges {
+	var changes EntryChanges
+	for name, entry := range next {
+		old, ok := prev[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, name)
+		case !reflect.DeepEqual(old, entry):
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+	return changes
+}
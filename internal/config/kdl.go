@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/standardbeagle/agnt/internal/project"
+
 	kdl "github.com/sblinch/kdl-go"
 )
 
@@ -21,6 +23,9 @@ type KDLConfig struct {
 	Version   string       `kdl:"version"`
 	Settings  KDLSettings  `kdl:"settings"`
 	Languages KDLLanguages `kdl:"languages"`
+	Remote    KDLRemote    `kdl:"remote"`
+	Metrics   KDLMetrics   `kdl:"metrics"`
+	Speech    KDLSpeech    `kdl:"speech"`
 }
 
 // KDLSettings holds global settings from KDL.
@@ -30,13 +35,34 @@ type KDLSettings struct {
 	GracefulTimeout int `kdl:"graceful-timeout"`
 }
 
-// KDLLanguages holds language configurations.
-type KDLLanguages struct {
-	Go     *KDLLanguage `kdl:"go"`
-	Node   *KDLLanguage `kdl:"node"`
-	Python *KDLLanguage `kdl:"python"`
+// KDLRemote holds the daemon's TCP listener settings from KDL.
+type KDLRemote struct {
+	Listen    string `kdl:"listen"`
+	Cert      string `kdl:"cert"`
+	Key       string `kdl:"key"`
+	ClientCA  string `kdl:"client-ca"`
+	TokenFile string `kdl:"token-file"`
+}
+
+// KDLMetrics holds the daemon's metrics endpoint settings from KDL.
+type KDLMetrics struct {
+	Listen string `kdl:"listen"`
+}
+
+// KDLSpeech holds the speech-to-text settings of voice input from KDL.
+type KDLSpeech struct {
+	Provider        string `kdl:"provider"`
+	URL             string `kdl:"url"`
+	APIKey          string `kdl:"api-key"`
+	Model           string `kdl:"model"`
+	Language        string `kdl:"language"`
+	InterimInterval int    `kdl:"interim-interval"` // Milliseconds
 }
 
+// KDLLanguages holds language configurations by name. Names other than
+// go, node and python define new languages.
+type KDLLanguages map[string]*KDLLanguage
+
 // KDLLanguage holds configuration for a specific language.
 type KDLLanguage struct {
 	Markers              []string               `kdl:"markers"`
@@ -47,18 +73,12 @@ type KDLLanguage struct {
 
 // KDLCommand holds a command configuration.
 type KDLCommand struct {
-	Command    string            `kdl:"cmd"`
-	Args       []string          `kdl:"args"`
-	Timeout    int               `kdl:"timeout"`
-	Persistent bool              `kdl:"persistent"`
-	Env        map[string]string `kdl:"env"`
-}
-
-// KDLProjectConfig holds per-project configuration.
-type KDLProjectConfig struct {
-	Language       string                 `kdl:"language"`
-	PackageManager string                 `kdl:"package-manager"`
-	Commands       map[string]*KDLCommand `kdl:"commands"`
+	Command     string            `kdl:"cmd"`
+	Args        []string          `kdl:"args"`
+	Description string            `kdl:"description"`
+	Timeout     int               `kdl:"timeout"`
+	Persistent  bool              `kdl:"persistent"`
+	Env         map[string]string `kdl:"env"`
 }
 
 // LoadGlobalConfig loads the global configuration from the default location.
@@ -122,15 +142,28 @@ func kdlConfigToConfig(kdlCfg *KDLConfig) *Config {
 		cfg.Settings.GracefulTimeout = time.Duration(kdlCfg.Settings.GracefulTimeout) * time.Second
 	}
 
-	// Languages
-	if kdlCfg.Languages.Go != nil {
-		mergeLanguageConfig(cfg, "go", kdlCfg.Languages.Go)
+	cfg.Remote = RemoteConfig{
+		Listen:       kdlCfg.Remote.Listen,
+		CertFile:     kdlCfg.Remote.Cert,
+		KeyFile:      kdlCfg.Remote.Key,
+		ClientCAFile: kdlCfg.Remote.ClientCA,
+		TokenFile:    kdlCfg.Remote.TokenFile,
 	}
-	if kdlCfg.Languages.Node != nil {
-		mergeLanguageConfig(cfg, "node", kdlCfg.Languages.Node)
+	cfg.Metrics = MetricsConfig{Listen: kdlCfg.Metrics.Listen}
+	cfg.Speech = SpeechConfig{
+		Provider:        kdlCfg.Speech.Provider,
+		URL:             kdlCfg.Speech.URL,
+		APIKey:          kdlCfg.Speech.APIKey,
+		Model:           kdlCfg.Speech.Model,
+		Language:        kdlCfg.Speech.Language,
+		InterimInterval: time.Duration(kdlCfg.Speech.InterimInterval) * time.Millisecond,
 	}
-	if kdlCfg.Languages.Python != nil {
-		mergeLanguageConfig(cfg, "python", kdlCfg.Languages.Python)
+
+	// Languages
+	for name, kdlLang := range kdlCfg.Languages {
+		if kdlLang != nil {
+			mergeLanguageConfig(cfg, name, kdlLang)
+		}
 	}
 
 	return cfg
@@ -149,23 +182,32 @@ func mergeLanguageConfig(cfg *Config, name string, kdlLang *KDLLanguage) {
 	langCfg.PackageManagerDetect = kdlLang.PackageManagerDetect
 
 	// Merge commands
+	if langCfg.Commands == nil {
+		langCfg.Commands = make(map[string]CommandConfig)
+	}
 	for cmdName, kdlCmd := range kdlLang.Commands {
 		if kdlCmd == nil {
 			continue
 		}
-		cmdCfg := CommandConfig{
-			Command:    kdlCmd.Command,
-			Args:       kdlCmd.Args,
-			Timeout:    kdlCmd.Timeout,
-			Persistent: kdlCmd.Persistent,
-			Env:        kdlCmd.Env,
-		}
-		langCfg.Commands[cmdName] = cmdCfg
+		langCfg.Commands[cmdName] = kdlCmd.toCommandConfig(project.SourceGlobal)
 	}
 
 	cfg.Languages[name] = langCfg
 }
 
+// toCommandConfig converts a KDL command defined in source.
+func (c *KDLCommand) toCommandConfig(source string) CommandConfig {
+	return CommandConfig{
+		Command:     c.Command,
+		Args:        c.Args,
+		Description: c.Description,
+		Timeout:     c.Timeout,
+		Persistent:  c.Persistent,
+		Env:         c.Env,
+		Source:      source,
+	}
+}
+
 // LoadProjectConfig loads per-project configuration from .agnt.kdl.
 func LoadProjectConfig(projectPath string) (*ProjectConfig, error) {
 	configPath := filepath.Join(projectPath, ProjectConfigFile)
@@ -183,38 +225,15 @@ func LoadProjectConfig(projectPath string) (*ProjectConfig, error) {
 	return ParseProjectConfig(string(data))
 }
 
-// ParseProjectConfig parses per-project KDL configuration.
+// ParseProjectConfig parses the language, package-manager and commands of
+// an .agnt.kdl file, which also holds the scripts and proxies.
 func ParseProjectConfig(data string) (*ProjectConfig, error) {
-	var kdlCfg KDLProjectConfig
-	if err := kdl.Unmarshal([]byte(data), &kdlCfg); err != nil {
+	agntCfg, err := ParseAgntConfig(data)
+	if err != nil {
 		return nil, err
 	}
 
-	return kdlProjectConfigToProjectConfig(&kdlCfg), nil
-}
-
-// kdlProjectConfigToProjectConfig converts KDL project config to ProjectConfig.
-func kdlProjectConfigToProjectConfig(kdlCfg *KDLProjectConfig) *ProjectConfig {
-	cfg := &ProjectConfig{
-		Language:       kdlCfg.Language,
-		PackageManager: kdlCfg.PackageManager,
-		Commands:       make(map[string]CommandConfig),
-	}
-
-	for name, kdlCmd := range kdlCfg.Commands {
-		if kdlCmd == nil {
-			continue
-		}
-		cfg.Commands[name] = CommandConfig{
-			Command:    kdlCmd.Command,
-			Args:       kdlCmd.Args,
-			Timeout:    kdlCmd.Timeout,
-			Persistent: kdlCmd.Persistent,
-			Env:        kdlCmd.Env,
-		}
-	}
-
-	return cfg
+	return agntCfg.ProjectConfig(), nil
 }
 
 // GlobalConfigPath returns the path to the global config file.
@@ -251,9 +270,11 @@ languages {
         markers "go.mod"
         priority 100
         commands {
-            test { cmd "go" "test" "-v" "./..." }
-            build { cmd "go" "build" "-v" "./..." }
-            lint { cmd "golangci-lint" "run" }
+            // Override a default command
+            test {
+                cmd "go"
+                args "test" "-v" "./..."
+            }
         }
     }
 
@@ -261,22 +282,58 @@ languages {
         markers "package.json"
         package-manager-detect true
         priority 90
-        commands {
-            test { cmd "npm" "test" }
-            build { cmd "npm" "run" "build" }
-            dev { cmd "npm" "run" "dev"; persistent true }
-        }
     }
 
     python {
-        markers "pyproject.toml" "setup.py" "requirements.txt"
+        markers "pyproject.toml" "setup.py" "setup.cfg" "requirements.txt"
         priority 80
-        commands {
-            test { cmd "pytest" "-v" }
-            lint { cmd "ruff" "check" "." }
-        }
     }
+
+    // Define a new language by its marker files
+    // rust {
+    //     markers "Cargo.toml"
+    //     priority 95
+    //     commands {
+    //         test {
+    //             cmd "cargo"
+    //             args "test"
+    //         }
+    //         build {
+    //             cmd "cargo"
+    //             args "build"
+    //         }
+    //     }
+    // }
 }
+
+// Let agnt clients in containers, WSL or VMs reach this daemon over TLS.
+// Clients connect with: agnt mcp --daemon tcp://<host>:7433
+// remote {
+//     listen "0.0.0.0:7433"
+//     // Clients authenticate with this token, a client certificate or both
+//     token-file "/home/me/.config/agnt/remote-token"
+//     // client-ca "/path/to/client-ca.pem"
+//     // Server certificate; defaults to one from the local agnt CA (agnt ca)
+//     // cert "/path/to/cert.pem"
+//     // key "/path/to/key.pem"
+// }
+
+// Serve Prometheus/OpenMetrics metrics of the daemon, processes and proxies
+// at http://127.0.0.1:9464/metrics (localhost only)
+// metrics {
+//     listen "127.0.0.1:9464"
+// }
+
+// Speech-to-text for voice input in the browser. Unset values come from
+// AGNT_SPEECH_PROVIDER, AGNT_SPEECH_URL, AGNT_SPEECH_API_KEY... and the
+// API key from DEEPGRAM_API_KEY or OPENAI_API_KEY.
+// speech {
+//     provider "whisper"              // deepgram (default), openai or whisper
+//     url "http://127.0.0.1:8080"
+//     language "en"
+//     // model "nova-2"
+//     // interim-interval 2000        // ms between interim results (whisper, openai)
+// }
 `
 	// Create directory if needed
 	dir := filepath.Dir(path)
@@ -5,7 +5,6 @@ package daemon
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -69,8 +68,7 @@ func TestActivityBroadcast_EndToEnd(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Connect WebSocket client to the proxy
-	wsURL := fmt.Sprintf("ws://%s/__devtool_metrics", listenAddr)
-	wsConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	wsConn, _, err := websocket.DefaultDialer.Dial(metricsURL(t, daemon, "test-proxy"), nil)
 	if err != nil {
 		t.Fatalf("Failed to connect WebSocket: %v", err)
 	}
@@ -185,17 +183,14 @@ func TestOutputPreviewBroadcast_EndToEnd(t *testing.T) {
 	defer client.Close()
 
 	// Create a proxy
-	proxyResult, err := client.ProxyStart("test-proxy", targetServer.URL, 0, 0, "")
-	if err != nil {
+	if _, err := client.ProxyStart("test-proxy", targetServer.URL, 0, 0, ""); err != nil {
 		t.Fatalf("Failed to start proxy: %v", err)
 	}
 
-	listenAddr := proxyResult["listen_addr"].(string)
 	time.Sleep(100 * time.Millisecond)
 
 	// Connect WebSocket
-	wsURL := fmt.Sprintf("ws://%s/__devtool_metrics", listenAddr)
-	wsConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	wsConn, _, err := websocket.DefaultDialer.Dial(metricsURL(t, daemon, "test-proxy"), nil)
 	if err != nil {
 		t.Fatalf("Failed to connect WebSocket: %v", err)
 	}
@@ -328,17 +323,12 @@ func TestActivityBroadcast_MultipleProxies(t *testing.T) {
 	defer client.Close()
 
 	// Create two proxies
-	proxy1Result, err := client.ProxyStart("proxy1", targetServer1.URL, 0, 0, "")
-	if err != nil {
+	if _, err := client.ProxyStart("proxy1", targetServer1.URL, 0, 0, ""); err != nil {
 		t.Fatalf("Failed to start proxy1: %v", err)
 	}
-	listenAddr1 := proxy1Result["listen_addr"].(string)
-
-	proxy2Result, err := client.ProxyStart("proxy2", targetServer2.URL, 0, 0, "")
-	if err != nil {
+	if _, err := client.ProxyStart("proxy2", targetServer2.URL, 0, 0, ""); err != nil {
 		t.Fatalf("Failed to start proxy2: %v", err)
 	}
-	listenAddr2 := proxy2Result["listen_addr"].(string)
 
 	time.Sleep(100 * time.Millisecond)
 
@@ -346,11 +336,10 @@ func TestActivityBroadcast_MultipleProxies(t *testing.T) {
 	var receivedCount atomic.Int32
 	var wg sync.WaitGroup
 
-	connectAndListen := func(addr string) {
-		wsURL := fmt.Sprintf("ws://%s/__devtool_metrics", addr)
+	connectAndListen := func(wsURL string) {
 		wsConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
 		if err != nil {
-			t.Errorf("Failed to connect WebSocket to %s: %v", addr, err)
+			t.Errorf("Failed to connect WebSocket to %s: %v", wsURL, err)
 			return
 		}
 		defer wsConn.Close()
@@ -377,8 +366,8 @@ func TestActivityBroadcast_MultipleProxies(t *testing.T) {
 		time.Sleep(500 * time.Millisecond)
 	}
 
-	go connectAndListen(listenAddr1)
-	go connectAndListen(listenAddr2)
+	go connectAndListen(metricsURL(t, daemon, "proxy1"))
+	go connectAndListen(metricsURL(t, daemon, "proxy2"))
 
 	time.Sleep(100 * time.Millisecond)
 
@@ -438,19 +427,15 @@ func TestActivityBroadcast_SpecificProxy(t *testing.T) {
 	defer client.Close()
 
 	// Create two proxies
-	proxy1Result, _ := client.ProxyStart("target-proxy", targetServer1.URL, 0, 0, "")
-	listenAddr1 := proxy1Result["listen_addr"].(string)
-
-	proxy2Result, _ := client.ProxyStart("other-proxy", targetServer2.URL, 0, 0, "")
-	listenAddr2 := proxy2Result["listen_addr"].(string)
+	client.ProxyStart("target-proxy", targetServer1.URL, 0, 0, "")
+	client.ProxyStart("other-proxy", targetServer2.URL, 0, 0, "")
 
 	time.Sleep(100 * time.Millisecond)
 
 	var proxy1Received, proxy2Received atomic.Int32
 
 	// Connect to proxy 1
-	ws1URL := fmt.Sprintf("ws://%s/__devtool_metrics", listenAddr1)
-	ws1, _, _ := websocket.DefaultDialer.Dial(ws1URL, nil)
+	ws1, _, _ := websocket.DefaultDialer.Dial(metricsURL(t, daemon, "target-proxy"), nil)
 	defer ws1.Close()
 
 	go func() {
@@ -469,8 +454,7 @@ func TestActivityBroadcast_SpecificProxy(t *testing.T) {
 	}()
 
 	// Connect to proxy 2
-	ws2URL := fmt.Sprintf("ws://%s/__devtool_metrics", listenAddr2)
-	ws2, _, _ := websocket.DefaultDialer.Dial(ws2URL, nil)
+	ws2, _, _ := websocket.DefaultDialer.Dial(metricsURL(t, daemon, "other-proxy"), nil)
 	defer ws2.Close()
 
 	go func() {
@@ -539,14 +523,12 @@ func TestActivityBroadcast_RapidFire(t *testing.T) {
 	}
 	defer client.Close()
 
-	proxyResult, _ := client.ProxyStart("test-proxy", targetServer.URL, 0, 0, "")
-	listenAddr := proxyResult["listen_addr"].(string)
+	client.ProxyStart("test-proxy", targetServer.URL, 0, 0, "")
 
 	time.Sleep(100 * time.Millisecond)
 
 	// Connect WebSocket
-	wsURL := fmt.Sprintf("ws://%s/__devtool_metrics", listenAddr)
-	wsConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	wsConn, _, err := websocket.DefaultDialer.Dial(metricsURL(t, daemon, "test-proxy"), nil)
 	if err != nil {
 		t.Fatalf("Failed to connect WebSocket: %v", err)
 	}
@@ -589,3 +571,13 @@ func TestActivityBroadcast_RapidFire(t *testing.T) {
 
 	client.ProxyStop("test-proxy")
 }
+
+// metricsURL returns the authenticated metrics WebSocket URL of a daemon proxy.
+func metricsURL(t *testing.T, d *Daemon, id string) string {
+	t.Helper()
+	p, err := d.ProxyManager().Get(id)
+	if err != nil {
+		t.Fatalf("proxy %s not found: %v", id, err)
+	}
+	return p.MetricsURL()
+}
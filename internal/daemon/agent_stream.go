@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"context"
+	"log"
+
+	"github.com/standardbeagle/agnt/internal/aichannel"
+	"github.com/standardbeagle/agnt/internal/proxy"
+)
+
+// agentStreamSystemPrompt instructs the agent answering browser panel and design requests.
+const agentStreamSystemPrompt = `You are assisting a developer from inside their web application.
+Requests come from a browser panel or a design tool and include the page URL and the
+relevant HTML. Answer concisely. When asked for markup, respond with HTML only.`
+
+// newAgentStreamChannel creates the AI channel used for browser streaming.
+// API mode is used when a provider key is configured; otherwise the Claude
+// Code CLI streams through stream-json.
+func newAgentStreamChannel() *aichannel.Channel {
+	config := aichannel.Config{
+		Agent:        aichannel.AgentClaude,
+		SystemPrompt: agentStreamSystemPrompt,
+	}
+	if provider := aichannel.GetDefaultProvider(); provider != "" {
+		config.UseAPI = true
+		config.LLMProvider = provider
+	}
+	return aichannel.NewWithConfig(config)
+}
+
+// streamAgentResponse is the proxy.AgentStreamer used by daemon-managed proxies.
+// It forwards aichannel stream events to the browser as they are produced.
+func (d *Daemon) streamAgentResponse(ctx context.Context, req proxy.AgentStreamRequest, emit func(proxy.AgentStreamEvent)) error {
+	ch := newAgentStreamChannel()
+	if !ch.IsAvailable() {
+		log.Printf("[WARN] agent stream %s: no AI provider or agent CLI available", req.StreamID)
+	}
+
+	_, err := ch.Stream(ctx, req.Prompt, req.Context, func(ev aichannel.StreamEvent) {
+		switch ev.Type {
+		case aichannel.StreamEventChunk:
+			emit(proxy.AgentStreamEvent{Type: string(ev.Type), Chunk: ev.Text})
+		case aichannel.StreamEventEnd:
+			out := proxy.AgentStreamEvent{Type: string(ev.Type), Complete: true}
+			if ev.Response != nil {
+				out.Content = ev.Response.Result
+			}
+			emit(out)
+		default:
+			emit(proxy.AgentStreamEvent{Type: string(ev.Type), Content: ev.Text})
+		}
+	})
+	return err
+}
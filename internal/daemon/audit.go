@@ -0,0 +1,156 @@
+package daemon
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+// defaultAuditLimit is the number of entries AUDIT QUERY returns by default.
+const defaultAuditLimit = 100
+
+// maxAuditLine bounds the size of an entry read back from a log.
+const maxAuditLine = 1 << 20
+
+// DefaultAuditDir returns the directory of the audit logs, next to the
+// state file.
+func DefaultAuditDir() string {
+	return filepath.Join(filepath.Dir(DefaultStatePath()), "audit")
+}
+
+// auditLog keeps an append-only JSONL file of the actions performed through
+// agnt per project. Entries are never rewritten or trimmed.
+type auditLog struct {
+	dir string
+	mu  sync.Mutex // serializes appends
+}
+
+func newAuditLog(dir string) *auditLog {
+	if dir == "" {
+		dir = DefaultAuditDir()
+	}
+	return &auditLog{dir: dir}
+}
+
+// path returns the log of a project: its directory name and a hash of its
+// path, so projects with the same name don't share a log. Entries without a
+// project go to global.jsonl.
+func (l *auditLog) path(projectPath string) string {
+	if projectPath == "" {
+		return filepath.Join(l.dir, "global.jsonl")
+	}
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, filepath.Base(projectPath))
+	sum := sha256.Sum256([]byte(projectPath))
+	return filepath.Join(l.dir, fmt.Sprintf("%s-%x.jsonl", name, sum[:4]))
+}
+
+// append adds an entry to the log of its project.
+func (l *auditLog) append(entry api.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(l.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path(entry.ProjectPath), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// query returns the entries of a project's log that match q, oldest first.
+func (l *auditLog) query(q api.AuditQuery) (*api.AuditResult, error) {
+	result := &api.AuditResult{
+		ProjectPath: q.ProjectPath,
+		File:        l.path(q.ProjectPath),
+		Entries:     []api.AuditEntry{},
+	}
+
+	f, err := os.Open(result.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLine)
+	for scanner.Scan() {
+		var entry api.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			debug.Log("audit", "skipping invalid entry in %s: %v", result.File, err)
+			continue
+		}
+		if q.Matches(entry) {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	result.Total = len(result.Entries)
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > 0 && len(result.Entries) > limit {
+		result.Entries = result.Entries[len(result.Entries)-limit:]
+	}
+	result.Count = len(result.Entries)
+	return result, nil
+}
+
+// WriteAuditJSONL writes audit entries one JSON object per line, the format
+// of the logs.
+func WriteAuditJSONL(w io.Writer, entries []api.AuditEntry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSince parses a duration before now, such as "24h", or an RFC3339
+// time.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q: want a duration such as 24h or an RFC3339 time", s)
+	}
+	return t, nil
+}
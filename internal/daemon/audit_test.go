@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+func TestAuditLog_AppendQuery(t *testing.T) {
+	l := newAuditLog(t.TempDir())
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, e := range []api.AuditEntry{
+		{Tool: "proc", Action: "stop", Status: api.AuditStatusOK},
+		{Tool: "proxy", Action: "exec", Status: api.AuditStatusOK, Args: []byte(`{"code":"location.reload()"}`)},
+		{Tool: "proc", Action: "cleanup_port", Status: api.AuditStatusError, Error: "permission denied"},
+		{Tool: "daemon", Action: "stop_all", Status: api.AuditStatusOK},
+	} {
+		e.Time = start.Add(time.Duration(i) * time.Minute)
+		e.ProjectPath = "/home/me/app"
+		if err := l.append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.append(api.AuditEntry{Tool: "proc", Action: "stop", ProjectPath: "/home/me/other/app", Time: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := l.query(api.AuditQuery{ProjectPath: "/home/me/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Count != 4 || all.Total != 4 || all.Entries[0].Action != "stop" || all.Entries[3].Action != "stop_all" {
+		t.Fatalf("all entries = %+v", all)
+	}
+	if string(all.Entries[1].Args) != `{"code":"location.reload()"}` {
+		t.Errorf("args = %s", all.Entries[1].Args)
+	}
+
+	tests := []struct {
+		name string
+		q    api.AuditQuery
+		want []string
+	}{
+		{"tool", api.AuditQuery{Tool: "proc"}, []string{"stop", "cleanup_port"}},
+		{"status", api.AuditQuery{Status: api.AuditStatusError}, []string{"cleanup_port"}},
+		{"since", api.AuditQuery{Since: start.Add(2 * time.Minute)}, []string{"cleanup_port", "stop_all"}},
+		{"limit keeps the most recent", api.AuditQuery{Limit: 1}, []string{"stop_all"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.q.ProjectPath = "/home/me/app"
+			result, err := l.query(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range result.Entries {
+				got = append(got, e.Action)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("actions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Projects with the same name have their own logs
+	other, err := l.query(api.AuditQuery{ProjectPath: "/home/me/other/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Count != 1 || other.File == all.File {
+		t.Errorf("other project = %+v", other)
+	}
+	if filepath.Base(other.File)[:4] != "app-" {
+		t.Errorf("log file %s should be named after the project", other.File)
+	}
+
+	// A project without a log has no entries
+	none, err := l.query(api.AuditQuery{ProjectPath: "/home/me/new"})
+	if err != nil || none.Count != 0 || none.Entries == nil {
+		t.Errorf("new project = %+v, %v", none, err)
+	}
+}
+
+func TestAuditLog_Append_KeepsEntries(t *testing.T) {
+	l := newAuditLog(t.TempDir())
+	for i := 0; i < 3; i++ {
+		if err := l.append(api.AuditEntry{Tool: "run", Time: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(l.path(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("global log has %d lines, want 3", lines)
+	}
+
+	var buf bytes.Buffer
+	result, _ := l.query(api.AuditQuery{})
+	if err := WriteAuditJSONL(&buf, result.Entries); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(data) {
+		t.Errorf("export differs from the log:\n%s\nwant:\n%s", buf.String(), data)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if got, err := ParseSince("24h", now); err != nil || !got.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("ParseSince(24h) = %v, %v", got, err)
+	}
+	if got, err := ParseSince("2026-10-17T08:00:00Z", now); err != nil || got.Hour() != 8 {
+		t.Errorf("ParseSince(RFC3339) = %v, %v", got, err)
+	}
+	if _, err := ParseSince("yesterday", now); err == nil {
+		t.Error("ParseSince(yesterday) should fail")
+	}
+}
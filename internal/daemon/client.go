@@ -7,6 +7,7 @@ import (
 
 	"github.com/standardbeagle/agnt/internal/debug"
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/pkg/api"
 	"github.com/standardbeagle/go-cli-server/client"
 )
 
@@ -216,6 +217,11 @@ type ProxyStartConfig struct {
 	PublicURL   string                 `json:"public_url,omitempty"`
 	VerifyTLS   bool                   `json:"verify_tls,omitempty"`
 	Tunnel      *protocol.TunnelConfig `json:"tunnel,omitempty"`
+	Routes      []protocol.ProxyRoute  `json:"routes,omitempty"`
+	HTTPS       bool                   `json:"https,omitempty"`
+	HTTPSPort   int                    `json:"https_port,omitempty"`
+
+	BodyCapture *protocol.BodyCaptureConfig `json:"body_capture,omitempty"`
 }
 
 // ProxyStart starts a reverse proxy.
@@ -261,6 +267,16 @@ func (c *Client) ProxyToast(id string, toast protocol.ToastConfig) (map[string]i
 	return c.conn.Request(protocol.VerbProxy, protocol.SubVerbToast, id).WithJSON(toast).JSON()
 }
 
+// ProxyShare shares a proxy on the local network and returns its LAN URLs.
+func (c *Client) ProxyShare(id string, config protocol.ShareConfig) (map[string]interface{}, error) {
+	return c.conn.Request(protocol.VerbProxy, protocol.SubVerbShare, id).WithJSON(config).JSON()
+}
+
+// ProxyUnshare stops sharing a proxy on the local network.
+func (c *Client) ProxyUnshare(id string) error {
+	return c.conn.Request(protocol.VerbProxy, protocol.SubVerbUnshare, id).OK()
+}
+
 // ProxyLogQuery queries proxy logs.
 func (c *Client) ProxyLogQuery(proxyID string, filter protocol.LogQueryFilter) (map[string]interface{}, error) {
 	return c.conn.Request(protocol.VerbProxyLog, protocol.SubVerbQuery, proxyID).WithJSON(filter).JSON()
@@ -276,6 +292,12 @@ func (c *Client) ProxyLogStats(proxyID string) (map[string]interface{}, error) {
 	return c.conn.Request(protocol.VerbProxyLog, protocol.SubVerbStats, proxyID).JSON()
 }
 
+// ProxyLogBody fetches the full captured request or response body of a
+// logged HTTP request.
+func (c *Client) ProxyLogBody(proxyID, requestID, part string) (map[string]interface{}, error) {
+	return c.conn.Request(protocol.VerbProxyLog, protocol.SubVerbBody, proxyID, requestID, part).JSON()
+}
+
 // CurrentPageList lists active page sessions.
 func (c *Client) CurrentPageList(proxyID string) (map[string]interface{}, error) {
 	return c.conn.Request(protocol.VerbCurrentPage, protocol.SubVerbList, proxyID).JSON()
@@ -539,3 +561,17 @@ func (c *Client) ProcRestart(processID string) (map[string]interface{}, error) {
 func (c *Client) ProxyRestart(id string) (map[string]interface{}, error) {
 	return c.conn.Request(protocol.VerbProxy, protocol.SubVerbRestart, id).JSON()
 }
+
+// AuditRecord appends an entry to the audit log of its project.
+func (c *Client) AuditRecord(entry api.AuditEntry) error {
+	return c.conn.Request(protocol.VerbAudit, protocol.SubVerbRecord).WithJSON(entry).OK()
+}
+
+// AuditQuery returns the entries of a project's audit log matching q.
+func (c *Client) AuditQuery(q api.AuditQuery) (*api.AuditResult, error) {
+	var result api.AuditResult
+	if err := c.conn.Request(protocol.VerbAudit, protocol.SubVerbQuery).WithJSON(q).JSONInto(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
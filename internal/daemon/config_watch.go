@@ -0,0 +1,370 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/project"
+	"github.com/standardbeagle/agnt/internal/proxy"
+)
+
+// configWatchInterval is how often the .agnt.kdl files of projects with
+// sessions are checked for changes.
+const configWatchInterval = 2 * time.Second
+
+// watchedConfig is the state of a project's .agnt.kdl when it was last
+// applied.
+type watchedConfig struct {
+	path    string // "" when the project has no config file
+	modTime time.Time
+	size    int64
+	cfg     *config.AgntConfig
+}
+
+// CheckProjectConfig checks the .agnt.kdl of a project, with the project's
+// scripts and the chaos presets as the known references. path is "" when the
+// project has no config file.
+func CheckProjectConfig(projectPath string) (path string, issues []config.Issue, err error) {
+	path = config.FindAgntConfigFile(projectPath)
+	if path == "" {
+		return "", nil, nil
+	}
+	issues, err = config.CheckAgntConfigFile(path, ConfigCheckOptions(projectPath))
+	return path, issues, err
+}
+
+// ConfigCheckOptions returns the references an .agnt.kdl of a project is
+// checked against: the project's scripts, the chaos presets and the
+// languages of the global config.
+func ConfigCheckOptions(projectPath string) config.CheckOptions {
+	globalConfig := loadGlobalConfig()
+	opts := config.CheckOptions{
+		ChaosPresets: proxy.ListPresets(),
+		Languages:    globalConfig.LanguageNames(),
+	}
+	if proj, err := globalConfig.DetectProject(projectPath); err == nil {
+		opts.Scripts = project.GetCommandNames(proj)
+		// package.json scripts, which run-script runs by name
+		if scripts := proj.Metadata["scripts"]; scripts != "" {
+			opts.Scripts = append(opts.Scripts, strings.Split(scripts, ",")...)
+		}
+	}
+	return opts
+}
+
+// configFileState returns the config file of a project and its modification
+// time and size.
+func configFileState(projectPath string) (string, time.Time, int64) {
+	path := config.FindAgntConfigFile(projectPath)
+	if path == "" {
+		return "", time.Time{}, 0
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, 0
+	}
+	return path, info.ModTime(), info.Size()
+}
+
+// watchConfig starts watching the config of a project, as applied by
+// RunAutostart.
+func (d *Daemon) watchConfig(projectPath string, cfg *config.AgntConfig) {
+	path, modTime, size := configFileState(projectPath)
+
+	d.configWatchMu.Lock()
+	defer d.configWatchMu.Unlock()
+	d.configWatch[projectPath] = &watchedConfig{path: path, modTime: modTime, size: size, cfg: cfg}
+}
+
+// watchConfigs reloads the .agnt.kdl of projects with sessions when it
+// changes, until the daemon stops.
+func (d *Daemon) watchConfigs() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.reloadChangedConfigs()
+		}
+	}
+}
+
+// reloadChangedConfigs reloads changed configs and stops watching projects
+// without sessions.
+func (d *Daemon) reloadChangedConfigs() {
+	d.configWatchMu.Lock()
+	watched := make(map[string]*watchedConfig, len(d.configWatch))
+	for projectPath, w := range d.configWatch {
+		if len(d.sessionRegistry.List(projectPath, false)) == 0 {
+			delete(d.configWatch, projectPath)
+			continue
+		}
+		watched[projectPath] = w
+	}
+	d.configWatchMu.Unlock()
+
+	for projectPath, w := range watched {
+		path, modTime, size := configFileState(projectPath)
+		if path == w.path && modTime.Equal(w.modTime) && size == w.size {
+			continue
+		}
+		next := &watchedConfig{path: path, modTime: modTime, size: size, cfg: w.cfg}
+		if cfg, ok := d.reloadConfig(projectPath, path, w.cfg); ok {
+			next.cfg = cfg
+		}
+
+		d.configWatchMu.Lock()
+		if d.configWatch[projectPath] == w {
+			d.configWatch[projectPath] = next
+		}
+		d.configWatchMu.Unlock()
+	}
+}
+
+// reloadConfig applies a changed config file to a project. A config with
+// errors is reported and not applied; it is retried when the file changes
+// again.
+func (d *Daemon) reloadConfig(projectPath, path string, prev *config.AgntConfig) (*config.AgntConfig, bool) {
+	next := config.DefaultAgntConfig()
+	name := config.AgntConfigFileName
+	if path != "" {
+		name = filepath.Base(path)
+		issues, err := config.CheckAgntConfigFile(path, ConfigCheckOptions(projectPath))
+		if err != nil {
+			log.Printf("[WARN] Config reload for %s failed: %v", projectPath, err)
+			return nil, false
+		}
+		if config.HasErrors(issues) {
+			for _, issue := range issues {
+				log.Printf("[WARN] Config reload: %s", issue)
+			}
+			d.configToast(projectPath, prev, "error", name+" not reloaded", configErrorSummary(issues))
+			return nil, false
+		}
+		if next, err = config.LoadAgntConfigFile(path); err != nil {
+			log.Printf("[WARN] Config reload for %s failed: %v", projectPath, err)
+			return nil, false
+		}
+	}
+
+	changes := config.DiffAgntConfig(prev, next)
+	if changes.Empty() {
+		log.Printf("[INFO] Config reloaded for %s: no script or proxy changes", projectPath)
+		return next, true
+	}
+
+	summary, failed := d.applyConfigChanges(d.ctx, projectPath, changes, next)
+	log.Printf("[INFO] Config reloaded for %s: %s", projectPath, strings.Join(summary, "; "))
+	toastType := "success"
+	if failed {
+		toastType = "warning"
+	}
+	d.configToast(projectPath, next, toastType, name+" reloaded", strings.Join(summary, "\n"))
+	return next, true
+}
+
+// configErrorSummary describes the errors of a config for a toast.
+func configErrorSummary(issues []config.Issue) string {
+	var errs []config.Issue
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	first := errs[0]
+	msg := fmt.Sprintf("line %d: %s", first.Line, first.Message)
+	if len(errs) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(errs)-1)
+	}
+	return msg
+}
+
+// applyConfigChanges reconciles the running scripts and proxies of a project
+// with a new config: new autostart entries are started, changed ones that
+// are running (or set to autostart) are restarted and removed ones are
+// stopped. It returns a line per action and whether any of them failed.
+func (d *Daemon) applyConfigChanges(ctx context.Context, projectPath string, changes config.ConfigChanges, cfg *config.AgntConfig) ([]string, bool) {
+	var summary []string
+	failed := false
+	report := func(err error, format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		if err != nil {
+			msg += ": " + err.Error()
+			failed = true
+		}
+		summary = append(summary, msg)
+	}
+
+	// Scripts
+	for _, name := range changes.Scripts.Removed {
+		if d.stopConfigScript(ctx, projectPath, name) {
+			report(nil, "stopped script %s", name)
+		}
+	}
+	for _, name := range changes.Scripts.Changed {
+		script := cfg.Scripts[name]
+		running := d.stopConfigScript(ctx, projectPath, name)
+		if !running && !script.Autostart {
+			continue
+		}
+		verb := "started"
+		if running {
+			verb = "restarted"
+		}
+		err := d.autostartScript(ctx, name, script, projectPath, cfg.Proxies)
+		report(err, "%s script %s", verb, name)
+	}
+	for _, name := range changes.Scripts.Added {
+		if script := cfg.Scripts[name]; script.Autostart {
+			err := d.autostartScript(ctx, name, script, projectPath, cfg.Proxies)
+			report(err, "started script %s", name)
+		}
+	}
+
+	// Proxies
+	for _, name := range changes.Proxies.Removed {
+		if d.stopConfigProxy(ctx, projectPath, name) > 0 {
+			report(nil, "stopped proxy %s", name)
+		}
+	}
+	for _, name := range changes.Proxies.Changed {
+		stopped := d.stopConfigProxy(ctx, projectPath, name)
+		if started := d.startConfigProxy(ctx, projectPath, name, cfg.Proxies[name]); started {
+			verb := "started"
+			if stopped > 0 {
+				verb = "restarted"
+			}
+			report(nil, "%s proxy %s", verb, name)
+		} else if stopped > 0 {
+			report(nil, "stopped proxy %s", name)
+		}
+	}
+	for _, name := range changes.Proxies.Added {
+		if d.startConfigProxy(ctx, projectPath, name, cfg.Proxies[name]) {
+			report(nil, "started proxy %s", name)
+		}
+	}
+
+	if len(summary) == 0 {
+		summary = append(summary, "no running scripts or proxies affected")
+	}
+	return summary, failed
+}
+
+// stopConfigScript stops and removes the process of a config script,
+// together with the proxies created from its URLs. It reports whether the
+// script was running.
+func (d *Daemon) stopConfigScript(ctx context.Context, projectPath, name string) bool {
+	processID := makeProcessID(projectPath, name)
+	proc, err := d.hub.ProcessManager().Get(processID)
+	if err != nil {
+		return false
+	}
+	running := proc.IsRunning()
+	if running {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if err := d.hub.ProcessManager().Stop(stopCtx, processID); err != nil {
+			log.Printf("[WARN] Config reload: error stopping script %s: %v", processID, err)
+		}
+		cancel()
+	}
+	d.hub.ProcessManager().RemoveByPath(processID, projectPath)
+	d.urlTracker.ClearProcess(processID)
+	d.handleScriptStopped(ProxyEvent{Type: ScriptStopped, ScriptID: processID, Path: projectPath})
+	return running
+}
+
+// stopConfigProxy stops the proxies of a config entry: the explicit proxy
+// or the ones created from the URLs of its script. It returns how many
+// were stopped.
+func (d *Daemon) stopConfigProxy(ctx context.Context, projectPath, name string) int {
+	proxyID := makeProcessID(projectPath, name)
+	stopped := 0
+	if _, err := d.proxym.Get(proxyID); err == nil {
+		if err := d.proxym.Stop(ctx, proxyID); err != nil {
+			log.Printf("[WARN] Config reload: error stopping proxy %s: %v", proxyID, err)
+		}
+		stopped++
+	}
+
+	// Script-linked proxies are named after the proxy and the detected URL
+	prefix := proxyID + ":"
+	d.scriptProxyMu.Lock()
+	var linked []string
+	for scriptID, proxyIDs := range d.scriptProxies {
+		kept := proxyIDs[:0]
+		for _, id := range proxyIDs {
+			if strings.HasPrefix(id, prefix) {
+				linked = append(linked, id)
+			} else {
+				kept = append(kept, id)
+			}
+		}
+		d.scriptProxies[scriptID] = kept
+	}
+	d.scriptProxyMu.Unlock()
+
+	for _, id := range linked {
+		if err := d.proxym.Stop(ctx, id); err != nil {
+			log.Printf("[WARN] Config reload: error stopping proxy %s: %v", id, err)
+			continue
+		}
+		stopped++
+	}
+	return stopped
+}
+
+// startConfigProxy starts a config proxy: an explicit proxy set to
+// autostart, or the proxies for the URLs its script has already printed.
+// It reports whether anything was queued.
+func (d *Daemon) startConfigProxy(ctx context.Context, projectPath, name string, pc *config.ProxyConfig) bool {
+	if pc.Script == "" {
+		if !pc.Autostart || (pc.URL == "" && pc.Port <= 0 && pc.Target == "") {
+			return false
+		}
+		if err := d.autostartProxy(ctx, name, pc, projectPath); err != nil {
+			log.Printf("[WARN] Config reload: proxy %s: %v", name, err)
+			return false
+		}
+		return true
+	}
+
+	scriptID := makeProcessID(projectPath, pc.Script)
+	queued := false
+	for _, u := range d.urlTracker.GetURLs(scriptID) {
+		select {
+		case d.proxyEvents <- ProxyEvent{Type: URLDetected, ScriptID: scriptID, URL: u, Path: projectPath}:
+			queued = true
+		default:
+			log.Printf("[WARN] Proxy event channel full, cannot queue proxy %s for %s", name, u)
+		}
+	}
+	return queued
+}
+
+// configToast shows a toast on the browser pages of a project's proxies.
+func (d *Daemon) configToast(projectPath string, cfg *config.AgntConfig, toastType, title, message string) {
+	duration := 0
+	if cfg != nil && cfg.Toast != nil {
+		duration = cfg.Toast.Duration
+	}
+	for _, p := range d.proxym.List() {
+		if p.Path != projectPath {
+			continue
+		}
+		if _, err := p.BroadcastToast(toastType, title, message, duration); err != nil {
+			log.Printf("[DEBUG] Config toast for proxy %s failed: %v", p.ID, err)
+		}
+	}
+}
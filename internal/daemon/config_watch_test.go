@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/config"
+)
+
+func TestCheckProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	if path, issues, err := CheckProjectConfig(dir); path != "" || issues != nil || err != nil {
+		t.Fatalf("expected no config, got %q %v %v", path, issues, err)
+	}
+
+	pkg := `{"name": "app", "scripts": {"dev": "vite"}}`
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	kdl := "proxies {\n    app {\n        script \"dev\"\n        chaos {\n            preset \"flaky-apii\"\n        }\n    }\n    api {\n        script \"serve\"\n    }\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, config.AgntConfigFileName), []byte(kdl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// package.json scripts are valid references; presets are checked
+	path, issues, err := CheckProjectConfig(dir)
+	if err != nil {
+		t.Fatalf("CheckProjectConfig: %v", err)
+	}
+	if filepath.Base(path) != config.AgntConfigFileName {
+		t.Errorf("unexpected path %q", path)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	}
+	if issues[0].Line != 5 || !strings.Contains(issues[0].Message, `did you mean "flaky-api"`) {
+		t.Errorf("unexpected preset issue %v", issues[0])
+	}
+	if issues[1].Line != 9 || !strings.Contains(issues[1].Message, `unknown script "serve"`) {
+		t.Errorf("unexpected script issue %v", issues[1])
+	}
+}
+
+func TestConfigErrorSummary(t *testing.T) {
+	issues := []config.Issue{
+		{Line: 2, Severity: config.SeverityWarning, Message: "proxy \"x\" has no target"},
+		{Line: 4, Severity: config.SeverityError, Message: "unknown key \"prot\""},
+		{Line: 9, Severity: config.SeverityError, Message: "port expects an integer"},
+	}
+	want := `line 4: unknown key "prot" (and 1 more)`
+	if got := configErrorSummary(issues); got != want {
+		t.Errorf("configErrorSummary = %q, want %q", got, want)
+	}
+}
+
+func TestRunAutostartWatchesNormalizedPath(t *testing.T) {
+	dir := t.TempDir()
+	kdl := "proxies {\n    app {\n        target \"http://localhost:3000\"\n    }\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, config.AgntConfigFileName), []byte(kdl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(DaemonConfig{
+		SocketPath: filepath.Join(dir, "test.sock"),
+		AuditDir:   filepath.Join(dir, "audit"),
+	})
+	session := &Session{
+		Code:        "test-1",
+		ProjectPath: normalizePath(dir),
+		StartedAt:   time.Now(),
+		Status:      SessionStatusActive,
+		LastSeen:    time.Now(),
+	}
+	if err := d.sessionRegistry.Register(session); err != nil {
+		t.Fatal(err)
+	}
+
+	// A non-clean path, as a client may send it
+	d.RunAutostart(context.Background(), filepath.Join(dir, "sub", "..")+string(filepath.Separator))
+	d.reloadChangedConfigs()
+
+	d.configWatchMu.Lock()
+	defer d.configWatchMu.Unlock()
+	if _, ok := d.configWatch[session.ProjectPath]; !ok || len(d.configWatch) != 1 {
+		t.Errorf("expected the config of %q to stay watched, got %v", session.ProjectPath, d.configWatch)
+	}
+}
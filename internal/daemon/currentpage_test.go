@@ -4,15 +4,20 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/project"
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/internal/proxy"
 )
 
 func TestClient_CurrentPage_EndToEnd(t *testing.T) {
@@ -115,3 +120,119 @@ func TestClient_CurrentPage_EndToEnd(t *testing.T) {
 		t.Logf("Failed to stop proxy: %v", err)
 	}
 }
+
+// sameJSON reports whether a and b encode to the same JSON values.
+func sameJSON(t *testing.T, a, b interface{}) bool {
+	t.Helper()
+	var va, vb interface{}
+	for _, x := range []struct {
+		v   interface{}
+		out *interface{}
+	}{{a, &va}, {b, &vb}} {
+		data, err := json.Marshal(x.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, x.out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// The API form of a page session keeps the JSON of the proxy's session.
+func TestAPIPageSession(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	nav := proxy.HTTPLogEntry{ID: "req-1", Timestamp: start, Method: "GET", URL: "http://localhost:3000/"}
+	session := &proxy.PageSession{
+		ID:              "page-1",
+		URL:             "http://localhost:3000/",
+		StartTime:       start,
+		LastActivity:    start.Add(time.Minute),
+		Active:          true,
+		Navigations:     []proxy.HTTPLogEntry{nav},
+		DocumentRequest: &nav,
+		Errors:          []proxy.FrontendError{{ID: "err-1", Timestamp: start, Message: "boom"}},
+		ConsoleCount:    2,
+		Vitals: proxy.Vitals{
+			WebVitals:     map[string]proxy.WebVital{"LCP": {ID: "v1", Timestamp: start, Name: "LCP", Value: 1200, Rating: "good"}},
+			LongTaskCount: 1,
+		},
+	}
+	if !sameJSON(t, apiPageSession(session), session) {
+		data, _ := json.Marshal(apiPageSession(session))
+		t.Errorf("API session differs: %s", data)
+	}
+	if empty := (&proxy.PageSession{ID: "page-2"}); !sameJSON(t, apiPageSession(empty), empty) {
+		data, _ := json.Marshal(apiPageSession(empty))
+		t.Errorf("empty API session differs: %s", data)
+	}
+}
+
+// The API form of proxy statistics keeps the JSON of the proxy's.
+func TestAPIProxyStats(t *testing.T) {
+	stats := proxy.ProxyStats{
+		ID:            "app",
+		TargetURL:     "http://localhost:3000",
+		ListenAddr:    "127.0.0.1:45849",
+		Running:       true,
+		Uptime:        time.Minute,
+		TotalRequests: 3,
+		LoggerStats:   proxy.LoggerStats{TotalEntries: 3, MaxSize: 1000},
+		ShareURLs:     []string{"http://192.168.1.2:45849"},
+	}
+	if !sameJSON(t, apiProxyStats(stats), stats) {
+		t.Errorf("API stats differ from %+v", stats)
+	}
+
+	routes := []protocol.ProxyRoute{{Path: "/api", Target: "http://localhost:8080", StripPrefix: true}}
+	if got := protocolRoutes(apiRoutes(routes)); !reflect.DeepEqual(got, routes) {
+		t.Errorf("routes = %+v, want %+v", got, routes)
+	}
+}
+
+// The API form of log entries and groups keeps the JSON of the proxy's.
+func TestAPILogEntries(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	entries := []proxy.LogEntry{
+		{Type: proxy.LogTypeHTTP, HTTP: &proxy.HTTPLogEntry{
+			ID:               "req-1",
+			Timestamp:        start,
+			Method:           "POST",
+			URL:              "http://localhost:3000/graphql",
+			StatusCode:       200,
+			Duration:         120 * time.Millisecond,
+			ResponseBody:     `{"data":{}}`,
+			ResponseBodyInfo: &proxy.BodyInfo{Size: 11, Kind: "json"},
+			GraphQL:          &proxy.GraphQLInfo{Operations: []proxy.GraphQLOperation{{Type: "query", Name: "GetViewer"}}},
+			Timing:           &proxy.TimingInfo{TTFB: 100 * time.Millisecond},
+		}},
+		{Type: proxy.LogTypeError, Error: &proxy.FrontendError{ID: "err-1", Timestamp: start, Message: "boom"}},
+		{Type: proxy.LogTypeConsole, Console: &proxy.ConsoleMessage{ID: "c-1", Timestamp: start, Level: "warn", Message: "slow"}},
+	}
+	if !sameJSON(t, apiLogEntries(entries), entries) {
+		data, _ := json.Marshal(apiLogEntries(entries))
+		t.Errorf("API entries differ: %s", data)
+	}
+
+	groups := proxy.AggregateHTTP(entries, []string{proxy.GroupByOperation})
+	if !sameJSON(t, apiLogGroups(groups), groups) {
+		t.Errorf("API groups differ from %+v", groups)
+	}
+}
+
+// The API form of DETECT keeps the commands and issues of the project.
+func TestAPIDetectConversions(t *testing.T) {
+	commands := []project.CommandDef{{Name: "test", Command: "gotestsum", Args: []string{"--", "./..."}, Env: map[string]string{"CGO_ENABLED": "0"}, Source: project.SourceGlobal}}
+	if !sameJSON(t, apiCommands(commands), commands) {
+		t.Errorf("API commands differ from %+v", commands)
+	}
+
+	issues := []config.Issue{{File: ".agnt.kdl", Line: 4, Column: 9, Severity: config.SeverityError, Message: "unknown key"}}
+	if !sameJSON(t, apiConfigIssues(issues), issues) {
+		t.Errorf("API issues differ from %+v", issues)
+	}
+	if got := apiConfigIssues(nil); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty issue list, got %v", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,6 +22,7 @@ import (
 	"github.com/standardbeagle/agnt/internal/store"
 	"github.com/standardbeagle/agnt/internal/tunnel"
 	"github.com/standardbeagle/agnt/internal/updater"
+	"github.com/standardbeagle/agnt/pkg/api"
 	"github.com/standardbeagle/go-cli-server/hub"
 	"github.com/standardbeagle/go-cli-server/process"
 )
@@ -95,6 +97,18 @@ type DaemonConfig struct {
 	// UpdateCheckInterval is the interval between update checks.
 	// Default: 24 hours
 	UpdateCheckInterval time.Duration
+
+	// Remote configures the TCP listener for remote clients.
+	// If not enabled, the remote block of the global config is used.
+	Remote config.RemoteConfig
+
+	// Metrics configures the Prometheus endpoint.
+	// If not enabled, the metrics block of the global config is used.
+	Metrics config.MetricsConfig
+
+	// AuditDir is the directory of the per-project audit logs.
+	// If empty, uses DefaultAuditDir().
+	AuditDir string
 }
 
 // DefaultDaemonConfig returns sensible defaults.
@@ -144,6 +158,27 @@ type Daemon struct {
 	scriptProxies map[string][]string // scriptID -> []proxyID
 	scriptProxyMu sync.RWMutex
 
+	// Applied .agnt.kdl per project, reloaded when the file changes
+	configWatch   map[string]*watchedConfig
+	configWatchMu sync.Mutex
+
+	// Global config.kdl, for the languages projects are detected as
+	globalConfig *config.Config
+
+	// Events streamed to SUBSCRIBE clients
+	events *eventBus
+
+	// TCP listener for remote clients, if configured
+	remote *remoteListener
+
+	// Append-only log of the actions MCP clients performed, per project
+	audit *auditLog
+
+	// Prometheus endpoint, if configured, and the process counters it serves
+	processMetrics *processMetrics
+	metricsServer  *http.Server
+	metricsURL     string
+
 	// Update checker
 	updateChecker *updater.UpdateChecker
 
@@ -206,9 +241,15 @@ func New(config DaemonConfig) *Daemon {
 		pidTracker:        pidTracker,
 		proxyEvents:       make(chan ProxyEvent, 10), // Buffer 10 events
 		scriptProxies:     make(map[string][]string),
+		configWatch:       make(map[string]*watchedConfig),
+		globalConfig:      loadGlobalConfig(),
+		events:            newEventBus(),
+		processMetrics:    newProcessMetrics(),
+		audit:             newAuditLog(config.AuditDir),
 		ctx:               ctx,
 		cancel:            cancel,
 	}
+	d.events.observe = d.processMetrics.observe
 
 	// Create URLTracker with callbacks to emit proxy events
 	// Access ProcessManager through Hub
@@ -220,6 +261,8 @@ func New(config DaemonConfig) *Daemon {
 			projectPath = proc.ProjectPath
 		}
 
+		d.events.publish(api.Event{Type: api.EventProcessURL, ProcessID: processID, ProjectPath: projectPath, URL: url})
+
 		// Send event to proxy event handler (non-blocking send)
 		select {
 		case d.proxyEvents <- ProxyEvent{
@@ -251,6 +294,17 @@ func New(config DaemonConfig) *Daemon {
 	}
 	d.urlTracker = urlTracker
 
+	d.proxym.SetEventHandler(d.publishProxyEvent)
+	scheduler.onDelivered = func(task *ScheduledTask) {
+		d.events.publish(api.Event{
+			Type:        api.EventTaskDelivered,
+			TaskID:      task.ID,
+			SessionCode: task.SessionCode,
+			ProjectPath: task.ProjectPath,
+			Message:     task.Message,
+		})
+	}
+
 	// Initialize state manager if persistence is enabled
 	if config.EnableStatePersistence {
 		d.stateMgr = NewStateManager(StateManagerConfig{
@@ -317,6 +371,16 @@ func (d *Daemon) Start() error {
 	}
 	d.started = time.Now()
 
+	// Accept remote clients over TCP if configured
+	if err := d.startRemote(); err != nil {
+		log.Printf("[WARN] Remote listener disabled: %v", err)
+	}
+
+	// Serve metrics if configured
+	if err := d.startMetrics(); err != nil {
+		log.Printf("[WARN] Metrics endpoint disabled: %v", err)
+	}
+
 	// Clean up orphaned processes from previous crash
 	d.cleanupOrphans()
 
@@ -331,10 +395,18 @@ func (d *Daemon) Start() error {
 	// Start URL tracker for process URL detection
 	d.urlTracker.Start(d.ctx)
 
+	// Publish process started and exited events
+	d.wg.Add(1)
+	go d.watchProcesses()
+
 	// Start proxy event handler for event-driven proxy creation
 	d.wg.Add(1)
 	go d.handleProxyEvents()
 
+	// Reload .agnt.kdl files when they change
+	d.wg.Add(1)
+	go d.watchConfigs()
+
 	// Start update checker if enabled
 	if d.updateChecker != nil {
 		d.updateChecker.Start()
@@ -366,6 +438,11 @@ func (d *Daemon) restoreProxies() {
 			MaxLogSize:  pc.MaxLogSize,
 			AutoRestart: true,
 			Path:        pc.Path,
+			Routes:      pc.Routes,
+			HTTPS:       pc.HTTPS,
+			HTTPSPort:   pc.HTTPSPort,
+			BodyCapture: pc.BodyCapture,
+			Speech:      proxySpeech(d.globalConfig.Speech),
 		}
 
 		proxyServer, err := d.proxym.Create(d.ctx, config)
@@ -380,6 +457,7 @@ func (d *Daemon) restoreProxies() {
 		if overlayEndpoint != "" {
 			proxyServer.SetOverlayEndpoint(overlayEndpoint)
 		}
+		proxyServer.SetAgentStreamer(d.streamAgentResponse)
 
 		// Removed startup log: restored proxy %s -> %s on port %d
 	}
@@ -422,6 +500,14 @@ func (d *Daemon) Stop(ctx context.Context) error {
 	// Signal all goroutines to stop
 	d.cancel()
 
+	// Stop accepting remote clients and close their connections
+	if d.remote != nil {
+		d.remote.Close()
+	}
+
+	// Stop serving metrics
+	d.stopMetrics(ctx)
+
 	// Stop Hub (handles listener, clients, connections)
 	if err := d.hub.Stop(ctx); err != nil {
 		log.Printf("[Daemon] error stopping hub: %v", err)
@@ -806,6 +892,8 @@ func (d *Daemon) RunAutostart(ctx context.Context, projectPath string) *Autostar
 		log.Printf("[DEBUG] RunAutostart: projectPath is empty")
 		return result
 	}
+	// Sessions, the config watch and process IDs all key on the normalized path
+	projectPath = normalizePath(projectPath)
 
 	log.Printf("[DEBUG] RunAutostart: loading config from %s", projectPath)
 
@@ -825,6 +913,16 @@ func (d *Daemon) RunAutostart(ctx context.Context, projectPath string) *Autostar
 	log.Printf("[DEBUG] RunAutostart: config loaded, scripts=%d proxies=%d",
 		len(agntConfig.Scripts), len(agntConfig.Proxies))
 
+	// Report config errors; what could be parsed is still started
+	if _, issues, err := CheckProjectConfig(projectPath); err == nil {
+		for _, issue := range issues {
+			if issue.Severity == config.SeverityError {
+				result.Errors = append(result.Errors, "config: "+issue.String())
+			}
+		}
+	}
+	d.watchConfig(projectPath, agntConfig)
+
 	// Start scripts (pass proxy configs for port detection)
 	autostartScripts := agntConfig.GetAutostartScripts()
 	proxyConfigs := agntConfig.Proxies // All proxies, not just autostart ones
@@ -921,15 +1019,20 @@ func (d *Daemon) autostartScript(ctx context.Context, name string, script *confi
 		command = script.Command
 		args = script.Args
 	} else {
-		// No command - run as package.json script via detected package manager
-		proj, err := project.Detect(projectPath)
+		// No command - run the configured command of that name, or else as
+		// package.json script via detected package manager
+		proj, err := d.detectProject(projectPath)
 		if err != nil {
 			debug.Error("daemon", "project detection failed for %s: %v", projectPath, err)
 			return fmt.Errorf("project detection failed: %v", err)
 		}
 
-		switch proj.Type {
-		case project.ProjectNode:
+		cmd := project.GetCommandByName(proj, name)
+		switch {
+		case cmd != nil && cmd.Source != project.SourceBuiltin:
+			command = cmd.Command
+			args = cmd.Args
+		case proj.Type == project.ProjectNode:
 			pm := proj.PackageManager
 			if pm == "" {
 				pm = "npm"
@@ -941,10 +1044,10 @@ func (d *Daemon) autostartScript(ctx context.Context, name string, script *confi
 			} else {
 				args = []string{name}
 			}
-		case project.ProjectGo:
+		case proj.Type == project.ProjectGo:
 			command = "go"
 			args = []string{"run", name}
-		case project.ProjectPython:
+		case proj.Type == project.ProjectPython:
 			command = "python"
 			args = []string{"-m", name}
 		default:
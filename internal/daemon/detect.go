@@ -0,0 +1,26 @@
+package daemon
+
+import (
+	"log"
+
+	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/project"
+)
+
+// loadGlobalConfig loads the global config.kdl, which defines the languages
+// projects are detected as. A config that can't be loaded is logged and the
+// defaults are used, so detection keeps working.
+func loadGlobalConfig() *config.Config {
+	cfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		log.Printf("[WARN] Failed to load %s, using default languages: %v", config.GlobalConfigPath(), err)
+		return config.DefaultConfig()
+	}
+	return cfg
+}
+
+// detectProject detects the project at path with the global languages and
+// the project's .agnt.kdl.
+func (d *Daemon) detectProject(path string) (*project.Project, error) {
+	return d.globalConfig.DetectProject(path)
+}
@@ -0,0 +1,249 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/proxy"
+	"github.com/standardbeagle/agnt/pkg/api"
+	"github.com/standardbeagle/go-cli-server/process"
+)
+
+const (
+	// eventHistory is how many events are kept for subscribers that resume
+	// with a sequence number.
+	eventHistory = 1024
+
+	// subscriberBuffer is how many events a subscriber may fall behind
+	// before it is dropped.
+	subscriberBuffer = 256
+
+	// eventHeartbeatInterval is how long a subscription may be idle before
+	// a heartbeat is sent.
+	eventHeartbeatInterval = 30 * time.Second
+
+	// processWatchInterval is how often processes are polled for started
+	// and exited events.
+	processWatchInterval = 500 * time.Millisecond
+)
+
+// eventBus numbers the daemon's events, keeps the most recent ones and
+// sends them to the SUBSCRIBE streams.
+type eventBus struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []api.Event
+	subs    map[*eventSubscriber]struct{}
+
+	// observe, if set, is called with each event before subscribers get it.
+	// It must not block.
+	observe func(api.Event)
+}
+
+// eventSubscriber receives the events matching its filter on ch. The bus
+// closes ch when the subscriber falls behind.
+type eventSubscriber struct {
+	filter api.SubscribeRequest
+	ch     chan api.Event
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[*eventSubscriber]struct{})}
+}
+
+// publish numbers ev, keeps it and sends it to the matching subscribers. A
+// subscriber whose buffer is full is dropped rather than blocking the
+// daemon; it can resume from the last event it got.
+func (b *eventBus) publish(ev api.Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.ProjectPath != "" {
+		ev.ProjectPath = normalizePath(ev.ProjectPath)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	ev.Seq = b.seq
+	b.history = append(b.history, ev)
+	if len(b.history) > eventHistory {
+		b.history = b.history[len(b.history)-eventHistory:]
+	}
+	if b.observe != nil {
+		b.observe(ev)
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Matches(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// subscribe adds a subscriber. With req.Since set, it also returns the kept
+// events after it that the subscriber receives, preceded by a gap event
+// when some are no longer kept.
+func (b *eventBus) subscribe(req api.SubscribeRequest) (*eventSubscriber, []api.Event) {
+	if req.ProjectPath != "" {
+		req.ProjectPath = normalizePath(req.ProjectPath)
+	}
+	sub := &eventSubscriber{filter: req, ch: make(chan api.Event, subscriberBuffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+
+	if req.Since == 0 {
+		return sub, nil
+	}
+
+	var backlog []api.Event
+	since := req.Since
+	oldest := b.seq + 1
+	if len(b.history) > 0 {
+		oldest = b.history[0].Seq
+	}
+	switch {
+	case since > b.seq:
+		// The daemon restarted since: every kept event is new
+		backlog = append(backlog, api.Event{Type: api.EventGap, Time: time.Now()})
+		since = 0
+	case since+1 < oldest:
+		backlog = append(backlog, api.Event{Type: api.EventGap, Time: time.Now(), Missed: oldest - since - 1})
+	}
+	for _, ev := range b.history {
+		if ev.Seq > since && req.Matches(ev) {
+			backlog = append(backlog, ev)
+		}
+	}
+	return sub, backlog
+}
+
+// unsubscribe removes a subscriber, if it wasn't dropped already.
+func (b *eventBus) unsubscribe(sub *eventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// publishProxyEvent turns the events of proxies into daemon events.
+func (d *Daemon) publishProxyEvent(ev proxy.Event) {
+	out := api.Event{ProxyID: ev.ProxyID, ProjectPath: ev.Path}
+	switch ev.Type {
+	case proxy.EventCreated:
+		out.Type = api.EventProxyCreated
+	case proxy.EventStopped:
+		out.Type = api.EventProxyStopped
+	case proxy.EventCrashed:
+		out.Type = api.EventProxyCrashed
+		out.Error = ev.Error
+	case proxy.EventRestarted:
+		out.Type = api.EventProxyRestarted
+	case proxy.EventPublicURL:
+		out.Type = api.EventTunnelURL
+		out.URL = ev.URL
+	case proxy.EventLogged:
+		out.Type = api.EventProxyError
+		if ev.Entry.Type == proxy.LogTypePanelMessage {
+			out.Type = api.EventPanelMessage
+		}
+		out.Entry = ev.Entry
+	default:
+		return
+	}
+	d.events.publish(out)
+}
+
+// tunnelURLPublisher returns the OnURL callback of a daemon tunnel, which
+// publishes each new URL and keeps the linked proxy's public URL current.
+// The URL found before the callback is set is published with it.
+func (d *Daemon) tunnelURLPublisher(tunnelID, projectPath string, p *proxy.ProxyServer) func(url string) {
+	var mu sync.Mutex
+	var last string
+	return func(url string) {
+		if p != nil {
+			p.UpdatePublicURL(url)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if url == last {
+			return
+		}
+		last = url
+		ev := api.Event{Type: api.EventTunnelURL, TunnelID: tunnelID, ProjectPath: projectPath, URL: url}
+		if p != nil {
+			ev.ProxyID = p.ID
+		}
+		d.events.publish(ev)
+	}
+}
+
+// watchedProcess is the state of a process at the previous poll.
+type watchedProcess struct {
+	running     bool
+	projectPath string
+}
+
+// watchProcesses publishes process.started and process.exited events. The
+// process manager has no lifecycle hooks, so processes are polled like the
+// URL tracker does.
+func (d *Daemon) watchProcesses() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(processWatchInterval)
+	defer ticker.Stop()
+
+	procs := make(map[string]watchedProcess)
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			procs = d.pollProcesses(procs)
+		}
+	}
+}
+
+// pollProcesses publishes the changes since the previous poll and returns
+// the current state.
+func (d *Daemon) pollProcesses(prev map[string]watchedProcess) map[string]watchedProcess {
+	current := make(map[string]watchedProcess)
+	for _, p := range d.hub.ProcessManager().List() {
+		state := p.State()
+		exited := state == process.StateStopped || state == process.StateFailed
+		before, seen := prev[p.ID]
+		current[p.ID] = watchedProcess{running: !exited, projectPath: p.ProjectPath}
+
+		started := api.Event{Type: api.EventProcessStarted, ProcessID: p.ID, Command: p.Command, ProjectPath: p.ProjectPath}
+		switch {
+		case !exited && !before.running:
+			d.events.publish(started)
+		case exited && (!seen || before.running):
+			if !seen {
+				// Started and exited between polls
+				d.events.publish(started)
+			}
+			exitCode := p.ExitCode()
+			d.events.publish(api.Event{Type: api.EventProcessExited, ProcessID: p.ID, ProjectPath: p.ProjectPath, ExitCode: &exitCode})
+		}
+	}
+
+	// Processes removed while running exited without a known exit code
+	for id, before := range prev {
+		if _, ok := current[id]; !ok && before.running {
+			d.events.publish(api.Event{Type: api.EventProcessExited, ProcessID: id, ProjectPath: before.projectPath})
+		}
+	}
+	return current
+}
@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+func TestEventBus_Subscribe(t *testing.T) {
+	bus := newEventBus()
+	sub, backlog := bus.subscribe(api.SubscribeRequest{Topics: []string{"process"}})
+	defer bus.unsubscribe(sub)
+	if backlog != nil {
+		t.Errorf("Expected no backlog without since, got %v", backlog)
+	}
+
+	bus.publish(api.Event{Type: api.EventProxyCreated, ProxyID: "app"})
+	bus.publish(api.Event{Type: api.EventProcessStarted, ProcessID: "dev"})
+
+	ev := <-sub.ch
+	if ev.Type != api.EventProcessStarted || ev.Seq != 2 || ev.Time.IsZero() {
+		t.Errorf("Expected process.started with seq 2, got %+v", ev)
+	}
+	select {
+	case ev := <-sub.ch:
+		t.Errorf("Expected no more events, got %+v", ev)
+	default:
+	}
+}
+
+func TestEventBus_Resume(t *testing.T) {
+	bus := newEventBus()
+	for i := 0; i < 5; i++ {
+		bus.publish(api.Event{Type: api.EventProcessURL, ProcessID: "dev"})
+	}
+
+	sub, backlog := bus.subscribe(api.SubscribeRequest{Since: 3})
+	bus.unsubscribe(sub)
+	if len(backlog) != 2 || backlog[0].Seq != 4 || backlog[1].Seq != 5 {
+		t.Errorf("Expected events 4 and 5, got %+v", backlog)
+	}
+
+	// Events no longer kept are reported as a gap
+	for i := 0; i < eventHistory; i++ {
+		bus.publish(api.Event{Type: api.EventProcessURL, ProcessID: "dev"})
+	}
+	sub, backlog = bus.subscribe(api.SubscribeRequest{Since: 3})
+	bus.unsubscribe(sub)
+	if backlog[0].Type != api.EventGap || backlog[0].Missed != 2 {
+		t.Errorf("Expected a gap of 2 events, got %+v", backlog[0])
+	}
+	if len(backlog) != eventHistory+1 || backlog[1].Seq != 6 {
+		t.Errorf("Expected the kept events after the gap, got %d from seq %d", len(backlog)-1, backlog[1].Seq)
+	}
+
+	// A sequence number from before a daemon restart replays everything
+	sub, backlog = bus.subscribe(api.SubscribeRequest{Since: 1 << 40})
+	bus.unsubscribe(sub)
+	if backlog[0].Type != api.EventGap || backlog[0].Missed != 0 || len(backlog) != eventHistory+1 {
+		t.Errorf("Expected a gap and every kept event, got %d events starting with %+v", len(backlog), backlog[0])
+	}
+}
+
+func TestEventBus_DropsLaggingSubscriber(t *testing.T) {
+	bus := newEventBus()
+	sub, _ := bus.subscribe(api.SubscribeRequest{})
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.publish(api.Event{Type: api.EventProcessURL})
+	}
+
+	n := 0
+	for range sub.ch {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before the channel closed, got %d", subscriberBuffer, n)
+	}
+
+	// Unsubscribing a dropped subscriber is safe
+	bus.unsubscribe(sub)
+}
+
+func TestEventBus_ProjectFilter(t *testing.T) {
+	bus := newEventBus()
+	sub, _ := bus.subscribe(api.SubscribeRequest{ProjectPath: "/work/app/"})
+	defer bus.unsubscribe(sub)
+
+	bus.publish(api.Event{Type: api.EventProxyCreated, ProjectPath: "/work/other"})
+	bus.publish(api.Event{Type: api.EventProxyCreated, ProjectPath: "/work/app"})
+
+	ev := <-sub.ch
+	if ev.ProjectPath != normalizePath("/work/app") {
+		t.Errorf("Expected the event of /work/app, got %+v", ev)
+	}
+}
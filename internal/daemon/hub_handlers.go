@@ -15,10 +15,13 @@ import (
 	"time"
 
 	"github.com/standardbeagle/agnt/internal/automation"
+	"github.com/standardbeagle/agnt/internal/config"
 	"github.com/standardbeagle/agnt/internal/debug"
 	"github.com/standardbeagle/agnt/internal/project"
+	"github.com/standardbeagle/agnt/internal/protocol"
 	"github.com/standardbeagle/agnt/internal/proxy"
 	"github.com/standardbeagle/agnt/internal/tunnel"
+	"github.com/standardbeagle/agnt/pkg/api"
 	hubpkg "github.com/standardbeagle/go-cli-server/hub"
 	goprocess "github.com/standardbeagle/go-cli-server/process"
 	hubproto "github.com/standardbeagle/go-cli-server/protocol"
@@ -208,7 +211,22 @@ func (d *Daemon) registerAgntCommands() {
 		Handler:     d.hubHandleRestartAll,
 	})
 
-	log.Printf("[DEBUG] Registered %d agnt-specific commands with Hub", 14)
+	// SUBSCRIBE command
+	d.hub.RegisterCommand(hubpkg.CommandDefinition{
+		Verb:        "SUBSCRIBE",
+		Description: "Stream process, proxy, tunnel and task events",
+		Handler:     d.hubHandleSubscribe,
+	})
+
+	// AUDIT command
+	d.hub.RegisterCommand(hubpkg.CommandDefinition{
+		Verb:        "AUDIT",
+		SubVerbs:    []string{"RECORD", "QUERY"},
+		Description: "Record and query the audit log of agent actions",
+		Handler:     d.hubHandleAudit,
+	})
+
+	log.Printf("[DEBUG] Registered %d agnt-specific commands with Hub", 16)
 }
 
 // hubHandleProc handles the PROC command (overrides Hub's built-in).
@@ -259,27 +277,14 @@ func (d *Daemon) hubHandleProcStatus(ctx context.Context, conn *hubpkg.Connectio
 		return conn.WriteErr(hubproto.ErrNotFound, fmt.Sprintf("process %q not found", processID))
 	}
 
-	resp := map[string]interface{}{
-		"id":           proc.ID,
-		"command":      proc.Command,
-		"args":         proc.Args,
-		"state":        proc.State().String(),
-		"summary":      proc.Summary(),
-		"runtime":      formatDuration(proc.Runtime()),
-		"runtime_ms":   proc.Runtime().Milliseconds(),
-		"project_path": proc.ProjectPath,
-	}
-
+	resp := d.processInfo(proc)
+	resp.Args = proc.Args
 	if pid := proc.PID(); pid > 0 {
-		resp["pid"] = pid
-	}
-	if proc.State().String() == "stopped" || proc.State().String() == "failed" {
-		resp["exit_code"] = proc.ExitCode()
+		resp.PID = pid
 	}
-
-	// Add URLs from URL tracker
-	if urls := d.urlTracker.GetURLs(processID); len(urls) > 0 {
-		resp["urls"] = urls
+	if state := proc.State().String(); state == "stopped" || state == "failed" {
+		exitCode := proc.ExitCode()
+		resp.ExitCode = &exitCode
 	}
 
 	data, _ := json.Marshal(resp)
@@ -434,41 +439,45 @@ func (d *Daemon) hubHandleProcList(ctx context.Context, conn *hubpkg.Connection,
 		filteredProcs = filtered
 	}
 
-	entries := make([]map[string]interface{}, len(filteredProcs))
+	entries := make([]api.Process, len(filteredProcs))
 	for i, p := range filteredProcs {
-		entry := map[string]interface{}{
-			"id":           p.ID,
-			"command":      p.Command,
-			"state":        p.State().String(),
-			"summary":      p.Summary(),
-			"runtime":      formatDuration(p.Runtime()),
-			"runtime_ms":   p.Runtime().Milliseconds(),
-			"project_path": p.ProjectPath,
-		}
-		// Add URLs from URL tracker
-		if urls := d.urlTracker.GetURLs(p.ID); len(urls) > 0 {
-			entry["urls"] = urls
-		}
-		entries[i] = entry
+		entries[i] = d.processInfo(p)
 	}
 
-	resp := map[string]interface{}{
-		"count":           len(filteredProcs),
-		"processes":       entries,
-		"global":          dirFilter.Global,
-		"total_in_daemon": len(procs),
+	resp := api.ProcessList{
+		Count:         len(filteredProcs),
+		Processes:     entries,
+		Global:        dirFilter.Global,
+		TotalInDaemon: len(procs),
+		SessionCode:   sessionCode,
 	}
 	if projectPath != "" {
-		resp["project_path"] = normalizePath(projectPath)
-	}
-	if sessionCode != "" {
-		resp["session_code"] = sessionCode
+		resp.ProjectPath = normalizePath(projectPath)
 	}
 
 	data, _ := json.Marshal(resp)
 	return conn.WriteJSON(data)
 }
 
+// processInfo returns the status of a process shared by PROC STATUS and
+// PROC LIST.
+func (d *Daemon) processInfo(p *goprocess.ManagedProcess) api.Process {
+	info := api.Process{
+		ID:          p.ID,
+		Command:     p.Command,
+		State:       p.State().String(),
+		Summary:     p.Summary(),
+		Runtime:     formatDuration(p.Runtime()),
+		RuntimeMs:   p.Runtime().Milliseconds(),
+		ProjectPath: p.ProjectPath,
+	}
+	// Add URLs from URL tracker
+	if urls := d.urlTracker.GetURLs(p.ID); len(urls) > 0 {
+		info.URLs = urls
+	}
+	return info
+}
+
 // hubHandleProcCleanupPort handles PROC CLEANUP-PORT <port>.
 func (d *Daemon) hubHandleProcCleanupPort(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	if len(cmd.Args) < 1 {
@@ -521,16 +530,23 @@ func (d *Daemon) hubHandleDetect(ctx context.Context, conn *hubpkg.Connection, c
 		path = cmd.Args[0]
 	}
 
-	proj, err := project.Detect(path)
+	proj, err := d.detectProject(path)
 	if err != nil {
 		return conn.WriteErr(hubproto.ErrInternal, err.Error())
 	}
 
-	resp := map[string]interface{}{
-		"type":            proj.Type,
-		"path":            proj.Path,
-		"package_manager": proj.PackageManager,
-		"scripts":         project.GetCommandNames(proj),
+	resp := api.DetectResult{
+		Type:           string(proj.Type),
+		Path:           proj.Path,
+		PackageManager: proj.PackageManager,
+		Scripts:        project.GetCommandNames(proj),
+		// Each command with its source: built-in, global or project
+		Commands: apiCommands(proj.Commands),
+	}
+
+	// Problems in .agnt.kdl that loading would silently ignore
+	if configPath, issues, err := CheckProjectConfig(proj.Path); err == nil && configPath != "" {
+		resp.Config = &api.ConfigCheck{Path: configPath, Issues: apiConfigIssues(issues)}
 	}
 
 	data, err := json.Marshal(resp)
@@ -541,6 +557,28 @@ func (d *Daemon) hubHandleDetect(ctx context.Context, conn *hubpkg.Connection, c
 	return conn.WriteJSON(data)
 }
 
+// apiCommands converts the commands of a project to their API form.
+func apiCommands(commands []project.CommandDef) []api.Command {
+	if commands == nil {
+		return nil
+	}
+	result := make([]api.Command, len(commands))
+	for i, c := range commands {
+		result[i] = api.Command(c)
+	}
+	return result
+}
+
+// apiConfigIssues converts config issues to their API form, an empty list
+// if there are none.
+func apiConfigIssues(issues []config.Issue) []api.ConfigIssue {
+	result := make([]api.ConfigIssue, len(issues))
+	for i, issue := range issues {
+		result[i] = api.ConfigIssue(issue)
+	}
+	return result
+}
+
 // hubHandleProxy handles the PROXY command and its sub-verbs.
 func (d *Daemon) hubHandleProxy(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	debug.Log("daemon", "PROXY %s: args=%v", cmd.SubVerb, cmd.Args)
@@ -559,12 +597,16 @@ func (d *Daemon) hubHandleProxy(ctx context.Context, conn *hubpkg.Connection, cm
 		return d.hubHandleProxyExec(conn, cmd)
 	case "TOAST":
 		return d.hubHandleProxyToast(conn, cmd)
+	case "SHARE":
+		return d.hubHandleProxyShare(conn, cmd)
+	case "UNSHARE":
+		return d.hubHandleProxyUnshare(conn, cmd)
 	default:
 		return writeStructuredErr(conn, "daemon", &hubproto.StructuredError{
 			Code:         hubproto.ErrInvalidArgs,
 			Message:      "unknown PROXY sub-command",
 			Command:      "PROXY",
-			ValidActions: []string{"START", "STOP", "RESTART", "STATUS", "LIST", "EXEC", "TOAST"},
+			ValidActions: []string{"START", "STOP", "RESTART", "STATUS", "LIST", "EXEC", "TOAST", "SHARE", "UNSHARE"},
 		})
 	}
 }
@@ -593,13 +635,12 @@ func (d *Daemon) hubHandleProxyStart(ctx context.Context, conn *hubpkg.Connectio
 	bindAddress := ""
 	publicURL := ""
 	verifyTLS := false
+	var routes []protocol.ProxyRoute
+	https := false
+	httpsPort := -1
+	var bodyCapture *protocol.BodyCaptureConfig
 	if len(cmd.Data) > 0 {
-		var data struct {
-			Path        string `json:"path"`
-			BindAddress string `json:"bind_address"`
-			PublicURL   string `json:"public_url"`
-			VerifyTLS   bool   `json:"verify_tls"`
-		}
+		var data api.ProxyStartOptions
 		if err := json.Unmarshal(cmd.Data, &data); err == nil {
 			if data.Path != "" {
 				path = data.Path
@@ -607,8 +648,17 @@ func (d *Daemon) hubHandleProxyStart(ctx context.Context, conn *hubpkg.Connectio
 			bindAddress = data.BindAddress
 			publicURL = data.PublicURL
 			verifyTLS = data.VerifyTLS
+			routes = protocolRoutes(data.Routes)
+			https = data.HTTPS
+			if data.HTTPSPort > 0 {
+				httpsPort = data.HTTPSPort
+			}
+			bodyCapture = (*protocol.BodyCaptureConfig)(data.BodyCapture)
 		}
 	}
+	if err := proxy.ValidateRoutes(routes); err != nil {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, err.Error())
+	}
 
 	// Create proxy config
 	proxyConfig := proxy.ProxyConfig{
@@ -621,6 +671,11 @@ func (d *Daemon) hubHandleProxyStart(ctx context.Context, conn *hubpkg.Connectio
 		BindAddress: bindAddress,
 		PublicURL:   publicURL,
 		VerifyTLS:   verifyTLS,
+		Routes:      routes,
+		HTTPS:       https,
+		HTTPSPort:   httpsPort,
+		BodyCapture: bodyCapture,
+		Speech:      proxySpeech(d.globalConfig.Speech),
 	}
 
 	proxyServer, err := d.proxym.Create(ctx, proxyConfig)
@@ -644,6 +699,9 @@ func (d *Daemon) hubHandleProxyStart(ctx context.Context, conn *hubpkg.Connectio
 		log.Printf("[DEBUG] Set global overlay endpoint for proxy %s: %s", proxyID, endpoint)
 	}
 
+	// Stream agent responses to browser panels
+	proxyServer.SetAgentStreamer(d.streamAgentResponse)
+
 	// Persist proxy config
 	if d.stateMgr != nil {
 		d.stateMgr.AddProxy(PersistentProxyConfig{
@@ -652,17 +710,25 @@ func (d *Daemon) hubHandleProxyStart(ctx context.Context, conn *hubpkg.Connectio
 			Port:       port,
 			MaxLogSize: maxLogSize,
 			Path:       path,
+			Routes:     routes,
+			HTTPS:      https,
+			HTTPSPort:  proxyServer.HTTPSPort(),
+
+			BodyCapture: bodyCapture,
 		})
 	}
 
-	resp := map[string]interface{}{
-		"id":          proxyServer.ID,
-		"listen_addr": proxyServer.ListenAddr,
-		"target_url":  proxyServer.TargetURL.String(),
-		"status":      "running",
+	resp := api.Proxy{
+		ID:          proxyServer.ID,
+		ListenAddr:  proxyServer.ListenAddr,
+		TargetURL:   proxyServer.TargetURL.String(),
+		Status:      "running",
+		BindAddress: proxyServer.BindAddress,
+		Routes:      apiRoutes(proxyServer.Routes()),
 	}
-	if proxyServer.BindAddress != "" {
-		resp["bind_address"] = proxyServer.BindAddress
+	if httpsURL := proxyServer.HTTPSURL(); httpsURL != "" {
+		resp.HTTPSURL = httpsURL
+		resp.CACertPath = proxyServer.CACertPath()
 	}
 
 	data, _ := json.Marshal(resp)
@@ -709,18 +775,69 @@ func (d *Daemon) hubHandleProxyStatus(conn *hubpkg.Connection, cmd *hubproto.Com
 		return conn.WriteErr(hubproto.ErrNotFound, err.Error())
 	}
 
-	resp := map[string]interface{}{
-		"id":          p.ID,
-		"listen_addr": p.ListenAddr,
-		"target_url":  p.TargetURL.String(),
-		"status":      "running",
-		"stats":       p.Stats(),
+	resp := api.Proxy{
+		ID:         p.ID,
+		ListenAddr: p.ListenAddr,
+		TargetURL:  p.TargetURL.String(),
+		Status:     "running",
+		Routes:     apiRoutes(p.Routes()),
+		Stats:      apiProxyStats(p.Stats()),
 	}
 
 	data, _ := json.Marshal(resp)
 	return conn.WriteJSON(data)
 }
 
+// apiRoutes converts the routes of a proxy to their API form.
+func apiRoutes(routes []protocol.ProxyRoute) []api.ProxyRoute {
+	if routes == nil {
+		return nil
+	}
+	result := make([]api.ProxyRoute, len(routes))
+	for i, r := range routes {
+		result[i] = api.ProxyRoute(r)
+	}
+	return result
+}
+
+// protocolRoutes converts the routes of PROXY START to proxy routes.
+func protocolRoutes(routes []api.ProxyRoute) []protocol.ProxyRoute {
+	if routes == nil {
+		return nil
+	}
+	result := make([]protocol.ProxyRoute, len(routes))
+	for i, r := range routes {
+		result[i] = protocol.ProxyRoute(r)
+	}
+	return result
+}
+
+// apiProxyStats converts the statistics of a proxy to their API form.
+func apiProxyStats(stats proxy.ProxyStats) *api.ProxyStats {
+	return &api.ProxyStats{
+		ID:            stats.ID,
+		TargetURL:     stats.TargetURL,
+		ListenAddr:    stats.ListenAddr,
+		HTTPSURL:      stats.HTTPSURL,
+		Path:          stats.Path,
+		BindAddress:   stats.BindAddress,
+		PublicURL:     stats.PublicURL,
+		Running:       stats.Running,
+		Uptime:        stats.Uptime,
+		TotalRequests: stats.TotalRequests,
+		LoggerStats: api.LoggerStats{
+			TotalEntries:     stats.LoggerStats.TotalEntries,
+			AvailableEntries: stats.LoggerStats.AvailableEntries,
+			MaxSize:          stats.LoggerStats.MaxSize,
+			Dropped:          stats.LoggerStats.Dropped,
+		},
+		LastError:    stats.LastError,
+		RestartCount: stats.RestartCount,
+		AutoRestart:  stats.AutoRestart,
+		ShareURLs:    stats.ShareURLs,
+	}
+}
+
 // hubHandleProxyList handles PROXY LIST command.
 func (d *Daemon) hubHandleProxyList(conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	// Parse filter from command data
@@ -744,7 +861,7 @@ func (d *Daemon) hubHandleProxyList(conn *hubpkg.Connection, cmd *hubproto.Comma
 
 	proxies := d.proxym.List()
 
-	var result []map[string]interface{}
+	result := []api.Proxy{}
 	for _, p := range proxies {
 		proxyPath := normalizePath(p.Path)
 
@@ -753,19 +870,20 @@ func (d *Daemon) hubHandleProxyList(conn *hubpkg.Connection, cmd *hubproto.Comma
 			continue
 		}
 
-		result = append(result, map[string]interface{}{
-			"id":          p.ID,
-			"listen_addr": p.ListenAddr,
-			"target_url":  p.TargetURL.String(),
-			"status":      "running",
-			"running":     true,
-			"path":        p.Path,
+		result = append(result, api.Proxy{
+			ID:         p.ID,
+			ListenAddr: p.ListenAddr,
+			TargetURL:  p.TargetURL.String(),
+			Status:     "running",
+			Running:    true,
+			Path:       p.Path,
+			HTTPSURL:   p.HTTPSURL(),
 		})
 	}
 
-	data, _ := json.Marshal(map[string]interface{}{
-		"proxies": result,
-		"count":   len(result),
+	data, _ := json.Marshal(api.ProxyList{
+		Proxies: result,
+		Count:   len(result),
 	})
 	return conn.WriteJSON(data)
 }
@@ -802,17 +920,14 @@ func (d *Daemon) hubHandleProxyExec(conn *hubpkg.Connection, cmd *hubproto.Comma
 			return conn.WriteErr(hubproto.ErrInternal, "execution channel closed")
 		}
 
-		resp := map[string]interface{}{
-			"execution_id": execID,
-			"success":      result.Error == "",
-			"result":       result.Result,
-			"error":        result.Error,
-			"duration":     result.Duration.String(),
-		}
-
-		// Include file path for large results
-		if result.FilePath != "" {
-			resp["file_path"] = result.FilePath
+		// File path is set for large results
+		resp := api.ExecResult{
+			ExecutionID: execID,
+			Success:     result.Error == "",
+			Result:      result.Result,
+			Error:       result.Error,
+			Duration:    result.Duration.String(),
+			FilePath:    result.FilePath,
 		}
 
 		data, _ := json.Marshal(resp)
@@ -823,6 +938,66 @@ func (d *Daemon) hubHandleProxyExec(conn *hubpkg.Connection, cmd *hubproto.Comma
 	}
 }
 
+// hubHandleProxyShare handles PROXY SHARE command.
+// PROXY SHARE <id> with optional {"https": bool, "port": int}
+func (d *Daemon) hubHandleProxyShare(conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	if len(cmd.Args) < 1 {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, "PROXY SHARE requires: <id>")
+	}
+
+	p, err := d.getSessionScopedProxy(conn, cmd.Args[0])
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrNotFound, err.Error())
+	}
+
+	var config struct {
+		HTTPS bool `json:"https"`
+		Port  int  `json:"port"`
+	}
+	if len(cmd.Data) > 0 {
+		if err := json.Unmarshal(cmd.Data, &config); err != nil {
+			return conn.WriteErr(hubproto.ErrInvalidArgs, "invalid share config: "+err.Error())
+		}
+	}
+
+	info, err := p.Share(proxy.ShareOptions{HTTPS: config.HTTPS, Port: config.Port})
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrInternal, err.Error())
+	}
+
+	resp := map[string]interface{}{
+		"id":          p.ID,
+		"urls":        info.URLs,
+		"addresses":   info.Addresses,
+		"https":       info.HTTPS,
+		"listen_addr": info.ListenAddr,
+		"qr_terminal": info.QRTerminal,
+		"qr_svg":      info.QRSVG,
+	}
+	if info.CACertPath != "" {
+		resp["ca_cert_path"] = info.CACertPath
+		resp["ca_url"] = info.CAURL
+	}
+
+	data, _ := json.Marshal(resp)
+	return conn.WriteJSON(data)
+}
+
+// hubHandleProxyUnshare handles PROXY UNSHARE command.
+func (d *Daemon) hubHandleProxyUnshare(conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	if len(cmd.Args) < 1 {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, "PROXY UNSHARE requires: <id>")
+	}
+
+	p, err := d.getSessionScopedProxy(conn, cmd.Args[0])
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrNotFound, err.Error())
+	}
+
+	p.StopShare()
+	return conn.WriteOK("LAN share stopped")
+}
+
 // hubHandleProxyToast handles PROXY TOAST command.
 func (d *Daemon) hubHandleProxyToast(conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	debug.Log("daemon", "PROXY TOAST: args=%v dataLen=%d", cmd.Args, len(cmd.Data))
@@ -898,12 +1073,14 @@ func (d *Daemon) hubHandleProxyLog(ctx context.Context, conn *hubpkg.Connection,
 		return d.hubHandleProxyLogClear(conn, cmd)
 	case "STATS":
 		return d.hubHandleProxyLogStats(conn, cmd)
+	case "BODY":
+		return d.hubHandleProxyLogBody(conn, cmd)
 	default:
 		return writeStructuredErr(conn, "daemon", &hubproto.StructuredError{
 			Code:         hubproto.ErrInvalidArgs,
 			Message:      "unknown PROXYLOG sub-command",
 			Command:      "PROXYLOG",
-			ValidActions: []string{"QUERY", "SUMMARY", "CLEAR", "STATS"},
+			ValidActions: []string{"QUERY", "SUMMARY", "CLEAR", "STATS", "BODY"},
 		})
 	}
 }
@@ -921,17 +1098,61 @@ func (d *Daemon) hubHandleProxyLogQuery(conn *hubpkg.Connection, cmd *hubproto.C
 		return conn.WriteErr(hubproto.ErrNotFound, err.Error())
 	}
 
-	var filter proxy.LogFilter
+	var query protocol.LogQueryFilter
 	if len(cmd.Data) > 0 {
-		json.Unmarshal(cmd.Data, &filter)
+		if err := json.Unmarshal(cmd.Data, &query); err != nil {
+			return conn.WriteErr(hubproto.ErrInvalidArgs, "invalid query filter: "+err.Error())
+		}
+	}
+	filter, err := proxy.FilterFromQuery(query)
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, err.Error())
+	}
+	if err := proxy.ValidateGroupBy(query.GroupBy); err != nil {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, err.Error())
 	}
 
 	entries := p.Logger().Query(filter)
 
-	data, _ := json.Marshal(map[string]interface{}{"logs": entries})
+	resp := api.LogQueryResult{Logs: apiLogEntries(entries), Count: len(entries)}
+	if len(query.GroupBy) > 0 {
+		resp = api.LogQueryResult{
+			Groups: apiLogGroups(proxy.AggregateHTTP(entries, query.GroupBy)),
+			Count:  len(entries),
+		}
+	}
+
+	data, _ := json.Marshal(resp)
 	return conn.WriteJSON(data)
 }
 
+// apiLogEntries converts log entries to their API form, which has the same
+// JSON.
+func apiLogEntries(entries []proxy.LogEntry) []api.LogEntry {
+	if entries == nil {
+		return nil
+	}
+	result := make([]api.LogEntry, len(entries))
+	for i, entry := range entries {
+		if data, err := json.Marshal(entry); err == nil {
+			json.Unmarshal(data, &result[i])
+		}
+	}
+	return result
+}
+
+// apiLogGroups converts aggregated log groups to their API form.
+func apiLogGroups(groups []proxy.LogGroup) []api.LogGroup {
+	if groups == nil {
+		return nil
+	}
+	result := make([]api.LogGroup, len(groups))
+	for i, g := range groups {
+		result[i] = api.LogGroup(g)
+	}
+	return result
+}
+
 // hubHandleProxyLogSummary handles PROXYLOG SUMMARY command.
 func (d *Daemon) hubHandleProxyLogSummary(conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	if len(cmd.Args) < 1 {
@@ -992,6 +1213,35 @@ func (d *Daemon) hubHandleProxyLogStats(conn *hubpkg.Connection, cmd *hubproto.C
 	return conn.WriteJSON(data)
 }
 
+// hubHandleProxyLogBody handles PROXYLOG BODY command.
+func (d *Daemon) hubHandleProxyLogBody(conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	if len(cmd.Args) < 2 {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, "PROXYLOG BODY requires: <proxy_id> <request_id> [request|response]")
+	}
+
+	proxyID, requestID := cmd.Args[0], cmd.Args[1]
+	part := ""
+	if len(cmd.Args) > 2 {
+		part = strings.ToLower(cmd.Args[2])
+	}
+	if part != "" && part != "request" && part != "response" {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, "body must be request or response")
+	}
+
+	p, err := d.getSessionScopedProxy(conn, proxyID)
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrNotFound, err.Error())
+	}
+
+	body, err := p.HTTPBody(requestID, part)
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrNotFound, err.Error())
+	}
+
+	data, _ := json.Marshal(body)
+	return conn.WriteJSON(data)
+}
+
 // hubHandleCurrentPage handles the CURRENTPAGE command.
 func (d *Daemon) hubHandleCurrentPage(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	debug.Log("daemon", "CURRENTPAGE %s: args=%v", cmd.SubVerb, cmd.Args)
@@ -1029,9 +1279,12 @@ func (d *Daemon) hubHandleCurrentPageList(conn *hubpkg.Connection, cmd *hubproto
 
 	sessions := p.PageTracker().GetActiveSessions()
 
-	resp := map[string]interface{}{
-		"sessions": sessions,
-		"count":    len(sessions),
+	resp := api.PageSessionList{
+		Sessions: make([]*api.PageSession, len(sessions)),
+		Count:    len(sessions),
+	}
+	for i, session := range sessions {
+		resp.Sessions[i] = apiPageSession(session)
 	}
 
 	data, _ := json.Marshal(resp)
@@ -1057,7 +1310,7 @@ func (d *Daemon) hubHandleCurrentPageGet(conn *hubpkg.Connection, cmd *hubproto.
 		return conn.WriteErr(hubproto.ErrNotFound, "session not found")
 	}
 
-	data, _ := json.Marshal(session)
+	data, _ := json.Marshal(apiPageSession(session))
 	return conn.WriteJSON(data)
 }
 
@@ -1081,10 +1334,67 @@ func (d *Daemon) hubHandleCurrentPageSummary(conn *hubpkg.Connection, cmd *hubpr
 	}
 
 	// Return a summary of the session
-	data, _ := json.Marshal(session)
+	data, _ := json.Marshal(apiPageSession(session))
 	return conn.WriteJSON(data)
 }
 
+// apiPageSession converts a page session to its API form. The recorded
+// events keep the JSON of the proxy's types.
+func apiPageSession(s *proxy.PageSession) *api.PageSession {
+	resp := &api.PageSession{
+		ID:               s.ID,
+		URL:              s.URL,
+		BrowserSession:   s.BrowserSession,
+		PageTitle:        s.PageTitle,
+		StartTime:        s.StartTime,
+		LastActivity:     s.LastActivity,
+		Active:           s.Active,
+		InteractionCount: s.InteractionCount,
+		MutationCount:    s.MutationCount,
+		RouteCount:       s.RouteCount,
+		ServiceWorker:    s.ServiceWorker,
+		ConsoleCount:     s.ConsoleCount,
+		RejectionCount:   s.RejectionCount,
+		LongTaskCount:    s.LongTaskCount,
+		BlockingTimeMs:   s.BlockingTimeMs,
+	}
+	resp.Resources, _ = json.Marshal(s.Resources)
+	if len(s.Navigations) > 0 {
+		resp.Navigations, _ = json.Marshal(s.Navigations)
+	}
+	if s.DocumentRequest != nil {
+		resp.DocumentRequest, _ = json.Marshal(s.DocumentRequest)
+	}
+	if len(s.Errors) > 0 {
+		resp.Errors, _ = json.Marshal(s.Errors)
+	}
+	if s.Performance != nil {
+		resp.Performance, _ = json.Marshal(s.Performance)
+	}
+	if len(s.Interactions) > 0 {
+		resp.Interactions, _ = json.Marshal(s.Interactions)
+	}
+	if len(s.Mutations) > 0 {
+		resp.Mutations, _ = json.Marshal(s.Mutations)
+	}
+	if len(s.Routes) > 0 {
+		resp.Routes, _ = json.Marshal(s.Routes)
+	}
+	if len(s.Console) > 0 {
+		resp.Console, _ = json.Marshal(s.Console)
+	}
+	if len(s.Rejections) > 0 {
+		resp.Rejections, _ = json.Marshal(s.Rejections)
+	}
+	if len(s.WebVitals) > 0 {
+		resp.WebVitals = make(map[string]api.WebVital, len(s.WebVitals))
+		for name, v := range s.WebVitals {
+			resp.WebVitals[name] = api.WebVital(v)
+		}
+	}
+	return resp
+}
+
 // hubHandleCurrentPageClear handles CURRENTPAGE CLEAR command.
 func (d *Daemon) hubHandleCurrentPageClear(conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	if len(cmd.Args) < 1 {
@@ -1288,11 +1598,18 @@ func (d *Daemon) hubHandleTunnelStart(ctx context.Context, conn *hubpkg.Connecti
 	tunnelID := cmd.Args[0]
 
 	var config struct {
-		Provider   string `json:"provider"`
-		LocalPort  int    `json:"local_port"`
-		LocalHost  string `json:"local_host"`
-		ProxyID    string `json:"proxy_id"`
-		BinaryPath string `json:"binary_path"`
+		Provider       string   `json:"provider"`
+		LocalPort      int      `json:"local_port"`
+		LocalHost      string   `json:"local_host"`
+		ProxyID        string   `json:"proxy_id"`
+		BinaryPath     string   `json:"binary_path"`
+		Command        string   `json:"command"`
+		Args           []string `json:"args"`
+		AuthToken      string   `json:"auth_token"`
+		Region         string   `json:"region"`
+		SSHHost        string   `json:"ssh_host"`
+		AutoRestart    *bool    `json:"auto_restart"`
+		HealthInterval int      `json:"health_interval"`
 	}
 
 	if len(cmd.Data) > 0 {
@@ -1302,6 +1619,10 @@ func (d *Daemon) hubHandleTunnelStart(ctx context.Context, conn *hubpkg.Connecti
 	if config.Provider == "" {
 		return conn.WriteErr(hubproto.ErrInvalidArgs, "provider is required")
 	}
+	provider, err := tunnel.ParseProvider(config.Provider)
+	if err != nil {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, err.Error())
+	}
 	if config.LocalPort == 0 {
 		return conn.WriteErr(hubproto.ErrInvalidArgs, "local_port is required")
 	}
@@ -1309,18 +1630,45 @@ func (d *Daemon) hubHandleTunnelStart(ctx context.Context, conn *hubpkg.Connecti
 	// Get project path from session for session scoping
 	projectPath := d.getSessionProjectPath(conn)
 
+	autoRestart := true
+	if config.AutoRestart != nil {
+		autoRestart = *config.AutoRestart
+	}
+
 	tunnelConfig := tunnel.Config{
-		Provider:   tunnel.Provider(config.Provider),
-		LocalPort:  config.LocalPort,
-		LocalHost:  config.LocalHost,
-		BinaryPath: config.BinaryPath,
-		Path:       projectPath,
+		Provider:    tunnel.Provider(config.Provider),
+		LocalPort:   config.LocalPort,
+		LocalHost:   config.LocalHost,
+		BinaryPath:  config.BinaryPath,
+		Command:     config.Command,
+		Args:        config.Args,
+		AuthToken:   config.AuthToken,
+		Region:      config.Region,
+		SSHHost:     config.SSHHost,
+		AutoRestart: autoRestart,
+		Path:        projectPath,
+	}
+	if config.HealthInterval != 0 {
+		tunnelConfig.HealthInterval = time.Duration(config.HealthInterval) * time.Second
+	}
+
+	// Resolve the proxy up front so restarts can keep its public URL current
+	var p *proxy.ProxyServer
+	if config.ProxyID != "" {
+		if sp, err := d.getSessionScopedProxy(conn, config.ProxyID); err == nil {
+			p = sp
+		}
 	}
 
 	t, err := d.tunnelm.Start(ctx, tunnelID, tunnelConfig)
 	if err != nil {
 		return conn.WriteErr(hubproto.ErrInternal, err.Error())
 	}
+	onURL := d.tunnelURLPublisher(tunnelID, projectPath, p)
+	t.OnURL(onURL)
+	if url := t.PublicURL(); url != "" {
+		onURL(url)
+	}
 
 	// Wait for public URL
 	publicURL, err := t.WaitForURL(ctx)
@@ -1329,18 +1677,17 @@ func (d *Daemon) hubHandleTunnelStart(ctx context.Context, conn *hubpkg.Connecti
 	}
 
 	// Update proxy public URL if proxy_id specified
-	if config.ProxyID != "" {
-		if p, err := d.getSessionScopedProxy(conn, config.ProxyID); err == nil {
-			p.SetPublicURL(publicURL)
-		}
+	if p != nil {
+		p.UpdatePublicURL(publicURL)
 	}
 
 	resp := map[string]interface{}{
-		"id":         tunnelID,
-		"provider":   config.Provider,
-		"local_port": config.LocalPort,
-		"public_url": publicURL,
-		"status":     "running",
+		"id":           tunnelID,
+		"provider":     string(provider),
+		"local_port":   config.LocalPort,
+		"public_url":   publicURL,
+		"status":       "running",
+		"auto_restart": autoRestart,
 	}
 
 	data, _ := json.Marshal(resp)
@@ -1391,10 +1738,18 @@ func (d *Daemon) hubHandleTunnelStatus(conn *hubpkg.Connection, cmd *hubproto.Co
 		"public_url": info.PublicURL,
 		"local_addr": info.LocalAddr,
 		"path":       info.Path,
+		"restarts":   info.Restarts,
 	}
 	if info.Error != "" {
 		resp["error"] = info.Error
 	}
+	if info.LastHealthCheck != nil {
+		resp["last_health_check"] = info.LastHealthCheck
+		resp["health_failures"] = info.HealthFailures
+	}
+	if info.HealthError != "" {
+		resp["health_error"] = info.HealthError
+	}
 
 	data, _ := json.Marshal(resp)
 	return conn.WriteJSON(data)
@@ -1432,10 +1787,14 @@ func (d *Daemon) hubHandleTunnelList(conn *hubpkg.Connection, cmd *hubproto.Comm
 			"public_url": info.PublicURL,
 			"local_addr": info.LocalAddr,
 			"path":       info.Path,
+			"restarts":   info.Restarts,
 		}
 		if info.Error != "" {
 			entry["error"] = info.Error
 		}
+		if info.HealthError != "" {
+			entry["health_error"] = info.HealthError
+		}
 		entries[i] = entry
 	}
 
@@ -2406,6 +2765,64 @@ func (d *Daemon) hubHandleStatus(ctx context.Context, conn *hubpkg.Connection, c
 	return conn.WriteJSON(data)
 }
 
+// hubHandleSubscribe handles SUBSCRIBE -- {"topics": [...], "project_path": "...", "since": N}.
+// Each event is written as a JSON chunk until the client disconnects or the
+// daemon stops, which ends the stream. A subscriber that falls behind gets an
+// error and can subscribe again with since set to the last sequence number
+// it received.
+func (d *Daemon) hubHandleSubscribe(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	var req api.SubscribeRequest
+	if len(cmd.Data) > 0 {
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return conn.WriteErr(hubproto.ErrInvalidArgs, fmt.Sprintf("invalid subscribe request: %v", err))
+		}
+	}
+	debug.Log("daemon", "SUBSCRIBE: topics=%v project=%s since=%d", req.Topics, req.ProjectPath, req.Since)
+
+	sub, backlog := d.events.subscribe(req)
+	defer d.events.unsubscribe(sub)
+
+	last := req.Since
+	write := func(ev api.Event) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if ev.Seq > 0 {
+			last = ev.Seq
+		}
+		return conn.WriteChunk(data)
+	}
+	for _, ev := range backlog {
+		if err := write(ev); err != nil {
+			return err
+		}
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return conn.WriteEnd()
+		case <-d.ctx.Done():
+			return conn.WriteEnd()
+		case ev, ok := <-sub.ch:
+			if !ok {
+				return conn.WriteErr(hubproto.ErrInvalidState, fmt.Sprintf("subscriber fell behind; subscribe again with since=%d", last))
+			}
+			if err := write(ev); err != nil {
+				return err
+			}
+			heartbeat.Reset(eventHeartbeatInterval)
+		case <-heartbeat.C:
+			if err := write(api.Event{Type: api.EventHeartbeat, Time: time.Now()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // hubHandleAutomate handles the AUTOMATE command and its sub-verbs.
 func (d *Daemon) hubHandleAutomate(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
 	debug.Log("daemon", "AUTOMATE %s: args=%v", cmd.SubVerb, cmd.Args)
@@ -2456,6 +2873,7 @@ func (d *Daemon) hubHandleAutomateProcess(ctx context.Context, conn *hubpkg.Conn
 			Model       string  `json:"model,omitempty"`
 			MaxTokens   int     `json:"max_tokens,omitempty"`
 			Temperature float64 `json:"temperature,omitempty"`
+			Offline     bool    `json:"offline,omitempty"`
 		} `json:"options,omitempty"`
 	}
 	if err := json.Unmarshal(cmd.Data, &req); err != nil {
@@ -2481,6 +2899,7 @@ func (d *Daemon) hubHandleAutomateProcess(ctx context.Context, conn *hubpkg.Conn
 			Model:       req.Options.Model,
 			MaxTokens:   req.Options.MaxTokens,
 			Temperature: req.Options.Temperature,
+			Offline:     req.Options.Offline,
 		},
 	}
 
@@ -2505,6 +2924,7 @@ func (d *Daemon) hubHandleAutomateProcess(ctx context.Context, conn *hubpkg.Conn
 
 	resp["tokens_used"] = result.Tokens
 	resp["cost_usd"] = result.Cost
+	resp["offline"] = result.Offline
 
 	data, _ := json.Marshal(resp)
 	return conn.WriteJSON(data)
@@ -2527,6 +2947,7 @@ func (d *Daemon) hubHandleAutomateBatch(ctx context.Context, conn *hubpkg.Connec
 				Model       string  `json:"model,omitempty"`
 				MaxTokens   int     `json:"max_tokens,omitempty"`
 				Temperature float64 `json:"temperature,omitempty"`
+				Offline     bool    `json:"offline,omitempty"`
 			} `json:"options,omitempty"`
 		} `json:"tasks"`
 	}
@@ -2555,6 +2976,7 @@ func (d *Daemon) hubHandleAutomateBatch(ctx context.Context, conn *hubpkg.Connec
 				Model:       t.Options.Model,
 				MaxTokens:   t.Options.MaxTokens,
 				Temperature: t.Options.Temperature,
+				Offline:     t.Options.Offline,
 			},
 		}
 	}
@@ -2589,6 +3011,7 @@ func (d *Daemon) hubHandleAutomateBatch(ctx context.Context, conn *hubpkg.Connec
 			r["tokens_used"] = result.Tokens
 			r["cost_usd"] = result.Cost
 			r["duration"] = result.Duration.String()
+			r["offline"] = result.Offline
 			totalTokens += result.Tokens
 			totalCost += result.Cost
 		} else {
@@ -2613,6 +3036,84 @@ func (d *Daemon) hubHandleAutomateBatch(ctx context.Context, conn *hubpkg.Connec
 	return conn.WriteJSON(data)
 }
 
+// hubHandleAudit handles AUDIT RECORD -- <entry> and AUDIT QUERY -- <query>.
+func (d *Daemon) hubHandleAudit(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	debug.Log("daemon", "AUDIT %s", cmd.SubVerb)
+	switch cmd.SubVerb {
+	case "RECORD":
+		return d.hubHandleAuditRecord(conn, cmd)
+	case "QUERY":
+		return d.hubHandleAuditQuery(conn, cmd)
+	default:
+		return writeStructuredErr(conn, "daemon", &hubproto.StructuredError{
+			Code:         hubproto.ErrInvalidAction,
+			Message:      "unknown AUDIT sub-command",
+			Command:      "AUDIT",
+			Action:       cmd.SubVerb,
+			ValidActions: []string{"RECORD", "QUERY"},
+		})
+	}
+}
+
+// hubHandleAuditRecord appends an entry to the audit log of the project of
+// the connection's session, or to the global log if the connection has no
+// session. The session and project are those of the connection: an entry
+// naming others is refused, so a client can't write to the log of another
+// session. Remote clients can't record entries (see relay).
+func (d *Daemon) hubHandleAuditRecord(conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	var entry api.AuditEntry
+	if err := json.Unmarshal(cmd.Data, &entry); err != nil {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, fmt.Sprintf("invalid audit entry: %v", err))
+	}
+	if entry.Tool == "" {
+		return conn.WriteErr(hubproto.ErrMissingParam, "tool required")
+	}
+
+	sessionCode := conn.SessionCode()
+	projectPath := d.getSessionProjectPath(conn)
+	if entry.SessionCode != "" && entry.SessionCode != sessionCode {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, fmt.Sprintf("session %q is not the session of this connection", entry.SessionCode))
+	}
+	if entry.ProjectPath != "" && normalizePath(entry.ProjectPath) != projectPath {
+		return conn.WriteErr(hubproto.ErrInvalidArgs, fmt.Sprintf("project %q is not the project of this connection's session", entry.ProjectPath))
+	}
+	entry.SessionCode = sessionCode
+	entry.ProjectPath = projectPath
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	if err := d.audit.append(entry); err != nil {
+		return writeErr(conn, hubproto.ErrInternal, "daemon", "%v", err)
+	}
+	return conn.WriteOK("recorded")
+}
+
+// hubHandleAuditQuery returns the entries of a project's audit log, by
+// default of the connection's session.
+func (d *Daemon) hubHandleAuditQuery(conn *hubpkg.Connection, cmd *hubproto.Command) error {
+	var q api.AuditQuery
+	if len(cmd.Data) > 0 {
+		if err := json.Unmarshal(cmd.Data, &q); err != nil {
+			return conn.WriteErr(hubproto.ErrInvalidArgs, fmt.Sprintf("invalid audit query: %v", err))
+		}
+	}
+	if q.ProjectPath == "" {
+		q.ProjectPath = d.getSessionProjectPath(conn)
+	}
+	if q.ProjectPath != "" {
+		q.ProjectPath = normalizePath(q.ProjectPath)
+	}
+
+	result, err := d.audit.query(q)
+	if err != nil {
+		return writeErr(conn, hubproto.ErrInternal, "daemon", "%v", err)
+	}
+	data, _ := json.Marshal(result)
+	return conn.WriteJSON(data)
+}
+
 // hubHandleStopAll handles the STOP-ALL command.
 // Stops all running processes, proxies, and tunnels without shutting down the daemon.
 func (d *Daemon) hubHandleStopAll(ctx context.Context, conn *hubpkg.Connection, cmd *hubproto.Command) error {
@@ -2659,6 +3160,10 @@ func (d *Daemon) hubHandleRestartAll(ctx context.Context, conn *hubpkg.Connectio
 		MaxLogSize  int
 		ProjectPath string
 		BindAddress string
+		Routes      []protocol.ProxyRoute
+		HTTPS       bool
+		HTTPSPort   int
+		BodyCapture *protocol.BodyCaptureConfig
 	}
 
 	var procsToRestart []procManifest
@@ -2684,6 +3189,10 @@ func (d *Daemon) hubHandleRestartAll(ctx context.Context, conn *hubpkg.Connectio
 				MaxLogSize:  int(p.Logger().Stats().MaxSize),
 				ProjectPath: p.Path,
 				BindAddress: p.BindAddress,
+				Routes:      p.Routes(),
+				HTTPS:       p.HTTPSEnabled(),
+				HTTPSPort:   p.HTTPSPort(),
+				BodyCapture: p.BodyCapture(),
 			})
 		}
 	}
@@ -2722,6 +3231,11 @@ func (d *Daemon) hubHandleRestartAll(ctx context.Context, conn *hubpkg.Connectio
 			MaxLogSize:  pm.MaxLogSize,
 			Path:        pm.ProjectPath,
 			BindAddress: pm.BindAddress,
+			Routes:      pm.Routes,
+			HTTPS:       pm.HTTPS,
+			HTTPSPort:   pm.HTTPSPort,
+			BodyCapture: pm.BodyCapture,
+			Speech:      proxySpeech(d.globalConfig.Speech),
 		})
 		if err != nil {
 			log.Printf("[RESTART-ALL] Failed to restart proxy %s: %v", pm.ID, err)
@@ -2835,6 +3349,9 @@ func (d *Daemon) hubHandleProxyRestart(ctx context.Context, conn *hubpkg.Connect
 	maxLogSize := int(p.Logger().Stats().MaxSize)
 	projectPath := p.Path
 	bindAddress := p.BindAddress
+	routes := p.Routes()
+	https, httpsPort := p.HTTPSEnabled(), p.HTTPSPort()
+	bodyCapture := p.BodyCapture()
 
 	// Stop the proxy
 	if err := d.proxym.Stop(ctx, proxyID); err != nil {
@@ -2857,6 +3374,11 @@ func (d *Daemon) hubHandleProxyRestart(ctx context.Context, conn *hubpkg.Connect
 		MaxLogSize:  maxLogSize,
 		Path:        projectPath,
 		BindAddress: bindAddress,
+		Routes:      routes,
+		HTTPS:       https,
+		HTTPSPort:   httpsPort,
+		BodyCapture: bodyCapture,
+		Speech:      proxySpeech(d.globalConfig.Speech),
 	})
 	if err != nil {
 		return conn.WriteErr(hubproto.ErrInternal, fmt.Sprintf("failed to restart proxy: %v", err))
@@ -2870,6 +3392,11 @@ func (d *Daemon) hubHandleProxyRestart(ctx context.Context, conn *hubpkg.Connect
 			Port:       0, // Auto-assigned
 			MaxLogSize: maxLogSize,
 			Path:       projectPath,
+			Routes:     routes,
+			HTTPS:      https,
+			HTTPSPort:  httpsPort,
+
+			BodyCapture: bodyCapture,
 		})
 	}
 
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/pkg/api"
 )
 
 // TestHubIntegration_CommandDispatch verifies that commands are dispatched through Hub.
@@ -608,6 +609,74 @@ func TestHubIntegration_SessionCommands(t *testing.T) {
 	})
 }
 
+// TestHubIntegration_AuditRecord verifies that audit entries go to the log
+// of the connection's session, whatever session the client names.
+func TestHubIntegration_AuditRecord(t *testing.T) {
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "test.sock")
+
+	daemon := New(DaemonConfig{
+		SocketPath:   sockPath,
+		MaxClients:   10,
+		WriteTimeout: 5 * time.Second,
+		AuditDir:     filepath.Join(tmpDir, "audit"),
+	})
+
+	if err := daemon.Start(); err != nil {
+		t.Fatalf("Failed to start daemon: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		daemon.Stop(ctx)
+	}()
+
+	connect := func() *Client {
+		client := NewClient(WithSocketPath(sockPath))
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+
+	projectA := filepath.Join(tmpDir, "a")
+	projectB := filepath.Join(tmpDir, "b")
+	owner := connect()
+	if _, err := owner.SessionRegister("session-a", tmpDir, projectA, "test", nil); err != nil {
+		t.Fatalf("SessionRegister failed: %v", err)
+	}
+	other := connect()
+	if _, err := other.SessionRegister("session-b", tmpDir, projectB, "test", nil); err != nil {
+		t.Fatalf("SessionRegister failed: %v", err)
+	}
+
+	if err := other.AuditRecord(api.AuditEntry{Tool: "proc", Action: "stop", SessionCode: "session-a"}); err == nil {
+		t.Error("Expected an entry naming another session to be refused")
+	}
+	if err := other.AuditRecord(api.AuditEntry{Tool: "proc", Action: "stop", ProjectPath: projectA}); err == nil {
+		t.Error("Expected an entry naming another project to be refused")
+	}
+	if err := other.AuditRecord(api.AuditEntry{Tool: "proxy", Action: "exec"}); err != nil {
+		t.Fatalf("AuditRecord failed: %v", err)
+	}
+
+	a, err := owner.AuditQuery(api.AuditQuery{ProjectPath: projectA})
+	if err != nil {
+		t.Fatalf("AuditQuery failed: %v", err)
+	}
+	if a.Total != 0 {
+		t.Errorf("Expected no entries for session-a, got %+v", a.Entries)
+	}
+	b, err := owner.AuditQuery(api.AuditQuery{ProjectPath: projectB})
+	if err != nil {
+		t.Fatalf("AuditQuery failed: %v", err)
+	}
+	if b.Total != 1 || b.Entries[0].SessionCode != "session-b" || b.Entries[0].Tool != "proxy" {
+		t.Errorf("Expected the entry of session-b, got %+v", b.Entries)
+	}
+}
+
 // TestHubIntegration_CurrentPageCommands tests current page commands through Hub.
 func TestHubIntegration_CurrentPageCommands(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -0,0 +1,357 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/proxy"
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+const (
+	// prometheusContentType is the Prometheus text format, served by default.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// openMetricsContentType is served to scrapers that accept it.
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// processMetrics counts process starts and exits from the daemon's events,
+// since the process manager only keeps totals.
+type processMetrics struct {
+	mu     sync.Mutex
+	starts map[string]int64
+	exits  map[processExit]int64
+}
+
+// processExit is a process and the exit code it exited with, "unknown" if
+// it was removed while running.
+type processExit struct {
+	processID string
+	code      string
+}
+
+func newProcessMetrics() *processMetrics {
+	return &processMetrics{
+		starts: make(map[string]int64),
+		exits:  make(map[processExit]int64),
+	}
+}
+
+// observe counts process.started and process.exited events.
+func (m *processMetrics) observe(ev api.Event) {
+	switch ev.Type {
+	case api.EventProcessStarted:
+		m.mu.Lock()
+		m.starts[ev.ProcessID]++
+		m.mu.Unlock()
+	case api.EventProcessExited:
+		code := "unknown"
+		if ev.ExitCode != nil {
+			code = strconv.Itoa(*ev.ExitCode)
+		}
+		m.mu.Lock()
+		m.exits[processExit{ev.ProcessID, code}]++
+		m.mu.Unlock()
+	}
+}
+
+// metricsWriter writes metric families in the Prometheus text format or,
+// for scrapers that ask for it, OpenMetrics.
+type metricsWriter struct {
+	buf         bytes.Buffer
+	openMetrics bool
+}
+
+// family starts a metric family. Counter names end in _total; OpenMetrics
+// names the family without it.
+func (w *metricsWriter) family(name, typ, help string) {
+	if w.openMetrics && typ == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample with labels given as name, value pairs.
+func (w *metricsWriter) sample(name string, value float64, labels ...string) {
+	w.buf.WriteString(name)
+	if len(labels) > 0 {
+		w.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&w.buf, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		w.buf.WriteByte('}')
+	}
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(formatMetricValue(value))
+	w.buf.WriteByte('\n')
+}
+
+// metric writes a family with a single unlabeled sample.
+func (w *metricsWriter) metric(name, typ, help string, value float64) {
+	w.family(name, typ, help)
+	w.sample(name, value)
+}
+
+// bytes returns the exposition, terminated as the format requires.
+func (w *metricsWriter) bytes() []byte {
+	if w.openMetrics {
+		w.buf.WriteString("# EOF\n")
+	}
+	return w.buf.Bytes()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatMetricValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeMetrics writes the metrics of the daemon, its processes, proxies,
+// sessions and scheduler.
+func (d *Daemon) writeMetrics(w *metricsWriter) {
+	info := d.Info()
+
+	w.family("agnt_build_info", "gauge", "Version of the agnt daemon.")
+	w.sample("agnt_build_info", 1, "version", info.Version, "commit", info.GitCommit)
+	w.metric("agnt_uptime_seconds", "gauge", "Time since the daemon started.", info.Uptime.Seconds())
+	w.metric("agnt_clients", "gauge", "Connected clients.", float64(info.ClientCount))
+
+	// Processes
+	w.metric("agnt_processes_active", "gauge", "Running processes.", float64(info.ProcessInfo.Active))
+	w.metric("agnt_processes_started_total", "counter", "Processes started.", float64(info.ProcessInfo.TotalStarted))
+	w.metric("agnt_processes_failed_total", "counter", "Processes that failed.", float64(info.ProcessInfo.TotalFailed))
+	d.writeProcessMetrics(w)
+
+	// Proxies
+	w.metric("agnt_proxies_active", "gauge", "Running proxies.", float64(info.ProxyInfo.Active))
+	w.metric("agnt_proxies_started_total", "counter", "Proxies started.", float64(info.ProxyInfo.TotalStarted))
+	d.writeProxyMetrics(w)
+
+	w.metric("agnt_tunnels_active", "gauge", "Running tunnels.", float64(info.TunnelInfo.Active))
+
+	// Sessions and scheduled tasks
+	w.metric("agnt_sessions_active", "gauge", "Registered sessions.", float64(info.SessionInfo.ActiveCount))
+	w.metric("agnt_sessions_registered_total", "counter", "Sessions registered.", float64(info.SessionInfo.TotalRegistered))
+	w.metric("agnt_scheduler_pending_tasks", "gauge", "Scheduled tasks waiting for delivery.", float64(info.SchedulerInfo.PendingCount))
+	w.family("agnt_scheduler_tasks_total", "counter", "Scheduled tasks by outcome.")
+	for _, s := range []struct {
+		outcome string
+		count   int64
+	}{
+		{"scheduled", info.SchedulerInfo.TotalScheduled},
+		{"delivered", info.SchedulerInfo.TotalDelivered},
+		{"failed", info.SchedulerInfo.TotalFailed},
+		{"cancelled", info.SchedulerInfo.TotalCancelled},
+	} {
+		w.sample("agnt_scheduler_tasks_total", float64(s.count), "outcome", s.outcome)
+	}
+}
+
+// writeProcessMetrics writes the starts, restarts and exit codes of each
+// process seen since the daemon started.
+func (d *Daemon) writeProcessMetrics(w *metricsWriter) {
+	m := d.processMetrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.starts))
+	for id := range m.starts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	w.family("agnt_process_starts_total", "counter", "Times each process was started.")
+	for _, id := range ids {
+		w.sample("agnt_process_starts_total", float64(m.starts[id]), "process", id)
+	}
+	w.family("agnt_process_restarts_total", "counter", "Times each process was started again after it exited.")
+	for _, id := range ids {
+		w.sample("agnt_process_restarts_total", float64(max(0, m.starts[id]-1)), "process", id)
+	}
+
+	exits := make([]processExit, 0, len(m.exits))
+	for exit := range m.exits {
+		exits = append(exits, exit)
+	}
+	sort.Slice(exits, func(i, j int) bool {
+		if exits[i].processID != exits[j].processID {
+			return exits[i].processID < exits[j].processID
+		}
+		return exits[i].code < exits[j].code
+	})
+	w.family("agnt_process_exits_total", "counter", "Process exits by exit code.")
+	for _, exit := range exits {
+		w.sample("agnt_process_exits_total", float64(m.exits[exit]), "process", exit.processID, "code", exit.code)
+	}
+}
+
+// writeProxyMetrics writes the requests, latency, frontend errors, restarts
+// and chaos injections of each proxy.
+func (d *Daemon) writeProxyMetrics(w *metricsWriter) {
+	proxies := d.proxym.List()
+	sort.Slice(proxies, func(i, j int) bool { return proxies[i].ID < proxies[j].ID })
+
+	metrics := make([]proxy.ProxyMetrics, len(proxies))
+	for i, p := range proxies {
+		metrics[i] = p.Metrics()
+	}
+
+	w.family("agnt_proxy_up", "gauge", "Whether each proxy is serving.")
+	for _, p := range proxies {
+		up := 0.0
+		if p.IsRunning() {
+			up = 1
+		}
+		w.sample("agnt_proxy_up", up, "proxy", p.ID)
+	}
+
+	w.family("agnt_proxy_requests_total", "counter", "Proxied requests by status class; error is no response from the upstream.")
+	for i, p := range proxies {
+		for _, class := range proxy.StatusClasses {
+			w.sample("agnt_proxy_requests_total", float64(metrics[i].Requests[class]), "proxy", p.ID, "status", class)
+		}
+	}
+
+	w.family("agnt_proxy_request_duration_seconds", "histogram", "Time to the upstream's response; for streaming responses, to its headers.")
+	for i, p := range proxies {
+		m := metrics[i]
+		for b, bound := range proxy.LatencyBuckets {
+			w.sample("agnt_proxy_request_duration_seconds_bucket", float64(m.LatencyBuckets[b]), "proxy", p.ID, "le", formatMetricValue(bound))
+		}
+		w.sample("agnt_proxy_request_duration_seconds_bucket", float64(m.LatencyCount), "proxy", p.ID, "le", "+Inf")
+		w.sample("agnt_proxy_request_duration_seconds_sum", m.LatencySum.Seconds(), "proxy", p.ID)
+		w.sample("agnt_proxy_request_duration_seconds_count", float64(m.LatencyCount), "proxy", p.ID)
+	}
+
+	w.family("agnt_proxy_frontend_errors_total", "counter", "Frontend errors and unhandled promise rejections reported by pages.")
+	for i, p := range proxies {
+		w.sample("agnt_proxy_frontend_errors_total", float64(metrics[i].FrontendErrors), "proxy", p.ID, "kind", "error")
+		w.sample("agnt_proxy_frontend_errors_total", float64(metrics[i].Rejections), "proxy", p.ID, "kind", "rejection")
+	}
+
+	w.family("agnt_proxy_restarts_total", "counter", "Times each proxy restarted after its server failed.")
+	for i, p := range proxies {
+		w.sample("agnt_proxy_restarts_total", float64(metrics[i].Restarts), "proxy", p.ID)
+	}
+
+	w.family("agnt_proxy_chaos_affected_requests_total", "counter", "Requests chaos rules applied to.")
+	for i, p := range proxies {
+		w.sample("agnt_proxy_chaos_affected_requests_total", float64(metrics[i].Chaos.AffectedCount), "proxy", p.ID)
+	}
+	w.family("agnt_proxy_chaos_injections_total", "counter", "Chaos injected by kind.")
+	for i, p := range proxies {
+		c := metrics[i].Chaos
+		for _, s := range []struct {
+			kind  string
+			count int64
+		}{
+			{"error", c.ErrorsInjected},
+			{"drop", c.DropsInjected},
+			{"truncate", c.TruncatedCount},
+			{"reorder", c.ReorderedCount},
+			{"event_delay", c.EventsDelayed},
+			{"event_drop", c.EventsDropped},
+		} {
+			w.sample("agnt_proxy_chaos_injections_total", float64(s.count), "proxy", p.ID, "kind", s.kind)
+		}
+	}
+	w.family("agnt_proxy_chaos_latency_seconds_total", "counter", "Latency added by chaos rules.")
+	for i, p := range proxies {
+		w.sample("agnt_proxy_chaos_latency_seconds_total", float64(metrics[i].Chaos.LatencyInjected)/1000, "proxy", p.ID)
+	}
+}
+
+// handleMetrics serves the metrics, as OpenMetrics if the scraper accepts it.
+func (d *Daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	mw := &metricsWriter{openMetrics: strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")}
+	d.writeMetrics(mw)
+
+	contentType := prometheusContentType
+	if mw.openMetrics {
+		contentType = openMetricsContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(mw.bytes())
+}
+
+// startMetrics starts the metrics endpoint, if configured. It only listens
+// on loopback addresses, since the metrics name processes and projects.
+func (d *Daemon) startMetrics() error {
+	cfg := d.config.Metrics
+	if !cfg.Enabled() {
+		cfg = d.globalConfig.Metrics
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	if err := checkLoopback(cfg.Listen); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.Listen, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", d.handleMetrics)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	d.metricsServer = server
+	d.metricsURL = "http://" + listener.Addr().String() + "/metrics"
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			debug.Error("daemon", "metrics endpoint stopped: %v", err)
+		}
+	}()
+
+	log.Printf("Serving metrics at %s", d.metricsURL)
+	return nil
+}
+
+// stopMetrics shuts down the metrics endpoint, if running.
+func (d *Daemon) stopMetrics(ctx context.Context) {
+	if d.metricsServer != nil {
+		d.metricsServer.Shutdown(ctx)
+	}
+}
+
+// checkLoopback returns an error unless addr is a loopback address.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid metrics address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("metrics endpoint must listen on a loopback address, not %q", host)
+	}
+	return nil
+}
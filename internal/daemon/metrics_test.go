@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+func TestMetricsWriter(t *testing.T) {
+	w := &metricsWriter{}
+	w.family("agnt_proxy_requests_total", "counter", "Proxied requests.")
+	w.sample("agnt_proxy_requests_total", 3, "proxy", `dev"1`, "status", "2xx")
+	w.metric("agnt_uptime_seconds", "gauge", "Uptime.", 1.5)
+
+	want := `# HELP agnt_proxy_requests_total Proxied requests.
+# TYPE agnt_proxy_requests_total counter
+agnt_proxy_requests_total{proxy="dev\"1",status="2xx"} 3
+# HELP agnt_uptime_seconds Uptime.
+# TYPE agnt_uptime_seconds gauge
+agnt_uptime_seconds 1.5
+`
+	if got := string(w.bytes()); got != want {
+		t.Errorf("Prometheus output:\n%s\nwant:\n%s", got, want)
+	}
+
+	// OpenMetrics names counter families without _total and ends with # EOF
+	w = &metricsWriter{openMetrics: true}
+	w.family("agnt_processes_started_total", "counter", "Processes started.")
+	w.sample("agnt_processes_started_total", 2)
+	got := string(w.bytes())
+	if !strings.Contains(got, "# TYPE agnt_processes_started counter\n") {
+		t.Errorf("OpenMetrics family should drop _total:\n%s", got)
+	}
+	if !strings.Contains(got, "agnt_processes_started_total 2\n") || !strings.HasSuffix(got, "# EOF\n") {
+		t.Errorf("OpenMetrics output:\n%s", got)
+	}
+}
+
+func TestProcessMetrics_Observe(t *testing.T) {
+	m := newProcessMetrics()
+	zero, one := 0, 1
+	for _, ev := range []api.Event{
+		{Type: api.EventProcessStarted, ProcessID: "dev"},
+		{Type: api.EventProcessExited, ProcessID: "dev", ExitCode: &one},
+		{Type: api.EventProcessStarted, ProcessID: "dev"},
+		{Type: api.EventProcessExited, ProcessID: "dev", ExitCode: &zero},
+		{Type: api.EventProcessStarted, ProcessID: "test"},
+		{Type: api.EventProcessExited, ProcessID: "test"},
+		{Type: api.EventProxyCreated, ProxyID: "dev"},
+	} {
+		m.observe(ev)
+	}
+
+	if m.starts["dev"] != 2 || m.starts["test"] != 1 {
+		t.Errorf("starts = %v", m.starts)
+	}
+	for exit, want := range map[processExit]int64{
+		{"dev", "0"}:        1,
+		{"dev", "1"}:        1,
+		{"test", "unknown"}: 1,
+	} {
+		if got := m.exits[exit]; got != want {
+			t.Errorf("exits[%v] = %d, want %d", exit, got, want)
+		}
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:9464", "localhost:9464", "[::1]:9464"} {
+		if err := checkLoopback(addr); err != nil {
+			t.Errorf("checkLoopback(%q) = %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"0.0.0.0:9464", ":9464", "192.168.1.10:9464", "127.0.0.1"} {
+		if err := checkLoopback(addr); err == nil {
+			t.Errorf("checkLoopback(%q) should fail", addr)
+		}
+	}
+}
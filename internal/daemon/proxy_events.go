@@ -1,12 +1,16 @@
 package daemon
 
 import (
+	"cmp"
 	"fmt"
 	"log"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/protocol"
 	"github.com/standardbeagle/agnt/internal/proxy"
 )
 
@@ -87,16 +91,14 @@ func (d *Daemon) handleURLDetected(event ProxyEvent) {
 		}
 
 		// Create proxy
-		proxyServerConfig := proxy.ProxyConfig{
-			ID:          proxyID,
-			TargetURL:   event.URL,
-			ListenPort:  -1, // Auto-assign
-			MaxLogSize:  proxyConfig.MaxLogSize,
-			AutoRestart: true,
-			Path:        projectPath,
+		serverConfig, err := proxyServerConfig(proxyID, event.URL, projectPath, proxyConfig)
+		if err != nil {
+			log.Printf("[ERROR] Invalid config for proxy %s: %v", proxyID, err)
+			continue
 		}
+		serverConfig.Speech = proxySpeech(d.globalConfig.Speech)
 
-		server, err := d.proxym.Create(d.ctx, proxyServerConfig)
+		server, err := d.proxym.Create(d.ctx, serverConfig)
 		if err != nil {
 			log.Printf("[ERROR] Failed to create proxy %s: %v", proxyID, err)
 			continue
@@ -112,6 +114,9 @@ func (d *Daemon) handleURLDetected(event ProxyEvent) {
 			log.Printf("[DEBUG] Set global overlay endpoint for proxy %s: %s", proxyID, overlayEndpoint)
 		}
 
+		// Stream agent responses to browser panels
+		server.SetAgentStreamer(d.streamAgentResponse)
+
 		// Track script → proxy association
 		d.trackScriptProxy(event.ScriptID, proxyID)
 
@@ -150,16 +155,14 @@ func (d *Daemon) handleExplicitStart(event ProxyEvent) {
 	}
 
 	// Create proxy
-	proxyServerConfig := proxy.ProxyConfig{
-		ID:          event.ProxyID,
-		TargetURL:   targetURL,
-		ListenPort:  -1, // Auto-assign
-		MaxLogSize:  event.Config.MaxLogSize,
-		AutoRestart: true,
-		Path:        event.Path,
+	serverConfig, err := proxyServerConfig(event.ProxyID, targetURL, event.Path, event.Config)
+	if err != nil {
+		log.Printf("[ERROR] Invalid config for proxy %s: %v", event.ProxyID, err)
+		return
 	}
+	serverConfig.Speech = proxySpeech(d.globalConfig.Speech)
 
-	server, err := d.proxym.Create(d.ctx, proxyServerConfig)
+	server, err := d.proxym.Create(d.ctx, serverConfig)
 	if err != nil {
 		log.Printf("[ERROR] Failed to create proxy %s: %v", event.ProxyID, err)
 		return
@@ -181,6 +184,9 @@ func (d *Daemon) handleExplicitStart(event ProxyEvent) {
 		log.Printf("[DEBUG] Set global overlay endpoint for explicit proxy %s: %s", event.ProxyID, overlayEndpoint)
 	}
 
+	// Stream agent responses to browser panels
+	server.SetAgentStreamer(d.streamAgentResponse)
+
 	log.Printf("[DEBUG] Created explicit proxy %s targeting %s", event.ProxyID, targetURL)
 }
 
@@ -241,6 +247,182 @@ func (d *Daemon) clearScriptProxies(scriptID string) {
 	log.Printf("[DEBUG] Cleared proxy tracking for script %s", scriptID)
 }
 
+// proxyServerConfig builds the configuration of a proxy declared in .agnt.kdl,
+// for both explicitly started proxies and ones created from detected URLs.
+func proxyServerConfig(proxyID, targetURL, path string, pc *config.ProxyConfig) (proxy.ProxyConfig, error) {
+	chaos, err := proxyChaos(pc.Chaos)
+	if err != nil {
+		return proxy.ProxyConfig{}, err
+	}
+	return proxy.ProxyConfig{
+		ID:          proxyID,
+		TargetURL:   targetURL,
+		ListenPort:  -1, // Auto-assign
+		MaxLogSize:  pc.MaxLogSize,
+		AutoRestart: true,
+		Path:        path,
+		BindAddress: pc.BindAddress,
+		PublicURL:   pc.PublicURL,
+		VerifyTLS:   pc.VerifyTLS,
+		Tunnel:      proxyTunnel(pc.Tunnel),
+		Routes:      proxyRoutes(pc.Routes),
+		HTTPS:       pc.HTTPS,
+		HTTPSPort:   httpsPort(pc.HTTPSPort),
+		Chaos:       chaos,
+		Inject:      proxyInject(pc.Inject),
+	}, nil
+}
+
+// proxyTunnel converts a .agnt.kdl tunnel block to a tunnel configuration.
+func proxyTunnel(t *config.TunnelConfig) *protocol.TunnelConfig {
+	if t == nil || t.Provider == "" {
+		return nil
+	}
+	return &protocol.TunnelConfig{
+		Provider:  t.Provider,
+		Command:   t.Command,
+		Args:      t.Args,
+		AuthToken: t.AuthToken,
+		Region:    t.Region,
+		SSHHost:   t.SSHHost,
+	}
+}
+
+// proxySpeech converts the speech block of the global config to the speech
+// backend of a proxy, with unset values from the environment. Returns nil
+// without a speech block, so proxies read the environment per session.
+func proxySpeech(s config.SpeechConfig) *proxy.SpeechConfig {
+	if !s.IsSet() {
+		return nil
+	}
+	env := proxy.SpeechConfigFromEnv()
+	return &proxy.SpeechConfig{
+		Provider:        cmp.Or(s.Provider, env.Provider),
+		URL:             cmp.Or(s.URL, env.URL),
+		APIKey:          cmp.Or(s.APIKey, env.APIKey),
+		Model:           cmp.Or(s.Model, env.Model),
+		Language:        cmp.Or(s.Language, env.Language),
+		InterimInterval: s.InterimInterval,
+	}
+}
+
+// proxyChaos converts a .agnt.kdl chaos block to a chaos configuration: the
+// preset's rules followed by the block's own rules.
+func proxyChaos(c *config.ChaosConfig) (*proxy.ChaosConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	cfg := &proxy.ChaosConfig{}
+	if c.Preset != "" {
+		if cfg = proxy.GetPreset(c.Preset); cfg == nil {
+			presets := proxy.ListPresets()
+			sort.Strings(presets)
+			return nil, fmt.Errorf("unknown chaos preset %q (available: %s)", c.Preset, strings.Join(presets, ", "))
+		}
+	}
+	cfg.Enabled = c.Enabled == nil || *c.Enabled
+	if c.Seed != 0 {
+		cfg.Seed = c.Seed
+	}
+	if c.GlobalOdds > 0 {
+		cfg.GlobalOdds = c.GlobalOdds
+	}
+
+	for i, r := range c.Rules {
+		if r == nil {
+			continue
+		}
+		if r.Type == "" {
+			return nil, fmt.Errorf("chaos rule %d has no type", i+1)
+		}
+		var codes []int
+		for _, s := range splitList(r.ErrorCodes) {
+			code, err := strconv.Atoi(s)
+			if err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("chaos rule %d: invalid error code %q", i+1, s)
+			}
+			codes = append(codes, code)
+		}
+		id := r.Name
+		if id == "" {
+			id = fmt.Sprintf("config-%d", i+1)
+		}
+		cfg.Rules = append(cfg.Rules, &proxy.ChaosRule{
+			ID:               id,
+			Name:             r.Name,
+			Type:             proxy.ChaosType(r.Type),
+			Enabled:          true,
+			URLPattern:       r.URLPattern,
+			Methods:          splitList(strings.ToUpper(r.Methods)),
+			Probability:      r.Probability,
+			Upstream:         r.Upstream,
+			Operation:        r.Operation,
+			MinLatencyMs:     r.MinLatencyMs,
+			MaxLatencyMs:     r.MaxLatencyMs,
+			JitterMs:         r.JitterMs,
+			ErrorCodes:       codes,
+			ErrorMessage:     r.ErrorMessage,
+			BytesPerMs:       r.BytesPerMs,
+			DropAfterPercent: r.DropAfterPercent,
+			TruncatePercent:  r.TruncatePercent,
+		})
+	}
+	return cfg, nil
+}
+
+// proxyInject converts a .agnt.kdl inject block to an injection configuration.
+func proxyInject(c *config.InjectConfig) *proxy.InjectConfig {
+	if c == nil {
+		return nil
+	}
+	return &proxy.InjectConfig{
+		Disabled: c.Enabled != nil && !*c.Enabled,
+		Off:      c.Off(),
+	}
+}
+
+// splitList splits a comma-separated .agnt.kdl value.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// proxyRoutes converts .agnt.kdl route entries to proxy routes.
+func proxyRoutes(routes []*config.RouteConfig) []protocol.ProxyRoute {
+	if len(routes) == 0 {
+		return nil
+	}
+	result := make([]protocol.ProxyRoute, 0, len(routes))
+	for _, r := range routes {
+		if r == nil {
+			continue
+		}
+		result = append(result, protocol.ProxyRoute{
+			Name:        r.Name,
+			Path:        r.Path,
+			Pattern:     r.Pattern,
+			Target:      r.Target,
+			StripPrefix: r.StripPrefix,
+			HostHeader:  r.HostHeader,
+		})
+	}
+	return result
+}
+
+// httpsPort maps an unset .agnt.kdl https-port to the stable default.
+func httpsPort(port int) int {
+	if port <= 0 {
+		return -1
+	}
+	return port
+}
+
 // makeProxyIDFromURL creates a unique proxy ID from project path, proxy name, and URL.
 // Format: {projectPath}:{proxyName}:{host}:{port}
 func makeProxyIDFromURL(projectPath, proxyName, urlStr string) string {
@@ -449,3 +449,68 @@ proxies {
 		t.Errorf("Expected 1 proxy (duplicate skipped), got %d", len(proxies))
 	}
 }
+
+func TestProxyServerConfig(t *testing.T) {
+	off := false
+	pc := &config.ProxyConfig{
+		BindAddress: "0.0.0.0",
+		PublicURL:   "https://dev.example.com",
+		VerifyTLS:   true,
+		Tunnel:      &config.TunnelConfig{Provider: "cloudflared"},
+		Chaos: &config.ChaosConfig{
+			Preset: "flaky-api",
+			Seed:   42,
+			Rules: []*config.ChaosRuleConfig{
+				{Type: "http_error", URLPattern: "/api/checkout", Methods: "post, put", ErrorCodes: "502,503"},
+			},
+		},
+		Inject: &config.InjectConfig{Indicator: &off},
+	}
+
+	cfg, err := proxyServerConfig("app", "http://localhost:3000", "/project", pc)
+	if err != nil {
+		t.Fatalf("proxyServerConfig: %v", err)
+	}
+	if cfg.BindAddress != "0.0.0.0" || cfg.PublicURL != "https://dev.example.com" || !cfg.VerifyTLS {
+		t.Errorf("listen options not applied: %+v", cfg)
+	}
+	if cfg.Tunnel == nil || cfg.Tunnel.Provider != "cloudflared" {
+		t.Errorf("tunnel not applied: %+v", cfg.Tunnel)
+	}
+	if cfg.Inject == nil || cfg.Inject.Disabled || len(cfg.Inject.Off) != 1 || cfg.Inject.Off[0] != "indicator" {
+		t.Errorf("inject not applied: %+v", cfg.Inject)
+	}
+
+	preset := proxy.GetPreset("flaky-api")
+	if cfg.Chaos == nil || !cfg.Chaos.Enabled || cfg.Chaos.Seed != 42 || len(cfg.Chaos.Rules) != len(preset.Rules)+1 {
+		t.Fatalf("chaos not applied: %+v", cfg.Chaos)
+	}
+	rule := cfg.Chaos.Rules[len(cfg.Chaos.Rules)-1]
+	if rule.ID != "config-1" || rule.Type != proxy.ChaosHTTPError || !rule.Enabled ||
+		len(rule.Methods) != 2 || rule.Methods[0] != "POST" || len(rule.ErrorCodes) != 2 || rule.ErrorCodes[1] != 503 {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	// Mistakes are reported instead of starting a proxy without them
+	pc.Chaos = &config.ChaosConfig{Preset: "mobile-5g"}
+	if _, err := proxyServerConfig("app", "http://localhost:3000", "/project", pc); err == nil || !strings.Contains(err.Error(), "mobile-3g") {
+		t.Errorf("expected unknown preset error listing presets, got %v", err)
+	}
+	pc.Chaos = &config.ChaosConfig{Rules: []*config.ChaosRuleConfig{{Type: "http_error", ErrorCodes: "5xx"}}}
+	if _, err := proxyServerConfig("app", "http://localhost:3000", "/project", pc); err == nil {
+		t.Error("expected invalid error code to fail")
+	}
+}
+
+func TestProxySpeech(t *testing.T) {
+	if proxySpeech(config.SpeechConfig{}) != nil {
+		t.Error("no speech block should leave the environment to each session")
+	}
+
+	t.Setenv("AGNT_SPEECH_API_KEY", "env-key")
+	t.Setenv("AGNT_SPEECH_LANGUAGE", "fr")
+	got := proxySpeech(config.SpeechConfig{Provider: "whisper", URL: "http://127.0.0.1:8080", Language: "de"})
+	if got == nil || got.Provider != "whisper" || got.URL != "http://127.0.0.1:8080" || got.Language != "de" || got.APIKey != "env-key" {
+		t.Errorf("proxySpeech = %+v", got)
+	}
+}
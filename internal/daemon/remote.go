@@ -0,0 +1,508 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/certs"
+	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/lanshare"
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+// remoteAuthTimeout is how long a remote connection has to complete the TLS
+// handshake and AUTH.
+const remoteAuthTimeout = 10 * time.Second
+
+// remoteListener accepts hub protocol connections over TLS. After AUTH,
+// each connection is relayed to the daemon's socket, so the hub serves it
+// like a local client.
+type remoteListener struct {
+	listener   net.Listener
+	token      []byte
+	socketPath string
+	wg         sync.WaitGroup
+}
+
+// newRemoteListener listens on cfg.Listen. It refuses configurations that
+// would accept unauthenticated clients.
+func newRemoteListener(cfg config.RemoteConfig, socketPath string) (*remoteListener, error) {
+	if cfg.TokenFile == "" && cfg.ClientCAFile == "" {
+		return nil, errors.New("remote listener requires token-file, client-ca or both")
+	}
+
+	var token []byte
+	if cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = bytes.TrimSpace(data)
+		if len(token) == 0 {
+			return nil, fmt.Errorf("token file %s is empty", cfg.TokenFile)
+		}
+	}
+
+	tlsConfig, err := remoteTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := tls.Listen("tcp", cfg.Listen, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.Listen, err)
+	}
+
+	return &remoteListener{
+		listener:   listener,
+		token:      token,
+		socketPath: socketPath,
+	}, nil
+}
+
+// remoteTLSConfig returns the listener's TLS configuration. Without a
+// certificate file, the server certificate is issued by the local agnt CA.
+func remoteTLSConfig(cfg config.RemoteConfig) (*tls.Config, error) {
+	var tlsConfig *tls.Config
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	} else {
+		ca, err := certs.LoadOrCreate("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local CA: %w", err)
+		}
+		tlsConfig, err = ca.TLSConfig(remoteHosts(cfg.Listen)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue certificate: %w", err)
+		}
+	}
+
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in client CA %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// remoteHosts returns the names clients may reach the listener by: this
+// machine, the name containers use for their host, and the listen address.
+func remoteHosts(listen string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1", "host.docker.internal"}
+	if machine, err := os.Hostname(); err == nil && machine != "" {
+		hosts = append(hosts, machine, strings.ToLower(machine)+".local")
+	}
+
+	host, _, _ := net.SplitHostPort(listen)
+	switch ip := net.ParseIP(host); {
+	case host == "" || (ip != nil && ip.IsUnspecified()):
+		if addrs, err := lanshare.Addresses(); err == nil {
+			hosts = append(hosts, lanshare.Hosts(addrs)...)
+		}
+	case ip == nil || !ip.IsLoopback():
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// Addr returns the address the listener is bound to.
+func (rl *remoteListener) Addr() string {
+	return rl.listener.Addr().String()
+}
+
+// serve accepts connections until the listener is closed.
+func (rl *remoteListener) serve(ctx context.Context) {
+	for {
+		conn, err := rl.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			debug.Error("remote", "accept failed: %v", err)
+			continue
+		}
+
+		rl.wg.Add(1)
+		go func() {
+			defer rl.wg.Done()
+			rl.handle(ctx, conn)
+		}()
+	}
+}
+
+// Close stops accepting connections and waits for the open ones, which end
+// when ctx is done.
+func (rl *remoteListener) Close() error {
+	err := rl.listener.Close()
+	rl.wg.Wait()
+	return err
+}
+
+// handle authenticates a connection and relays it to the daemon's socket.
+func (rl *remoteListener) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	addr := conn.RemoteAddr().String()
+
+	conn.SetDeadline(time.Now().Add(remoteAuthTimeout))
+	parser := protocol.NewParser(conn)
+	auth, err := rl.authenticate(conn, parser)
+	if err != nil {
+		log.Printf("[WARN] Remote connection from %s refused: %v", addr, err)
+		conn.Write(protocol.FormatErr(protocol.ErrInvalidArgs, "authentication failed"))
+		return
+	}
+
+	local, err := Connect(rl.socketPath)
+	if err != nil {
+		debug.Error("remote", "failed to connect %s to the daemon: %v", addr, err)
+		conn.Write(protocol.FormatErr(protocol.ErrInternal, "daemon unavailable"))
+		return
+	}
+	defer local.Close()
+
+	if _, err := conn.Write(protocol.FormatOK("")); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	debug.Log("remote", "connection from %s authenticated (%d path mappings)", addr, len(auth.PathMap))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+		local.Close()
+	}()
+
+	relay(conn, parser, local, newPathMapper(auth.PathMap))
+}
+
+// authenticate completes the TLS handshake, which verifies the client
+// certificate if required, and checks the token of AUTH.
+func (rl *remoteListener) authenticate(conn net.Conn, parser *protocol.Parser) (*protocol.RemoteAuthRequest, error) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("TLS handshake: %w", err)
+		}
+	}
+
+	cmd, err := parser.ParseCommand()
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Verb != protocol.VerbAuth {
+		return nil, fmt.Errorf("expected %s, got %s", protocol.VerbAuth, cmd.Verb)
+	}
+
+	var req protocol.RemoteAuthRequest
+	if len(cmd.Data) > 0 {
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return nil, fmt.Errorf("invalid %s data: %w", protocol.VerbAuth, err)
+		}
+	}
+	if rl.token != nil && subtle.ConstantTimeCompare([]byte(req.Token), rl.token) != 1 {
+		return nil, errors.New("invalid token")
+	}
+	for _, m := range req.PathMap {
+		if m.Client == "" || !filepath.IsAbs(m.Daemon) {
+			return nil, fmt.Errorf("invalid path mapping %q -> %q", m.Client, m.Daemon)
+		}
+	}
+	return &req, nil
+}
+
+// relay forwards the commands of a remote client to the daemon and the
+// responses back, rewriting paths if the client mapped any. It returns when
+// either side closes.
+//
+// AUDIT RECORD is answered with an error rather than forwarded: the audit
+// log records what agents on this machine did, and a remote client could
+// write whatever it likes to it.
+func relay(remote net.Conn, commands *protocol.Parser, local net.Conn, paths *pathMapper) {
+	// Responses are written whole, so the relay's own errors can't land
+	// inside one
+	var writeMu sync.Mutex
+	write := func(data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, err := remote.Write(data)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer remote.Close()
+		responses := protocol.NewParser(local)
+		for {
+			resp, err := responses.ParseResponse()
+			if err != nil {
+				return
+			}
+			if err := write(paths.response(resp)); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The parser may hold commands sent right after AUTH, so commands are
+	// always parsed rather than copied.
+	for {
+		cmd, err := commands.ParseCommand()
+		if err != nil {
+			break
+		}
+		if cmd.Verb == protocol.VerbAudit && cmd.SubVerb == protocol.SubVerbRecord {
+			if err := write(protocol.FormatErr(protocol.ErrInvalidCommand, "AUDIT RECORD is not accepted from remote clients")); err != nil {
+				break
+			}
+			continue
+		}
+		paths.command(cmd)
+		if _, err := local.Write(protocol.FormatCommand(cmd)); err != nil {
+			break
+		}
+	}
+	local.Close()
+	wg.Wait()
+}
+
+// pathMapper rewrites the paths of a remote connection between the client's
+// directories and the daemon's. Paths are rewritten in command arguments and
+// in the path fields of JSON data.
+type pathMapper struct {
+	mappings []protocol.PathMapping
+}
+
+// newPathMapper returns a mapper for mappings, or nil if there are none.
+func newPathMapper(mappings []protocol.PathMapping) *pathMapper {
+	if len(mappings) == 0 {
+		return nil
+	}
+	m := &pathMapper{}
+	for _, mapping := range mappings {
+		m.mappings = append(m.mappings, protocol.PathMapping{
+			Client: trimTrailingSeparator(mapping.Client),
+			Daemon: trimTrailingSeparator(mapping.Daemon),
+		})
+	}
+	return m
+}
+
+func trimTrailingSeparator(path string) string {
+	if trimmed := strings.TrimRight(path, `/\`); trimmed != "" {
+		return trimmed
+	}
+	return path
+}
+
+// rewrite maps path by the longest matching directory, from the client's
+// side to the daemon's or back.
+func (m *pathMapper) rewrite(path string, toDaemon bool) string {
+	best, bestLen := path, -1
+	for _, mapping := range m.mappings {
+		from, to := mapping.Daemon, mapping.Client
+		if toDaemon {
+			from, to = mapping.Client, mapping.Daemon
+		}
+		if len(from) <= bestLen {
+			continue
+		}
+		switch {
+		case path == from:
+			best, bestLen = to, len(from)
+		case strings.HasPrefix(path, from) && (path[len(from)] == '/' || path[len(from)] == '\\'):
+			best, bestLen = to+path[len(from):], len(from)
+		}
+	}
+	return best
+}
+
+// pathFields are the JSON fields of the protocol that hold file system
+// paths.
+var pathFields = map[string]bool{
+	"path":                true,
+	"project_path":        true,
+	"cwd":                 true,
+	"dir":                 true,
+	"directory":           true,
+	"file":                true,
+	"file_path":           true,
+	"body_path":           true,
+	"request_body_path":   true,
+	"ca_cert_path":        true,
+	"overlay_path":        true,
+	"binary_path":         true,
+	"token_file":          true,
+	"baseline_image_path": true,
+	"current_image_path":  true,
+	"diff_image_path":     true,
+}
+
+// urlFields are the JSON fields whose "path" values are URL paths rather
+// than file system paths.
+var urlFields = map[string]bool{
+	"routes": true,
+}
+
+// rewriteJSON maps the values of the path fields of JSON data. Data that
+// isn't JSON, or has no paths to map, is returned as is.
+func (m *pathMapper) rewriteJSON(data []byte, toDaemon bool) []byte {
+	if m == nil {
+		return data
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return data
+	}
+
+	if !m.rewriteFields(v, toDaemon) {
+		return data
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return data
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// rewriteFields maps the path fields of the objects in v, in place, and
+// reports whether any changed.
+func (m *pathMapper) rewriteFields(v interface{}, toDaemon bool) bool {
+	changed := false
+	switch v := v.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			changed = m.rewriteFields(elem, toDaemon) || changed
+		}
+	case map[string]interface{}:
+		for key, elem := range v {
+			switch {
+			case pathFields[key]:
+				var c bool
+				v[key], c = m.rewritePaths(elem, toDaemon)
+				changed = changed || c
+			case !urlFields[key]:
+				changed = m.rewriteFields(elem, toDaemon) || changed
+			}
+		}
+	}
+	return changed
+}
+
+// rewritePaths maps the value of a path field: a path or a list of them.
+func (m *pathMapper) rewritePaths(v interface{}, toDaemon bool) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		mapped := m.rewrite(v, toDaemon)
+		return mapped, mapped != v
+	case []interface{}:
+		changed := false
+		for i, elem := range v {
+			if path, ok := elem.(string); ok {
+				v[i] = m.rewrite(path, toDaemon)
+				changed = changed || v[i] != path
+			}
+		}
+		return v, changed
+	}
+	return v, false
+}
+
+// command maps the paths of a client command to the daemon's. A nil mapper
+// leaves it as is.
+func (m *pathMapper) command(cmd *protocol.Command) {
+	if m == nil {
+		return
+	}
+	for i, arg := range cmd.Args {
+		cmd.Args[i] = m.rewrite(arg, true)
+	}
+	if len(cmd.Data) > 0 {
+		cmd.Data = m.rewriteJSON(cmd.Data, true)
+	}
+}
+
+// response formats a daemon response with its paths mapped to the client's.
+// A nil mapper formats it as is.
+func (m *pathMapper) response(resp *protocol.Response) []byte {
+	switch resp.Type {
+	case protocol.ResponseJSON:
+		return protocol.FormatJSON(m.rewriteJSON(resp.Data, false))
+	case protocol.ResponseChunk:
+		return protocol.FormatChunk(m.rewriteJSON(resp.Data, false))
+	case protocol.ResponseData:
+		return protocol.FormatData(resp.Data)
+	case protocol.ResponseErr:
+		return protocol.FormatErr(protocol.ErrorCode(resp.Code), resp.Message)
+	case protocol.ResponsePong:
+		return protocol.FormatPong()
+	case protocol.ResponseEnd:
+		return protocol.FormatEnd()
+	default:
+		return protocol.FormatOK(resp.Message)
+	}
+}
+
+// startRemote starts the TCP listener for remote clients, if configured.
+func (d *Daemon) startRemote() error {
+	cfg := d.config.Remote
+	if !cfg.Enabled() {
+		cfg = d.globalConfig.Remote
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	rl, err := newRemoteListener(cfg, d.hub.SocketPath())
+	if err != nil {
+		return err
+	}
+	d.remote = rl
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		rl.serve(d.ctx)
+	}()
+
+	log.Printf("Accepting remote clients on %s", rl.Addr())
+	return nil
+}
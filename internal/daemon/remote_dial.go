@@ -0,0 +1,243 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/go-cli-server/socket"
+)
+
+// RemoteScheme prefixes the address of a daemon reached over TCP.
+const RemoteScheme = "tcp://"
+
+// defaultRemoteDialTimeout bounds connecting to a remote daemon and AUTH.
+const defaultRemoteDialTimeout = 10 * time.Second
+
+// ErrRemoteAuth is returned when a remote daemon refuses the credentials.
+var ErrRemoteAuth = errors.New("remote daemon refused authentication")
+
+// RemoteDialConfig configures connections to a daemon's TCP listener.
+type RemoteDialConfig struct {
+	// Addr is the daemon's host:port
+	Addr string
+	// Token is the content of the daemon's token file, if it has one
+	Token string
+	// CAFile verifies the daemon's certificate, e.g. a copy of the daemon
+	// machine's agnt CA (agnt ca --path). Empty uses the system roots.
+	CAFile string
+	// CertFile and KeyFile are the client certificate, if the daemon
+	// requires one
+	CertFile string
+	KeyFile  string
+	// PathMap maps directories of this machine to the daemon's
+	PathMap []protocol.PathMapping
+	// DialTimeout bounds connecting and AUTH (0 = 10 seconds)
+	DialTimeout time.Duration
+}
+
+// ParseRemoteAddr returns the host:port of a tcp://host:port daemon
+// address. ok is false if addr is not a TCP address.
+func ParseRemoteAddr(addr string) (hostport string, ok bool, err error) {
+	hostport, ok = strings.CutPrefix(addr, RemoteScheme)
+	if !ok {
+		return "", false, nil
+	}
+	if _, _, err := net.SplitHostPort(hostport); err != nil {
+		return "", true, fmt.Errorf("invalid daemon address %q: %w", addr, err)
+	}
+	return hostport, true, nil
+}
+
+// ParsePathMapping parses a "client=daemon" path mapping, e.g.
+// "/workspace=/home/me/project".
+func ParsePathMapping(s string) (protocol.PathMapping, error) {
+	client, daemonPath, ok := strings.Cut(s, "=")
+	if !ok || client == "" || daemonPath == "" {
+		return protocol.PathMapping{}, fmt.Errorf("invalid path mapping %q: want client=daemon", s)
+	}
+	return protocol.PathMapping{Client: client, Daemon: daemonPath}, nil
+}
+
+// tlsConfig returns the TLS configuration for dialing the daemon.
+func (c RemoteDialConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read daemon CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in daemon CA %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// DialRemote connects to a daemon's TCP listener and authenticates. The
+// returned connection speaks the hub protocol like a socket connection.
+func DialRemote(cfg RemoteDialConfig) (net.Conn, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	timeout := cfg.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultRemoteDialTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", cfg.Addr, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon at %s: %w", cfg.Addr, err)
+	}
+
+	data, err := json.Marshal(protocol.RemoteAuthRequest{Token: cfg.Token, PathMap: cfg.PathMap})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(protocol.FormatCommand(&protocol.Command{Verb: protocol.VerbAuth, Data: data})); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to authenticate with daemon at %s: %w", cfg.Addr, err)
+	}
+	// The daemon sends nothing after the AUTH response until the next
+	// command, so the parser's buffer holds no more than the response.
+	resp, err := protocol.NewParser(conn).ParseResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to authenticate with daemon at %s: %w", cfg.Addr, err)
+	}
+	if resp.Type != protocol.ResponseOK {
+		conn.Close()
+		return nil, fmt.Errorf("%w at %s: %s", ErrRemoteAuth, cfg.Addr, resp.Message)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// bridgeCount numbers the bridges of this process.
+var bridgeCount atomic.Int32
+
+// remoteBridge listens on a private local socket and relays each connection
+// to a remote daemon, so clients built on socket paths can use it.
+type remoteBridge struct {
+	cfg  RemoteDialConfig
+	path string
+
+	mu       sync.Mutex
+	sm       *SocketManager
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+func newRemoteBridge(cfg RemoteDialConfig) *remoteBridge {
+	name := fmt.Sprintf("%s-remote-%d-%d", SocketName, os.Getpid(), bridgeCount.Add(1))
+	return &remoteBridge{cfg: cfg, path: socket.DefaultSocketPath(name)}
+}
+
+// SocketPath returns the local socket of the bridge.
+func (b *remoteBridge) SocketPath() string {
+	return b.path
+}
+
+// start checks that the remote daemon accepts the credentials and starts
+// listening. It does nothing if the bridge is already listening.
+func (b *remoteBridge) start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.listener != nil {
+		return nil
+	}
+
+	conn, err := DialRemote(b.cfg)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+
+	sm := NewSocketManager(SocketConfig{Path: b.path, Mode: 0600})
+	listener, err := sm.Listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", b.path, err)
+	}
+	b.sm = sm
+	b.listener = listener
+
+	b.wg.Add(1)
+	go b.serve(listener)
+	debug.Log("remote", "bridging %s to daemon at %s", b.path, b.cfg.Addr)
+	return nil
+}
+
+func (b *remoteBridge) serve(listener net.Listener) {
+	defer b.wg.Done()
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.forward(local)
+	}
+}
+
+// forward relays a local connection to a new connection to the daemon.
+func (b *remoteBridge) forward(local net.Conn) {
+	defer local.Close()
+
+	remote, err := DialRemote(b.cfg)
+	if err != nil {
+		debug.Error("remote", "%v", err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(remote, local)
+		remote.Close()
+		close(done)
+	}()
+	io.Copy(local, remote)
+	local.Close()
+	<-done
+}
+
+// Close stops the bridge and removes its socket. Relayed connections end
+// when their client closes them.
+func (b *remoteBridge) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.listener == nil {
+		return nil
+	}
+	b.listener.Close()
+	b.wg.Wait()
+	err := b.sm.Close()
+	b.listener = nil
+	return err
+}
@@ -0,0 +1,235 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/standardbeagle/agnt/internal/certs"
+	"github.com/standardbeagle/agnt/internal/config"
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+func TestPathMapper_Rewrite(t *testing.T) {
+	m := newPathMapper([]protocol.PathMapping{
+		{Client: "/workspace/", Daemon: "/home/me/project"},
+		{Client: "/workspace/web", Daemon: "/srv/web"},
+	})
+
+	tests := []struct {
+		path     string
+		toDaemon bool
+		want     string
+	}{
+		{"/workspace", true, "/home/me/project"},
+		{"/workspace/cmd/main.go", true, "/home/me/project/cmd/main.go"},
+		{"/workspace/web/src", true, "/srv/web/src"},
+		{"/workspaces/other", true, "/workspaces/other"},
+		{"dev", true, "dev"},
+		{"/home/me/project/go.mod", false, "/workspace/go.mod"},
+		{"/srv/web", false, "/workspace/web"},
+		{"/home/me/projects", false, "/home/me/projects"},
+	}
+	for _, tt := range tests {
+		if got := m.rewrite(tt.path, tt.toDaemon); got != tt.want {
+			t.Errorf("rewrite(%q, %v) = %q, want %q", tt.path, tt.toDaemon, got, tt.want)
+		}
+	}
+
+	if newPathMapper(nil) != nil {
+		t.Error("no mappings should need no mapper")
+	}
+}
+
+func TestPathMapper_Command(t *testing.T) {
+	m := newPathMapper([]protocol.PathMapping{{Client: "/workspace", Daemon: "/home/me/project"}})
+
+	cmd := &protocol.Command{
+		Verb: protocol.VerbDetect,
+		Args: []string{"/workspace/app"},
+		Data: []byte(`{"path":"/workspace","routes":[{"path":"/workspace/api"}],"port":8080,"html":"<b>","code":"/workspace/x","items":[{"project_path":"/workspace/a"}]}`),
+	}
+	m.command(cmd)
+
+	if cmd.Args[0] != "/home/me/project/app" {
+		t.Errorf("arg = %q", cmd.Args[0])
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(cmd.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["path"] != "/home/me/project" || data["port"] != float64(8080) || data["html"] != "<b>" {
+		t.Errorf("data = %s", cmd.Data)
+	}
+	// Only path fields are mapped; route paths are URL paths
+	if data["code"] != "/workspace/x" {
+		t.Errorf("code = %v, want it unchanged", data["code"])
+	}
+	if route := data["routes"].([]interface{})[0].(map[string]interface{}); route["path"] != "/workspace/api" {
+		t.Errorf("route path = %v, want it unchanged", route["path"])
+	}
+	if item := data["items"].([]interface{})[0].(map[string]interface{}); item["project_path"] != "/home/me/project/a" {
+		t.Errorf("nested project_path = %v", item["project_path"])
+	}
+
+	// Data without paths is passed on unchanged
+	raw := []byte(`{"b":1, "a":"x"}`)
+	if got := m.rewriteJSON(raw, true); string(got) != string(raw) {
+		t.Errorf("rewriteJSON changed %s to %s", raw, got)
+	}
+}
+
+// fakeHub answers PING with PONG and DETECT with its argument as JSON,
+// standing in for the hub behind the remote listener.
+func fakeHub(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hub.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				parser := protocol.NewParser(conn)
+				for {
+					cmd, err := parser.ParseCommand()
+					if err != nil {
+						return
+					}
+					switch cmd.Verb {
+					case protocol.VerbPing:
+						conn.Write(protocol.FormatPong())
+					case protocol.VerbDetect:
+						data, _ := json.Marshal(map[string]string{"path": cmd.Args[0]})
+						conn.Write(protocol.FormatJSON(data))
+					}
+				}
+			}()
+		}
+	}()
+	return path
+}
+
+func startTestRemote(t *testing.T) (*remoteListener, RemoteDialConfig) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rl, err := newRemoteListener(config.RemoteConfig{Listen: "127.0.0.1:0", TokenFile: tokenFile}, fakeHub(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rl.serve(t.Context())
+	t.Cleanup(func() { rl.Close() })
+
+	ca, err := certs.LoadOrCreate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rl, RemoteDialConfig{Addr: rl.Addr(), Token: "secret", CAFile: ca.CertPath()}
+}
+
+func TestRemoteListener_Relay(t *testing.T) {
+	_, cfg := startTestRemote(t)
+	cfg.PathMap = []protocol.PathMapping{{Client: "/workspace", Daemon: "/home/me/project"}}
+
+	conn, err := DialRemote(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	parser := protocol.NewParser(conn)
+
+	conn.Write(protocol.FormatCommand(&protocol.Command{Verb: protocol.VerbPing}))
+	if resp, err := parser.ParseResponse(); err != nil || resp.Type != protocol.ResponsePong {
+		t.Fatalf("PING: %+v, %v", resp, err)
+	}
+
+	// The hub sees the daemon's path; the client gets its own back
+	conn.Write(protocol.FormatCommand(&protocol.Command{Verb: protocol.VerbDetect, Args: []string{"/workspace/app"}}))
+	resp, err := parser.ParseResponse()
+	if err != nil || resp.Type != protocol.ResponseJSON {
+		t.Fatalf("DETECT: %+v, %v", resp, err)
+	}
+	if string(resp.Data) != `{"path":"/workspace/app"}` {
+		t.Errorf("DETECT = %s", resp.Data)
+	}
+}
+
+// The relay answers AUDIT RECORD itself and goes on relaying.
+func TestRemoteListener_RefusesAuditRecord(t *testing.T) {
+	_, cfg := startTestRemote(t)
+
+	conn, err := DialRemote(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	parser := protocol.NewParser(conn)
+
+	conn.Write(protocol.FormatCommand(&protocol.Command{
+		Verb:    protocol.VerbAudit,
+		SubVerb: protocol.SubVerbRecord,
+		Data:    []byte(`{"tool":"proc","action":"stop"}`),
+	}))
+	if resp, err := parser.ParseResponse(); err != nil || resp.Type != protocol.ResponseErr {
+		t.Fatalf("AUDIT RECORD: %+v, %v", resp, err)
+	}
+
+	conn.Write(protocol.FormatCommand(&protocol.Command{Verb: protocol.VerbPing}))
+	if resp, err := parser.ParseResponse(); err != nil || resp.Type != protocol.ResponsePong {
+		t.Fatalf("PING: %+v, %v", resp, err)
+	}
+}
+
+func TestRemoteListener_RefusesBadToken(t *testing.T) {
+	_, cfg := startTestRemote(t)
+	cfg.Token = "wrong"
+	if _, err := DialRemote(cfg); !errors.Is(err, ErrRemoteAuth) {
+		t.Errorf("err = %v, want ErrRemoteAuth", err)
+	}
+}
+
+func TestNewRemoteListener_RequiresAuth(t *testing.T) {
+	if _, err := newRemoteListener(config.RemoteConfig{Listen: "127.0.0.1:0"}, ""); err == nil {
+		t.Error("a listener without token or client CA should be refused")
+	}
+}
+
+func TestParsePathMapping(t *testing.T) {
+	m, err := ParsePathMapping("/workspace=/home/me/project")
+	if err != nil || m.Client != "/workspace" || m.Daemon != "/home/me/project" {
+		t.Errorf("got %+v, %v", m, err)
+	}
+	for _, s := range []string{"/workspace", "=/home", "/workspace="} {
+		if _, err := ParsePathMapping(s); err == nil {
+			t.Errorf("%q should be invalid", s)
+		}
+	}
+
+	if addr, ok, err := ParseRemoteAddr("tcp://host.docker.internal:7433"); !ok || err != nil || addr != "host.docker.internal:7433" {
+		t.Errorf("ParseRemoteAddr = %q, %v, %v", addr, ok, err)
+	}
+	if _, ok, _ := ParseRemoteAddr("/tmp/agnt.sock"); ok {
+		t.Error("a socket path is not a remote address")
+	}
+	if _, _, err := ParseRemoteAddr("tcp://host"); err == nil {
+		t.Error("an address without port should be invalid")
+	}
+}
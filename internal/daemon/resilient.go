@@ -8,6 +8,7 @@ import (
 
 	"github.com/standardbeagle/agnt/internal/debug"
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/pkg/api"
 )
 
 var (
@@ -59,6 +60,11 @@ type ResilientClientConfig struct {
 	// If non-nil, the callback can handle the mismatch (e.g., trigger upgrade).
 	// Return nil to proceed with mismatched versions, or error to fail connection.
 	OnVersionMismatch func(clientVer, daemonVer string) error
+
+	// Remote connects to a daemon's TCP listener instead of the local
+	// socket. The daemon is neither auto-started nor upgraded; a version
+	// mismatch is only logged.
+	Remote *RemoteDialConfig
 }
 
 // DefaultResilientClientConfig returns sensible defaults.
@@ -78,10 +84,25 @@ func DefaultResilientClientConfig() ResilientClientConfig {
 type ResilientClient struct {
 	config ResilientClientConfig
 	rc     *goclient.ResilientConn
+
+	// bridge relays the local socket the client connects to to a remote daemon
+	bridge *remoteBridge
 }
 
 // NewResilientClient creates a new resilient client.
 func NewResilientClient(config ResilientClientConfig) *ResilientClient {
+	// A remote daemon is reached through a local bridge socket, which is
+	// always listening, so the library never auto-starts a daemon for it
+	var bridge *remoteBridge
+	if config.Remote != nil {
+		bridge = newRemoteBridge(*config.Remote)
+		config.AutoStartConfig.SocketPath = bridge.SocketPath()
+		config.OnVersionMismatch = func(clientVer, daemonVer string) error {
+			debug.Log("client", "remote daemon version mismatch: client=%s daemon=%s", clientVer, daemonVer)
+			return nil
+		}
+	}
+
 	// Map our config to go-cli-server config
 	autoStartCfg := goclient.AutoStartConfig{
 		SocketPath:     config.AutoStartConfig.SocketPath,
@@ -146,17 +167,33 @@ func NewResilientClient(config ResilientClientConfig) *ResilientClient {
 	return &ResilientClient{
 		config: config,
 		rc:     goclient.NewResilientConn(resilientCfg),
+		bridge: bridge,
 	}
 }
 
 // Connect establishes the initial connection to the daemon.
 func (rc *ResilientClient) Connect() error {
+	if rc.bridge != nil {
+		if err := rc.bridge.start(); err != nil {
+			return err
+		}
+	}
 	return rc.rc.Connect()
 }
 
 // Close shuts down the resilient client.
 func (rc *ResilientClient) Close() error {
-	return rc.rc.Close()
+	err := rc.rc.Close()
+	if rc.bridge != nil {
+		rc.bridge.Close()
+	}
+	return err
+}
+
+// SocketPath returns the socket the client connects to: the daemon's, or
+// the local bridge to a remote daemon.
+func (rc *ResilientClient) SocketPath() string {
+	return rc.config.AutoStartConfig.SocketPath
 }
 
 // IsConnected returns whether the client is currently connected.
@@ -371,6 +408,24 @@ func (rc *ResilientClient) ProxyToast(id string, toast protocol.ToastConfig) (ma
 	return result, err
 }
 
+// ProxyShare shares a proxy on the local network.
+func (rc *ResilientClient) ProxyShare(id string, config protocol.ShareConfig) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := rc.WithClient(func(c *Client) error {
+		var e error
+		result, e = c.ProxyShare(id, config)
+		return e
+	})
+	return result, err
+}
+
+// ProxyUnshare stops sharing a proxy on the local network.
+func (rc *ResilientClient) ProxyUnshare(id string) error {
+	return rc.WithClient(func(c *Client) error {
+		return c.ProxyUnshare(id)
+	})
+}
+
 // ProxyLogQuery queries proxy logs.
 func (rc *ResilientClient) ProxyLogQuery(proxyID string, filter protocol.LogQueryFilter) (map[string]interface{}, error) {
 	var result map[string]interface{}
@@ -400,6 +455,17 @@ func (rc *ResilientClient) ProxyLogStats(proxyID string) (map[string]interface{}
 	return result, err
 }
 
+// ProxyLogBody fetches the full captured body of a logged HTTP request.
+func (rc *ResilientClient) ProxyLogBody(proxyID, requestID, part string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := rc.WithClient(func(c *Client) error {
+		var e error
+		result, e = c.ProxyLogBody(proxyID, requestID, part)
+		return e
+	})
+	return result, err
+}
+
 // CurrentPageList lists active page sessions.
 func (rc *ResilientClient) CurrentPageList(proxyID string) (map[string]interface{}, error) {
 	var result map[string]interface{}
@@ -832,3 +898,21 @@ func (rc *ResilientClient) RestartAll() (map[string]interface{}, error) {
 	})
 	return result, err
 }
+
+// AuditRecord appends an entry to the audit log of its project.
+func (rc *ResilientClient) AuditRecord(entry api.AuditEntry) error {
+	return rc.WithClient(func(c *Client) error {
+		return c.AuditRecord(entry)
+	})
+}
+
+// AuditQuery returns the entries of a project's audit log matching q.
+func (rc *ResilientClient) AuditQuery(q api.AuditQuery) (*api.AuditResult, error) {
+	var result *api.AuditResult
+	err := rc.WithClient(func(c *Client) error {
+		var e error
+		result, e = c.AuditQuery(q)
+		return e
+	})
+	return result, err
+}
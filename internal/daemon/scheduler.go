@@ -101,6 +101,9 @@ type Scheduler struct {
 
 	// Task ID counter
 	nextTaskID atomic.Int64
+
+	// onDelivered is called when a task has been delivered
+	onDelivered func(task *ScheduledTask)
 }
 
 // NewScheduler creates a new scheduler.
@@ -289,6 +292,10 @@ func (s *Scheduler) deliverTask(task *ScheduledTask) {
 	task.Status = TaskStatusDelivered
 	s.totalDelivered.Add(1)
 	s.removeTaskFromStorage(task)
+
+	if s.onDelivered != nil {
+		s.onDelivered(task)
+	}
 }
 
 // createOverlayClient creates an HTTP client that connects via Unix socket.
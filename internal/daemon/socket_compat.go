@@ -12,11 +12,12 @@ import (
 	"os"
 	"strings"
 
+	"github.com/standardbeagle/agnt/pkg/api"
 	"github.com/standardbeagle/go-cli-server/socket"
 )
 
 // SocketName is the socket name used for agnt daemon.
-const SocketName = "devtool-mcp"
+const SocketName = api.SocketName
 
 // Re-export error types from go-cli-server/socket for backward compatibility.
 var (
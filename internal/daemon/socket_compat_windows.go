@@ -11,12 +11,13 @@ import (
 	"os"
 	"strings"
 
+	"github.com/standardbeagle/agnt/pkg/api"
 	"github.com/standardbeagle/go-cli-server/socket"
 	"golang.org/x/sys/windows"
 )
 
 // SocketName is the socket name used for agnt daemon.
-const SocketName = "devtool-mcp"
+const SocketName = api.SocketName
 
 // Re-export error types from go-cli-server/socket for backward compatibility.
 var (
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/standardbeagle/agnt/internal/protocol"
 )
 
 // PersistentProxyConfig stores the configuration needed to recreate a proxy.
@@ -17,6 +19,12 @@ type PersistentProxyConfig struct {
 	MaxLogSize int    `json:"max_log_size"`
 	Path       string `json:"path"`
 	CreatedAt  string `json:"created_at"`
+
+	Routes    []protocol.ProxyRoute `json:"routes,omitempty"`
+	HTTPS     bool                  `json:"https,omitempty"`
+	HTTPSPort int                   `json:"https_port,omitempty"`
+
+	BodyCapture *protocol.BodyCaptureConfig `json:"body_capture,omitempty"`
 }
 
 // PersistentState stores daemon state that should survive restarts.
@@ -0,0 +1,132 @@
+// Package lanshare finds LAN addresses and renders QR codes so devices on
+// the same network can open agnt proxies.
+package lanshare
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Address is a LAN-reachable address of this machine.
+type Address struct {
+	Interface string `json:"interface"`
+	IP        string `json:"ip"`
+	IPv6      bool   `json:"ipv6,omitempty"`
+	Private   bool   `json:"private"`
+}
+
+// virtualPrefixes are interface name prefixes for container, VM and VPN
+// bridges that phones on the Wi-Fi cannot reach.
+var virtualPrefixes = []string{
+	"docker", "br-", "veth", "virbr", "vmnet", "vboxnet", "cni", "flannel",
+	"podman", "lxc", "lxd", "zt", "utun", "tun", "tap", "awdl", "llw",
+}
+
+// Addresses returns the LAN addresses of all up, non-loopback interfaces.
+// Private IPv4 addresses on physical interfaces are listed first.
+// Link-local addresses are skipped since browsers cannot use them in URLs.
+func Addresses() ([]Address, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("list interfaces: %w", err)
+	}
+
+	var addrs []Address
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range ifAddrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if addr, ok := classify(iface.Name, ipNet.IP); ok {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	sortAddresses(addrs)
+	return addrs, nil
+}
+
+// classify converts an interface IP to an Address, rejecting unusable ones.
+func classify(ifaceName string, ip net.IP) (Address, bool) {
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return Address{}, false
+	}
+	return Address{
+		Interface: ifaceName,
+		IP:        ip.String(),
+		IPv6:      ip.To4() == nil,
+		Private:   ip.IsPrivate(),
+	}, true
+}
+
+// sortAddresses orders addresses by how likely a phone can reach them:
+// IPv4 before IPv6, private before public, physical before virtual.
+func sortAddresses(addrs []Address) {
+	rank := func(a Address) int {
+		r := 0
+		if a.IPv6 {
+			r += 4
+		}
+		if !a.Private {
+			r += 2
+		}
+		if IsVirtualInterface(a.Interface) {
+			r++
+		}
+		return r
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return rank(addrs[i]) < rank(addrs[j])
+	})
+}
+
+// IsVirtualInterface reports whether an interface name looks like a
+// container bridge, VM network or VPN tunnel.
+func IsVirtualInterface(name string) bool {
+	name = strings.ToLower(name)
+	for _, prefix := range virtualPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// URLs builds URLs for each address with the given scheme and port.
+// Virtual interfaces are skipped unless they are the only addresses.
+func URLs(scheme string, port int, addrs []Address) []string {
+	var physical, virtual []string
+	for _, a := range addrs {
+		u := scheme + "://" + net.JoinHostPort(a.IP, strconv.Itoa(port))
+		if IsVirtualInterface(a.Interface) {
+			virtual = append(virtual, u)
+		} else {
+			physical = append(physical, u)
+		}
+	}
+	if len(physical) == 0 {
+		return virtual
+	}
+	return physical
+}
+
+// Hosts returns the IPs of addrs plus localhost, for use in certificates.
+func Hosts(addrs []Address) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	for _, a := range addrs {
+		hosts = append(hosts, a.IP)
+	}
+	return hosts
+}
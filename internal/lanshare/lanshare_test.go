@@ -0,0 +1,131 @@
+package lanshare
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		ip      string
+		ok      bool
+		private bool
+		ipv6    bool
+	}{
+		{"192.168.1.20", true, true, false},
+		{"10.0.0.5", true, true, false},
+		{"203.0.113.7", true, false, false},
+		{"fd00::1", true, true, true},
+		{"127.0.0.1", false, false, false},
+		{"169.254.10.1", false, false, false},
+		{"fe80::1", false, false, false},
+		{"0.0.0.0", false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			addr, ok := classify("en0", net.ParseIP(tt.ip))
+			if ok != tt.ok {
+				t.Fatalf("classify(%s) ok = %v, expected %v", tt.ip, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if addr.Private != tt.private || addr.IPv6 != tt.ipv6 {
+				t.Errorf("classify(%s) = %+v", tt.ip, addr)
+			}
+		})
+	}
+}
+
+func TestSortAddresses(t *testing.T) {
+	addrs := []Address{
+		{Interface: "en0", IP: "fd00::1", IPv6: true, Private: true},
+		{Interface: "docker0", IP: "172.17.0.1", Private: true},
+		{Interface: "eth1", IP: "203.0.113.7"},
+		{Interface: "wlan0", IP: "192.168.1.20", Private: true},
+	}
+	sortAddresses(addrs)
+
+	expected := []string{"192.168.1.20", "172.17.0.1", "203.0.113.7", "fd00::1"}
+	for i, ip := range expected {
+		if addrs[i].IP != ip {
+			t.Errorf("addrs[%d] = %s, expected %s", i, addrs[i].IP, ip)
+		}
+	}
+}
+
+func TestURLs(t *testing.T) {
+	addrs := []Address{
+		{Interface: "wlan0", IP: "192.168.1.20", Private: true},
+		{Interface: "docker0", IP: "172.17.0.1", Private: true},
+		{Interface: "en0", IP: "fd00::1", IPv6: true, Private: true},
+	}
+
+	urls := URLs("https", 8443, addrs)
+	expected := []string{"https://192.168.1.20:8443", "https://[fd00::1]:8443"}
+	if len(urls) != len(expected) {
+		t.Fatalf("URLs = %v, expected %v", urls, expected)
+	}
+	for i := range expected {
+		if urls[i] != expected[i] {
+			t.Errorf("urls[%d] = %s, expected %s", i, urls[i], expected[i])
+		}
+	}
+
+	// Only virtual interfaces: still better than nothing
+	urls = URLs("http", 80, addrs[1:2])
+	if len(urls) != 1 || urls[0] != "http://172.17.0.1:80" {
+		t.Errorf("expected virtual fallback, got %v", urls)
+	}
+}
+
+func TestIsVirtualInterface(t *testing.T) {
+	for _, name := range []string{"docker0", "br-1a2b", "veth123", "utun3", "vboxnet0"} {
+		if !IsVirtualInterface(name) {
+			t.Errorf("%s should be virtual", name)
+		}
+	}
+	for _, name := range []string{"en0", "eth0", "wlan0", "wlp2s0", "Wi-Fi"} {
+		if IsVirtualInterface(name) {
+			t.Errorf("%s should not be virtual", name)
+		}
+	}
+}
+
+func TestHosts(t *testing.T) {
+	hosts := Hosts([]Address{{IP: "192.168.1.20"}})
+	if hosts[0] != "localhost" || hosts[len(hosts)-1] != "192.168.1.20" {
+		t.Errorf("unexpected hosts: %v", hosts)
+	}
+}
+
+func TestQR(t *testing.T) {
+	qr, err := NewQR("http://192.168.1.20:12345")
+	if err != nil {
+		t.Fatalf("NewQR failed: %v", err)
+	}
+
+	term := qr.Terminal()
+	if !strings.Contains(term, "█") || strings.Count(term, "\n") < 10 {
+		t.Errorf("unexpected terminal rendering:\n%s", term)
+	}
+
+	svg := qr.SVG()
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") || !strings.Contains(svg, "M") {
+		t.Errorf("unexpected SVG: %s", svg)
+	}
+	if !strings.HasPrefix(qr.DataURI(), "data:image/svg+xml;base64,") {
+		t.Errorf("unexpected data URI prefix")
+	}
+
+	png, err := qr.PNG(0)
+	if err != nil {
+		t.Fatalf("PNG failed: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("expected PNG signature")
+	}
+}
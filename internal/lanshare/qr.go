@@ -0,0 +1,72 @@
+package lanshare
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QR is an encoded QR code that can be rendered for terminals and browsers.
+type QR struct {
+	Content string
+	code    *qrcode.QRCode
+}
+
+// NewQR encodes content as a QR code with medium error correction,
+// which keeps codes for LAN URLs small enough to scan from a terminal.
+func NewQR(content string) (*QR, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("encode QR code: %w", err)
+	}
+	return &QR{Content: content, code: code}, nil
+}
+
+// Terminal renders the code with Unicode half blocks, two modules per
+// character row. Light modules are drawn as blocks, so the code scans on
+// the dark backgrounds most terminals use.
+func (q *QR) Terminal() string {
+	return q.code.ToSmallString(false)
+}
+
+// SVG renders the code as a standalone SVG document. Each module is one
+// user unit; the caller sizes the image with CSS or the width attribute.
+func (q *QR) SVG() string {
+	bitmap := q.code.Bitmap()
+	size := len(bitmap)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Merge horizontal runs into one rectangle to keep the path short
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run - 1
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+// PNG renders the code as a PNG image of the given width in pixels.
+func (q *QR) PNG(size int) ([]byte, error) {
+	if size <= 0 {
+		size = 256
+	}
+	return q.code.PNG(size)
+}
+
+// DataURI returns the SVG rendering as a data URI for use in <img> tags.
+func (q *QR) DataURI() string {
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(q.SVG()))
+}
@@ -31,6 +31,12 @@ type DaemonConnector interface {
 	IsConnected() bool
 }
 
+// ProxySharer is an interface for sharing proxies on the local network.
+type ProxySharer interface {
+	// ShareProxy shares a proxy and returns its LAN URLs and a terminal QR code.
+	ShareProxy(proxyID string) (string, error)
+}
+
 // StatusSummarizer is an interface for summarizing system status.
 type StatusSummarizer interface {
 	// Summarize aggregates all system data and generates a summary.
@@ -52,6 +58,7 @@ type InputRouter struct {
 	daemonConnector DaemonConnector
 	statusFetcher   *StatusFetcher
 	summarizer      StatusSummarizer
+	proxySharer     ProxySharer
 
 	// Process viewer state
 	viewerActive bool
@@ -96,6 +103,11 @@ func (r *InputRouter) SetSummarizer(summarizer StatusSummarizer) {
 	r.summarizer = summarizer
 }
 
+// SetProxySharer sets the sharer used by the "Share on LAN" menu item.
+func (r *InputRouter) SetProxySharer(sharer ProxySharer) {
+	r.proxySharer = sharer
+}
+
 // GetLastDaemonError returns the last error from daemon connection attempt.
 func (r *InputRouter) GetLastDaemonError() string {
 	return r.lastDaemonError
@@ -437,6 +449,29 @@ func (r *InputRouter) executeMenuItem(item MenuItem) {
 			}
 		}
 
+	case ActionShareProxy:
+		r.overlay.hideMenu()
+		proxies := r.overlay.GetStatus().Proxies
+		r.overlay.mu.Unlock()
+		switch {
+		case r.proxySharer == nil:
+			io.WriteString(r.ptmx, "\r\n[agnt] LAN sharing not available\r\n")
+		case len(proxies) == 0:
+			io.WriteString(r.ptmx, "\r\n[agnt] No proxies to share\r\n")
+		default:
+			for _, p := range proxies {
+				text, err := r.proxySharer.ShareProxy(p.ID)
+				if err != nil {
+					io.WriteString(r.ptmx, "\r\n[agnt] Share "+p.ID+" failed: "+err.Error()+"\r\n")
+					continue
+				}
+				io.WriteString(r.ptmx, "\r\n--- LAN share: "+p.ID+" ---\r\n")
+				io.WriteString(r.ptmx, strings.ReplaceAll(text, "\n", "\r\n"))
+				io.WriteString(r.ptmx, "--- End LAN share ---\r\n")
+			}
+		}
+		r.overlay.mu.Lock()
+
 	case ActionSummarize:
 		r.overlay.hideMenu()
 		if r.summarizer == nil {
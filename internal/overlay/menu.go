@@ -16,6 +16,7 @@ const (
 	ActionToggleIndicator
 	ActionConnectDaemon
 	ActionSummarize
+	ActionShareProxy
 	ActionClose
 )
 
@@ -96,6 +97,7 @@ func proxiesMenu() *Menu {
 			{Label: "List all", Shortcut: 'l', Action: ActionShowProxies},
 			{Label: "Start new...", Shortcut: 's', Action: ActionStartProxy},
 			{Label: "Stop...", Shortcut: 'x', Action: ActionStopProxy},
+			{Label: "Share on LAN (QR)", Shortcut: 'q', Action: ActionShareProxy},
 			{Label: "Back", Shortcut: 'b', Action: ActionClose},
 		},
 	}
@@ -592,6 +592,43 @@ func (r *DaemonBashRunner) RunBashCommand(command string) (string, error) {
 	return processID, nil
 }
 
+// DaemonProxySharer implements ProxySharer using a shared daemon connection.
+type DaemonProxySharer struct {
+	conn *daemon.Conn
+}
+
+// NewDaemonProxySharer creates a new DaemonProxySharer using a shared connection.
+func NewDaemonProxySharer(conn *daemon.Conn) *DaemonProxySharer {
+	return &DaemonProxySharer{
+		conn: conn,
+	}
+}
+
+// ShareProxy shares a proxy on the LAN and formats the QR code and URLs for the terminal.
+func (s *DaemonProxySharer) ShareProxy(proxyID string) (string, error) {
+	var result struct {
+		URLs       []string `json:"urls"`
+		QRTerminal string   `json:"qr_terminal"`
+		CAURL      string   `json:"ca_url"`
+	}
+	err := s.conn.Request(protocol.VerbProxy, protocol.SubVerbShare, proxyID).
+		WithJSON(protocol.ShareConfig{}).
+		JSONInto(&result)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(result.QRTerminal)
+	for _, u := range result.URLs {
+		b.WriteString("  " + u + "\n")
+	}
+	if result.CAURL != "" {
+		b.WriteString("  CA certificate: " + result.CAURL + "\n")
+	}
+	return b.String(), nil
+}
+
 // DaemonOutputFetcher implements ProcessOutputFetcher using a shared daemon connection.
 type DaemonOutputFetcher struct {
 	conn *daemon.Conn
@@ -14,8 +14,23 @@ type CommandDef struct {
 	Timeout int `json:"timeout,omitempty"`
 	// Persistent indicates this is a long-running process (dev server).
 	Persistent bool `json:"persistent,omitempty"`
+	// Env holds environment variables to set.
+	Env map[string]string `json:"env,omitempty"`
+	// Source is where the command is defined: SourceBuiltin, SourceGlobal
+	// or SourceProject. Empty for commands of Detect, which are built in.
+	Source string `json:"source,omitempty"`
 }
 
+// Command sources, for CommandDef.Source.
+const (
+	// SourceBuiltin is a default command of a built-in project type.
+	SourceBuiltin = "built-in"
+	// SourceGlobal is a command from the global config.kdl.
+	SourceGlobal = "global"
+	// SourceProject is a command from the project's .agnt.kdl.
+	SourceProject = "project"
+)
+
 // DefaultGoCommands returns the default commands for a Go project.
 func DefaultGoCommands() []CommandDef {
 	return []CommandDef{
@@ -41,20 +41,11 @@ type Project struct {
 // Detect examines the given path and returns project information.
 // Returns a Project with Type=ProjectUnknown if no project type is detected.
 func Detect(path string) (*Project, error) {
-	absPath, err := filepath.Abs(path)
+	absPath, err := projectDir(path)
 	if err != nil {
 		return nil, err
 	}
 
-	// Stat the path to verify it exists
-	info, err := os.Stat(absPath)
-	if err != nil {
-		return nil, err
-	}
-	if !info.IsDir() {
-		return nil, os.ErrInvalid
-	}
-
 	// Try each detector in priority order
 	if proj := detectGo(absPath); proj != nil {
 		return proj, nil
@@ -75,17 +66,67 @@ func Detect(path string) (*Project, error) {
 	}, nil
 }
 
+// DetectAs returns the project at path as a project of the given type,
+// whether or not the type's marker files exist. It is used when the type
+// comes from configuration. Types without a built-in detector get no
+// commands.
+func DetectAs(path string, typ ProjectType) (*Project, error) {
+	absPath, err := projectDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case ProjectGo:
+		return newGoProject(absPath), nil
+	case ProjectNode:
+		return newNodeProject(absPath), nil
+	case ProjectPython:
+		return newPythonProject(absPath, pythonMarker(absPath)), nil
+	case "":
+		typ = ProjectUnknown
+	}
+
+	return &Project{
+		Path: absPath,
+		Type: typ,
+		Name: filepath.Base(absPath),
+	}, nil
+}
+
+// projectDir returns the absolute path of a project directory, verifying
+// it exists.
+func projectDir(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Stat the path to verify it exists
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", os.ErrInvalid
+	}
+	return absPath, nil
+}
+
 // detectGo checks for a Go project.
 func detectGo(path string) *Project {
-	goModPath := filepath.Join(path, "go.mod")
-	if _, err := os.Stat(goModPath); err != nil {
+	if !fileExists(filepath.Join(path, "go.mod")) {
 		return nil
 	}
+	return newGoProject(path)
+}
 
+// newGoProject returns the Go project at path.
+func newGoProject(path string) *Project {
 	proj := &Project{
 		Path:     path,
 		Type:     ProjectGo,
-		Name:     parseGoModuleName(goModPath),
+		Name:     parseGoModuleName(filepath.Join(path, "go.mod")),
 		Commands: DefaultGoCommands(),
 		Metadata: make(map[string]string),
 	}
@@ -118,11 +159,15 @@ func parseGoModuleName(goModPath string) string {
 
 // detectNode checks for a Node.js project.
 func detectNode(path string) *Project {
-	packagePath := filepath.Join(path, "package.json")
-	if _, err := os.Stat(packagePath); err != nil {
+	if !fileExists(filepath.Join(path, "package.json")) {
 		return nil
 	}
+	return newNodeProject(path)
+}
 
+// newNodeProject returns the Node.js project at path.
+func newNodeProject(path string) *Project {
+	packagePath := filepath.Join(path, "package.json")
 	proj := &Project{
 		Path:     path,
 		Type:     ProjectNode,
@@ -226,21 +271,27 @@ func detectPackageManager(path string) string {
 
 // detectPython checks for a Python project.
 func detectPython(path string) *Project {
+	marker := pythonMarker(path)
+	if marker == "" {
+		return nil
+	}
+	return newPythonProject(path, marker)
+}
+
+// pythonMarker returns the first Python marker file found at path, or "".
+func pythonMarker(path string) string {
 	// Check markers in priority order
-	markers := []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"}
-	found := false
-	var marker string
-	for _, m := range markers {
+	for _, m := range []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"} {
 		if fileExists(filepath.Join(path, m)) {
-			found = true
-			marker = m
-			break
+			return m
 		}
 	}
-	if !found {
-		return nil
-	}
+	return ""
+}
 
+// newPythonProject returns the Python project at path, named from its
+// marker file.
+func newPythonProject(path, marker string) *Project {
 	proj := &Project{
 		Path:     path,
 		Type:     ProjectPython,
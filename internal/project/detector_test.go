@@ -180,6 +180,41 @@ func TestDetect_File(t *testing.T) {
 	}
 }
 
+func TestDetectAs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/both\n"), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), nil, 0644); err != nil {
+		t.Fatalf("failed to write pnpm-lock.yaml: %v", err)
+	}
+
+	// A configured type wins over the markers, even without its own
+	proj, err := DetectAs(dir, ProjectNode)
+	if err != nil {
+		t.Fatalf("DetectAs failed: %v", err)
+	}
+	if proj.Type != ProjectNode || proj.PackageManager != "pnpm" {
+		t.Errorf("expected node with pnpm, got %s with %q", proj.Type, proj.PackageManager)
+	}
+	if !HasCommand(proj, "dev") {
+		t.Error("expected 'dev' command")
+	}
+
+	// Types without a detector get no commands
+	proj, err = DetectAs(dir, ProjectType("rust"))
+	if err != nil {
+		t.Fatalf("DetectAs failed: %v", err)
+	}
+	if proj.Type != "rust" || proj.Name != filepath.Base(dir) || len(proj.Commands) != 0 {
+		t.Errorf("unexpected project %+v", proj)
+	}
+
+	if _, err := DetectAs(filepath.Join(dir, "go.mod"), ProjectGo); err != os.ErrInvalid {
+		t.Errorf("expected ErrInvalid for file path, got %v", err)
+	}
+}
+
 func TestGetCommandByName(t *testing.T) {
 	proj := &Project{
 		Commands: []CommandDef{
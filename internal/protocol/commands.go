@@ -1,24 +1,30 @@
 package protocol
 
-// Agnt-specific command verbs (beyond those in go-cli-server).
+import "github.com/standardbeagle/agnt/pkg/api"
+
+// Agnt-specific command verbs (beyond those in go-cli-server). The ones
+// pkg/agntclient sends are defined in pkg/api.
 const (
-	VerbProxy       = "PROXY"
-	VerbProxyLog    = "PROXYLOG"
-	VerbCurrentPage = "CURRENTPAGE"
+	VerbProxy       = api.VerbProxy
+	VerbProxyLog    = api.VerbProxyLog
+	VerbCurrentPage = api.VerbCurrentPage
 	VerbTunnel      = "TUNNEL"
 	VerbChaos       = "CHAOS"
-	VerbDetect      = "DETECT"
+	VerbDetect      = api.VerbDetect
 	VerbOverlay     = "OVERLAY"
-	VerbStatus      = "STATUS" // Full daemon status (Hub's INFO is minimal)
+	VerbStatus      = api.VerbStatus // Full daemon status (Hub's INFO is minimal)
 	VerbStore       = "STORE"
-	VerbAutomate    = "AUTOMATE" // Agent-based automation processing
+	VerbAutomate    = "AUTOMATE"        // Agent-based automation processing
+	VerbSubscribe   = api.VerbSubscribe // Stream daemon events
+	VerbAuth        = "AUTH"            // Authenticate a remote TCP connection
+	VerbAudit       = api.VerbAudit     // Record and query the audit log of agent actions
 )
 
 // Agnt-specific sub-verbs (beyond those in go-cli-server).
 const (
-	SubVerbExec          = "EXEC"
+	SubVerbExec          = api.SubVerbExec
 	SubVerbToast         = "TOAST"
-	SubVerbQuery         = "QUERY"
+	SubVerbQuery         = api.SubVerbQuery
 	SubVerbStats         = "STATS"
 	SubVerbActivity      = "ACTIVITY"
 	SubVerbOutputPreview = "OUTPUT-PREVIEW"
@@ -41,8 +47,25 @@ const (
 	SubVerbProcess       = "PROCESS" // Process a single automation task
 	SubVerbBatch         = "BATCH"   // Process multiple automation tasks
 	SubVerbRestart       = "RESTART" // Restart a process or proxy
+	SubVerbShare         = "SHARE"   // Share a proxy on the local network
+	SubVerbUnshare       = "UNSHARE" // Stop sharing a proxy on the local network
+	SubVerbBody          = "BODY"    // Fetch a captured request or response body
+	SubVerbRecord        = "RECORD"  // Append an entry to the audit log
 )
 
+// BodyCaptureConfig sets how much of each proxied request and response body
+// is kept. Zero values use the defaults.
+type BodyCaptureConfig struct {
+	// InlineLimit is the size of the preview stored in the log entry (default 10KB)
+	InlineLimit int `json:"inline_limit,omitempty"`
+	// MaxSize is the most bytes captured per body (default 10MB); negative disables capture
+	MaxSize int64 `json:"max_size,omitempty"`
+	// StoreLimit caps the bytes of full bodies kept on disk per proxy (default 256MB)
+	StoreLimit int64 `json:"store_limit,omitempty"`
+	// Dir is where full bodies are written (default: a temp directory per proxy)
+	Dir string `json:"dir,omitempty"`
+}
+
 // ProxyStartConfig represents configuration for a PROXY START command.
 type ProxyStartConfig struct {
 	ID          string        `json:"id"`
@@ -56,7 +79,8 @@ type ProxyStartConfig struct {
 
 // TunnelConfig represents configuration for starting a tunnel alongside a proxy.
 type TunnelConfig struct {
-	// Provider is the tunnel provider: "ngrok", "cloudflared", "tailscale", or "custom"
+	// Provider is the tunnel provider: "ngrok", "cloudflared", "tailscale", "ssh"
+	// (also "localhost.run" or "serveo"), or "custom"
 	Provider string `json:"provider"`
 	// Command is used when Provider is "custom" - the full command to run
 	Command string `json:"command,omitempty"`
@@ -66,6 +90,25 @@ type TunnelConfig struct {
 	AuthToken string `json:"auth_token,omitempty"`
 	// Region is the tunnel region (optional)
 	Region string `json:"region,omitempty"`
+	// SSHHost is the host for the ssh provider (default: localhost.run)
+	SSHHost string `json:"ssh_host,omitempty"`
+}
+
+// ProxyRoute sends requests matching a path prefix or pattern to a different
+// upstream than the proxy target. Routes are matched in order.
+type ProxyRoute struct {
+	// Name labels the route in logs and chaos rules (default: path or pattern)
+	Name string `json:"name,omitempty"`
+	// Path is a path prefix, matched on segment boundaries ("/api" matches "/api/users")
+	Path string `json:"path,omitempty"`
+	// Pattern is a regular expression matched against the path (instead of Path)
+	Pattern string `json:"pattern,omitempty"`
+	// Target is the upstream URL (e.g., "http://localhost:8080")
+	Target string `json:"target"`
+	// StripPrefix removes the matched prefix before forwarding
+	StripPrefix bool `json:"strip_prefix,omitempty"`
+	// HostHeader overrides the Host header sent upstream (default: target host)
+	HostHeader string `json:"host_header,omitempty"`
 }
 
 // LogQueryFilter represents filters for PROXYLOG QUERY command.
@@ -77,6 +120,16 @@ type LogQueryFilter struct {
 	Since       string   `json:"since,omitempty"`
 	Until       string   `json:"until,omitempty"`
 	Limit       int      `json:"limit,omitempty"`
+
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`   // Header name → substring ("" = present)
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`  // Header name → substring ("" = present)
+	MinDuration     string            `json:"min_duration,omitempty"`      // e.g. "500ms"
+	MaxDuration     string            `json:"max_duration,omitempty"`      // e.g. "2s"
+	BodyPath        string            `json:"body_path,omitempty"`         // JSONPath predicate on the response body
+	RequestBodyPath string            `json:"request_body_path,omitempty"` // JSONPath predicate on the request body
+	ErrorPattern    string            `json:"error_pattern,omitempty"`     // Regex on error messages
+	Operation       string            `json:"operation,omitempty"`         // GraphQL operation name substring
+	GroupBy         []string          `json:"group_by,omitempty"`          // Aggregate HTTP entries instead of listing them
 }
 
 // ToastConfig represents configuration for a PROXY TOAST command.
@@ -87,14 +140,30 @@ type ToastConfig struct {
 	Duration int    `json:"duration,omitempty"` // Duration in ms (0 for default)
 }
 
+// ShareConfig represents configuration for a PROXY SHARE command.
+type ShareConfig struct {
+	HTTPS bool `json:"https,omitempty"` // Serve the share over HTTPS with the local agnt CA
+	Port  int  `json:"port,omitempty"`  // Share listener port (0 picks a free port)
+}
+
 // TunnelStartConfig represents configuration for a TUNNEL START command.
 type TunnelStartConfig struct {
-	ID         string `json:"id"`                    // Tunnel ID (usually same as proxy ID)
-	Provider   string `json:"provider"`              // "cloudflare" or "ngrok"
-	LocalPort  int    `json:"local_port"`            // Local port to tunnel
-	LocalHost  string `json:"local_host,omitempty"`  // Local host (default: localhost)
-	BinaryPath string `json:"binary_path,omitempty"` // Optional path to tunnel binary
-	ProxyID    string `json:"proxy_id,omitempty"`    // Optional proxy ID to auto-configure public_url
+	ID         string   `json:"id"`                    // Tunnel ID (usually same as proxy ID)
+	Provider   string   `json:"provider"`              // "cloudflare", "ngrok", "tailscale", "ssh" or "custom"
+	LocalPort  int      `json:"local_port"`            // Local port to tunnel
+	LocalHost  string   `json:"local_host,omitempty"`  // Local host (default: localhost)
+	BinaryPath string   `json:"binary_path,omitempty"` // Optional path to tunnel binary
+	ProxyID    string   `json:"proxy_id,omitempty"`    // Optional proxy ID to auto-configure public_url
+	Command    string   `json:"command,omitempty"`     // Command for the custom provider ({{PORT}} is expanded)
+	Args       []string `json:"args,omitempty"`        // Extra arguments for the tunnel command
+	AuthToken  string   `json:"auth_token,omitempty"`  // ngrok auth token
+	Region     string   `json:"region,omitempty"`      // ngrok region
+	SSHHost    string   `json:"ssh_host,omitempty"`    // Host for the ssh provider (default: localhost.run)
+
+	// AutoRestart restarts the tunnel with a new URL when it dies (default: true)
+	AutoRestart *bool `json:"auto_restart,omitempty"`
+	// HealthInterval is the seconds between health probes (default: 30, negative disables)
+	HealthInterval int `json:"health_interval,omitempty"`
 }
 
 // ChaosRuleConfig represents configuration for a CHAOS ADD-RULE command.
@@ -106,6 +175,11 @@ type ChaosRuleConfig struct {
 	URLPattern  string   `json:"url_pattern,omitempty"`
 	Methods     []string `json:"methods,omitempty"`
 	Probability float64  `json:"probability,omitempty"` // 0.0-1.0, default 1.0
+	Upstream    string   `json:"upstream,omitempty"`    // Route name, upstream URL or host
+
+	// GraphQL matching
+	Operation     string `json:"operation,omitempty"`      // Regex for operation names
+	OperationType string `json:"operation_type,omitempty"` // query, mutation or subscription
 
 	// Latency config
 	MinLatencyMs int `json:"min_latency_ms,omitempty"`
@@ -121,8 +195,13 @@ type ChaosRuleConfig struct {
 	DropAfterBytes   int64   `json:"drop_after_bytes,omitempty"`
 
 	// Error injection config
-	ErrorCodes   []int  `json:"error_codes,omitempty"`
-	ErrorMessage string `json:"error_message,omitempty"`
+	ErrorCodes       []int  `json:"error_codes,omitempty"`
+	ErrorMessage     string `json:"error_message,omitempty"`
+	GraphQLErrorCode string `json:"graphql_error_code,omitempty"` // extensions.code for graphql_error
+
+	// Stream event config (sse_delay uses the latency config per event)
+	EventPattern     string  `json:"event_pattern,omitempty"`     // Regex on the event name or data
+	EventProbability float64 `json:"event_probability,omitempty"` // Per event, 0.0-1.0, default 1.0
 
 	// Truncation config
 	TruncatePercent float64 `json:"truncate_percent,omitempty"`
@@ -215,6 +294,7 @@ type AutomateOptions struct {
 	Model       string  `json:"model,omitempty"`       // Model to use (haiku, sonnet)
 	MaxTokens   int     `json:"max_tokens,omitempty"`  // Max response tokens
 	Temperature float64 `json:"temperature,omitempty"` // 0.0-1.0
+	Offline     bool    `json:"offline,omitempty"`     // Force rule-based processing (audit_process only)
 }
 
 // AutomateBatchRequest represents an AUTOMATE BATCH command.
@@ -230,4 +310,22 @@ type AutomateProcessResponse struct {
 	Tokens   int         `json:"tokens_used"`
 	CostUSD  float64     `json:"cost_usd"`
 	Duration string      `json:"duration"`
+	Offline  bool        `json:"offline,omitempty"`
+}
+
+// RemoteAuthRequest is the JSON data of AUTH, the first command of a
+// connection to the daemon's TCP listener.
+type RemoteAuthRequest struct {
+	// Token is the token of the listener's token file, if it has one
+	Token string `json:"token,omitempty"`
+	// PathMap rewrites project paths between the client and the daemon for
+	// the rest of the connection
+	PathMap []PathMapping `json:"path_map,omitempty"`
+}
+
+// PathMapping maps a directory as the client sees it, e.g. a container
+// mount, to the same directory on the daemon's machine.
+type PathMapping struct {
+	Client string `json:"client"`
+	Daemon string `json:"daemon"`
 }
@@ -147,6 +147,9 @@ func init() {
 		VerbOverlay,
 		VerbStatus,
 		VerbStore,
+		VerbSubscribe,
+		VerbAuth,
+		VerbAudit,
 	)
 
 	// Register agnt-specific sub-verbs.
@@ -173,5 +176,9 @@ func init() {
 		SubVerbURL,
 		SubVerbGetAll,
 		SubVerbDelete,
+		SubVerbShare,
+		SubVerbUnshare,
+		SubVerbBody,
+		SubVerbRecord,
 	)
 }
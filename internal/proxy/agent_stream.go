@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/standardbeagle/agnt/internal/debug"
+)
+
+// AgentStreamRequest is a browser request for a streamed agent response.
+type AgentStreamRequest struct {
+	StreamID string `json:"stream_id"` // Assigned by the proxy, unique across connections
+	ProxyID  string `json:"proxy_id"`
+	Path     string `json:"path,omitempty"`    // Project directory of the proxy
+	Source   string `json:"source"`            // prompt, panel_message, design_request, design_chat
+	Prompt   string `json:"prompt"`            // User prompt sent to the agent
+	Context  string `json:"context,omitempty"` // Page/element context for the prompt
+	URL      string `json:"url,omitempty"`
+}
+
+// AgentStreamEvent is a single streaming update sent to the browser.
+// Field names match the detail of the '__devtool:agent:message' event consumed
+// by the ResponseStreamPanel UI.
+type AgentStreamEvent struct {
+	Type     string `json:"type"` // start, chunk, system, end, error
+	StreamID string `json:"sessionId"`
+	Chunk    string `json:"chunk,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Complete bool   `json:"complete,omitempty"`
+	Source   string `json:"source,omitempty"`
+}
+
+// AgentStreamer runs an agent request and reports progress through emit.
+// It must return when ctx is cancelled. The returned error is reported to the
+// browser if no end or error event was emitted.
+type AgentStreamer func(ctx context.Context, req AgentStreamRequest, emit func(AgentStreamEvent)) error
+
+// agentStream tracks a running stream so it can be cancelled by the
+// connection that started it.
+type agentStream struct {
+	connID   string
+	clientID string // Stream ID chosen by the browser, echoed in events
+	cancel   context.CancelFunc
+}
+
+// SetAgentStreamer sets the function used to stream agent responses to the browser.
+// When nil, streaming requests fall back to the overlay.
+func (ps *ProxyServer) SetAgentStreamer(streamer AgentStreamer) {
+	ps.mu.Lock()
+	ps.agentStreamer = streamer
+	ps.mu.Unlock()
+}
+
+// HasAgentStreamer returns true if agent responses can be streamed to the browser.
+func (ps *ProxyServer) HasAgentStreamer() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.agentStreamer != nil
+}
+
+// StartAgentStream starts streaming an agent response to the browser connection
+// that asked for it. The stream ID in req is the browser's; events carry it back,
+// but the stream is tracked under an ID assigned here so connections can't
+// replace or cancel each other's streams.
+// Returns an error if no streamer is configured.
+func (ps *ProxyServer) StartAgentStream(connID string, req AgentStreamRequest) error {
+	ps.mu.Lock()
+	streamer := ps.agentStreamer
+	ps.mu.Unlock()
+	if streamer == nil {
+		return fmt.Errorf("agent streaming not configured")
+	}
+
+	clientID := req.StreamID
+	req.StreamID = fmt.Sprintf("stream-%d", ps.agentStreamSeq.Add(1))
+	if clientID == "" {
+		clientID = req.StreamID
+	}
+	req.ProxyID = ps.ID
+	req.Path = ps.Path
+
+	// A browser reusing a stream ID replaces its own earlier stream
+	ps.CancelAgentStream(connID, clientID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ps.agentStreams.Store(req.StreamID, &agentStream{connID: connID, clientID: clientID, cancel: cancel})
+
+	go func() {
+		defer cancel()
+		defer ps.agentStreams.Delete(req.StreamID)
+
+		var finished atomic.Bool
+		emit := func(ev AgentStreamEvent) {
+			ev.StreamID = clientID
+			ev.Source = req.Source
+			if ev.Type == "end" || ev.Type == "error" {
+				finished.Store(true)
+			}
+			ps.sendAgentStream(connID, ev)
+		}
+
+		debug.Log("proxy", "agent stream %s started: proxy=%s conn=%s source=%s", req.StreamID, ps.ID, connID, req.Source)
+		err := streamer(ctx, req, emit)
+		if !finished.Load() {
+			switch {
+			case ctx.Err() != nil:
+				emit(AgentStreamEvent{Type: "error", Content: "cancelled"})
+			case err != nil:
+				emit(AgentStreamEvent{Type: "error", Content: err.Error()})
+			default:
+				emit(AgentStreamEvent{Type: "end", Complete: true})
+			}
+		}
+		debug.Log("proxy", "agent stream %s finished: err=%v", req.StreamID, err)
+	}()
+
+	return nil
+}
+
+// CancelAgentStream cancels a stream a connection started, by the stream ID the
+// browser chose. Returns false if the connection has no such stream.
+func (ps *ProxyServer) CancelAgentStream(connID, clientID string) bool {
+	found := false
+	ps.agentStreams.Range(func(key, value interface{}) bool {
+		if s := value.(*agentStream); s.connID == connID && s.clientID == clientID {
+			s.cancel()
+			found = true
+		}
+		return true
+	})
+	return found
+}
+
+// cancelConnAgentStreams cancels all streams started by a WebSocket connection.
+func (ps *ProxyServer) cancelConnAgentStreams(connID string) {
+	ps.agentStreams.Range(func(key, value interface{}) bool {
+		if s := value.(*agentStream); s.connID == connID {
+			s.cancel()
+		}
+		return true
+	})
+}
+
+// sendAgentStream sends an agent stream event to the browser connection that
+// started the stream. Returns false if the connection is gone.
+func (ps *ProxyServer) sendAgentStream(connID string, ev AgentStreamEvent) bool {
+	value, ok := ps.wsConns.Load(connID)
+	if !ok {
+		return false
+	}
+	message := map[string]interface{}{
+		"type":    "agent_stream",
+		"payload": ev,
+	}
+	return value.(*wsConn).WriteJSON(message) == nil
+}
+
+// wantsAgentStream returns true if a browser message asked for a streamed response
+// and a streamer is available to provide one.
+func (ps *ProxyServer) wantsAgentStream(data map[string]interface{}) bool {
+	return getBoolField(data, "stream") && ps.HasAgentStreamer()
+}
+
+// panelMessageStreamRequest builds a stream request from a floating panel message.
+func panelMessageStreamRequest(data map[string]interface{}, msg PanelMessage) AgentStreamRequest {
+	var b strings.Builder
+	for _, a := range msg.Attachments {
+		if a.Selector != "" {
+			fmt.Fprintf(&b, "- %s element: %s\n", a.Type, a.Selector)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", a.Type)
+		}
+	}
+	attachments := ""
+	if b.Len() > 0 {
+		attachments = "Attachments:\n" + b.String()
+	}
+	return AgentStreamRequest{
+		StreamID: getStringField(data, "stream_id"),
+		Source:   "panel_message",
+		Prompt:   msg.Message,
+		Context:  pageContext(msg.URL, attachments),
+		URL:      msg.URL,
+	}
+}
+
+// designRequestStreamRequest builds a stream request asking for a new design alternative.
+func designRequestStreamRequest(data map[string]interface{}, req DesignRequest) AgentStreamRequest {
+	prompt := fmt.Sprintf("Propose a new design alternative for the element %s. "+
+		"%d alternatives already exist; make this one distinct. "+
+		"Respond with the replacement HTML only.", req.Selector, req.AlternativesCount)
+	return AgentStreamRequest{
+		StreamID: getStringField(data, "stream_id"),
+		Source:   "design_request",
+		Prompt:   prompt,
+		Context:  pageContext(req.URL, designContext(req.CurrentHTML, req.OriginalHTML, req.ContextHTML, req.ChatHistory)),
+		URL:      req.URL,
+	}
+}
+
+// designChatStreamRequest builds a stream request from a design-mode chat message.
+func designChatStreamRequest(data map[string]interface{}, chat DesignChat) AgentStreamRequest {
+	return AgentStreamRequest{
+		StreamID: getStringField(data, "stream_id"),
+		Source:   "design_chat",
+		Prompt:   chat.Message,
+		Context:  pageContext(chat.URL, "Element: "+chat.Selector+"\n"+designContext(chat.CurrentHTML, chat.OriginalHTML, chat.ContextHTML, chat.ChatHistory)),
+		URL:      chat.URL,
+	}
+}
+
+// designContext formats element HTML and chat history as prompt context.
+func designContext(current, original, parent string, history []DesignChatMessage) string {
+	var b strings.Builder
+	if current != "" {
+		fmt.Fprintf(&b, "Current HTML:\n%s\n\n", current)
+	}
+	if original != "" && original != current {
+		fmt.Fprintf(&b, "Original HTML:\n%s\n\n", original)
+	}
+	if parent != "" {
+		fmt.Fprintf(&b, "Surrounding HTML:\n%s\n\n", parent)
+	}
+	if len(history) > 0 {
+		b.WriteString("Conversation so far:\n")
+		for _, m := range history {
+			fmt.Fprintf(&b, "%s: %s\n", m.Role, m.Message)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// pageContext prefixes context with the page URL.
+func pageContext(pageURL, extra string) string {
+	if pageURL == "" {
+		return extra
+	}
+	if extra == "" {
+		return "Page: " + pageURL
+	}
+	return "Page: " + pageURL + "\n" + extra
+}
@@ -0,0 +1,225 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// startStreamProxy starts a proxy with the given streamer and connects a browser WebSocket.
+func startStreamProxy(t *testing.T, streamer AgentStreamer) (*ProxyServer, *websocket.Conn) {
+	t.Helper()
+	ps, err := NewProxyServer(ProxyConfig{ID: "test-stream", TargetURL: "http://localhost:9999", ListenPort: 0})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	ps.SetAgentStreamer(streamer)
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = ps.Stop(context.Background()) })
+
+	conn, _, err := websocket.DefaultDialer.Dial(ps.MetricsURL(), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ps, conn
+}
+
+// readStreamEvents reads agent_stream events until an end or error event.
+func readStreamEvents(t *testing.T, conn *websocket.Conn) []AgentStreamEvent {
+	t.Helper()
+	var events []AgentStreamEvent
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v (events so far: %+v)", err, events)
+		}
+		var msg struct {
+			Type    string           `json:"type"`
+			Payload AgentStreamEvent `json:"payload"`
+		}
+		if json.Unmarshal(raw, &msg) != nil || msg.Type != "agent_stream" {
+			continue
+		}
+		events = append(events, msg.Payload)
+		if msg.Payload.Type == "end" || msg.Payload.Type == "error" {
+			return events
+		}
+	}
+}
+
+func TestAgentStream_Request(t *testing.T) {
+	reqs := make(chan AgentStreamRequest, 1)
+	_, conn := startStreamProxy(t, func(ctx context.Context, req AgentStreamRequest, emit func(AgentStreamEvent)) error {
+		reqs <- req
+		emit(AgentStreamEvent{Type: "start"})
+		emit(AgentStreamEvent{Type: "chunk", Chunk: "Hel"})
+		emit(AgentStreamEvent{Type: "chunk", Chunk: "lo"})
+		return nil
+	})
+
+	conn.WriteJSON(map[string]interface{}{
+		"type": "agent_request",
+		"url":  "http://localhost:3000/page",
+		"data": map[string]interface{}{"stream_id": "s1", "prompt": "say hello"},
+	})
+
+	events := readStreamEvents(t, conn)
+	var types []string
+	var text strings.Builder
+	for _, ev := range events {
+		types = append(types, ev.Type)
+		text.WriteString(ev.Chunk)
+		if ev.StreamID != "s1" {
+			t.Errorf("event stream ID = %q, want s1", ev.StreamID)
+		}
+	}
+	if strings.Join(types, ",") != "start,chunk,chunk,end" || text.String() != "Hello" {
+		t.Errorf("events = %+v", events)
+	}
+	if got := <-reqs; got.Prompt != "say hello" || got.Source != "prompt" || !strings.Contains(got.Context, "http://localhost:3000/page") {
+		t.Errorf("streamer request = %+v", got)
+	}
+}
+
+func TestAgentStream_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	_, conn := startStreamProxy(t, func(ctx context.Context, req AgentStreamRequest, emit func(AgentStreamEvent)) error {
+		emit(AgentStreamEvent{Type: "start"})
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	conn.WriteJSON(map[string]interface{}{
+		"type": "agent_request",
+		"data": map[string]interface{}{"stream_id": "s2", "prompt": "long task"},
+	})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not start")
+	}
+	conn.WriteJSON(map[string]interface{}{
+		"type": "agent_cancel",
+		"data": map[string]interface{}{"stream_id": "s2"},
+	})
+
+	events := readStreamEvents(t, conn)
+	last := events[len(events)-1]
+	if last.Type != "error" || last.Content != "cancelled" {
+		t.Errorf("last event = %+v, want cancelled error", last)
+	}
+}
+
+func TestAgentStream_OtherConnection(t *testing.T) {
+	started := make(chan string, 2)
+	ps, conn := startStreamProxy(t, func(ctx context.Context, req AgentStreamRequest, emit func(AgentStreamEvent)) error {
+		started <- req.StreamID
+		emit(AgentStreamEvent{Type: "start"})
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	other, _, err := websocket.DefaultDialer.Dial(ps.MetricsURL(), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer other.Close()
+
+	conn.WriteJSON(map[string]interface{}{
+		"type": "agent_request",
+		"data": map[string]interface{}{"stream_id": "s4", "prompt": "long task"},
+	})
+	var first string
+	select {
+	case first = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not start")
+	}
+
+	// The other connection reuses the ID and tries to cancel it
+	other.WriteJSON(map[string]interface{}{
+		"type": "agent_request",
+		"data": map[string]interface{}{"stream_id": "s4", "prompt": "hijack"},
+	})
+	select {
+	case second := <-started:
+		if second == first || second == "s4" {
+			t.Errorf("stream IDs = %q, %q, want distinct server IDs", first, second)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second stream did not start")
+	}
+	other.WriteJSON(map[string]interface{}{
+		"type": "agent_cancel",
+		"data": map[string]interface{}{"stream_id": "s4"},
+	})
+
+	// Only the other connection's stream is cancelled, and only it hears about it
+	events := readStreamEvents(t, other)
+	if last := events[len(events)-1]; last.Type != "error" || last.Content != "cancelled" {
+		t.Errorf("other connection last event = %+v", last)
+	}
+	if ps.CancelAgentStream("", "s4") {
+		t.Error("cancel from an unknown connection should find nothing")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		var msg struct {
+			Type    string           `json:"type"`
+			Payload AgentStreamEvent `json:"payload"`
+		}
+		if json.Unmarshal(raw, &msg) != nil || msg.Type != "agent_stream" {
+			continue
+		}
+		if msg.Payload.Type != "start" {
+			t.Fatalf("first connection got %+v", msg.Payload)
+		}
+		break
+	}
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, raw, err := conn.ReadMessage(); err == nil && strings.Contains(string(raw), "agent_stream") {
+		t.Errorf("first connection got another connection's event: %s", raw)
+	}
+}
+
+func TestAgentStream_DesignChat(t *testing.T) {
+	reqs := make(chan AgentStreamRequest, 1)
+	_, conn := startStreamProxy(t, func(ctx context.Context, req AgentStreamRequest, emit func(AgentStreamEvent)) error {
+		reqs <- req
+		return nil
+	})
+
+	conn.WriteJSON(map[string]interface{}{
+		"type": "design_chat",
+		"data": map[string]interface{}{
+			"stream":      true,
+			"stream_id":   "s3",
+			"message":     "make it blue",
+			"selector":    "#cta",
+			"currentHTML": "<button id=\"cta\">Go</button>",
+		},
+	})
+
+	select {
+	case req := <-reqs:
+		if req.Source != "design_chat" || req.Prompt != "make it blue" ||
+			!strings.Contains(req.Context, "#cta") || !strings.Contains(req.Context, "<button") {
+			t.Errorf("request = %+v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("design_chat with stream: true did not start a stream")
+	}
+}
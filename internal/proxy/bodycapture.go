@@ -0,0 +1,682 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/internal/textutil"
+)
+
+// Body capture defaults.
+const (
+	DefaultBodyInlineLimit = 10 * 1024         // Preview kept in the log entry
+	DefaultBodyMaxSize     = 10 * 1024 * 1024  // Most bytes captured per body
+	DefaultBodyStoreLimit  = 256 * 1024 * 1024 // Full bodies kept on disk per proxy
+
+	// maxBodyFetch is the most decoded bytes HTTPBody returns inline.
+	maxBodyFetch = 1024 * 1024
+)
+
+// Body kinds recorded in BodyInfo.Kind.
+const (
+	BodyKindJSON      = "json"
+	BodyKindForm      = "form"
+	BodyKindMultipart = "multipart"
+	BodyKindText      = "text"
+	BodyKindBinary    = "binary"
+)
+
+// BodyInfo describes a captured request or response body.
+type BodyInfo struct {
+	Size        int64  `json:"size"` // Bytes on the wire, before decoding
+	Kind        string `json:"kind"` // json, form, multipart, text or binary
+	ContentType string `json:"content_type,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`  // Content-Encoding of the captured bytes
+	File        string `json:"file,omitempty"`      // Full body on disk, as sent (still encoded)
+	Truncated   bool   `json:"truncated,omitempty"` // Body exceeded the capture limit
+}
+
+// BodyContent is a full captured body, fetched by request ID.
+type BodyContent struct {
+	RequestID string    `json:"request_id"`
+	Part      string    `json:"part"` // "request" or "response"
+	Info      *BodyInfo `json:"info"`
+	Body      string    `json:"body,omitempty"`      // Decoded text; JSON is pretty-printed
+	Base64    string    `json:"base64,omitempty"`    // Decoded binary content
+	Truncated bool      `json:"truncated,omitempty"` // Cut at the fetch limit; read Info.File for the rest
+}
+
+// bodyStore keeps full request and response bodies on disk so they can be
+// fetched by request ID after the log entry's preview. It is created on the
+// first body that outgrows the preview, and removes the oldest files once it
+// exceeds its limit.
+type bodyStore struct {
+	inlineLimit int
+	maxSize     int64
+	storeLimit  int64
+	baseDir     string // Parent of the store directory ("" for the temp dir)
+	prefix      string
+
+	mu    sync.Mutex
+	dir   string
+	files []storedBody
+	total int64
+}
+
+type storedBody struct {
+	path string
+	size int64
+}
+
+// newBodyStore resolves the capture configuration of a proxy.
+func newBodyStore(proxyID string, cfg *protocol.BodyCaptureConfig) *bodyStore {
+	bs := &bodyStore{
+		inlineLimit: DefaultBodyInlineLimit,
+		maxSize:     DefaultBodyMaxSize,
+		storeLimit:  DefaultBodyStoreLimit,
+		prefix:      "agnt-bodies-" + sanitizeFileName(proxyID) + "-",
+	}
+	if cfg == nil {
+		return bs
+	}
+	if cfg.InlineLimit > 0 {
+		bs.inlineLimit = cfg.InlineLimit
+	}
+	if cfg.MaxSize != 0 {
+		bs.maxSize = cfg.MaxSize
+	}
+	if cfg.StoreLimit > 0 {
+		bs.storeLimit = cfg.StoreLimit
+	}
+	bs.baseDir = cfg.Dir
+	return bs
+}
+
+// config returns the effective capture configuration.
+func (bs *bodyStore) config() *protocol.BodyCaptureConfig {
+	return &protocol.BodyCaptureConfig{
+		InlineLimit: bs.inlineLimit,
+		MaxSize:     bs.maxSize,
+		StoreLimit:  bs.storeLimit,
+		Dir:         bs.baseDir,
+	}
+}
+
+// capture starts recording a body. Returns nil when capture is disabled.
+func (bs *bodyStore) capture(name string) *bodyCapture {
+	if bs == nil || bs.maxSize <= 0 {
+		return nil
+	}
+	return &bodyCapture{store: bs, name: name}
+}
+
+// create opens a new body file, creating the store directory if needed.
+func (bs *bodyStore) create(name string) (*os.File, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.dir == "" {
+		if bs.baseDir != "" {
+			if err := os.MkdirAll(bs.baseDir, 0755); err != nil {
+				return nil, err
+			}
+		}
+		dir, err := os.MkdirTemp(bs.baseDir, bs.prefix)
+		if err != nil {
+			return nil, err
+		}
+		bs.dir = dir
+	}
+	return os.Create(filepath.Join(bs.dir, name))
+}
+
+// add records a finished body file and evicts the oldest ones over the limit.
+func (bs *bodyStore) add(path string, size int64) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.files = append(bs.files, storedBody{path: path, size: size})
+	bs.total += size
+	for bs.total > bs.storeLimit && len(bs.files) > 1 {
+		oldest := bs.files[0]
+		bs.files = bs.files[1:]
+		bs.total -= oldest.size
+		os.Remove(oldest.path)
+	}
+}
+
+// close removes every stored body.
+func (bs *bodyStore) close() {
+	if bs == nil {
+		return
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.dir != "" {
+		if err := os.RemoveAll(bs.dir); err != nil {
+			debug.Error("proxy", "failed to remove body store %s: %v", bs.dir, err)
+		}
+	}
+	bs.dir = ""
+	bs.files = nil
+	bs.total = 0
+}
+
+// bodyCapture records a body as it streams through the proxy. The first
+// inlineLimit bytes stay in memory for the preview; larger bodies continue
+// into a file in the store, up to maxSize. The transport may still be
+// reading a request body after the response is done, hence the mutex.
+type bodyCapture struct {
+	store *bodyStore
+	name  string
+
+	mu       sync.Mutex
+	head     bytes.Buffer
+	file     *os.File
+	size     int64 // Bytes seen
+	captured int64 // Bytes kept (head and file)
+	failed   bool  // Spilling to disk failed; only the head is kept
+	done     bool
+}
+
+// Write records p. It never fails, so the proxied stream is unaffected.
+func (bc *bodyCapture) Write(p []byte) (int, error) {
+	n := len(p)
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.done {
+		return n, nil
+	}
+
+	bc.size += int64(n)
+	if room := bc.store.maxSize - bc.captured; int64(len(p)) > room {
+		p = p[:max(room, 0)]
+	}
+	bc.captured += int64(len(p))
+
+	taken := 0
+	if room := bc.store.inlineLimit - bc.head.Len(); room > 0 {
+		taken = min(room, len(p))
+		bc.head.Write(p[:taken])
+	}
+	if taken == len(p) {
+		return n, nil
+	}
+
+	if bc.file == nil && !bc.failed {
+		bc.spill()
+	}
+	if bc.file != nil {
+		if _, err := bc.file.Write(p[taken:]); err != nil {
+			bc.abandon(err)
+		}
+	}
+	return n, nil
+}
+
+// spill moves the body to a file in the store. Must be called with bc.mu held.
+func (bc *bodyCapture) spill() {
+	f, err := bc.store.create(bc.name)
+	if err != nil {
+		debug.Error("proxy", "failed to store body %s: %v", bc.name, err)
+		bc.failed = true
+		return
+	}
+	bc.file = f
+	if _, err := f.Write(bc.head.Bytes()); err != nil {
+		bc.abandon(err)
+	}
+}
+
+// abandon drops the body file after a write error. Must be called with bc.mu held.
+func (bc *bodyCapture) abandon(err error) {
+	debug.Error("proxy", "failed to store body %s: %v", bc.name, err)
+	path := bc.file.Name()
+	bc.file.Close()
+	os.Remove(path)
+	bc.file = nil
+	bc.failed = true
+}
+
+// finish stops recording and describes the body using the headers sent with
+// it. Returns the preview for the log entry and nil info for empty bodies.
+func (bc *bodyCapture) finish(header http.Header) (string, *BodyInfo) {
+	if bc == nil {
+		return "", nil
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.done = true
+
+	if bc.size == 0 {
+		return "", nil
+	}
+
+	info := &BodyInfo{
+		Size:        bc.size,
+		ContentType: header.Get("Content-Type"),
+		Encoding:    strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))),
+		Truncated:   bc.captured < bc.size,
+	}
+	limit := bc.store.inlineLimit
+
+	sample, decoded := decodeSample(bc.head.Bytes(), info.Encoding, limit)
+	info.Kind = bodyKind(info.ContentType, sample, decoded)
+	complete := decoded && len(sample) <= limit && bc.captured <= int64(limit) && !info.Truncated
+
+	var preview string
+	whole := false
+	if info.Kind != BodyKindMultipart {
+		preview, whole = bodyPreview(info, sample, limit, complete)
+	}
+
+	// Keep the body on disk whenever the preview doesn't hold all of it
+	if !whole && bc.file == nil && !bc.failed {
+		bc.spill()
+	}
+	if bc.file != nil {
+		path := bc.file.Name()
+		if err := bc.file.Close(); err != nil {
+			debug.Error("proxy", "failed to store body %s: %v", bc.name, err)
+			os.Remove(path)
+		} else {
+			info.File = path
+			bc.store.add(path, bc.captured)
+		}
+		bc.file = nil
+	}
+
+	if info.Kind == BodyKindMultipart {
+		if preview = bc.multipartPreview(info, limit); preview == "" {
+			info.Kind = BodyKindText
+			preview, _ = bodyPreview(info, sample, limit, complete)
+		}
+	}
+	return preview, info
+}
+
+// multipartPreview reads the whole captured body to summarize its parts.
+// Must be called with bc.mu held, after the file is closed.
+func (bc *bodyCapture) multipartPreview(info *BodyInfo, limit int) string {
+	var src io.Reader = bytes.NewReader(bc.head.Bytes())
+	if info.File != "" {
+		f, err := os.Open(info.File)
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		src = f
+	}
+	r, ok := decodeReader(src, info.Encoding)
+	if !ok {
+		return ""
+	}
+	return multipartPreview(r, info.ContentType, limit)
+}
+
+// captureReader records a request body as the upstream reads it, which works
+// for chunked uploads of unknown length.
+type captureReader struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (cr *captureReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	if n > 0 {
+		cr.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+// decodeReader undoes a Content-Encoding. ok is false for encodings that
+// can't be decoded here (e.g. br, zstd); r is then returned unchanged.
+func decodeReader(r io.Reader, encoding string) (io.Reader, bool) {
+	switch encoding {
+	case "", "identity":
+		return r, true
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return r, false
+		}
+		return zr, true
+	case "deflate":
+		// "deflate" is zlib-wrapped per the spec, but some servers send raw deflate
+		br := bufio.NewReader(r)
+		if head, err := br.Peek(2); err == nil && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+			if zr, err := zlib.NewReader(br); err == nil {
+				return zr, true
+			}
+		}
+		return flate.NewReader(br), true
+	default:
+		return r, false
+	}
+}
+
+// decodeSample decodes up to limit+1 bytes of the start of a body, so callers
+// can tell whether the sample is complete. Partial input is expected: the
+// sample of a large compressed body is only its first bytes.
+func decodeSample(raw []byte, encoding string, limit int) ([]byte, bool) {
+	r, ok := decodeReader(bytes.NewReader(raw), encoding)
+	if !ok {
+		return raw, false
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil && len(data) == 0 {
+		return raw, false
+	}
+	return data, true
+}
+
+// bodyKind classifies a body by its Content-Type, sniffing the content when
+// the type is missing or generic.
+func bodyKind(contentType string, sample []byte, decoded bool) string {
+	if !decoded {
+		return BodyKindBinary
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return BodyKindJSON
+	case mediaType == "application/x-www-form-urlencoded":
+		return BodyKindForm
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return BodyKindMultipart
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/javascript",
+		mediaType == "application/xml",
+		mediaType == "application/graphql",
+		mediaType == "application/x-ndjson",
+		strings.HasSuffix(mediaType, "+xml"):
+		return BodyKindText
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "font/"),
+		mediaType == "application/pdf",
+		mediaType == "application/zip",
+		mediaType == "application/wasm",
+		mediaType == "application/protobuf",
+		mediaType == "application/x-protobuf":
+		return BodyKindBinary
+	}
+
+	if !looksLikeText(sample) {
+		return BodyKindBinary
+	}
+	if trimmed := bytes.TrimSpace(sample); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return BodyKindJSON
+	}
+	return BodyKindText
+}
+
+// looksLikeText reports whether data is UTF-8 text without control
+// characters. A rune cut off at the end of the sample is allowed.
+func looksLikeText(data []byte) bool {
+	if len(data) > 1024 {
+		data = data[:1024]
+	}
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size <= 1 {
+			return len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' && r != 0x1b {
+			return false
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// bodyPreview renders a decoded sample for the log entry. complete reports
+// whether the sample is the whole body; whole reports whether the preview
+// still holds all of it.
+func bodyPreview(info *BodyInfo, sample []byte, limit int, complete bool) (preview string, whole bool) {
+	if info.Kind == BodyKindBinary {
+		return binaryLabel(info, sample), false
+	}
+
+	preview = string(sample)
+	if complete {
+		switch info.Kind {
+		case BodyKindJSON:
+			var buf bytes.Buffer
+			if json.Indent(&buf, sample, "", "  ") == nil {
+				preview = buf.String()
+			}
+		case BodyKindForm:
+			if form := formPreview(preview); form != "" {
+				preview = form
+			}
+		}
+	}
+
+	if len(preview) > limit {
+		return textutil.Truncate(preview, limit) + "... [truncated]", false
+	}
+	if !complete {
+		return preview + "... [truncated]", false
+	}
+	return preview, true
+}
+
+// binaryLabel describes a binary body by type and size.
+func binaryLabel(info *BodyInfo, sample []byte) string {
+	mediaType, _, err := mime.ParseMediaType(info.ContentType)
+	if err != nil || mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType = strings.Split(http.DetectContentType(sample), ";")[0]
+	}
+	if info.Encoding != "" && !canDecode(info.Encoding) {
+		return fmt.Sprintf("[binary %s, %s-encoded, %s]", mediaType, info.Encoding, formatByteSize(info.Size))
+	}
+	return fmt.Sprintf("[binary %s, %s]", mediaType, formatByteSize(info.Size))
+}
+
+// canDecode reports whether decodeReader supports the encoding.
+func canDecode(encoding string) bool {
+	switch encoding {
+	case "", "identity", "gzip", "x-gzip", "deflate":
+		return true
+	}
+	return false
+}
+
+// formPreview lists urlencoded form fields one per line, sorted by name.
+func formPreview(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range values[k] {
+			fmt.Fprintf(&b, "%s: %s\n", k, v)
+		}
+	}
+	return b.String()
+}
+
+// multipartPreview lists the parts of a multipart body: field values inline,
+// files by name, type and size.
+func multipartPreview(r io.Reader, contentType string, limit int) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["boundary"] == "" {
+		return ""
+	}
+
+	const maxField = 200
+	var b strings.Builder
+	mr := multipart.NewReader(r, params["boundary"])
+	for b.Len() < limit {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		data, _ := io.ReadAll(io.LimitReader(part, maxField+1))
+		rest, _ := io.Copy(io.Discard, part)
+		size := int64(len(data)) + rest
+
+		name := part.FormName()
+		partType := part.Header.Get("Content-Type")
+		switch {
+		case part.FileName() != "":
+			if partType == "" {
+				partType = "application/octet-stream"
+			}
+			fmt.Fprintf(&b, "%s: [file %q, %s, %s]\n", name, part.FileName(), partType, formatByteSize(size))
+		case !looksLikeText(data):
+			fmt.Fprintf(&b, "%s: [binary, %s]\n", name, formatByteSize(size))
+		case len(data) > maxField:
+			fmt.Fprintf(&b, "%s: %s... [%s]\n", name, textutil.Truncate(string(data), maxField), formatByteSize(size))
+		default:
+			fmt.Fprintf(&b, "%s: %s\n", name, data)
+		}
+	}
+	return b.String()
+}
+
+// formatByteSize renders a byte count for previews, e.g. "12.3 KB".
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
+
+// sanitizeFileName keeps letters, digits, '-' and '_' so a proxy ID can be
+// used in a file name.
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// HTTPBody returns the full captured request or response body of a logged
+// HTTP request. part is "request" or "response" (the default).
+func (ps *ProxyServer) HTTPBody(requestID, part string) (*BodyContent, error) {
+	entry, ok := ps.logger.HTTPEntry(requestID)
+	if !ok {
+		return nil, fmt.Errorf("request %s not found in logs", requestID)
+	}
+
+	var info *BodyInfo
+	var preview string
+	switch part {
+	case "response", "":
+		part = "response"
+		info, preview = entry.ResponseBodyInfo, entry.ResponseBody
+	case "request":
+		info, preview = entry.RequestBodyInfo, entry.RequestBody
+	default:
+		return nil, fmt.Errorf("invalid body part %q (use request or response)", part)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("request %s has no captured %s body", requestID, part)
+	}
+
+	content := &BodyContent{RequestID: requestID, Part: part, Info: info}
+	if info.File == "" {
+		// Small text bodies are kept whole in the log entry
+		content.Body = preview
+		return content, nil
+	}
+
+	data, truncated, err := readStoredBody(info)
+	if err != nil {
+		return nil, fmt.Errorf("%s body of %s is no longer stored: %w", part, requestID, err)
+	}
+	content.Truncated = truncated
+
+	switch {
+	case info.Kind == BodyKindBinary || !utf8.Valid(data):
+		content.Base64 = base64.StdEncoding.EncodeToString(data)
+	case info.Kind == BodyKindJSON && !content.Truncated:
+		var buf bytes.Buffer
+		if json.Indent(&buf, data, "", "  ") == nil {
+			content.Body = buf.String()
+		} else {
+			content.Body = string(data)
+		}
+	default:
+		content.Body = string(data)
+	}
+	return content, nil
+}
+
+// capturedBody returns a logged text body: the preview when it holds the
+// whole body, otherwise the stored copy.
+func capturedBody(preview string, info *BodyInfo) ([]byte, bool) {
+	if info.File == "" {
+		return []byte(preview), true
+	}
+	data, _, err := readStoredBody(info)
+	return data, err == nil
+}
+
+// readStoredBody reads and decodes a body spilled to disk, up to maxBodyFetch
+// bytes.
+func readStoredBody(info *BodyInfo) ([]byte, bool, error) {
+	f, err := os.Open(info.File)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	r, _ := decodeReader(f, info.Encoding)
+	data, _ := io.ReadAll(io.LimitReader(r, maxBodyFetch+1))
+	if len(data) <= maxBodyFetch {
+		return data, false, nil
+	}
+	if info.Kind == BodyKindBinary {
+		return data[:maxBodyFetch], true, nil
+	}
+	return []byte(textutil.Truncate(string(data), maxBodyFetch)), true, nil
+}
+
+// BodyCapture returns the proxy's effective body capture configuration.
+func (ps *ProxyServer) BodyCapture() *protocol.BodyCaptureConfig {
+	return ps.bodies.config()
+}
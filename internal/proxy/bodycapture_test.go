@@ -0,0 +1,295 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+func TestBodyKind(t *testing.T) {
+	tests := []struct {
+		contentType string
+		sample      string
+		want        string
+	}{
+		{"application/json; charset=utf-8", `{"a":1}`, BodyKindJSON},
+		{"application/problem+json", `{}`, BodyKindJSON},
+		{"application/x-www-form-urlencoded", "a=1&b=2", BodyKindForm},
+		{"multipart/form-data; boundary=x", "--x", BodyKindMultipart},
+		{"text/html", "<p>hi</p>", BodyKindText},
+		{"image/png", "\x89PNG\r\n\x1a\n", BodyKindBinary},
+		{"", `[1, 2, 3]`, BodyKindJSON},
+		{"", "plain words", BodyKindText},
+		{"application/octet-stream", "\x00\x01\x02", BodyKindBinary},
+		{"", "héllo wörld", BodyKindText},
+	}
+
+	for _, tt := range tests {
+		if got := bodyKind(tt.contentType, []byte(tt.sample), true); got != tt.want {
+			t.Errorf("bodyKind(%q, %q) = %q, want %q", tt.contentType, tt.sample, got, tt.want)
+		}
+	}
+	if got := bodyKind("application/json", []byte("{}"), false); got != BodyKindBinary {
+		t.Errorf("undecodable bodies should be binary, got %q", got)
+	}
+}
+
+func TestLooksLikeText_CutRune(t *testing.T) {
+	// A multi-byte rune cut off by the preview limit is still text
+	if !looksLikeText([]byte("caf\xc3")) {
+		t.Error("expected a trailing partial rune to be allowed")
+	}
+	if looksLikeText([]byte("caf\xc3 more")) {
+		t.Error("expected invalid UTF-8 in the middle to be binary")
+	}
+}
+
+func TestBodyPreview(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      string
+		sample    string
+		complete  bool
+		want      string
+		wantWhole bool
+	}{
+		{"json pretty", BodyKindJSON, `{"a":1,"b":[true]}`, true, "{\n  \"a\": 1,\n  \"b\": [\n    true\n  ]\n}", true},
+		{"partial json", BodyKindJSON, `{"a":1,`, false, `{"a":1,... [truncated]`, false},
+		{"form", BodyKindForm, "b=2&a=1&a=3", true, "a: 1\na: 3\nb: 2\n", true},
+		{"text", BodyKindText, "hello", true, "hello", true},
+		{"over limit", BodyKindText, strings.Repeat("x", 80), true, strings.Repeat("x", 64) + "... [truncated]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, whole := bodyPreview(&BodyInfo{Kind: tt.kind}, []byte(tt.sample), 64, tt.complete)
+			if got != tt.want || whole != tt.wantWhole {
+				t.Errorf("bodyPreview() = %q, %v; want %q, %v", got, whole, tt.want, tt.wantWhole)
+			}
+		})
+	}
+
+	label, _ := bodyPreview(&BodyInfo{Kind: BodyKindBinary, Size: 2048}, []byte("\x89PNG\r\n\x1a\n"), 32, true)
+	if label != "[binary image/png, 2.0 KB]" {
+		t.Errorf("unexpected binary label %q", label)
+	}
+	label, _ = bodyPreview(&BodyInfo{Kind: BodyKindBinary, Size: 10, ContentType: "text/css", Encoding: "br"}, nil, 32, true)
+	if label != "[binary text/css, br-encoded, 10 bytes]" {
+		t.Errorf("unexpected label for undecodable encoding %q", label)
+	}
+}
+
+func TestMultipartPreview(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "Holiday")
+	fw, _ := mw.CreateFormFile("photo", "beach.png")
+	fw.Write(bytes.Repeat([]byte{0x89, 0x00}, 1024))
+	mw.Close()
+
+	got := multipartPreview(&buf, mw.FormDataContentType(), 1024)
+	want := "title: Holiday\nphoto: [file \"beach.png\", application/octet-stream, 2.0 KB]\n"
+	if got != want {
+		t.Errorf("multipartPreview() = %q, want %q", got, want)
+	}
+}
+
+func TestBodyCapture_SpillAndLimit(t *testing.T) {
+	store := newBodyStore("test", &protocol.BodyCaptureConfig{InlineLimit: 16, MaxSize: 64, Dir: t.TempDir()})
+	defer store.close()
+
+	bc := store.capture("req-1-response")
+	for i := 0; i < 10; i++ {
+		bc.Write([]byte("0123456789"))
+	}
+	preview, info := bc.finish(http.Header{"Content-Type": {"text/plain"}})
+
+	if info.Size != 100 || !info.Truncated || info.Kind != BodyKindText {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if preview != "0123456789012345... [truncated]" {
+		t.Errorf("unexpected preview %q", preview)
+	}
+	data, err := os.ReadFile(info.File)
+	if err != nil {
+		t.Fatalf("read spilled body: %v", err)
+	}
+	if len(data) != 64 || !strings.HasPrefix(string(data), "0123456789") {
+		t.Errorf("expected the first 64 bytes on disk, got %d bytes", len(data))
+	}
+
+	// Writes after finish are ignored
+	bc.Write([]byte("late"))
+	if bc.size != 100 {
+		t.Errorf("expected writes after finish to be ignored")
+	}
+
+	// Small text bodies stay inline; empty bodies have no info
+	small := store.capture("req-2-response")
+	small.Write([]byte("ok"))
+	if preview, info := small.finish(http.Header{}); preview != "ok" || info.File != "" {
+		t.Errorf("expected small body inline, got %q %+v", preview, info)
+	}
+	if _, info := store.capture("req-3-request").finish(http.Header{}); info != nil {
+		t.Errorf("expected no info for an empty body")
+	}
+
+	// Negative max size disables capture
+	if newBodyStore("off", &protocol.BodyCaptureConfig{MaxSize: -1}).capture("x") != nil {
+		t.Error("expected capture to be disabled")
+	}
+}
+
+func TestBodyStore_Evicts(t *testing.T) {
+	store := newBodyStore("test", &protocol.BodyCaptureConfig{InlineLimit: 4, StoreLimit: 25, Dir: t.TempDir()})
+	defer store.close()
+
+	var files []string
+	for _, name := range []string{"a", "b", "c"} {
+		bc := store.capture(name)
+		bc.Write(bytes.Repeat([]byte("x"), 10))
+		_, info := bc.finish(http.Header{})
+		files = append(files, info.File)
+	}
+
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("expected the oldest body to be evicted")
+	}
+	for _, f := range files[1:] {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("expected %s to be kept: %v", f, err)
+		}
+	}
+
+	dir := store.dir
+	store.close()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected close to remove the store directory")
+	}
+}
+
+func TestProxyBodyCapture(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	large := `{"items":[` + strings.Repeat(`{"id":1},`, 500) + `{"id":2}]}`
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			io.WriteString(zw, large)
+			zw.Close()
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ok":true}`)
+		}
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	ps, err := NewProxyServer(ProxyConfig{
+		ID:          "test-bodies",
+		TargetURL:   upstream.URL,
+		ListenPort:  0,
+		BodyCapture: &protocol.BodyCaptureConfig{InlineLimit: 1024, Dir: dir},
+	})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ps.Stop(context.Background())
+	<-ps.Ready()
+
+	base := "http://" + ps.ListenAddr
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	// Chunked upload of unknown length
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, `{"name":`)
+		io.WriteString(pw, `"widget"}`)
+		pw.Close()
+	}()
+	resp, err := client.Post(base+"/items", "application/json", pr)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, path := range []string{"/large", "/logo.png"} {
+		req, _ := http.NewRequest("GET", base+path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	entries := map[string]*HTTPLogEntry{}
+	for _, e := range ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeHTTP}}) {
+		entries[e.HTTP.URL] = e.HTTP
+	}
+
+	post := entries["/items"]
+	if post.RequestBody != "{\n  \"name\": \"widget\"\n}" || post.RequestBodyInfo.Kind != BodyKindJSON {
+		t.Errorf("expected pretty-printed chunked request body, got %q %+v", post.RequestBody, post.RequestBodyInfo)
+	}
+
+	gz := entries["/large"]
+	if info := gz.ResponseBodyInfo; info.Encoding != "gzip" || info.Kind != BodyKindJSON || info.File == "" {
+		t.Errorf("unexpected gzip body info %+v", info)
+	}
+	if !strings.HasPrefix(gz.ResponseBody, `{"items":[{"id":1}`) || !strings.HasSuffix(gz.ResponseBody, "... [truncated]") {
+		t.Errorf("expected decoded, truncated preview, got %.60q", gz.ResponseBody)
+	}
+	full, err := ps.HTTPBody(gz.ID, "response")
+	if err != nil {
+		t.Fatalf("HTTPBody: %v", err)
+	}
+	if !strings.Contains(full.Body, `"id": 2`) || full.Truncated {
+		t.Errorf("expected the full decoded body, got %d bytes (truncated=%v)", len(full.Body), full.Truncated)
+	}
+
+	img := entries["/logo.png"]
+	if img.ResponseBody != "[binary image/png, 108 bytes]" || img.ResponseBodyInfo.Kind != BodyKindBinary {
+		t.Errorf("unexpected binary preview %q", img.ResponseBody)
+	}
+	content, err := ps.HTTPBody(img.ID, "")
+	if err != nil {
+		t.Fatalf("HTTPBody: %v", err)
+	}
+	if decoded, _ := base64.StdEncoding.DecodeString(content.Base64); !bytes.Equal(decoded, png) {
+		t.Errorf("expected the image bytes back")
+	}
+
+	if _, err := ps.HTTPBody(img.ID, "request"); err == nil {
+		t.Error("expected an error for a GET without a request body")
+	}
+	if _, err := ps.HTTPBody("req-999", "response"); err == nil {
+		t.Error("expected an error for an unknown request")
+	}
+
+	ps.Stop(context.Background())
+	if _, err := os.Stat(gz.ResponseBodyInfo.File); !os.IsNotExist(err) {
+		t.Error("expected stored bodies to be removed when the proxy stops")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math/rand"
 	"net/http"
 	"regexp"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,14 +28,19 @@ const (
 	ChaosOutOfOrder ChaosType = "out_of_order" // Reorder responses
 
 	// HTTP errors
-	ChaosHTTPError ChaosType = "http_error" // Inject HTTP error codes
-	ChaosRateLimit ChaosType = "rate_limit" // Simulate rate limiting (429)
+	ChaosHTTPError    ChaosType = "http_error"    // Inject HTTP error codes
+	ChaosRateLimit    ChaosType = "rate_limit"    // Simulate rate limiting (429)
+	ChaosGraphQLError ChaosType = "graphql_error" // Answer GraphQL requests with an errors response
 
 	// Data corruption
 	ChaosBitFlip     ChaosType = "bit_flip"     // Random byte changes
 	ChaosTruncate    ChaosType = "truncate"     // Cut off response body
 	ChaosCorruptJSON ChaosType = "corrupt_json" // Malform JSON responses
 
+	// Streaming responses (SSE events and NDJSON lines)
+	ChaosSSEDelay ChaosType = "sse_delay" // Delay individual events
+	ChaosSSEDrop  ChaosType = "sse_drop"  // Drop individual events
+
 	// Protocol edge cases
 	ChaosChunkedAbort ChaosType = "chunked_abort" // No terminal chunk
 	ChaosPartialBody  ChaosType = "partial_body"  // Incomplete body
@@ -61,6 +67,11 @@ type ChaosRule struct {
 	URLPattern  string   `json:"url_pattern,omitempty"` // Regex pattern for URL
 	Methods     []string `json:"methods,omitempty"`     // HTTP methods (empty = all)
 	Probability float64  `json:"probability,omitempty"` // 0.0-1.0, default 1.0
+	Upstream    string   `json:"upstream,omitempty"`    // Route name, upstream URL or host (empty = all)
+
+	// GraphQL matching (rules with either only match GraphQL requests)
+	Operation     string `json:"operation,omitempty"`      // Regex for operation names
+	OperationType string `json:"operation_type,omitempty"` // query, mutation or subscription
 
 	// Latency config
 	MinLatencyMs int `json:"min_latency_ms,omitempty"`
@@ -76,8 +87,13 @@ type ChaosRule struct {
 	DropAfterBytes   int64   `json:"drop_after_bytes,omitempty"`   // Drop after N bytes
 
 	// Error injection config
-	ErrorCodes   []int  `json:"error_codes,omitempty"` // HTTP status codes
-	ErrorMessage string `json:"error_message,omitempty"`
+	ErrorCodes       []int  `json:"error_codes,omitempty"` // HTTP status codes (graphql_error: default 200)
+	ErrorMessage     string `json:"error_message,omitempty"`
+	GraphQLErrorCode string `json:"graphql_error_code,omitempty"` // extensions.code for graphql_error
+
+	// Stream event config (sse_delay uses the latency config per event)
+	EventPattern     string  `json:"event_pattern,omitempty"`     // Regex on the event name or data (empty = all)
+	EventProbability float64 `json:"event_probability,omitempty"` // Chance per event, 0.0-1.0 (default 1.0)
 
 	// Truncation config
 	TruncatePercent float64 `json:"truncate_percent,omitempty"` // Keep this % of response
@@ -90,7 +106,44 @@ type ChaosRule struct {
 	StaleDelayMs int64 `json:"stale_delay_ms,omitempty"` // Delay in milliseconds
 
 	// Compiled regex (internal)
-	urlRegex *regexp.Regexp
+	urlRegex       *regexp.Regexp
+	operationRegex *regexp.Regexp
+	eventRegex     *regexp.Regexp
+}
+
+// compile compiles the rule's patterns and applies defaults.
+func (r *ChaosRule) compile() error {
+	if r.URLPattern != "" {
+		regex, err := regexp.Compile(r.URLPattern)
+		if err != nil {
+			return err
+		}
+		r.urlRegex = regex
+	}
+	if r.Operation != "" {
+		regex, err := regexp.Compile(r.Operation)
+		if err != nil {
+			return err
+		}
+		r.operationRegex = regex
+	}
+	if r.EventPattern != "" {
+		regex, err := regexp.Compile(r.EventPattern)
+		if err != nil {
+			return err
+		}
+		r.eventRegex = regex
+	}
+
+	if r.Probability == 0 {
+		r.Probability = 1.0
+	}
+	return nil
+}
+
+// matchesGraphQL reports whether the rule only applies to GraphQL requests.
+func (r *ChaosRule) matchesGraphQL() bool {
+	return r.Type == ChaosGraphQLError || r.Operation != "" || r.OperationType != ""
 }
 
 // ChaosConfig defines chaos rules for a proxy
@@ -111,6 +164,8 @@ type ChaosStats struct {
 	DropsInjected   int64            `json:"drops_injected"`
 	TruncatedCount  int64            `json:"truncated_count"`
 	ReorderedCount  int64            `json:"reordered_count"`
+	EventsDelayed   int64            `json:"events_delayed"`
+	EventsDropped   int64            `json:"events_dropped"`
 	RuleStats       map[string]int64 `json:"rule_stats"` // Rule ID -> times applied
 }
 
@@ -150,6 +205,8 @@ type chaosStatsAtomic struct {
 	dropsInjected   atomic.Int64
 	truncatedCount  atomic.Int64
 	reorderedCount  atomic.Int64
+	eventsDelayed   atomic.Int64
+	eventsDropped   atomic.Int64
 }
 
 // NewChaosEngine creates a new chaos engine
@@ -192,20 +249,11 @@ func (ce *ChaosEngine) SetConfig(config *ChaosConfig) error {
 	ce.mu.Lock()
 	defer ce.mu.Unlock()
 
-	// Compile URL patterns
+	// Compile patterns and set defaults
 	rules := make([]*chaosRuleState, 0, len(config.Rules))
 	for _, r := range config.Rules {
-		if r.URLPattern != "" {
-			regex, err := regexp.Compile(r.URLPattern)
-			if err != nil {
-				return err
-			}
-			r.urlRegex = regex
-		}
-
-		// Set defaults
-		if r.Probability == 0 {
-			r.Probability = 1.0
+		if err := r.compile(); err != nil {
+			return err
 		}
 
 		state := &chaosRuleState{rule: r}
@@ -251,16 +299,8 @@ func (ce *ChaosEngine) AddRule(rule *ChaosRule) error {
 	ce.mu.Lock()
 	defer ce.mu.Unlock()
 
-	if rule.URLPattern != "" {
-		regex, err := regexp.Compile(rule.URLPattern)
-		if err != nil {
-			return err
-		}
-		rule.urlRegex = regex
-	}
-
-	if rule.Probability == 0 {
-		rule.Probability = 1.0
+	if err := rule.compile(); err != nil {
+		return err
 	}
 
 	state := &chaosRuleState{rule: rule}
@@ -329,6 +369,8 @@ func (ce *ChaosEngine) GetStats() ChaosStats {
 		DropsInjected:   ce.stats.dropsInjected.Load(),
 		TruncatedCount:  ce.stats.truncatedCount.Load(),
 		ReorderedCount:  ce.stats.reorderedCount.Load(),
+		EventsDelayed:   ce.stats.eventsDelayed.Load(),
+		EventsDropped:   ce.stats.eventsDropped.Load(),
 		RuleStats:       ruleStats,
 	}
 }
@@ -410,9 +452,53 @@ func (ce *ChaosEngine) ruleMatches(rule *ChaosRule, req *http.Request) bool {
 		}
 	}
 
+	// Check upstream (for proxies with multiple routes)
+	if !upstreamMatches(rule.Upstream, req) {
+		return false
+	}
+
+	// Check GraphQL operation
+	if rule.matchesGraphQL() && !graphQLRuleMatches(rule, requestGraphQL(req)) {
+		return false
+	}
+
 	return true
 }
 
+// graphQLRuleMatches checks a rule's operation criteria against a GraphQL
+// request. Batches match if any operation does.
+func graphQLRuleMatches(rule *ChaosRule, g *GraphQLInfo) bool {
+	if g == nil {
+		return false
+	}
+	for _, op := range g.Operations {
+		if rule.OperationType != "" && !strings.EqualFold(rule.OperationType, op.Type) {
+			continue
+		}
+		if rule.operationRegex != nil && !rule.operationRegex.MatchString(op.Name) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// NeedsGraphQL reports whether any enabled rule matches by GraphQL
+// operation, so requests must be parsed before the rules are checked.
+func (ce *ChaosEngine) NeedsGraphQL() bool {
+	if !ce.enabled.Load() {
+		return false
+	}
+	ce.mu.RLock()
+	defer ce.mu.RUnlock()
+	for _, state := range ce.rules {
+		if state.enabled.Load() && state.rule.matchesGraphQL() {
+			return true
+		}
+	}
+	return false
+}
+
 // GetLatencyDelay calculates latency delay for matching rules
 func (ce *ChaosEngine) GetLatencyDelay(rules []*ChaosRule) time.Duration {
 	var totalDelay time.Duration
@@ -467,6 +553,64 @@ func (ce *ChaosEngine) GetHTTPError(rules []*ChaosRule) (int, string) {
 	return 0, ""
 }
 
+// GetGraphQLError returns the first matching graphql_error rule, or nil.
+func (ce *ChaosEngine) GetGraphQLError(rules []*ChaosRule) *ChaosRule {
+	for _, rule := range rules {
+		if rule.Type == ChaosGraphQLError {
+			ce.stats.errorsInjected.Add(1)
+			return rule
+		}
+	}
+	return nil
+}
+
+// StreamEventRules returns the matching rules that act on individual events
+// of streaming responses.
+func (ce *ChaosEngine) StreamEventRules(rules []*ChaosRule) []*ChaosRule {
+	var events []*ChaosRule
+	for _, rule := range rules {
+		if rule.Type == ChaosSSEDelay || rule.Type == ChaosSSEDrop {
+			events = append(events, rule)
+		}
+	}
+	return events
+}
+
+// StreamEventAction decides whether to drop or delay one stream event.
+func (ce *ChaosEngine) StreamEventAction(rules []*ChaosRule, event, data string) (drop bool, delay time.Duration) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	for _, rule := range rules {
+		if rule.eventRegex != nil && !rule.eventRegex.MatchString(event) && !rule.eventRegex.MatchString(data) {
+			continue
+		}
+		if p := rule.EventProbability; p > 0 && p < 1.0 && ce.rng.Float64() > p {
+			continue
+		}
+
+		switch rule.Type {
+		case ChaosSSEDrop:
+			ce.stats.eventsDropped.Add(1)
+			return true, 0
+		case ChaosSSEDelay:
+			minMs, maxMs := rule.MinLatencyMs, rule.MaxLatencyMs
+			if maxMs <= minMs {
+				maxMs = minMs + 1
+			}
+			ms := minMs + ce.rng.Intn(maxMs-minMs)
+			if rule.JitterMs > 0 {
+				ms = max(ms+ce.rng.Intn(rule.JitterMs*2)-rule.JitterMs, 0)
+			}
+			delay += time.Duration(ms) * time.Millisecond
+		}
+	}
+	if delay > 0 {
+		ce.stats.eventsDelayed.Add(1)
+	}
+	return false, delay
+}
+
 // ShouldDrop returns true if the connection should be dropped
 func (ce *ChaosEngine) ShouldDrop(rules []*ChaosRule) bool {
 	for _, rule := range rules {
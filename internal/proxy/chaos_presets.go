@@ -352,6 +352,9 @@ func copyRule(src *ChaosRule) *ChaosRule {
 		Enabled:            src.Enabled,
 		URLPattern:         src.URLPattern,
 		Probability:        src.Probability,
+		Upstream:           src.Upstream,
+		Operation:          src.Operation,
+		OperationType:      src.OperationType,
 		MinLatencyMs:       src.MinLatencyMs,
 		MaxLatencyMs:       src.MaxLatencyMs,
 		JitterMs:           src.JitterMs,
@@ -360,6 +363,9 @@ func copyRule(src *ChaosRule) *ChaosRule {
 		DropAfterPercent:   src.DropAfterPercent,
 		DropAfterBytes:     src.DropAfterBytes,
 		ErrorMessage:       src.ErrorMessage,
+		GraphQLErrorCode:   src.GraphQLErrorCode,
+		EventPattern:       src.EventPattern,
+		EventProbability:   src.EventProbability,
 		TruncatePercent:    src.TruncatePercent,
 		ReorderMinRequests: src.ReorderMinRequests,
 		ReorderMaxWaitMs:   src.ReorderMaxWaitMs,
@@ -39,10 +39,16 @@ func (ct *ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return ct.underlying.RoundTrip(req)
 	}
 
+	// Time the upstream phases when the proxy asked for it
+	timer := requestUpstreamTimer(req.Context())
+	if timer != nil {
+		req = timer.trace(req)
+	}
+
 	// Get matching chaos rules
 	rules := ct.engine.MatchingRules(req)
 	if len(rules) == 0 {
-		return ct.underlying.RoundTrip(req)
+		return timer.timeBody(ct.underlying.RoundTrip(req))
 	}
 
 	// Check for packet loss (drop request entirely)
@@ -53,6 +59,7 @@ func (ct *ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	// Check for stale delay (very long delays)
 	if staleDelay := ct.engine.GetStaleDelay(rules); staleDelay > 0 {
+		timer.markChaos()
 		select {
 		case <-req.Context().Done():
 			return nil, req.Context().Err()
@@ -63,6 +70,7 @@ func (ct *ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	// Apply latency injection
 	if delay := ct.engine.GetLatencyDelay(rules); delay > 0 {
+		timer.markChaos()
 		select {
 		case <-req.Context().Done():
 			return nil, req.Context().Err()
@@ -73,11 +81,12 @@ func (ct *ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	// Check for out-of-order responses
 	if ct.engine.ShouldReorder(rules) {
-		return ct.engine.reorderQueue.Submit(req, ct.underlying, rules)
+		timer.markChaos()
+		return timer.timeBody(ct.engine.reorderQueue.Submit(req, ct.underlying, rules))
 	}
 
 	// Execute request normally
-	return ct.underlying.RoundTrip(req)
+	return timer.timeBody(ct.underlying.RoundTrip(req))
 }
 
 // isDevtoolPath checks if the path is a devtool reserved endpoint
@@ -0,0 +1,79 @@
+package proxy
+
+// EventType is the type of a proxy Event.
+type EventType string
+
+const (
+	// EventCreated is reported when a proxy has started.
+	EventCreated EventType = "created"
+	// EventStopped is reported when a proxy has been stopped.
+	EventStopped EventType = "stopped"
+	// EventCrashed is reported when a proxy's server fails unexpectedly.
+	EventCrashed EventType = "crashed"
+	// EventRestarted is reported when a crashed proxy has been restarted.
+	EventRestarted EventType = "restarted"
+	// EventPublicURL is reported when the proxy's own tunnel gets a URL.
+	EventPublicURL EventType = "public_url"
+	// EventLogged is reported for each frontend error, rejection and panel
+	// message logged.
+	EventLogged EventType = "logged"
+)
+
+// Event is something that happened to a proxy, reported to the handler set
+// with ProxyManager.SetEventHandler.
+type Event struct {
+	Type    EventType
+	ProxyID string
+	// Path is the project the proxy belongs to
+	Path string
+	// Error is why the server crashed
+	Error string
+	// URL is the public URL of EventPublicURL
+	URL string
+	// Entry is the log entry of EventLogged
+	Entry *LogEntry
+}
+
+// EventHandler receives the events of proxies. It is called synchronously
+// and must not block.
+type EventHandler func(Event)
+
+// SetEventHandler sets the handler for the events of the proxies created
+// from now on.
+func (pm *ProxyManager) SetEventHandler(handler EventHandler) {
+	pm.eventHandler.Store(&handler)
+}
+
+// emit reports an event to the handler, if any.
+func (pm *ProxyManager) emit(ev Event) {
+	if handler := pm.eventHandler.Load(); handler != nil && *handler != nil {
+		(*handler)(ev)
+	}
+}
+
+// emit reports an event of the proxy to its manager's handler.
+func (ps *ProxyServer) emit(ev Event) {
+	if ps.onEvent == nil {
+		return
+	}
+	ev.ProxyID = ps.ID
+	ev.Path = ps.Path
+	ps.onEvent(ev)
+}
+
+// logged counts log entries for the metrics and reports those that clients
+// react to: frontend errors, unhandled rejections and panel messages.
+func (ps *ProxyServer) logged(entry LogEntry) {
+	ps.metrics.observe(entry)
+
+	switch entry.Type {
+	case LogTypeError, LogTypeRejection, LogTypePanelMessage:
+		ps.emit(Event{Type: EventLogged, Entry: &entry})
+	}
+}
+
+// tunnelURL keeps the public URL in sync with the proxy's tunnel.
+func (ps *ProxyServer) tunnelURL(url string) {
+	ps.UpdatePublicURL(url)
+	ps.emit(Event{Type: EventPublicURL, URL: url})
+}
@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestProxyManager_Events(t *testing.T) {
+	pm := NewProxyManager()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var events []Event
+	pm.SetEventHandler(func(ev Event) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	})
+
+	proxy, err := pm.Create(ctx, ProxyConfig{
+		ID:         "events",
+		TargetURL:  "http://localhost:9999",
+		ListenPort: 0,
+		Path:       "/app",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+
+	proxy.Logger().LogHTTP(HTTPLogEntry{ID: "req-1"})
+	proxy.Logger().LogError(FrontendError{Message: "boom"})
+	proxy.Logger().LogPanelMessage(PanelMessage{Message: "hello"})
+	proxy.tunnelURL("https://abc.trycloudflare.com")
+
+	if err := pm.Stop(ctx, "events"); err != nil {
+		t.Fatalf("Failed to stop proxy: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []EventType{EventCreated, EventLogged, EventLogged, EventPublicURL, EventStopped}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), events)
+	}
+	for i, ev := range events {
+		if ev.Type != want[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, want[i], ev.Type)
+		}
+		if ev.ProxyID != "events" || ev.Path != "/app" {
+			t.Errorf("Event %d: expected proxy events in /app, got %q in %q", i, ev.ProxyID, ev.Path)
+		}
+	}
+	if events[1].Entry.Error == nil || events[1].Entry.Error.Message != "boom" {
+		t.Errorf("Expected the logged error, got %+v", events[1].Entry)
+	}
+	if events[2].Entry.Type != LogTypePanelMessage {
+		t.Errorf("Expected a panel message, got %s", events[2].Entry.Type)
+	}
+	if events[3].URL != "https://abc.trycloudflare.com" {
+		t.Errorf("Expected the tunnel URL, got %q", events[3].URL)
+	}
+}
@@ -0,0 +1,471 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GraphQL operation types.
+const (
+	GraphQLQuery        = "query"
+	GraphQLMutation     = "mutation"
+	GraphQLSubscription = "subscription"
+)
+
+const (
+	// maxGraphQLVariables is the largest variables object kept in log
+	// entries; the request body holds larger ones.
+	maxGraphQLVariables = 2048
+	// maxGraphQLErrors caps the response errors recorded per request.
+	maxGraphQLErrors = 10
+	// maxGraphQLPeek is how much of a request body is read ahead to match
+	// chaos rules by operation.
+	maxGraphQLPeek = 1024 * 1024
+)
+
+// GraphQLInfo describes the GraphQL operations of an HTTP request and the
+// errors its response reported.
+type GraphQLInfo struct {
+	Operations []GraphQLOperation `json:"operations"`
+	Batch      bool               `json:"batch,omitempty"` // Request was an array of operations
+	Errors     []GraphQLError     `json:"errors,omitempty"`
+	ErrorCount int                `json:"error_count,omitempty"` // Including errors beyond those listed
+}
+
+// GraphQLOperation is one operation of a GraphQL request.
+type GraphQLOperation struct {
+	Type                string          `json:"type,omitempty"` // query, mutation, subscription (empty for persisted queries)
+	Name                string          `json:"name,omitempty"`
+	Variables           json.RawMessage `json:"variables,omitempty"` // Omitted when larger than 2KB
+	PersistedQueryHash  string          `json:"persisted_query_hash,omitempty"`
+	VariablesOmittedLen int             `json:"variables_omitted_bytes,omitempty"`
+}
+
+// GraphQLError is an entry of a GraphQL response's errors array.
+type GraphQLError struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"` // extensions.code
+	Path    string `json:"path,omitempty"` // e.g. "viewer.repositories.0"
+}
+
+// Label names the operation for summaries, e.g. "query GetViewer".
+func (op GraphQLOperation) Label() string {
+	name := op.Name
+	if name == "" && op.PersistedQueryHash != "" {
+		hash := op.PersistedQueryHash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		name = "persisted:" + hash
+	}
+	if name == "" {
+		name = "(anonymous)"
+	}
+	if op.Type == "" {
+		return name
+	}
+	return op.Type + " " + name
+}
+
+// Label names the request's operations, joining batches with "+".
+func (g *GraphQLInfo) Label() string {
+	labels := make([]string, len(g.Operations))
+	for i, op := range g.Operations {
+		labels[i] = op.Label()
+	}
+	return strings.Join(labels, " + ")
+}
+
+// graphQLKey is the request context key for parsed GraphQL operations.
+type graphQLKey struct{}
+
+// requestGraphQL returns the GraphQL operations parsed ahead of proxying.
+func requestGraphQL(r *http.Request) *GraphQLInfo {
+	g, _ := r.Context().Value(graphQLKey{}).(*GraphQLInfo)
+	return g
+}
+
+// peekGraphQL reads a GraphQL request body ahead of proxying so chaos rules
+// can match by operation, and stores the parsed operations in the request
+// context. The body is restored for the upstream.
+func peekGraphQL(r *http.Request) *http.Request {
+	if r.Method == http.MethodGet {
+		if g := parseGraphQLRequest(r, nil); g != nil {
+			return r.WithContext(context.WithValue(r.Context(), graphQLKey{}, g))
+		}
+		return r
+	}
+	if !isGraphQLContentType(r.Header.Get("Content-Type")) || r.Body == nil || r.Body == http.NoBody {
+		return r
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r.Body, maxGraphQLPeek))
+	r.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(head), r.Body), Closer: r.Body}
+	if err != nil {
+		return r
+	}
+	if g := parseGraphQLRequest(r, head); g != nil {
+		return r.WithContext(context.WithValue(r.Context(), graphQLKey{}, g))
+	}
+	return r
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// isGraphQLContentType reports whether a request body may carry GraphQL.
+func isGraphQLContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || mediaType == "application/graphql" ||
+		mediaType == "application/graphql+json" || mediaType == ""
+}
+
+// graphQLParams are the fields of a GraphQL-over-HTTP request.
+type graphQLParams struct {
+	Query         *string         `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+	Extensions    struct {
+		PersistedQuery struct {
+			Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// parseGraphQLRequest extracts GraphQL operations from a request, using
+// the query string for GET and body for other methods. It returns nil for
+// requests that aren't GraphQL.
+func parseGraphQLRequest(r *http.Request, body []byte) *GraphQLInfo {
+	if r.Method == http.MethodGet {
+		return parseGraphQLQueryString(r.URL.Query())
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/graphql" {
+		op, ok := parseGraphQLParams(graphQLParams{Query: ptr(string(body))})
+		if !ok {
+			return nil
+		}
+		return &GraphQLInfo{Operations: []GraphQLOperation{op}}
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	if body[0] == '[' {
+		var batch []graphQLParams
+		if json.Unmarshal(body, &batch) != nil || len(batch) == 0 {
+			return nil
+		}
+		info := &GraphQLInfo{Batch: true}
+		for _, p := range batch {
+			op, ok := parseGraphQLParams(p)
+			if !ok {
+				return nil
+			}
+			info.Operations = append(info.Operations, op)
+		}
+		return info
+	}
+
+	var p graphQLParams
+	if json.Unmarshal(body, &p) != nil {
+		return nil
+	}
+	op, ok := parseGraphQLParams(p)
+	if !ok {
+		return nil
+	}
+	return &GraphQLInfo{Operations: []GraphQLOperation{op}}
+}
+
+// parseGraphQLQueryString parses a GraphQL GET request.
+func parseGraphQLQueryString(q url.Values) *GraphQLInfo {
+	if !q.Has("query") && !q.Has("extensions") {
+		return nil
+	}
+	p := graphQLParams{OperationName: q.Get("operationName")}
+	if q.Has("query") {
+		p.Query = ptr(q.Get("query"))
+	}
+	if v := q.Get("variables"); v != "" && json.Valid([]byte(v)) {
+		p.Variables = json.RawMessage(v)
+	}
+	if ext := q.Get("extensions"); ext != "" {
+		json.Unmarshal([]byte(ext), &p.Extensions)
+	}
+	op, ok := parseGraphQLParams(p)
+	if !ok {
+		return nil
+	}
+	return &GraphQLInfo{Operations: []GraphQLOperation{op}}
+}
+
+// parseGraphQLParams builds an operation from request parameters. Requests
+// need a query document or a persisted query hash to count as GraphQL.
+func parseGraphQLParams(p graphQLParams) (GraphQLOperation, bool) {
+	op := GraphQLOperation{
+		Name:               p.OperationName,
+		PersistedQueryHash: p.Extensions.PersistedQuery.Hash,
+	}
+
+	if p.Query != nil && strings.TrimSpace(*p.Query) != "" {
+		ops := graphQLDefinitions(*p.Query)
+		if len(ops) == 0 {
+			return op, false
+		}
+		chosen := ops[0]
+		if op.Name != "" {
+			for _, d := range ops {
+				if d.Name == op.Name {
+					chosen = d
+					break
+				}
+			}
+		}
+		op.Type = chosen.Type
+		if op.Name == "" {
+			op.Name = chosen.Name
+		}
+	} else if op.PersistedQueryHash == "" {
+		return op, false
+	}
+
+	vars := bytes.TrimSpace(p.Variables)
+	if len(vars) > 0 && !bytes.Equal(vars, []byte("null")) {
+		var compact bytes.Buffer
+		if json.Compact(&compact, vars) == nil {
+			vars = compact.Bytes()
+		}
+		if len(vars) <= maxGraphQLVariables {
+			op.Variables = json.RawMessage(vars)
+		} else {
+			op.VariablesOmittedLen = len(vars)
+		}
+	}
+	return op, true
+}
+
+// graphQLDefinitions returns the operation definitions of a GraphQL
+// document in order: their type and name. Fragments are skipped.
+func graphQLDefinitions(doc string) []GraphQLOperation {
+	const (
+		topLevel     = iota // Between definitions
+		awaitingName        // After query/mutation/subscription
+		awaitingBody        // Inside a definition header, before its selection set
+	)
+
+	var ops []GraphQLOperation
+	state := topLevel
+	depth := 0
+
+	for i := 0; i < len(doc); {
+		c := doc[i]
+		switch {
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '"':
+			i = skipGraphQLString(doc, i)
+			continue
+		case isGraphQLNameStart(c):
+			start := i
+			for i < len(doc) && isGraphQLNameByte(doc[i]) {
+				i++
+			}
+			if depth > 0 {
+				continue
+			}
+			word := doc[start:i]
+			switch state {
+			case awaitingName:
+				ops[len(ops)-1].Name = word
+				state = awaitingBody
+			case topLevel:
+				switch word {
+				case GraphQLQuery, GraphQLMutation, GraphQLSubscription:
+					ops = append(ops, GraphQLOperation{Type: word})
+					state = awaitingName
+				case "fragment":
+					state = awaitingBody
+				}
+			}
+			continue
+		case c == '{' || c == '(' || c == '[':
+			if depth == 0 && c == '{' {
+				if state == topLevel {
+					// Shorthand "{ ... }" is an anonymous query
+					ops = append(ops, GraphQLOperation{Type: GraphQLQuery})
+				}
+				state = topLevel
+			} else if state == awaitingName {
+				state = awaitingBody
+			}
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+		case c == '$' || c == '@':
+			if state == awaitingName {
+				state = awaitingBody
+			}
+		}
+		i++
+	}
+	return ops
+}
+
+// skipGraphQLString returns the index after the string or block string at i.
+func skipGraphQLString(doc string, i int) int {
+	if strings.HasPrefix(doc[i:], `"""`) {
+		if end := strings.Index(doc[i+3:], `"""`); end >= 0 {
+			return i + 3 + end + 3
+		}
+		return len(doc)
+	}
+	for j := i + 1; j < len(doc); j++ {
+		switch doc[j] {
+		case '\\':
+			j++
+		case '"', '\n':
+			return j + 1
+		}
+	}
+	return len(doc)
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isGraphQLNameByte(c byte) bool {
+	return isGraphQLNameStart(c) || c >= '0' && c <= '9'
+}
+
+// recordErrors records the errors of a GraphQL JSON response, or of each
+// response in a batch.
+func (g *GraphQLInfo) recordErrors(body []byte) {
+	type response struct {
+		Errors []struct {
+			Message    string        `json:"message"`
+			Path       []interface{} `json:"path"`
+			Extensions struct {
+				Code interface{} `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+
+	var responses []response
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		if json.Unmarshal(body, &responses) != nil {
+			return
+		}
+	} else {
+		var single response
+		if json.Unmarshal(body, &single) != nil {
+			return
+		}
+		responses = []response{single}
+	}
+
+	for _, resp := range responses {
+		for _, e := range resp.Errors {
+			g.ErrorCount++
+			if len(g.Errors) >= maxGraphQLErrors {
+				continue
+			}
+			gqlErr := GraphQLError{Message: e.Message}
+			if e.Extensions.Code != nil {
+				gqlErr.Code = strings.Trim(jsonString(e.Extensions.Code), `"`)
+			}
+			if len(e.Path) > 0 {
+				parts := make([]string, len(e.Path))
+				for i, p := range e.Path {
+					parts[i] = strings.Trim(jsonString(p), `"`)
+				}
+				gqlErr.Path = strings.Join(parts, ".")
+			}
+			g.Errors = append(g.Errors, gqlErr)
+		}
+	}
+}
+
+// graphQLErrorBody builds a GraphQL error response for each operation of a
+// request, as injected by graphql_error chaos rules.
+func graphQLErrorBody(g *GraphQLInfo, message, code string) []byte {
+	if message == "" {
+		message = "Injected GraphQL error"
+	}
+	if code == "" {
+		code = "INTERNAL_SERVER_ERROR"
+	}
+	result := map[string]interface{}{
+		"data": nil,
+		"errors": []map[string]interface{}{{
+			"message":    message,
+			"extensions": map[string]string{"code": code},
+		}},
+	}
+
+	var body []byte
+	if g != nil && g.Batch {
+		batch := make([]interface{}, len(g.Operations))
+		for i := range batch {
+			batch[i] = result
+		}
+		body, _ = json.Marshal(batch)
+	} else {
+		body, _ = json.Marshal(result)
+	}
+	return body
+}
+
+// graphQLForLog returns the GraphQL details of a logged request: its
+// operations, parsed ahead of proxying or now from the captured request
+// body, and the errors of its JSON response.
+func graphQLForLog(r *http.Request, reqBody string, reqInfo *BodyInfo, respBody string, respInfo *BodyInfo) *GraphQLInfo {
+	var g *GraphQLInfo
+	switch {
+	case requestGraphQL(r) != nil:
+		c := *requestGraphQL(r)
+		g = &c
+	case r.Method == http.MethodGet:
+		g = parseGraphQLQueryString(r.URL.Query())
+	case reqInfo != nil && (reqInfo.Kind == BodyKindJSON || reqInfo.Kind == BodyKindText):
+		if data, ok := capturedBody(reqBody, reqInfo); ok {
+			g = parseGraphQLRequest(r, data)
+		}
+	}
+	if g == nil {
+		return nil
+	}
+
+	if respInfo != nil && respInfo.Kind == BodyKindJSON {
+		if data, ok := capturedBody(respBody, respInfo); ok {
+			g.recordErrors(data)
+		}
+	}
+	return g
+}
+
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
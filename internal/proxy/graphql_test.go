@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLDefinitions(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{`{ viewer { id } }`, "query "},
+		{`query GetViewer { viewer { id } }`, "query GetViewer"},
+		{`mutation AddStar($id: ID!) @live { addStar(id: $id) { ok } }`, "mutation AddStar"},
+		{`query ($id: ID!) { node(id: $id) { id } }`, "query "},
+		{`# comment with query Fake
+		fragment F on User { name }
+		subscription OnEvent { event { ...F } }`, "subscription OnEvent"},
+		{`query A { a(s: "query B { }") } mutation C { c }`, "query A,mutation C"},
+		{`query Doc { a(s: """ block "quoted" { """) }`, "query Doc"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, op := range graphQLDefinitions(tt.doc) {
+			got = append(got, op.Type+" "+op.Name)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("graphQLDefinitions(%q) = %q, want %q", tt.doc, strings.Join(got, ","), tt.want)
+		}
+	}
+}
+
+func TestParseGraphQLRequest(t *testing.T) {
+	post := func(contentType, body string) *GraphQLInfo {
+		r := httptest.NewRequest("POST", "/graphql", nil)
+		r.Header.Set("Content-Type", contentType)
+		return parseGraphQLRequest(r, []byte(body))
+	}
+
+	g := post("application/json", `{"query": "query A { a } mutation B { b }", "operationName": "B", "variables": {"id": 1}}`)
+	if g == nil || g.Operations[0].Label() != "mutation B" || string(g.Operations[0].Variables) != `{"id":1}` {
+		t.Errorf("unexpected single operation %+v", g)
+	}
+
+	g = post("application/json", `[{"query": "query A { a }"}, {"operationName": "B", "extensions": {"persistedQuery": {"version": 1, "sha256Hash": "abc123"}}}]`)
+	if g == nil || !g.Batch || g.Label() != "query A + B" || g.Operations[1].PersistedQueryHash != "abc123" {
+		t.Errorf("unexpected batch %+v", g)
+	}
+
+	g = post("application/graphql", `mutation Save { save }`)
+	if g == nil || g.Label() != "mutation Save" {
+		t.Errorf("unexpected application/graphql request %+v", g)
+	}
+
+	big := `{"query": "{ a }", "variables": {"blob": "` + strings.Repeat("x", maxGraphQLVariables) + `"}}`
+	if g = post("application/json", big); g == nil || g.Operations[0].Variables != nil || g.Operations[0].VariablesOmittedLen == 0 {
+		t.Errorf("expected large variables to be omitted, got %+v", g)
+	}
+
+	for _, body := range []string{`{"name": "widget"}`, `[1, 2]`, `{"query": ""}`, `not json`} {
+		if g := post("application/json", body); g != nil {
+			t.Errorf("expected %q not to be GraphQL, got %+v", body, g)
+		}
+	}
+
+	get := httptest.NewRequest("GET", "/graphql?"+url.Values{
+		"query":     {"query Search($q: String) { search(q: $q) }"},
+		"variables": {`{"q": "go"}`},
+	}.Encode(), nil)
+	if g := parseGraphQLRequest(get, nil); g == nil || g.Label() != "query Search" {
+		t.Errorf("unexpected GET request %+v", g)
+	}
+	if g := parseGraphQLRequest(httptest.NewRequest("GET", "/graphql", nil), nil); g != nil {
+		t.Errorf("expected a GET without query to be ignored")
+	}
+}
+
+func TestGraphQLRecordErrors(t *testing.T) {
+	g := &GraphQLInfo{}
+	g.recordErrors([]byte(`{"data": null, "errors": [{"message": "denied", "path": ["viewer", "repos", 0], "extensions": {"code": "FORBIDDEN"}}]}`))
+	if g.ErrorCount != 1 || g.Errors[0] != (GraphQLError{Message: "denied", Code: "FORBIDDEN", Path: "viewer.repos.0"}) {
+		t.Errorf("unexpected errors %+v", g.Errors)
+	}
+
+	batch := &GraphQLInfo{}
+	batch.recordErrors([]byte(`[{"data": {}}, {"errors": [{"message": "a"}, {"message": "b"}]}]`))
+	if batch.ErrorCount != 2 || len(batch.Errors) != 2 {
+		t.Errorf("unexpected batch errors %+v", batch)
+	}
+}
+
+func TestChaosEngine_GraphQLRules(t *testing.T) {
+	ce := NewChaosEngine(nil)
+	if err := ce.SetConfig(&ChaosConfig{
+		Enabled: true,
+		Rules: []*ChaosRule{
+			{ID: "viewer", Type: ChaosGraphQLError, Enabled: true, Operation: "^GetViewer$"},
+			{ID: "mutations", Type: ChaosLatency, Enabled: true, OperationType: "mutation"},
+			{ID: "all", Type: ChaosLatency, Enabled: true},
+		},
+	}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if !ce.NeedsGraphQL() {
+		t.Fatal("expected operation rules to need GraphQL parsing")
+	}
+
+	ruleIDs := func(body string) string {
+		r := httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r = peekGraphQL(r)
+		if rest, _ := io.ReadAll(r.Body); string(rest) != body {
+			t.Errorf("expected the body to be restored, got %q", rest)
+		}
+		var ids []string
+		for _, rule := range ce.MatchingRules(r) {
+			ids = append(ids, rule.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	if got := ruleIDs(`{"query": "query GetViewer { viewer { id } }"}`); got != "viewer,all" {
+		t.Errorf("query GetViewer matched %q", got)
+	}
+	if got := ruleIDs(`[{"query": "query Other { a }"}, {"query": "mutation Save { save }"}]`); got != "mutations,all" {
+		t.Errorf("batch matched %q", got)
+	}
+	if got := ruleIDs(`{"name": "not graphql"}`); got != "all" {
+		t.Errorf("plain JSON matched %q", got)
+	}
+
+	if err := ce.AddRule(&ChaosRule{ID: "bad", Operation: "("}); err == nil {
+		t.Error("expected an invalid operation regex to be rejected")
+	}
+}
+
+func TestProxyGraphQL(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data": null, "errors": [{"message": "no", "extensions": {"code": "FORBIDDEN"}}]}`)
+	}))
+	defer upstream.Close()
+
+	ps, err := NewProxyServer(ProxyConfig{ID: "test-graphql", TargetURL: upstream.URL, ListenPort: 0})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ps.Stop(context.Background())
+	<-ps.Ready()
+
+	ps.ChaosEngine().SetConfig(&ChaosConfig{Enabled: true, Rules: []*ChaosRule{{
+		ID: "unauth", Type: ChaosGraphQLError, Enabled: true, Operation: "Viewer",
+		GraphQLErrorCode: "UNAUTHENTICATED", ErrorMessage: "Session expired",
+	}}})
+
+	send := func(body string) (int, string) {
+		resp, err := http.Post("http://"+ps.ListenAddr+"/graphql", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	status, body := send(`{"query": "query GetViewer { viewer { id } }"}`)
+	var injected struct {
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	json.Unmarshal([]byte(body), &injected)
+	if status != 200 || len(injected.Errors) != 1 || injected.Errors[0].Extensions.Code != "UNAUTHENTICATED" {
+		t.Errorf("unexpected injected response %d %s", status, body)
+	}
+
+	send(`{"query": "mutation Save($v: Int) { save(v: $v) }", "variables": {"v": 3}}`)
+
+	entries := ps.Logger().Query(LogFilter{Operation: "save"})
+	if len(entries) != 1 {
+		t.Fatalf("expected one Save entry, got %d", len(entries))
+	}
+	g := entries[0].HTTP.GraphQL
+	if g.Label() != "mutation Save" || string(g.Operations[0].Variables) != `{"v":3}` || g.ErrorCount != 1 || g.Errors[0].Code != "FORBIDDEN" {
+		t.Errorf("unexpected logged GraphQL info %+v", g)
+	}
+
+	viewer := ps.Logger().Query(LogFilter{Operation: "GetViewer"})
+	if len(viewer) != 1 || viewer[0].HTTP.GraphQL.Errors[0].Code != "UNAUTHENTICATED" {
+		t.Errorf("expected the injected error to be logged, got %+v", viewer)
+	}
+
+	groups := AggregateHTTP(ps.Logger().Query(LogFilter{}), []string{GroupByOperation})
+	if len(groups) != 2 || groups[0].Errors != 1 {
+		t.Errorf("unexpected operation groups %+v", groups)
+	}
+}
@@ -2,6 +2,9 @@ package proxy
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -27,8 +30,56 @@ func instrumentationScript() string {
 // The wsPort parameter is deprecated and unused (kept for backward compatibility).
 // The script now uses relative URLs via window.location.host.
 func InjectInstrumentation(body []byte, wsPort int) []byte {
-	script := instrumentationScript()
+	return injectScript(body, instrumentationScript())
+}
+
+// InjectInstrumentationWithToken adds monitoring JavaScript that authenticates
+// its WebSocket with the proxy's token. The token and the features switched
+// off go in their own script tag so the cached instrumentation script stays
+// shared across proxies.
+func InjectInstrumentationWithToken(body []byte, token string, off ...string) []byte {
+	settings := "window.__devtool_token=" + strconv.Quote(token) + ";"
+	if len(off) > 0 {
+		features := make(map[string]bool, len(off))
+		for _, name := range off {
+			features[name] = false
+		}
+		data, _ := json.Marshal(features)
+		settings += "window.__devtool_features=" + string(data) + ";"
+	}
+	return injectScript(body, "<script>"+settings+"</script>\n"+instrumentationScript())
+}
+
+// InjectFeatures are the optional browser features InjectConfig can switch
+// off: the floating indicator, console capture, interaction and mutation
+// tracking, and Web Vitals.
+var InjectFeatures = []string{"indicator", "console", "interactions", "mutations", "vitals"}
+
+// InjectConfig controls the instrumentation injected into HTML pages.
+type InjectConfig struct {
+	Disabled bool     // Inject nothing; traffic is still logged
+	Off      []string // InjectFeatures switched off
+}
+
+// validate checks the names of the features switched off.
+func (c *InjectConfig) validate() error {
+	for _, name := range c.Off {
+		known := false
+		for _, feature := range InjectFeatures {
+			if name == feature {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown feature %q (available: %s)", name, strings.Join(InjectFeatures, ", "))
+		}
+	}
+	return nil
+}
 
+// injectScript inserts script into an HTML document, as early as possible.
+func injectScript(body []byte, script string) []byte {
 	// Try to inject before </head>
 	if idx := bytes.Index(body, []byte("</head>")); idx != -1 {
 		result := make([]byte, 0, len(body)+len(script))
@@ -2,6 +2,9 @@ package proxy
 
 import (
 	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -260,3 +263,49 @@ func TestInjectInstrumentation_DifferentPorts(t *testing.T) {
 		}
 	}
 }
+
+func TestInjectInstrumentation_Features(t *testing.T) {
+	html := []byte("<html><head></head><body></body></html>")
+
+	result := string(InjectInstrumentationWithToken(html, "secret"))
+	if strings.Contains(result, "__devtool_features=") {
+		t.Error("features should only be passed when some are off")
+	}
+
+	result = string(InjectInstrumentationWithToken(html, "secret", "indicator", "vitals"))
+	if !strings.Contains(result, `window.__devtool_features={"indicator":false,"vitals":false};`) {
+		t.Errorf("features not passed to the page: %s", result[:200])
+	}
+}
+
+func TestNewProxyServer_InjectAndChaos(t *testing.T) {
+	if _, err := NewProxyServer(ProxyConfig{ID: "x", TargetURL: "http://localhost:3000", Inject: &InjectConfig{Off: []string{"sparkles"}}}); err == nil {
+		t.Error("expected unknown feature to fail")
+	}
+
+	ps, err := NewProxyServer(ProxyConfig{
+		ID:        "x",
+		TargetURL: "http://localhost:3000",
+		Inject:    &InjectConfig{Disabled: true},
+		Chaos:     GetPreset("flaky-api"),
+	})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if !ps.ChaosEngine().IsEnabled() || len(ps.ChaosEngine().GetConfig().Rules) == 0 {
+		t.Error("chaos preset should be active from the start")
+	}
+
+	resp := &http.Response{
+		Header:  http.Header{"Content-Type": {"text/html"}},
+		Body:    io.NopCloser(strings.NewReader("<html><head></head></html>")),
+		Request: httptest.NewRequest("GET", "http://localhost:3000/", nil),
+	}
+	if err := ps.modifyResponse(resp); err != nil {
+		t.Fatalf("modifyResponse: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "<script>") {
+		t.Errorf("nothing should be injected when disabled: %s", body)
+	}
+}
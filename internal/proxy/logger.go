@@ -44,6 +44,14 @@ const (
 	LogTypeDesignRequest LogEntryType = "design_request"
 	// LogTypeDesignChat represents a chat message about the selected element.
 	LogTypeDesignChat LogEntryType = "design_chat"
+	// LogTypeStreamEvent represents one event of a streaming (SSE or NDJSON) response.
+	LogTypeStreamEvent LogEntryType = "stream_event"
+	// LogTypeConsole represents a console.warn or console.error call in the page.
+	LogTypeConsole LogEntryType = "console"
+	// LogTypeRejection represents an unhandled promise rejection.
+	LogTypeRejection LogEntryType = "rejection"
+	// LogTypeWebVital represents a Core Web Vitals measurement or a long task.
+	LogTypeWebVital LogEntryType = "web_vital"
 )
 
 // HTTPLogEntry represents a logged HTTP request/response pair.
@@ -53,12 +61,49 @@ type HTTPLogEntry struct {
 	Method          string            `json:"method"`
 	URL             string            `json:"url"`
 	RequestHeaders  map[string]string `json:"request_headers"`
-	RequestBody     string            `json:"request_body,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"` // Preview; see RequestBodyInfo for the full body
 	StatusCode      int               `json:"status_code"`
 	ResponseHeaders map[string]string `json:"response_headers"`
-	ResponseBody    string            `json:"response_body,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"` // Preview; see ResponseBodyInfo for the full body
 	Duration        time.Duration     `json:"duration"`
 	Error           string            `json:"error,omitempty"`
+	Upstream        string            `json:"upstream,omitempty"` // Route that served the request (empty for the proxy target)
+
+	RequestBodyInfo  *BodyInfo `json:"request_body_info,omitempty"`
+	ResponseBodyInfo *BodyInfo `json:"response_body_info,omitempty"`
+
+	GraphQL *GraphQLInfo `json:"graphql,omitempty"` // Set for GraphQL requests
+	Stream  *StreamInfo  `json:"stream,omitempty"`  // Set for streaming responses
+	Timing  *TimingInfo  `json:"timing,omitempty"`  // Upstream phases and Server-Timing metrics
+}
+
+// StreamInfo describes a streaming response. Streaming requests are logged
+// when the response starts and updated when it ends; until then Duration is
+// the time to the response headers.
+type StreamInfo struct {
+	Kind      string        `json:"kind"` // sse or ndjson
+	Open      bool          `json:"open"` // Still streaming
+	FirstByte time.Duration `json:"first_byte"`
+	Events    int           `json:"events"`
+	Logged    int           `json:"logged"`            // Events recorded as stream_event entries
+	Dropped   int           `json:"dropped,omitempty"` // Events dropped by chaos rules
+	Bytes     int64         `json:"bytes"`
+}
+
+// StreamEvent is one event of a streaming response: an SSE event or an
+// NDJSON line.
+type StreamEvent struct {
+	ID        string        `json:"id"`
+	RequestID string        `json:"request_id"` // HTTP log entry of the stream
+	URL       string        `json:"url"`
+	Timestamp time.Time     `json:"timestamp"`
+	Seq       int           `json:"seq"`                // Position in the stream, from 1
+	Event     string        `json:"event,omitempty"`    // SSE event name
+	EventID   string        `json:"event_id,omitempty"` // SSE id field
+	Data      string        `json:"data"`               // Preview of the event data
+	Size      int           `json:"size"`               // Bytes of the raw event
+	Offset    time.Duration `json:"offset"`             // Since the request started
+	Chaos     string        `json:"chaos,omitempty"`    // e.g. "dropped" or "delayed 250ms"
 }
 
 // FrontendError represents a JavaScript error from the frontend.
@@ -70,8 +115,88 @@ type FrontendError struct {
 	LineNo    int       `json:"lineno,omitempty"`
 	ColNo     int       `json:"colno,omitempty"`
 	Error     string    `json:"error,omitempty"`
-	Stack     string    `json:"stack,omitempty"`
-	URL       string    `json:"url"` // Page URL where error occurred
+	Stack     string    `json:"stack,omitempty"` // As reported by the browser
+	URL       string    `json:"url"`             // Page URL where error occurred
+
+	// Stack resolved to original sources with source maps, if available
+	ResolvedStack string       `json:"resolved_stack,omitempty"`
+	Frames        []StackFrame `json:"frames,omitempty"`
+}
+
+// ConsoleMessage is a console.warn or console.error call (or a failed
+// console.assert) in the page.
+type ConsoleMessage struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`           // warn or error
+	Message   string    `json:"message"`         // Arguments formatted like the console, truncated
+	Args      []string  `json:"args,omitempty"`  // Preview of each argument
+	Stack     string    `json:"stack,omitempty"` // Where it was logged from
+	URL       string    `json:"url"`             // Page URL
+}
+
+// PromiseRejection is an unhandled promise rejection in the page.
+type PromiseRejection struct {
+	ID         string    `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Message    string    `json:"message"`
+	Name       string    `json:"name,omitempty"` // Error class, e.g. TypeError; empty unless the reason is an Error
+	ReasonType string    `json:"reason_type"`    // error, string, object, undefined, ...
+	Stack      string    `json:"stack,omitempty"`
+	URL        string    `json:"url"` // Page URL
+
+	// Stack resolved to original sources with source maps, if available
+	ResolvedStack string       `json:"resolved_stack,omitempty"`
+	Frames        []StackFrame `json:"frames,omitempty"`
+}
+
+// Web vital names. Long tasks are reported individually; the others are
+// the page's current value of the metric.
+const (
+	WebVitalLCP      = "LCP"
+	WebVitalCLS      = "CLS"
+	WebVitalINP      = "INP"
+	WebVitalFCP      = "FCP"
+	WebVitalTTFB     = "TTFB"
+	WebVitalLongTask = "long_task"
+)
+
+// WebVital is a Core Web Vitals measurement or a long task.
+type WebVital struct {
+	ID        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Name      string                 `json:"name"`              // WebVital*
+	Value     float64                `json:"value"`             // Milliseconds; unitless for CLS
+	Rating    string                 `json:"rating,omitempty"`  // good, needs-improvement or poor
+	Element   string                 `json:"element,omitempty"` // Selector of the attributed element
+	Details   map[string]interface{} `json:"details,omitempty"` // e.g. event type for INP, resource for LCP
+	URL       string                 `json:"url"`               // Page URL
+}
+
+// webVitalThresholds are the good and poor limits of each metric
+// (https://web.dev/articles/vitals).
+var webVitalThresholds = map[string][2]float64{
+	WebVitalLCP:  {2500, 4000},
+	WebVitalCLS:  {0.1, 0.25},
+	WebVitalINP:  {200, 500},
+	WebVitalFCP:  {1800, 3000},
+	WebVitalTTFB: {800, 1800},
+}
+
+// RateWebVital returns good, needs-improvement or poor for a metric value,
+// or "" for metrics without thresholds.
+func RateWebVital(name string, value float64) string {
+	t, ok := webVitalThresholds[name]
+	switch {
+	case !ok:
+		return ""
+	case value <= t[0]:
+		return "good"
+	case value <= t[1]:
+		return "needs-improvement"
+	default:
+		return "poor"
+	}
 }
 
 // PerformanceMetric represents frontend performance data.
@@ -387,6 +512,10 @@ type LogEntry struct {
 	DesignState       *DesignState       `json:"design_state,omitempty"`
 	DesignRequest     *DesignRequest     `json:"design_request,omitempty"`
 	DesignChat        *DesignChat        `json:"design_chat,omitempty"`
+	StreamEvent       *StreamEvent       `json:"stream_event,omitempty"`
+	Console           *ConsoleMessage    `json:"console,omitempty"`
+	Rejection         *PromiseRejection  `json:"rejection,omitempty"`
+	WebVital          *WebVital          `json:"web_vital,omitempty"`
 }
 
 // TrafficLogger stores proxy traffic logs with bounded memory.
@@ -396,6 +525,9 @@ type TrafficLogger struct {
 	head    atomic.Int64 // Next write position
 	count   atomic.Int64 // Total entries written (for ID generation)
 	mu      sync.RWMutex // Protects entries slice
+
+	// onLog is called with each entry logged (set before use)
+	onLog func(LogEntry)
 }
 
 // NewTrafficLogger creates a new logger with specified max entries.
@@ -417,6 +549,31 @@ func (tl *TrafficLogger) LogHTTP(entry HTTPLogEntry) {
 	})
 }
 
+// LogStreamEvent adds an event of a streaming response.
+func (tl *TrafficLogger) LogStreamEvent(entry StreamEvent) {
+	tl.log(LogEntry{
+		Type:        LogTypeStreamEvent,
+		StreamEvent: &entry,
+	})
+}
+
+// UpdateHTTP replaces the logged HTTP entry with the same ID, if it is still
+// in the buffer. The entry is swapped rather than modified so readers holding
+// the previous one are unaffected.
+func (tl *TrafficLogger) UpdateHTTP(entry HTTPLogEntry) bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	available := int(min(tl.count.Load(), int64(tl.maxSize)))
+	for i := 0; i < available; i++ {
+		if e := tl.entries[i]; e.HTTP != nil && e.HTTP.ID == entry.ID {
+			tl.entries[i].HTTP = &entry
+			return true
+		}
+	}
+	return false
+}
+
 // LogError adds a frontend error log entry.
 func (tl *TrafficLogger) LogError(entry FrontendError) {
 	tl.log(LogEntry{
@@ -425,6 +582,30 @@ func (tl *TrafficLogger) LogError(entry FrontendError) {
 	})
 }
 
+// LogConsole adds a console message from the page.
+func (tl *TrafficLogger) LogConsole(entry ConsoleMessage) {
+	tl.log(LogEntry{
+		Type:    LogTypeConsole,
+		Console: &entry,
+	})
+}
+
+// LogRejection adds an unhandled promise rejection.
+func (tl *TrafficLogger) LogRejection(entry PromiseRejection) {
+	tl.log(LogEntry{
+		Type:      LogTypeRejection,
+		Rejection: &entry,
+	})
+}
+
+// LogWebVital adds a web vital measurement.
+func (tl *TrafficLogger) LogWebVital(entry WebVital) {
+	tl.log(LogEntry{
+		Type:     LogTypeWebVital,
+		WebVital: &entry,
+	})
+}
+
 // LogPerformance adds a frontend performance log entry.
 func (tl *TrafficLogger) LogPerformance(entry PerformanceMetric) {
 	tl.log(LogEntry{
@@ -555,27 +736,58 @@ func (tl *TrafficLogger) log(entry LogEntry) {
 	tl.mu.Unlock()
 
 	tl.count.Add(1)
+
+	if tl.onLog != nil {
+		tl.onLog(entry)
+	}
 }
 
 // Query retrieves log entries matching the filter.
 func (tl *TrafficLogger) Query(filter LogFilter) []LogEntry {
-	tl.mu.RLock()
-	defer tl.mu.RUnlock()
+	q := filter.compile()
 
+	tl.mu.RLock()
 	total := tl.count.Load()
 	available := int(min(total, int64(tl.maxSize)))
 
-	var results []LogEntry
+	var candidates []LogEntry
 	for i := 0; i < available; i++ {
 		entry := tl.entries[i]
-		if filter.Matches(entry) {
-			results = append(results, entry)
+		if filter.Matches(entry) && filter.matchesQuery(entry, q) {
+			candidates = append(candidates, entry)
 		}
 	}
+	tl.mu.RUnlock()
 
+	// Body predicates read and parse stored bodies; evaluating them under
+	// the lock would stall log() and with it all proxied traffic.
+	if q.bodyPath == nil && q.requestBodyPath == nil {
+		return candidates
+	}
+	results := candidates[:0]
+	for _, entry := range candidates {
+		if q.matchesBody(entry) {
+			results = append(results, entry)
+		}
+	}
 	return results
 }
 
+// HTTPEntry returns the logged HTTP request with the given ID, if it is
+// still in the buffer.
+func (tl *TrafficLogger) HTTPEntry(id string) (*HTTPLogEntry, bool) {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+
+	available := int(min(tl.count.Load(), int64(tl.maxSize)))
+	for i := 0; i < available; i++ {
+		if entry := tl.entries[i]; entry.HTTP != nil && entry.HTTP.ID == id {
+			return entry.HTTP, true
+		}
+	}
+	return nil, false
+}
+
 // Clear removes all log entries.
 func (tl *TrafficLogger) Clear() {
 	tl.mu.Lock()
@@ -620,6 +832,17 @@ type LogFilter struct {
 	Limit            int            `json:"limit,omitempty"`             // Max results (0 = all)
 	InteractionTypes []string       `json:"interaction_types,omitempty"` // click, keydown, scroll, etc.
 	MutationTypes    []string       `json:"mutation_types,omitempty"`    // added, removed, attributes
+
+	// Structured predicates (see query.go). Header, duration and body
+	// filters only match HTTP entries; ErrorPattern only entries with an error.
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`   // Header name → substring ("" = present)
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`  // Header name → substring ("" = present)
+	MinDuration     time.Duration     `json:"min_duration,omitempty"`      // Slower than
+	MaxDuration     time.Duration     `json:"max_duration,omitempty"`      // Faster than
+	BodyPath        string            `json:"body_path,omitempty"`         // JSONPath predicate on the response body
+	RequestBodyPath string            `json:"request_body_path,omitempty"` // JSONPath predicate on the request body
+	ErrorPattern    string            `json:"error_pattern,omitempty"`     // Regex on error messages
+	Operation       string            `json:"operation,omitempty"`         // GraphQL operation name or persisted query hash substring
 }
 
 // Matches returns true if the entry matches the filter.
@@ -709,6 +932,22 @@ func (f LogFilter) Matches(entry LogEntry) bool {
 		if entry.DesignChat != nil {
 			timestamp = entry.DesignChat.Timestamp
 		}
+	case LogTypeStreamEvent:
+		if entry.StreamEvent != nil {
+			timestamp = entry.StreamEvent.Timestamp
+		}
+	case LogTypeConsole:
+		if entry.Console != nil {
+			timestamp = entry.Console.Timestamp
+		}
+	case LogTypeRejection:
+		if entry.Rejection != nil {
+			timestamp = entry.Rejection.Timestamp
+		}
+	case LogTypeWebVital:
+		if entry.WebVital != nil {
+			timestamp = entry.WebVital.Timestamp
+		}
 	}
 
 	if f.Since != nil && timestamp.Before(*f.Since) {
@@ -756,6 +995,13 @@ func (f LogFilter) Matches(entry LogEntry) bool {
 		}
 	}
 
+	// Stream events carry the URL of their request
+	if entry.Type == LogTypeStreamEvent && entry.StreamEvent != nil && f.URLPattern != "" {
+		if !contains(entry.StreamEvent.URL, f.URLPattern) {
+			return false
+		}
+	}
+
 	// Interaction type filter
 	if entry.Type == LogTypeInteraction && entry.Interaction != nil && len(f.InteractionTypes) > 0 {
 		match := false
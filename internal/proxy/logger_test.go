@@ -319,3 +319,26 @@ func TestTrafficLogger_ConcurrentWrites(t *testing.T) {
 		t.Errorf("Expected %d total entries, got %d", expectedTotal, stats.TotalEntries)
 	}
 }
+
+func TestRateWebVital(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  string
+	}{
+		{WebVitalLCP, 2500, "good"},
+		{WebVitalLCP, 2501, "needs-improvement"},
+		{WebVitalLCP, 4001, "poor"},
+		{WebVitalCLS, 0.05, "good"},
+		{WebVitalCLS, 0.3, "poor"},
+		{WebVitalINP, 350, "needs-improvement"},
+		{WebVitalTTFB, 900, "needs-improvement"},
+		{WebVitalLongTask, 120, ""},
+		{"unknown", 1, ""},
+	}
+	for _, tt := range tests {
+		if got := RateWebVital(tt.name, tt.value); got != tt.want {
+			t.Errorf("RateWebVital(%s, %v) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
@@ -25,6 +25,8 @@ type ProxyManager struct {
 
 	shutdownOnce sync.Once
 	shuttingDown atomic.Bool
+
+	eventHandler atomic.Pointer[EventHandler]
 }
 
 // NewProxyManager creates a new proxy manager.
@@ -48,6 +50,7 @@ func (pm *ProxyManager) Create(ctx context.Context, config ProxyConfig) (*ProxyS
 	if err != nil {
 		return nil, err
 	}
+	proxy.onEvent = pm.emit
 
 	// Start proxy
 	if err := proxy.Start(ctx); err != nil {
@@ -58,6 +61,7 @@ func (pm *ProxyManager) Create(ctx context.Context, config ProxyConfig) (*ProxyS
 	pm.proxies.Store(config.ID, proxy)
 	pm.activeCount.Add(1)
 	pm.totalStarted.Add(1)
+	proxy.emit(Event{Type: EventCreated})
 
 	return proxy, nil
 }
@@ -142,6 +146,7 @@ func (pm *ProxyManager) Stop(ctx context.Context, id string) error {
 
 	pm.proxies.Delete(id)
 	pm.activeCount.Add(-1)
+	proxy.emit(Event{Type: EventStopped})
 
 	return nil
 }
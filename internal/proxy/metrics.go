@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram.
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// StatusClasses are the classes requests are counted by. "error" counts
+// requests that got no response from the upstream.
+var StatusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx", "error"}
+
+// requestMetrics counts the requests and frontend errors of a proxy since it
+// was created. The counters only grow, unlike the log buffer.
+type requestMetrics struct {
+	byClass    [6]atomic.Int64 // indexed like StatusClasses
+	buckets    []atomic.Int64  // per bucket, not cumulative; the last is +Inf
+	latencySum atomic.Int64    // nanoseconds
+	errors     atomic.Int64
+	rejections atomic.Int64
+	restarts   atomic.Int64
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{buckets: make([]atomic.Int64, len(LatencyBuckets)+1)}
+}
+
+// observe records a log entry. Streaming responses are logged when their
+// headers arrive, so their latency is the time to the first byte.
+// WebSocket upgrades are counted but have no latency.
+func (m *requestMetrics) observe(entry LogEntry) {
+	switch entry.Type {
+	case LogTypeHTTP:
+		if entry.HTTP == nil {
+			return
+		}
+		m.byClass[statusClass(entry.HTTP.StatusCode)].Add(1)
+		if entry.HTTP.StatusCode != 101 {
+			m.observeLatency(entry.HTTP.Duration)
+		}
+	case LogTypeError:
+		m.errors.Add(1)
+	case LogTypeRejection:
+		m.rejections.Add(1)
+	}
+}
+
+func (m *requestMetrics) observeLatency(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d.Seconds() > LatencyBuckets[i] {
+		i++
+	}
+	m.buckets[i].Add(1)
+	m.latencySum.Add(int64(d))
+}
+
+// statusClass returns the index in StatusClasses of a status code.
+func statusClass(code int) int {
+	if code < 100 || code > 599 {
+		return len(StatusClasses) - 1
+	}
+	return code/100 - 1
+}
+
+// ProxyMetrics are the counters of a proxy since it was created.
+type ProxyMetrics struct {
+	// Requests counts requests by status class, keyed like StatusClasses
+	Requests map[string]int64
+	// LatencyBuckets are cumulative request counts per LatencyBuckets bound,
+	// followed by the count for +Inf
+	LatencyBuckets []int64
+	LatencySum     time.Duration
+	LatencyCount   int64
+	FrontendErrors int64
+	Rejections     int64
+	Restarts       int64
+	Chaos          ChaosStats
+}
+
+// Metrics returns the counters of the proxy since it was created.
+func (ps *ProxyServer) Metrics() ProxyMetrics {
+	m := ps.metrics
+	out := ProxyMetrics{
+		Requests:       make(map[string]int64, len(StatusClasses)),
+		LatencyBuckets: make([]int64, len(m.buckets)),
+		LatencySum:     time.Duration(m.latencySum.Load()),
+		FrontendErrors: m.errors.Load(),
+		Rejections:     m.rejections.Load(),
+		Restarts:       m.restarts.Load(),
+		Chaos:          ps.chaosEngine.GetStats(),
+	}
+	for i, class := range StatusClasses {
+		out.Requests[class] = m.byClass[i].Load()
+	}
+	var cumulative int64
+	for i := range m.buckets {
+		cumulative += m.buckets[i].Load()
+		out.LatencyBuckets[i] = cumulative
+	}
+	out.LatencyCount = cumulative
+	return out
+}
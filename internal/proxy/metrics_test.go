@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	ps := &ProxyServer{metrics: newRequestMetrics(), chaosEngine: NewChaosEngine(nil)}
+
+	for _, e := range []HTTPLogEntry{
+		{StatusCode: 200, Duration: 3 * time.Millisecond},
+		{StatusCode: 204, Duration: 40 * time.Millisecond},
+		{StatusCode: 404, Duration: 40 * time.Millisecond},
+		{StatusCode: 502, Duration: 2 * time.Second},
+		{StatusCode: 0, Duration: 30 * time.Second, Error: "connection refused"},
+		{StatusCode: 101}, // WebSocket upgrade: counted, no latency
+	} {
+		e := e
+		ps.metrics.observe(LogEntry{Type: LogTypeHTTP, HTTP: &e})
+	}
+	ps.metrics.observe(LogEntry{Type: LogTypeError, Error: &FrontendError{}})
+	ps.metrics.observe(LogEntry{Type: LogTypeRejection})
+	ps.metrics.observe(LogEntry{Type: LogTypeConsole})
+
+	m := ps.Metrics()
+	want := map[string]int64{"1xx": 1, "2xx": 2, "3xx": 0, "4xx": 1, "5xx": 1, "error": 1}
+	if !reflect.DeepEqual(m.Requests, want) {
+		t.Errorf("Requests = %v, want %v", m.Requests, want)
+	}
+
+	// Buckets: 0.005 0.01 0.025 0.05 0.1 0.25 0.5 1 2.5 5 10 +Inf
+	wantBuckets := []int64{1, 1, 1, 3, 3, 3, 3, 3, 4, 4, 4, 5}
+	if !reflect.DeepEqual(m.LatencyBuckets, wantBuckets) {
+		t.Errorf("LatencyBuckets = %v, want %v", m.LatencyBuckets, wantBuckets)
+	}
+	if m.LatencyCount != 5 {
+		t.Errorf("LatencyCount = %d, want 5", m.LatencyCount)
+	}
+	if want := 32083 * time.Millisecond; m.LatencySum != want {
+		t.Errorf("LatencySum = %v, want %v", m.LatencySum, want)
+	}
+	if m.FrontendErrors != 1 || m.Rejections != 1 {
+		t.Errorf("FrontendErrors = %d, Rejections = %d, want 1 and 1", m.FrontendErrors, m.Rejections)
+	}
+}
@@ -12,6 +12,30 @@ import (
 const (
 	MaxInteractionsPerSession = 200
 	MaxMutationsPerSession    = 100
+	MaxRoutesPerSession       = 50
+	MaxErrorsPerRoute         = 20
+	MaxCachedFetchesPerRoute  = 50
+	MaxConsolePerSession      = 100
+	MaxRejectionsPerSession   = 50
+)
+
+// Route sources: how the browser arrived at a route.
+const (
+	RouteSourceDocument      = "document"       // Document request through the proxy
+	RouteSourceServiceWorker = "service_worker" // Page served by a service worker without a request
+	RouteSourceCache         = "cache"          // Page restored from the back/forward or HTTP cache
+	RouteSourcePush          = "push"           // history.pushState
+	RouteSourcePop           = "pop"            // Back/forward between client-side routes
+	RouteSourceHash          = "hash"           // Fragment change of a hash router
+)
+
+// Navigation kinds reported by the injected script.
+const (
+	NavigationLoad    = "load"    // Page loaded (source says where from)
+	NavigationPush    = "push"    // history.pushState
+	NavigationReplace = "replace" // history.replaceState; updates the current route
+	NavigationPop     = "pop"     // popstate
+	NavigationHash    = "hash"    // hashchange
 )
 
 // appendBounded appends an item to a slice while maintaining a maximum length.
@@ -52,6 +76,89 @@ type PageSession struct {
 	// DOM mutation tracking
 	Mutations     []MutationEvent `json:"mutations,omitempty"`
 	MutationCount int             `json:"mutation_count"` // Total count (may exceed slice length)
+
+	// Routes visited in this tab, oldest first: document loads, pages served
+	// by a service worker and client-side navigations of single-page apps
+	Routes        []PageRoute `json:"routes,omitempty"`
+	RouteCount    int         `json:"route_count"`              // Total count (may exceed slice length)
+	ServiceWorker bool        `json:"service_worker,omitempty"` // Page is controlled by a service worker
+
+	// Console warnings and errors, unhandled rejections and web vitals
+	Console        []ConsoleMessage   `json:"console,omitempty"`
+	ConsoleCount   int                `json:"console_count"` // Total count (may exceed slice length)
+	Rejections     []PromiseRejection `json:"rejections,omitempty"`
+	RejectionCount int                `json:"rejection_count"` // Total count (may exceed slice length)
+	Vitals
+}
+
+// Vitals holds the web vitals of a page or route: the latest value of each
+// metric and the long tasks that blocked the main thread.
+type Vitals struct {
+	WebVitals      map[string]WebVital `json:"web_vitals,omitempty"` // By name (LCP, CLS, INP, FCP, TTFB)
+	LongTaskCount  int                 `json:"long_task_count,omitempty"`
+	BlockingTimeMs float64             `json:"blocking_time_ms,omitempty"` // Sum of long task time over 50ms
+}
+
+// add records a measurement.
+func (v *Vitals) add(vital WebVital) {
+	if vital.Name == WebVitalLongTask {
+		v.LongTaskCount++
+		v.BlockingTimeMs += max(vital.Value-50, 0)
+		return
+	}
+	if v.WebVitals == nil {
+		v.WebVitals = make(map[string]WebVital)
+	}
+	v.WebVitals[vital.Name] = vital
+}
+
+// PageRoute is one route of a page session, with the errors, interactions
+// and performance recorded while it was current.
+type PageRoute struct {
+	URL               string     `json:"url"`
+	Title             string     `json:"title,omitempty"`
+	Source            string     `json:"source"` // RouteSource*
+	StartTime         time.Time  `json:"start_time"`
+	EndTime           *time.Time `json:"end_time,omitempty"` // Unset for the current route
+	DocumentRequestID string     `json:"document_request_id,omitempty"`
+
+	Errors           []FrontendError    `json:"errors,omitempty"`
+	ErrorCount       int                `json:"error_count"` // Total count (may exceed slice length)
+	InteractionCount int                `json:"interaction_count"`
+	MutationCount    int                `json:"mutation_count"`
+	ResourceCount    int                `json:"resource_count"`
+	CachedFetches    []CachedFetch      `json:"cached_fetches,omitempty"`
+	CachedFetchCount int                `json:"cached_fetch_count"` // Total count (may exceed slice length)
+	Performance      *PerformanceMetric `json:"performance,omitempty"`
+
+	ConsoleErrorCount int `json:"console_error_count,omitempty"`
+	ConsoleWarnCount  int `json:"console_warn_count,omitempty"`
+	RejectionCount    int `json:"rejection_count,omitempty"`
+	Vitals
+}
+
+// NavigationEvent is a page load or client-side navigation reported by the
+// injected script.
+type NavigationEvent struct {
+	ID            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Kind          string    `json:"kind"` // Navigation*
+	URL           string    `json:"url"`  // URL navigated to
+	From          string    `json:"from,omitempty"`
+	Title         string    `json:"title,omitempty"`
+	Source        string    `json:"source,omitempty"`         // For loads: network, service_worker or cache
+	ServiceWorker bool      `json:"service_worker,omitempty"` // Page is controlled by a service worker
+}
+
+// CachedFetch is a request of the page that a service worker or the browser
+// cache answered without it reaching the proxy.
+type CachedFetch struct {
+	Timestamp  time.Time `json:"timestamp"`
+	URL        string    `json:"url"`
+	Initiator  string    `json:"initiator,omitempty"` // fetch, xmlhttprequest, script, img, ...
+	Source     string    `json:"source"`              // service_worker or cache
+	DurationMs int64     `json:"duration_ms"`
+	Size       int64     `json:"size,omitempty"` // Decoded body size
 }
 
 // PageTracker tracks page sessions and groups requests by page.
@@ -131,10 +238,16 @@ func (pt *PageTracker) TrackError(err FrontendError, browserSessionID string) {
 
 	session := val.(*PageSession)
 	session.Errors = append(session.Errors, err)
+	if route := routeForURL(session, err.URL); route != nil {
+		route.ErrorCount++
+		route.Errors = appendBounded(route.Errors, err, MaxErrorsPerRoute)
+	}
 	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
 }
 
 // TrackPerformance associates performance metrics with a page session.
+// Metrics of client-side navigations (soft_navigation) only apply to their
+// route, so the session keeps those of the page load.
 // browserSessionID is the unique ID from the browser tab's sessionStorage.
 func (pt *PageTracker) TrackPerformance(perf PerformanceMetric, browserSessionID string) {
 	sessionID := pt.ResolveSession(browserSessionID, perf.URL)
@@ -148,7 +261,16 @@ func (pt *PageTracker) TrackPerformance(perf PerformanceMetric, browserSessionID
 	}
 
 	session := val.(*PageSession)
-	session.Performance = &perf
+	if soft, _ := perf.Custom["soft_navigation"].(bool); !soft {
+		session.Performance = &perf
+	}
+	if route := routeForURL(session, perf.URL); route != nil {
+		route.Performance = &perf
+		if title, _ := perf.Custom["title"].(string); title != "" {
+			route.Title = title
+			session.PageTitle = title
+		}
+	}
 	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
 }
 
@@ -168,6 +290,9 @@ func (pt *PageTracker) TrackInteraction(interaction InteractionEvent, browserSes
 	session := val.(*PageSession)
 	session.InteractionCount++
 	session.Interactions = appendBounded(session.Interactions, interaction, MaxInteractionsPerSession)
+	if route := routeForURL(session, interaction.URL); route != nil {
+		route.InteractionCount++
+	}
 	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
 }
 
@@ -187,6 +312,178 @@ func (pt *PageTracker) TrackMutation(mutation MutationEvent, browserSessionID st
 	session := val.(*PageSession)
 	session.MutationCount++
 	session.Mutations = appendBounded(session.Mutations, mutation, MaxMutationsPerSession)
+	if route := routeForURL(session, mutation.URL); route != nil {
+		route.MutationCount++
+	}
+	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
+}
+
+// TrackConsole associates a console message with a page session.
+// browserSessionID is the unique ID from the browser tab's sessionStorage.
+func (pt *PageTracker) TrackConsole(msg ConsoleMessage, browserSessionID string) {
+	sessionID := pt.ResolveSession(browserSessionID, msg.URL)
+	if sessionID == "" {
+		return
+	}
+
+	val, ok := pt.sessions.Load(sessionID)
+	if !ok {
+		return
+	}
+
+	session := val.(*PageSession)
+	session.ConsoleCount++
+	session.Console = appendBounded(session.Console, msg, MaxConsolePerSession)
+	if route := routeForURL(session, msg.URL); route != nil {
+		if msg.Level == "error" {
+			route.ConsoleErrorCount++
+		} else {
+			route.ConsoleWarnCount++
+		}
+	}
+	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
+}
+
+// TrackRejection associates an unhandled promise rejection with a page session.
+// browserSessionID is the unique ID from the browser tab's sessionStorage.
+func (pt *PageTracker) TrackRejection(rejection PromiseRejection, browserSessionID string) {
+	sessionID := pt.ResolveSession(browserSessionID, rejection.URL)
+	if sessionID == "" {
+		return
+	}
+
+	val, ok := pt.sessions.Load(sessionID)
+	if !ok {
+		return
+	}
+
+	session := val.(*PageSession)
+	session.RejectionCount++
+	session.Rejections = appendBounded(session.Rejections, rejection, MaxRejectionsPerSession)
+	if route := routeForURL(session, rejection.URL); route != nil {
+		route.RejectionCount++
+	}
+	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
+}
+
+// TrackWebVital associates a web vital measurement with a page session and
+// the route it was measured on.
+// browserSessionID is the unique ID from the browser tab's sessionStorage.
+func (pt *PageTracker) TrackWebVital(vital WebVital, browserSessionID string) {
+	sessionID := pt.ResolveSession(browserSessionID, vital.URL)
+	if sessionID == "" {
+		return
+	}
+
+	val, ok := pt.sessions.Load(sessionID)
+	if !ok {
+		return
+	}
+
+	session := val.(*PageSession)
+	session.Vitals.add(vital)
+	if route := routeForURL(session, vital.URL); route != nil {
+		route.Vitals.add(vital)
+	}
+	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
+}
+
+// TrackNavigation records a page load or client-side navigation reported by
+// the browser. Pages that never reached the proxy as a document request,
+// such as those served by a service worker, get their session and route here.
+// browserSessionID is the unique ID from the browser tab's sessionStorage.
+func (pt *PageTracker) TrackNavigation(nav NavigationEvent, browserSessionID string) {
+	sessionID := pt.ResolveSession(browserSessionID, nav.From)
+	var session *PageSession
+	if val, ok := pt.sessions.Load(sessionID); ok {
+		session = val.(*PageSession)
+	} else {
+		sessionID = pt.generateSessionID()
+		session = &PageSession{
+			ID:           sessionID,
+			URL:          nav.URL,
+			StartTime:    nav.Timestamp,
+			Active:       true,
+			Resources:    make([]HTTPLogEntry, 0),
+			Errors:       make([]FrontendError, 0),
+			Interactions: make([]InteractionEvent, 0),
+			Mutations:    make([]MutationEvent, 0),
+		}
+		defer pt.cleanupOldSessions()
+	}
+
+	if nav.ServiceWorker {
+		session.ServiceWorker = true
+	}
+	if nav.Title != "" {
+		session.PageTitle = nav.Title
+	}
+	session.URL = nav.URL
+
+	current := currentRoute(session)
+	switch nav.Kind {
+	case NavigationLoad:
+		source := nav.Source
+		if source != RouteSourceServiceWorker && source != RouteSourceCache {
+			// Loaded from the network: the document request started the route
+			source = RouteSourceDocument
+			if current != nil && current.Source == RouteSourceDocument && sameRoute(current.URL, nav.URL) {
+				current.URL = nav.URL
+				if nav.Title != "" {
+					current.Title = nav.Title
+				}
+				break
+			}
+		}
+		startRoute(session, PageRoute{URL: nav.URL, Title: nav.Title, Source: source, StartTime: nav.Timestamp})
+
+	case NavigationReplace:
+		if current != nil {
+			current.URL = nav.URL
+			if nav.Title != "" {
+				current.Title = nav.Title
+			}
+			break
+		}
+		startRoute(session, PageRoute{URL: nav.URL, Title: nav.Title, Source: RouteSourcePush, StartTime: nav.Timestamp})
+
+	default:
+		source := RouteSourcePush
+		switch nav.Kind {
+		case NavigationPop:
+			source = RouteSourcePop
+		case NavigationHash:
+			source = RouteSourceHash
+		}
+		if current != nil && sameRoute(current.URL, nav.URL) {
+			break
+		}
+		startRoute(session, PageRoute{URL: nav.URL, Title: nav.Title, Source: source, StartTime: nav.Timestamp})
+	}
+
+	pt.urlToSession.Store(normalizeURL(nav.URL), sessionID)
+	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
+}
+
+// TrackCachedFetch records a request the page made that never reached the
+// proxy because a service worker or the browser cache answered it.
+// browserSessionID is the unique ID from the browser tab's sessionStorage.
+func (pt *PageTracker) TrackCachedFetch(fetch CachedFetch, pageURL, browserSessionID string) {
+	sessionID := pt.ResolveSession(browserSessionID, pageURL)
+	if sessionID == "" {
+		return
+	}
+
+	val, ok := pt.sessions.Load(sessionID)
+	if !ok {
+		return
+	}
+
+	session := val.(*PageSession)
+	if route := routeForURL(session, pageURL); route != nil {
+		route.CachedFetchCount++
+		route.CachedFetches = appendBounded(route.CachedFetches, fetch, MaxCachedFetchesPerRoute)
+	}
 	pt.updateSessionWithBrowserID(sessionID, session, browserSessionID)
 }
 
@@ -247,6 +544,7 @@ func (pt *PageTracker) createOrUpdatePageSession(entry HTTPLogEntry, browserSess
 				session.Navigations = append(session.Navigations, entry)
 				// Clear resources for new page (they belong to old navigation)
 				session.Resources = make([]HTTPLogEntry, 0)
+				startRoute(session, documentRoute(entry, now))
 				pt.sessions.Store(existingSessionID, session)
 				pt.urlToSession.Store(normalizeURL(entry.URL), existingSessionID)
 				return
@@ -270,6 +568,7 @@ func (pt *PageTracker) createOrUpdatePageSession(entry HTTPLogEntry, browserSess
 		Interactions:    make([]InteractionEvent, 0),
 		Mutations:       make([]MutationEvent, 0),
 	}
+	startRoute(session, documentRoute(entry, now))
 
 	pt.sessions.Store(sessionID, session)
 	pt.urlToSession.Store(normalizeURL(entry.URL), sessionID)
@@ -298,6 +597,9 @@ func (pt *PageTracker) addResourceToSession(entry HTTPLogEntry) {
 
 	session := val.(*PageSession)
 	session.Resources = append(session.Resources, entry)
+	if route := routeForURL(session, headerOrEmpty(entry.RequestHeaders, "Referer")); route != nil {
+		route.ResourceCount++
+	}
 	session.LastActivity = time.Now()
 	pt.sessions.Store(sessionID, session)
 }
@@ -420,6 +722,79 @@ func (pt *PageTracker) cleanupOldSessions() {
 	}
 }
 
+// documentRoute returns the route started by a document request.
+func documentRoute(entry HTTPLogEntry, now time.Time) PageRoute {
+	return PageRoute{
+		URL:               entry.URL,
+		Source:            RouteSourceDocument,
+		StartTime:         now,
+		DocumentRequestID: entry.ID,
+	}
+}
+
+// startRoute ends the current route of a session and makes route current.
+func startRoute(session *PageSession, route PageRoute) {
+	if current := currentRoute(session); current != nil {
+		end := route.StartTime
+		current.EndTime = &end
+	}
+	session.RouteCount++
+	session.Routes = appendBounded(session.Routes, route, MaxRoutesPerSession)
+}
+
+// currentRoute returns the route the tab is on, or nil before the first one.
+func currentRoute(session *PageSession) *PageRoute {
+	if len(session.Routes) == 0 {
+		return nil
+	}
+	return &session.Routes[len(session.Routes)-1]
+}
+
+// routeForURL returns the most recent route with the given URL, falling back
+// to the current route when the URL is empty or unknown. Events carry the
+// URL the page was on when they happened, so ones sent just before a
+// navigation still count for the route they belong to.
+func routeForURL(session *PageSession, urlStr string) *PageRoute {
+	if urlStr != "" {
+		for i := len(session.Routes) - 1; i >= 0; i-- {
+			if sameRoute(session.Routes[i].URL, urlStr) {
+				return &session.Routes[i]
+			}
+		}
+	}
+	return currentRoute(session)
+}
+
+// sameRoute reports whether two URLs are the same route. Document requests
+// are logged with the path only and the browser reports full URLs, so
+// scheme and host are ignored. Fragments only count for hash routers
+// ("#/users" or "#!/users").
+func sameRoute(a, b string) bool {
+	return pageRouteKey(a) == pageRouteKey(b)
+}
+
+// pageRouteKey returns the part of a URL that identifies a route.
+func pageRouteKey(urlStr string) string {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return urlStr
+	}
+	key := parsed.Path
+	if len(key) > 1 {
+		key = strings.TrimSuffix(key, "/")
+	}
+	if key == "" {
+		key = "/"
+	}
+	if parsed.RawQuery != "" {
+		key += "?" + parsed.RawQuery
+	}
+	if strings.HasPrefix(parsed.Fragment, "/") || strings.HasPrefix(parsed.Fragment, "!") {
+		key += "#" + parsed.Fragment
+	}
+	return key
+}
+
 // generateSessionID generates a unique session ID.
 func (pt *PageTracker) generateSessionID() string {
 	seq := pt.sessionSeq.Add(1)
@@ -430,6 +805,21 @@ func (pt *PageTracker) generateSessionID() string {
 
 // isDocumentRequest determines if an HTTP request is for a document (HTML).
 func isDocumentRequest(entry HTTPLogEntry) bool {
+	// Browsers say what a request is for, which beats the heuristics below:
+	// an SPA's fetch of /users/1 is not a navigation, and neither is an
+	// iframe. Requests a service worker makes itself aren't either, but the
+	// injected script reports the page load they serve.
+	switch dest := headerOrEmpty(entry.RequestHeaders, "Sec-Fetch-Dest"); dest {
+	case "", "empty":
+	case "document":
+		return true
+	default:
+		return false
+	}
+	if mode := headerOrEmpty(entry.RequestHeaders, "Sec-Fetch-Mode"); mode != "" && mode != "navigate" {
+		return false
+	}
+
 	contentType := entry.ResponseHeaders["Content-Type"]
 	if contentType == "" {
 		contentType = entry.ResponseHeaders["content-type"]
@@ -580,6 +970,12 @@ func itoa(i int) string {
 	return string(buf[pos:])
 }
 
+// headerOrEmpty looks up a logged header by name, ignoring case.
+func headerOrEmpty(headers map[string]string, name string) string {
+	v, _ := headerValue(headers, name)
+	return v
+}
+
 // extractBrowserSessionID extracts the __devtool_sid cookie from request headers.
 func extractBrowserSessionID(headers map[string]string) string {
 	// Try both capitalized and lowercase header names
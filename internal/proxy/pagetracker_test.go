@@ -205,6 +205,44 @@ func TestIsDocumentRequest(t *testing.T) {
 			},
 			expected: false, // JSON is not a document
 		},
+		{
+			name: "fetch of an SPA route",
+			entry: HTTPLogEntry{
+				Method: "GET",
+				URL:    "/users/1",
+				RequestHeaders: map[string]string{
+					"Sec-Fetch-Dest": "empty",
+					"Sec-Fetch-Mode": "cors",
+				},
+			},
+			expected: false, // Browser says it's not a navigation
+		},
+		{
+			name: "navigation by fetch metadata",
+			entry: HTTPLogEntry{
+				Method: "GET",
+				URL:    "/users/1",
+				RequestHeaders: map[string]string{
+					"sec-fetch-dest": "document",
+					"Accept":         "application/json",
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "iframe document",
+			entry: HTTPLogEntry{
+				Method: "GET",
+				URL:    "/embed",
+				RequestHeaders: map[string]string{
+					"Sec-Fetch-Dest": "iframe",
+				},
+				ResponseHeaders: map[string]string{
+					"Content-Type": "text/html",
+				},
+			},
+			expected: false, // Frames belong to the page
+		},
 		{
 			name: "REST API path",
 			entry: HTTPLogEntry{
@@ -633,3 +671,142 @@ func TestHasResourceExtension(t *testing.T) {
 		})
 	}
 }
+
+func TestPageTracker_ClientSideNavigation(t *testing.T) {
+	pt := NewPageTracker(100, 5*time.Minute)
+	sid := "sess-spa"
+	origin := "http://localhost:8080"
+
+	pt.TrackHTTPRequest(HTTPLogEntry{
+		ID:              "req-1",
+		Method:          "GET",
+		URL:             "/",
+		RequestHeaders:  map[string]string{"Cookie": "__devtool_sid=" + sid},
+		ResponseHeaders: map[string]string{"Content-Type": "text/html"},
+	})
+	pt.TrackPerformance(PerformanceMetric{URL: origin + "/", LoadEventEnd: 450}, sid)
+	// The script's load report belongs to the document request's route
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationLoad, URL: origin + "/", Title: "Home", Source: "network", Timestamp: time.Now()}, sid)
+
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationPush, From: origin + "/", URL: origin + "/users?page=1", Timestamp: time.Now()}, sid)
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationReplace, URL: origin + "/users?page=2", Timestamp: time.Now()}, sid)
+	pt.TrackError(FrontendError{Message: "users failed", URL: origin + "/users?page=2"}, sid)
+	pt.TrackError(FrontendError{Message: "sent before the navigation", URL: origin + "/"}, sid)
+	pt.TrackInteraction(InteractionEvent{EventType: "click", URL: origin + "/users?page=2"}, sid)
+	pt.TrackPerformance(PerformanceMetric{URL: origin + "/users?page=2", Custom: map[string]interface{}{
+		"soft_navigation": true, "settle_ms": float64(120), "title": "Users",
+	}}, sid)
+	pt.TrackHTTPRequest(HTTPLogEntry{
+		Method:         "GET",
+		URL:            "/api/users?page=2",
+		RequestHeaders: map[string]string{"Referer": origin + "/users?page=2", "Sec-Fetch-Dest": "empty"},
+	})
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationPop, From: origin + "/users?page=2", URL: origin + "/", Timestamp: time.Now()}, sid)
+
+	sessions := pt.GetActiveSessions()
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+	session := sessions[0]
+	if session.RouteCount != 3 || len(session.Routes) != 3 {
+		t.Fatalf("expected 3 routes, got %d: %+v", session.RouteCount, session.Routes)
+	}
+	home, users, back := session.Routes[0], session.Routes[1], session.Routes[2]
+
+	if home.Source != RouteSourceDocument || home.DocumentRequestID != "req-1" || home.Title != "Home" || home.EndTime == nil {
+		t.Errorf("unexpected first route %+v", home)
+	}
+	if home.ErrorCount != 1 || home.Errors[0].Message != "sent before the navigation" {
+		t.Errorf("expected the late error on the first route, got %+v", home.Errors)
+	}
+	if users.Source != RouteSourcePush || users.URL != origin+"/users?page=2" || users.Title != "Users" {
+		t.Errorf("unexpected second route %+v", users)
+	}
+	if users.ErrorCount != 1 || users.InteractionCount != 1 || users.ResourceCount != 1 {
+		t.Errorf("expected an error, an interaction and a resource on the second route, got %+v", users)
+	}
+	if users.Performance == nil || users.Performance.Custom["settle_ms"] != float64(120) {
+		t.Errorf("expected route performance, got %+v", users.Performance)
+	}
+	if session.Performance == nil || session.Performance.LoadEventEnd != 450 {
+		t.Errorf("expected the page load metrics to stay on the session, got %+v", session.Performance)
+	}
+	if back.Source != RouteSourcePop || back.EndTime != nil || session.URL != origin+"/" {
+		t.Errorf("unexpected current route %+v (session URL %s)", back, session.URL)
+	}
+}
+
+func TestPageTracker_ServiceWorkerLoad(t *testing.T) {
+	pt := NewPageTracker(100, 5*time.Minute)
+	sid := "sess-sw"
+	url := "http://localhost:8080/inbox"
+
+	// No document request reaches the proxy; errors used to be dropped
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationLoad, URL: url, Source: RouteSourceServiceWorker, ServiceWorker: true, Timestamp: time.Now()}, sid)
+	pt.TrackError(FrontendError{Message: "boom", URL: url}, sid)
+	pt.TrackCachedFetch(CachedFetch{URL: "http://localhost:8080/app.js", Source: RouteSourceServiceWorker}, url, sid)
+
+	// Reloading serves it from the service worker again: a new route
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationLoad, URL: url, Source: RouteSourceServiceWorker, Timestamp: time.Now()}, sid)
+
+	sessions := pt.GetActiveSessions()
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+	session := sessions[0]
+	if !session.ServiceWorker || session.BrowserSession != sid || len(session.Errors) != 1 {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if len(session.Routes) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", session.Routes)
+	}
+	first := session.Routes[0]
+	if first.Source != RouteSourceServiceWorker || first.ErrorCount != 1 || first.CachedFetchCount != 1 {
+		t.Errorf("unexpected route %+v", first)
+	}
+}
+
+func TestPageTracker_ConsoleRejectionsAndVitals(t *testing.T) {
+	pt := NewPageTracker(100, 5*time.Minute)
+	sid := "sess-vitals"
+	origin := "http://localhost:8080"
+
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationLoad, URL: origin + "/", Source: "network", Timestamp: time.Now()}, sid)
+	pt.TrackConsole(ConsoleMessage{Level: "warn", Message: "deprecated", URL: origin + "/"}, sid)
+	pt.TrackWebVital(WebVital{Name: WebVitalLCP, Value: 1800, Rating: "good", Element: "img.hero", URL: origin + "/"}, sid)
+	pt.TrackWebVital(WebVital{Name: WebVitalLongTask, Value: 120, URL: origin + "/"}, sid)
+	pt.TrackWebVital(WebVital{Name: WebVitalLongTask, Value: 40, URL: origin + "/"}, sid)
+
+	pt.TrackNavigation(NavigationEvent{Kind: NavigationPush, From: origin + "/", URL: origin + "/cart", Timestamp: time.Now()}, sid)
+	pt.TrackConsole(ConsoleMessage{Level: "error", Message: "cart failed", URL: origin + "/cart"}, sid)
+	pt.TrackRejection(PromiseRejection{Message: "fetch failed", Name: "TypeError", ReasonType: "error", URL: origin + "/cart"}, sid)
+	// CLS of the previous route, reported after the navigation
+	pt.TrackWebVital(WebVital{Name: WebVitalCLS, Value: 0.02, URL: origin + "/"}, sid)
+	pt.TrackWebVital(WebVital{Name: WebVitalCLS, Value: 0.3, URL: origin + "/cart"}, sid)
+
+	sessions := pt.GetActiveSessions()
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+	session := sessions[0]
+	if session.ConsoleCount != 2 || len(session.Console) != 2 || session.RejectionCount != 1 {
+		t.Errorf("unexpected console/rejections: %+v %+v", session.Console, session.Rejections)
+	}
+	if session.LongTaskCount != 2 || session.BlockingTimeMs != 70 {
+		t.Errorf("expected 2 long tasks blocking 70ms, got %d, %v", session.LongTaskCount, session.BlockingTimeMs)
+	}
+	if lcp := session.WebVitals[WebVitalLCP]; lcp.Element != "img.hero" || session.WebVitals[WebVitalCLS].Value != 0.3 {
+		t.Errorf("unexpected session vitals %+v", session.WebVitals)
+	}
+
+	if len(session.Routes) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", session.Routes)
+	}
+	home, cart := session.Routes[0], session.Routes[1]
+	if home.ConsoleWarnCount != 1 || home.LongTaskCount != 2 || home.WebVitals[WebVitalCLS].Value != 0.02 {
+		t.Errorf("unexpected home route %+v", home)
+	}
+	if cart.ConsoleErrorCount != 1 || cart.RejectionCount != 1 || cart.WebVitals[WebVitalCLS].Value != 0.3 || cart.WebVitals[WebVitalLCP].Value != 0 {
+		t.Errorf("unexpected cart route %+v", cart)
+	}
+}
@@ -0,0 +1,717 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+// compiledQuery holds the parsed regular expressions and JSONPath predicates
+// of a LogFilter, so they are compiled once per query rather than per entry.
+type compiledQuery struct {
+	errorPattern    *regexp.Regexp
+	bodyPath        *jsonPredicate
+	requestBodyPath *jsonPredicate
+	err             error // Invalid filter: nothing matches
+}
+
+// compile parses the filter's patterns.
+func (f LogFilter) compile() *compiledQuery {
+	q := &compiledQuery{}
+	var err error
+	if f.ErrorPattern != "" {
+		if q.errorPattern, err = regexp.Compile(f.ErrorPattern); err != nil {
+			q.err = fmt.Errorf("invalid error_pattern: %w", err)
+			return q
+		}
+	}
+	if f.BodyPath != "" {
+		if q.bodyPath, err = parseJSONPredicate(f.BodyPath); err != nil {
+			q.err = fmt.Errorf("invalid body_path: %w", err)
+			return q
+		}
+	}
+	if f.RequestBodyPath != "" {
+		if q.requestBodyPath, err = parseJSONPredicate(f.RequestBodyPath); err != nil {
+			q.err = fmt.Errorf("invalid request_body_path: %w", err)
+			return q
+		}
+	}
+	return q
+}
+
+// Validate reports invalid regular expressions or JSONPath predicates.
+// Query treats an invalid filter as matching nothing.
+func (f LogFilter) Validate() error {
+	return f.compile().err
+}
+
+// httpOnly reports whether the filter uses predicates that only HTTP entries
+// can satisfy.
+func (f LogFilter) httpOnly() bool {
+	return len(f.RequestHeaders) > 0 || len(f.ResponseHeaders) > 0 ||
+		f.MinDuration > 0 || f.MaxDuration > 0 ||
+		f.BodyPath != "" || f.RequestBodyPath != "" || f.Operation != ""
+}
+
+// matchesQuery applies the structured predicates of the filter, except the
+// body predicates (see matchesBody).
+func (f LogFilter) matchesQuery(entry LogEntry, q *compiledQuery) bool {
+	if q.err != nil {
+		return false
+	}
+
+	if q.errorPattern != nil {
+		msg, ok := errorMessage(entry)
+		if !ok || !q.errorPattern.MatchString(msg) {
+			return false
+		}
+	}
+
+	if !f.httpOnly() {
+		return true
+	}
+	h := entry.HTTP
+	if entry.Type != LogTypeHTTP || h == nil {
+		return false
+	}
+
+	if !headersMatch(h.RequestHeaders, f.RequestHeaders) || !headersMatch(h.ResponseHeaders, f.ResponseHeaders) {
+		return false
+	}
+	if f.MinDuration > 0 && h.Duration < f.MinDuration {
+		return false
+	}
+	if f.MaxDuration > 0 && h.Duration > f.MaxDuration {
+		return false
+	}
+	if f.Operation != "" && !operationMatches(h.GraphQL, f.Operation) {
+		return false
+	}
+	return true
+}
+
+// matchesBody applies the JSONPath body predicates of the filter to an entry
+// that passed matchesQuery. Stored bodies are read from disk, so Query calls
+// it without holding the logger lock.
+func (q *compiledQuery) matchesBody(entry LogEntry) bool {
+	h := entry.HTTP
+	if q.bodyPath != nil && !q.bodyPath.matchBody(h.ResponseBody, h.ResponseBodyInfo) {
+		return false
+	}
+	if q.requestBodyPath != nil && !q.requestBodyPath.matchBody(h.RequestBody, h.RequestBodyInfo) {
+		return false
+	}
+	return true
+}
+
+// operationMatches reports whether any GraphQL operation of a request has a
+// name or persisted query hash containing pattern (case-insensitive).
+func operationMatches(g *GraphQLInfo, pattern string) bool {
+	if g == nil {
+		return false
+	}
+	pattern = strings.ToLower(pattern)
+	for _, op := range g.Operations {
+		if strings.Contains(strings.ToLower(op.Name), pattern) ||
+			op.PersistedQueryHash != "" && strings.HasPrefix(op.PersistedQueryHash, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorMessage returns the error text of entries that carry one.
+func errorMessage(entry LogEntry) (string, bool) {
+	switch {
+	case entry.Type == LogTypeError && entry.Error != nil:
+		return strings.TrimSpace(entry.Error.Message + "\n" + entry.Error.Error), true
+	case entry.Type == LogTypeHTTP && entry.HTTP != nil && entry.HTTP.Error != "":
+		return entry.HTTP.Error, true
+	case entry.Type == LogTypeCustom && entry.Custom != nil && entry.Custom.Level == "error":
+		return entry.Custom.Message, true
+	case entry.Type == LogTypeExecution && entry.Execution != nil && entry.Execution.Error != "":
+		return entry.Execution.Error, true
+	case entry.Type == LogTypeConsole && entry.Console != nil && entry.Console.Level == "error":
+		return entry.Console.Message, true
+	case entry.Type == LogTypeRejection && entry.Rejection != nil:
+		return entry.Rejection.Message, true
+	}
+	return "", false
+}
+
+// headersMatch checks header filters: each named header must be present and,
+// if the pattern is not empty, contain it (case-insensitive).
+func headersMatch(headers map[string]string, filters map[string]string) bool {
+	for name, pattern := range filters {
+		value, ok := headerValue(headers, name)
+		if !ok {
+			return false
+		}
+		if pattern != "" && !strings.Contains(strings.ToLower(value), strings.ToLower(pattern)) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerValue looks up a logged header by name, ignoring case.
+func headerValue(headers map[string]string, name string) (string, bool) {
+	if v, ok := headers[name]; ok {
+		return v, true
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// FilterFromQuery converts a wire query filter into a LogFilter. since
+// accepts a duration ("5m", meaning that long ago) or an RFC3339 time.
+func FilterFromQuery(q protocol.LogQueryFilter) (LogFilter, error) {
+	filter := LogFilter{
+		Methods:         q.Methods,
+		URLPattern:      q.URLPattern,
+		StatusCodes:     q.StatusCodes,
+		Limit:           q.Limit,
+		RequestHeaders:  q.RequestHeaders,
+		ResponseHeaders: q.ResponseHeaders,
+		BodyPath:        q.BodyPath,
+		RequestBodyPath: q.RequestBodyPath,
+		ErrorPattern:    q.ErrorPattern,
+		Operation:       q.Operation,
+	}
+	for _, t := range q.Types {
+		filter.Types = append(filter.Types, LogEntryType(t))
+	}
+
+	if q.Since != "" {
+		since, err := parseQueryTime(q.Since, true)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since: %w", err)
+		}
+		filter.Since = &since
+	}
+	if q.Until != "" {
+		until, err := parseQueryTime(q.Until, false)
+		if err != nil {
+			return filter, fmt.Errorf("invalid until: %w", err)
+		}
+		filter.Until = &until
+	}
+
+	var err error
+	if q.MinDuration != "" {
+		if filter.MinDuration, err = time.ParseDuration(q.MinDuration); err != nil {
+			return filter, fmt.Errorf("invalid min_duration: %w", err)
+		}
+	}
+	if q.MaxDuration != "" {
+		if filter.MaxDuration, err = time.ParseDuration(q.MaxDuration); err != nil {
+			return filter, fmt.Errorf("invalid max_duration: %w", err)
+		}
+	}
+
+	return filter, filter.Validate()
+}
+
+// parseQueryTime parses an RFC3339 time, or for since a duration ago.
+func parseQueryTime(s string, allowDuration bool) (time.Time, error) {
+	if allowDuration {
+		if d, err := time.ParseDuration(s); err == nil {
+			return time.Now().Add(-d), nil
+		}
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Aggregation group keys accepted by AggregateHTTP.
+const (
+	GroupByEndpoint    = "endpoint"     // Method and path template, e.g. "GET /users/{id}"
+	GroupByPath        = "path"         // Exact path without query string
+	GroupByMethod      = "method"       // HTTP method
+	GroupByStatus      = "status"       // Status code
+	GroupByStatusClass = "status_class" // 2xx, 3xx, 4xx, 5xx
+	GroupByUpstream    = "upstream"     // Route that served the request ("default" for the target)
+	GroupByRoute       = "route"        // Alias for upstream
+	GroupByOperation   = "operation"    // GraphQL operations, e.g. "query GetViewer" ("-" for other requests)
+)
+
+// LogGroup is one row of an HTTP log aggregation.
+type LogGroup struct {
+	Key      map[string]string `json:"key"`
+	Count    int               `json:"count"`
+	Errors   int               `json:"errors,omitempty"` // 5xx responses, failed requests and GraphQL errors
+	AvgMs    float64           `json:"avg_ms"`
+	P50Ms    float64           `json:"p50_ms"`
+	P95Ms    float64           `json:"p95_ms"`
+	MaxMs    float64           `json:"max_ms"`
+	Statuses map[string]int    `json:"statuses,omitempty"` // Status code → count
+
+	// Upstream time (connection to last byte) and injected chaos delay, for
+	// requests that reached the upstream
+	UpstreamP50Ms float64 `json:"upstream_p50_ms,omitempty"`
+	UpstreamP95Ms float64 `json:"upstream_p95_ms,omitempty"`
+	ChaosAvgMs    float64 `json:"chaos_avg_ms,omitempty"`
+}
+
+// ValidateGroupBy checks aggregation group keys.
+func ValidateGroupBy(groupBy []string) error {
+	for _, g := range groupBy {
+		switch g {
+		case GroupByEndpoint, GroupByPath, GroupByMethod, GroupByStatus, GroupByStatusClass, GroupByUpstream, GroupByRoute, GroupByOperation:
+		default:
+			return fmt.Errorf("unknown group_by %q (use endpoint, path, method, status, status_class, upstream or operation)", g)
+		}
+	}
+	return nil
+}
+
+// AggregateHTTP groups the HTTP entries among entries by the given keys
+// (default: endpoint) and computes counts, duration percentiles and status
+// breakdowns. Groups are sorted by count, largest first.
+func AggregateHTTP(entries []LogEntry, groupBy []string) []LogGroup {
+	if len(groupBy) == 0 {
+		groupBy = []string{GroupByEndpoint}
+	}
+
+	type bucket struct {
+		group     *LogGroup
+		durations []float64
+		upstream  []float64
+		chaos     float64
+	}
+	buckets := make(map[string]*bucket)
+	var order []string
+
+	for _, entry := range entries {
+		h := entry.HTTP
+		if entry.Type != LogTypeHTTP || h == nil {
+			continue
+		}
+
+		key := make(map[string]string, len(groupBy))
+		parts := make([]string, len(groupBy))
+		for i, g := range groupBy {
+			key[g] = groupValue(h, g)
+			parts[i] = key[g]
+		}
+		id := strings.Join(parts, "\x00")
+
+		b, ok := buckets[id]
+		if !ok {
+			b = &bucket{group: &LogGroup{Key: key, Statuses: make(map[string]int)}}
+			buckets[id] = b
+			order = append(order, id)
+		}
+		b.group.Count++
+		if h.StatusCode >= 500 || h.Error != "" || h.GraphQL != nil && h.GraphQL.ErrorCount > 0 {
+			b.group.Errors++
+		}
+		b.group.Statuses[strconv.Itoa(h.StatusCode)]++
+		b.durations = append(b.durations, float64(h.Duration)/float64(time.Millisecond))
+		if t := h.Timing; t != nil && t.Upstream > 0 {
+			b.upstream = append(b.upstream, float64(t.Upstream)/float64(time.Millisecond))
+			b.chaos += float64(t.Chaos) / float64(time.Millisecond)
+		}
+	}
+
+	groups := make([]LogGroup, 0, len(order))
+	for _, id := range order {
+		b := buckets[id]
+		sort.Float64s(b.durations)
+		var sum float64
+		for _, d := range b.durations {
+			sum += d
+		}
+		g := b.group
+		g.AvgMs = roundMs(sum / float64(len(b.durations)))
+		g.P50Ms = roundMs(percentile(b.durations, 50))
+		g.P95Ms = roundMs(percentile(b.durations, 95))
+		g.MaxMs = roundMs(b.durations[len(b.durations)-1])
+		if len(b.upstream) > 0 {
+			sort.Float64s(b.upstream)
+			g.UpstreamP50Ms = roundMs(percentile(b.upstream, 50))
+			g.UpstreamP95Ms = roundMs(percentile(b.upstream, 95))
+			g.ChaosAvgMs = roundMs(b.chaos / float64(len(b.upstream)))
+		}
+		groups = append(groups, *g)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+	return groups
+}
+
+// groupValue returns the value of a group key for an HTTP entry.
+func groupValue(h *HTTPLogEntry, groupBy string) string {
+	switch groupBy {
+	case GroupByEndpoint:
+		return h.Method + " " + EndpointTemplate(h.URL)
+	case GroupByPath:
+		return requestPath(h.URL)
+	case GroupByMethod:
+		return h.Method
+	case GroupByStatus:
+		return strconv.Itoa(h.StatusCode)
+	case GroupByStatusClass:
+		return fmt.Sprintf("%dxx", h.StatusCode/100)
+	case GroupByUpstream, GroupByRoute:
+		if h.Upstream == "" {
+			return "default"
+		}
+		return h.Upstream
+	case GroupByOperation:
+		if h.GraphQL == nil {
+			return "-"
+		}
+		return h.GraphQL.Label()
+	}
+	return ""
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// roundMs rounds milliseconds to two decimals for compact output.
+func roundMs(ms float64) float64 {
+	return math.Round(ms*100) / 100
+}
+
+// requestPath returns the path of a logged request URL, without the query.
+func requestPath(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		return u.Path
+	}
+	path, _, _ := strings.Cut(rawURL, "?")
+	return path
+}
+
+var (
+	uuidSegment  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegment   = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	tokenSegment = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+)
+
+// EndpointTemplate replaces the variable segments of a request path with
+// placeholders, so "/users/42/posts?page=2" becomes "/users/{id}/posts".
+func EndpointTemplate(rawURL string) string {
+	segments := strings.Split(requestPath(rawURL), "/")
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+		case isNumeric(seg):
+			segments[i] = "{id}"
+		case uuidSegment.MatchString(seg):
+			segments[i] = "{uuid}"
+		case hexSegment.MatchString(seg):
+			segments[i] = "{hash}"
+		case tokenSegment.MatchString(seg) && strings.ContainsAny(seg, "0123456789"):
+			segments[i] = "{token}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// jsonPredicate is a JSONPath expression with an optional comparison, e.g.
+// `$.errors[0].code == "UNAUTHENTICATED"`. Supported paths use .name,
+// ['name'], [index] (negative counts from the end) and [*] or .* wildcards.
+// Without an operator the predicate checks the path exists and is not null.
+// With wildcards it matches if any selected value does.
+type jsonPredicate struct {
+	path  []pathStep
+	op    string // "", ==, !=, <, <=, >, >=, =~
+	value interface{}
+	re    *regexp.Regexp
+}
+
+type pathStep struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+var predicateOps = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// parseJSONPredicate parses a predicate such as `$.data.user.role != "admin"`.
+func parseJSONPredicate(expr string) (*jsonPredicate, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	path, rest, err := parsePath(expr[1:])
+	if err != nil {
+		return nil, err
+	}
+	pred := &jsonPredicate{path: path}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return pred, nil
+	}
+	for _, op := range predicateOps {
+		if strings.HasPrefix(rest, op) {
+			pred.op = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if pred.op == "" {
+		return nil, fmt.Errorf("unexpected %q (use ==, !=, <, <=, >, >= or =~)", rest)
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("missing value after %s", pred.op)
+	}
+
+	if pred.op == "=~" {
+		pattern := rest
+		if len(pattern) >= 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
+			pattern = pattern[1 : len(pattern)-1]
+		} else if s, ok := parseQuoted(pattern); ok {
+			pattern = s
+		}
+		if pred.re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+		return pred, nil
+	}
+
+	if s, ok := parseQuoted(rest); ok {
+		pred.value = s
+	} else if err := json.Unmarshal([]byte(rest), &pred.value); err != nil {
+		return nil, fmt.Errorf("invalid value %s (quote strings)", rest)
+	}
+	return pred, nil
+}
+
+// parsePath parses path steps up to the first character that can't continue
+// the path, returning the remainder.
+func parsePath(s string) ([]pathStep, string, error) {
+	var steps []pathStep
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			if strings.HasPrefix(s, "*") {
+				steps = append(steps, pathStep{wildcard: true})
+				s = s[1:]
+				continue
+			}
+			n := 0
+			for n < len(s) && (isIdentByte(s[n])) {
+				n++
+			}
+			if n == 0 {
+				return nil, "", fmt.Errorf("expected a name after '.'")
+			}
+			steps = append(steps, pathStep{name: s[:n]})
+			s = s[n:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unclosed [")
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) > 0 && (inner[0] == '\'' || inner[0] == '"'):
+				name, ok := parseQuoted(inner)
+				if !ok {
+					return nil, "", fmt.Errorf("invalid name %s", inner)
+				}
+				steps = append(steps, pathStep{name: name})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid index [%s]", inner)
+				}
+				steps = append(steps, pathStep{index: n, isIndex: true})
+			}
+		default:
+			return steps, s, nil
+		}
+	}
+	return steps, "", nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '-' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseQuoted unquotes a single- or double-quoted string.
+func parseQuoted(s string) (string, bool) {
+	if len(s) < 2 {
+		return "", false
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		v, err := strconv.Unquote(s)
+		return v, err == nil
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), true
+	}
+	return "", false
+}
+
+// matchBody evaluates the predicate against a logged JSON body, reading the
+// stored copy when the preview doesn't hold all of it.
+func (p *jsonPredicate) matchBody(preview string, info *BodyInfo) bool {
+	if info == nil || info.Kind != BodyKindJSON {
+		return false
+	}
+	data, ok := capturedBody(preview, info)
+	if !ok {
+		return false
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return p.match(doc)
+}
+
+// match evaluates the predicate against a decoded JSON document.
+func (p *jsonPredicate) match(doc interface{}) bool {
+	for _, v := range selectPath(doc, p.path) {
+		if p.compare(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectPath returns the values a path selects.
+func selectPath(doc interface{}, steps []pathStep) []interface{} {
+	current := []interface{}{doc}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range current {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				switch {
+				case step.wildcard:
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, node[k])
+					}
+				case !step.isIndex:
+					if child, ok := node[step.name]; ok {
+						next = append(next, child)
+					}
+				}
+			case []interface{}:
+				switch {
+				case step.wildcard:
+					next = append(next, node...)
+				case step.isIndex:
+					i := step.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// compare applies the predicate's operator to a selected value.
+func (p *jsonPredicate) compare(v interface{}) bool {
+	switch p.op {
+	case "":
+		return v != nil
+	case "=~":
+		s, ok := v.(string)
+		if !ok {
+			b, _ := json.Marshal(v)
+			s = string(b)
+		}
+		return p.re.MatchString(s)
+	case "==":
+		return jsonEqual(v, p.value)
+	case "!=":
+		return !jsonEqual(v, p.value)
+	}
+
+	// Ordering works on numbers and on strings
+	switch want := p.value.(type) {
+	case float64:
+		got, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		return compareOrdered(got, want, p.op)
+	case string:
+		got, ok := v.(string)
+		if !ok {
+			return false
+		}
+		return compareOrdered(got, want, p.op)
+	}
+	return false
+}
+
+func compareOrdered[T float64 | string](got, want T, op string) bool {
+	switch op {
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	}
+	return false
+}
+
+// jsonEqual compares decoded JSON values.
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case string, float64, bool, nil:
+		return a == b
+	default:
+		ab, _ := json.Marshal(av)
+		bb, _ := json.Marshal(b)
+		return string(ab) == string(bb)
+	}
+}
@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+func TestJSONPredicate(t *testing.T) {
+	doc := `{
+		"errors": [{"code": "UNAUTHENTICATED", "path": ["viewer"]}],
+		"data": {"user": {"id": 42, "role": "admin", "tags": ["a", "b"]}, "items": [{"price": 5}, {"price": 20}]},
+		"odd key": true,
+		"missing": null
+	}`
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`$.errors[0].code == "UNAUTHENTICATED"`, true},
+		{`$.errors[0].code == 'FORBIDDEN'`, false},
+		{`$.errors[-1].path[0] == "viewer"`, true},
+		{`$.errors`, true},
+		{`$.missing`, false},
+		{`$.nope`, false},
+		{`$.data.user.id == 42`, true},
+		{`$.data.user.id >= 40`, true},
+		{`$.data.user.id < 40`, false},
+		{`$.data.user.role != "admin"`, false},
+		{`$.data.user.tags == ["a","b"]`, true},
+		{`$.data.items[*].price > 10`, true},
+		{`$.data.items[*].price > 50`, false},
+		{`$.data.*.role == "admin"`, true},
+		{`$['odd key'] == true`, true},
+		{`$.errors[0].code =~ /^UNAUTH/`, true},
+		{`$.data.user.id =~ "4\\d"`, true},
+	}
+	for _, tt := range tests {
+		p, err := parseJSONPredicate(tt.expr)
+		if err != nil {
+			t.Errorf("parseJSONPredicate(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := p.match(v); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`errors[0]`, `$.a[`, `$.a ==`, `$.a ~ 1`, `$.a == unquoted`, `$.a =~ /(/`} {
+		if _, err := parseJSONPredicate(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestQuery_StructuredFilters(t *testing.T) {
+	logger := NewTrafficLogger(100)
+	jsonInfo := &BodyInfo{Kind: BodyKindJSON}
+
+	logger.LogHTTP(HTTPLogEntry{
+		ID: "req-1", Method: "POST", URL: "/graphql", StatusCode: 200, Duration: 800 * time.Millisecond,
+		RequestHeaders:   map[string]string{"Authorization": "Bearer abc"},
+		ResponseHeaders:  map[string]string{"Content-Type": "application/json"},
+		RequestBody:      `{"operationName": "GetViewer"}`,
+		RequestBodyInfo:  jsonInfo,
+		ResponseBody:     `{"errors": [{"code": "UNAUTHENTICATED"}]}`,
+		ResponseBodyInfo: jsonInfo,
+	})
+	logger.LogHTTP(HTTPLogEntry{
+		ID: "req-2", Method: "GET", URL: "/api/users/7", StatusCode: 200, Duration: 20 * time.Millisecond,
+		ResponseHeaders:  map[string]string{"Content-Type": "application/json", "Cache-Control": "no-store"},
+		ResponseBody:     `{"id": 7}`,
+		ResponseBodyInfo: jsonInfo,
+	})
+	logger.LogHTTP(HTTPLogEntry{ID: "req-3", Method: "GET", URL: "/down", Error: "connection refused", Duration: time.Second})
+	logger.LogError(FrontendError{ID: "err-1", Message: "TypeError: Cannot read properties of undefined"})
+
+	ids := func(filter LogFilter) string {
+		var out []string
+		for _, e := range logger.Query(filter) {
+			switch {
+			case e.HTTP != nil:
+				out = append(out, e.HTTP.ID)
+			case e.Error != nil:
+				out = append(out, e.Error.ID)
+			}
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   string
+	}{
+		{"request header present", LogFilter{RequestHeaders: map[string]string{"authorization": ""}}, "req-1"},
+		{"response header substring", LogFilter{ResponseHeaders: map[string]string{"cache-control": "NO-STORE"}}, "req-2"},
+		{"min duration", LogFilter{MinDuration: 500 * time.Millisecond}, "req-1,req-3"},
+		{"max duration", LogFilter{MaxDuration: 100 * time.Millisecond}, "req-2"},
+		{"response body path", LogFilter{BodyPath: `$.errors[0].code == "UNAUTHENTICATED"`}, "req-1"},
+		{"request body path", LogFilter{RequestBodyPath: `$.operationName =~ /^Get/`}, "req-1"},
+		{"error pattern", LogFilter{ErrorPattern: `(?i)refused|undefined`}, "req-3,err-1"},
+		{"combined with type", LogFilter{Types: []LogEntryType{LogTypeError}, ErrorPattern: "undefined"}, "err-1"},
+		{"invalid pattern matches nothing", LogFilter{ErrorPattern: "("}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.filter); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery_BodyPathReadsStoredBody(t *testing.T) {
+	store := newBodyStore("test", &protocol.BodyCaptureConfig{InlineLimit: 16, Dir: t.TempDir()})
+	defer store.close()
+
+	bc := store.capture("req-1-response")
+	bc.Write([]byte(`{"padding": "` + strings.Repeat("x", 100) + `", "status": "failed"}`))
+	preview, info := bc.finish(http.Header{"Content-Type": {"application/json"}})
+	if info.File == "" {
+		t.Fatal("expected the body to be stored")
+	}
+
+	logger := NewTrafficLogger(10)
+	logger.LogHTTP(HTTPLogEntry{ID: "req-1", URL: "/job", ResponseBody: preview, ResponseBodyInfo: info})
+
+	if got := logger.Query(LogFilter{BodyPath: `$.status == "failed"`}); len(got) != 1 {
+		t.Errorf("expected the stored body to match, got %d entries", len(got))
+	}
+}
+
+func TestFilterFromQuery(t *testing.T) {
+	filter, err := FilterFromQuery(protocol.LogQueryFilter{
+		Types:       []string{"http"},
+		Since:       "5m",
+		Until:       "2026-01-02T15:04:05Z",
+		MinDuration: "250ms",
+		BodyPath:    "$.ok == false",
+	})
+	if err != nil {
+		t.Fatalf("FilterFromQuery: %v", err)
+	}
+	if filter.Types[0] != LogTypeHTTP || filter.MinDuration != 250*time.Millisecond {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+	if filter.Since == nil || time.Since(*filter.Since) < 5*time.Minute-time.Second {
+		t.Errorf("expected since five minutes ago, got %v", filter.Since)
+	}
+	if filter.Until == nil || filter.Until.Year() != 2026 {
+		t.Errorf("unexpected until %v", filter.Until)
+	}
+
+	for _, q := range []protocol.LogQueryFilter{
+		{Since: "yesterday"},
+		{Until: "5m"},
+		{MaxDuration: "fast"},
+		{BodyPath: "errors"},
+		{ErrorPattern: "[a-"},
+	} {
+		if _, err := FilterFromQuery(q); err == nil {
+			t.Errorf("expected an error for %+v", q)
+		}
+	}
+}
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := map[string]string{
+		"/api/users/42/posts?page=2":                                        "/api/users/{id}/posts",
+		"http://localhost:3000/orders/550e8400-e29b-41d4-a716-446655440000": "/orders/{uuid}",
+		"/blobs/d41d8cd98f00b204e9800998ecf8427e":                           "/blobs/{hash}",
+		"/invite/Xk9aPq2LmN7vB3sT5wYzR1":                                    "/invite/{token}",
+		"/static/app.js":                                                    "/static/app.js",
+		"/":                                                                 "/",
+	}
+	for in, want := range tests {
+		if got := EndpointTemplate(in); got != want {
+			t.Errorf("EndpointTemplate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAggregateHTTP(t *testing.T) {
+	var entries []LogEntry
+	add := func(method, url, upstream string, status int, ms int) {
+		entries = append(entries, LogEntry{Type: LogTypeHTTP, HTTP: &HTTPLogEntry{
+			Method: method, URL: url, Upstream: upstream, StatusCode: status,
+			Duration: time.Duration(ms) * time.Millisecond,
+		}})
+	}
+	for i := 1; i <= 20; i++ {
+		add("GET", "/users/"+strings.Repeat("1", i%3+1), "api", 200, i*10)
+	}
+	add("GET", "/users/9", "api", 500, 1000)
+	add("POST", "/login", "", 302, 50)
+	entries = append(entries, LogEntry{Type: LogTypeError, Error: &FrontendError{}})
+
+	groups := AggregateHTTP(entries, nil)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 endpoint groups, got %+v", groups)
+	}
+	users := groups[0]
+	if users.Key[GroupByEndpoint] != "GET /users/{id}" || users.Count != 21 || users.Errors != 1 {
+		t.Errorf("unexpected users group %+v", users)
+	}
+	if users.P50Ms != 110 || users.P95Ms != 200 || users.MaxMs != 1000 {
+		t.Errorf("unexpected percentiles p50=%v p95=%v max=%v", users.P50Ms, users.P95Ms, users.MaxMs)
+	}
+	if users.Statuses["200"] != 20 || users.Statuses["500"] != 1 {
+		t.Errorf("unexpected statuses %v", users.Statuses)
+	}
+
+	byRoute := AggregateHTTP(entries, []string{GroupByUpstream, GroupByStatusClass})
+	if len(byRoute) != 3 || byRoute[0].Key[GroupByUpstream] != "api" || byRoute[0].Key[GroupByStatusClass] != "2xx" {
+		t.Errorf("unexpected route groups %+v", byRoute)
+	}
+	for _, g := range byRoute {
+		if g.Key[GroupByUpstream] == "default" && g.Key[GroupByStatusClass] != "3xx" {
+			t.Errorf("unexpected default route group %+v", g)
+		}
+	}
+
+	if err := ValidateGroupBy([]string{"endpoint", "nope"}); err == nil {
+		t.Error("expected an error for an unknown group key")
+	}
+}
@@ -0,0 +1,286 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+// routeKey is the context key for the route a request matched.
+type routeKey struct{}
+
+// upstreamRoute is a compiled entry of a proxy's route table.
+type upstreamRoute struct {
+	spec   protocol.ProxyRoute
+	re     *regexp.Regexp
+	target *url.URL
+	proxy  *httputil.ReverseProxy
+}
+
+// routeTable is an ordered list of routes; the first match wins.
+type routeTable struct {
+	routes []*upstreamRoute
+}
+
+// Name identifies the route in logs and chaos rules: the explicit name, the
+// path prefix, or the pattern.
+func (rt *upstreamRoute) Name() string {
+	switch {
+	case rt.spec.Name != "":
+		return rt.spec.Name
+	case rt.spec.Path != "":
+		return rt.spec.Path
+	default:
+		return "~" + rt.spec.Pattern
+	}
+}
+
+// match reports whether path belongs to the route, and the length of the
+// leading part to remove when the route strips its prefix.
+func (rt *upstreamRoute) match(path string) (int, bool) {
+	if rt.re != nil {
+		loc := rt.re.FindStringIndex(path)
+		if loc == nil {
+			return 0, false
+		}
+		if loc[0] != 0 {
+			// Only a match anchored at the start can be stripped
+			return 0, true
+		}
+		return loc[1], true
+	}
+
+	prefix := rt.spec.Path
+	if !strings.HasPrefix(path, prefix) {
+		return 0, false
+	}
+	// "/api" matches "/api" and "/api/users" but not "/apidocs"
+	if len(path) > len(prefix) && !strings.HasSuffix(prefix, "/") && path[len(prefix)] != '/' {
+		return 0, false
+	}
+	return len(prefix), true
+}
+
+// stripPath removes the first n bytes of a request path, keeping it rooted.
+func stripPath(path string, n int) string {
+	path = path[n:]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// ValidateRoutes checks a route table without building it.
+func ValidateRoutes(specs []protocol.ProxyRoute) error {
+	_, err := compileRoutes(specs)
+	return err
+}
+
+// compileRoutes validates route specs and parses their targets and patterns.
+// Reverse proxies are attached separately by SetRoutes.
+func compileRoutes(specs []protocol.ProxyRoute) ([]*upstreamRoute, error) {
+	routes := make([]*upstreamRoute, 0, len(specs))
+	for i, spec := range specs {
+		label := fmt.Sprintf("route %d", i+1)
+		if spec.Name != "" {
+			label = fmt.Sprintf("route %q", spec.Name)
+		}
+
+		if (spec.Path == "") == (spec.Pattern == "") {
+			return nil, fmt.Errorf("%s: exactly one of path or pattern is required", label)
+		}
+		if spec.Path != "" && !strings.HasPrefix(spec.Path, "/") {
+			return nil, fmt.Errorf("%s: path %q must start with /", label, spec.Path)
+		}
+		if spec.Target == "" {
+			return nil, fmt.Errorf("%s: target is required", label)
+		}
+		target, err := url.Parse(spec.Target)
+		if err != nil || target.Host == "" || (target.Scheme != "http" && target.Scheme != "https") {
+			return nil, fmt.Errorf("%s: invalid target URL %q", label, spec.Target)
+		}
+
+		rt := &upstreamRoute{spec: spec, target: target}
+		if spec.Pattern != "" {
+			rt.re, err = regexp.Compile(spec.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern: %w", label, err)
+			}
+		}
+		routes = append(routes, rt)
+	}
+	return routes, nil
+}
+
+// SetRoutes replaces the proxy's route table. Requests that match no route go
+// to the proxy target. Safe to call while the proxy is running.
+func (ps *ProxyServer) SetRoutes(specs []protocol.ProxyRoute) error {
+	routes, err := compileRoutes(specs)
+	if err != nil {
+		return err
+	}
+	for _, rt := range routes {
+		rt.proxy = ps.newRouteProxy(rt)
+	}
+	ps.routes.Store(&routeTable{routes: routes})
+	return nil
+}
+
+// Routes returns the proxy's route table in match order.
+func (ps *ProxyServer) Routes() []protocol.ProxyRoute {
+	table := ps.routes.Load()
+	if table == nil {
+		return nil
+	}
+	specs := make([]protocol.ProxyRoute, len(table.routes))
+	for i, rt := range table.routes {
+		specs[i] = rt.spec
+	}
+	return specs
+}
+
+// newRouteProxy builds the reverse proxy for a route. It shares the proxy's
+// transport (and so its chaos injection) and response rewriting.
+func (ps *ProxyServer) newRouteProxy(rt *upstreamRoute) *httputil.ReverseProxy {
+	rp := httputil.NewSingleHostReverseProxy(rt.target)
+	rp.Transport = ps.proxy.Transport
+	rp.ErrorHandler = ps.errorHandler
+	rp.ModifyResponse = ps.modifyResponse
+
+	director := rp.Director
+	rp.Director = func(req *http.Request) {
+		originalHost := req.Host
+
+		if rt.spec.StripPrefix {
+			if n, ok := rt.match(req.URL.Path); ok && n > 0 {
+				req.URL.Path = stripPath(req.URL.Path, n)
+				req.URL.RawPath = ""
+			}
+		}
+
+		director(req)
+
+		req.Host = rt.target.Host
+		if rt.spec.HostHeader != "" {
+			req.Host = rt.spec.HostHeader
+		}
+		ps.setForwardedHeaders(req, originalHost)
+	}
+	return rp
+}
+
+// matchRoute returns the first route matching the request path, or nil.
+func (ps *ProxyServer) matchRoute(r *http.Request) *upstreamRoute {
+	table := ps.routes.Load()
+	if table == nil {
+		return nil
+	}
+	for _, rt := range table.routes {
+		if _, ok := rt.match(r.URL.Path); ok {
+			return rt
+		}
+	}
+	return nil
+}
+
+// defaultRouteName names the proxy target in logs and chaos rules.
+const defaultRouteName = "default"
+
+// newDefaultRoute wraps the proxy target as a route, so every proxied request
+// carries a route in its context.
+func newDefaultRoute(target *url.URL, rp *httputil.ReverseProxy) *upstreamRoute {
+	return &upstreamRoute{
+		spec:   protocol.ProxyRoute{Name: defaultRouteName, Path: "/", Target: target.String()},
+		target: target,
+		proxy:  rp,
+	}
+}
+
+// routeRequest picks the upstream for a request and stores the route in the
+// request context for logging, chaos rules and response rewriting.
+func (ps *ProxyServer) routeRequest(r *http.Request) (*upstreamRoute, *http.Request) {
+	rt := ps.matchRoute(r)
+	if rt == nil {
+		rt = ps.defaultRoute
+	}
+	return rt, r.WithContext(context.WithValue(r.Context(), routeKey{}, rt))
+}
+
+// requestRoute returns the route a request was sent through, or nil if it
+// was not routed.
+func requestRoute(r *http.Request) *upstreamRoute {
+	if r == nil {
+		return nil
+	}
+	rt, _ := r.Context().Value(routeKey{}).(*upstreamRoute)
+	return rt
+}
+
+// upstreamURL returns the upstream a request was sent to.
+func (ps *ProxyServer) upstreamURL(r *http.Request) *url.URL {
+	if rt := requestRoute(r); rt != nil {
+		return rt.target
+	}
+	return ps.TargetURL
+}
+
+// rewriteHosts returns the upstream hosts whose absolute URLs are rewritten to
+// the proxy origin: the target, plus route upstreams that keep their path.
+// URLs to routes that strip a prefix cannot be mapped back, so they are left.
+func (ps *ProxyServer) rewriteHosts() []string {
+	var hosts []string
+	if ps.TargetURL != nil && ps.TargetURL.Host != "" {
+		hosts = append(hosts, ps.TargetURL.Host)
+	}
+	table := ps.routes.Load()
+	if table == nil {
+		return hosts
+	}
+	for _, rt := range table.routes {
+		if rt.spec.StripPrefix {
+			continue
+		}
+		dup := false
+		for _, h := range hosts {
+			if h == rt.target.Host {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			hosts = append(hosts, rt.target.Host)
+		}
+	}
+	return hosts
+}
+
+// upstreamMatches reports whether a chaos rule's upstream filter selects the
+// request. The filter is a route name ("default" for the proxy target), or an
+// upstream URL or host.
+func upstreamMatches(filter string, r *http.Request) bool {
+	if filter == "" {
+		return true
+	}
+	rt := requestRoute(r)
+	if rt == nil {
+		return false
+	}
+	return filter == rt.Name() || filter == rt.target.Host ||
+		strings.TrimRight(filter, "/") == strings.TrimRight(rt.target.String(), "/")
+}
+
+// routeLogName returns the route name recorded in HTTP logs. Requests to the
+// proxy target are left unlabelled so logs of single-upstream proxies are
+// unchanged.
+func (ps *ProxyServer) routeLogName(rt *upstreamRoute) string {
+	if rt == nil || rt == ps.defaultRoute {
+		return ""
+	}
+	return rt.Name()
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/standardbeagle/agnt/internal/protocol"
+)
+
+func TestCompileRoutes_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   protocol.ProxyRoute
+		wantErr string
+	}{
+		{"prefix", protocol.ProxyRoute{Path: "/api", Target: "http://localhost:8080"}, ""},
+		{"pattern", protocol.ProxyRoute{Pattern: `^/v\d+/`, Target: "https://api.example.com"}, ""},
+		{"neither", protocol.ProxyRoute{Target: "http://localhost:8080"}, "exactly one"},
+		{"both", protocol.ProxyRoute{Path: "/api", Pattern: "^/api", Target: "http://localhost:8080"}, "exactly one"},
+		{"relative path", protocol.ProxyRoute{Path: "api", Target: "http://localhost:8080"}, "must start with /"},
+		{"no target", protocol.ProxyRoute{Path: "/api"}, "target is required"},
+		{"bad target", protocol.ProxyRoute{Path: "/api", Target: "localhost:8080"}, "invalid target"},
+		{"bad pattern", protocol.ProxyRoute{Name: "broken", Pattern: "(", Target: "http://localhost:8080"}, `route "broken": invalid pattern`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRoutes([]protocol.ProxyRoute{tt.route})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpstreamRoute_Match(t *testing.T) {
+	routes, err := compileRoutes([]protocol.ProxyRoute{
+		{Path: "/api", Target: "http://localhost:8080"},
+		{Path: "/static/", Target: "http://localhost:9000"},
+		{Pattern: `^/v\d+`, Target: "http://localhost:9100"},
+		{Pattern: `\.map$`, Target: "http://localhost:9200"},
+	})
+	if err != nil {
+		t.Fatalf("compileRoutes: %v", err)
+	}
+
+	tests := []struct {
+		route int
+		path  string
+		want  bool
+		strip string
+	}{
+		{0, "/api", true, "/"},
+		{0, "/api/users", true, "/users"},
+		{0, "/apidocs", false, ""},
+		{1, "/static/app.js", true, "/app.js"},
+		{1, "/static", false, ""},
+		{2, "/v2/items", true, "/items"},
+		{2, "/items/v2", false, ""},
+		{3, "/js/app.js.map", true, "/js/app.js.map"}, // unanchored match is not stripped
+	}
+
+	for _, tt := range tests {
+		n, ok := routes[tt.route].match(tt.path)
+		if ok != tt.want {
+			t.Errorf("route %d match(%q) = %v, want %v", tt.route, tt.path, ok, tt.want)
+			continue
+		}
+		if ok {
+			if got := stripPath(tt.path, n); got != tt.strip {
+				t.Errorf("route %d strip(%q) = %q, want %q", tt.route, tt.path, got, tt.strip)
+			}
+		}
+	}
+}
+
+func TestProxyRoutes_EndToEnd(t *testing.T) {
+	echo := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, name+" host="+r.Host+" path="+r.URL.Path)
+		}))
+	}
+	frontend := echo("frontend")
+	defer frontend.Close()
+	api := echo("api")
+	defer api.Close()
+	auth := echo("auth")
+	defer auth.Close()
+
+	ps, err := NewProxyServer(ProxyConfig{
+		ID:         "test-routes",
+		TargetURL:  frontend.URL,
+		ListenPort: 0,
+		Routes: []protocol.ProxyRoute{
+			{Path: "/api", Target: api.URL, StripPrefix: true},
+			{Name: "auth", Pattern: "^/(login|logout)", Target: auth.URL, HostHeader: "auth.local"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ps.Stop(context.Background())
+	<-ps.Ready()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get("http://" + ps.ListenAddr + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if _, body := get("/api/users"); !strings.HasPrefix(body, "api ") || !strings.Contains(body, "path=/users") {
+		t.Errorf("expected /api to reach the API with the prefix stripped, got %q", body)
+	}
+	if _, body := get("/login"); !strings.HasPrefix(body, "auth ") || !strings.Contains(body, "host=auth.local") {
+		t.Errorf("expected /login to reach auth with the Host override, got %q", body)
+	}
+	if _, body := get("/apidocs"); !strings.HasPrefix(body, "frontend ") {
+		t.Errorf("expected unmatched paths to reach the target, got %q", body)
+	}
+
+	// HTTP logs record which upstream served each request
+	upstreams := map[string]string{}
+	for _, e := range ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeHTTP}}) {
+		upstreams[e.HTTP.URL] = e.HTTP.Upstream
+	}
+	if upstreams["/api/users"] != "/api" || upstreams["/login"] != "auth" || upstreams["/apidocs"] != "" {
+		t.Errorf("unexpected upstream labels: %v", upstreams)
+	}
+
+	// Chaos rules can target a single upstream
+	ps.ChaosEngine().AddRule(&ChaosRule{
+		ID:         "api-down",
+		Type:       ChaosHTTPError,
+		Enabled:    true,
+		ErrorCodes: []int{503},
+		Upstream:   "/api",
+	})
+	ps.ChaosEngine().Enable()
+
+	if status, _ := get("/api/users"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected chaos 503 on the API route, got %d", status)
+	}
+	if status, _ := get("/"); status != http.StatusOK {
+		t.Errorf("expected the target to be unaffected, got %d", status)
+	}
+
+	// Routes can be replaced while running
+	if err := ps.SetRoutes(nil); err != nil {
+		t.Fatalf("SetRoutes: %v", err)
+	}
+	if len(ps.Routes()) != 0 {
+		t.Errorf("expected no routes, got %v", ps.Routes())
+	}
+	if _, body := get("/login"); !strings.HasPrefix(body, "frontend ") {
+		t.Errorf("expected /login to reach the target after clearing routes, got %q", body)
+	}
+}
+
+func TestRewriteHosts(t *testing.T) {
+	ps := newTestProxyServer("http://localhost:3000", ":8080")
+	routes, _ := compileRoutes([]protocol.ProxyRoute{
+		{Path: "/api", Target: "http://localhost:8080"},
+		{Path: "/auth", Target: "http://localhost:9000", StripPrefix: true},
+		{Path: "/v2", Target: "http://localhost:8080"},
+	})
+	ps.routes.Store(&routeTable{routes: routes})
+
+	got := ps.rewriteHosts()
+	if len(got) != 2 || got[0] != "localhost:3000" || got[1] != "localhost:8080" {
+		t.Errorf("rewriteHosts() = %v, want target and non-stripping route hosts", got)
+	}
+}
@@ -0,0 +1,155 @@
+// Agent Response Streaming for DevTool
+// Relays streamed agent responses from the daemon to page listeners and the
+// ResponseStreamPanel via '__devtool:agent:message' events
+
+(function() {
+  'use strict';
+
+  var core = window.__devtool_core;
+
+  var EVENT_NAME = '__devtool:agent:message';
+
+  // Active streams (stream_id -> {content, listeners, resolve, reject})
+  var streams = {};
+  var streamIdCounter = 0;
+
+  // Generate unique stream ID
+  function generateStreamId() {
+    return 'stream_' + Date.now().toString(36) + '_' + (++streamIdCounter);
+  }
+
+  // Re-dispatch a stream event as a DOM event for UI components
+  function dispatch(detail) {
+    try {
+      window.dispatchEvent(new CustomEvent(EVENT_NAME, { detail: detail }));
+    } catch (e) {
+      console.error('[DevTool] Failed to dispatch agent stream event:', e);
+    }
+  }
+
+  // Handle agent stream events from server
+  function handleAgentStream(message) {
+    if (message.type !== 'agent_stream') return;
+
+    var payload = message.payload || message.data || {};
+    var streamId = payload.sessionId;
+    var stream = streamId ? streams[streamId] : null;
+
+    if (stream) {
+      if (payload.type === 'chunk' && payload.chunk) {
+        stream.content += payload.chunk;
+      }
+      for (var i = 0; i < stream.listeners.length; i++) {
+        try {
+          stream.listeners[i](payload);
+        } catch (e) {
+          console.error('[DevTool] Agent stream listener failed:', e);
+        }
+      }
+      if (payload.type === 'end') {
+        delete streams[streamId];
+        stream.resolve(payload.content || stream.content);
+      } else if (payload.type === 'error') {
+        delete streams[streamId];
+        stream.reject(new Error(payload.content || 'Agent stream failed'));
+      }
+    }
+
+    // The panel renders the full content on 'end'
+    if (payload.type === 'end' && !payload.content && stream) {
+      payload.content = stream.content;
+    }
+    dispatch(payload);
+  }
+
+  // Register message handler for stream events
+  if (core && core.onMessage) {
+    core.onMessage(handleAgentStream);
+  }
+
+  // Track a stream started by this page
+  function track(streamId, onEvent) {
+    var stream = { content: '', listeners: [] };
+    if (typeof onEvent === 'function') {
+      stream.listeners.push(onEvent);
+    }
+    stream.promise = new Promise(function(resolve, reject) {
+      stream.resolve = resolve;
+      stream.reject = reject;
+    });
+    streams[streamId] = stream;
+    return stream;
+  }
+
+  // Agent streaming API
+  var agentStream = {
+    /**
+     * Send a prompt to the agent and stream the response
+     * @param {string} prompt - Prompt for the agent
+     * @param {Object} [options] - Options
+     * @param {string} [options.context] - Extra context sent with the prompt
+     * @param {Function} [options.onEvent] - Called for each start/chunk/system/end/error event
+     * @returns {{id: string, done: Promise<string>, cancel: Function}} - Stream handle
+     */
+    request: function(prompt, options) {
+      options = options || {};
+      if (!prompt) {
+        return { id: null, done: Promise.reject(new Error('Prompt is required')), cancel: function() {} };
+      }
+
+      var streamId = generateStreamId();
+      var stream = track(streamId, options.onEvent);
+
+      core.send('agent_request', {
+        stream_id: streamId,
+        prompt: prompt,
+        context: options.context || ''
+      });
+
+      return {
+        id: streamId,
+        done: stream.promise,
+        cancel: function() { agentStream.cancel(streamId); }
+      };
+    },
+
+    /**
+     * Create a stream ID to attach to panel or design messages sent with stream: true
+     * @param {Function} [onEvent] - Called for each stream event
+     * @returns {{id: string, done: Promise<string>}} - Stream handle
+     */
+    prepare: function(onEvent) {
+      var streamId = generateStreamId();
+      var stream = track(streamId, onEvent);
+      return { id: streamId, done: stream.promise };
+    },
+
+    /**
+     * Cancel a running stream
+     * @param {string} streamId - Stream ID returned by request()
+     */
+    cancel: function(streamId) {
+      if (!streamId) return;
+      core.send('agent_cancel', { stream_id: streamId });
+    },
+
+    /**
+     * List IDs of streams started by this page that are still running
+     * @returns {string[]}
+     */
+    active: function() {
+      return Object.keys(streams);
+    }
+  };
+
+  // Cancel requests from the ResponseStreamPanel
+  window.addEventListener('__devtool:agent:cancel', function(event) {
+    var detail = event && event.detail;
+    if (detail && detail.sessionId) {
+      agentStream.cancel(detail.sessionId);
+    }
+  });
+
+  // Export to global scope
+  window.__devtool_agentStream = agentStream;
+})();
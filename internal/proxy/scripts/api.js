@@ -24,6 +24,7 @@
   var diagnostics = window.__devtool_diagnostics;
   var session = window.__devtool_session;
   var store = window.__devtool_store;
+  var agentStream = window.__devtool_agentStream;
   var content = window.__devtool_content;
   var wireframe = window.__devtool_wireframe;
 
@@ -297,6 +298,17 @@
       page: {}
     },
 
+    // ========================================================================
+    // AGENT RESPONSE STREAMING
+    // ========================================================================
+
+    agent: agentStream || {
+      request: function() { return { id: null, done: Promise.reject(new Error('Agent stream module not loaded')), cancel: function() {} }; },
+      prepare: function() { return { id: null, done: Promise.reject(new Error('Agent stream module not loaded')) }; },
+      cancel: function() {},
+      active: function() { return []; }
+    },
+
     // ========================================================================
     // CONTENT EXTRACTION & INFORMATION ARCHITECTURE
     // ========================================================================
@@ -49,6 +49,32 @@
     })();
 
     var WS_URL = protocol + '//' + window.location.host + '/__devtool_metrics';
+
+    // Per-proxy secret injected alongside this script. Without it the proxy
+    // refuses the connection, so other pages in the browser cannot use the
+    // socket to send prompts to the agent. Removed from window once read.
+    var WS_TOKEN = '';
+    try {
+      WS_TOKEN = window.__devtool_token || '';
+      delete window.__devtool_token;
+    } catch (e) {
+      window.__devtool_token = undefined;
+    }
+    var WS_CLOSE_AUTH_FAILED = 4401;
+
+    // Browser features switched off in the proxy configuration, e.g.
+    // { indicator: false }. Features not listed are on.
+    var FEATURES = {};
+    try {
+      FEATURES = window.__devtool_features || {};
+      delete window.__devtool_features;
+    } catch (e) {
+      window.__devtool_features = undefined;
+    }
+
+    function isEnabled(name) {
+      return FEATURES[name] !== false;
+    }
     var ws = null;
     var reconnectAttempts = 0;
     var MAX_RECONNECT_ATTEMPTS = 5;
@@ -219,13 +245,14 @@
           }
         }
 
-        ws = new WebSocket(WS_URL);
+        ws = new WebSocket(WS_TOKEN ? WS_URL + '?token=' + encodeURIComponent(WS_TOKEN) : WS_URL);
 
         ws.onopen = function() {
           try {
             console.log('[DevTool] Metrics connection established');
             reconnectAttempts = 0;
             sendPageLoad();
+            flushConsolePending();
           } catch (e) {
             reportInternalError('onopen_handler_failed', e);
           }
@@ -251,10 +278,16 @@
           }
         };
 
-        ws.onclose = function() {
+        ws.onclose = function(event) {
           try {
             console.log('[DevTool] Metrics connection closed');
 
+            // The token is fixed for the page, so retrying cannot succeed
+            if (event && event.code === WS_CLOSE_AUTH_FAILED) {
+              console.warn('[DevTool] Metrics connection rejected; reload the page to reconnect');
+              return;
+            }
+
             if (reconnectAttempts < MAX_RECONNECT_ATTEMPTS) {
               reconnectAttempts++;
               var delay = 1000 * reconnectAttempts;
@@ -294,6 +327,19 @@
           executeJavaScript(message.id, message.code);
         }
 
+        // Stale token (e.g. the daemon restarted since the page loaded)
+        if (message.type === 'auth_error' && message.payload) {
+          console.warn('[DevTool] ' + message.payload.message);
+        }
+
+        // Tunnel restarts change the public URL
+        if (message.type === 'public_url' && message.payload) {
+          window.__devtool_public_url = message.payload.url;
+          window.dispatchEvent(new CustomEvent('__devtool:public-url', {
+            detail: { url: message.payload.url }
+          }));
+        }
+
         // Notify registered handlers
         for (var i = 0; i < messageHandlers.length; i++) {
           try {
@@ -476,17 +522,7 @@
         window.addEventListener('unhandledrejection', function(event) {
           try {
             if (!event) return;
-
-            var reason = event.reason || 'Unknown rejection';
-            send('error', {
-              message: 'Unhandled Promise Rejection: ' + reason,
-              source: '',
-              lineno: 0,
-              colno: 0,
-              error: reason.toString ? reason.toString() : String(reason),
-              stack: reason.stack || '',
-              timestamp: Date.now()
-            });
+            sendOrQueue('rejection', describeRejection(event.reason));
           } catch (e) {
             reportInternalError('rejection_handler_failed', e);
           }
@@ -496,6 +532,215 @@
       }
     }
 
+    // Console and rejection messages logged before the connection opens
+    // are kept and sent once it does
+    var consoleConfig = {
+      maxArgs: 10,
+      maxArgLength: 200,
+      maxMessageLength: 1000,
+      maxPerSecond: 20,
+      maxPending: 50
+    };
+    var consolePending = [];
+    var consoleCapturing = false;
+    var consoleWindowStart = 0;
+    var consoleWindowCount = 0;
+    var consoleDropped = 0;
+
+    function sendOrQueue(type, data) {
+      if (send(type, data)) return;
+      consolePending.push({ type: type, data: data });
+      if (consolePending.length > consoleConfig.maxPending) consolePending.shift();
+    }
+
+    function flushConsolePending() {
+      while (consolePending.length) {
+        var pending = consolePending[0];
+        if (!send(pending.type, pending.data)) return;
+        consolePending.shift();
+      }
+    }
+
+    function truncateConsoleText(text, max) {
+      return text.length > max ? text.substring(0, max) + '...' : text;
+    }
+
+    // A short preview of a logged value, like the console shows it
+    function previewConsoleArg(arg) {
+      var text;
+      try {
+        if (typeof arg === 'string') {
+          text = arg;
+        } else if (arg instanceof Error) {
+          text = (arg.name || 'Error') + ': ' + arg.message;
+        } else if (typeof arg === 'function') {
+          text = 'function ' + (arg.name || 'anonymous') + '()';
+        } else if (arg === null || typeof arg !== 'object') {
+          text = String(arg);
+        } else if (typeof Element !== 'undefined' && arg instanceof Element) {
+          text = '<' + arg.tagName.toLowerCase() + (arg.id ? '#' + arg.id : '') + '>';
+        } else {
+          try {
+            text = JSON.stringify(arg);
+          } catch (e) {
+            text = undefined;
+          }
+          if (text === undefined) text = Object.prototype.toString.call(arg);
+        }
+      } catch (e) {
+        text = '[unavailable]';
+      }
+      return truncateConsoleText(text, consoleConfig.maxArgLength);
+    }
+
+    // Format arguments like the console: substitutions in a leading format
+    // string, then the remaining arguments separated by spaces
+    function formatConsoleArgs(args, previews) {
+      var parts = [];
+      var next = 0;
+      if (typeof args[0] === 'string' && args[0].indexOf('%') !== -1) {
+        next = 1;
+        parts.push(args[0].replace(/%[sdifoOc%]/g, function(spec) {
+          if (spec === '%%') return '%';
+          if (next >= args.length) return spec;
+          var arg = args[next];
+          var preview = previews[next] !== undefined ? previews[next] : previewConsoleArg(arg);
+          next++;
+          if (spec === '%c') return '';
+          if (spec === '%d' || spec === '%i') return String(parseInt(arg, 10));
+          if (spec === '%f') return String(parseFloat(arg));
+          return preview;
+        }));
+      }
+      for (; next < args.length; next++) {
+        parts.push(previews[next] !== undefined ? previews[next] : previewConsoleArg(args[next]));
+      }
+      return parts.join(' ');
+    }
+
+    // Where the console was called from, without this module's frames
+    function consoleCallStack() {
+      try {
+        var stack = new Error().stack || '';
+        var lines = stack.split('\n');
+        var kept = [];
+        for (var i = 0; i < lines.length; i++) {
+          if (i === 0 && /^Error/.test(lines[i])) continue;
+          if (lines[i].indexOf('consoleCallStack') !== -1 || lines[i].indexOf('captureConsoleCall') !== -1 ||
+              lines[i].indexOf('devtoolConsole') !== -1) continue;
+          kept.push(lines[i]);
+        }
+        return kept.join('\n');
+      } catch (e) {
+        return '';
+      }
+    }
+
+    // At most maxPerSecond messages are sent; the rest are counted
+    function consoleRateAllowed() {
+      var now = Date.now();
+      if (now - consoleWindowStart >= 1000) {
+        if (consoleDropped > 0) {
+          sendOrQueue('console', {
+            level: 'warn',
+            message: consoleDropped + ' console messages not captured (rate limit)',
+            timestamp: now
+          });
+        }
+        consoleWindowStart = now;
+        consoleWindowCount = 0;
+        consoleDropped = 0;
+      }
+      if (consoleWindowCount >= consoleConfig.maxPerSecond) {
+        consoleDropped++;
+        return false;
+      }
+      consoleWindowCount++;
+      return true;
+    }
+
+    function captureConsoleCall(level, args, prefix) {
+      // The capture itself may log (reportInternalError); don't recurse
+      if (consoleCapturing) return;
+      consoleCapturing = true;
+      try {
+        if (typeof args[0] === 'string' && args[0].indexOf('[DevTool') === 0) return;
+        if (!consoleRateAllowed()) return;
+
+        var previews = [];
+        var argPreviews = [];
+        for (var i = 0; i < args.length; i++) {
+          previews[i] = previewConsoleArg(args[i]);
+          if (i < consoleConfig.maxArgs) argPreviews.push(previews[i]);
+        }
+        var message = formatConsoleArgs(args, previews);
+        if (prefix) message = message ? prefix + ': ' + message : prefix;
+
+        sendOrQueue('console', {
+          level: level,
+          message: truncateConsoleText(message, consoleConfig.maxMessageLength),
+          args: argPreviews,
+          stack: consoleCallStack(),
+          timestamp: Date.now()
+        });
+      } catch (e) {
+        reportInternalError('console_capture_failed', e);
+      } finally {
+        consoleCapturing = false;
+      }
+    }
+
+    // Capture console.warn, console.error and failed console.assert calls;
+    // the original methods still run
+    function setupConsoleCapture() {
+      try {
+        if (typeof console === 'undefined') return;
+        ['warn', 'error'].forEach(function(level) {
+          var original = console[level];
+          if (typeof original !== 'function') return;
+          console[level] = function devtoolConsoleCapture() {
+            captureConsoleCall(level, Array.prototype.slice.call(arguments), '');
+            return original.apply(this, arguments);
+          };
+        });
+        var originalAssert = console.assert;
+        if (typeof originalAssert === 'function') {
+          console.assert = function devtoolConsoleAssert(condition) {
+            if (!condition) {
+              captureConsoleCall('error', Array.prototype.slice.call(arguments, 1), 'Assertion failed');
+            }
+            return originalAssert.apply(this, arguments);
+          };
+        }
+      } catch (e) {
+        reportInternalError('console_capture_setup_failed', e);
+      }
+    }
+
+    // Describe the reason of an unhandled rejection; anything can be thrown
+    function describeRejection(reason) {
+      var data = {
+        message: '',
+        name: '',
+        reason_type: reason === null ? 'null' : typeof reason,
+        stack: '',
+        timestamp: Date.now()
+      };
+      if (reason instanceof Error) {
+        data.reason_type = 'error';
+        data.name = reason.name || 'Error';
+        data.message = reason.message || data.name;
+        data.stack = reason.stack || '';
+      } else if (reason === undefined) {
+        data.message = 'undefined';
+      } else {
+        data.message = previewConsoleArg(reason);
+        if (reason && typeof reason.stack === 'string') data.stack = reason.stack;
+      }
+      data.message = truncateConsoleText(data.message, consoleConfig.maxMessageLength);
+      return data;
+    }
+
     // Performance tracking
     function sendPageLoad() {
       try {
@@ -591,6 +836,9 @@
     // Initialize
     try {
       setupErrorTracking();
+      if (isEnabled('console')) {
+        setupConsoleCapture();
+      }
       connect();
     } catch (e) {
       reportInternalError('initialization_failed', e);
@@ -612,6 +860,7 @@
             }
           },
           getSessionId: getOrCreateSessionId,
+          isEnabled: isEnabled,
           reportError: reportInternalError
         };
       }
@@ -48,6 +48,12 @@ var (
 	//go:embed mutation.js
 	mutationJS string
 
+	//go:embed navigation.js
+	navigationJS string
+
+	//go:embed vitals.js
+	vitalsJS string
+
 	//go:embed toast.js
 	toastJS string
 
@@ -75,6 +81,9 @@ var (
 	//go:embed store.js
 	storeJS string
 
+	//go:embed agent-stream.js
+	agentStreamJS string
+
 	//go:embed content.js
 	contentJS string
 
@@ -189,6 +198,16 @@ func buildCombinedScript() string {
 	sb.WriteString(wrapModule(mutationJS))
 	sb.WriteString("\n\n")
 
+	// 13a. Navigation tracking (depends on core)
+	sb.WriteString("  // Navigation tracking module\n")
+	sb.WriteString(wrapModule(navigationJS))
+	sb.WriteString("\n\n")
+
+	// 13b. Web vitals (depends on utils, core; listens for navigation's route events)
+	sb.WriteString("  // Web vitals module\n")
+	sb.WriteString(wrapModule(vitalsJS))
+	sb.WriteString("\n\n")
+
 	// 14. Toast notifications (no dependencies)
 	sb.WriteString("  // Toast notification module\n")
 	sb.WriteString(wrapModule(toastJS))
@@ -234,6 +253,11 @@ func buildCombinedScript() string {
 	sb.WriteString(wrapModule(storeJS))
 	sb.WriteString("\n\n")
 
+	// 22a. Agent response streaming (depends on core)
+	sb.WriteString("  // Agent stream module\n")
+	sb.WriteString(wrapModule(agentStreamJS))
+	sb.WriteString("\n\n")
+
 	// 23. Content extraction (depends on utils)
 	sb.WriteString("  // Content extraction module\n")
 	sb.WriteString(wrapModule(contentJS))
@@ -322,6 +346,8 @@ func GetScriptNames() []string {
 		"audit.js",
 		"interaction.js",
 		"mutation.js",
+		"navigation.js",
+		"vitals.js",
 		"toast.js",
 		"voice.js",
 		"sketch.js",
@@ -331,6 +357,7 @@ func GetScriptNames() []string {
 		"diagnostics.js",
 		"session.js",
 		"store.js",
+		"agent-stream.js",
 		"content.js",
 		"text-fragility.js",
 		"responsive-risk.js",
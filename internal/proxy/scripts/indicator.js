@@ -19,6 +19,7 @@
     bug: null,
     panel: null,
     outputPreview: null, // Floating output preview element
+    shareCard: null, // LAN share QR card
     isExpanded: false,
     isDragging: false,
     dragOffset: { x: 0, y: 0 },
@@ -141,6 +142,25 @@
       'transform: translateX(0)'
     ].join(';'),
 
+    // LAN share card - QR code for opening the page on another device
+    shareCard: [
+      'position: fixed',
+      'top: 50%',
+      'left: 50%',
+      'transform: translate(-50%, -50%)',
+      'width: 280px',
+      'background: ' + TOKENS.colors.surface,
+      'color: ' + TOKENS.colors.text,
+      'border: 1px solid ' + TOKENS.colors.border,
+      'border-radius: ' + TOKENS.radius.lg,
+      'box-shadow: ' + TOKENS.shadow.lg,
+      'padding: 16px',
+      'font-family: system-ui, -apple-system, sans-serif',
+      'font-size: 13px',
+      'text-align: center',
+      'z-index: 2147483646'
+    ].join(';'),
+
     // Panel - the main interface
     panel: [
       'position: fixed',
@@ -551,6 +571,73 @@
     }, 3000);
   }
 
+  // Show the LAN share QR code and URLs
+  function showShareCard(share) {
+    if (!state.container || !share || !share.urls || share.urls.length === 0) return;
+    hideShareCard();
+
+    var card = document.createElement('div');
+    card.id = '__devtool-share-card';
+    card.style.cssText = STYLES.shareCard;
+
+    var title = document.createElement('div');
+    title.style.cssText = 'font-weight:600;margin-bottom:8px';
+    title.textContent = 'Open on another device';
+    card.appendChild(title);
+
+    var img = document.createElement('img');
+    img.alt = share.urls[0];
+    img.style.cssText = 'width:200px;height:200px;image-rendering:pixelated';
+    img.src = share.qr_svg
+      ? 'data:image/svg+xml;utf8,' + encodeURIComponent(share.qr_svg)
+      : '/__devtool_share.png';
+    card.appendChild(img);
+
+    share.urls.forEach(function(url) {
+      var link = document.createElement('div');
+      link.style.cssText = 'font-family:ui-monospace,monospace;margin-top:6px;word-break:break-all';
+      link.textContent = url;
+      card.appendChild(link);
+    });
+
+    if (share.ca_url) {
+      var ca = document.createElement('div');
+      ca.style.cssText = 'margin-top:8px;color:' + TOKENS.colors.textMuted;
+      ca.textContent = 'Trust HTTPS by installing the CA from ' + share.ca_url;
+      card.appendChild(ca);
+    }
+
+    var close = document.createElement('button');
+    close.textContent = 'Close';
+    close.style.cssText = 'margin-top:12px;padding:6px 14px;border-radius:' + TOKENS.radius.sm +
+      ';border:1px solid ' + TOKENS.colors.border + ';background:transparent;cursor:pointer';
+    close.onclick = hideShareCard;
+    card.appendChild(close);
+
+    state.shareCard = card;
+    state.container.appendChild(card);
+  }
+
+  function hideShareCard() {
+    if (state.shareCard && state.shareCard.parentNode) {
+      state.shareCard.parentNode.removeChild(state.shareCard);
+    }
+    state.shareCard = null;
+  }
+
+  // Fetch the active LAN share from the proxy and show it
+  function showShare() {
+    return fetch('/__devtool_share', { cache: 'no-store' })
+      .then(function(res) {
+        if (!res.ok) throw new Error('Proxy is not shared on the LAN');
+        return res.json();
+      })
+      .then(function(share) {
+        showShareCard(share);
+        return share;
+      });
+  }
+
   // Hide output preview
   function hideOutputPreview() {
     if (!state.outputPreview) return;
@@ -1920,6 +2007,8 @@
       if (payload.lines && Array.isArray(payload.lines)) {
         showOutputPreview(payload.lines);
       }
+    } else if (message.type === 'share') {
+      showShareCard(message.payload || message);
     }
   }
 
@@ -1974,11 +2063,13 @@
     state.panel = null;
   }
 
-  // Init on ready
-  if (document.readyState === 'loading') {
-    document.addEventListener('DOMContentLoaded', init);
-  } else {
-    init();
+  // Init on ready, unless switched off in the proxy configuration
+  if (!core || typeof core.isEnabled !== 'function' || core.isEnabled('indicator')) {
+    if (document.readyState === 'loading') {
+      document.addEventListener('DOMContentLoaded', init);
+    } else {
+      init();
+    }
   }
 
   // Export
@@ -1990,6 +2081,8 @@
     destroy: destroy,
     togglePanel: togglePanel,
     setActivityState: setActivityState,
+    showShare: showShare,
+    hideShare: hideShareCard,
     state: state
   };
 })();
@@ -540,9 +540,11 @@
       reportError('setInterval_failed', e);
     }
 
-    // Initialize
+    // Initialize, unless switched off in the proxy configuration
     try {
-      attachListeners();
+      if (typeof core.isEnabled !== 'function' || core.isEnabled('interactions')) {
+        attachListeners();
+      }
     } catch (e) {
       reportError('initialization_failed', e);
     }
@@ -538,9 +538,13 @@
       }
     }
 
+    // Tracking can be switched off in the proxy configuration; the API
+    // below then answers with empty history
+    var trackingEnabled = typeof core.isEnabled !== 'function' || core.isEnabled('mutations');
+
     // Initialize when DOM is ready
     try {
-      if (document.body) {
+      if (trackingEnabled && document.body) {
         startObserver();
 
         // Start max rate tracking after 3 seconds (let initial render settle)
@@ -551,7 +555,7 @@
             reportError('delayed_max_tracking_start_failed', e);
           }
         }, 3000);
-      } else {
+      } else if (trackingEnabled) {
         if (typeof document.addEventListener === 'function') {
           document.addEventListener('DOMContentLoaded', function() {
             try {
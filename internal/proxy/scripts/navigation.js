@@ -0,0 +1,287 @@
+// Navigation tracking for DevTool
+// Reports page loads (including pages served by a service worker or the
+// back/forward cache), client-side route changes of single-page apps and
+// requests answered without reaching the proxy, so the proxy can attribute
+// errors, interactions and performance to the route they happened on.
+
+(function() {
+  'use strict';
+
+  var core = window.__devtool_core;
+
+  var navConfig = {
+    flushInterval: 1000,   // How often queued messages are sent
+    maxQueued: 50,         // Messages kept while disconnected
+    settleQuiet: 300,      // DOM quiet time that ends a route change
+    settleTimeout: 10000,  // Give up waiting for a route to settle
+    maxFetchBatch: 50
+  };
+
+  var navQueue = [];
+  var navFetches = [];
+  var navRouteKey = '';
+  var navRouteUrl = '';
+  var navSettle = null;
+
+  function navReportError(context, error) {
+    if (core && typeof core.reportError === 'function') {
+      core.reportError('[Navigation] ' + context, error);
+    }
+  }
+
+  function navHref() {
+    try {
+      return window.location.href || '';
+    } catch (e) {
+      return '';
+    }
+  }
+
+  // The part of a URL that identifies a route: path and query, plus the
+  // fragment for hash routers ("#/users", "#!/users")
+  function navKey(href) {
+    try {
+      var u = new URL(href, navHref());
+      var hash = u.hash.indexOf('#/') === 0 || u.hash.indexOf('#!') === 0 ? u.hash : '';
+      return u.pathname + u.search + hash;
+    } catch (e) {
+      return href;
+    }
+  }
+
+  // Send in order; keep messages queued while the WebSocket is down
+  function navSend(type, data) {
+    navQueue.push({ type: type, data: data });
+    if (navQueue.length > navConfig.maxQueued) navQueue.shift();
+    navFlush();
+  }
+
+  function navFlush() {
+    if (!core || typeof core.isConnected !== 'function' || !core.isConnected()) return;
+    while (navQueue.length) {
+      var msg = navQueue[0];
+      if (!core.send(msg.type, msg.data)) return;
+      navQueue.shift();
+    }
+  }
+
+  function controlledByServiceWorker() {
+    try {
+      return !!(navigator.serviceWorker && navigator.serviceWorker.controller);
+    } catch (e) {
+      return false;
+    }
+  }
+
+  // Where the document came from: network, service_worker or cache.
+  // A response without transferred bytes never reached the proxy.
+  function loadSource() {
+    try {
+      var entries = performance.getEntriesByType ? performance.getEntriesByType('navigation') : [];
+      var entry = entries && entries[0];
+      if (!entry || typeof entry.transferSize !== 'number' || entry.transferSize > 0) return 'network';
+      if (entry.workerStart > 0 && controlledByServiceWorker()) return 'service_worker';
+      if (entry.decodedBodySize > 0 || entry.type === 'back_forward') return 'cache';
+    } catch (e) {
+      navReportError('load_source_failed', e);
+    }
+    return 'network';
+  }
+
+  function reportLoad(source) {
+    var href = navHref();
+    navRouteKey = navKey(href);
+    navRouteUrl = href;
+    navSend('navigation', {
+      kind: 'load',
+      to: href,
+      title: document.title || '',
+      source: source,
+      service_worker: controlledByServiceWorker(),
+      timestamp: Date.now()
+    });
+  }
+
+  // Report a route change if the route is different from the current one
+  function reportRouteChange(kind) {
+    try {
+      var href = navHref();
+      var key = navKey(href);
+      if (key === navRouteKey) return;
+
+      navFlushFetches();
+      var from = navRouteUrl;
+      navRouteKey = key;
+      navRouteUrl = href;
+
+      // Let other modules (vitals) end their per-route measurements
+      try {
+        window.dispatchEvent(new CustomEvent('__devtool:route', { detail: { kind: kind, from: from, to: href } }));
+      } catch (e) {
+        navReportError('route_event_failed', e);
+      }
+
+      navSend('navigation', {
+        kind: kind,
+        from: from,
+        to: href,
+        title: document.title || '',
+        timestamp: Date.now()
+      });
+
+      if (kind !== 'replace') {
+        watchSettle(href);
+      }
+    } catch (e) {
+      navReportError('route_change_failed', e);
+    }
+  }
+
+  // Measure how long a client-side route takes to render: until the DOM has
+  // been quiet for settleQuiet ms
+  function watchSettle(href) {
+    if (navSettle) navSettle.cancel();
+    if (typeof MutationObserver !== 'function' || !window.performance) return;
+
+    var start = performance.now();
+    var last = start;
+    var quietTimer = null;
+    var giveUp = null;
+    var observer = new MutationObserver(function() {
+      last = performance.now();
+      schedule();
+    });
+
+    function schedule() {
+      clearTimeout(quietTimer);
+      quietTimer = setTimeout(finish, navConfig.settleQuiet);
+    }
+
+    function cancel() {
+      clearTimeout(quietTimer);
+      clearTimeout(giveUp);
+      observer.disconnect();
+      if (navSettle && navSettle.cancel === cancel) navSettle = null;
+    }
+
+    function finish() {
+      cancel();
+      var metrics = {
+        soft_navigation: true,
+        settle_ms: Math.round(last - start),
+        title: document.title || '',
+        timestamp: Date.now()
+      };
+      try {
+        var resources = performance.getEntriesByType('resource');
+        var list = [];
+        for (var i = 0; i < resources.length && list.length < 50; i++) {
+          if (resources[i].startTime >= start) {
+            list.push({
+              name: resources[i].name || '',
+              duration: Math.round(resources[i].duration || 0),
+              size: resources[i].transferSize || 0
+            });
+          }
+        }
+        if (list.length) metrics.resources = list;
+      } catch (e) {
+        navReportError('route_resources_failed', e);
+      }
+      // The route may have changed while sending was pending
+      if (navKey(navHref()) === navKey(href)) {
+        navSend('performance', metrics);
+      }
+    }
+
+    try {
+      observer.observe(document.documentElement || document, { childList: true, subtree: true, attributes: true, characterData: true });
+    } catch (e) {
+      navReportError('settle_observe_failed', e);
+      return;
+    }
+    schedule();
+    giveUp = setTimeout(finish, navConfig.settleTimeout);
+    navSettle = { cancel: cancel };
+  }
+
+  // Wrap history methods; routers call them instead of navigating
+  function wrapHistory(method, kind) {
+    try {
+      var original = history[method];
+      if (typeof original !== 'function') return;
+      history[method] = function() {
+        var result = original.apply(this, arguments);
+        reportRouteChange(kind);
+        return result;
+      };
+    } catch (e) {
+      navReportError('wrap_' + method + '_failed', e);
+    }
+  }
+
+  // Same-origin requests answered by a service worker or the browser cache
+  // never reach the proxy; report them from resource timing
+  function watchCachedFetches() {
+    if (typeof PerformanceObserver !== 'function') return;
+    var origin = window.location.origin;
+    try {
+      var observer = new PerformanceObserver(function(list) {
+        var entries = list.getEntries();
+        for (var i = 0; i < entries.length; i++) {
+          var e = entries[i];
+          if (e.name.indexOf(origin) !== 0 || e.name.indexOf('/__devtool') !== -1) continue;
+          if (typeof e.transferSize !== 'number' || e.transferSize > 0) continue;
+          if (!(e.decodedBodySize > 0) && !(e.workerStart > 0)) continue;
+          navFetches.push({
+            url: e.name,
+            initiator: e.initiatorType || '',
+            source: e.workerStart > 0 ? 'service_worker' : 'cache',
+            duration: Math.round(e.duration || 0),
+            size: e.decodedBodySize || 0
+          });
+          if (navFetches.length > navConfig.maxFetchBatch * 4) navFetches.shift();
+        }
+      });
+      observer.observe({ type: 'resource', buffered: true });
+    } catch (e) {
+      navReportError('resource_observer_failed', e);
+    }
+  }
+
+  // Send cached fetches for the route they were made on
+  function navFlushFetches() {
+    while (navFetches.length) {
+      navSend('cached_fetches', {
+        page: navRouteUrl,
+        events: navFetches.splice(0, navConfig.maxFetchBatch)
+      });
+    }
+  }
+
+  if (core && typeof core.send === 'function') {
+    try {
+      reportLoad(loadSource());
+
+      wrapHistory('pushState', 'push');
+      wrapHistory('replaceState', 'replace');
+      window.addEventListener('popstate', function() { reportRouteChange('pop'); });
+      window.addEventListener('hashchange', function() { reportRouteChange('hash'); });
+      window.addEventListener('pageshow', function(event) {
+        if (event.persisted) reportLoad('cache');
+      });
+
+      watchCachedFetches();
+      setInterval(function() {
+        try {
+          navFlushFetches();
+          navFlush();
+        } catch (e) {
+          navReportError('flush_failed', e);
+        }
+      }, navConfig.flushInterval);
+    } catch (e) {
+      navReportError('initialization_failed', e);
+    }
+  }
+})();
@@ -389,6 +389,13 @@
         message: payload.message,
         duration: payload.duration
       });
+    } else if (message.type === 'auth_error' && message.payload) {
+      show({
+        type: 'warning',
+        title: 'DevTool disconnected',
+        message: message.payload.message,
+        duration: 15000
+      });
     }
   }
 
@@ -0,0 +1,296 @@
+// Web Vitals tracking for DevTool
+// Measures LCP, CLS, INP, FCP, TTFB and long tasks with PerformanceObserver
+// and reports them with the selector of the element responsible, so the
+// proxy can rate them and attribute them to the route they were measured on.
+
+(function() {
+  'use strict';
+
+  var core = window.__devtool_core;
+  var vitalsUtils = window.__devtool_utils;
+
+  var vitalsConfig = {
+    reportDelay: 1000,   // Debounce for metrics that change while loading
+    clsGap: 1000,        // Layout shifts further apart start a new session window
+    clsWindow: 5000,     // Maximum length of a session window
+    maxInteractions: 10, // Slowest interactions kept for INP
+    maxLongTasks: 200    // Long tasks reported per route
+  };
+
+  var vitalsRouteUrl = '';
+  var vitalsReported = {};  // Last reported value by metric name
+  var vitalsPending = {};   // Metrics waiting for the debounce
+  var vitalsTimer = null;
+  var vitalsLongTasks = 0;
+
+  // CLS: largest session window of layout shifts
+  var vitalsCLS = { value: 0, element: '', windowValue: 0, windowStart: 0, windowLast: 0, windowElement: '' };
+  // INP: slowest interactions, one entry per interactionId
+  var vitalsInteractions = [];
+  var vitalsInteractionCount = 0;
+
+  function vitalsReportError(context, error) {
+    if (core && typeof core.reportError === 'function') {
+      core.reportError('[Vitals] ' + context, error);
+    }
+  }
+
+  function vitalsHref() {
+    try {
+      return window.location.href || '';
+    } catch (e) {
+      return '';
+    }
+  }
+
+  function vitalsSelector(node) {
+    try {
+      if (!node || node.nodeType !== 1) return '';
+      if (vitalsUtils && typeof vitalsUtils.generateSelector === 'function') {
+        return vitalsUtils.generateSelector(node) || '';
+      }
+      return node.tagName.toLowerCase() + (node.id ? '#' + node.id : '');
+    } catch (e) {
+      return '';
+    }
+  }
+
+  function vitalsSend(name, value, element, details) {
+    if (!core || typeof core.send !== 'function') return false;
+    return core.send('web_vital', {
+      name: name,
+      value: value,
+      element: element || '',
+      details: details || {},
+      url: vitalsRouteUrl,
+      timestamp: Date.now()
+    });
+  }
+
+  // Queue a metric; it is sent after reportDelay unless it changes again
+  function vitalsUpdate(name, value, element, details) {
+    vitalsPending[name] = { value: value, element: element, details: details };
+    if (!vitalsTimer) {
+      vitalsTimer = setTimeout(vitalsFlush, vitalsConfig.reportDelay);
+    }
+  }
+
+  function vitalsFlush() {
+    clearTimeout(vitalsTimer);
+    vitalsTimer = null;
+    for (var name in vitalsPending) {
+      if (!Object.prototype.hasOwnProperty.call(vitalsPending, name)) continue;
+      var m = vitalsPending[name];
+      if (vitalsReported[name] === m.value) {
+        delete vitalsPending[name];
+        continue;
+      }
+      if (!vitalsSend(name, m.value, m.element, m.details)) {
+        // Keep it for the next flush while disconnected
+        if (!vitalsTimer) vitalsTimer = setTimeout(vitalsFlush, vitalsConfig.reportDelay);
+        continue;
+      }
+      vitalsReported[name] = m.value;
+      delete vitalsPending[name];
+    }
+  }
+
+  function vitalsObserve(type, callback, options) {
+    try {
+      if (typeof PerformanceObserver !== 'function') return null;
+      var supported = PerformanceObserver.supportedEntryTypes;
+      if (supported && supported.indexOf(type) === -1) return null;
+      var observer = new PerformanceObserver(function(list) {
+        try {
+          callback(list.getEntries());
+        } catch (e) {
+          vitalsReportError(type + '_observer_failed', e);
+        }
+      });
+      var opts = { type: type, buffered: true };
+      for (var key in options || {}) opts[key] = options[key];
+      observer.observe(opts);
+      return observer;
+    } catch (e) {
+      vitalsReportError(type + '_observe_failed', e);
+      return null;
+    }
+  }
+
+  function observeLCP() {
+    var observer = vitalsObserve('largest-contentful-paint', function(entries) {
+      var entry = entries[entries.length - 1];
+      if (!entry) return;
+      var details = { size: entry.size || 0 };
+      if (entry.url) details.resource = entry.url;
+      vitalsUpdate('LCP', Math.round(entry.startTime), vitalsSelector(entry.element), details);
+    });
+    if (!observer) return;
+
+    // The largest paint is final once the user interacts
+    var stop = function() {
+      try {
+        observer.takeRecords();
+        observer.disconnect();
+      } catch (e) {
+        // Ignore
+      }
+      window.removeEventListener('keydown', stop, true);
+      window.removeEventListener('pointerdown', stop, true);
+    };
+    window.addEventListener('keydown', stop, true);
+    window.addEventListener('pointerdown', stop, true);
+  }
+
+  function observeCLS() {
+    vitalsObserve('layout-shift', function(entries) {
+      for (var i = 0; i < entries.length; i++) {
+        var entry = entries[i];
+        if (entry.hadRecentInput) continue;
+
+        if (vitalsCLS.windowValue > 0 &&
+            entry.startTime - vitalsCLS.windowLast < vitalsConfig.clsGap &&
+            entry.startTime - vitalsCLS.windowStart < vitalsConfig.clsWindow) {
+          vitalsCLS.windowValue += entry.value;
+        } else {
+          vitalsCLS.windowValue = entry.value;
+          vitalsCLS.windowStart = entry.startTime;
+          vitalsCLS.windowElement = '';
+        }
+        vitalsCLS.windowLast = entry.startTime;
+
+        // Attribute the window to the node that moved the most
+        var sources = entry.sources || [];
+        var largest = null;
+        for (var j = 0; j < sources.length; j++) {
+          var rect = sources[j].currentRect;
+          var area = rect ? rect.width * rect.height : 0;
+          if (!largest || area > largest.area) largest = { node: sources[j].node, area: area };
+        }
+        if (largest && !vitalsCLS.windowElement) vitalsCLS.windowElement = vitalsSelector(largest.node);
+
+        if (vitalsCLS.windowValue > vitalsCLS.value) {
+          vitalsCLS.value = vitalsCLS.windowValue;
+          vitalsCLS.element = vitalsCLS.windowElement;
+          vitalsUpdate('CLS', Math.round(vitalsCLS.value * 10000) / 10000, vitalsCLS.element, {});
+        }
+      }
+    });
+  }
+
+  // INP is the slowest interaction, ignoring one outlier per 50 interactions
+  function observeINP() {
+    vitalsObserve('event', function(entries) {
+      var changed = false;
+      for (var i = 0; i < entries.length; i++) {
+        var entry = entries[i];
+        if (!entry.interactionId) continue;
+
+        var existing = null;
+        for (var j = 0; j < vitalsInteractions.length; j++) {
+          if (vitalsInteractions[j].id === entry.interactionId) {
+            existing = vitalsInteractions[j];
+            break;
+          }
+        }
+        if (existing) {
+          if (entry.duration <= existing.duration) continue;
+          existing.duration = entry.duration;
+          existing.type = entry.name;
+          existing.element = vitalsSelector(entry.target);
+        } else {
+          vitalsInteractionCount++;
+          vitalsInteractions.push({
+            id: entry.interactionId,
+            duration: entry.duration,
+            type: entry.name,
+            element: vitalsSelector(entry.target)
+          });
+        }
+        changed = true;
+      }
+      if (!changed) return;
+
+      vitalsInteractions.sort(function(a, b) { return b.duration - a.duration; });
+      vitalsInteractions.length = Math.min(vitalsInteractions.length, vitalsConfig.maxInteractions);
+      var index = Math.min(vitalsInteractions.length - 1, Math.floor(vitalsInteractionCount / 50));
+      var inp = vitalsInteractions[index];
+      vitalsUpdate('INP', Math.round(inp.duration), inp.element, {
+        event_type: inp.type,
+        interactions: vitalsInteractionCount
+      });
+    }, { durationThreshold: 40 });
+  }
+
+  function observePaintAndTTFB() {
+    vitalsObserve('paint', function(entries) {
+      for (var i = 0; i < entries.length; i++) {
+        if (entries[i].name === 'first-contentful-paint') {
+          vitalsUpdate('FCP', Math.round(entries[i].startTime), '', {});
+        }
+      }
+    });
+
+    try {
+      var nav = performance.getEntriesByType ? performance.getEntriesByType('navigation')[0] : null;
+      if (nav && nav.responseStart > 0) {
+        var ttfb = Math.max(nav.responseStart - (nav.activationStart || 0), 0);
+        vitalsUpdate('TTFB', Math.round(ttfb), '', { type: nav.type || '' });
+      }
+    } catch (e) {
+      vitalsReportError('ttfb_failed', e);
+    }
+  }
+
+  function observeLongTasks() {
+    vitalsObserve('longtask', function(entries) {
+      for (var i = 0; i < entries.length; i++) {
+        if (vitalsLongTasks >= vitalsConfig.maxLongTasks) return;
+        vitalsLongTasks++;
+        var entry = entries[i];
+        var attribution = entry.attribution && entry.attribution[0];
+        var details = { start: Math.round(entry.startTime) };
+        if (attribution && attribution.containerSrc) details.container = attribution.containerSrc;
+        vitalsSend('long_task', Math.round(entry.duration), '', details);
+      }
+    });
+  }
+
+  // A client-side route change ends the CLS and INP measurement of the
+  // previous route
+  function vitalsRouteChanged() {
+    try {
+      vitalsFlush();
+      vitalsRouteUrl = vitalsHref();
+      vitalsReported = {};
+      vitalsLongTasks = 0;
+      vitalsCLS = { value: 0, element: '', windowValue: 0, windowStart: 0, windowLast: 0, windowElement: '' };
+      vitalsInteractions = [];
+      vitalsInteractionCount = 0;
+    } catch (e) {
+      vitalsReportError('route_change_failed', e);
+    }
+  }
+
+  var vitalsEnabled = !core || typeof core.isEnabled !== 'function' || core.isEnabled('vitals');
+
+  if (vitalsEnabled && core && typeof core.send === 'function' && window.performance) {
+    try {
+      vitalsRouteUrl = vitalsHref();
+
+      observeLCP();
+      observeCLS();
+      observeINP();
+      observePaintAndTTFB();
+      observeLongTasks();
+
+      window.addEventListener('__devtool:route', vitalsRouteChanged);
+      document.addEventListener('visibilitychange', function() {
+        if (document.visibilityState === 'hidden') vitalsFlush();
+      });
+      window.addEventListener('pagehide', vitalsFlush);
+    } catch (e) {
+      vitalsReportError('initialization_failed', e);
+    }
+  }
+})();
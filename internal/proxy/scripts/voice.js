@@ -1,7 +1,8 @@
 // Voice Transcription Module for DevTool
-// Uses proxy-side Deepgram (primary) and Web Speech API (fallback)
-// Audio is streamed to proxy via WebSocket, proxy forwards to Deepgram
-// API key stays server-side, never exposed to browser
+// Uses proxy-side speech-to-text (primary) and Web Speech API (fallback)
+// Audio is streamed to proxy via WebSocket, proxy forwards to the configured
+// provider (Deepgram, an OpenAI-compatible endpoint or whisper.cpp)
+// API keys stay server-side, never exposed to browser
 
 (function() {
   'use strict';
@@ -21,7 +22,7 @@
     onError: null,         // Callback for errors
     onStateChange: null,   // Callback for listening state changes
 
-    // Proxy-based voice (Deepgram via proxy)
+    // Proxy-based voice (speech provider via proxy)
     proxy: {
       audioStream: null,
       audioContext: null,
@@ -39,7 +40,7 @@
       language: 'en-US',
       interimResults: true,
       continuous: false,
-      model: 'nova-3'
+      model: ''  // Provider default when empty
     }
   };
 
@@ -48,7 +49,7 @@
   // ============================================================================
 
   function detectProvider() {
-    // Primary: Use proxy WebSocket if core is available (Deepgram server-side)
+    // Primary: Use proxy WebSocket if core is available (speech provider server-side)
     if (core && core.isConnected && core.isConnected()) {
       return 'proxy';
     }
@@ -97,7 +98,7 @@
   }
 
   // ============================================================================
-  // PROXY-BASED VOICE (Deepgram via proxy WebSocket)
+  // PROXY-BASED VOICE (speech provider via proxy WebSocket)
   // ============================================================================
 
   function initProxyVoice() {
@@ -32,24 +32,32 @@ import (
 
 // ProxyServer is a reverse proxy that logs traffic and injects instrumentation.
 type ProxyServer struct {
-	ID          string
-	TargetURL   *url.URL
-	ListenAddr  string
-	Path        string
-	BindAddress string // Bind address used (127.0.0.1 or 0.0.0.0)
-	PublicURL   string // Optional public URL for tunnel services
-	logger      *TrafficLogger
-	pageTracker *PageTracker
-	httpServer  *http.Server
-	wsUpgrader  websocket.Upgrader
-	proxy       *httputil.ReverseProxy
-	running     atomic.Bool
-	startTime   time.Time
-	requestSeq  atomic.Int64
-	mu          sync.Mutex
-	cancelFunc  context.CancelFunc
-	wsConns     sync.Map     // Active WebSocket connections
-	lastError   atomic.Value // stores last error (string) if server crashed
+	ID           string
+	TargetURL    *url.URL
+	ListenAddr   string
+	HTTPSAddr    string // Address of the HTTPS listener ("" when HTTPS is off)
+	Path         string
+	BindAddress  string // Bind address used (127.0.0.1 or 0.0.0.0)
+	PublicURL    string // Optional public URL for tunnel services
+	publicURLMu  sync.RWMutex
+	logger       *TrafficLogger
+	pageTracker  *PageTracker
+	httpServer   *http.Server
+	wsUpgrader   websocket.Upgrader
+	wsToken      string // Secret the injected script must present on /__devtool_metrics
+	proxy        *httputil.ReverseProxy
+	routes       atomic.Pointer[routeTable] // Path-based routes to other upstreams
+	defaultRoute *upstreamRoute             // The target, for requests that match no route
+	running      atomic.Bool
+	startTime    time.Time
+	requestSeq   atomic.Int64
+	mu           sync.Mutex
+	cancelFunc   context.CancelFunc
+	wsConns      sync.Map     // Active WebSocket connections
+	lastError    atomic.Value // stores last error (string) if server crashed
+
+	// Last rejection toast (unix nanos), to throttle toasts for hostile pages
+	lastAuthToast atomic.Int64
 
 	// Ready signal - closed when server is ready to accept connections
 	ready     chan struct{}
@@ -70,15 +78,50 @@ type ProxyServer struct {
 
 	// Voice sessions for speech-to-text (map[connID]*VoiceSession)
 	voiceSessions sync.Map
+	speech        *SpeechConfig // nil: read from the environment per session
 
 	// Tunnel manager for ngrok/cloudflared integration
 	tunnel *TunnelManager
 
+	// HTTPS listener with a certificate from the local agnt CA
+	https       bool
+	httpsPort   int
+	caDir       string
+	caCertPath  string
+	httpsServer *http.Server
+
+	// Captured bodies too large for the log entry preview
+	bodies *bodyStore
+
+	// Source maps for resolving frontend stacks, fetched from the upstreams
+	// without logging or chaos
+	sourceMaps     *SourceMapResolver
+	stackResolves  chan struct{} // Limits concurrent stack resolutions
+	upstreamClient *http.Client
+
+	// LAN share listener (nil when not shared)
+	share   *shareState
+	shareMu sync.Mutex
+
 	// Chaos engine for failure injection
 	chaosEngine *ChaosEngine
 
+	// Counters for the daemon's metrics endpoint
+	metrics *requestMetrics
+
+	// Instrumentation injected into HTML pages
+	inject InjectConfig
+
 	// Session client factory for handling session API requests from browser
 	sessionClientFactory SessionClientFactory
+
+	// Agent streaming for live responses in the browser
+	agentStreamer  AgentStreamer
+	agentStreams   sync.Map // map[streamID]*agentStream
+	agentStreamSeq atomic.Int64
+
+	// Reports lifecycle and log events to the manager (nil: not managed)
+	onEvent func(Event)
 }
 
 // ProxyConfig holds configuration for creating a proxy server.
@@ -93,6 +136,18 @@ type ProxyConfig struct {
 	PublicURL   string // Optional public URL for tunnel services (e.g., "https://abc123.trycloudflare.com")
 	VerifyTLS   bool   // Verify TLS certificates (default: false, accepts self-signed/expired certs for dev)
 	Tunnel      *protocol.TunnelConfig
+	Routes      []protocol.ProxyRoute       // Routes to other upstreams by path, first match wins
+	HTTPS       bool                        // Also serve HTTPS with a certificate from the local agnt CA
+	HTTPSPort   int                         // HTTPS listen port (negative: stable default, 0: auto-assign)
+	CADir       string                      // CA directory (default: certs.DefaultDir())
+	BodyCapture *protocol.BodyCaptureConfig // Body capture limits (nil: defaults)
+	Speech      *SpeechConfig               // Speech-to-text backend for voice input (nil: from the environment)
+	Chaos       *ChaosConfig                // Chaos rules active from the start (nil: none)
+	Inject      *InjectConfig               // What is injected into HTML pages (nil: everything)
+
+	// SourceMapDirs are build directories (relative to Path) searched for
+	// source maps the served scripts don't reference (nil: DefaultSourceMapDirs)
+	SourceMapDirs []string
 }
 
 // DefaultPortForURL computes a stable default port based on the target URL.
@@ -141,6 +196,12 @@ func NewProxyServer(config ProxyConfig) (*ProxyServer, error) {
 		}
 	}
 
+	// HTTPS port follows the same rules as the HTTP port, offset so both
+	// defaults can be bound at once
+	if config.HTTPS && config.HTTPSPort < 0 {
+		config.HTTPSPort = DefaultPortForURL("https:" + config.TargetURL)
+	}
+
 	logger := NewTrafficLogger(config.MaxLogSize)
 	ps := &ProxyServer{
 		ID:              config.ID,
@@ -158,9 +219,16 @@ func NewProxyServer(config ProxyConfig) (*ProxyServer, error) {
 		restarts:        make([]time.Time, 0, 5),
 		overlayNotifier: NewOverlayNotifier(),
 		chaosEngine:     NewChaosEngine(logger),
+		metrics:         newRequestMetrics(),
+		wsToken:         newWSToken(),
+		https:           config.HTTPS,
+		httpsPort:       config.HTTPSPort,
+		caDir:           config.CADir,
+		bodies:          newBodyStore(config.ID, config.BodyCapture),
+		speech:          config.Speech,
 		wsUpgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for development
+				return true // Checked by authorizeWebSocket before upgrading
 			},
 		},
 	}
@@ -217,6 +285,10 @@ func NewProxyServer(config ProxyConfig) (*ProxyServer, error) {
 		}
 	}
 
+	ps.upstreamClient = &http.Client{Transport: baseTransport, Timeout: sourceMapTimeout}
+	ps.sourceMaps = NewSourceMapResolver(ps.fetchUpstream, config.Path, config.SourceMapDirs)
+	ps.stackResolves = make(chan struct{}, maxStackResolves)
+
 	// Wrap the transport with chaos transport for failure injection
 	ps.proxy.Transport = NewChaosTransport(baseTransport, ps.chaosEngine)
 
@@ -233,35 +305,90 @@ func NewProxyServer(config ProxyConfig) (*ProxyServer, error) {
 		// Ensure Host header matches target (critical for WordPress and other apps)
 		req.Host = targetURL.Host
 
-		// Add/update X-Forwarded headers for applications that need them
-		// These help apps know the original request came through a proxy
-		if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
-			if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
-				req.Header.Set("X-Forwarded-For", prior+", "+clientIP)
-			} else {
-				req.Header.Set("X-Forwarded-For", clientIP)
-			}
-		}
-
-		// Set X-Forwarded-Host to the proxy's host (original request host)
-		// This tells backend apps the host the client originally connected to
-		req.Header.Set("X-Forwarded-Host", originalHost)
-
-		// Set protocol - proxy is HTTP
-		req.Header.Set("X-Forwarded-Proto", "http")
+		ps.setForwardedHeaders(req, originalHost)
 	}
 
 	ps.proxy.ErrorHandler = ps.errorHandler
 	ps.proxy.ModifyResponse = ps.modifyResponse
 
+	logger.onLog = ps.logged
+
+	ps.defaultRoute = newDefaultRoute(targetURL, ps.proxy)
+	if err := ps.SetRoutes(config.Routes); err != nil {
+		return nil, fmt.Errorf("invalid routes: %w", err)
+	}
+
+	if config.Chaos != nil {
+		if err := ps.chaosEngine.SetConfig(config.Chaos); err != nil {
+			return nil, fmt.Errorf("invalid chaos config: %w", err)
+		}
+	}
+	if config.Inject != nil {
+		if err := config.Inject.validate(); err != nil {
+			return nil, fmt.Errorf("invalid inject config: %w", err)
+		}
+		ps.inject = *config.Inject
+	}
+
 	// Initialize tunnel manager if configured
 	if config.Tunnel != nil && config.Tunnel.Provider != "" {
 		ps.tunnel = NewTunnelManager(config.Tunnel, config.ListenPort)
+		// Tunnels get a new URL when they restart, so keep rewriting in sync
+		ps.tunnel.OnURL(ps.tunnelURL)
 	}
 
 	return ps, nil
 }
 
+// setForwardedHeaders adds X-Forwarded-* headers to an upstream request.
+// originalHost is the Host the client used to reach the proxy.
+func (ps *ProxyServer) setForwardedHeaders(req *http.Request, originalHost string) {
+	// Add/update X-Forwarded headers for applications that need them
+	// These help apps know the original request came through a proxy
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			req.Header.Set("X-Forwarded-For", prior+", "+clientIP)
+		} else {
+			req.Header.Set("X-Forwarded-For", clientIP)
+		}
+	}
+
+	// Set X-Forwarded-Host to the proxy's host (original request host)
+	// This tells backend apps the host the client originally connected to
+	req.Header.Set("X-Forwarded-Host", originalHost)
+
+	// Set protocol - proxy is HTTP unless reached through an HTTPS listener
+	proto := "http"
+	if scheme, _, ok := requestOrigin(req.Context()); ok {
+		proto = scheme
+	}
+	req.Header.Set("X-Forwarded-Proto", proto)
+
+	port := "80"
+	if proto == "https" {
+		port = "443"
+	}
+	if _, p, err := net.SplitHostPort(originalHost); err == nil {
+		port = p
+	}
+	req.Header.Set("X-Forwarded-Port", port)
+
+	// RFC 7239 form for frameworks that only read Forwarded
+	forwarded := "host=\"" + originalHost + "\";proto=" + proto
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		forwarded = "for=\"" + forwardedNode(clientIP) + "\";" + forwarded
+	}
+	req.Header.Set("Forwarded", forwarded)
+}
+
+// forwardedNode formats an IP for the Forwarded header (IPv6 in brackets).
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]"
+	}
+	return ip
+}
+
 // Start begins the proxy server.
 func (ps *ProxyServer) Start(ctx context.Context) error {
 	debug.Log("proxy", "Start: id=%s addr=%s", ps.ID, ps.ListenAddr)
@@ -278,6 +405,8 @@ func (ps *ProxyServer) Start(ctx context.Context) error {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/__devtool_metrics", ps.handleWebSocket)
+	mux.HandleFunc(shareInfoPath, ps.handleShareInfo)
+	mux.HandleFunc(shareQRPath, ps.handleShareQR)
 	mux.HandleFunc("/", ps.handleProxy)
 
 	// Try to bind to requested port first
@@ -311,6 +440,15 @@ func (ps *ProxyServer) Start(ctx context.Context) error {
 		},
 	}
 
+	if ps.https {
+		if err := ps.startHTTPS(ctx, mux); err != nil {
+			debug.Error("proxy", "failed to start HTTPS for %s: %v", ps.ID, err)
+			listener.Close()
+			cancel()
+			return err
+		}
+	}
+
 	ps.startTime = time.Now()
 	ps.running.Store(true)
 
@@ -323,8 +461,13 @@ func (ps *ProxyServer) Start(ctx context.Context) error {
 	// Start server in goroutine using existing listener
 	go ps.runServer(ctx, listener)
 
-	// Start tunnel if configured
+	// Start tunnel if configured, pointed at the port we actually bound
 	if ps.tunnel != nil {
+		if _, port, err := net.SplitHostPort(ps.ListenAddr); err == nil {
+			if n, err := strconv.Atoi(port); err == nil {
+				ps.tunnel.proxyPort = n
+			}
+		}
 		if err := ps.tunnel.Start(ctx); err != nil {
 			// Log but don't fail - proxy can work without tunnel
 			ps.logger.LogError(FrontendError{
@@ -351,6 +494,7 @@ func (ps *ProxyServer) runServer(ctx context.Context, listener net.Listener) {
 		if err != nil {
 			ps.running.Store(false)
 			ps.lastError.Store(err.Error())
+			ps.emit(Event{Type: EventCrashed, Error: err.Error()})
 
 			// Check if auto-restart is enabled
 			if !ps.autoRestart {
@@ -383,6 +527,8 @@ func (ps *ProxyServer) runServer(ctx context.Context, listener net.Listener) {
 				},
 			}
 			ps.running.Store(true)
+			ps.metrics.restarts.Add(1)
+			ps.emit(Event{Type: EventRestarted})
 
 			// Continue loop to restart server
 			continue
@@ -442,10 +588,13 @@ func (ps *ProxyServer) Stop(ctx context.Context) error {
 		return fmt.Errorf("proxy server not running")
 	}
 
-	// Stop tunnel first
+	// Stop tunnel and LAN share first
 	if ps.tunnel != nil {
 		ps.tunnel.Stop()
 	}
+	ps.StopShare()
+	ps.stopHTTPS(ctx)
+	ps.bodies.close()
 
 	if ps.cancelFunc != nil {
 		ps.cancelFunc()
@@ -513,7 +662,26 @@ func (ps *ProxyServer) SetOverlayEndpoint(endpoint string) {
 // This URL is used for URL rewriting when behind a tunnel.
 // Example: "https://abc123.trycloudflare.com"
 func (ps *ProxyServer) SetPublicURL(publicURL string) {
+	ps.publicURLMu.Lock()
 	ps.PublicURL = publicURL
+	ps.publicURLMu.Unlock()
+}
+
+// UpdatePublicURL sets the public URL and notifies connected browsers.
+// Used when a tunnel restarts and comes back with a different URL.
+func (ps *ProxyServer) UpdatePublicURL(publicURL string) {
+	if ps.currentPublicURL() == publicURL {
+		return
+	}
+	ps.SetPublicURL(publicURL)
+	ps.BroadcastPublicURL(publicURL)
+}
+
+// currentPublicURL returns the public URL under the read lock.
+func (ps *ProxyServer) currentPublicURL() string {
+	ps.publicURLMu.RLock()
+	defer ps.publicURLMu.RUnlock()
+	return ps.PublicURL
 }
 
 // SetSessionClientFactory sets the factory for creating session clients.
@@ -533,9 +701,10 @@ func (ps *ProxyServer) Stats() ProxyStats {
 		ID:            ps.ID,
 		TargetURL:     ps.TargetURL.String(),
 		ListenAddr:    ps.ListenAddr,
+		HTTPSURL:      ps.HTTPSURL(),
 		Path:          ps.Path,
 		BindAddress:   ps.BindAddress,
-		PublicURL:     ps.PublicURL,
+		PublicURL:     ps.currentPublicURL(),
 		Running:       ps.running.Load(),
 		Uptime:        time.Since(ps.startTime),
 		TotalRequests: ps.requestSeq.Load(),
@@ -561,6 +730,10 @@ func (ps *ProxyServer) Stats() ProxyStats {
 	ps.restartsMu.Unlock()
 	stats.RestartCount = restartCount
 
+	if share := ps.CurrentShare(); share != nil {
+		stats.ShareURLs = share.URLs
+	}
+
 	return stats
 }
 
@@ -569,6 +742,7 @@ type ProxyStats struct {
 	ID            string        `json:"id"`
 	TargetURL     string        `json:"target_url"`
 	ListenAddr    string        `json:"listen_addr"`
+	HTTPSURL      string        `json:"https_url,omitempty"`    // Local HTTPS URL if HTTPS is on
 	Path          string        `json:"path,omitempty"`         // Working directory where proxy was created
 	BindAddress   string        `json:"bind_address,omitempty"` // Bind address (127.0.0.1 or 0.0.0.0)
 	PublicURL     string        `json:"public_url,omitempty"`   // Public URL for tunnels
@@ -579,6 +753,7 @@ type ProxyStats struct {
 	LastError     string        `json:"last_error,omitempty"` // Set if server crashed
 	RestartCount  int           `json:"restart_count"`        // Number of restarts in current window
 	AutoRestart   bool          `json:"auto_restart"`         // Whether auto-restart is enabled
+	ShareURLs     []string      `json:"share_urls,omitempty"` // LAN URLs if shared on the local network
 }
 
 // handleProxy handles HTTP requests and logs traffic.
@@ -587,6 +762,10 @@ func (ps *ProxyServer) handleProxy(w http.ResponseWriter, r *http.Request) {
 	seq := ps.requestSeq.Add(1)
 	reqID := fmt.Sprintf("req-%d", seq)
 
+	// Pick the upstream; the route travels in the request context
+	route, r := ps.routeRequest(r)
+	upstream := ps.routeLogName(route)
+
 	// Check if this is a WebSocket upgrade request
 	isWebSocket := strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
 		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
@@ -597,13 +776,11 @@ func (ps *ProxyServer) handleProxy(w http.ResponseWriter, r *http.Request) {
 		reqHeaders[k] = strings.Join(v, ", ")
 	}
 
-	var reqBody string
-	if !isWebSocket && r.Body != nil && r.ContentLength > 0 && r.ContentLength < 10*1024 { // Limit to 10KB
-		bodyBytes, err := io.ReadAll(r.Body)
-		if err == nil {
-			reqBody = string(bodyBytes)
-			// Restore body for proxy
-			r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	// Record the request body as the upstream reads it
+	var reqCapture *bodyCapture
+	if !isWebSocket && r.Body != nil && r.Body != http.NoBody {
+		if reqCapture = ps.bodies.capture(reqID + "-request"); reqCapture != nil {
+			r.Body = &captureReader{ReadCloser: r.Body, capture: reqCapture}
 		}
 	}
 
@@ -618,46 +795,77 @@ func (ps *ProxyServer) handleProxy(w http.ResponseWriter, r *http.Request) {
 			RequestHeaders: reqHeaders,
 			StatusCode:     http.StatusSwitchingProtocols,
 			Duration:       0,
+			Upstream:       upstream,
 		})
 
 		// Proxy the WebSocket upgrade directly
-		ps.proxy.ServeHTTP(w, r)
+		route.proxy.ServeHTTP(w, r)
 		return
 	}
 
+	// Parse GraphQL operations up front when chaos rules match by operation
+	if ps.chaosEngine.NeedsGraphQL() {
+		r = peekGraphQL(r)
+	}
+
 	// Check for chaos rules that apply to this request
 	chaosRules := ps.chaosEngine.MatchingRules(r)
 
-	// HTTP error injection - return error without calling backend
-	if errorCode, errorMsg := ps.chaosEngine.GetHTTPError(chaosRules); errorCode != 0 {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// Error injection - return an error without calling the backend
+	errorCode, errorMsg := ps.chaosEngine.GetHTTPError(chaosRules)
+	errorType := "text/plain; charset=utf-8"
+	if errorCode == 0 {
+		if rule := ps.chaosEngine.GetGraphQLError(chaosRules); rule != nil {
+			errorCode = http.StatusOK
+			if len(rule.ErrorCodes) > 0 {
+				errorCode = rule.ErrorCodes[0]
+			}
+			errorType = "application/json"
+			errorMsg = string(graphQLErrorBody(requestGraphQL(r), rule.ErrorMessage, rule.GraphQLErrorCode))
+		}
+	} else if errorMsg == "" {
+		errorMsg = http.StatusText(errorCode)
+	}
+	if errorCode != 0 {
+		w.Header().Set("Content-Type", errorType)
 		w.Header().Set("X-Chaos-Injected", "true")
 		w.WriteHeader(errorCode)
-		if errorMsg == "" {
-			errorMsg = http.StatusText(errorCode)
-		}
 		w.Write([]byte(errorMsg))
 
+		// The backend never read the request body, so drain it for the log
+		if reqCapture != nil {
+			io.Copy(io.Discard, io.LimitReader(r.Body, ps.bodies.maxSize))
+		}
+		reqBody, reqBodyInfo := reqCapture.finish(r.Header)
+		respInfo := &BodyInfo{Size: int64(len(errorMsg)), Kind: bodyKind(errorType, []byte(errorMsg), true), ContentType: errorType}
+
 		// Log the chaos-injected error
 		ps.logger.LogHTTP(HTTPLogEntry{
-			ID:             reqID,
-			Timestamp:      startTime,
-			Method:         r.Method,
-			URL:            r.URL.String(),
-			RequestHeaders: reqHeaders,
-			RequestBody:    reqBody,
-			StatusCode:     errorCode,
-			ResponseBody:   errorMsg,
-			Duration:       time.Since(startTime),
+			ID:               reqID,
+			Timestamp:        startTime,
+			Method:           r.Method,
+			URL:              r.URL.String(),
+			RequestHeaders:   reqHeaders,
+			RequestBody:      reqBody,
+			RequestBodyInfo:  reqBodyInfo,
+			StatusCode:       errorCode,
+			ResponseBody:     errorMsg,
+			ResponseBodyInfo: respInfo,
+			Duration:         time.Since(startTime),
+			Upstream:         upstream,
+			GraphQL:          graphQLForLog(r, reqBody, reqBodyInfo, errorMsg, respInfo),
 		})
 		return
 	}
 
+	// Time the upstream call phase by phase
+	r, timer := withUpstreamTimer(r)
+
 	// Create response recorder to capture response for non-WebSocket requests
 	recorder := &responseRecorder{
 		ResponseWriter: w,
 		statusCode:     http.StatusOK,
-		body:           &bytes.Buffer{},
+		body:           ps.bodies.capture(reqID + "-response"),
 	}
 
 	// Wrap with chaos writers if needed
@@ -687,36 +895,70 @@ func (ps *ProxyServer) handleProxy(w http.ResponseWriter, r *http.Request) {
 		recorder.ResponseWriter = chaosWriter
 	}
 
+	// Streaming responses are logged as soon as headers arrive, then event
+	// by event; the HTTP entry is updated when the stream ends
+	var stream *streamTracker
+	recorder.onHeader = func(statusCode int) {
+		kind := streamKind(recorder.Header())
+		if kind == "" {
+			return
+		}
+		stream = newStreamTracker(recorder.ResponseWriter, r, kind, reqID, startTime, ps.logger, ps.chaosEngine, chaosRules)
+		recorder.stream = stream
+		info := stream.info
+		ps.logger.LogHTTP(HTTPLogEntry{
+			ID:              reqID,
+			Timestamp:       startTime,
+			Method:          r.Method,
+			URL:             r.URL.String(),
+			RequestHeaders:  reqHeaders,
+			StatusCode:      statusCode,
+			ResponseHeaders: headerMap(recorder.Header()),
+			Duration:        info.FirstByte,
+			Upstream:        upstream,
+			Stream:          &info,
+			Timing:          timer.logTiming(recorder.Header()),
+		})
+	}
+
 	// Proxy the request
-	ps.proxy.ServeHTTP(recorder, r)
+	route.proxy.ServeHTTP(recorder, r)
 
 	duration := time.Since(startTime)
 
 	// Capture response
-	respHeaders := make(map[string]string)
-	for k, v := range recorder.Header() {
-		respHeaders[k] = strings.Join(v, ", ")
-	}
+	respHeaders := headerMap(recorder.Header())
 
-	respBody := recorder.body.String()
-	if len(respBody) > 10*1024 { // Truncate large responses
-		respBody = respBody[:10*1024] + "... [truncated]"
-	}
+	reqBody, reqBodyInfo := reqCapture.finish(r.Header)
+	respBody, respBodyInfo := recorder.body.finish(recorder.Header())
 
 	// Log the HTTP transaction
 	httpEntry := HTTPLogEntry{
-		ID:              reqID,
-		Timestamp:       startTime,
-		Method:          r.Method,
-		URL:             r.URL.String(),
-		RequestHeaders:  reqHeaders,
-		RequestBody:     reqBody,
-		StatusCode:      recorder.statusCode,
-		ResponseHeaders: respHeaders,
-		ResponseBody:    respBody,
-		Duration:        duration,
-	}
-	ps.logger.LogHTTP(httpEntry)
+		ID:               reqID,
+		Timestamp:        startTime,
+		Method:           r.Method,
+		URL:              r.URL.String(),
+		RequestHeaders:   reqHeaders,
+		RequestBody:      reqBody,
+		RequestBodyInfo:  reqBodyInfo,
+		StatusCode:       recorder.statusCode,
+		ResponseHeaders:  respHeaders,
+		ResponseBody:     respBody,
+		ResponseBodyInfo: respBodyInfo,
+		Duration:         duration,
+		Upstream:         upstream,
+		GraphQL:          graphQLForLog(r, reqBody, reqBodyInfo, respBody, respBodyInfo),
+		Timing:           timer.logTiming(recorder.Header()),
+	}
+	if stream != nil {
+		info := stream.close()
+		httpEntry.Stream = &info
+		if !ps.logger.UpdateHTTP(httpEntry) {
+			ps.logger.LogHTTP(httpEntry)
+		}
+	} else {
+		ps.logger.LogHTTP(httpEntry)
+	}
 
 	// Track page session
 	ps.pageTracker.TrackHTTPRequest(httpEntry)
@@ -760,19 +1002,14 @@ func (ps *ProxyServer) modifyResponse(resp *http.Response) error {
 	}
 	resp.Body.Close()
 
-	// Extract port from ListenAddr (handles both :port and [::]:port formats)
-	port := 8080
-	if lastColon := strings.LastIndex(ps.ListenAddr, ":"); lastColon != -1 {
-		if p, err := strconv.Atoi(ps.ListenAddr[lastColon+1:]); err == nil {
-			port = p
-		}
-	}
-
 	// Rewrite absolute URLs in HTML content pointing to target back to proxy
-	modifiedBody := ps.rewriteURLsInBody(bodyBytes)
+	scheme, host := ps.responseOrigin(resp)
+	modifiedBody := ps.rewriteURLsInBodyTo(bodyBytes, scheme, host)
 
-	// Inject instrumentation
-	modifiedBody = InjectInstrumentation(modifiedBody, port)
+	// Inject instrumentation, unless switched off for this proxy
+	if !ps.inject.Disabled {
+		modifiedBody = InjectInstrumentationWithToken(modifiedBody, ps.wsToken, ps.inject.Off...)
+	}
 
 	// Update response with uncompressed modified content
 	resp.Body = io.NopCloser(bytes.NewReader(modifiedBody))
@@ -792,7 +1029,8 @@ func (ps *ProxyServer) rewriteLocationHeader(resp *http.Response) {
 		return
 	}
 
-	rewritten := ps.rewriteURL(location)
+	scheme, host := ps.responseOrigin(resp)
+	rewritten := ps.rewriteURLTo(location, scheme, host)
 	if rewritten != location {
 		resp.Header.Set("Location", rewritten)
 	}
@@ -805,7 +1043,8 @@ func (ps *ProxyServer) rewriteSetCookieHeaders(resp *http.Response) {
 		return
 	}
 
-	targetHost := ps.TargetURL.Hostname()
+	targetHost := ps.upstreamURL(resp.Request).Hostname()
+	secure := secureOrigin(ps.responseOrigin(resp))
 
 	for i, cookie := range cookies {
 		// Remove or rewrite Domain attribute if it matches target
@@ -813,8 +1052,10 @@ func (ps *ProxyServer) rewriteSetCookieHeaders(resp *http.Response) {
 		if strings.Contains(strings.ToLower(cookie), "domain=") {
 			// Parse and rebuild cookie without domain restriction
 			// or with proxy domain
-			cookies[i] = ps.rewriteCookieDomain(cookie, targetHost)
+			cookie = ps.rewriteCookieDomain(cookie, targetHost)
 		}
+		// Match Secure/SameSite to the scheme the browser used
+		cookies[i] = rewriteCookieSecure(cookie, secure)
 	}
 
 	resp.Header["Set-Cookie"] = cookies
@@ -849,6 +1090,11 @@ func (ps *ProxyServer) rewriteCookieDomain(cookie string, targetHost string) str
 
 // rewriteURL rewrites a URL from the target server to the proxy server.
 func (ps *ProxyServer) rewriteURL(rawURL string) string {
+	return ps.rewriteURLTo(rawURL, ps.getProxyScheme(), ps.getProxyHost())
+}
+
+// rewriteURLTo rewrites a URL from the target server to the given proxy origin.
+func (ps *ProxyServer) rewriteURLTo(rawURL, proxyScheme, proxyHost string) string {
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return rawURL
@@ -860,31 +1106,45 @@ func (ps *ProxyServer) rewriteURL(rawURL string) string {
 		return rawURL
 	}
 
-	// Check if this URL points to our target
-	targetHost := ps.TargetURL.Host
-	if parsed.Host != targetHost {
+	// Check if this URL points to our target or a route upstream
+	rewrite := false
+	for _, host := range ps.rewriteHosts() {
+		if parsed.Host == host {
+			rewrite = true
+			break
+		}
+	}
+	if !rewrite {
 		// Different host, don't rewrite
 		return rawURL
 	}
 
 	// Rewrite to proxy URL
-	// Extract proxy host from ListenAddr
-	proxyHost := ps.getProxyHost()
-	proxyScheme := ps.getProxyScheme()
-
 	parsed.Scheme = proxyScheme
 	parsed.Host = proxyHost
 
 	return parsed.String()
 }
 
+// responseOrigin returns the scheme and host the client used for the request
+// behind resp. Requests through a LAN share keep their device-facing
+// address; all others use the configured proxy origin.
+func (ps *ProxyServer) responseOrigin(resp *http.Response) (string, string) {
+	if resp != nil && resp.Request != nil {
+		if scheme, host, ok := requestOrigin(resp.Request.Context()); ok {
+			return scheme, host
+		}
+	}
+	return ps.getProxyScheme(), ps.getProxyHost()
+}
+
 // getProxyHost returns the host:port for the proxy server.
 // If a public URL is configured (for tunnels), returns that host.
 // Otherwise returns localhost:port for local development.
 func (ps *ProxyServer) getProxyHost() string {
 	// If a public URL is configured (for tunnels), use its host
-	if ps.PublicURL != "" {
-		if parsed, err := url.Parse(ps.PublicURL); err == nil && parsed.Host != "" {
+	if publicURL := ps.currentPublicURL(); publicURL != "" {
+		if parsed, err := url.Parse(publicURL); err == nil && parsed.Host != "" {
 			return parsed.Host
 		}
 	}
@@ -901,8 +1161,8 @@ func (ps *ProxyServer) getProxyHost() string {
 // getProxyScheme returns the scheme (http/https) for the proxy server.
 // If a public URL is configured with HTTPS (common for tunnels), returns https.
 func (ps *ProxyServer) getProxyScheme() string {
-	if ps.PublicURL != "" {
-		if parsed, err := url.Parse(ps.PublicURL); err == nil && parsed.Scheme != "" {
+	if publicURL := ps.currentPublicURL(); publicURL != "" {
+		if parsed, err := url.Parse(publicURL); err == nil && parsed.Scheme != "" {
 			return parsed.Scheme
 		}
 	}
@@ -911,39 +1171,39 @@ func (ps *ProxyServer) getProxyScheme() string {
 
 // rewriteURLsInBody rewrites absolute URLs in HTML/JS content from target to proxy.
 func (ps *ProxyServer) rewriteURLsInBody(body []byte) []byte {
+	return ps.rewriteURLsInBodyTo(body, ps.getProxyScheme(), ps.getProxyHost())
+}
+
+// rewriteURLsInBodyTo rewrites absolute target URLs in body to the given proxy origin.
+func (ps *ProxyServer) rewriteURLsInBodyTo(body []byte, proxyScheme, proxyHost string) []byte {
 	// Guard against nil TargetURL (can happen in tests with partial setup)
 	if ps.TargetURL == nil {
 		return body
 	}
 
-	targetHost := ps.TargetURL.Host
-	if targetHost == "" {
-		return body
-	}
-
-	proxyHost := ps.getProxyHost()
-	proxyScheme := ps.getProxyScheme()
-
-	// Rewrite common URL patterns pointing to target
+	// Rewrite common URL patterns pointing to target (or a route upstream)
 	// http://target:port -> scheme://proxyhost
 	// https://target:port -> scheme://proxyhost
-
-	// Build replacement patterns
-	targetHTTP := "http://" + targetHost
-	targetHTTPS := "https://" + targetHost
 	proxyURL := proxyScheme + "://" + proxyHost
+	proxyURLEscaped := strings.ReplaceAll(proxyURL, "/", "\\/")
 
-	// Replace URLs (simple byte replacement for performance)
-	result := bytes.ReplaceAll(body, []byte(targetHTTPS), []byte(proxyURL))
-	result = bytes.ReplaceAll(result, []byte(targetHTTP), []byte(proxyURL))
+	result := body
+	for _, targetHost := range ps.rewriteHosts() {
+		// Build replacement patterns
+		targetHTTP := "http://" + targetHost
+		targetHTTPS := "https://" + targetHost
 
-	// Also handle URLs with escaped slashes (common in JSON)
-	targetHTTPEscaped := strings.ReplaceAll(targetHTTP, "/", "\\/")
-	targetHTTPSEscaped := strings.ReplaceAll(targetHTTPS, "/", "\\/")
-	proxyURLEscaped := strings.ReplaceAll(proxyURL, "/", "\\/")
+		// Replace URLs (simple byte replacement for performance)
+		result = bytes.ReplaceAll(result, []byte(targetHTTPS), []byte(proxyURL))
+		result = bytes.ReplaceAll(result, []byte(targetHTTP), []byte(proxyURL))
 
-	result = bytes.ReplaceAll(result, []byte(targetHTTPSEscaped), []byte(proxyURLEscaped))
-	result = bytes.ReplaceAll(result, []byte(targetHTTPEscaped), []byte(proxyURLEscaped))
+		// Also handle URLs with escaped slashes (common in JSON)
+		targetHTTPEscaped := strings.ReplaceAll(targetHTTP, "/", "\\/")
+		targetHTTPSEscaped := strings.ReplaceAll(targetHTTPS, "/", "\\/")
+
+		result = bytes.ReplaceAll(result, []byte(targetHTTPSEscaped), []byte(proxyURLEscaped))
+		result = bytes.ReplaceAll(result, []byte(targetHTTPEscaped), []byte(proxyURLEscaped))
+	}
 
 	return result
 }
@@ -966,22 +1226,26 @@ func (ps *ProxyServer) errorHandler(w http.ResponseWriter, r *http.Request, err
 		URL:        r.URL.String(),
 		StatusCode: http.StatusBadGateway,
 		Error:      errStr,
+		Upstream:   ps.routeLogName(requestRoute(r)),
 	})
 
+	// Report the upstream the request was routed to
+	target := ps.upstreamURL(r)
+
 	// Provide helpful error message based on error type
 	var userMsg string
 
 	if strings.Contains(errStr, "context canceled") {
-		userMsg = fmt.Sprintf("Proxy Error: Request canceled. The proxy may be shutting down, or the target server (%s) is unavailable.", ps.TargetURL.String())
+		userMsg = fmt.Sprintf("Proxy Error: Request canceled. The proxy may be shutting down, or the target server (%s) is unavailable.", target.String())
 	} else if strings.Contains(errStr, "connection refused") {
-		userMsg = fmt.Sprintf("Proxy Error: Cannot connect to target server %s. Make sure the server is running.", ps.TargetURL.String())
+		userMsg = fmt.Sprintf("Proxy Error: Cannot connect to target server %s. Make sure the server is running.", target.String())
 	} else if strings.Contains(errStr, "no such host") {
-		userMsg = fmt.Sprintf("Proxy Error: Cannot resolve target host %s. Check the target URL.", ps.TargetURL.String())
+		userMsg = fmt.Sprintf("Proxy Error: Cannot resolve target host %s. Check the target URL.", target.String())
 	} else if isTransient {
 		// Friendly message for transient errors - these are normal during development
-		userMsg = fmt.Sprintf("Connection to %s was interrupted. This often happens when the dev server restarts. Refresh to retry.", ps.TargetURL.Host)
+		userMsg = fmt.Sprintf("Connection to %s was interrupted. This often happens when the dev server restarts. Refresh to retry.", target.Host)
 	} else {
-		userMsg = fmt.Sprintf("Proxy Error: %s (target: %s)", errStr, ps.TargetURL.String())
+		userMsg = fmt.Sprintf("Proxy Error: %s (target: %s)", errStr, target.String())
 	}
 
 	http.Error(w, userMsg, http.StatusBadGateway)
@@ -1020,12 +1284,25 @@ func isTransientConnectionError(errStr string) bool {
 func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	debug.Log("proxy", "WebSocket connection attempt from %s to proxy %s", r.RemoteAddr, ps.ID)
 
-	conn, err := ps.wsUpgrader.Upgrade(w, r, nil)
+	// Plain HTTP requests (e.g. tunnel health probes) are not connection attempts
+	if !websocket.IsWebSocketUpgrade(r) {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+
+	auth := ps.authorizeWebSocket(r)
+	if auth.reason != "" {
+		ps.rejectWebSocket(w, r, auth)
+		return
+	}
+
+	rawConn, err := ps.wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		debug.Log("proxy", "WebSocket upgrade failed for proxy %s: %v", ps.ID, err)
 		return
 	}
-	defer conn.Close()
+	defer rawConn.Close()
+	conn := newWSConn(rawConn)
 
 	// Store connection for sending messages
 	connID := fmt.Sprintf("conn-%d", time.Now().UnixNano())
@@ -1034,6 +1311,7 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		ps.wsConns.Delete(connID)
+		ps.cancelConnAgentStreams(connID)
 		debug.Log("proxy", "WebSocket client disconnected: proxy=%s connID=%s", ps.ID, connID)
 	}()
 
@@ -1044,6 +1322,9 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Message types denied to this client, so each is logged once
+	denied := make(map[string]bool)
+
 	// Read messages from frontend
 	for {
 		messageType, rawMessage, err := conn.ReadMessage()
@@ -1071,6 +1352,11 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		if !auth.caps.allows(msg.Type) {
+			ps.denyWebSocketMessage(conn, auth, msg.Type, msg.URL, denied)
+			continue
+		}
+
 		seq := ps.requestSeq.Add(1)
 		id := fmt.Sprintf("metric-%d", seq)
 		timestamp := time.Now()
@@ -1088,8 +1374,12 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				Stack:     getStringField(msg.Data, "stack"),
 				URL:       msg.URL,
 			}
-			ps.logger.LogError(errEntry)
-			ps.pageTracker.TrackError(errEntry, msg.SessionID)
+			sessionID := msg.SessionID
+			ps.resolveStackAsync(errEntry.Stack, errEntry.Source, errEntry.LineNo, errEntry.ColNo, msg.URL, func(resolved string, frames []StackFrame) {
+				errEntry.ResolvedStack, errEntry.Frames = resolved, frames
+				ps.logger.LogError(errEntry)
+				ps.pageTracker.TrackError(errEntry, sessionID)
+			})
 
 		case "performance":
 			metric := PerformanceMetric{
@@ -1220,13 +1510,52 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 
+		case "console":
+			// console.warn, console.error and failed console.assert calls
+			consoleMsg := parseConsoleMessage(msg.Data, id, timestamp, msg.URL)
+			ps.logger.LogConsole(consoleMsg)
+			ps.pageTracker.TrackConsole(consoleMsg, msg.SessionID)
+
+		case "rejection":
+			rejection := parsePromiseRejection(msg.Data, id, timestamp, msg.URL)
+			sessionID := msg.SessionID
+			ps.resolveStackAsync(rejection.Stack, "", 0, 0, msg.URL, func(resolved string, frames []StackFrame) {
+				rejection.ResolvedStack, rejection.Frames = resolved, frames
+				ps.logger.LogRejection(rejection)
+				ps.pageTracker.TrackRejection(rejection, sessionID)
+			})
+
+		case "web_vital":
+			vital := parseWebVital(msg.Data, id, timestamp, msg.URL)
+			ps.logger.LogWebVital(vital)
+			ps.pageTracker.TrackWebVital(vital, msg.SessionID)
+
+		case "navigation":
+			// Page load or client-side route change
+			ps.pageTracker.TrackNavigation(parseNavigationEvent(msg.Data, id, timestamp, msg.URL), msg.SessionID)
+
+		case "cached_fetches":
+			// Requests answered by a service worker or the browser cache,
+			// sent for the page they were made on
+			page := getStringField(msg.Data, "page")
+			if page == "" {
+				page = msg.URL
+			}
+			for _, eventData := range getArrayField(msg.Data, "events") {
+				if em, ok := eventData.(map[string]interface{}); ok {
+					ps.pageTracker.TrackCachedFetch(parseCachedFetch(em, timestamp), page, msg.SessionID)
+				}
+			}
+
 		case "panel_message":
 			// Handle message from floating indicator panel
 			panelMsg := parsePanelMessage(msg.Data, id, timestamp, msg.URL)
 			ps.logger.LogPanelMessage(panelMsg)
 
-			// Forward to overlay if configured
-			if ps.overlayNotifier.IsEnabled() {
+			// Stream the agent response back to the browser if requested
+			if ps.wantsAgentStream(msg.Data) {
+				_ = ps.StartAgentStream(connID, panelMessageStreamRequest(msg.Data, panelMsg))
+			} else if ps.overlayNotifier.IsEnabled() {
 				_ = ps.overlayNotifier.NotifyPanelMessage(ps.ID, &panelMsg)
 			}
 
@@ -1288,8 +1617,10 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			designRequest := parseDesignRequest(msg.Data, id, timestamp, msg.URL)
 			ps.logger.LogDesignRequest(designRequest)
 
-			// Forward to overlay if configured
-			if ps.overlayNotifier.IsEnabled() {
+			// Stream the agent response back to the browser if requested
+			if ps.wantsAgentStream(msg.Data) {
+				_ = ps.StartAgentStream(connID, designRequestStreamRequest(msg.Data, designRequest))
+			} else if ps.overlayNotifier.IsEnabled() {
 				_ = ps.overlayNotifier.NotifyDesignRequest(ps.ID, &designRequest)
 			}
 
@@ -1298,11 +1629,35 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			designChat := parseDesignChat(msg.Data, id, timestamp, msg.URL)
 			ps.logger.LogDesignChat(designChat)
 
-			// Forward to overlay if configured
-			if ps.overlayNotifier.IsEnabled() {
+			// Stream the agent response back to the browser if requested
+			if ps.wantsAgentStream(msg.Data) {
+				_ = ps.StartAgentStream(connID, designChatStreamRequest(msg.Data, designChat))
+			} else if ps.overlayNotifier.IsEnabled() {
 				_ = ps.overlayNotifier.NotifyDesignChat(ps.ID, &designChat)
 			}
 
+		case "agent_request":
+			// Handle direct agent prompt from browser
+			req := AgentStreamRequest{
+				StreamID: getStringField(msg.Data, "stream_id"),
+				Source:   "prompt",
+				Prompt:   getStringField(msg.Data, "prompt"),
+				Context:  pageContext(msg.URL, getStringField(msg.Data, "context")),
+				URL:      msg.URL,
+			}
+			if req.StreamID == "" {
+				req.StreamID = "stream-" + id
+			}
+			if req.Prompt == "" {
+				ps.sendAgentStream(connID, AgentStreamEvent{Type: "error", StreamID: req.StreamID, Content: "prompt is required"})
+			} else if err := ps.StartAgentStream(connID, req); err != nil {
+				ps.sendAgentStream(connID, AgentStreamEvent{Type: "error", StreamID: req.StreamID, Content: err.Error()})
+			}
+
+		case "agent_cancel":
+			// Cancel a running agent stream started by this connection
+			ps.CancelAgentStream(connID, getStringField(msg.Data, "stream_id"))
+
 		case "session_request":
 			// Handle session API requests from browser
 			go ps.handleSessionRequest(conn, msg.Data)
@@ -1312,18 +1667,25 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			go ps.handleStoreRequest(conn, msg.Data)
 
 		case "voice_start":
-			// Start voice transcription session
-			config := DefaultDeepgramConfig()
-
-			// Apply any config from message
-			if lang := getStringField(msg.Data, "language"); lang != "" {
-				config.Language = lang
+			// Start voice transcription session with the configured provider
+			provider, err := NewSpeechProvider(ps.speechConfig())
+			if err != nil {
+				conn.WriteJSON(map[string]interface{}{
+					"type":  "voice_error",
+					"error": err.Error(),
+				})
+				continue
 			}
-			if model := getStringField(msg.Data, "model"); model != "" {
-				config.Model = model
+
+			// Apply any options from message
+			opts := SpeechOptions{
+				Language:   getStringField(msg.Data, "language"),
+				Model:      getStringField(msg.Data, "model"),
+				SampleRate: 16000,
+				Channels:   1,
 			}
 
-			session, err := NewVoiceSession(connID, conn, config)
+			session, err := NewVoiceSession(connID, conn, provider, opts)
 			if err != nil {
 				conn.WriteJSON(map[string]interface{}{
 					"type":  "voice_error",
@@ -1340,7 +1702,7 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				Timestamp: timestamp,
 				Level:     "info",
 				Message:   "[Voice] Transcription session started",
-				Data:      map[string]interface{}{"model": config.Model, "language": config.Language},
+				Data:      map[string]interface{}{"provider": provider.Name(), "model": opts.Model, "language": opts.Language},
 				URL:       msg.URL,
 			})
 
@@ -1369,7 +1731,7 @@ func (ps *ProxyServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 // handleSessionRequest processes session API requests from the browser.
 // It creates a daemon client, executes the session operation, and sends the response back.
-func (ps *ProxyServer) handleSessionRequest(conn *websocket.Conn, data map[string]interface{}) {
+func (ps *ProxyServer) handleSessionRequest(conn *wsConn, data map[string]interface{}) {
 	requestID := getStringField(data, "request_id")
 	action := getStringField(data, "action")
 	params := getMapField(data, "params")
@@ -1504,7 +1866,7 @@ func (ps *ProxyServer) handleSessionRequest(conn *websocket.Conn, data map[strin
 
 // handleStoreRequest processes store API requests from the browser.
 // It creates a daemon client, executes the store operation, and sends the response back.
-func (ps *ProxyServer) handleStoreRequest(conn *websocket.Conn, data map[string]interface{}) {
+func (ps *ProxyServer) handleStoreRequest(conn *wsConn, data map[string]interface{}) {
 	requestID := getStringField(data, "request_id")
 	action := getStringField(data, "action")
 	params := getMapField(data, "params")
@@ -1688,14 +2050,23 @@ func (ps *ProxyServer) handleStoreRequest(conn *websocket.Conn, data map[string]
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode  int
-	body        *bytes.Buffer
+	body        *bodyCapture // nil when body capture is disabled
 	wroteHeader bool
+
+	// onHeader runs before the status is sent; it may set stream.
+	onHeader func(statusCode int)
+	// stream receives the body instead of ResponseWriter for streaming
+	// responses.
+	stream *streamTracker
 }
 
 func (rr *responseRecorder) WriteHeader(statusCode int) {
 	if !rr.wroteHeader {
 		rr.statusCode = statusCode
 		rr.wroteHeader = true
+		if rr.onHeader != nil {
+			rr.onHeader(statusCode)
+		}
 		rr.ResponseWriter.WriteHeader(statusCode)
 	}
 }
@@ -1704,10 +2075,28 @@ func (rr *responseRecorder) Write(b []byte) (int, error) {
 	if !rr.wroteHeader {
 		rr.WriteHeader(http.StatusOK)
 	}
-	rr.body.Write(b) // Capture for logging
+	if rr.body != nil {
+		rr.body.Write(b) // Capture for logging
+	}
+	if rr.stream != nil {
+		return rr.stream.Write(b)
+	}
 	return rr.ResponseWriter.Write(b)
 }
 
+// Flush implements http.Flusher so streamed responses reach the client as
+// the upstream sends them.
+func (rr *responseRecorder) Flush() {
+	if flusher, ok := rr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
 // Hijack implements http.Hijacker for WebSocket support.
 func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hijacker, ok := rr.ResponseWriter.(http.Hijacker)
@@ -1717,6 +2106,15 @@ func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return hijacker.Hijack()
 }
 
+// headerMap flattens headers for logging.
+func headerMap(h http.Header) map[string]string {
+	m := make(map[string]string, len(h))
+	for k, v := range h {
+		m[k] = strings.Join(v, ", ")
+	}
+	return m
+}
+
 // Helper functions for extracting fields from JSON data
 
 func getStringField(data map[string]interface{}, key string) string {
@@ -1862,7 +2260,7 @@ func (ps *ProxyServer) ExecuteJavaScript(code string) (string, <-chan *Execution
 	// Send to all connected clients
 	sentCount := 0
 	ps.wsConns.Range(func(key, value interface{}) bool {
-		conn := value.(*websocket.Conn)
+		conn := value.(*wsConn)
 		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
 		if err == nil {
 			sentCount++
@@ -1897,7 +2295,7 @@ func (ps *ProxyServer) BroadcastActivityState(active bool) int {
 
 	sentCount := 0
 	ps.wsConns.Range(func(key, value interface{}) bool {
-		conn := value.(*websocket.Conn)
+		conn := value.(*wsConn)
 		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
 		if err == nil {
 			sentCount++
@@ -1946,7 +2344,7 @@ func (ps *ProxyServer) BroadcastToast(toastType, title, message string, duration
 	sentCount := 0
 	failCount := 0
 	ps.wsConns.Range(func(key, value interface{}) bool {
-		conn := value.(*websocket.Conn)
+		conn := value.(*wsConn)
 		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
 		if err == nil {
 			sentCount++
@@ -1965,6 +2363,34 @@ func (ps *ProxyServer) BroadcastToast(toastType, title, message string, duration
 	return sentCount, nil
 }
 
+// BroadcastPublicURL notifies connected browser clients that the proxy's
+// public URL changed. Returns the number of clients that received it.
+func (ps *ProxyServer) BroadcastPublicURL(publicURL string) int {
+	message := map[string]interface{}{
+		"type": "public_url",
+		"payload": map[string]interface{}{
+			"url": publicURL,
+		},
+	}
+
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return 0
+	}
+
+	sentCount := 0
+	ps.wsConns.Range(func(key, value interface{}) bool {
+		conn := value.(*wsConn)
+		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
+		if err == nil {
+			sentCount++
+		}
+		return true
+	})
+
+	return sentCount
+}
+
 // BroadcastOutputPreview sends output preview lines to all connected browser clients.
 // Returns the number of clients that received the preview.
 func (ps *ProxyServer) BroadcastOutputPreview(lines []string) int {
@@ -1982,7 +2408,7 @@ func (ps *ProxyServer) BroadcastOutputPreview(lines []string) int {
 
 	sentCount := 0
 	ps.wsConns.Range(func(key, value interface{}) bool {
-		conn := value.(*websocket.Conn)
+		conn := value.(*wsConn)
 		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
 		if err == nil {
 			sentCount++
@@ -2003,6 +2429,90 @@ func getArrayField(data map[string]interface{}, key string) []interface{} {
 	return nil
 }
 
+// parseNavigationEvent parses a navigation message; url is the page URL
+// after the navigation.
+func parseNavigationEvent(data map[string]interface{}, id string, timestamp time.Time, url string) NavigationEvent {
+	nav := NavigationEvent{
+		ID:            id,
+		Timestamp:     timestamp,
+		Kind:          getStringField(data, "kind"),
+		URL:           getStringField(data, "to"),
+		From:          getStringField(data, "from"),
+		Title:         getStringField(data, "title"),
+		Source:        getStringField(data, "source"),
+		ServiceWorker: getBoolField(data, "service_worker"),
+	}
+	if nav.URL == "" {
+		nav.URL = url
+	}
+	return nav
+}
+
+// parseCachedFetch parses one entry of a cached_fetches message.
+func parseCachedFetch(data map[string]interface{}, timestamp time.Time) CachedFetch {
+	return CachedFetch{
+		Timestamp:  timestamp,
+		URL:        getStringField(data, "url"),
+		Initiator:  getStringField(data, "initiator"),
+		Source:     getStringField(data, "source"),
+		DurationMs: getInt64Field(data, "duration"),
+		Size:       getInt64Field(data, "size"),
+	}
+}
+
+// parseConsoleMessage parses a console message from JSON data.
+func parseConsoleMessage(data map[string]interface{}, id string, timestamp time.Time, url string) ConsoleMessage {
+	consoleMsg := ConsoleMessage{
+		ID:        id,
+		Timestamp: timestamp,
+		Level:     getStringField(data, "level"),
+		Message:   getStringField(data, "message"),
+		Stack:     getStringField(data, "stack"),
+		URL:       url,
+	}
+	if consoleMsg.Level != "error" {
+		consoleMsg.Level = "warn"
+	}
+	for _, arg := range getArrayField(data, "args") {
+		if s, ok := arg.(string); ok {
+			consoleMsg.Args = append(consoleMsg.Args, s)
+		}
+	}
+	return consoleMsg
+}
+
+// parsePromiseRejection parses an unhandled promise rejection from JSON data.
+func parsePromiseRejection(data map[string]interface{}, id string, timestamp time.Time, url string) PromiseRejection {
+	return PromiseRejection{
+		ID:         id,
+		Timestamp:  timestamp,
+		Message:    getStringField(data, "message"),
+		Name:       getStringField(data, "name"),
+		ReasonType: getStringField(data, "reason_type"),
+		Stack:      getStringField(data, "stack"),
+		URL:        url,
+	}
+}
+
+// parseWebVital parses a web vital measurement from JSON data. The rating
+// is computed here so all clients use the same thresholds.
+func parseWebVital(data map[string]interface{}, id string, timestamp time.Time, url string) WebVital {
+	vital := WebVital{
+		ID:        id,
+		Timestamp: timestamp,
+		Name:      getStringField(data, "name"),
+		Value:     getFloatField(data, "value"),
+		Element:   getStringField(data, "element"),
+		Details:   getMapField(data, "details"),
+		URL:       getStringField(data, "url"), // Route the metric was measured on
+	}
+	if vital.URL == "" {
+		vital.URL = url
+	}
+	vital.Rating = RateWebVital(vital.Name, vital.Value)
+	return vital
+}
+
 // parseInteractionEvent parses an interaction event from JSON data.
 func parseInteractionEvent(data map[string]interface{}, id string, timestamp time.Time, url string) InteractionEvent {
 	event := InteractionEvent{
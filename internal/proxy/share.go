@@ -0,0 +1,293 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/certs"
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/lanshare"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// shareInfoPath serves the active share as JSON (with an SVG QR code).
+	shareInfoPath = "/__devtool_share"
+	// shareQRPath serves a PNG QR code for the first share URL.
+	shareQRPath = "/__devtool_share.png"
+	// shareCAPath serves the local CA certificate for installing on devices.
+	shareCAPath = "/__devtool_ca.pem"
+)
+
+// ShareOptions configures LAN sharing of a proxy.
+type ShareOptions struct {
+	// HTTPS serves the share listener over TLS with a certificate from the
+	// local agnt CA, so secure-context APIs work on devices.
+	HTTPS bool
+	// Port for the share listener (0 picks a free port).
+	Port int
+	// CADir overrides the CA directory (default: certs.DefaultDir()).
+	CADir string
+}
+
+// ShareInfo describes an active LAN share.
+type ShareInfo struct {
+	URLs       []string           `json:"urls"`
+	Addresses  []lanshare.Address `json:"addresses"`
+	HTTPS      bool               `json:"https"`
+	ListenAddr string             `json:"listen_addr"`
+	QRTerminal string             `json:"qr_terminal,omitempty"`
+	QRSVG      string             `json:"qr_svg,omitempty"`
+	CACertPath string             `json:"ca_cert_path,omitempty"`
+	CAURL      string             `json:"ca_url,omitempty"`
+	StartedAt  time.Time          `json:"started_at"`
+}
+
+// shareState holds the listener of an active share.
+type shareState struct {
+	info   *ShareInfo
+	server *http.Server
+}
+
+// originKey is the context key for the client-facing origin of a request
+// that arrived through a share or HTTPS listener.
+type originKey struct{}
+
+// requestOrigin returns the scheme and host a client used to reach the proxy,
+// if the request came through a share or HTTPS listener.
+func requestOrigin(ctx context.Context) (scheme, host string, ok bool) {
+	if ctx == nil {
+		return "", "", false
+	}
+	origin, ok := ctx.Value(originKey{}).([2]string)
+	if !ok {
+		return "", "", false
+	}
+	return origin[0], origin[1], true
+}
+
+// Share exposes the proxy to devices on the local network.
+// A dedicated listener on all interfaces serves the same handler as the
+// proxy, so a proxy bound to 127.0.0.1 stays private until shared.
+// Calling Share again with the same options returns the active share.
+func (ps *ProxyServer) Share(opts ShareOptions) (*ShareInfo, error) {
+	// Read the handler before taking shareMu; Stop holds ps.mu while it
+	// stops the share, so the locks must not be nested the other way round.
+	ps.mu.Lock()
+	var handler http.Handler
+	if ps.running.Load() && ps.httpServer != nil {
+		handler = ps.httpServer.Handler
+	}
+	ps.mu.Unlock()
+	if handler == nil {
+		return nil, fmt.Errorf("proxy %s is not running", ps.ID)
+	}
+
+	ps.shareMu.Lock()
+	defer ps.shareMu.Unlock()
+
+	if ps.share != nil {
+		if ps.share.info.HTTPS == opts.HTTPS && (opts.Port == 0 || ps.shareListenPort() == opts.Port) {
+			return ps.share.info, nil
+		}
+		ps.stopShareLocked()
+	}
+
+	addrs, err := lanshare.Addresses()
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no LAN interfaces found; connect to a network or use a tunnel")
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(opts.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for LAN share: %w", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	scheme := "http"
+	info := &ShareInfo{
+		Addresses:  addrs,
+		HTTPS:      opts.HTTPS,
+		ListenAddr: listener.Addr().String(),
+		StartedAt:  time.Now(),
+	}
+
+	var ca *certs.CA
+	if opts.HTTPS {
+		scheme = "https"
+		ca, err = certs.LoadOrCreate(opts.CADir)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to load local CA: %w", err)
+		}
+		tlsConfig, err := ca.TLSConfig(lanshare.Hosts(addrs)...)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to issue certificate: %w", err)
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+		info.CACertPath = ca.CertPath()
+	}
+
+	info.URLs = lanshare.URLs(scheme, port, addrs)
+	if len(info.URLs) > 0 {
+		if qr, err := lanshare.NewQR(info.URLs[0]); err == nil {
+			info.QRTerminal = qr.Terminal()
+			info.QRSVG = qr.SVG()
+		}
+		if ca != nil {
+			info.CAURL = info.URLs[0] + shareCAPath
+		}
+	}
+
+	mux := http.NewServeMux()
+	if ca != nil {
+		certPEM := ca.CertPEM()
+		mux.HandleFunc(shareCAPath, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+			w.Header().Set("Content-Disposition", `attachment; filename="agnt-ca.pem"`)
+			w.Write(certPEM)
+		})
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Rewrite target URLs to the address the device used, not localhost
+		ctx := context.WithValue(r.Context(), originKey{}, [2]string{scheme, r.Host})
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			debug.Error("proxy", "LAN share for %s stopped: %v", ps.ID, err)
+		}
+	}()
+
+	ps.share = &shareState{info: info, server: server}
+	debug.Log("proxy", "LAN share for %s: %v", ps.ID, info.URLs)
+
+	ps.BroadcastShare(info)
+	return info, nil
+}
+
+// StopShare stops LAN sharing. It is a no-op if the proxy is not shared.
+func (ps *ProxyServer) StopShare() {
+	ps.shareMu.Lock()
+	defer ps.shareMu.Unlock()
+	ps.stopShareLocked()
+}
+
+func (ps *ProxyServer) stopShareLocked() {
+	if ps.share == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ps.share.server.Shutdown(ctx)
+	ps.share = nil
+}
+
+// shareListenPort returns the port of the active share listener.
+func (ps *ProxyServer) shareListenPort() int {
+	if ps.share == nil {
+		return 0
+	}
+	_, port, err := net.SplitHostPort(ps.share.info.ListenAddr)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// CurrentShare returns the active LAN share, or nil if not shared.
+func (ps *ProxyServer) CurrentShare() *ShareInfo {
+	ps.shareMu.Lock()
+	defer ps.shareMu.Unlock()
+	if ps.share == nil {
+		return nil
+	}
+	return ps.share.info
+}
+
+// handleShareInfo serves the active share to the browser indicator.
+func (ps *ProxyServer) handleShareInfo(w http.ResponseWriter, r *http.Request) {
+	info := ps.CurrentShare()
+	if info == nil {
+		http.Error(w, "proxy is not shared on the LAN", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleShareQR serves a PNG QR code for the first share URL.
+func (ps *ProxyServer) handleShareQR(w http.ResponseWriter, r *http.Request) {
+	info := ps.CurrentShare()
+	if info == nil || len(info.URLs) == 0 {
+		http.Error(w, "proxy is not shared on the LAN", http.StatusNotFound)
+		return
+	}
+
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	if size <= 0 || size > 1024 {
+		size = 256
+	}
+	qr, err := lanshare.NewQR(info.URLs[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	png, err := qr.PNG(size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+// BroadcastShare sends LAN share details to connected browser clients so the
+// indicator can show the QR code. Returns the number of clients notified.
+func (ps *ProxyServer) BroadcastShare(info *ShareInfo) int {
+	message := map[string]interface{}{
+		"type": "share",
+		"payload": map[string]interface{}{
+			"urls":   info.URLs,
+			"https":  info.HTTPS,
+			"qr_svg": info.QRSVG,
+			"ca_url": info.CAURL,
+		},
+	}
+
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return 0
+	}
+
+	sentCount := 0
+	ps.wsConns.Range(func(key, value interface{}) bool {
+		conn := value.(*wsConn)
+		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
+		if err == nil {
+			sentCount++
+		}
+		return true
+	})
+
+	return sentCount
+}
@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseOrigin(t *testing.T) {
+	ps := newTestProxyServer("http://localhost:3000", ":8080")
+	body := []byte(`<a href="http://localhost:3000/about">About</a>`)
+
+	// Requests through the proxy port use the local origin
+	resp := &http.Response{Request: httptest.NewRequest("GET", "/", nil)}
+	scheme, host := ps.responseOrigin(resp)
+	got := string(ps.rewriteURLsInBodyTo(body, scheme, host))
+	if !strings.Contains(got, "http://localhost:8080/about") {
+		t.Errorf("expected local rewrite, got %s", got)
+	}
+
+	// Requests through a LAN share keep the address the device used
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), originKey{}, [2]string{"https", "192.168.1.20:9443"}))
+	resp = &http.Response{Request: req, Header: make(http.Header)}
+	scheme, host = ps.responseOrigin(resp)
+	got = string(ps.rewriteURLsInBodyTo(body, scheme, host))
+	if !strings.Contains(got, "https://192.168.1.20:9443/about") {
+		t.Errorf("expected share rewrite, got %s", got)
+	}
+
+	resp.Header.Set("Location", "http://localhost:3000/login")
+	ps.rewriteLocationHeader(resp)
+	if loc := resp.Header.Get("Location"); loc != "https://192.168.1.20:9443/login" {
+		t.Errorf("expected share Location rewrite, got %s", loc)
+	}
+
+	// Responses without a request fall back to the local origin
+	scheme, host = ps.responseOrigin(&http.Response{})
+	if scheme != "http" || host != "localhost:8080" {
+		t.Errorf("unexpected fallback origin %s://%s", scheme, host)
+	}
+}
+
+func TestShare_NotRunning(t *testing.T) {
+	ps, err := NewProxyServer(ProxyConfig{ID: "test-share", TargetURL: "http://localhost:9999", ListenPort: 0})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if _, err := ps.Share(ShareOptions{}); err == nil {
+		t.Error("expected error sharing a stopped proxy")
+	}
+	if ps.CurrentShare() != nil {
+		t.Error("expected no active share")
+	}
+}
+
+func TestShare_HTTPS(t *testing.T) {
+	var upstreamURL string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head></head><body><a href="`+upstreamURL+`/next">next</a> proto=`+r.Header.Get("X-Forwarded-Proto")+`</body></html>`)
+	}))
+	defer upstream.Close()
+	upstreamURL = upstream.URL
+
+	ps, err := NewProxyServer(ProxyConfig{ID: "test-share", TargetURL: upstream.URL, ListenPort: 0})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ps.Stop(context.Background())
+	<-ps.Ready()
+
+	info, err := ps.Share(ShareOptions{HTTPS: true, CADir: t.TempDir()})
+	if err != nil {
+		if strings.Contains(err.Error(), "no LAN interfaces") {
+			t.Skip("no LAN interfaces in this environment")
+		}
+		t.Fatalf("Share: %v", err)
+	}
+	if !info.HTTPS || len(info.URLs) == 0 || !strings.HasPrefix(info.URLs[0], "https://") {
+		t.Fatalf("unexpected share info: %+v", info)
+	}
+	if info.QRTerminal == "" || !strings.HasPrefix(info.QRSVG, "<svg") {
+		t.Error("expected QR renderings")
+	}
+	if again, _ := ps.Share(ShareOptions{HTTPS: true}); again != info {
+		t.Error("expected Share to return the active share")
+	}
+
+	// The share listener is on all interfaces, so loopback reaches it too
+	_, port, _ := net.SplitHostPort(info.ListenAddr)
+	base := "https://127.0.0.1:" + port
+
+	caResp, err := (&http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}).Get(base + shareCAPath)
+	if err != nil {
+		t.Fatalf("fetch CA: %v", err)
+	}
+	caPEM, _ := io.ReadAll(caResp.Body)
+	caResp.Body.Close()
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		t.Fatalf("invalid CA from share: %q", caPEM)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get(base + "/")
+	if err != nil {
+		t.Fatalf("request through share: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !strings.Contains(string(body), `href="`+base+`/next"`) {
+		t.Errorf("expected links rewritten to share origin, got %s", body)
+	}
+	if !strings.Contains(string(body), "proto=https") {
+		t.Errorf("expected X-Forwarded-Proto https, got %s", body)
+	}
+
+	// The local indicator can fetch the share details
+	infoResp, err := http.Get("http://" + ps.ListenAddr + shareInfoPath)
+	if err != nil {
+		t.Fatalf("fetch share info: %v", err)
+	}
+	var served ShareInfo
+	json.NewDecoder(infoResp.Body).Decode(&served)
+	infoResp.Body.Close()
+	if len(served.URLs) == 0 || served.URLs[0] != info.URLs[0] {
+		t.Errorf("unexpected served share info: %+v", served)
+	}
+
+	qrResp, err := http.Get("http://" + ps.ListenAddr + shareQRPath)
+	if err != nil {
+		t.Fatalf("fetch QR: %v", err)
+	}
+	qrResp.Body.Close()
+	if qrResp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("expected PNG QR, got %s", qrResp.Header.Get("Content-Type"))
+	}
+
+	if stats := ps.Stats(); len(stats.ShareURLs) == 0 {
+		t.Error("expected share URLs in stats")
+	}
+
+	ps.StopShare()
+	if ps.CurrentShare() != nil {
+		t.Error("expected share to stop")
+	}
+	if _, err := client.Get(base + "/"); err == nil {
+		t.Error("expected share listener to be closed")
+	}
+	if resp, err := http.Get("http://" + ps.ListenAddr + shareInfoPath); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404 after unshare, got %d", resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceMap is a parsed source map (revision 3), either a plain map or an
+// index map made of sections.
+type SourceMap struct {
+	sources  []string
+	names    []string
+	lines    [][]mapping // Segments of each generated line, by column
+	sections []mapSection
+}
+
+// mapping is one segment of a source map: a generated column and the
+// original position it came from. source is -1 for unmapped code.
+type mapping struct {
+	genCol  int
+	source  int
+	line    int
+	column  int
+	nameIdx int // -1 without a name
+}
+
+// mapSection is a map embedded in an index map, starting at a generated offset.
+type mapSection struct {
+	line, column int
+	m            *SourceMap
+}
+
+// OriginalPosition is a location in the original sources. Line and Column
+// are 1-based, like the locations in JavaScript stack traces.
+type OriginalPosition struct {
+	Source string
+	Line   int
+	Column int
+	Name   string // Identifier at the location, if the map names it
+}
+
+type rawSourceMap struct {
+	Version    int      `json:"version"`
+	Sources    []string `json:"sources"`
+	SourceRoot string   `json:"sourceRoot"`
+	Names      []string `json:"names"`
+	Mappings   string   `json:"mappings"`
+	Sections   []struct {
+		Offset struct {
+			Line   int `json:"line"`
+			Column int `json:"column"`
+		} `json:"offset"`
+		Map json.RawMessage `json:"map"`
+	} `json:"sections"`
+}
+
+// ParseSourceMap parses a source map. Sources are normalized to paths
+// relative to the project: bundler schemes (webpack://app/) are removed and
+// relative paths are resolved against base, the directory of the map.
+func ParseSourceMap(data []byte, base string) (*SourceMap, error) {
+	// Maps may start with an XSSI guard
+	if len(data) > 3 && string(data[:3]) == ")]}" {
+		if i := strings.IndexByte(string(data), '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	var raw rawSourceMap
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+
+	sm := &SourceMap{names: raw.Names}
+	if len(raw.Sections) > 0 {
+		for _, section := range raw.Sections {
+			m, err := ParseSourceMap(section.Map, base)
+			if err != nil {
+				return nil, err
+			}
+			sm.sections = append(sm.sections, mapSection{line: section.Offset.Line, column: section.Offset.Column, m: m})
+		}
+		return sm, nil
+	}
+
+	for _, source := range raw.Sources {
+		sm.sources = append(sm.sources, normalizeSourcePath(source, raw.SourceRoot, base))
+	}
+	lines, err := decodeMappings(raw.Mappings)
+	if err != nil {
+		return nil, err
+	}
+	sm.lines = lines
+	return sm, nil
+}
+
+// Lookup returns the original position of a 1-based generated location.
+func (sm *SourceMap) Lookup(line, column int) (OriginalPosition, bool) {
+	genLine, genCol := line-1, column-1
+	if genLine < 0 || genCol < 0 {
+		return OriginalPosition{}, false
+	}
+
+	if len(sm.sections) > 0 {
+		// Last section starting at or before the location
+		i := sort.Search(len(sm.sections), func(i int) bool {
+			s := sm.sections[i]
+			return s.line > genLine || (s.line == genLine && s.column > genCol)
+		}) - 1
+		if i < 0 {
+			return OriginalPosition{}, false
+		}
+		s := sm.sections[i]
+		if genLine == s.line {
+			genCol -= s.column
+		}
+		return s.m.Lookup(genLine-s.line+1, genCol+1)
+	}
+
+	if genLine >= len(sm.lines) {
+		return OriginalPosition{}, false
+	}
+	segments := sm.lines[genLine]
+	i := sort.Search(len(segments), func(i int) bool { return segments[i].genCol > genCol }) - 1
+	if i < 0 || segments[i].source < 0 || segments[i].source >= len(sm.sources) {
+		return OriginalPosition{}, false
+	}
+
+	seg := segments[i]
+	pos := OriginalPosition{Source: sm.sources[seg.source], Line: seg.line + 1, Column: seg.column + 1}
+	if seg.nameIdx >= 0 && seg.nameIdx < len(sm.names) {
+		pos.Name = sm.names[seg.nameIdx]
+	}
+	return pos, true
+}
+
+// decodeMappings decodes the Base64 VLQ "mappings" field into segments per
+// generated line.
+func decodeMappings(mappings string) ([][]mapping, error) {
+	var lines [][]mapping
+	var source, line, column, name int
+	for _, group := range strings.Split(mappings, ";") {
+		var segments []mapping
+		genCol := 0
+		for _, segment := range strings.Split(group, ",") {
+			if segment == "" {
+				continue
+			}
+			fields, err := decodeVLQ(segment)
+			if err != nil {
+				return nil, err
+			}
+			genCol += fields[0]
+			m := mapping{genCol: genCol, source: -1, nameIdx: -1}
+			if len(fields) >= 4 {
+				source += fields[1]
+				line += fields[2]
+				column += fields[3]
+				m.source, m.line, m.column = source, line, column
+			}
+			if len(fields) >= 5 {
+				name += fields[4]
+				m.nameIdx = name
+			}
+			segments = append(segments, m)
+		}
+		// Segments are normally in column order already
+		sort.SliceStable(segments, func(i, j int) bool { return segments[i].genCol < segments[j].genCol })
+		lines = append(lines, segments)
+	}
+	return lines, nil
+}
+
+const vlqChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// decodeVLQ decodes the Base64 VLQ fields of one segment.
+func decodeVLQ(segment string) ([]int, error) {
+	var fields []int
+	value, shift := 0, 0
+	for i := 0; i < len(segment); i++ {
+		digit := strings.IndexByte(vlqChars, segment[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid source map mapping %q", segment)
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			fields = append(fields, -(value >> 1))
+		} else {
+			fields = append(fields, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 || len(fields) == 0 {
+		return nil, fmt.Errorf("invalid source map mapping %q", segment)
+	}
+	return fields, nil
+}
+
+// bundlerScheme matches the scheme and namespace bundlers prefix sources
+// with, e.g. "webpack://my-app/" or "vite:///".
+var bundlerScheme = regexp.MustCompile(`^[a-z][a-z0-9+.-]*://[^/]*/`)
+
+// normalizeSourcePath makes a source path readable and relative to the
+// project, e.g. "webpack://app/./src/App.tsx" and "../../src/App.tsx" (in a
+// map under /assets/js) both become "src/App.tsx".
+func normalizeSourcePath(source, sourceRoot, base string) string {
+	if sourceRoot != "" && !bundlerScheme.MatchString(source) && !strings.HasPrefix(source, "/") {
+		source = strings.TrimSuffix(sourceRoot, "/") + "/" + source
+	}
+	if loc := bundlerScheme.FindStringIndex(source); loc != nil {
+		return strings.TrimPrefix(path.Clean("/"+source[loc[1]:]), "/")
+	}
+	if !strings.HasPrefix(source, "/") {
+		source = path.Join("/", base, source)
+	}
+	return strings.TrimPrefix(path.Clean(source), "/")
+}
+
+// StackFrame is one frame of a JavaScript stack trace. Line and Column are
+// 1-based.
+type StackFrame struct {
+	Function string `json:"function,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	// Generated is the bundle location ("index-4f2a.js:1:48213") of a
+	// frame resolved with a source map.
+	Generated string `json:"generated,omitempty"`
+}
+
+// String formats the frame like a V8 stack line.
+func (f StackFrame) String() string {
+	loc := fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	if f.Function == "" {
+		return "    at " + loc
+	}
+	return "    at " + f.Function + " (" + loc + ")"
+}
+
+var (
+	// "    at fn (https://host/app.js:1:2)", "    at https://host/app.js:1:2"
+	v8FrameRE = regexp.MustCompile(`^\s*at (?:(.*?) \()?(.+?):(\d+):(\d+)\)?\s*$`)
+	// "fn@https://host/app.js:1:2" (Firefox, Safari)
+	geckoFrameRE = regexp.MustCompile(`^\s*(.*?)@(.+?):(\d+):(\d+)\s*$`)
+)
+
+// parseStackFrame parses one line of a V8, SpiderMonkey or JavaScriptCore
+// stack trace.
+func parseStackFrame(line string) (StackFrame, bool) {
+	m := v8FrameRE.FindStringSubmatch(line)
+	if m == nil {
+		m = geckoFrameRE.FindStringSubmatch(line)
+	}
+	if m == nil {
+		return StackFrame{}, false
+	}
+	lineNo, _ := strconv.Atoi(m[3])
+	col, _ := strconv.Atoi(m[4])
+	return StackFrame{Function: m[1], File: m[2], Line: lineNo, Column: col}, true
+}
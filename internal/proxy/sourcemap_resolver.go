@@ -0,0 +1,438 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sourceMapTimeout bounds resolving one stack, including fetching maps.
+	sourceMapTimeout = 5 * time.Second
+	// sourceMapScriptTTL is how long a script's build is trusted before it
+	// is fetched again to see whether it was rebuilt.
+	sourceMapScriptTTL = 10 * time.Second
+	// maxSourceMaps is the number of parsed maps kept.
+	maxSourceMaps = 50
+	// maxSourceMapScripts is the number of scripts whose map is remembered.
+	maxSourceMapScripts = 500
+	// maxStackResolves is the number of stacks resolved at once; errors
+	// reported while all are busy are logged unresolved.
+	maxStackResolves = 4
+	// maxSourceMapSize limits scripts and maps read.
+	maxSourceMapSize = 64 << 20
+	// maxSourceMapWalk limits the files visited looking for a map in a build
+	// directory.
+	maxSourceMapWalk = 20000
+)
+
+// DefaultSourceMapDirs are the build directories, relative to the project,
+// searched for source maps that the served scripts don't reference.
+var DefaultSourceMapDirs = []string{"dist", "build", "out", ".next", ".output", "public"}
+
+// sourceMapFetcher fetches a same-origin URL through the proxy's upstreams.
+type sourceMapFetcher func(ctx context.Context, u *url.URL) ([]byte, http.Header, error)
+
+// SourceMapResolver maps bundled stack frames back to original sources.
+// Maps are found through the proxy, from the script's SourceMap header or
+// sourceMappingURL comment, or in the project's build directories for
+// builds with hidden source maps. Parsed maps are cached by build hash (the
+// hash of the script), so a rebuild is picked up and an unchanged build is
+// parsed once.
+type SourceMapResolver struct {
+	fetch sourceMapFetcher
+	root  string   // Project directory
+	dirs  []string // Build directories, relative to root
+
+	mu          sync.Mutex
+	scripts     map[string]scriptMap  // By script URL without query
+	scriptOrder []string              // Script URLs, oldest first
+	maps        map[string]*SourceMap // By build hash
+	order       []string              // Build hashes, oldest first
+}
+
+// scriptMap is the map found for a script; m is nil if it has none.
+type scriptMap struct {
+	m       *SourceMap
+	checked time.Time
+}
+
+// NewSourceMapResolver creates a resolver. dirs are relative to root; nil
+// uses DefaultSourceMapDirs.
+func NewSourceMapResolver(fetch sourceMapFetcher, root string, dirs []string) *SourceMapResolver {
+	if dirs == nil {
+		dirs = DefaultSourceMapDirs
+	}
+	return &SourceMapResolver{
+		fetch:   fetch,
+		root:    root,
+		dirs:    dirs,
+		scripts: make(map[string]scriptMap),
+		maps:    make(map[string]*SourceMap),
+	}
+}
+
+// ResolveStack resolves the frames of a stack trace to original sources.
+// Only scripts from the page's origin are looked up; other frames keep their
+// location. It returns the stack in V8 format and the resolved frames, or
+// false if no frame could be resolved.
+func (r *SourceMapResolver) ResolveStack(ctx context.Context, stack, pageURL string) (string, []StackFrame, bool) {
+	page, err := url.Parse(pageURL)
+	if err != nil || page.Host == "" {
+		return "", nil, false
+	}
+
+	var frames []StackFrame
+	var original []OriginalPosition // Per frame; Source is empty if unresolved
+	resolved := 0
+	for _, line := range strings.Split(stack, "\n") {
+		frame, ok := parseStackFrame(line)
+		if !ok {
+			continue
+		}
+		var pos OriginalPosition
+		if script, err := url.Parse(frame.File); err == nil && script.Host == page.Host {
+			if sm := r.mapFor(ctx, script); sm != nil {
+				if p, ok := sm.Lookup(frame.Line, frame.Column); ok {
+					pos = p
+					resolved++
+				}
+			}
+		}
+		frames = append(frames, frame)
+		original = append(original, pos)
+	}
+	if resolved == 0 {
+		return "", nil, false
+	}
+
+	lines := make([]string, len(frames))
+	for i := range frames {
+		if pos := original[i]; pos.Source != "" {
+			generated := fmt.Sprintf("%s:%d:%d", scriptName(frames[i].File), frames[i].Line, frames[i].Column)
+			frames[i].File, frames[i].Line, frames[i].Column = pos.Source, pos.Line, pos.Column
+			frames[i].Generated = generated
+			// A frame's original function name is the name at its caller's
+			// call site; minified names are meaningless
+			if i+1 < len(original) && original[i+1].Name != "" {
+				frames[i].Function = original[i+1].Name
+			}
+		}
+		lines[i] = frames[i].String()
+	}
+	return strings.Join(lines, "\n"), frames, true
+}
+
+// scriptName returns the file name of a script URL.
+func scriptName(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		return path.Base(u.Path)
+	}
+	return rawURL
+}
+
+// mapFor returns the source map of a script, or nil. Scripts are remembered
+// without their query, so cache-busting parameters don't grow the cache.
+func (r *SourceMapResolver) mapFor(ctx context.Context, script *url.URL) *SourceMap {
+	keyURL := *script
+	keyURL.RawQuery, keyURL.Fragment, keyURL.RawFragment = "", "", ""
+	key := keyURL.String()
+	r.mu.Lock()
+	entry, ok := r.scripts[key]
+	r.mu.Unlock()
+	if ok && time.Since(entry.checked) < sourceMapScriptTTL {
+		return entry.m
+	}
+
+	sm := r.loadMap(ctx, script)
+
+	r.mu.Lock()
+	if _, ok := r.scripts[key]; !ok {
+		r.scriptOrder = append(r.scriptOrder, key)
+	}
+	r.scripts[key] = scriptMap{m: sm, checked: time.Now()}
+	for len(r.scriptOrder) > maxSourceMapScripts {
+		delete(r.scripts, r.scriptOrder[0])
+		r.scriptOrder = r.scriptOrder[1:]
+	}
+	r.mu.Unlock()
+	return sm
+}
+
+// loadMap finds and parses the map of a script, using the cache when the
+// script's build was seen before.
+func (r *SourceMapResolver) loadMap(ctx context.Context, script *url.URL) *SourceMap {
+	body, header, err := r.fetch(ctx, script)
+	if err != nil {
+		// The dev server may be down; the build directory may still have it
+		sm, _ := r.findInDirs(script.Path)
+		return sm
+	}
+
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:8])
+	if sm := r.cached(hash); sm != nil {
+		return sm
+	}
+
+	var sm *SourceMap
+	if ref := sourceMapRef(body, header); ref != "" {
+		sm, _ = r.loadRef(ctx, script, ref)
+	}
+	if sm == nil {
+		sm, _ = r.findInDirs(script.Path)
+	}
+	if sm != nil {
+		r.store(hash, sm)
+	}
+	return sm
+}
+
+func (r *SourceMapResolver) cached(hash string) *SourceMap {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maps[hash]
+}
+
+func (r *SourceMapResolver) store(hash string, sm *SourceMap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.maps[hash]; !ok {
+		r.order = append(r.order, hash)
+	}
+	r.maps[hash] = sm
+	for len(r.order) > maxSourceMaps {
+		delete(r.maps, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+// sourceMapRef returns the map URL a script declares: the SourceMap header
+// or the last sourceMappingURL comment.
+func sourceMapRef(body []byte, header http.Header) string {
+	if ref := header.Get("SourceMap"); ref != "" {
+		return ref
+	}
+	if ref := header.Get("X-SourceMap"); ref != "" {
+		return ref
+	}
+	for _, marker := range []string{"//# sourceMappingURL=", "//@ sourceMappingURL="} {
+		if i := bytes.LastIndex(body, []byte(marker)); i >= 0 {
+			ref := body[i+len(marker):]
+			if end := bytes.IndexAny(ref, " \t\r\n*"); end >= 0 {
+				ref = ref[:end]
+			}
+			return string(ref)
+		}
+	}
+	return ""
+}
+
+// loadRef loads a map referenced by a script: an inline data URL or a URL
+// relative to the script, fetched through the proxy.
+func (r *SourceMapResolver) loadRef(ctx context.Context, script *url.URL, ref string) (*SourceMap, error) {
+	if strings.HasPrefix(ref, "data:") {
+		data, err := decodeDataURL(ref)
+		if err != nil {
+			return nil, err
+		}
+		return ParseSourceMap(data, path.Dir(script.Path))
+	}
+
+	refURL, err := script.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if refURL.Host != script.Host {
+		return nil, fmt.Errorf("source map %s is not on the page's origin", refURL)
+	}
+	data, _, err := r.fetch(ctx, refURL)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSourceMap(data, path.Dir(refURL.Path))
+}
+
+// decodeDataURL decodes a data: URL with a base64 or percent-encoded payload.
+func decodeDataURL(ref string) ([]byte, error) {
+	comma := strings.IndexByte(ref, ',')
+	if comma < 0 {
+		return nil, errors.New("invalid data URL")
+	}
+	meta, payload := ref[len("data:"):comma], ref[comma+1:]
+	if strings.HasSuffix(meta, ";base64") {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	s, err := url.PathUnescape(payload)
+	return []byte(s), err
+}
+
+// errSourceMapNotFound is returned when no build directory has the map.
+var errSourceMapNotFound = errors.New("source map not found")
+
+// findInDirs looks for "<script>.map" in the build directories: at the
+// script's URL path, then anywhere below the directory. The path comes from
+// the browser, so it is never allowed to leave the directory.
+func (r *SourceMapResolver) findInDirs(scriptPath string) (*SourceMap, error) {
+	scriptPath = path.Clean("/" + scriptPath)
+	if r.root == "" || scriptPath == "/" {
+		return nil, errSourceMapNotFound
+	}
+	name := path.Base(scriptPath) + ".map"
+	for _, dir := range r.dirs {
+		abs := dir
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(r.root, dir)
+		}
+		if file := filepath.Join(abs, filepath.FromSlash(scriptPath)) + ".map"; isWithin(abs, file) && isFile(file) {
+			return r.parseFile(file)
+		}
+		if file := findFile(abs, name); file != "" {
+			return r.parseFile(file)
+		}
+	}
+	return nil, errSourceMapNotFound
+}
+
+// parseFile parses a map file; sources are resolved relative to its
+// directory within the project.
+func (r *SourceMapResolver) parseFile(file string) (*SourceMap, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	base, err := filepath.Rel(r.root, filepath.Dir(file))
+	if err != nil || strings.HasPrefix(base, "..") {
+		base = filepath.Dir(file)
+	}
+	return ParseSourceMap(data, filepath.ToSlash(base))
+}
+
+// isWithin reports whether file is below dir.
+func isWithin(dir, file string) bool {
+	rel, err := filepath.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// findFile returns the first file called name below dir, skipping
+// dependencies.
+func findFile(dir, name string) string {
+	var found string
+	visited := 0
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		visited++
+		if visited > maxSourceMapWalk {
+			return fs.SkipAll
+		}
+		if d.IsDir() {
+			if d.Name() == "node_modules" || d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == name {
+			found = p
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// fetchUpstream fetches a URL of the page's origin from the upstream that
+// serves it, bypassing logging and chaos rules.
+func (ps *ProxyServer) fetchUpstream(ctx context.Context, u *url.URL) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rt := ps.matchRoute(req)
+	if rt == nil {
+		rt = ps.defaultRoute
+	}
+
+	p := u.Path
+	if rt.spec.StripPrefix {
+		if n, ok := rt.match(p); ok && n > 0 {
+			p = stripPath(p, n)
+		}
+	}
+	upstream := *rt.target
+	upstream.Path = strings.TrimSuffix(upstream.Path, "/") + p
+	upstream.RawPath = ""
+	upstream.RawQuery = u.RawQuery
+	req.URL = &upstream
+	req.Host = rt.target.Host
+	if rt.spec.HostHeader != "" {
+		req.Host = rt.spec.HostHeader
+	}
+
+	resp, err := ps.upstreamClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceMapSize))
+	return body, resp.Header, err
+}
+
+// resolveStackAsync resolves a stack off the WebSocket read loop, since an
+// unseen script can take a fetch and a build directory walk, and calls done
+// with the result. Errors without a stack, or reported while
+// maxStackResolves stacks are being resolved, are passed on unresolved.
+func (ps *ProxyServer) resolveStackAsync(stack, source string, line, column int, pageURL string, done func(string, []StackFrame)) {
+	if ps.sourceMaps == nil || (stack == "" && (source == "" || line <= 0)) {
+		done("", nil)
+		return
+	}
+	select {
+	case ps.stackResolves <- struct{}{}:
+	default:
+		done("", nil)
+		return
+	}
+	go func() {
+		defer func() { <-ps.stackResolves }()
+		done(ps.resolveStack(stack, source, line, column, pageURL))
+	}()
+}
+
+// resolveStack returns the stack (or the single location of an error
+// without one) resolved to original sources.
+func (ps *ProxyServer) resolveStack(stack, source string, line, column int, pageURL string) (string, []StackFrame) {
+	if stack == "" && source != "" && line > 0 {
+		stack = fmt.Sprintf("    at %s:%d:%d", source, line, column)
+	}
+	if stack == "" || ps.sourceMaps == nil {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sourceMapTimeout)
+	defer cancel()
+	resolved, frames, _ := ps.sourceMaps.ResolveStack(ctx, stack, pageURL)
+	return resolved, frames
+}
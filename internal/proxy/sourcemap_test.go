@@ -0,0 +1,281 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// encodeVLQ encodes source map segments, each as absolute
+// [genCol, source, line, column(, name)] values on one generated line per
+// entry of lines.
+func encodeVLQ(lines [][][]int) string {
+	var out strings.Builder
+	prev := make([]int, 5)
+	for i, segments := range lines {
+		if i > 0 {
+			out.WriteByte(';')
+		}
+		prev[0] = 0
+		for j, seg := range segments {
+			if j > 0 {
+				out.WriteByte(',')
+			}
+			for k, v := range seg {
+				delta := v - prev[k]
+				prev[k] = v
+				vlq := delta << 1
+				if delta < 0 {
+					vlq = (-delta << 1) | 1
+				}
+				for {
+					digit := vlq & 31
+					vlq >>= 5
+					if vlq > 0 {
+						digit |= 32
+					}
+					out.WriteByte(vlqChars[digit])
+					if vlq == 0 {
+						break
+					}
+				}
+			}
+		}
+	}
+	return out.String()
+}
+
+// testSourceMap is a map of a one-line bundle: loadCart from column 51,
+// calling fetchCart at column 101, whose body (in api.ts) is at column 151.
+func testSourceMap() []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"version": 3,
+		"sources": []string{"../../src/cart.ts", "webpack://shop/./src/api.ts"},
+		"names":   []string{"loadCart", "fetchCart"},
+		"mappings": encodeVLQ([][][]int{{
+			{0, 1, 0, 0},
+			{50, 0, 9, 2, 0},
+			{100, 0, 11, 4, 1},
+			{150, 1, 2, 8},
+		}}),
+	})
+	return data
+}
+
+func TestSourceMap_Lookup(t *testing.T) {
+	sm, err := ParseSourceMap(testSourceMap(), "/assets/js")
+	if err != nil {
+		t.Fatalf("ParseSourceMap: %v", err)
+	}
+
+	tests := []struct {
+		line, column int
+		want         OriginalPosition
+	}{
+		{1, 1, OriginalPosition{Source: "src/api.ts", Line: 1, Column: 1}},
+		{1, 51, OriginalPosition{Source: "src/cart.ts", Line: 10, Column: 3, Name: "loadCart"}},
+		{1, 120, OriginalPosition{Source: "src/cart.ts", Line: 12, Column: 5, Name: "fetchCart"}},
+		{1, 500, OriginalPosition{Source: "src/api.ts", Line: 3, Column: 9}},
+	}
+	for _, tt := range tests {
+		got, ok := sm.Lookup(tt.line, tt.column)
+		if !ok || got != tt.want {
+			t.Errorf("Lookup(%d, %d) = %+v, %v; want %+v", tt.line, tt.column, got, ok, tt.want)
+		}
+	}
+	if _, ok := sm.Lookup(2, 1); ok {
+		t.Error("expected no mapping past the last line")
+	}
+
+	// An index map offsets its sections
+	index, _ := json.Marshal(map[string]interface{}{
+		"version": 3,
+		"sections": []interface{}{
+			map[string]interface{}{"offset": map[string]int{"line": 0, "column": 0}, "map": json.RawMessage(testSourceMap())},
+			map[string]interface{}{"offset": map[string]int{"line": 5, "column": 0}, "map": json.RawMessage(testSourceMap())},
+		},
+	})
+	sm, err = ParseSourceMap(index, "/assets/js")
+	if err != nil {
+		t.Fatalf("ParseSourceMap(index): %v", err)
+	}
+	if got, ok := sm.Lookup(6, 120); !ok || got.Name != "fetchCart" || got.Line != 12 {
+		t.Errorf("index map Lookup = %+v, %v", got, ok)
+	}
+}
+
+func TestNormalizeSourcePath(t *testing.T) {
+	tests := []struct {
+		source, root, base, want string
+	}{
+		{"../../src/App.tsx", "", "/assets/js", "src/App.tsx"},
+		{"/src/App.tsx", "", "/assets", "src/App.tsx"},
+		{"webpack://my-app/./src/App.tsx", "", "/static/js", "src/App.tsx"},
+		{"App.tsx", "webpack://my-app/src/", "/static", "src/App.tsx"},
+		{"../src/main.ts", "", "dist", "src/main.ts"},
+	}
+	for _, tt := range tests {
+		if got := normalizeSourcePath(tt.source, tt.root, tt.base); got != tt.want {
+			t.Errorf("normalizeSourcePath(%q, %q, %q) = %q, want %q", tt.source, tt.root, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestParseStackFrame(t *testing.T) {
+	tests := []struct {
+		line string
+		want StackFrame
+	}{
+		{"    at a (http://localhost:8080/assets/index-4f2a.js:1:48213)", StackFrame{Function: "a", File: "http://localhost:8080/assets/index-4f2a.js", Line: 1, Column: 48213}},
+		{"    at http://localhost:8080/app.js:3:7", StackFrame{File: "http://localhost:8080/app.js", Line: 3, Column: 7}},
+		{"    at async Promise.all (index 0)", StackFrame{}},
+		{"b@http://localhost:8080/app.js:2:10", StackFrame{Function: "b", File: "http://localhost:8080/app.js", Line: 2, Column: 10}},
+		{"TypeError: x is undefined", StackFrame{}},
+	}
+	for _, tt := range tests {
+		got, ok := parseStackFrame(tt.line)
+		if ok != (tt.want.File != "") || got != tt.want {
+			t.Errorf("parseStackFrame(%q) = %+v, %v", tt.line, got, ok)
+		}
+	}
+}
+
+func TestSourceMapResolver_ThroughProxy(t *testing.T) {
+	var scriptFetches, mapFetches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/assets/js/app.js":
+			scriptFetches.Add(1)
+			w.Write([]byte("minified();\n//# sourceMappingURL=app.js.map"))
+		case "/assets/js/app.js.map":
+			mapFetches.Add(1)
+			w.Write(testSourceMap())
+		case "/assets/js/inline.js":
+			w.Write([]byte("minified();\n//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(testSourceMap())))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	ps, err := NewProxyServer(ProxyConfig{ID: "maps", TargetURL: upstream.URL, ListenPort: 0, Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+
+	page := "http://localhost:45000/cart"
+	stack := strings.Join([]string{
+		"TypeError: Cannot read properties of undefined (reading 'items')",
+		"    at a (http://localhost:45000/assets/js/app.js:1:160)",
+		"    at b (http://localhost:45000/assets/js/app.js:1:120)",
+		"    at https://cdn.example.com/vendor.js:1:10",
+	}, "\n")
+
+	resolved, frames := ps.resolveStack(stack, "", 0, 0, page)
+	want := strings.Join([]string{
+		"    at fetchCart (src/api.ts:3:9)",
+		"    at b (src/cart.ts:12:5)",
+		"    at https://cdn.example.com/vendor.js:1:10",
+	}, "\n")
+	if resolved != want {
+		t.Errorf("resolved stack:\n%s\nwant:\n%s", resolved, want)
+	}
+	if len(frames) != 3 || frames[0].Generated != "app.js:1:160" || frames[2].Generated != "" {
+		t.Errorf("unexpected frames %+v", frames)
+	}
+
+	// Cached for the build
+	ps.resolveStack(stack, "", 0, 0, page)
+	if scriptFetches.Load() != 1 || mapFetches.Load() != 1 {
+		t.Errorf("expected one fetch of the script and map, got %d and %d", scriptFetches.Load(), mapFetches.Load())
+	}
+
+	// An error without a stack resolves its location; inline maps work too
+	if _, frames := ps.resolveStack("", "http://localhost:45000/assets/js/inline.js", 1, 60, page); len(frames) != 1 || frames[0].File != "src/cart.ts" || frames[0].Line != 10 {
+		t.Errorf("unexpected inline frames %+v", frames)
+	}
+	// Other origins are never fetched
+	if resolved, _ := ps.resolveStack(stack, "", 0, 0, "http://other.test/"); resolved != "" {
+		t.Errorf("expected no resolution for another origin, got %s", resolved)
+	}
+}
+
+func TestSourceMapResolver_BuildDir(t *testing.T) {
+	// Hidden source maps: the served script has no reference
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("minified();"))
+	}))
+	defer upstream.Close()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "dist", "assets")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.3f9a.js.map"), testSourceMap(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := NewProxyServer(ProxyConfig{ID: "maps", TargetURL: upstream.URL, ListenPort: 0, Path: root})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	_, frames := ps.resolveStack("    at x (http://localhost:45000/js/main.3f9a.js:1:120)", "", 0, 0, "http://localhost:45000/")
+	// Found by name although the URL path differs; sources are relative to
+	// the map's directory
+	if len(frames) != 1 || frames[0].File != "src/cart.ts" || frames[0].Generated != "main.3f9a.js:1:120" {
+		t.Errorf("unexpected frames %+v", frames)
+	}
+}
+
+func TestSourceMapResolver_FindInDirsStaysInBuildDir(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "project")
+	if err := os.MkdirAll(filepath.Join(root, "dist"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A map outside the build directory, next to the project
+	if err := os.WriteFile(filepath.Join(dir, "secret.js.map"), testSourceMap(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewSourceMapResolver(nil, root, nil)
+	for _, p := range []string{"/../../secret.js", "../../secret.js", "/assets/../../../secret.js"} {
+		if sm, err := r.findInDirs(p); sm != nil || err == nil {
+			t.Errorf("findInDirs(%q) escaped the build directory", p)
+		}
+	}
+}
+
+func TestSourceMapResolver_ScriptCacheIgnoresQuery(t *testing.T) {
+	var fetches atomic.Int32
+	r := NewSourceMapResolver(func(ctx context.Context, u *url.URL) ([]byte, http.Header, error) {
+		fetches.Add(1)
+		return []byte("minified();"), nil, nil
+	}, "", nil)
+
+	for i := 0; i < maxSourceMapScripts+10; i++ {
+		u, _ := url.Parse(fmt.Sprintf("http://localhost:45000/app.js?v=%d", i))
+		r.mapFor(context.Background(), u)
+	}
+	if fetches.Load() != 1 || len(r.scripts) != 1 {
+		t.Errorf("fetches = %d, cached scripts = %d, want 1 and 1", fetches.Load(), len(r.scripts))
+	}
+
+	for i := 0; i < maxSourceMapScripts+10; i++ {
+		u, _ := url.Parse(fmt.Sprintf("http://localhost:45000/chunk-%d.js", i))
+		r.mapFor(context.Background(), u)
+	}
+	if len(r.scripts) != maxSourceMapScripts || len(r.scriptOrder) != maxSourceMapScripts {
+		t.Errorf("cached scripts = %d, want %d", len(r.scripts), maxSourceMapScripts)
+	}
+}
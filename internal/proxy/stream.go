@@ -0,0 +1,277 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/textutil"
+)
+
+// Streaming response kinds that are logged event by event.
+const (
+	StreamKindSSE    = "sse"    // text/event-stream
+	StreamKindNDJSON = "ndjson" // One JSON value per line
+)
+
+const (
+	// maxStreamEventPreview is the event data kept per stream_event entry.
+	maxStreamEventPreview = 2048
+	// maxStreamEventsLogged caps stream_event entries per response so one
+	// long stream can't push everything else out of the log; later events
+	// are only counted.
+	maxStreamEventsLogged = 500
+	// maxStreamPending is the most data held while waiting for the end of
+	// an event. Longer events are passed on unparsed.
+	maxStreamPending = 1024 * 1024
+)
+
+// streamKind returns the stream kind of a response, or "" if it isn't one.
+func streamKind(header http.Header) string {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		return StreamKindSSE
+	case "application/x-ndjson", "application/ndjson", "application/jsonl",
+		"application/x-jsonlines", "application/stream+json":
+		return StreamKindNDJSON
+	}
+	return ""
+}
+
+// streamTracker splits a streaming response into events as it is written,
+// logs each one, and applies sse_delay and sse_drop chaos rules. Without
+// event rules bytes pass straight through and events are parsed from a
+// copy; with them each event is held until complete, then delayed, dropped
+// or written. Every write is flushed so events reach the client at once.
+type streamTracker struct {
+	w         http.ResponseWriter
+	ctx       context.Context
+	logger    *TrafficLogger
+	chaos     *ChaosEngine
+	rules     []*ChaosRule // sse_delay and sse_drop rules for this request
+	requestID string
+	url       string
+	start     time.Time
+
+	info    StreamInfo
+	pending []byte // Start of an incomplete event
+}
+
+// newStreamTracker starts tracking a streaming response.
+func newStreamTracker(w http.ResponseWriter, r *http.Request, kind, requestID string, start time.Time,
+	logger *TrafficLogger, chaos *ChaosEngine, rules []*ChaosRule) *streamTracker {
+	return &streamTracker{
+		w:         w,
+		ctx:       r.Context(),
+		logger:    logger,
+		chaos:     chaos,
+		rules:     chaos.StreamEventRules(rules),
+		requestID: requestID,
+		url:       r.URL.String(),
+		start:     start,
+		info:      StreamInfo{Kind: kind, Open: true, FirstByte: time.Since(start)},
+	}
+}
+
+// Write passes stream data on to the client and logs complete events.
+func (st *streamTracker) Write(b []byte) (int, error) {
+	st.info.Bytes += int64(len(b))
+
+	if len(st.rules) == 0 {
+		if _, err := st.w.Write(b); err != nil {
+			return 0, err
+		}
+		st.flush()
+	}
+
+	st.pending = append(st.pending, b...)
+	for {
+		raw, rest, ok := st.nextEvent(st.pending)
+		if !ok {
+			break
+		}
+		st.pending = rest
+		if err := st.event(raw); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(st.pending) > maxStreamPending {
+		if len(st.rules) > 0 {
+			if _, err := st.w.Write(st.pending); err != nil {
+				return 0, err
+			}
+			st.flush()
+		}
+		st.pending = nil
+	}
+	return len(b), nil
+}
+
+// close handles data left when the stream ends and returns its summary.
+func (st *streamTracker) close() StreamInfo {
+	if len(st.pending) > 0 {
+		if st.info.Kind == StreamKindNDJSON && len(bytes.TrimSpace(st.pending)) > 0 {
+			// A final line without a newline is still a value
+			st.event(st.pending)
+		} else if len(st.rules) > 0 {
+			// An unterminated SSE event is never dispatched, but pass it on
+			st.w.Write(st.pending)
+			st.flush()
+		}
+		st.pending = nil
+	}
+	st.info.Open = false
+	return st.info
+}
+
+// nextEvent splits the first complete event off the pending data.
+func (st *streamTracker) nextEvent(buf []byte) (raw, rest []byte, ok bool) {
+	if st.info.Kind == StreamKindNDJSON {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			return buf[:i+1], buf[i+1:], true
+		}
+		return nil, buf, false
+	}
+	return nextSSEEvent(buf)
+}
+
+// event logs one raw event and, when chaos rules apply, decides whether and
+// when it reaches the client.
+func (st *streamTracker) event(raw []byte) error {
+	name, id, data, dispatched := st.parse(raw)
+
+	var note string
+	if len(st.rules) > 0 {
+		drop, delay := false, time.Duration(0)
+		if dispatched {
+			drop, delay = st.chaos.StreamEventAction(st.rules, name, data)
+		}
+		if drop {
+			note = "dropped"
+			st.info.Dropped++
+		} else {
+			if delay > 0 {
+				note = "delayed " + delay.String()
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-st.ctx.Done():
+					t.Stop()
+					return st.ctx.Err()
+				}
+			}
+			if _, err := st.w.Write(raw); err != nil {
+				return err
+			}
+			st.flush()
+		}
+	}
+
+	if !dispatched {
+		return nil
+	}
+	st.info.Events++
+	if st.info.Logged >= maxStreamEventsLogged {
+		return nil
+	}
+	st.info.Logged++
+
+	now := time.Now()
+	preview := data
+	if len(preview) > maxStreamEventPreview {
+		preview = textutil.Truncate(preview, maxStreamEventPreview) + "... [truncated]"
+	}
+	st.logger.LogStreamEvent(StreamEvent{
+		ID:        fmt.Sprintf("%s-ev-%d", st.requestID, st.info.Events),
+		RequestID: st.requestID,
+		URL:       st.url,
+		Timestamp: now,
+		Seq:       st.info.Events,
+		Event:     name,
+		EventID:   id,
+		Data:      preview,
+		Size:      len(raw),
+		Offset:    now.Sub(st.start),
+		Chaos:     note,
+	})
+	return nil
+}
+
+// parse extracts the fields of a raw event. dispatched is false for SSE
+// blocks a browser would not deliver, such as comments and keep-alives.
+func (st *streamTracker) parse(raw []byte) (name, id, data string, dispatched bool) {
+	if st.info.Kind == StreamKindNDJSON {
+		line := strings.TrimSpace(string(raw))
+		return "", "", line, line != ""
+	}
+	return parseSSEEvent(raw)
+}
+
+func (st *streamTracker) flush() {
+	if f, ok := st.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// nextSSEEvent splits the first complete SSE event, ended by a blank line,
+// off buf. Lines may end in \n, \r\n or \r.
+func nextSSEEvent(buf []byte) (raw, rest []byte, ok bool) {
+	lineStart := 0
+	for i := 0; i < len(buf); i++ {
+		c := buf[i]
+		if c != '\n' && c != '\r' {
+			continue
+		}
+		end := i + 1
+		if c == '\r' {
+			if end == len(buf) {
+				// Wait to see whether \n follows
+				return nil, buf, false
+			}
+			if buf[end] == '\n' {
+				end++
+			}
+		}
+		if i == lineStart {
+			return buf[:end], buf[end:], true
+		}
+		lineStart = end
+		i = end - 1
+	}
+	return nil, buf, false
+}
+
+// parseSSEEvent parses an SSE event block. Events without data aren't
+// dispatched, matching EventSource.
+func parseSSEEvent(raw []byte) (name, id, data string, dispatched bool) {
+	var dataLines []string
+	text := strings.ReplaceAll(strings.ReplaceAll(string(raw), "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			dataLines = append(dataLines, value)
+		case "id":
+			id = value
+		}
+	}
+	if dataLines == nil {
+		return "", "", "", false
+	}
+	if name == "" {
+		name = "message"
+	}
+	return name, id, strings.Join(dataLines, "\n"), true
+}
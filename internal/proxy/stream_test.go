@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextSSEEvent(t *testing.T) {
+	tests := []struct {
+		buf  string
+		raw  string
+		rest string
+		ok   bool
+	}{
+		{"data: a\n\ndata: b\n\n", "data: a\n\n", "data: b\n\n", true},
+		{"data: a\r\n\r\nrest", "data: a\r\n\r\n", "rest", true},
+		{"data: a\r\rrest", "data: a\r\r", "rest", true},
+		{"data: a\n", "", "data: a\n", false},
+		{"data: a\r\n\r", "", "data: a\r\n\r", false},
+		{"\n", "\n", "", true},
+	}
+	for _, tt := range tests {
+		raw, rest, ok := nextSSEEvent([]byte(tt.buf))
+		if ok != tt.ok || string(raw) != tt.raw || string(rest) != tt.rest {
+			t.Errorf("nextSSEEvent(%q) = %q, %q, %v", tt.buf, raw, rest, ok)
+		}
+	}
+}
+
+func TestParseSSEEvent(t *testing.T) {
+	name, id, data, ok := parseSSEEvent([]byte("event: update\nid: 7\ndata: {\"a\":1}\ndata:line two\n\n"))
+	if !ok || name != "update" || id != "7" || data != "{\"a\":1}\nline two" {
+		t.Errorf("unexpected event %q %q %q %v", name, id, data, ok)
+	}
+	if name, _, _, ok := parseSSEEvent([]byte("data: x\n\n")); !ok || name != "message" {
+		t.Errorf("expected the default event name, got %q", name)
+	}
+	for _, raw := range []string{": keep-alive\n\n", "event: ping\n\n", "retry: 1000\n\n"} {
+		if _, _, _, ok := parseSSEEvent([]byte(raw)); ok {
+			t.Errorf("expected %q not to be dispatched", raw)
+		}
+	}
+}
+
+func TestStreamKind(t *testing.T) {
+	tests := map[string]string{
+		"text/event-stream":                StreamKindSSE,
+		"text/event-stream; charset=utf-8": StreamKindSSE,
+		"application/x-ndjson":             StreamKindNDJSON,
+		"application/json":                 "",
+		"":                                 "",
+	}
+	for contentType, want := range tests {
+		h := http.Header{}
+		h.Set("Content-Type", contentType)
+		if got := streamKind(h); got != want {
+			t.Errorf("streamKind(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
+
+// startSSEProxy starts a proxy in front of handler.
+func startSSEProxy(t *testing.T, handler http.HandlerFunc) *ProxyServer {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+
+	ps, err := NewProxyServer(ProxyConfig{ID: "test-stream", TargetURL: upstream.URL, ListenPort: 0})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ps.Stop(context.Background()) })
+	<-ps.Ready()
+	return ps
+}
+
+func TestProxySSE(t *testing.T) {
+	release := make(chan struct{})
+	ps := startSSEProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\nevent: tick\nid: 1\ndata: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "event: tick\nid: 2\ndata: second\n\n")
+	})
+
+	resp, err := http.Get("http://" + ps.ListenAddr + "/events")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// The first event must arrive while the upstream is still holding the stream
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading stream: %v", err)
+			}
+			if line == "\n" {
+				if len(lines) > 0 && strings.HasPrefix(lines[0], ":") {
+					lines = nil
+					continue
+				}
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	if got := readEvent(); !strings.Contains(got, "data: first") {
+		t.Fatalf("unexpected first event %q", got)
+	}
+
+	open := ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeHTTP}})
+	if len(open) != 1 || open[0].HTTP.Stream == nil || !open[0].HTTP.Stream.Open || open[0].HTTP.Stream.Kind != StreamKindSSE {
+		t.Fatalf("expected an open stream entry, got %+v", open)
+	}
+	events := ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeStreamEvent}})
+	if len(events) != 1 || events[0].StreamEvent.Data != "first" || events[0].StreamEvent.EventID != "1" ||
+		events[0].StreamEvent.RequestID != open[0].HTTP.ID {
+		t.Fatalf("unexpected stream events %+v", events)
+	}
+
+	close(release)
+	if got := readEvent(); !strings.Contains(got, "data: second") {
+		t.Fatalf("unexpected second event %q", got)
+	}
+	resp.Body.Close()
+
+	// The HTTP entry is updated once the handler returns
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries := ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeHTTP}})
+		if len(entries) != 1 {
+			t.Fatalf("expected the stream entry to be updated in place, got %d entries", len(entries))
+		}
+		if s := entries[0].HTTP.Stream; !s.Open {
+			if s.Events != 2 || s.Logged != 2 {
+				t.Errorf("unexpected stream summary %+v", s)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream entry was never closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeStreamEvent}, URLPattern: "/events"})); n != 2 {
+		t.Errorf("expected 2 stream events, got %d", n)
+	}
+}
+
+func TestProxyStreamChaos(t *testing.T) {
+	ps := startSSEProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= 4; i++ {
+			name := "update"
+			if i%2 == 0 {
+				name = "heartbeat"
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %d\n\n", name, i)
+			w.(http.Flusher).Flush()
+		}
+	})
+	ps.ChaosEngine().SetConfig(&ChaosConfig{Enabled: true, Rules: []*ChaosRule{
+		{ID: "drop", Type: ChaosSSEDrop, Enabled: true, EventPattern: "^heartbeat$"},
+		{ID: "delay", Type: ChaosSSEDelay, Enabled: true, EventPattern: "^update$", MinLatencyMs: 50, MaxLatencyMs: 51},
+	}})
+
+	start := time.Now()
+	resp, err := http.Get("http://" + ps.ListenAddr + "/events")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body := new(strings.Builder)
+	bufio.NewReader(resp.Body).WriteTo(body)
+	resp.Body.Close()
+
+	if got := body.String(); got != "event: update\ndata: 1\n\nevent: update\ndata: 3\n\n" {
+		t.Errorf("unexpected stream body %q", got)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected two delayed events, took %v", elapsed)
+	}
+
+	stats := ps.ChaosEngine().GetStats()
+	if stats.EventsDropped != 2 || stats.EventsDelayed != 2 {
+		t.Errorf("unexpected chaos stats %+v", stats)
+	}
+	var notes []string
+	for _, e := range ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeStreamEvent}}) {
+		notes = append(notes, e.StreamEvent.Chaos)
+	}
+	if strings.Join(notes, ",") != "delayed 50ms,dropped,delayed 50ms,dropped" {
+		t.Errorf("unexpected chaos notes %q", notes)
+	}
+}
+
+func TestProxyNDJSON(t *testing.T) {
+	ps := startSSEProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "{\"n\":1}\n{\"n\":2}\n{\"n\":3}")
+	})
+
+	resp, err := http.Get("http://" + ps.ListenAddr + "/feed")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries := ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeHTTP}})
+		if len(entries) == 1 && !entries[0].HTTP.Stream.Open {
+			if entries[0].HTTP.Stream.Events != 3 {
+				t.Errorf("expected 3 NDJSON events, got %+v", entries[0].HTTP.Stream)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream entry was never closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
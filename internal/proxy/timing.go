@@ -0,0 +1,242 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxServerTimingMetrics caps the Server-Timing metrics kept per response.
+const maxServerTimingMetrics = 20
+
+// TimingInfo breaks the duration of a proxied request into phases, so a
+// slow backend can be told apart from injected chaos or a slow client.
+type TimingInfo struct {
+	Chaos    time.Duration `json:"chaos,omitempty"`   // Delay injected by chaos rules before the upstream call
+	DNS      time.Duration `json:"dns,omitempty"`     // Name lookup
+	Connect  time.Duration `json:"connect,omitempty"` // TCP connect
+	TLS      time.Duration `json:"tls,omitempty"`     // TLS handshake
+	TTFB     time.Duration `json:"ttfb"`              // Request written to first response byte
+	Transfer time.Duration `json:"transfer"`          // First to last byte of the body, paced by the client
+	Upstream time.Duration `json:"upstream"`          // Connection start to the end of the body
+	Reused   bool          `json:"reused,omitempty"`  // Connection was reused from the pool
+
+	ServerTiming []ServerTimingMetric `json:"server_timing,omitempty"` // From the Server-Timing header
+}
+
+// ServerTimingMetric is one metric of a Server-Timing response header.
+type ServerTimingMetric struct {
+	Name        string  `json:"name"`
+	DurationMs  float64 `json:"dur,omitempty"`
+	Description string  `json:"desc,omitempty"`
+}
+
+// upstreamTimer records when each phase of an upstream request happens.
+// Trace hooks may run on transport goroutines, hence the lock.
+type upstreamTimer struct {
+	mu sync.Mutex
+
+	chaosFrom    time.Time // Chaos transport started applying rules
+	getConn      time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+	reused       bool
+}
+
+// upstreamTimerKey is the request context key for the upstream timer.
+type upstreamTimerKey struct{}
+
+// withUpstreamTimer attaches a timer to the request for the transport to fill in.
+func withUpstreamTimer(r *http.Request) (*http.Request, *upstreamTimer) {
+	timer := &upstreamTimer{}
+	return r.WithContext(context.WithValue(r.Context(), upstreamTimerKey{}, timer)), timer
+}
+
+// requestUpstreamTimer returns the timer attached by withUpstreamTimer.
+func requestUpstreamTimer(ctx context.Context) *upstreamTimer {
+	t, _ := ctx.Value(upstreamTimerKey{}).(*upstreamTimer)
+	return t
+}
+
+// mark records now in *field.
+func (t *upstreamTimer) mark(field *time.Time) {
+	t.mu.Lock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// markChaos records that chaos rules start holding the request. Safe on a
+// nil timer.
+func (t *upstreamTimer) markChaos() {
+	if t != nil {
+		t.mark(&t.chaosFrom)
+	}
+}
+
+// timeBody wraps a response body to record when its transfer ends. Safe on
+// a nil timer.
+func (t *upstreamTimer) timeBody(resp *http.Response, err error) (*http.Response, error) {
+	if t != nil && resp != nil && resp.Body != nil {
+		resp.Body = &timedBody{ReadCloser: resp.Body, timer: t}
+	}
+	return resp, err
+}
+
+// trace returns the request with httptrace hooks that fill in the timer.
+func (t *upstreamTimer) trace(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) { t.mark(&t.getConn) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { t.mark(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { t.mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// info computes the phase durations. Phases that didn't happen are zero.
+func (t *upstreamTimer) info() *TimingInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.getConn.IsZero() {
+		return nil
+	}
+
+	span := func(start, end time.Time) time.Duration {
+		if start.IsZero() || end.IsZero() || end.Before(start) {
+			return 0
+		}
+		return end.Sub(start)
+	}
+	end := t.bodyDone
+	if end.IsZero() {
+		end = t.firstByte
+	}
+	sent := t.wroteRequest
+	if sent.IsZero() {
+		sent = t.getConn
+	}
+	return &TimingInfo{
+		Chaos:    span(t.chaosFrom, t.getConn),
+		DNS:      span(t.dnsStart, t.dnsDone),
+		Connect:  span(t.connectStart, t.connectDone),
+		TLS:      span(t.tlsStart, t.tlsDone),
+		TTFB:     span(sent, t.firstByte),
+		Transfer: span(t.firstByte, t.bodyDone),
+		Upstream: span(t.getConn, end),
+		Reused:   t.reused,
+	}
+}
+
+// logTiming returns the timing for a log entry, with the Server-Timing
+// metrics of the response. It is nil if the upstream was never called.
+func (t *upstreamTimer) logTiming(header http.Header) *TimingInfo {
+	info := t.info()
+	if metrics := parseServerTiming(header.Values("Server-Timing")); len(metrics) > 0 {
+		if info == nil {
+			info = &TimingInfo{}
+		}
+		info.ServerTiming = metrics
+	}
+	return info
+}
+
+// timedBody marks the end of the body transfer when the body is exhausted
+// or closed.
+type timedBody struct {
+	io.ReadCloser
+	timer *upstreamTimer
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.timer.mark(&b.timer.bodyDone)
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.timer.mark(&b.timer.bodyDone)
+	return b.ReadCloser.Close()
+}
+
+// parseServerTiming parses Server-Timing header values, e.g.
+// `db;dur=53.2;desc="Query", cache;desc=miss`.
+func parseServerTiming(values []string) []ServerTimingMetric {
+	var metrics []ServerTimingMetric
+	for _, value := range values {
+		for _, part := range splitUnquoted(value, ',') {
+			params := splitUnquoted(part, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+			m := ServerTimingMetric{Name: name}
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				val = strings.TrimSpace(val)
+				if unquoted, err := strconv.Unquote(val); err == nil && strings.HasPrefix(val, `"`) {
+					val = unquoted
+				}
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					if d, err := strconv.ParseFloat(val, 64); err == nil && m.DurationMs == 0 {
+						m.DurationMs = d
+					}
+				case "desc":
+					if m.Description == "" {
+						m.Description = val
+					}
+				}
+			}
+			metrics = append(metrics, m)
+			if len(metrics) == maxServerTimingMetrics {
+				return metrics
+			}
+		}
+	}
+	return metrics
+}
+
+// splitUnquoted splits s at sep outside double-quoted strings.
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	got := parseServerTiming([]string{
+		`db;dur=53.2;desc="Query, users", cache;desc=miss`,
+		`total;dur=120`,
+		`;dur=1`,
+	})
+	want := []ServerTimingMetric{
+		{Name: "db", DurationMs: 53.2, Description: "Query, users"},
+		{Name: "cache", Description: "miss"},
+		{Name: "total", DurationMs: 120},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseServerTiming = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("metric %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestProxyTiming(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Header().Set("Server-Timing", `db;dur=25;desc="Users"`)
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	ps, err := NewProxyServer(ProxyConfig{ID: "test-timing", TargetURL: upstream.URL, ListenPort: 0})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ps.Stop(context.Background())
+	<-ps.Ready()
+
+	get := func(path string) {
+		resp, err := http.Get("http://" + ps.ListenAddr + path)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	get("/plain")
+	ps.ChaosEngine().SetConfig(&ChaosConfig{Enabled: true, Rules: []*ChaosRule{
+		{ID: "slow", Type: ChaosLatency, Enabled: true, URLPattern: "/slow", MinLatencyMs: 50, MaxLatencyMs: 51},
+	}})
+	get("/slow")
+
+	entries := ps.Logger().Query(LogFilter{Types: []LogEntryType{LogTypeHTTP}})
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		timing := e.HTTP.Timing
+		if timing == nil {
+			t.Fatalf("%s: no timing", e.HTTP.URL)
+		}
+		if timing.TTFB < 30*time.Millisecond || timing.Upstream < timing.TTFB || timing.Upstream > e.HTTP.Duration {
+			t.Errorf("%s: unexpected upstream timing %+v (duration %v)", e.HTTP.URL, timing, e.HTTP.Duration)
+		}
+		if len(timing.ServerTiming) != 1 || timing.ServerTiming[0] != (ServerTimingMetric{Name: "db", DurationMs: 25, Description: "Users"}) {
+			t.Errorf("%s: unexpected server timing %+v", e.HTTP.URL, timing.ServerTiming)
+		}
+	}
+	if chaos := entries[0].HTTP.Timing.Chaos; chaos != 0 {
+		t.Errorf("expected no chaos delay without rules, got %v", chaos)
+	}
+	if chaos := entries[1].HTTP.Timing.Chaos; chaos < 50*time.Millisecond {
+		t.Errorf("expected the injected latency to be reported as chaos, got %v", chaos)
+	}
+	if upstream := entries[1].HTTP.Timing.Upstream; upstream >= 80*time.Millisecond {
+		t.Errorf("expected upstream time to exclude the chaos delay, got %v", upstream)
+	}
+
+	groups := AggregateHTTP(entries, []string{GroupByPath})
+	for _, g := range groups {
+		if g.UpstreamP95Ms < 30 {
+			t.Errorf("%v: expected upstream percentiles, got %+v", g.Key, g)
+		}
+		if g.Key[GroupByPath] == "/slow" && g.ChaosAvgMs < 50 {
+			t.Errorf("expected chaos time for /slow, got %+v", g)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/certs"
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/lanshare"
+)
+
+// httpsHosts returns the host names and addresses the HTTPS listener's
+// certificate must cover for the proxy's bind address.
+func (ps *ProxyServer) httpsHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if machine, err := os.Hostname(); err == nil && machine != "" {
+		hosts = append(hosts, machine, strings.ToLower(machine)+".local")
+	}
+
+	switch ip := net.ParseIP(ps.BindAddress); {
+	case ip == nil:
+	case ip.IsUnspecified():
+		// Reachable from the network, so cover every LAN address
+		if addrs, err := lanshare.Addresses(); err == nil {
+			hosts = append(hosts, lanshare.Hosts(addrs)...)
+		}
+	case !ip.IsLoopback():
+		hosts = append(hosts, ps.BindAddress)
+	}
+	return hosts
+}
+
+// startHTTPS opens the TLS listener next to the HTTP one. It serves the same
+// handler, with certificates from the local agnt CA and HTTP/2 for browsers.
+// Must be called with ps.mu held.
+func (ps *ProxyServer) startHTTPS(ctx context.Context, handler http.Handler) error {
+	ca, err := certs.LoadOrCreate(ps.caDir)
+	if err != nil {
+		return fmt.Errorf("failed to load local CA: %w", err)
+	}
+	tlsConfig, err := ca.TLSConfig(ps.httpsHosts()...)
+	if err != nil {
+		return fmt.Errorf("failed to issue certificate: %w", err)
+	}
+
+	addr := net.JoinHostPort(ps.BindAddress, strconv.Itoa(ps.httpsPort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil && isAddressInUse(err) {
+		debug.Log("proxy", "HTTPS address %s in use, trying auto-assign", addr)
+		listener, err = net.Listen("tcp", net.JoinHostPort(ps.BindAddress, "0"))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTPS on %s: %w", addr, err)
+	}
+	ps.HTTPSAddr = listener.Addr().String()
+	ps.caCertPath = ca.CertPath()
+
+	// Rewrite target URLs to https://<host the browser used>, and tell the
+	// upstream the original request was secure.
+	tlsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), originKey{}, [2]string{"https", r.Host})
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	// ServeTLS enables HTTP/2 via ALPN. WebSocket upgrades still arrive over
+	// HTTP/1.1 because extended CONNECT is not advertised.
+	server := &http.Server{
+		Handler:           tlsHandler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(l net.Listener) context.Context {
+			return ctx
+		},
+	}
+	ps.httpsServer = server
+
+	go func() {
+		if err := server.ServeTLS(listener, "", ""); err != nil && err != http.ErrServerClosed {
+			debug.Error("proxy", "HTTPS listener for %s stopped: %v", ps.ID, err)
+			ps.lastError.Store(fmt.Sprintf("HTTPS listener stopped: %v", err))
+		}
+	}()
+
+	debug.Log("proxy", "HTTPS for %s on %s", ps.ID, ps.HTTPSAddr)
+	return nil
+}
+
+// stopHTTPS shuts down the TLS listener, if any. Must be called with ps.mu held.
+func (ps *ProxyServer) stopHTTPS(ctx context.Context) {
+	if ps.httpsServer == nil {
+		return
+	}
+	_ = ps.httpsServer.Shutdown(ctx)
+	ps.httpsServer = nil
+}
+
+// HTTPSURL returns the local HTTPS URL of the proxy, or "" if HTTPS is off.
+func (ps *ProxyServer) HTTPSURL() string {
+	if ps.HTTPSAddr == "" {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(ps.HTTPSAddr)
+	if err != nil {
+		return ""
+	}
+	return "https://localhost:" + port
+}
+
+// HTTPSEnabled reports whether the proxy serves HTTPS.
+func (ps *ProxyServer) HTTPSEnabled() bool {
+	return ps.https
+}
+
+// HTTPSPort returns the bound HTTPS port, or the configured port if the
+// listener is not running. Restarts reuse it so HTTPS URLs stay stable.
+func (ps *ProxyServer) HTTPSPort() int {
+	if _, port, err := net.SplitHostPort(ps.HTTPSAddr); err == nil {
+		if n, err := strconv.Atoi(port); err == nil {
+			return n
+		}
+	}
+	return ps.httpsPort
+}
+
+// CACertPath returns the path of the CA certificate the HTTPS listener's
+// certificate was issued from, or "" if HTTPS is off.
+func (ps *ProxyServer) CACertPath() string {
+	return ps.caCertPath
+}
+
+// secureOrigin reports whether browsers treat the origin as a secure context:
+// HTTPS, or plain HTTP on this machine.
+func secureOrigin(scheme, host string) bool {
+	if scheme == "https" {
+		return true
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return isLoopbackHost(strings.Trim(host, "[]"))
+}
+
+// rewriteCookieSecure adapts a Set-Cookie header to the security of the
+// origin the browser used. Over HTTPS, SameSite=None cookies get the Secure
+// attribute browsers require. On insecure origins (e.g. a LAN address over
+// HTTP) Secure cookies would be dropped, so Secure is removed and
+// SameSite=None, which requires Secure, becomes Lax.
+func rewriteCookieSecure(cookie string, secure bool) string {
+	parts := strings.Split(cookie, ";")
+	hasSecure, sameSiteNone := false, -1
+	for i, part := range parts[1:] {
+		lower := strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case lower == "secure":
+			hasSecure = true
+		case strings.HasPrefix(lower, "samesite=") && strings.TrimSpace(strings.TrimPrefix(lower, "samesite=")) == "none":
+			sameSiteNone = i + 1
+		}
+	}
+
+	if secure {
+		if sameSiteNone < 0 || hasSecure {
+			return cookie
+		}
+		return cookie + "; Secure"
+	}
+
+	if !hasSecure && sameSiteNone < 0 {
+		return cookie
+	}
+	out := []string{parts[0]}
+	for i, part := range parts[1:] {
+		switch {
+		case strings.EqualFold(strings.TrimSpace(part), "secure"):
+			continue
+		case i+1 == sameSiteNone:
+			out = append(out, " SameSite=Lax")
+		default:
+			out = append(out, part)
+		}
+	}
+	return strings.Join(out, ";")
+}
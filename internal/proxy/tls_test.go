@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRewriteCookieSecure(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		secure bool
+		want   string
+	}{
+		{"https keeps secure", "sid=1; Path=/; Secure; SameSite=None", true, "sid=1; Path=/; Secure; SameSite=None"},
+		{"https adds secure for SameSite=None", "sid=1; Path=/; SameSite=None", true, "sid=1; Path=/; SameSite=None; Secure"},
+		{"https leaves lax cookies", "sid=1; Path=/; SameSite=Lax", true, "sid=1; Path=/; SameSite=Lax"},
+		{"http strips secure", "sid=1; Path=/; Secure; HttpOnly", false, "sid=1; Path=/; HttpOnly"},
+		{"http downgrades SameSite=None", "sid=1; secure; samesite=none", false, "sid=1; SameSite=Lax"},
+		{"http leaves plain cookies", "prefs=dark; Path=/", false, "prefs=dark; Path=/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteCookieSecure(tt.cookie, tt.secure); got != tt.want {
+				t.Errorf("rewriteCookieSecure(%q, %v) = %q, want %q", tt.cookie, tt.secure, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecureOrigin(t *testing.T) {
+	tests := []struct {
+		scheme, host string
+		want         bool
+	}{
+		{"https", "192.168.1.20:9443", true},
+		{"http", "localhost:8080", true},
+		{"http", "127.0.0.1:8080", true},
+		{"http", "[::1]:8080", true},
+		{"http", "192.168.1.20:8080", false},
+		{"http", "myapp.test", false},
+	}
+	for _, tt := range tests {
+		if got := secureOrigin(tt.scheme, tt.host); got != tt.want {
+			t.Errorf("secureOrigin(%q, %q) = %v, want %v", tt.scheme, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestProxyHTTPS(t *testing.T) {
+	var upstreamURL string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1", Path: "/", SameSite: http.SameSiteNoneMode})
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head></head><body><a href="`+upstreamURL+`/next">next</a>`+
+			` proto=`+r.Header.Get("X-Forwarded-Proto")+
+			` port=`+r.Header.Get("X-Forwarded-Port")+
+			` forwarded=`+r.Header.Get("Forwarded")+`</body></html>`)
+	}))
+	defer upstream.Close()
+	upstreamURL = upstream.URL
+
+	ps, err := NewProxyServer(ProxyConfig{
+		ID:         "test-https",
+		TargetURL:  upstream.URL,
+		ListenPort: 0,
+		HTTPS:      true,
+		HTTPSPort:  0,
+		CADir:      t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewProxyServer: %v", err)
+	}
+	if err := ps.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ps.Stop(context.Background())
+	<-ps.Ready()
+
+	httpsURL := ps.HTTPSURL()
+	if !strings.HasPrefix(httpsURL, "https://localhost:") {
+		t.Fatalf("unexpected HTTPS URL %q", httpsURL)
+	}
+	if ps.Stats().HTTPSURL != httpsURL {
+		t.Errorf("expected HTTPS URL in stats")
+	}
+
+	caPEM, err := os.ReadFile(ps.CACertPath())
+	if err != nil {
+		t.Fatalf("read CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+
+	resp, err := client.Get(httpsURL + "/")
+	if err != nil {
+		t.Fatalf("request over HTTPS: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+	_, port, _ := strings.Cut(strings.TrimPrefix(httpsURL, "https://"), ":")
+	for _, want := range []string{
+		`href="` + httpsURL + `/next"`,
+		"proto=https",
+		"port=" + port,
+		`forwarded=for="127.0.0.1";host="localhost:` + port + `";proto=https`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in body, got %s", want, body)
+		}
+	}
+	if cookie := resp.Header.Get("Set-Cookie"); !strings.Contains(cookie, "Secure") {
+		t.Errorf("expected SameSite=None cookie to be Secure over HTTPS, got %q", cookie)
+	}
+
+	// The injected script reconnects over wss on HTTPS pages
+	dialer := websocket.Dialer{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	wsURL := "wss://localhost:" + port + "/__devtool_metrics?token=" + ps.wsToken
+	conn, _, err := dialer.Dial(wsURL, http.Header{"Origin": {httpsURL}})
+	if err != nil {
+		t.Fatalf("wss dial: %v", err)
+	}
+	conn.Close()
+
+	ps.Stop(context.Background())
+	if _, err := client.Get(httpsURL + "/"); err == nil {
+		t.Error("expected HTTPS listener to stop with the proxy")
+	}
+}
@@ -1,58 +1,61 @@
 package proxy
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"os/exec"
-	"regexp"
-	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/internal/tunnel"
 )
 
 // TunnelManager manages a tunnel process alongside a proxy.
+// It adapts a protocol.TunnelConfig to the shared tunnel package, which
+// handles provider commands, health checks and automatic restarts.
 type TunnelManager struct {
 	config    *protocol.TunnelConfig
 	proxyPort int
-	publicURL atomic.Value // stores string
-	running   atomic.Bool
-	cmd       *exec.Cmd
-	cancel    context.CancelFunc
+	tunnel    *tunnel.Tunnel
+	onURL     func(url string)
 	mu        sync.Mutex
-	output    []string // recent output lines for debugging
-	outputMu  sync.RWMutex
-}
-
-// Common patterns to extract public URLs from tunnel output
-var tunnelURLPatterns = []*regexp.Regexp{
-	// ngrok patterns
-	regexp.MustCompile(`Forwarding\s+(https?://[^\s]+)\s+->`),
-	regexp.MustCompile(`url=(https?://[^\s]+)`),
-	regexp.MustCompile(`https?://[a-zA-Z0-9-]+\.ngrok(?:-free)?\.(?:app|io)[^\s]*`),
-	// cloudflared patterns
-	regexp.MustCompile(`https?://[a-zA-Z0-9-]+\.trycloudflare\.com[^\s]*`),
-	regexp.MustCompile(`\|\s+(https?://[^\s|]+)`),
-	// tailscale funnel patterns (https://machine.tailnet.ts.net)
-	regexp.MustCompile(`(https://[a-zA-Z0-9-]+\.[a-zA-Z0-9-]+\.ts\.net[^\s]*)`),
-	// localtunnel patterns
-	regexp.MustCompile(`your url is:\s*(https?://[^\s]+)`),
-	// Generic https URL pattern (fallback)
-	regexp.MustCompile(`(https://[a-zA-Z0-9][a-zA-Z0-9-]*\.[a-zA-Z0-9.-]+[^\s]*)`),
 }
 
 // NewTunnelManager creates a new tunnel manager.
 func NewTunnelManager(config *protocol.TunnelConfig, proxyPort int) *TunnelManager {
-	tm := &TunnelManager{
+	return &TunnelManager{
 		config:    config,
 		proxyPort: proxyPort,
-		output:    make([]string, 0, 100),
 	}
-	tm.publicURL.Store("")
-	return tm
+}
+
+// OnURL sets a callback invoked whenever the tunnel reports a public URL,
+// including new URLs after automatic restarts. Must be called before Start.
+func (tm *TunnelManager) OnURL(fn func(url string)) {
+	tm.mu.Lock()
+	tm.onURL = fn
+	tm.mu.Unlock()
+}
+
+// tunnelConfig converts the protocol configuration to a tunnel.Config.
+func (tm *TunnelManager) tunnelConfig() tunnel.Config {
+	return tunnel.Config{
+		Provider:    tunnel.Provider(tm.config.Provider),
+		LocalPort:   tm.proxyPort,
+		Command:     tm.config.Command,
+		Args:        tm.config.Args,
+		AuthToken:   tm.config.AuthToken,
+		Region:      tm.config.Region,
+		SSHHost:     tm.config.SSHHost,
+		AutoRestart: true,
+		// Probe the devtool endpoint so health does not depend on the upstream app
+		HealthPath: "/__devtool_metrics",
+	}
+}
+
+// buildCommand returns the tunnel command for the current configuration.
+func (tm *TunnelManager) buildCommand() (string, []string, error) {
+	return tunnel.BuildCommand(tm.tunnelConfig())
 }
 
 // Start starts the tunnel process.
@@ -60,232 +63,103 @@ func (tm *TunnelManager) Start(ctx context.Context) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	if tm.running.Load() {
+	if tm.isRunningLocked() {
 		return fmt.Errorf("tunnel already running")
 	}
 
-	// Build the command based on provider
-	cmdName, args, err := tm.buildCommand()
-	if err != nil {
+	if _, _, err := tm.buildCommand(); err != nil {
 		return err
 	}
 
-	// Create cancellable context
-	tunnelCtx, cancel := context.WithCancel(ctx)
-	tm.cancel = cancel
-
-	// Create command
-	tm.cmd = exec.CommandContext(tunnelCtx, cmdName, args...)
-
-	// Capture stdout and stderr
-	stdout, err := tm.cmd.StdoutPipe()
-	if err != nil {
-		cancel()
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	stderr, err := tm.cmd.StderrPipe()
-	if err != nil {
-		cancel()
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	t := tunnel.New(tm.tunnelConfig())
+	if tm.onURL != nil {
+		t.OnURL(tm.onURL)
 	}
-
-	// Start the process
-	if err := tm.cmd.Start(); err != nil {
-		cancel()
+	if err := t.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start tunnel: %w", err)
 	}
-
-	tm.running.Store(true)
-
-	// Monitor output for URL extraction
-	go tm.monitorOutput(bufio.NewScanner(stdout))
-	go tm.monitorOutput(bufio.NewScanner(stderr))
-
-	// Wait for process to exit
-	go func() {
-		tm.cmd.Wait()
-		tm.running.Store(false)
-	}()
-
+	tm.tunnel = t
 	return nil
 }
 
-// buildCommand builds the tunnel command based on provider.
-func (tm *TunnelManager) buildCommand() (string, []string, error) {
-	switch strings.ToLower(tm.config.Provider) {
-	case "ngrok":
-		return tm.buildNgrokCommand()
-	case "cloudflared", "cloudflare":
-		return tm.buildCloudflaredCommand()
-	case "tailscale":
-		return tm.buildTailscaleCommand()
-	case "custom":
-		return tm.buildCustomCommand()
-	default:
-		return "", nil, fmt.Errorf("unknown tunnel provider: %s", tm.config.Provider)
-	}
-}
-
-func (tm *TunnelManager) buildNgrokCommand() (string, []string, error) {
-	args := []string{"http", fmt.Sprintf("%d", tm.proxyPort)}
-
-	if tm.config.AuthToken != "" {
-		args = append(args, "--authtoken", tm.config.AuthToken)
-	}
+// Stop stops the tunnel process.
+func (tm *TunnelManager) Stop() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
-	if tm.config.Region != "" {
-		args = append(args, "--region", tm.config.Region)
+	if !tm.isRunningLocked() {
+		return nil
 	}
 
-	// Add any extra args
-	args = append(args, tm.config.Args...)
-
-	return "ngrok", args, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return tm.tunnel.Stop(ctx)
 }
 
-func (tm *TunnelManager) buildCloudflaredCommand() (string, []string, error) {
-	args := []string{"tunnel", "--url", fmt.Sprintf("http://localhost:%d", tm.proxyPort)}
-
-	// Add any extra args
-	args = append(args, tm.config.Args...)
-
-	return "cloudflared", args, nil
-}
-
-func (tm *TunnelManager) buildTailscaleCommand() (string, []string, error) {
-	// tailscale funnel exposes a local port via Tailscale Funnel
-	// Usage: tailscale funnel <port>
-	// Or: tailscale funnel --bg <port> (background mode, but we manage the process)
-	//
-	// The funnel URL is typically: https://<machine>.<tailnet>.ts.net
-	// Tailscale will output the URL when funnel starts
-
-	args := []string{"funnel", fmt.Sprintf("%d", tm.proxyPort)}
-
-	// Add any extra args (e.g., --bg for background, --https for custom port)
-	args = append(args, tm.config.Args...)
-
-	return "tailscale", args, nil
-}
-
-func (tm *TunnelManager) buildCustomCommand() (string, []string, error) {
-	if tm.config.Command == "" {
-		return "", nil, fmt.Errorf("custom tunnel requires command to be set")
-	}
-
-	// Replace {{PORT}} placeholder in command and args
-	cmd := strings.ReplaceAll(tm.config.Command, "{{PORT}}", fmt.Sprintf("%d", tm.proxyPort))
-
-	args := make([]string, len(tm.config.Args))
-	for i, arg := range tm.config.Args {
-		args[i] = strings.ReplaceAll(arg, "{{PORT}}", fmt.Sprintf("%d", tm.proxyPort))
+// PublicURL returns the detected public URL.
+func (tm *TunnelManager) PublicURL() string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.tunnel == nil {
+		return ""
 	}
-
-	return cmd, args, nil
+	return tm.tunnel.PublicURL()
 }
 
-// monitorOutput monitors tunnel output for URL extraction.
-func (tm *TunnelManager) monitorOutput(scanner *bufio.Scanner) {
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Store recent output for debugging
-		tm.outputMu.Lock()
-		tm.output = append(tm.output, line)
-		if len(tm.output) > 100 {
-			tm.output = tm.output[1:]
-		}
-		tm.outputMu.Unlock()
-
-		// Try to extract URL if we don't have one yet
-		if tm.publicURL.Load().(string) == "" {
-			if url := tm.extractURL(line); url != "" {
-				tm.publicURL.Store(url)
-			}
-		}
-	}
+// IsRunning returns whether the tunnel is running.
+func (tm *TunnelManager) IsRunning() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.isRunningLocked()
 }
 
-// extractURL tries to extract a public URL from a line of output.
-func (tm *TunnelManager) extractURL(line string) string {
-	for _, pattern := range tunnelURLPatterns {
-		if matches := pattern.FindStringSubmatch(line); len(matches) > 0 {
-			// Return the captured group if present, otherwise the full match
-			if len(matches) > 1 {
-				return matches[1]
-			}
-			return matches[0]
-		}
+func (tm *TunnelManager) isRunningLocked() bool {
+	if tm.tunnel == nil {
+		return false
+	}
+	select {
+	case <-tm.tunnel.Done():
+		return false
+	default:
+		return true
 	}
-	return ""
 }
 
-// Stop stops the tunnel process.
-func (tm *TunnelManager) Stop() error {
+// Info returns the tunnel status, or nil if the tunnel was never started.
+func (tm *TunnelManager) Info() *tunnel.TunnelInfo {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-
-	if !tm.running.Load() {
+	if tm.tunnel == nil {
 		return nil
 	}
-
-	if tm.cancel != nil {
-		tm.cancel()
-	}
-
-	// Give it a moment to exit gracefully
-	done := make(chan struct{})
-	go func() {
-		if tm.cmd != nil && tm.cmd.Process != nil {
-			tm.cmd.Wait()
-		}
-		close(done)
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(5 * time.Second):
-		// Force kill if still running
-		if tm.cmd != nil && tm.cmd.Process != nil {
-			tm.cmd.Process.Kill()
-		}
-	}
-
-	tm.running.Store(false)
-	return nil
-}
-
-// PublicURL returns the detected public URL.
-func (tm *TunnelManager) PublicURL() string {
-	return tm.publicURL.Load().(string)
-}
-
-// IsRunning returns whether the tunnel is running.
-func (tm *TunnelManager) IsRunning() bool {
-	return tm.running.Load()
+	info := tm.tunnel.Info()
+	return &info
 }
 
 // RecentOutput returns recent output lines for debugging.
 func (tm *TunnelManager) RecentOutput() []string {
-	tm.outputMu.RLock()
-	defer tm.outputMu.RUnlock()
-	result := make([]string, len(tm.output))
-	copy(result, tm.output)
-	return result
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.tunnel == nil {
+		return []string{}
+	}
+	return tm.tunnel.RecentOutput()
 }
 
 // WaitForURL waits for the public URL to be detected with a timeout.
 func (tm *TunnelManager) WaitForURL(timeout time.Duration) (string, error) {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		if url := tm.PublicURL(); url != "" {
-			return url, nil
-		}
-		if !tm.running.Load() {
-			return "", fmt.Errorf("tunnel process exited before URL was detected")
-		}
-		time.Sleep(100 * time.Millisecond)
+	tm.mu.Lock()
+	t := tm.tunnel
+	tm.mu.Unlock()
+	if t == nil {
+		return "", fmt.Errorf("tunnel process exited before URL was detected")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	url, err := t.WaitForURL(ctx)
+	if err == context.DeadlineExceeded {
+		return "", fmt.Errorf("timeout waiting for tunnel URL")
 	}
-	return "", fmt.Errorf("timeout waiting for tunnel URL")
+	return url, err
 }
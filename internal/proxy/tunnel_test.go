@@ -2,10 +2,12 @@ package proxy
 
 import (
 	"context"
+	"os/exec"
 	"testing"
 	"time"
 
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/internal/tunnel"
 )
 
 func TestNewTunnelManager(t *testing.T) {
@@ -32,8 +34,8 @@ func TestNewTunnelManager(t *testing.T) {
 	}
 }
 
-func TestTunnelManager_ExtractURL(t *testing.T) {
-	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "ngrok"}, 8080)
+// Custom tunnel commands fall back to the generic URL patterns.
+func TestTunnelManager_ExtractURL_Custom(t *testing.T) {
 
 	tests := []struct {
 		name     string
@@ -110,7 +112,7 @@ func TestTunnelManager_ExtractURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tm.extractURL(tt.line)
+			result := tunnel.ExtractURL(tunnel.ProviderCustom, tt.line)
 			if result != tt.expected {
 				t.Errorf("extractURL(%q) = %q, expected %q", tt.line, result, tt.expected)
 			}
@@ -347,77 +349,112 @@ func TestTunnelManager_BuildCommand_UnknownProvider(t *testing.T) {
 func TestTunnelManager_RecentOutput(t *testing.T) {
 	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "ngrok"}, 8080)
 
-	// Initially empty
+	// Empty before the tunnel is started
 	output := tm.RecentOutput()
 	if len(output) != 0 {
 		t.Errorf("expected empty output initially, got %d lines", len(output))
 	}
+}
 
-	// Add some output manually (simulating monitorOutput behavior)
-	tm.outputMu.Lock()
-	tm.output = append(tm.output, "line 1", "line 2", "line 3")
-	tm.outputMu.Unlock()
+func TestTunnelManager_TunnelConfig(t *testing.T) {
+	tm := NewTunnelManager(&protocol.TunnelConfig{
+		Provider:  "ssh",
+		Args:      []string{"-v"},
+		AuthToken: "secret",
+		Region:    "eu",
+		SSHHost:   "serveo.net",
+	}, 4567)
 
-	output = tm.RecentOutput()
-	if len(output) != 3 {
-		t.Errorf("expected 3 lines, got %d", len(output))
+	cfg := tm.tunnelConfig()
+	if cfg.LocalPort != 4567 {
+		t.Errorf("expected LocalPort 4567, got %d", cfg.LocalPort)
 	}
-
-	// Verify it's a copy, not the original slice
-	output[0] = "modified"
-	original := tm.RecentOutput()
-	if original[0] == "modified" {
-		t.Error("RecentOutput should return a copy, not the original slice")
+	if cfg.Provider != "ssh" || cfg.SSHHost != "serveo.net" {
+		t.Errorf("unexpected provider config: %s %s", cfg.Provider, cfg.SSHHost)
+	}
+	if cfg.AuthToken != "secret" || cfg.Region != "eu" || len(cfg.Args) != 1 {
+		t.Errorf("provider options not passed through: %+v", cfg)
+	}
+	if !cfg.AutoRestart {
+		t.Error("expected proxy tunnels to restart automatically")
+	}
+	if cfg.HealthPath != "/__devtool_metrics" {
+		t.Errorf("expected health path /__devtool_metrics, got %q", cfg.HealthPath)
 	}
 }
 
-func TestTunnelManager_WaitForURL_AlreadyAvailable(t *testing.T) {
+func TestTunnelManager_WaitForURL_NotStarted(t *testing.T) {
 	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "ngrok"}, 8080)
-	tm.publicURL.Store("https://test.ngrok.io")
-	tm.running.Store(true)
 
-	url, err := tm.WaitForURL(100 * time.Millisecond)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	_, err := tm.WaitForURL(1 * time.Second)
+	if err == nil {
+		t.Error("expected error when tunnel not started")
+	}
+}
+
+func TestTunnelManager_StartUnknownProvider(t *testing.T) {
+	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "unknown-provider"}, 8080)
+
+	if err := tm.Start(context.Background()); err == nil {
+		t.Error("expected error for unknown provider")
 	}
-	if url != "https://test.ngrok.io" {
-		t.Errorf("expected 'https://test.ngrok.io', got %q", url)
+	if tm.IsRunning() {
+		t.Error("expected tunnel not to be running")
 	}
 }
 
-func TestTunnelManager_WaitForURL_Timeout(t *testing.T) {
-	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "ngrok"}, 8080)
-	tm.running.Store(true)
+func TestTunnelManager_CustomCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
 
-	start := time.Now()
-	_, err := tm.WaitForURL(100 * time.Millisecond)
-	elapsed := time.Since(start)
+	tm := NewTunnelManager(&protocol.TunnelConfig{
+		Provider: "custom",
+		Command:  "sh",
+		Args:     []string{"-c", "echo 'your url is: https://port-{{PORT}}.example.dev'; sleep 30"},
+	}, 7070)
 
-	if err == nil {
-		t.Error("expected timeout error")
+	urls := make(chan string, 1)
+	tm.OnURL(func(url string) { urls <- url })
+
+	if err := tm.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
 	}
-	if elapsed < 100*time.Millisecond {
-		t.Errorf("expected at least 100ms elapsed, got %v", elapsed)
+	defer tm.Stop()
+
+	if err := tm.Start(context.Background()); err == nil {
+		t.Error("expected error when starting already running tunnel")
 	}
-}
 
-func TestTunnelManager_WaitForURL_ProcessExited(t *testing.T) {
-	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "ngrok"}, 8080)
-	tm.running.Store(false) // Process not running
+	url, err := tm.WaitForURL(5 * time.Second)
+	if err != nil {
+		t.Fatalf("WaitForURL failed: %v", err)
+	}
+	if url != "https://port-7070.example.dev" {
+		t.Errorf("unexpected URL %q", url)
+	}
 
-	_, err := tm.WaitForURL(1 * time.Second)
-	if err == nil {
-		t.Error("expected error when process not running")
+	select {
+	case got := <-urls:
+		if got != url {
+			t.Errorf("OnURL got %q, expected %q", got, url)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("OnURL callback not invoked")
 	}
-}
 
-func TestTunnelManager_StartAlreadyRunning(t *testing.T) {
-	tm := NewTunnelManager(&protocol.TunnelConfig{Provider: "ngrok"}, 8080)
-	tm.running.Store(true)
+	if info := tm.Info(); info == nil || info.PublicURL != url {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if len(tm.RecentOutput()) == 0 {
+		t.Error("expected recorded output")
+	}
 
-	err := tm.Start(context.Background())
-	if err == nil {
-		t.Error("expected error when starting already running tunnel")
+	if err := tm.Stop(); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+	if tm.IsRunning() {
+		t.Error("expected tunnel to be stopped")
 	}
 }
 
@@ -447,37 +484,3 @@ func TestTunnelManager_ProviderCaseInsensitive(t *testing.T) {
 		})
 	}
 }
-
-// TestTunnelURLPatterns tests that the URL patterns compile and work correctly
-func TestTunnelURLPatterns(t *testing.T) {
-	// Verify all patterns compiled (would panic at init if not)
-	if len(tunnelURLPatterns) == 0 {
-		t.Error("expected at least one URL pattern")
-	}
-
-	// Test specific pattern matches
-	testCases := []struct {
-		input   string
-		matches bool
-	}{
-		{"https://abc.ngrok.io", true},
-		{"https://abc.ngrok-free.app", true},
-		{"https://random.trycloudflare.com", true},
-		{"https://box.tail123.ts.net", true},
-		{"http://localhost:8080", false}, // We match http but prefer https in patterns
-		{"not a url at all", false},
-	}
-
-	for _, tc := range testCases {
-		matched := false
-		for _, pattern := range tunnelURLPatterns {
-			if pattern.MatchString(tc.input) {
-				matched = true
-				break
-			}
-		}
-		if matched != tc.matches {
-			t.Errorf("pattern match for %q: got %v, expected %v", tc.input, matched, tc.matches)
-		}
-	}
-}
@@ -1,71 +1,129 @@
 package proxy
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
+)
 
-	"github.com/gorilla/websocket"
+// Speech providers for browser voice input.
+const (
+	SpeechProviderDeepgram = "deepgram" // Deepgram streaming API (default)
+	SpeechProviderOpenAI   = "openai"   // OpenAI-compatible /audio/transcriptions endpoint
+	SpeechProviderWhisper  = "whisper"  // Local whisper.cpp server (/inference)
 )
 
-// VoiceSession manages a single voice transcription session between browser and Deepgram.
-type VoiceSession struct {
-	id            string
-	browserConn   *websocket.Conn
-	deepgramConn  *websocket.Conn
-	mu            sync.Mutex
-	closed        bool
-	keepAliveDone chan struct{}
+// Speech event types delivered by a SpeechStream.
+const (
+	SpeechEventReady         = "ready"
+	SpeechEventTranscript    = "transcript"
+	SpeechEventSpeechStarted = "speech_started"
+	SpeechEventUtteranceEnd  = "utterance_end"
+	SpeechEventError         = "error"
+)
+
+// SpeechConfig selects and configures the speech-to-text backend.
+type SpeechConfig struct {
+	Provider string // deepgram (default), openai or whisper
+	URL      string // Endpoint; empty for the provider's default
+	APIKey   string // Falls back to DEEPGRAM_API_KEY or OPENAI_API_KEY
+	Model    string // Provider default when empty
+	Language string // Default language when the browser doesn't send one
+
+	// InterimInterval is how often speech in progress is transcribed for
+	// interim results by providers without streaming (0: 2s, negative: off).
+	InterimInterval time.Duration
 }
 
-// DeepgramConfig holds configuration for Deepgram connection.
-type DeepgramConfig struct {
-	Model          string
-	Language       string
-	Punctuate      bool
-	SmartFormat    bool
-	InterimResults bool
-	Encoding       string
-	SampleRate     int
-	Channels       int
+// SpeechConfigFromEnv reads the speech configuration from AGNT_SPEECH_PROVIDER,
+// AGNT_SPEECH_URL, AGNT_SPEECH_API_KEY, AGNT_SPEECH_MODEL and
+// AGNT_SPEECH_LANGUAGE.
+func SpeechConfigFromEnv() SpeechConfig {
+	return SpeechConfig{
+		Provider: os.Getenv("AGNT_SPEECH_PROVIDER"),
+		URL:      os.Getenv("AGNT_SPEECH_URL"),
+		APIKey:   os.Getenv("AGNT_SPEECH_API_KEY"),
+		Model:    os.Getenv("AGNT_SPEECH_MODEL"),
+		Language: os.Getenv("AGNT_SPEECH_LANGUAGE"),
+	}
+}
+
+// SpeechOptions are the per-session transcription options. Audio is
+// linear16 PCM.
+type SpeechOptions struct {
+	Language   string
+	Model      string
+	SampleRate int
+	Channels   int
 }
 
-// DefaultDeepgramConfig returns sensible defaults for voice transcription.
-func DefaultDeepgramConfig() DeepgramConfig {
-	return DeepgramConfig{
-		Model:          "nova-3",
-		Language:       "en",
-		Punctuate:      true,
-		SmartFormat:    true,
-		InterimResults: true,
-		Encoding:       "linear16",
-		SampleRate:     16000,
-		Channels:       1,
+// SpeechEvent is a result or status change from a speech stream.
+type SpeechEvent struct {
+	Type       string
+	Transcript string
+	IsFinal    bool // Transcript won't change; otherwise an interim result
+	Confidence float64
+	Start      float64 // Seconds since the stream started
+	Duration   float64
+	Message    string // Status or error text
+	Details    string // Raw provider error
+}
+
+// SpeechProvider is a speech-to-text backend.
+type SpeechProvider interface {
+	// Name identifies the provider in logs and status messages.
+	Name() string
+	// Start opens a transcription stream. Events are passed to emit, from
+	// any goroutine, until the stream has been closed and flushed.
+	Start(opts SpeechOptions, emit func(SpeechEvent)) (SpeechStream, error)
+}
+
+// SpeechStream receives the audio of one voice session.
+type SpeechStream interface {
+	SendAudio(pcm []byte) error
+	// Close ends the stream. Speech still being transcribed may produce
+	// final results afterwards.
+	Close() error
+}
+
+// NewSpeechProvider returns the provider selected by config.
+func NewSpeechProvider(config SpeechConfig) (SpeechProvider, error) {
+	switch strings.ToLower(config.Provider) {
+	case "", SpeechProviderDeepgram:
+		return newDeepgramProvider(config)
+	case SpeechProviderOpenAI:
+		return newOpenAIProvider(config)
+	case SpeechProviderWhisper, "whisper.cpp":
+		return newWhisperProvider(config)
+	default:
+		return nil, fmt.Errorf("unknown speech provider %q (use deepgram, openai or whisper)", config.Provider)
 	}
 }
 
-// DeepgramMessage represents a message from Deepgram.
-type DeepgramMessage struct {
-	Type    string `json:"type"`
-	Channel struct {
-		Alternatives []struct {
-			Transcript string  `json:"transcript"`
-			Confidence float64 `json:"confidence"`
-			Words      []struct {
-				Word       string  `json:"word"`
-				Start      float64 `json:"start"`
-				End        float64 `json:"end"`
-				Confidence float64 `json:"confidence"`
-			} `json:"words"`
-		} `json:"alternatives"`
-	} `json:"channel"`
-	IsFinal     bool    `json:"is_final"`
-	SpeechFinal bool    `json:"speech_final"`
-	Start       float64 `json:"start"`
-	Duration    float64 `json:"duration"`
+// speechConfig returns the speech configuration for a new voice session.
+func (ps *ProxyServer) speechConfig() SpeechConfig {
+	if ps.speech != nil {
+		return *ps.speech
+	}
+	return SpeechConfigFromEnv()
+}
+
+// jsonWriter writes JSON messages to the browser, such as a *websocket.Conn.
+type jsonWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+// VoiceSession manages a single voice transcription session between the
+// browser and a speech provider.
+type VoiceSession struct {
+	id          string
+	provider    string
+	browserConn jsonWriter
+	stream      SpeechStream
+	mu          sync.Mutex
+	closed      bool
 }
 
 // VoiceTranscript is sent back to the browser.
@@ -78,67 +136,32 @@ type VoiceTranscript struct {
 	Duration   float64 `json:"duration"`
 }
 
-// getDeepgramAPIKey retrieves the API key from environment.
-func getDeepgramAPIKey() string {
-	return os.Getenv("DEEPGRAM_API_KEY")
-}
-
-// NewVoiceSession creates a new voice session and connects to Deepgram.
-func NewVoiceSession(id string, browserConn *websocket.Conn, config DeepgramConfig) (*VoiceSession, error) {
-	apiKey := getDeepgramAPIKey()
-	if apiKey == "" {
-		return nil, fmt.Errorf("DEEPGRAM_API_KEY environment variable not set")
-	}
-
-	// Build Deepgram WebSocket URL
-	params := url.Values{}
-	params.Set("model", config.Model)
-	params.Set("language", config.Language)
-	params.Set("punctuate", fmt.Sprintf("%t", config.Punctuate))
-	params.Set("smart_format", fmt.Sprintf("%t", config.SmartFormat))
-	params.Set("interim_results", fmt.Sprintf("%t", config.InterimResults))
-	params.Set("encoding", config.Encoding)
-	params.Set("sample_rate", fmt.Sprintf("%d", config.SampleRate))
-	params.Set("channels", fmt.Sprintf("%d", config.Channels))
-
-	wsURL := "wss://api.deepgram.com/v1/listen?" + params.Encode()
-
-	// Connect to Deepgram with token authentication
-	dialer := websocket.Dialer{
-		Subprotocols: []string{"token", apiKey},
+// NewVoiceSession starts a transcription stream and forwards its results to
+// the browser.
+func NewVoiceSession(id string, browserConn jsonWriter, provider SpeechProvider, opts SpeechOptions) (*VoiceSession, error) {
+	vs := &VoiceSession{
+		id:          id,
+		provider:    provider.Name(),
+		browserConn: browserConn,
 	}
-
-	deepgramConn, _, err := dialer.Dial(wsURL, nil)
+	stream, err := provider.Start(opts, vs.forward)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Deepgram: %w", err)
+		return nil, err
 	}
-
-	vs := &VoiceSession{
-		id:            id,
-		browserConn:   browserConn,
-		deepgramConn:  deepgramConn,
-		keepAliveDone: make(chan struct{}),
-	}
-
-	// Start goroutine to read Deepgram responses
-	go vs.readDeepgramResponses()
-
-	// Start keepalive
-	go vs.keepAlive()
-
+	vs.stream = stream
 	return vs, nil
 }
 
-// SendAudio forwards audio data to Deepgram.
+// SendAudio forwards audio data to the speech provider.
 func (vs *VoiceSession) SendAudio(data []byte) error {
 	vs.mu.Lock()
-	defer vs.mu.Unlock()
+	closed := vs.closed
+	vs.mu.Unlock()
 
-	if vs.closed {
+	if closed {
 		return fmt.Errorf("session closed")
 	}
-
-	return vs.deepgramConn.WriteMessage(websocket.BinaryMessage, data)
+	return vs.stream.SendAudio(data)
 }
 
 // Close terminates the voice session.
@@ -151,111 +174,59 @@ func (vs *VoiceSession) Close() {
 	vs.closed = true
 	vs.mu.Unlock()
 
-	// Stop keepalive
-	close(vs.keepAliveDone)
-
-	// Send close message to Deepgram
-	closeMsg := map[string]string{"type": "CloseStream"}
-	if data, err := json.Marshal(closeMsg); err == nil {
-		vs.deepgramConn.WriteMessage(websocket.TextMessage, data)
-	}
-
-	vs.deepgramConn.Close()
+	vs.stream.Close()
 }
 
-// readDeepgramResponses reads transcription results from Deepgram and forwards to browser.
-func (vs *VoiceSession) readDeepgramResponses() {
-	defer vs.Close()
-
-	for {
-		_, message, err := vs.deepgramConn.ReadMessage()
-		if err != nil {
-			// Connection closed
-			vs.sendToBrowser(map[string]interface{}{
-				"type":  "voice_error",
-				"error": "Deepgram connection closed",
-			})
+// forward sends a speech event to the browser in the voice message format.
+func (vs *VoiceSession) forward(ev SpeechEvent) {
+	switch ev.Type {
+	case SpeechEventTranscript:
+		if ev.Transcript == "" {
 			return
 		}
-
-		var dgMsg DeepgramMessage
-		if err := json.Unmarshal(message, &dgMsg); err != nil {
-			continue
+		vs.sendToBrowser(VoiceTranscript{
+			Type:       "voice_transcript",
+			Transcript: ev.Transcript,
+			IsFinal:    ev.IsFinal,
+			Confidence: ev.Confidence,
+			Start:      ev.Start,
+			Duration:   ev.Duration,
+		})
+
+	case SpeechEventReady:
+		vs.sendToBrowser(map[string]interface{}{
+			"type":     "voice_ready",
+			"message":  ev.Message,
+			"provider": vs.provider,
+		})
+
+	case SpeechEventSpeechStarted:
+		vs.sendToBrowser(map[string]interface{}{
+			"type": "voice_speech_started",
+		})
+
+	case SpeechEventUtteranceEnd:
+		vs.sendToBrowser(map[string]interface{}{
+			"type": "voice_utterance_end",
+		})
+
+	case SpeechEventError:
+		msg := map[string]interface{}{
+			"type":  "voice_error",
+			"error": ev.Message,
 		}
-
-		switch dgMsg.Type {
-		case "Results":
-			if len(dgMsg.Channel.Alternatives) > 0 {
-				alt := dgMsg.Channel.Alternatives[0]
-				if alt.Transcript != "" {
-					vs.sendToBrowser(VoiceTranscript{
-						Type:       "voice_transcript",
-						Transcript: alt.Transcript,
-						IsFinal:    dgMsg.IsFinal,
-						Confidence: alt.Confidence,
-						Start:      dgMsg.Start,
-						Duration:   dgMsg.Duration,
-					})
-				}
-			}
-
-		case "Metadata":
-			// Connection established
-			vs.sendToBrowser(map[string]interface{}{
-				"type":    "voice_ready",
-				"message": "Deepgram connected",
-			})
-
-		case "SpeechStarted":
-			vs.sendToBrowser(map[string]interface{}{
-				"type": "voice_speech_started",
-			})
-
-		case "UtteranceEnd":
-			vs.sendToBrowser(map[string]interface{}{
-				"type": "voice_utterance_end",
-			})
-
-		case "Error":
-			vs.sendToBrowser(map[string]interface{}{
-				"type":    "voice_error",
-				"error":   "Deepgram error",
-				"details": string(message),
-			})
+		if ev.Details != "" {
+			msg["details"] = ev.Details
 		}
+		vs.sendToBrowser(msg)
 	}
 }
 
-// sendToBrowser sends a message to the browser WebSocket.
+// sendToBrowser sends a message to the browser WebSocket. Final results can
+// arrive after Close, so closed sessions still deliver them.
 func (vs *VoiceSession) sendToBrowser(msg interface{}) {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
-	if vs.closed {
-		return
-	}
-
 	vs.browserConn.WriteJSON(msg)
 }
-
-// keepAlive sends periodic keepalive messages to Deepgram.
-func (vs *VoiceSession) keepAlive() {
-	ticker := time.NewTicker(8 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-vs.keepAliveDone:
-			return
-		case <-ticker.C:
-			vs.mu.Lock()
-			if !vs.closed {
-				msg := map[string]string{"type": "KeepAlive"}
-				if data, err := json.Marshal(msg); err == nil {
-					vs.deepgramConn.WriteMessage(websocket.TextMessage, data)
-				}
-			}
-			vs.mu.Unlock()
-		}
-	}
-}
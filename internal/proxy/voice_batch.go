@@ -0,0 +1,472 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOpenAISpeechURL   = "https://api.openai.com/v1"
+	defaultOpenAISpeechModel = "whisper-1"
+	defaultWhisperURL        = "http://127.0.0.1:8080"
+
+	// defaultInterimInterval is how often speech in progress is transcribed
+	// for interim results.
+	defaultInterimInterval = 2 * time.Second
+	// speechSilenceRMS is the RMS level of 16-bit samples below which audio
+	// counts as silence.
+	speechSilenceRMS = 500
+	// speechEndSilence ends an utterance.
+	speechEndSilence = 800 * time.Millisecond
+	// maxUtterance forces a final result for long monologues.
+	maxUtterance = 30 * time.Second
+	// minUtterance is the least speech worth transcribing.
+	minUtterance = 300 * time.Millisecond
+	// speechRequestTimeout bounds one transcription request.
+	speechRequestTimeout = 30 * time.Second
+)
+
+// transcribeFunc transcribes a WAV file.
+type transcribeFunc func(ctx context.Context, wav []byte, opts SpeechOptions) (string, error)
+
+// openAIProvider uses an OpenAI-compatible /audio/transcriptions endpoint.
+type openAIProvider struct {
+	url    string
+	apiKey string
+	model  string
+	config SpeechConfig
+	client *http.Client
+}
+
+func newOpenAIProvider(config SpeechConfig) (*openAIProvider, error) {
+	p := &openAIProvider{
+		url:    strings.TrimRight(config.URL, "/"),
+		apiKey: config.APIKey,
+		model:  config.Model,
+		config: config,
+		client: &http.Client{Timeout: speechRequestTimeout},
+	}
+	if p.url == "" {
+		p.url = defaultOpenAISpeechURL
+	}
+	if p.apiKey == "" {
+		p.apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if p.apiKey == "" && p.url == defaultOpenAISpeechURL {
+		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	}
+	if p.model == "" {
+		p.model = defaultOpenAISpeechModel
+	}
+	return p, nil
+}
+
+func (p *openAIProvider) Name() string { return SpeechProviderOpenAI }
+
+func (p *openAIProvider) Start(opts SpeechOptions, emit func(SpeechEvent)) (SpeechStream, error) {
+	if opts.Model == "" {
+		opts.Model = p.model
+	}
+	return startBatchStream(p.config, opts, emit, "Transcribing with "+p.url, p.transcribe), nil
+}
+
+func (p *openAIProvider) transcribe(ctx context.Context, wav []byte, opts SpeechOptions) (string, error) {
+	fields := map[string]string{"model": opts.Model, "response_format": "json"}
+	if opts.Language != "" {
+		fields["language"] = opts.Language
+	}
+	headers := http.Header{}
+	if p.apiKey != "" {
+		headers.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return postTranscription(ctx, p.client, p.url+"/audio/transcriptions", headers, fields, wav)
+}
+
+// whisperProvider uses the /inference endpoint of a whisper.cpp server,
+// which transcribes with the model it was started with.
+type whisperProvider struct {
+	url    string
+	config SpeechConfig
+	client *http.Client
+}
+
+func newWhisperProvider(config SpeechConfig) (*whisperProvider, error) {
+	p := &whisperProvider{
+		url:    strings.TrimRight(config.URL, "/"),
+		config: config,
+		client: &http.Client{Timeout: speechRequestTimeout},
+	}
+	if p.url == "" {
+		p.url = defaultWhisperURL
+	}
+	return p, nil
+}
+
+func (p *whisperProvider) Name() string { return SpeechProviderWhisper }
+
+func (p *whisperProvider) Start(opts SpeechOptions, emit func(SpeechEvent)) (SpeechStream, error) {
+	return startBatchStream(p.config, opts, emit, "Transcribing with whisper.cpp at "+p.url, p.transcribe), nil
+}
+
+func (p *whisperProvider) transcribe(ctx context.Context, wav []byte, opts SpeechOptions) (string, error) {
+	fields := map[string]string{"temperature": "0.0", "response_format": "json"}
+	if opts.Language != "" {
+		fields["language"] = opts.Language
+	}
+	return postTranscription(ctx, p.client, p.url+"/inference", nil, fields, wav)
+}
+
+// postTranscription uploads a WAV file as multipart form data and returns
+// the "text" of the JSON response.
+func postTranscription(ctx context.Context, client *http.Client, endpoint string, headers http.Header, fields map[string]string, wav []byte) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", err
+	}
+	part.Write(wav)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("transcription failed: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("invalid transcription response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// batchStream gives providers that transcribe whole files the interim and
+// final semantics of a streaming API. Audio is split into utterances at
+// pauses; speech in progress is transcribed every interim interval for
+// interim results, and each utterance once more when it ends for the final
+// result.
+//
+// Transcription runs on its own goroutine so a slow backend never holds up
+// the audio, and with it the browser connection. Interim results still
+// waiting for it are replaced by newer ones.
+type batchStream struct {
+	transcribe transcribeFunc
+	emit       func(SpeechEvent)
+	opts       SpeechOptions
+	interim    time.Duration
+
+	audio chan []byte
+	stop  chan struct{}
+	taken chan struct{} // Closed once run has queued all the audio
+	done  chan struct{} // Closed once the queued jobs are done
+
+	mu     sync.Mutex
+	closed bool
+
+	// Events and transcriptions in the order they are emitted
+	jobsMu   sync.Mutex
+	jobs     []speechJob
+	jobReady chan struct{}
+	ended    bool // No more jobs will be queued
+
+	// Owned by run
+	utterance   []byte        // PCM of the current utterance
+	uttStart    time.Duration // Stream offset of the utterance
+	offset      time.Duration // Audio received so far
+	silence     time.Duration // Trailing silence in the utterance
+	lastInterim time.Duration // Utterance length at the last interim result
+}
+
+// speechJob is an event to emit or, if event is nil, audio to transcribe.
+type speechJob struct {
+	event  *SpeechEvent
+	wav    []byte
+	final  bool
+	start  time.Duration
+	length time.Duration
+}
+
+func startBatchStream(config SpeechConfig, opts SpeechOptions, emit func(SpeechEvent), ready string, transcribe transcribeFunc) *batchStream {
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 16000
+	}
+	if opts.Channels <= 0 {
+		opts.Channels = 1
+	}
+	if opts.Language == "" {
+		opts.Language = config.Language
+	}
+	interim := config.InterimInterval
+	if interim == 0 {
+		interim = defaultInterimInterval
+	}
+
+	bs := &batchStream{
+		transcribe: transcribe,
+		emit:       emit,
+		opts:       opts,
+		interim:    interim,
+		audio:      make(chan []byte, 256),
+		stop:       make(chan struct{}),
+		taken:      make(chan struct{}),
+		done:       make(chan struct{}),
+		jobReady:   make(chan struct{}, 1),
+	}
+	emit(SpeechEvent{Type: SpeechEventReady, Message: ready})
+	go bs.run()
+	go bs.process()
+	return bs
+}
+
+// SendAudio queues audio for transcription.
+func (bs *batchStream) SendAudio(pcm []byte) error {
+	bs.mu.Lock()
+	closed := bs.closed
+	bs.mu.Unlock()
+	if closed {
+		return fmt.Errorf("session closed")
+	}
+
+	select {
+	case bs.audio <- append([]byte(nil), pcm...):
+		return nil
+	case <-bs.stop:
+		return fmt.Errorf("session closed")
+	}
+}
+
+// Close stops taking audio and returns once the audio sent before has been
+// queued; the current utterance is still transcribed.
+func (bs *batchStream) Close() error {
+	bs.mu.Lock()
+	if !bs.closed {
+		bs.closed = true
+		close(bs.stop)
+	}
+	bs.mu.Unlock()
+	<-bs.taken
+	return nil
+}
+
+func (bs *batchStream) run() {
+	defer close(bs.taken)
+	defer bs.endJobs()
+	for {
+		select {
+		case pcm := <-bs.audio:
+			bs.add(pcm)
+		case <-bs.stop:
+			// Take what was queued before the stream closed
+			for len(bs.audio) > 0 {
+				bs.add(<-bs.audio)
+			}
+			bs.finish()
+			return
+		}
+	}
+}
+
+// add appends audio and produces results at pauses and interim intervals.
+func (bs *batchStream) add(pcm []byte) {
+	chunk := bs.duration(len(pcm))
+	bs.offset += chunk
+	speaking := pcmRMS(pcm) >= speechSilenceRMS
+
+	if bs.utterance == nil {
+		if !speaking {
+			return
+		}
+		bs.uttStart = bs.offset - chunk
+		bs.queueEvent(SpeechEvent{Type: SpeechEventSpeechStarted, Start: bs.uttStart.Seconds()})
+	}
+	bs.utterance = append(bs.utterance, pcm...)
+	if speaking {
+		bs.silence = 0
+	} else {
+		bs.silence += chunk
+	}
+
+	length := bs.duration(len(bs.utterance))
+	switch {
+	case bs.silence >= speechEndSilence || length >= maxUtterance:
+		bs.finish()
+	case bs.interim > 0 && length-bs.lastInterim >= bs.interim:
+		bs.lastInterim = length
+		bs.queueResult(false)
+	}
+}
+
+// finish transcribes the current utterance as a final result.
+func (bs *batchStream) finish() {
+	if bs.utterance == nil {
+		return
+	}
+	if bs.duration(len(bs.utterance))-bs.silence >= minUtterance {
+		bs.queueResult(true)
+	}
+	bs.queueEvent(SpeechEvent{Type: SpeechEventUtteranceEnd})
+	bs.utterance = nil
+	bs.silence = 0
+	bs.lastInterim = 0
+}
+
+// queueResult queues the utterance so far for transcription. Interim
+// results not yet transcribed are superseded: a newer interim replaces them,
+// a final result drops them.
+func (bs *batchStream) queueResult(final bool) {
+	job := speechJob{
+		wav:    pcmToWAV(bs.utterance, bs.opts.SampleRate, bs.opts.Channels),
+		final:  final,
+		start:  bs.uttStart,
+		length: bs.duration(len(bs.utterance)),
+	}
+
+	bs.jobsMu.Lock()
+	for n := len(bs.jobs); n > 0 && bs.jobs[n-1].event == nil && !bs.jobs[n-1].final; n-- {
+		bs.jobs = bs.jobs[:n-1]
+	}
+	bs.jobs = append(bs.jobs, job)
+	bs.jobsMu.Unlock()
+	bs.signal()
+}
+
+// queueEvent queues an event behind the pending transcriptions.
+func (bs *batchStream) queueEvent(ev SpeechEvent) {
+	bs.jobsMu.Lock()
+	bs.jobs = append(bs.jobs, speechJob{event: &ev})
+	bs.jobsMu.Unlock()
+	bs.signal()
+}
+
+// endJobs lets process return once the queued jobs are done.
+func (bs *batchStream) endJobs() {
+	bs.jobsMu.Lock()
+	bs.ended = true
+	bs.jobsMu.Unlock()
+	bs.signal()
+}
+
+func (bs *batchStream) signal() {
+	select {
+	case bs.jobReady <- struct{}{}:
+	default:
+	}
+}
+
+// process emits the queued events and transcribes the queued audio, in
+// order, until the stream has ended.
+func (bs *batchStream) process() {
+	defer close(bs.done)
+	for {
+		bs.jobsMu.Lock()
+		if len(bs.jobs) == 0 {
+			ended := bs.ended
+			bs.jobsMu.Unlock()
+			if ended {
+				return
+			}
+			<-bs.jobReady
+			continue
+		}
+		job := bs.jobs[0]
+		bs.jobs = bs.jobs[1:]
+		bs.jobsMu.Unlock()
+
+		if job.event != nil {
+			bs.emit(*job.event)
+		} else {
+			bs.result(job)
+		}
+	}
+}
+
+// result transcribes the audio of a job.
+func (bs *batchStream) result(job speechJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), speechRequestTimeout)
+	defer cancel()
+
+	text, err := bs.transcribe(ctx, job.wav, bs.opts)
+	if err != nil {
+		bs.emit(SpeechEvent{Type: SpeechEventError, Message: "Transcription failed", Details: err.Error()})
+		return
+	}
+	bs.emit(SpeechEvent{
+		Type:       SpeechEventTranscript,
+		Transcript: text,
+		IsFinal:    job.final,
+		Confidence: 1,
+		Start:      job.start.Seconds(),
+		Duration:   job.length.Seconds(),
+	})
+}
+
+// duration returns the playing time of n bytes of 16-bit PCM.
+func (bs *batchStream) duration(n int) time.Duration {
+	samples := n / 2 / bs.opts.Channels
+	return time.Duration(samples) * time.Second / time.Duration(bs.opts.SampleRate)
+}
+
+// pcmRMS returns the root mean square of little-endian 16-bit samples.
+func pcmRMS(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// pcmToWAV wraps 16-bit PCM in a WAV header.
+func pcmToWAV(pcm []byte, sampleRate, channels int) []byte {
+	var b bytes.Buffer
+	b.Grow(44 + len(pcm))
+	le := binary.LittleEndian
+	b.WriteString("RIFF")
+	binary.Write(&b, le, uint32(36+len(pcm)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, le, uint32(16)) // fmt chunk size
+	binary.Write(&b, le, uint16(1))  // PCM
+	binary.Write(&b, le, uint16(channels))
+	binary.Write(&b, le, uint32(sampleRate))
+	binary.Write(&b, le, uint32(sampleRate*channels*2)) // Byte rate
+	binary.Write(&b, le, uint16(channels*2))            // Block align
+	binary.Write(&b, le, uint16(16))                    // Bits per sample
+	b.WriteString("data")
+	binary.Write(&b, le, uint32(len(pcm)))
+	b.Write(pcm)
+	return b.Bytes()
+}
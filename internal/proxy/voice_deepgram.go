@@ -0,0 +1,275 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultDeepgramURL is Deepgram's streaming transcription endpoint.
+const defaultDeepgramURL = "wss://api.deepgram.com/v1/listen"
+
+// DeepgramConfig holds configuration for Deepgram connection.
+type DeepgramConfig struct {
+	Model          string
+	Language       string
+	Punctuate      bool
+	SmartFormat    bool
+	InterimResults bool
+	Encoding       string
+	SampleRate     int
+	Channels       int
+}
+
+// DefaultDeepgramConfig returns sensible defaults for voice transcription.
+func DefaultDeepgramConfig() DeepgramConfig {
+	return DeepgramConfig{
+		Model:          "nova-3",
+		Language:       "en",
+		Punctuate:      true,
+		SmartFormat:    true,
+		InterimResults: true,
+		Encoding:       "linear16",
+		SampleRate:     16000,
+		Channels:       1,
+	}
+}
+
+// DeepgramMessage represents a message from Deepgram.
+type DeepgramMessage struct {
+	Type    string `json:"type"`
+	Channel struct {
+		Alternatives []struct {
+			Transcript string  `json:"transcript"`
+			Confidence float64 `json:"confidence"`
+			Words      []struct {
+				Word       string  `json:"word"`
+				Start      float64 `json:"start"`
+				End        float64 `json:"end"`
+				Confidence float64 `json:"confidence"`
+			} `json:"words"`
+		} `json:"alternatives"`
+	} `json:"channel"`
+	IsFinal     bool    `json:"is_final"`
+	SpeechFinal bool    `json:"speech_final"`
+	Start       float64 `json:"start"`
+	Duration    float64 `json:"duration"`
+}
+
+// getDeepgramAPIKey retrieves the API key from environment.
+func getDeepgramAPIKey() string {
+	return os.Getenv("DEEPGRAM_API_KEY")
+}
+
+// deepgramProvider streams audio to Deepgram over a WebSocket, which returns
+// interim and final results itself.
+type deepgramProvider struct {
+	url    string
+	apiKey string
+	config DeepgramConfig
+}
+
+func newDeepgramProvider(config SpeechConfig) (*deepgramProvider, error) {
+	p := &deepgramProvider{
+		url:    config.URL,
+		apiKey: config.APIKey,
+		config: DefaultDeepgramConfig(),
+	}
+	if p.url == "" {
+		p.url = defaultDeepgramURL
+	}
+	if p.apiKey == "" {
+		p.apiKey = getDeepgramAPIKey()
+	}
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("DEEPGRAM_API_KEY environment variable not set")
+	}
+	if config.Model != "" {
+		p.config.Model = config.Model
+	}
+	if config.Language != "" {
+		p.config.Language = config.Language
+	}
+	return p, nil
+}
+
+func (p *deepgramProvider) Name() string { return SpeechProviderDeepgram }
+
+// Start connects to Deepgram.
+func (p *deepgramProvider) Start(opts SpeechOptions, emit func(SpeechEvent)) (SpeechStream, error) {
+	config := p.config
+	if opts.Model != "" {
+		config.Model = opts.Model
+	}
+	if opts.Language != "" {
+		config.Language = opts.Language
+	}
+	if opts.SampleRate > 0 {
+		config.SampleRate = opts.SampleRate
+	}
+	if opts.Channels > 0 {
+		config.Channels = opts.Channels
+	}
+
+	// Build Deepgram WebSocket URL
+	params := url.Values{}
+	params.Set("model", config.Model)
+	params.Set("language", config.Language)
+	params.Set("punctuate", fmt.Sprintf("%t", config.Punctuate))
+	params.Set("smart_format", fmt.Sprintf("%t", config.SmartFormat))
+	params.Set("interim_results", fmt.Sprintf("%t", config.InterimResults))
+	params.Set("encoding", config.Encoding)
+	params.Set("sample_rate", fmt.Sprintf("%d", config.SampleRate))
+	params.Set("channels", fmt.Sprintf("%d", config.Channels))
+
+	wsURL := p.url + "?" + params.Encode()
+
+	// Connect to Deepgram with token authentication
+	dialer := websocket.Dialer{
+		Subprotocols: []string{"token", p.apiKey},
+	}
+
+	conn, _, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Deepgram: %w", err)
+	}
+
+	ds := &deepgramStream{
+		conn:          conn,
+		emit:          emit,
+		keepAliveDone: make(chan struct{}),
+	}
+
+	// Start goroutine to read Deepgram responses
+	go ds.readResponses()
+
+	// Start keepalive
+	go ds.keepAlive()
+
+	return ds, nil
+}
+
+// deepgramStream is one Deepgram WebSocket connection.
+type deepgramStream struct {
+	conn          *websocket.Conn
+	emit          func(SpeechEvent)
+	mu            sync.Mutex // Guards writes to conn and closed
+	closed        bool
+	keepAliveDone chan struct{}
+}
+
+// SendAudio forwards audio data to Deepgram.
+func (ds *deepgramStream) SendAudio(data []byte) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.closed {
+		return fmt.Errorf("session closed")
+	}
+
+	return ds.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Close ends the Deepgram stream.
+func (ds *deepgramStream) Close() error {
+	ds.mu.Lock()
+	if ds.closed {
+		ds.mu.Unlock()
+		return nil
+	}
+	ds.closed = true
+
+	// Stop keepalive
+	close(ds.keepAliveDone)
+
+	// Send close message to Deepgram
+	closeMsg := map[string]string{"type": "CloseStream"}
+	if data, err := json.Marshal(closeMsg); err == nil {
+		ds.conn.WriteMessage(websocket.TextMessage, data)
+	}
+	ds.mu.Unlock()
+
+	return ds.conn.Close()
+}
+
+func (ds *deepgramStream) isClosed() bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.closed
+}
+
+// readResponses reads transcription results from Deepgram.
+func (ds *deepgramStream) readResponses() {
+	defer ds.Close()
+
+	for {
+		_, message, err := ds.conn.ReadMessage()
+		if err != nil {
+			// Connection closed
+			if !ds.isClosed() {
+				ds.emit(SpeechEvent{Type: SpeechEventError, Message: "Deepgram connection closed"})
+			}
+			return
+		}
+
+		var dgMsg DeepgramMessage
+		if err := json.Unmarshal(message, &dgMsg); err != nil {
+			continue
+		}
+
+		switch dgMsg.Type {
+		case "Results":
+			if len(dgMsg.Channel.Alternatives) > 0 {
+				alt := dgMsg.Channel.Alternatives[0]
+				ds.emit(SpeechEvent{
+					Type:       SpeechEventTranscript,
+					Transcript: alt.Transcript,
+					IsFinal:    dgMsg.IsFinal,
+					Confidence: alt.Confidence,
+					Start:      dgMsg.Start,
+					Duration:   dgMsg.Duration,
+				})
+			}
+
+		case "Metadata":
+			// Connection established
+			ds.emit(SpeechEvent{Type: SpeechEventReady, Message: "Deepgram connected"})
+
+		case "SpeechStarted":
+			ds.emit(SpeechEvent{Type: SpeechEventSpeechStarted})
+
+		case "UtteranceEnd":
+			ds.emit(SpeechEvent{Type: SpeechEventUtteranceEnd})
+
+		case "Error":
+			ds.emit(SpeechEvent{Type: SpeechEventError, Message: "Deepgram error", Details: string(message)})
+		}
+	}
+}
+
+// keepAlive sends periodic keepalive messages to Deepgram.
+func (ds *deepgramStream) keepAlive() {
+	ticker := time.NewTicker(8 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ds.keepAliveDone:
+			return
+		case <-ticker.C:
+			ds.mu.Lock()
+			if !ds.closed {
+				msg := map[string]string{"type": "KeepAlive"}
+				if data, err := json.Marshal(msg); err == nil {
+					ds.conn.WriteMessage(websocket.TextMessage, data)
+				}
+			}
+			ds.mu.Unlock()
+		}
+	}
+}
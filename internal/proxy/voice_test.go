@@ -0,0 +1,294 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testPCM returns 16kHz mono PCM: a tone when loud, otherwise silence.
+func testPCM(d time.Duration, loud bool) []byte {
+	n := int(d / (time.Second / 16000))
+	pcm := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		var s int16
+		if loud {
+			s = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/16000))
+		}
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(s))
+	}
+	return pcm
+}
+
+// eventRecorder collects speech events.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []SpeechEvent
+}
+
+func (er *eventRecorder) emit(ev SpeechEvent) {
+	er.mu.Lock()
+	er.events = append(er.events, ev)
+	er.mu.Unlock()
+}
+
+func (er *eventRecorder) summary() string {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	var parts []string
+	for _, ev := range er.events {
+		switch {
+		case ev.Type == SpeechEventTranscript && ev.IsFinal:
+			parts = append(parts, "final:"+ev.Transcript)
+		case ev.Type == SpeechEventTranscript:
+			parts = append(parts, "interim:"+ev.Transcript)
+		default:
+			parts = append(parts, ev.Type)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// waitFor waits until n events have been emitted.
+func (er *eventRecorder) waitFor(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		er.mu.Lock()
+		got := len(er.events)
+		er.mu.Unlock()
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d events, got %s", n, er.summary())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatchStream(t *testing.T) {
+	var calls []time.Duration
+	transcribe := func(ctx context.Context, wav []byte, opts SpeechOptions) (string, error) {
+		if string(wav[:4]) != "RIFF" || string(wav[8:12]) != "WAVE" || opts.Language != "de" {
+			t.Errorf("unexpected upload: %q, %+v", wav[:12], opts)
+		}
+		d := time.Duration(len(wav)-44) / 2 * time.Second / 16000
+		calls = append(calls, d)
+		return "hello", nil
+	}
+
+	var rec eventRecorder
+	bs := startBatchStream(SpeechConfig{Language: "de", InterimInterval: time.Second}, SpeechOptions{}, rec.emit, "ready", transcribe)
+
+	// 1.5s of speech, then enough silence to end the utterance after 2.3s,
+	// sent in 100ms chunks like the browser. Each result is awaited before
+	// more audio is sent, so none is superseded.
+	send := func(d time.Duration, loud bool, events int) {
+		for i := time.Duration(0); i < d; i += 100 * time.Millisecond {
+			bs.SendAudio(testPCM(100*time.Millisecond, loud))
+		}
+		rec.waitFor(t, events)
+	}
+	send(time.Second, true, 3)
+	send(500*time.Millisecond, true, 3)
+	send(500*time.Millisecond, false, 4)
+	send(300*time.Millisecond, false, 6)
+	send(400*time.Millisecond, false, 6) // Silence between utterances is dropped
+	send(500*time.Millisecond, true, 7)  // Finalised by Close
+	bs.Close()
+	<-bs.done
+
+	want := "ready,speech_started,interim:hello,interim:hello,final:hello,utterance_end,speech_started,final:hello,utterance_end"
+	if got := rec.summary(); got != want {
+		t.Errorf("events = %s\nwant %s", got, want)
+	}
+	wantCalls := []time.Duration{time.Second, 2 * time.Second, 2300 * time.Millisecond, 500 * time.Millisecond}
+	if fmt.Sprint(calls) != fmt.Sprint(wantCalls) {
+		t.Errorf("unexpected transcribed lengths %v", calls)
+	}
+	if err := bs.SendAudio(testPCM(time.Millisecond, true)); err == nil {
+		t.Error("expected audio after Close to be rejected")
+	}
+}
+
+// A slow backend holds up neither the audio nor the order of the events;
+// interim results it can't keep up with are skipped. The backend blocks
+// until the end, so only the first interim and the final are transcribed.
+func TestBatchStream_SlowTranscription(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	transcribe := func(ctx context.Context, wav []byte, opts SpeechOptions) (string, error) {
+		<-release
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return "hello", nil
+	}
+
+	var rec eventRecorder
+	bs := startBatchStream(SpeechConfig{InterimInterval: 100 * time.Millisecond}, SpeechOptions{}, rec.emit, "ready", transcribe)
+
+	// More chunks than the audio queue holds, with an interim result each
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 300; i++ {
+			bs.SendAudio(testPCM(100*time.Millisecond, true))
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SendAudio blocked on transcription")
+	}
+
+	// Let the backend answer once all the audio has been queued
+	bs.Close()
+	close(release)
+	select {
+	case <-bs.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("expected pending interim results to be dropped, got %d transcriptions", calls)
+	}
+	got := rec.summary()
+	if !strings.HasPrefix(got, "ready,speech_started,") || !strings.HasSuffix(got, "final:hello,utterance_end") {
+		t.Errorf("events = %s", got)
+	}
+}
+
+func TestBatchStream_ShortNoise(t *testing.T) {
+	var rec eventRecorder
+	bs := startBatchStream(SpeechConfig{InterimInterval: -1}, SpeechOptions{}, rec.emit, "ready",
+		func(context.Context, []byte, SpeechOptions) (string, error) {
+			t.Error("expected a click not to be transcribed")
+			return "", nil
+		})
+	bs.SendAudio(testPCM(100*time.Millisecond, true))
+	bs.SendAudio(testPCM(time.Second, false))
+	bs.Close()
+	<-bs.done
+	if got := rec.summary(); got != "ready,speech_started,utterance_end" {
+		t.Errorf("events = %s", got)
+	}
+}
+
+func TestSpeechProviders_HTTP(t *testing.T) {
+	var gotPath, gotAuth, gotModel, gotLanguage string
+	var gotFile int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		gotModel, gotLanguage = r.FormValue("model"), r.FormValue("language")
+		if f, _, err := r.FormFile("file"); err == nil {
+			data, _ := io.ReadAll(f)
+			gotFile = len(data)
+		}
+		io.WriteString(w, `{"text": " transcribed "}`)
+	}))
+	defer server.Close()
+
+	wav := pcmToWAV(testPCM(100*time.Millisecond, true), 16000, 1)
+	opts := SpeechOptions{Language: "en", Model: "whisper-1"}
+
+	openai, err := NewSpeechProvider(SpeechConfig{Provider: "openai", URL: server.URL + "/v1/", APIKey: "sk-test"})
+	if err != nil {
+		t.Fatalf("openai provider: %v", err)
+	}
+	text, err := openai.(*openAIProvider).transcribe(context.Background(), wav, opts)
+	if err != nil || text != "transcribed" {
+		t.Fatalf("transcribe = %q, %v", text, err)
+	}
+	if gotPath != "/v1/audio/transcriptions" || gotAuth != "Bearer sk-test" || gotModel != "whisper-1" || gotLanguage != "en" || gotFile != len(wav) {
+		t.Errorf("unexpected openai request: %s %q %q %q %d", gotPath, gotAuth, gotModel, gotLanguage, gotFile)
+	}
+
+	whisper, err := NewSpeechProvider(SpeechConfig{Provider: "whisper", URL: server.URL})
+	if err != nil {
+		t.Fatalf("whisper provider: %v", err)
+	}
+	if text, err := whisper.(*whisperProvider).transcribe(context.Background(), wav, opts); err != nil || text != "transcribed" {
+		t.Fatalf("transcribe = %q, %v", text, err)
+	}
+	if gotPath != "/inference" || gotAuth != "" {
+		t.Errorf("unexpected whisper request: %s %q", gotPath, gotAuth)
+	}
+
+	if _, err := NewSpeechProvider(SpeechConfig{Provider: "acme"}); err == nil {
+		t.Error("expected an unknown provider to be rejected")
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+	if _, err := NewSpeechProvider(SpeechConfig{Provider: "openai"}); err == nil {
+		t.Error("expected the hosted OpenAI endpoint to require a key")
+	}
+}
+
+func TestDeepgramProvider(t *testing.T) {
+	var gotQuery, gotProtocol string
+	audio := make(chan int, 1)
+	upgrader := websocket.Upgrader{Subprotocols: []string{"token"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotProtocol = r.Header.Get("Sec-WebSocket-Protocol")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "Metadata"}`))
+		_, data, _ := conn.ReadMessage()
+		audio <- len(data)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "Results", "is_final": true, "start": 0.5, "duration": 1,
+			"channel": {"alternatives": [{"transcript": "make it blue", "confidence": 0.9}]}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	provider, err := NewSpeechProvider(SpeechConfig{URL: "ws" + strings.TrimPrefix(server.URL, "http"), APIKey: "dg-key"})
+	if err != nil {
+		t.Fatalf("NewSpeechProvider: %v", err)
+	}
+
+	var rec eventRecorder
+	stream, err := provider.Start(SpeechOptions{Language: "fr", SampleRate: 16000, Channels: 1}, rec.emit)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	stream.SendAudio([]byte{1, 2, 3, 4})
+	if n := <-audio; n != 4 {
+		t.Errorf("expected 4 bytes of audio, got %d", n)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for rec.summary() != "ready,final:make it blue" {
+		if time.Now().After(deadline) {
+			t.Fatalf("events = %s", rec.summary())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stream.Close()
+
+	if !strings.Contains(gotQuery, "language=fr") || !strings.Contains(gotQuery, "model=nova-3") || gotProtocol != "token, dg-key" {
+		t.Errorf("unexpected Deepgram request %q %q", gotQuery, gotProtocol)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if strings.Contains(rec.summary(), SpeechEventError) {
+		t.Errorf("expected no error after closing the stream, got %s", rec.summary())
+	}
+}
@@ -0,0 +1,264 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/debug"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsTokenParam is the query parameter carrying the per-proxy WebSocket secret.
+	wsTokenParam = "token"
+
+	// wsCloseAuthFailed is the close code sent to clients with a bad token.
+	// The injected script stops reconnecting when it sees it.
+	wsCloseAuthFailed = 4401
+
+	// authToastInterval limits rejection toasts so a hostile page that keeps
+	// reconnecting cannot flood the indicator.
+	authToastInterval = 10 * time.Second
+)
+
+// wsCapability is a set of things a browser WebSocket client may do.
+type wsCapability uint8
+
+const (
+	// capTelemetry covers logs, metrics and captures the page reports.
+	capTelemetry wsCapability = 1 << iota
+	// capAgent covers messages forwarded to the agent or typed into its PTY.
+	capAgent
+	// capData covers session and store API access.
+	capData
+
+	capAll = capTelemetry | capAgent | capData
+)
+
+// messageCapabilities maps browser message types that act on the agent or
+// on project data to the capability they require. Other types are telemetry.
+var messageCapabilities = map[string]wsCapability{
+	"panel_message":   capAgent,
+	"sketch":          capAgent,
+	"design_state":    capAgent,
+	"design_request":  capAgent,
+	"design_chat":     capAgent,
+	"agent_request":   capAgent,
+	"agent_cancel":    capAgent,
+	"voice_start":     capAgent,
+	"voice_stop":      capAgent,
+	"session_request": capData,
+	"store_request":   capData,
+}
+
+// allows reports whether c permits the given message type.
+func (c wsCapability) allows(msgType string) bool {
+	need, ok := messageCapabilities[msgType]
+	if !ok {
+		need = capTelemetry
+	}
+	return c&need == need
+}
+
+// wsAuth is the outcome of authorizing a browser WebSocket connection.
+type wsAuth struct {
+	origin string
+	caps   wsCapability
+	// reason is set when the connection is rejected.
+	reason string
+	// badToken is set when the origin is trusted but the token is wrong,
+	// e.g. a tab left open across a daemon restart.
+	badToken bool
+}
+
+// newWSToken returns a random per-proxy secret for the metrics WebSocket.
+func newWSToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms; fall back to
+		// something unpredictable enough to not be a fixed string.
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// MetricsURL returns the authenticated WebSocket URL of the metrics endpoint.
+// Browsers get the token through the injected script; this is for local tools.
+func (ps *ProxyServer) MetricsURL() string {
+	return "ws://" + ps.ListenAddr + "/__devtool_metrics?" + wsTokenParam + "=" + ps.wsToken
+}
+
+// authorizeWebSocket checks the origin and token of a metrics WebSocket
+// request and decides what the client may do.
+//
+// Pages served by the proxy connect same-origin with the token injected into
+// the page. Any other page open in the browser gets a cross-origin Origin
+// header, and DNS-rebinding pages are caught by the host check, so neither can
+// drive the agent even though the proxy port is predictable.
+func (ps *ProxyServer) authorizeWebSocket(r *http.Request) wsAuth {
+	auth := wsAuth{origin: r.Header.Get("Origin")}
+
+	loopbackOrigin := false
+	if auth.origin != "" {
+		u, err := url.Parse(auth.origin)
+		if err != nil || u.Host == "" {
+			auth.reason = "malformed origin"
+			return auth
+		}
+		if !strings.EqualFold(u.Host, r.Host) && !ps.isPublicHost(u.Host) {
+			auth.reason = "cross-origin connection from " + auth.origin
+			return auth
+		}
+		if !ps.trustedHost(u.Hostname()) {
+			auth.reason = "untrusted host " + u.Hostname()
+			return auth
+		}
+		loopbackOrigin = isLoopbackHost(u.Hostname())
+	}
+
+	token := r.URL.Query().Get(wsTokenParam)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(ps.wsToken)) != 1 {
+		auth.reason = "missing or invalid token"
+		auth.badToken = true
+		return auth
+	}
+
+	// Only pages opened on this machine may talk to the agent. Devices on a
+	// LAN share or tunnel still report telemetry.
+	if loopbackOrigin || (auth.origin == "" && isLoopbackAddr(r.RemoteAddr)) {
+		auth.caps = capAll
+	} else {
+		auth.caps = capTelemetry
+	}
+	return auth
+}
+
+// isPublicHost reports whether host matches the tunnel public URL.
+func (ps *ProxyServer) isPublicHost(host string) bool {
+	publicURL := ps.currentPublicURL()
+	if publicURL == "" {
+		return false
+	}
+	u, err := url.Parse(publicURL)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// trustedHost reports whether a browser-facing host name can be trusted not
+// to be a DNS-rebinding alias for the proxy.
+func (ps *ProxyServer) trustedHost(hostname string) bool {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if isLoopbackHost(hostname) || net.ParseIP(hostname) != nil {
+		return true
+	}
+	if publicURL := ps.currentPublicURL(); publicURL != "" {
+		if u, err := url.Parse(publicURL); err == nil && strings.EqualFold(u.Hostname(), hostname) {
+			return true
+		}
+	}
+	if machine, err := os.Hostname(); err == nil && machine != "" {
+		machine = strings.ToLower(machine)
+		if hostname == machine || hostname == machine+".local" {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopbackHost reports whether hostname refers to this machine.
+func isLoopbackHost(hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isLoopbackAddr reports whether a remote address is on this machine.
+func isLoopbackAddr(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// rejectWebSocket refuses a metrics WebSocket connection. Clients with a
+// trusted origin but a stale token are upgraded and told why, so the page can
+// stop reconnecting; everything else gets a plain 403.
+func (ps *ProxyServer) rejectWebSocket(w http.ResponseWriter, r *http.Request, auth wsAuth) {
+	debug.Log("proxy", "WebSocket rejected for proxy %s: remote=%s origin=%q: %s", ps.ID, r.RemoteAddr, auth.origin, auth.reason)
+
+	ps.logger.LogCustom(CustomLog{
+		ID:        fmt.Sprintf("metric-%d", ps.requestSeq.Add(1)),
+		Timestamp: time.Now(),
+		Level:     "warn",
+		Message:   "[Security] Rejected DevTool WebSocket connection: " + auth.reason,
+		Data: map[string]interface{}{
+			"origin":      auth.origin,
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		},
+	})
+
+	now := time.Now().UnixNano()
+	if last := ps.lastAuthToast.Load(); now-last >= int64(authToastInterval) && ps.lastAuthToast.CompareAndSwap(last, now) {
+		msg := "Blocked a connection to the DevTool socket: " + auth.reason
+		_, _ = ps.BroadcastToast("warning", "Connection blocked", msg, 0)
+	}
+
+	if !auth.badToken {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	conn, err := ps.wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_ = conn.WriteJSON(map[string]interface{}{
+		"type": "auth_error",
+		"payload": map[string]interface{}{
+			"message": "This page's DevTool session has expired. Reload the page to reconnect.",
+		},
+	})
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(wsCloseAuthFailed, "authentication failed"),
+		time.Now().Add(time.Second))
+}
+
+// denyWebSocketMessage tells a client it may not send a message type.
+// The log entry is written once per connection and type.
+func (ps *ProxyServer) denyWebSocketMessage(conn *wsConn, auth wsAuth, msgType, pageURL string, logged map[string]bool) {
+	if !logged[msgType] {
+		logged[msgType] = true
+		debug.Log("proxy", "WebSocket message %q denied for proxy %s from origin %q", msgType, ps.ID, auth.origin)
+		ps.logger.LogCustom(CustomLog{
+			ID:        fmt.Sprintf("metric-%d", ps.requestSeq.Add(1)),
+			Timestamp: time.Now(),
+			Level:     "warn",
+			Message:   fmt.Sprintf("[Security] Denied %s message from %s", msgType, auth.origin),
+			URL:       pageURL,
+		})
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{
+		"type": "toast",
+		"payload": map[string]interface{}{
+			"type":    "warning",
+			"title":   "Not available here",
+			"message": "Sending to the agent is only allowed from pages opened on localhost.",
+		},
+	})
+}
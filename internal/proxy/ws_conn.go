@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn is a browser WebSocket connection. gorilla/websocket supports one
+// writer at a time, and the read loop, broadcasts, agent streams and voice
+// sessions all write to the same connection, so writes are serialized.
+type wsConn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
+}
+
+func newWSConn(conn *websocket.Conn) *wsConn {
+	return &wsConn{Conn: conn}
+}
+
+// WriteMessage writes a message, waiting for other writers to finish.
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteMessage(messageType, data)
+}
+
+// WriteJSON writes a JSON message, waiting for other writers to finish.
+func (c *wsConn) WriteJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
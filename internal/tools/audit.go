@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/daemon"
+	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/textutil"
+	"github.com/standardbeagle/agnt/pkg/api"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// readOnlyActions are the actions of each tool that change nothing and are
+// not audited.
+var readOnlyActions = map[string]map[string]bool{
+	"proc":        {"status": true, "output": true, "list": true},
+	"proxy":       {"status": true, "list": true, "ca": true},
+	"proxylog":    {"query": true, "summary": true, "aggregate": true, "stats": true, "body": true},
+	"currentpage": {"list": true, "get": true, "summary": true},
+	"daemon":      {"status": true, "info": true, "audit": true},
+	"tunnel":      {"status": true, "list": true},
+	"session":     {"list": true, "get": true, "tasks": true},
+	"store":       {"list": true, "get": true, "get_all": true},
+	"snapshot":    {"list": true, "get": true},
+}
+
+// defaultActions are the actions of tools whose action is optional.
+var defaultActions = map[string]string{
+	"proxylog":    "query",
+	"currentpage": "list",
+}
+
+// auditInput holds the arguments of a tool call that decide how it is
+// audited.
+type auditInput struct {
+	Action string `json:"action"`
+	// Export is a file a read-only action writes, as in daemon audit
+	Export string `json:"export"`
+}
+
+// isAuditedCall reports whether a call of tool is recorded in the audit log:
+// every call that can change something, including read-only actions that
+// export to a file.
+func isAuditedCall(tool string, input auditInput) bool {
+	if tool == "detect" {
+		return false
+	}
+	action := input.Action
+	if action == "" {
+		action = defaultActions[tool]
+	}
+	return input.Export != "" || !readOnlyActions[tool][action]
+}
+
+// stopsDaemon reports whether a call of tool stops the daemon, which then
+// can't record it.
+func stopsDaemon(tool string, input auditInput) bool {
+	return tool == "daemon" && input.Action == "stop"
+}
+
+// maxPendingAudit bounds the entries kept while the daemon can't record
+// them. Once reached, audited calls are refused until the daemon is back.
+const maxPendingAudit = 1000
+
+// maxAuditString bounds each string argument kept in the audit log, such as
+// the JavaScript of proxy exec.
+const maxAuditString = 8 * 1024
+
+const redacted = "[REDACTED]"
+
+var (
+	// secretKey matches argument names whose values are secrets.
+	secretKey = regexp.MustCompile(`(?i)(token|secret|passw|api[_-]?key|auth|credential|cookie|private[_-]?key)`)
+	// urlPassword matches the password of a URL such as postgres://user:pw@host.
+	urlPassword = regexp.MustCompile(`(://[^:/@\s]+):[^@/\s]+@`)
+	// bearerToken matches bearer tokens in header values and code.
+	bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9._~+/=-]+`)
+)
+
+// redactArgs returns the arguments of a tool call with secrets replaced:
+// the values of secret-sounding keys, URL passwords and bearer tokens.
+// Long strings are truncated.
+func redactArgs(args json.RawMessage) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(args, &v); err != nil {
+		return nil
+	}
+	data, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if secretKey.MatchString(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+		return v
+	case string:
+		v = urlPassword.ReplaceAllString(v, "$1:"+redacted+"@")
+		v = bearerToken.ReplaceAllString(v, "${1}"+redacted)
+		if len(v) > maxAuditString {
+			v = fmt.Sprintf("%s... (%d bytes)", textutil.Truncate(v, maxAuditString), len(v))
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// AuditMiddleware records the tool calls that can change something in the
+// daemon's audit log of the project, with the MCP client and session that
+// made them. Calls are recorded once they return, except a daemon stop, which
+// is recorded before it runs and again if it fails. Entries are kept while
+// the daemon is not connected and recorded once it is; if too many are
+// waiting, audited calls are refused rather than left out of the log. A
+// remote daemon doesn't accept entries, so calls through one are not audited.
+func AuditMiddleware(dt *DaemonTools) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			call, ok := req.(*mcp.CallToolRequest)
+			if method != "tools/call" || !ok || call.Params == nil || dt.Remote() != nil {
+				return next(ctx, method, req)
+			}
+
+			var input auditInput
+			json.Unmarshal(call.Params.Arguments, &input)
+			if !isAuditedCall(call.Params.Name, input) {
+				return next(ctx, method, req)
+			}
+			if n := dt.pendingAudit(); n >= maxPendingAudit {
+				return errorResult(fmt.Sprintf("%s refused: %d earlier actions are waiting to be recorded in the audit log and the daemon is not reachable", call.Params.Name, n)), nil
+			}
+
+			// The daemon adds the session and project of the connection
+			entry := api.AuditEntry{
+				Time:   time.Now(),
+				Client: auditClient(call.Session),
+				Tool:   call.Params.Name,
+				Action: input.Action,
+				Args:   redactArgs(call.Params.Arguments),
+				Status: api.AuditStatusOK,
+			}
+			before := stopsDaemon(call.Params.Name, input)
+			if before {
+				dt.recordAudit(entry)
+			}
+
+			result, err := next(ctx, method, req)
+
+			entry.DurationMs = time.Since(entry.Time).Milliseconds()
+			msg, failed := callError(result, err)
+			if failed {
+				entry.Status = api.AuditStatusError
+				entry.Error = msg
+			}
+			if failed || !before {
+				dt.recordAudit(entry)
+			}
+
+			return result, err
+		}
+	}
+}
+
+// auditClient returns the name and version of the MCP client.
+func auditClient(session *mcp.ServerSession) string {
+	if session == nil {
+		return ""
+	}
+	params := session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return ""
+	}
+	return strings.TrimSpace(params.ClientInfo.Name + " " + params.ClientInfo.Version)
+}
+
+// callError returns the error of a failed tool call.
+func callError(result mcp.Result, err error) (string, bool) {
+	if err != nil {
+		return err.Error(), true
+	}
+	res, ok := result.(*mcp.CallToolResult)
+	if !ok || res == nil || !res.IsError {
+		return "", false
+	}
+	var msgs []string
+	for _, c := range res.Content {
+		if text, ok := c.(*mcp.TextContent); ok {
+			msgs = append(msgs, text.Text)
+		}
+	}
+	msg := strings.Join(msgs, "\n")
+	if len(msg) > 1024 {
+		msg = textutil.Truncate(msg, 1024) + "..."
+	}
+	return msg, true
+}
+
+// recordAudit queues an entry and sends the queue to the daemon, if
+// connected. Entries the daemon can't be reached for stay queued until the
+// next call or until the connection is restored.
+func (dt *DaemonTools) recordAudit(entry api.AuditEntry) {
+	dt.auditMu.Lock()
+	dt.auditPending = append(dt.auditPending, entry)
+	dt.auditMu.Unlock()
+
+	if client := dt.daemonClient(); client != nil {
+		dt.flushAudit(client.AuditRecord)
+	}
+}
+
+// flushAudit sends the queued entries with record, oldest first. It stops at
+// the first entry the daemon can't be reached for. An entry the daemon
+// refuses is dropped, as it would be refused again.
+func (dt *DaemonTools) flushAudit(record func(api.AuditEntry) error) {
+	dt.auditMu.Lock()
+	defer dt.auditMu.Unlock()
+	for len(dt.auditPending) > 0 {
+		entry := dt.auditPending[0]
+		if err := record(entry); errors.Is(err, daemon.ErrServerError) {
+			debug.Error("audit", "daemon refused %s %s: %v", entry.Tool, entry.Action, err)
+		} else if err != nil {
+			debug.Log("audit", "daemon unavailable, %d entries pending: %v", len(dt.auditPending), err)
+			return
+		}
+		dt.auditPending = dt.auditPending[1:]
+	}
+	dt.auditPending = nil
+}
+
+// pendingAudit returns the number of entries not recorded yet, after trying
+// to connect and send them if the queue is full.
+func (dt *DaemonTools) pendingAudit() int {
+	dt.auditMu.Lock()
+	n := len(dt.auditPending)
+	dt.auditMu.Unlock()
+	if n < maxPendingAudit || dt.ensureConnected() != nil {
+		return n
+	}
+
+	if client := dt.daemonClient(); client != nil {
+		dt.flushAudit(client.AuditRecord)
+	}
+	dt.auditMu.Lock()
+	defer dt.auditMu.Unlock()
+	return len(dt.auditPending)
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/standardbeagle/agnt/internal/daemon"
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+func TestIsAuditedCall(t *testing.T) {
+	tests := []struct {
+		tool  string
+		input auditInput
+		want  bool
+	}{
+		{"proc", auditInput{Action: "stop"}, true},
+		{"proc", auditInput{Action: "cleanup_port"}, true},
+		{"proxy", auditInput{Action: "exec"}, true},
+		{"daemon", auditInput{Action: "stop_all"}, true},
+		{"run", auditInput{}, true},
+		{"proxylog", auditInput{Action: "clear"}, true},
+		{"proc", auditInput{Action: "list"}, false},
+		{"proc", auditInput{Action: "output"}, false},
+		{"proxylog", auditInput{}, false},
+		{"currentpage", auditInput{}, false},
+		{"daemon", auditInput{Action: "audit"}, false},
+		{"daemon", auditInput{Action: "audit", Export: "audit.jsonl"}, true},
+		{"detect", auditInput{}, false},
+		// Exemptions belong to their tool
+		{"store", auditInput{Action: "get"}, false},
+		{"store", auditInput{Action: "set"}, true},
+		{"proc", auditInput{Action: "info"}, true},
+		{"proc", auditInput{Action: "audit"}, true},
+	}
+	for _, tt := range tests {
+		if got := isAuditedCall(tt.tool, tt.input); got != tt.want {
+			t.Errorf("isAuditedCall(%q, %+v) = %v, want %v", tt.tool, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := json.RawMessage(`{
+		"action": "start",
+		"auth_token": "ngrok-123",
+		"env": {"GITHUB_TOKEN": "ghp_abc", "PORT": "3000", "DATABASE_URL": "postgres://app:hunter2@db:5432/app"},
+		"code": "fetch('/api', {headers: {Authorization: 'Bearer eyJhbGciOi.x.y'}})"
+	}`)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(redactArgs(args), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	env := got["env"].(map[string]interface{})
+	if got["auth_token"] != redacted || env["GITHUB_TOKEN"] != redacted {
+		t.Errorf("secret keys not redacted: %v", got)
+	}
+	if env["PORT"] != "3000" || got["action"] != "start" {
+		t.Errorf("other values changed: %v", got)
+	}
+	if env["DATABASE_URL"] != "postgres://app:[REDACTED]@db:5432/app" {
+		t.Errorf("DATABASE_URL = %v", env["DATABASE_URL"])
+	}
+	if code := got["code"].(string); strings.Contains(code, "eyJ") || !strings.Contains(code, "Bearer [REDACTED]") {
+		t.Errorf("code = %s", code)
+	}
+
+	long := strings.Repeat("x", maxAuditString+10)
+	data, _ := json.Marshal(map[string]string{"code": long})
+	if out := string(redactArgs(data)); len(out) > maxAuditString+100 || !strings.Contains(out, "bytes)") {
+		t.Errorf("long string not truncated: %d bytes", len(out))
+	}
+
+	// A cut inside a multi-byte rune would be written as U+FFFD
+	long = "x" + strings.Repeat("é", maxAuditString)
+	data, _ = json.Marshal(map[string]string{"code": long})
+	if err := json.Unmarshal(redactArgs(data), &got); err != nil {
+		t.Fatal(err)
+	}
+	if code := got["code"].(string); strings.ContainsRune(code, utf8.RuneError) {
+		t.Error("truncated string split a rune")
+	}
+
+	if redactArgs(nil) != nil {
+		t.Error("no arguments should stay empty")
+	}
+}
+
+func TestFlushAudit_KeepsEntriesUntilRecorded(t *testing.T) {
+	dt := NewDaemonTools(daemon.AutoStartConfig{}, "test")
+
+	// Without a daemon the entries are kept
+	dt.recordAudit(api.AuditEntry{Tool: "proc", Action: "stop"})
+	dt.recordAudit(api.AuditEntry{Tool: "daemon", Action: "stop_all"})
+
+	var recorded []string
+	down := func(entry api.AuditEntry) error { return daemon.ErrNotConnected }
+	dt.flushAudit(down)
+	if len(dt.auditPending) != 2 {
+		t.Fatalf("expected 2 pending entries, got %d", len(dt.auditPending))
+	}
+
+	// The first entry is refused, the second recorded
+	dt.flushAudit(func(entry api.AuditEntry) error {
+		if entry.Tool == "proc" {
+			return fmt.Errorf("%w: [invalid_args] bad entry", daemon.ErrServerError)
+		}
+		recorded = append(recorded, entry.Tool+" "+entry.Action)
+		return nil
+	})
+	if len(dt.auditPending) != 0 || strings.Join(recorded, ",") != "daemon stop_all" {
+		t.Errorf("recorded %v, %d pending", recorded, len(dt.auditPending))
+	}
+}
@@ -3,15 +3,27 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/standardbeagle/agnt/internal/daemon"
+	"github.com/standardbeagle/agnt/pkg/api"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // DaemonInput defines input for the daemon management tool.
 type DaemonInput struct {
-	Action string `json:"action" jsonschema:"Action: status, info, start, stop, restart, stop_all, restart_all"`
+	Action string `json:"action" jsonschema:"Action: status, info, start, stop, restart, stop_all, restart_all, audit"`
+
+	// For audit
+	Path   string `json:"path,omitempty" jsonschema:"Project directory whose audit log is read (default: the session's project, or the global log without a session)"`
+	Tool   string `json:"tool,omitempty" jsonschema:"Only entries of this tool, e.g. proc"`
+	Status string `json:"status,omitempty" jsonschema:"Only entries with this result: ok or error"`
+	Since  string `json:"since,omitempty" jsonschema:"Only entries since a duration ago (e.g. 24h) or an RFC3339 time"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"Most recent entries to return (default 100, -1 for all)"`
+	Export string `json:"export,omitempty" jsonschema:"Write the matching entries to this file as JSONL instead of returning them"`
 }
 
 // DaemonOutput defines output for daemon management.
@@ -27,6 +39,9 @@ type DaemonOutput struct {
 	ProcessInfo *ProcessInfo `json:"process_info,omitempty"`
 	ProxyInfo   *ProxyInfo   `json:"proxy_info,omitempty"`
 
+	// For audit
+	Audit *api.AuditResult `json:"audit,omitempty"`
+
 	// For stop_all/restart_all
 	ProcessesStopped int `json:"processes_stopped,omitempty"`
 	ProxiesStopped   int `json:"proxies_stopped,omitempty"`
@@ -70,6 +85,8 @@ Actions:
   restart: Restart the daemon
   stop_all: Stop all processes and proxies (daemon keeps running)
   restart_all: Restart all processes and proxies (stop then start with same config)
+  audit: Show the actions MCP clients performed in the project (tool calls that
+    change something, with client, session, redacted arguments and result)
 
 Examples:
   daemon {action: "status"}
@@ -79,6 +96,8 @@ Examples:
   daemon {action: "restart"}
   daemon {action: "stop_all"}
   daemon {action: "restart_all"}
+  daemon {action: "audit", since: "24h", tool: "proxy"}
+  daemon {action: "audit", limit: -1, export: "audit.jsonl"}
 
 The daemon auto-starts when needed, so manual start is rarely required.
 Use stop_all/restart_all to manage running resources without stopping the daemon.`,
@@ -87,6 +106,15 @@ Use stop_all/restart_all to manage running resources without stopping the daemon
 
 func makeDaemonHandler(dt *DaemonTools) func(context.Context, *mcp.CallToolRequest, DaemonInput) (*mcp.CallToolResult, DaemonOutput, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input DaemonInput) (*mcp.CallToolResult, DaemonOutput, error) {
+		if remote := dt.Remote(); remote != nil {
+			switch input.Action {
+			case "status":
+				return handleRemoteDaemonStatus(dt, remote)
+			case "start", "stop", "restart":
+				return errorResult(fmt.Sprintf("the daemon at %s%s is remote: %s it on its machine", daemon.RemoteScheme, remote.Addr, input.Action)), DaemonOutput{}, nil
+			}
+		}
+
 		switch input.Action {
 		case "status":
 			return handleDaemonStatus(dt)
@@ -102,8 +130,10 @@ func makeDaemonHandler(dt *DaemonTools) func(context.Context, *mcp.CallToolReque
 			return handleDaemonStopAll(dt)
 		case "restart_all":
 			return handleDaemonRestartAll(dt)
+		case "audit":
+			return handleDaemonAudit(dt, input)
 		default:
-			return errorResult(fmt.Sprintf("unknown action %q. Use: status, info, start, stop, restart, stop_all, restart_all", input.Action)), DaemonOutput{}, nil
+			return errorResult(fmt.Sprintf("unknown action %q. Use: status, info, start, stop, restart, stop_all, restart_all, audit", input.Action)), DaemonOutput{}, nil
 		}
 	}
 }
@@ -123,6 +153,16 @@ func handleDaemonStatus(dt *DaemonTools) (*mcp.CallToolResult, DaemonOutput, err
 	}, nil
 }
 
+// handleRemoteDaemonStatus reports whether the remote daemon can be reached.
+func handleRemoteDaemonStatus(dt *DaemonTools, remote *daemon.RemoteDialConfig) (*mcp.CallToolResult, DaemonOutput, error) {
+	running := dt.ensureConnected() == nil
+	return nil, DaemonOutput{
+		Running:    running,
+		SocketPath: daemon.RemoteScheme + remote.Addr,
+		Message:    formatStatusMessage(running),
+	}, nil
+}
+
 func handleDaemonInfo(dt *DaemonTools) (*mcp.CallToolResult, DaemonOutput, error) {
 	if err := dt.ensureConnected(); err != nil {
 		return errorResult(fmt.Sprintf("daemon not running: %v", err)), DaemonOutput{}, nil
@@ -204,10 +244,7 @@ func handleDaemonStop(dt *DaemonTools) (*mcp.CallToolResult, DaemonOutput, error
 	}
 
 	// Close our connection
-	if dt.client != nil {
-		dt.client.Close()
-		dt.client = nil
-	}
+	dt.Close()
 
 	return nil, DaemonOutput{
 		Running:    false,
@@ -230,10 +267,7 @@ func handleDaemonRestart(dt *DaemonTools) (*mcp.CallToolResult, DaemonOutput, er
 		}
 
 		// Close our connection
-		if dt.client != nil {
-			dt.client.Close()
-			dt.client = nil
-		}
+		dt.Close()
 	}
 
 	// Start again
@@ -303,3 +337,61 @@ func handleDaemonRestartAll(dt *DaemonTools) (*mcp.CallToolResult, DaemonOutput,
 		Message:          fmt.Sprintf("Restarted %d processes, %d proxies", processesRestarted, proxiesRestarted),
 	}, nil
 }
+
+func handleDaemonAudit(dt *DaemonTools, input DaemonInput) (*mcp.CallToolResult, DaemonOutput, error) {
+	if err := dt.ensureConnected(); err != nil {
+		return errorResult(fmt.Sprintf("daemon not running: %v", err)), DaemonOutput{}, nil
+	}
+
+	q := api.AuditQuery{Tool: input.Tool, Status: input.Status, Limit: input.Limit}
+	if input.Path != "" {
+		path, err := filepath.Abs(input.Path)
+		if err != nil {
+			return errorResult(fmt.Sprintf("invalid path: %v", err)), DaemonOutput{}, nil
+		}
+		q.ProjectPath = path
+	}
+	if input.Since != "" {
+		since, err := daemon.ParseSince(input.Since, time.Now())
+		if err != nil {
+			return errorResult(err.Error()), DaemonOutput{}, nil
+		}
+		q.Since = since
+	}
+
+	result, err := dt.client.AuditQuery(q)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to query audit log: %v", err)), DaemonOutput{}, nil
+	}
+
+	if input.Export != "" {
+		if err := exportAudit(input.Export, result.Entries); err != nil {
+			return errorResult(fmt.Sprintf("failed to export audit log: %v", err)), DaemonOutput{}, nil
+		}
+		return nil, DaemonOutput{
+			Running: true,
+			Success: true,
+			Message: fmt.Sprintf("Exported %d of %d entries to %s", result.Count, result.Total, input.Export),
+		}, nil
+	}
+
+	return nil, DaemonOutput{
+		Running: true,
+		Audit:   result,
+		Success: true,
+		Message: fmt.Sprintf("%d of %d entries from %s", result.Count, result.Total, result.File),
+	}, nil
+}
+
+// exportAudit writes audit entries to a JSONL file.
+func exportAudit(path string, entries []api.AuditEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := daemon.WriteAuditJSONL(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -6,28 +6,38 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/standardbeagle/agnt/internal/daemon"
 	"github.com/standardbeagle/agnt/internal/debug"
+	"github.com/standardbeagle/agnt/internal/project"
 	"github.com/standardbeagle/agnt/internal/protocol"
+	"github.com/standardbeagle/agnt/internal/proxy"
+	"github.com/standardbeagle/agnt/pkg/api"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // DaemonTools wraps a daemon client for MCP tool handlers.
 type DaemonTools struct {
-	client  *daemon.ResilientClient
-	config  daemon.AutoStartConfig
-	version string // Client version for validation
+	client   *daemon.ResilientClient
+	clientMu sync.RWMutex // Protects client
+	config   daemon.AutoStartConfig
+	version  string // Client version for validation
+	remote   *daemon.RemoteDialConfig
 
 	// Session management
 	sessionCode     string     // Attached session code (empty if not attached)
 	sessionMu       sync.Mutex // Protects sessionCode
 	noAutoAttach    bool       // If true, skip auto-attach on connect
 	attachAttempted bool       // Whether we've attempted auto-attach
+
+	// Audit entries the daemon has not recorded yet, oldest first
+	auditPending []api.AuditEntry
+	auditMu      sync.Mutex // Protects auditPending and orders the records
 }
 
 // NewDaemonTools creates a new daemon tools wrapper with auto-start and version checking.
@@ -47,6 +57,18 @@ func (dt *DaemonTools) SetNoAutoAttach(noAttach bool) {
 	dt.noAutoAttach = noAttach
 }
 
+// SetRemote connects to a daemon's TCP listener instead of the local socket.
+// Call this before any tool calls.
+func (dt *DaemonTools) SetRemote(remote *daemon.RemoteDialConfig) {
+	dt.remote = remote
+}
+
+// Remote returns the remote daemon's configuration, or nil if the daemon is
+// local.
+func (dt *DaemonTools) Remote() *daemon.RemoteDialConfig {
+	return dt.remote
+}
+
 // SetSessionCode sets the session code directly (useful for testing or explicit attachment).
 func (dt *DaemonTools) SetSessionCode(code string) {
 	dt.sessionMu.Lock()
@@ -98,6 +120,27 @@ func (dt *DaemonTools) tryAutoAttach() {
 	}
 }
 
+// reattachSession attaches a new connection to the session the tools were
+// attached to, as the daemon ties a session to the connection.
+func (dt *DaemonTools) reattachSession(attach func(directory string) (map[string]interface{}, error)) {
+	code := dt.SessionCode()
+	if code == "" {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	result, err := attach(cwd)
+	if err != nil {
+		debug.Log("tools", "failed to re-attach to session %s: %v", code, err)
+		return
+	}
+	if newCode, ok := result["session_code"].(string); ok && newCode != "" && newCode != code {
+		dt.SetSessionCode(newCode)
+	}
+}
+
 // ensureConnected ensures we have a connection to the daemon with automatic version checking and upgrade.
 // It also attempts to auto-attach to a session on first connection.
 func (dt *DaemonTools) ensureConnected() error {
@@ -111,6 +154,7 @@ func (dt *DaemonTools) ensureConnected() error {
 	resilientConfig := daemon.DefaultResilientClientConfig()
 	resilientConfig.AutoStartConfig = dt.config
 	resilientConfig.ClientVersion = dt.version
+	resilientConfig.Remote = dt.remote
 
 	// Configure auto-upgrade callback for version mismatches
 	resilientConfig.OnVersionMismatch = func(clientVer, daemonVer string) error {
@@ -137,26 +181,50 @@ func (dt *DaemonTools) ensureConnected() error {
 		return nil
 	}
 
+	// Once the daemon is back, attach to the session again and record the
+	// audit entries kept meanwhile
+	resilientConfig.OnReconnect = func(c *daemon.Client) error {
+		dt.reattachSession(c.SessionAttach)
+		dt.flushAudit(c.AuditRecord)
+		return nil
+	}
+
 	// Create and connect ResilientClient
 	client := daemon.NewResilientClient(resilientConfig)
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 
+	dt.clientMu.Lock()
 	dt.client = client
+	dt.clientMu.Unlock()
 
-	// Try to auto-attach to a session for the current directory
+	// Attach to the session of a previous connection, or else try to
+	// auto-attach to a session for the current directory
+	dt.reattachSession(client.SessionAttach)
 	dt.tryAutoAttach()
+	dt.flushAudit(client.AuditRecord)
 
 	return nil
 }
 
 // Close closes the daemon client connection.
 func (dt *DaemonTools) Close() error {
-	if dt.client != nil {
-		return dt.client.Close()
+	dt.clientMu.Lock()
+	defer dt.clientMu.Unlock()
+	if dt.client == nil {
+		return nil
 	}
-	return nil
+	err := dt.client.Close()
+	dt.client = nil
+	return err
+}
+
+// daemonClient returns the daemon client, or nil if not connected.
+func (dt *DaemonTools) daemonClient() *daemon.ResilientClient {
+	dt.clientMu.RLock()
+	defer dt.clientMu.RUnlock()
+	return dt.client
 }
 
 // RegisterDaemonTools adds all MCP tools that communicate with the daemon.
@@ -165,6 +233,8 @@ func RegisterDaemonTools(server *mcp.Server, dt *DaemonTools) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "detect",
 		Description: `Detect project type and available scripts.
+Also checks the project's .agnt.kdl: config.issues lists unknown keys, type errors and
+invalid references with file, line and column.
 Example: detect {path: "."} → {type: "go", scripts: ["test", "build", "lint"]}`,
 	}, dt.makeDetectHandler())
 
@@ -233,6 +303,9 @@ Actions:
   list: List all running proxies
   exec: Execute JavaScript in connected browser clients
   toast: Send toast notification to connected browsers
+  share: Share the proxy with phones/tablets on the same network (returns LAN URLs and a QR code)
+  unshare: Stop sharing the proxy on the local network
+  ca: Show the local agnt CA certificate and how to trust it
 
 Examples:
   proxy {action: "start", id: "dev", target_url: "http://localhost:3000"}
@@ -261,6 +334,47 @@ Toast notifications:
   proxy {action: "toast", id: "dev", toast_type: "warning", toast_message: "Slow network detected", toast_duration: 8000}
   Toast types: success, error, warning, info (default)
 
+LAN sharing (device testing on the same Wi-Fi):
+  proxy {action: "share", id: "dev"}
+  proxy {action: "share", id: "dev", share_https: true}
+  Opens an extra listener on all interfaces; the proxy's own port stays as bound.
+  The QR code is also shown in the browser indicator. With share_https, certificates
+  come from a local agnt CA; install it on the device from ca_url to avoid warnings.
+
+HTTPS (secure cookies, SameSite=None, service workers, OAuth redirects to https):
+  proxy {action: "start", id: "dev", target_url: "http://localhost:3000", https: true}
+  proxy {action: "ca"}   # CA path and install instructions per OS and for phones
+  Adds an HTTP/2 listener (https_url) next to the HTTP one, with a certificate from
+  a local agnt CA. Upstreams see X-Forwarded-Proto: https; Set-Cookie Secure and
+  SameSite=None are adjusted to the scheme the browser used.
+
+Multi-upstream routing (one origin for a frontend and its backends, no CORS):
+  proxy {action: "start", id: "app", target_url: "http://localhost:3000", routes: [
+    {path: "/api", target: "http://localhost:8080", strip_prefix: true},
+    {name: "auth", pattern: "^/(login|logout)", target: "http://localhost:9000", host_header: "auth.local"}]}
+  Routes are checked in order; unmatched paths go to target_url. Logged http entries
+  carry an 'upstream' field, and chaos rules accept 'upstream' to target one backend.
+
+Body capture (full request/response bodies, fetched with proxylog action "body"):
+  proxy {action: "start", id: "dev", target_url: "http://localhost:3000", body_inline_limit: 32768}
+  proxy {action: "start", id: "dev", target_url: "http://localhost:3000", body_max_size: -1}  # off
+  Logs preview the first 10KB of each body; up to 10MB per body is kept on disk.
+
+GraphQL (operations are parsed from requests, including batches and persisted queries):
+  proxy {action: "chaos", id: "dev", chaos_operation: "add_rule", chaos_rule: {id: "unauth",
+    type: "graphql_error", enabled: true, operation: "^GetViewer$", graphql_error_code: "UNAUTHENTICATED"}}
+  proxy {action: "chaos", id: "dev", chaos_operation: "add_rule", chaos_rule: {id: "slow-mutations",
+    type: "latency", enabled: true, operation_type: "mutation", min_latency_ms: 2000}}
+  Any chaos rule accepts 'operation' (regex on operation names) and 'operation_type'.
+  graphql_error answers with {"data": null, "errors": [...]} (status 200 unless error_codes).
+
+Streaming responses (SSE, NDJSON; each event is flushed to the browser as it arrives):
+  proxy {action: "chaos", id: "dev", chaos_operation: "add_rule", chaos_rule: {id: "slow-ticks",
+    type: "sse_delay", enabled: true, url_pattern: "/events", min_latency_ms: 500, max_latency_ms: 2000}}
+  proxy {action: "chaos", id: "dev", chaos_operation: "add_rule", chaos_rule: {id: "lose-updates",
+    type: "sse_drop", enabled: true, event_pattern: "^update$", event_probability: 0.2}}
+  sse_delay and sse_drop act per event; event_pattern matches the event name or data.
+
 __devtool API (injected into browser):
   proxy {action: "exec", help: true}                    # Full API overview
   proxy {action: "exec", describe: "screenshot"}        # Detailed function docs
@@ -284,8 +398,10 @@ Each proxy has separate log storage and WebSocket connections.`,
 Actions:
   query: Search logs with filters (default, may be large)
   summary: Get compact aggregated summary (recommended for large logs)
+  aggregate: Group HTTP entries with count, duration percentiles and statuses
   clear: Clear all logs for a proxy
   stats: Get log statistics
+  body: Fetch the full request or response body of an HTTP entry
 
 Log Types:
   http: HTTP request/response pairs
@@ -297,15 +413,23 @@ Log Types:
   response: JavaScript execution responses
   interaction: User interactions (clicks, keyboard, scroll)
   mutation: DOM mutations (added, removed, modified elements)
+  console: console.warn/console.error calls and failed console.assert, with argument previews
+  rejection: Unhandled promise rejections with stacks
+  web_vital: LCP, CLS, INP, FCP, TTFB and long tasks, rated and attributed to an element
   panel_message: Messages sent from the floating indicator panel
   sketch: Sketches/wireframes from sketch mode (includes JSON data and PNG image path)
+  stream_event: One event of an SSE or NDJSON response (request_id links the http entry)
 
 Summary Action (Recommended for Large Logs):
   The summary action aggregates logs by type and provides:
   - Counts by type (errors, http, performance, etc.)
   - Deduplicated error summaries (top 10 unique errors)
   - HTTP status/method breakdown
+  - Latency percentiles for the slowest endpoints, split into upstream and chaos time
   - Average performance metrics
+  - Console messages by level, deduplicated; unhandled rejections
+  - Web vitals per metric (p75, rating, worst value with element and page)
+    and long task count with total blocking time
   - Recent entries for each type (last 5)
 
   Progressive reveal with detail parameter:
@@ -314,6 +438,8 @@ Summary Action (Recommended for Large Logs):
   - detail: ["performance"] - include performance metrics
   - detail: ["interactions"] - include user interactions
   - detail: ["mutations"] - include DOM mutations
+  - detail: ["console"] - include console messages
+  - detail: ["rejections"] - include unhandled rejections
   - detail: ["other"] - include custom/panel/sketch logs
   - limit: N - max items per detailed section (default: 10, max: 100)
 
@@ -334,6 +460,61 @@ Summary Examples (Recommended):
   proxylog {proxy_id: "dev", action: "summary", detail: ["errors", "http"], limit: 20}
   proxylog {proxy_id: "dev", action: "summary", types: ["error"]}
 
+Structured Filters (query, summary and aggregate):
+  request_headers / response_headers: {name: substring}, "" = header present
+  min_duration / max_duration: request time bounds, e.g. "500ms"
+  body_path / request_body_path: JSONPath predicate on a JSON body, with
+    == != < <= > >= or =~ /regex/ (no operator: path exists)
+  error_pattern: regex over error messages
+  Header, duration and body filters only match HTTP entries.
+  proxylog {proxy_id: "dev", body_path: "$.errors[0].code == \"UNAUTHENTICATED\""}
+  proxylog {proxy_id: "dev", min_duration: "1s", response_headers: {"cache-control": "no-store"}}
+  proxylog {proxy_id: "dev", types: ["error"], error_pattern: "Cannot read prop"}
+
+Aggregate Action:
+  Groups HTTP entries and reports count, errors, avg/p50/p95/max duration
+  and status counts, largest groups first. group_by: endpoint (method and
+  path template, ids replaced by {id}), path, method, status, status_class,
+  upstream, operation (GraphQL) (default: endpoint).
+  proxylog {proxy_id: "dev", action: "aggregate"}
+  proxylog {proxy_id: "dev", action: "aggregate", group_by: ["upstream", "status"]}
+  proxylog {proxy_id: "dev", action: "aggregate", url_pattern: "/api", since: "10m"}
+
+GraphQL:
+  http entries of GraphQL requests carry 'graphql': operation type, name, variables,
+  persisted query hash, batch flag and the response's errors (message, code, path).
+  proxylog {proxy_id: "dev", operation: "GetViewer"}
+  proxylog {proxy_id: "dev", action: "aggregate", group_by: ["operation"]}
+  The summary action counts requests and error responses per operation.
+
+Latency Breakdown:
+  http entries carry 'timing' (nanoseconds): chaos (delay injected by chaos rules),
+  dns, connect, tls, ttfb (request sent to first byte), transfer (body, paced by
+  the browser), upstream (connection to last byte), reused, and server_timing
+  metrics parsed from the upstream's Server-Timing header. duration minus upstream
+  and chaos is time spent in the proxy and the browser. The summary lists the
+  slowest endpoints (http_endpoints) with p50/p95 overall and upstream; aggregate
+  groups report upstream_p50_ms, upstream_p95_ms and chaos_avg_ms.
+  proxylog {proxy_id: "dev", action: "summary"}
+  proxylog {proxy_id: "dev", min_duration: "1s"}   # then compare timing.upstream
+
+Streaming Responses:
+  SSE (text/event-stream) and NDJSON responses get an http entry when headers
+  arrive, with 'stream': {kind, open, first_byte, events, bytes}, updated when
+  the stream ends. Each event is a stream_event entry (event name, id, data
+  preview, offset from the request start) whose request_id is the http entry id.
+  proxylog {proxy_id: "dev", types: ["stream_event"], url_pattern: "/events"}
+
+HTTP Bodies:
+  Bodies are decoded (gzip, deflate) and previewed by type: JSON is
+  pretty-printed, forms and multipart uploads are listed field by field,
+  and binary bodies show their type and size. request_body_info and
+  response_body_info record size, kind and encoding. Bodies larger than the
+  preview (10KB by default, see proxy body_inline_limit) are stored on disk:
+  proxylog {proxy_id: "dev", action: "body", request_id: "req-42"}
+  proxylog {proxy_id: "dev", action: "body", request_id: "req-42", part: "request"}
+  Binary bodies are returned as base64.
+
 Other Actions:
   proxylog {proxy_id: "dev", action: "stats"}
   proxylog {proxy_id: "dev", action: "clear"}
@@ -358,12 +539,19 @@ A page session groups together:
   - Performance metrics (page load time, paint timing, etc.)
   - User interactions (clicks, keyboard, scroll, etc.)
   - DOM mutations (added, removed, modified elements)
+  - Console warnings/errors and unhandled promise rejections
+  - Web vitals (LCP, CLS, INP, FCP, TTFB) and long tasks
+  - Routes: document loads, pages served by a service worker or the
+    back/forward cache, and client-side navigations (pushState, popstate,
+    hash changes) of single-page apps, each with its own errors,
+    interactions and performance
 
 Examples:
   currentpage {proxy_id: "dev"}
   currentpage {proxy_id: "dev", action: "summary", session_id: "page-1"}
   currentpage {proxy_id: "dev", action: "summary", session_id: "page-1", detail: ["interactions"], limit: 20}
   currentpage {proxy_id: "dev", action: "summary", session_id: "page-1", detail: ["interactions", "mutations"]}
+  currentpage {proxy_id: "dev", action: "summary", session_id: "page-1", detail: ["routes"], limit: 20}
   currentpage {proxy_id: "dev", action: "get", session_id: "page-1"}
   currentpage {proxy_id: "dev", action: "clear"}
 
@@ -375,6 +563,16 @@ The summary action returns aggregated data (errors by type, interactions by type
   - detail: ["mutations"] - include full mutation list
   - detail: ["errors"] - include compact error list (truncated stacks/messages)
   - detail: ["resources"] - include full resource URL list
+  - detail: ["console"] - include the last N console messages instead of the last 5
+  - detail: ["routes"] - include the last N routes instead of the last 5
+
+Each route lists its source (document, service_worker, cache, push, pop, hash),
+time spent on it, error/interaction/mutation counts, requests answered by a
+service worker or cache (cached_fetch_count), load_time_ms for document loads
+and settle_ms (time until the DOM was quiet) for client-side navigations.
+Web vitals are reported per page and per route (CLS and INP restart on a
+client-side navigation), each with a rating (good, needs-improvement, poor)
+and the selector of the element responsible.
 
 Error format is automatically compacted to prevent token overflow:
   - Stack traces limited to first 3 lines
@@ -438,10 +636,34 @@ func (dt *DaemonTools) makeDetectHandler() func(context.Context, *mcp.CallToolRe
 			output.PackageManager = pm
 		}
 
+		output.Commands = detectedCommands(result)
+
+		if cfg, ok := result["config"]; ok {
+			if data, err := json.Marshal(cfg); err == nil {
+				var check ConfigCheckOutput
+				if json.Unmarshal(data, &check) == nil {
+					output.Config = &check
+				}
+			}
+		}
+
 		return nil, output, nil
 	}
 }
 
+// detectedCommands returns the commands of a DETECT response.
+func detectedCommands(result map[string]interface{}) []project.CommandDef {
+	var detected api.DetectResult
+	if decodeResult(result, &detected) != nil || detected.Commands == nil {
+		return nil
+	}
+	commands := make([]project.CommandDef, len(detected.Commands))
+	for i, c := range detected.Commands {
+		commands[i] = project.CommandDef(c)
+	}
+	return commands
+}
+
 // makeRunHandler creates a handler for the run tool.
 func (dt *DaemonTools) makeRunHandler() func(context.Context, *mcp.CallToolRequest, RunInput) (*mcp.CallToolResult, RunOutput, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input RunInput) (*mcp.CallToolResult, RunOutput, error) {
@@ -477,6 +699,26 @@ func (dt *DaemonTools) makeRunHandler() func(context.Context, *mcp.CallToolReque
 			config.Mode = "background"
 		}
 
+		// The daemon only knows the built-in commands of a script name; a
+		// command from config.kdl or .agnt.kdl is run as given
+		if !config.Raw && config.ScriptName != "" {
+			if detected, err := dt.client.Detect(absPath); err == nil {
+				for _, cmd := range detectedCommands(detected) {
+					if cmd.Name != config.ScriptName || cmd.Source == "" || cmd.Source == project.SourceBuiltin {
+						continue
+					}
+					if config.ID == "" {
+						config.ID = config.ScriptName
+					}
+					config.Raw = true
+					config.Command = cmd.Command
+					config.Args = append(append([]string{}, cmd.Args...), input.Args...)
+					config.Env = commandEnv(config.Env, cmd.Env)
+					break
+				}
+			}
+		}
+
 		result, err := dt.client.Run(config)
 		if err != nil {
 			return formatDaemonError(err, "run"), RunOutput{}, nil
@@ -534,13 +776,21 @@ func (dt *DaemonTools) handleProcStatus(input ProcInput) (*mcp.CallToolResult, P
 		return formatDaemonError(err, "proc"), ProcOutput{}, nil
 	}
 
-	return nil, ProcOutput{
-		ProcessID: getString(result, "process_id"),
-		State:     getString(result, "state"),
-		Summary:   getString(result, "summary"),
-		ExitCode:  getInt(result, "exit_code"),
-		Runtime:   getString(result, "runtime"),
-	}, nil
+	var proc api.Process
+	if err := decodeResult(result, &proc); err != nil {
+		return errorResult(err.Error()), ProcOutput{}, nil
+	}
+
+	output := ProcOutput{
+		ProcessID: proc.ID,
+		State:     proc.State,
+		Summary:   proc.Summary,
+		Runtime:   proc.Runtime,
+	}
+	if proc.ExitCode != nil {
+		output.ExitCode = *proc.ExitCode
+	}
+	return nil, output, nil
 }
 
 func (dt *DaemonTools) handleProcOutput(input ProcInput) (*mcp.CallToolResult, ProcOutput, error) {
@@ -702,8 +952,14 @@ func (dt *DaemonTools) makeProxyHandler() func(context.Context, *mcp.CallToolReq
 			return dt.handleProxyExec(input)
 		case "toast":
 			return dt.handleProxyToast(input)
+		case "share":
+			return dt.handleProxyShare(input)
+		case "unshare":
+			return dt.handleProxyUnshare(input)
 		case "chaos":
 			return dt.handleProxyChaos(input)
+		case "ca":
+			return handleProxyCA()
 		default:
 			return errorResult(fmt.Sprintf("unknown action %q", input.Action)), ProxyOutput{}, nil
 		}
@@ -736,6 +992,10 @@ func (dt *DaemonTools) handleProxyStart(input ProxyInput) (*mcp.CallToolResult,
 		BindAddress: input.BindAddress,
 		PublicURL:   input.PublicURL,
 		VerifyTLS:   input.VerifyTLS,
+		Routes:      routeInputsToProtocol(input.Routes),
+		HTTPS:       input.HTTPS,
+		HTTPSPort:   input.HTTPSPort,
+		BodyCapture: bodyCaptureInput(input),
 	}
 
 	// Configure tunnel if specified
@@ -769,6 +1029,15 @@ func (dt *DaemonTools) handleProxyStart(input ProxyInput) (*mcp.CallToolResult,
 		accessURL = fmt.Sprintf("http://<your-ip>%s", listenAddr)
 	}
 
+	message := fmt.Sprintf("Proxy started. Access at %s", accessURL)
+	httpsURL := getString(result, "https_url")
+	if httpsURL != "" {
+		message += " or " + httpsURL + " (trust the agnt CA: proxy {action: \"ca\"})"
+	}
+	if len(input.Routes) > 0 {
+		message += fmt.Sprintf(" (%d routes, unmatched paths go to %s)", len(input.Routes), getString(result, "target_url"))
+	}
+
 	return nil, ProxyOutput{
 		ID:          getString(result, "id"),
 		TargetURL:   getString(result, "target_url"),
@@ -776,7 +1045,9 @@ func (dt *DaemonTools) handleProxyStart(input ProxyInput) (*mcp.CallToolResult,
 		BindAddress: bindAddress,
 		PublicURL:   publicURL,
 		TunnelURL:   tunnelURL,
-		Message:     fmt.Sprintf("Proxy started. Access at %s", accessURL),
+		HTTPSURL:    httpsURL,
+		CACertPath:  getString(result, "ca_cert_path"),
+		Message:     message,
 	}, nil
 }
 
@@ -845,6 +1116,11 @@ func (dt *DaemonTools) handleProxyStatus(input ProxyInput) (*mcp.CallToolResult,
 		}
 	}
 
+	if stats, ok := result["stats"].(map[string]interface{}); ok {
+		output.ShareURLs = getStringSlice(stats, "share_urls")
+		output.HTTPSURL = getString(stats, "https_url")
+	}
+
 	return nil, output, nil
 }
 
@@ -1007,6 +1283,54 @@ func (dt *DaemonTools) handleProxyToast(input ProxyInput) (*mcp.CallToolResult,
 	}, nil
 }
 
+func (dt *DaemonTools) handleProxyShare(input ProxyInput) (*mcp.CallToolResult, ProxyOutput, error) {
+	if input.ID == "" {
+		return errorResult("id required for share"), ProxyOutput{}, nil
+	}
+
+	result, err := dt.client.ProxyShare(input.ID, protocol.ShareConfig{
+		HTTPS: input.ShareHTTPS,
+		Port:  input.SharePort,
+	})
+	if err != nil {
+		return formatDaemonError(err, "proxy"), ProxyOutput{}, nil
+	}
+
+	urls := getStringSlice(result, "urls")
+
+	message := "Open on a device on the same network: " + strings.Join(urls, ", ")
+	if getString(result, "ca_url") != "" {
+		message += ". Install the agnt CA from " + getString(result, "ca_url") + " to trust HTTPS."
+	}
+
+	return nil, ProxyOutput{
+		Success:    true,
+		ID:         getString(result, "id"),
+		ListenAddr: getString(result, "listen_addr"),
+		ShareURLs:  urls,
+		QRCode:     getString(result, "qr_terminal"),
+		CACertPath: getString(result, "ca_cert_path"),
+		CAURL:      getString(result, "ca_url"),
+		Message:    message,
+	}, nil
+}
+
+func (dt *DaemonTools) handleProxyUnshare(input ProxyInput) (*mcp.CallToolResult, ProxyOutput, error) {
+	if input.ID == "" {
+		return errorResult("id required for unshare"), ProxyOutput{}, nil
+	}
+
+	if err := dt.client.ProxyUnshare(input.ID); err != nil {
+		return formatDaemonError(err, "proxy"), ProxyOutput{}, nil
+	}
+
+	return nil, ProxyOutput{
+		Success: true,
+		ID:      input.ID,
+		Message: "LAN share stopped",
+	}, nil
+}
+
 // parseChaosStats extracts ChaosStatsOutput from a map result.
 func parseChaosStats(stats map[string]interface{}) *ChaosStatsOutput {
 	output := &ChaosStatsOutput{
@@ -1017,6 +1341,8 @@ func parseChaosStats(stats map[string]interface{}) *ChaosStatsOutput {
 		DropsInjected:   getInt64(stats, "drops_injected"),
 		TruncatedCount:  getInt64(stats, "truncated_count"),
 		ReorderedCount:  getInt64(stats, "reordered_count"),
+		EventsDelayed:   getInt64(stats, "events_delayed"),
+		EventsDropped:   getInt64(stats, "events_dropped"),
 	}
 	if ruleStats, ok := stats["rule_stats"].(map[string]interface{}); ok {
 		output.RuleStats = make(map[string]int64)
@@ -1040,6 +1366,7 @@ func parseChaosRules(rules []interface{}) []ChaosRuleOutput {
 				Type:         getString(rm, "type"),
 				Enabled:      getBool(rm, "enabled"),
 				URLPattern:   getString(rm, "url_pattern"),
+				Operation:    getString(rm, "operation"),
 				Probability:  getFloat64(rm, "probability"),
 				TimesApplied: getInt64(rm, "times_applied"),
 			})
@@ -1058,6 +1385,9 @@ func inputRuleToProtocol(r ChaosRuleInput) protocol.ChaosRuleConfig {
 		URLPattern:         r.URLPattern,
 		Methods:            r.Methods,
 		Probability:        r.Probability,
+		Upstream:           r.Upstream,
+		Operation:          r.Operation,
+		OperationType:      r.OperationType,
 		MinLatencyMs:       r.MinLatencyMs,
 		MaxLatencyMs:       r.MaxLatencyMs,
 		JitterMs:           r.JitterMs,
@@ -1067,6 +1397,9 @@ func inputRuleToProtocol(r ChaosRuleInput) protocol.ChaosRuleConfig {
 		DropAfterBytes:     r.DropAfterBytes,
 		ErrorCodes:         r.ErrorCodes,
 		ErrorMessage:       r.ErrorMessage,
+		GraphQLErrorCode:   r.GraphQLErrorCode,
+		EventPattern:       r.EventPattern,
+		EventProbability:   r.EventProbability,
 		TruncatePercent:    r.TruncatePercent,
 		ReorderMinRequests: r.ReorderMinRequests,
 		ReorderMaxWaitMs:   r.ReorderMaxWaitMs,
@@ -1266,6 +1599,10 @@ func (dt *DaemonTools) makeProxyLogHandler() func(context.Context, *mcp.CallTool
 			return dt.handleProxyLogClear(input)
 		case "stats":
 			return dt.handleProxyLogStats(input)
+		case "body":
+			return dt.handleProxyLogBody(input)
+		case "aggregate":
+			return dt.handleProxyLogAggregate(input)
 		default:
 			return errorResult(fmt.Sprintf("unknown action %q", action)), ProxyLogOutput{}, nil
 		}
@@ -1273,45 +1610,49 @@ func (dt *DaemonTools) makeProxyLogHandler() func(context.Context, *mcp.CallTool
 }
 
 func (dt *DaemonTools) handleProxyLogQuery(input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
-	filter := protocol.LogQueryFilter{
-		Types:       input.Types,
-		Methods:     input.Methods,
-		URLPattern:  input.URLPattern,
-		StatusCodes: input.StatusCodes,
-		Since:       input.Since,
-		Until:       input.Until,
-		Limit:       input.Limit,
-	}
+	filter := logQueryFilter(input)
+	filter.GroupBy = nil
 
 	result, err := dt.client.ProxyLogQuery(input.ProxyID, filter)
 	if err != nil {
 		return formatDaemonError(err, "proxylog"), ProxyLogOutput{}, nil
 	}
 
+	entries := queryLogEntries(result)
 	output := ProxyLogOutput{
 		Count: getInt(result, "count"),
 	}
+	if output.Count == 0 {
+		output.Count = len(entries)
+	}
 
-	if entries, ok := result["entries"].([]interface{}); ok {
-		for _, e := range entries {
-			if em, ok := e.(map[string]interface{}); ok {
-				entry := LogEntryOutput{
-					Type: getString(em, "type"),
-				}
-				if data, ok := em["data"].(map[string]interface{}); ok {
-					if b, err := json.Marshal(data); err == nil {
-						entry.Data = string(b)
-					} else {
-						entry.Data = "{}"
-					}
+	// The daemon returns every match; keep the first limit (default 100)
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	for _, e := range entries {
+		if em, ok := e.(map[string]interface{}); ok {
+			entry := LogEntryOutput{
+				Type: getString(em, "type"),
+			}
+			if data, ok := em["data"].(map[string]interface{}); ok {
+				if b, err := json.Marshal(data); err == nil {
+					entry.Data = string(b)
+				} else {
+					entry.Data = "{}"
 				}
-				if ts, ok := em["timestamp"].(string); ok {
-					if t, err := time.Parse(time.RFC3339, ts); err == nil {
-						entry.Timestamp = t
-					}
+			}
+			if ts, ok := em["timestamp"].(string); ok {
+				if t, err := time.Parse(time.RFC3339, ts); err == nil {
+					entry.Timestamp = t
 				}
-				output.Entries = append(output.Entries, entry)
 			}
+			output.Entries = append(output.Entries, entry)
 		}
 	}
 
@@ -1320,25 +1661,16 @@ func (dt *DaemonTools) handleProxyLogQuery(input ProxyLogInput) (*mcp.CallToolRe
 
 func (dt *DaemonTools) handleProxyLogSummary(input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
 	// Query all logs (up to a reasonable limit for aggregation)
-	filter := protocol.LogQueryFilter{
-		Types:       input.Types,
-		Methods:     input.Methods,
-		URLPattern:  input.URLPattern,
-		StatusCodes: input.StatusCodes,
-		Since:       input.Since,
-		Until:       input.Until,
-		Limit:       0, // Get all entries for aggregation (limited by log buffer size)
-	}
+	filter := logQueryFilter(input)
+	filter.GroupBy = nil
+	filter.Limit = 0 // Get all entries for aggregation (limited by log buffer size)
 
 	result, err := dt.client.ProxyLogQuery(input.ProxyID, filter)
 	if err != nil {
 		return formatDaemonError(err, "proxylog"), ProxyLogOutput{}, nil
 	}
 
-	entries, ok := result["entries"].([]interface{})
-	if !ok {
-		entries = []interface{}{}
-	}
+	entries := queryLogEntries(result)
 
 	// Build detail set for quick lookup
 	detailSet := make(map[string]bool)
@@ -1362,6 +1694,51 @@ func (dt *DaemonTools) handleProxyLogSummary(input ProxyLogInput) (*mcp.CallTool
 	}, nil
 }
 
+func (dt *DaemonTools) handleProxyLogAggregate(input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
+	filter := logQueryFilter(input)
+	if len(filter.GroupBy) == 0 {
+		filter.GroupBy = []string{proxy.GroupByEndpoint}
+	}
+	filter.Limit = 0
+
+	result, err := dt.client.ProxyLogQuery(input.ProxyID, filter)
+	if err != nil {
+		return formatDaemonError(err, "proxylog"), ProxyLogOutput{}, nil
+	}
+
+	output := ProxyLogOutput{Count: getInt(result, "count")}
+	if raw, ok := result["groups"]; ok {
+		b, _ := json.Marshal(raw)
+		json.Unmarshal(b, &output.Groups)
+	}
+	return nil, output, nil
+}
+
+// queryLogEntries returns the entries of a PROXYLOG QUERY result as
+// {type, timestamp, data} maps. The daemon returns proxy log entries under
+// "logs", keyed by their type; "entries" is accepted in that shape as-is.
+func queryLogEntries(result map[string]interface{}) []interface{} {
+	if entries, ok := result["entries"].([]interface{}); ok {
+		return entries
+	}
+	logs, _ := result["logs"].([]interface{})
+	entries := make([]interface{}, 0, len(logs))
+	for _, l := range logs {
+		lm, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		logType := getString(lm, "type")
+		data, _ := lm[logType].(map[string]interface{})
+		entry := map[string]interface{}{"type": logType, "data": data}
+		if data != nil {
+			entry["timestamp"] = data["timestamp"]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
 func (dt *DaemonTools) handleProxyLogClear(input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
 	err := dt.client.ProxyLogClear(input.ProxyID)
 	if err != nil {
@@ -1390,6 +1767,23 @@ func (dt *DaemonTools) handleProxyLogStats(input ProxyLogInput) (*mcp.CallToolRe
 	}, nil
 }
 
+func (dt *DaemonTools) handleProxyLogBody(input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
+	if input.RequestID == "" {
+		return errorResult("request_id required for body"), ProxyLogOutput{}, nil
+	}
+
+	result, err := dt.client.ProxyLogBody(input.ProxyID, input.RequestID, input.Part)
+	if err != nil {
+		return formatDaemonError(err, "proxylog"), ProxyLogOutput{}, nil
+	}
+
+	var body proxy.BodyContent
+	if data, err := json.Marshal(result); err == nil {
+		json.Unmarshal(data, &body)
+	}
+	return nil, ProxyLogOutput{Body: &body}, nil
+}
+
 // makeCurrentPageHandler creates a handler for the currentpage tool.
 func (dt *DaemonTools) makeCurrentPageHandler() func(context.Context, *mcp.CallToolRequest, CurrentPageInput) (*mcp.CallToolResult, CurrentPageOutput, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input CurrentPageInput) (*mcp.CallToolResult, CurrentPageOutput, error) {
@@ -1505,6 +1899,13 @@ func convertToPageSummary(m map[string]interface{}, detailSet map[string]bool, l
 		LoadTimeMs:       getInt64(m, "load_time_ms"),
 		InteractionCount: getInt(m, "interaction_count"),
 		MutationCount:    getInt(m, "mutation_count"),
+		RouteCount:       getInt(m, "route_count"),
+		ServiceWorker:    getBool(m, "service_worker"),
+		ConsoleCount:     getInt(m, "console_count"),
+		RejectionCount:   getInt(m, "rejection_count"),
+		WebVitals:        convertToWebVitals(m),
+		LongTaskCount:    getInt(m, "long_task_count"),
+		BlockingTimeMs:   getFloat64(m, "blocking_time_ms"),
 		DetailLimit:      limit,
 	}
 
@@ -1687,6 +2088,72 @@ func convertToPageSummary(m map[string]interface{}, detailSet map[string]bool, l
 		}
 	}
 
+	// Console messages by level, and the most recent
+	if messages, ok := m["console"].([]interface{}); ok {
+		summary.ConsoleByLevel = make(map[string]int)
+		var compact []CompactConsole
+		for _, c := range messages {
+			if cm, ok := c.(map[string]interface{}); ok {
+				summary.ConsoleByLevel[getString(cm, "level")]++
+				compact = append(compact, convertToCompactConsole(cm))
+			}
+		}
+
+		recentLimit := 5
+		if detailSet["console"] {
+			detailSections = append(detailSections, "console")
+			recentLimit = limit
+		} else if limit < recentLimit {
+			recentLimit = limit
+		}
+		start := len(compact) - recentLimit
+		if start < 0 {
+			start = 0
+		}
+		if detailSet["console"] {
+			summary.Console = compact[start:]
+		} else {
+			summary.RecentConsole = compact[start:]
+		}
+	}
+
+	// Most recent unhandled rejections
+	if rejections, ok := m["rejections"].([]interface{}); ok {
+		recentLimit := 5
+		if limit < recentLimit {
+			recentLimit = limit
+		}
+		start := len(rejections) - recentLimit
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < len(rejections); i++ {
+			if rm, ok := rejections[i].(map[string]interface{}); ok {
+				summary.Rejections = append(summary.Rejections, convertRejectionToCompactError(rm))
+			}
+		}
+	}
+
+	// Most recent routes, with per-route errors, interactions and performance
+	if routes, ok := m["routes"].([]interface{}); ok {
+		recentLimit := 5
+		if detailSet["routes"] {
+			detailSections = append(detailSections, "routes")
+			recentLimit = limit
+		} else if limit < recentLimit {
+			recentLimit = limit
+		}
+		start := len(routes) - recentLimit
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < len(routes); i++ {
+			if rm, ok := routes[i].(map[string]interface{}); ok {
+				summary.Routes = append(summary.Routes, convertToRouteSummary(rm))
+			}
+		}
+	}
+
 	// Extract page dimensions if available (from performance data)
 	if perf, ok := m["performance"].(map[string]interface{}); ok {
 		summary.FirstPaintMs = getInt64(perf, "first_paint_ms")
@@ -1704,6 +2171,157 @@ func convertToPageSummary(m map[string]interface{}, detailSet map[string]bool, l
 	return summary
 }
 
+// convertToRouteSummary summarizes a page route.
+func convertToRouteSummary(m map[string]interface{}) RouteSummary {
+	route := RouteSummary{
+		URL:              getString(m, "url"),
+		Title:            getString(m, "title"),
+		Source:           getString(m, "source"),
+		StartTime:        getTime(m, "start_time"),
+		ErrorCount:       getInt(m, "error_count"),
+		InteractionCount: getInt(m, "interaction_count"),
+		MutationCount:    getInt(m, "mutation_count"),
+		ResourceCount:    getInt(m, "resource_count"),
+		CachedFetchCount: getInt(m, "cached_fetch_count"),
+
+		ConsoleErrorCount: getInt(m, "console_error_count"),
+		ConsoleWarnCount:  getInt(m, "console_warn_count"),
+		RejectionCount:    getInt(m, "rejection_count"),
+		WebVitals:         convertToWebVitals(m),
+		LongTaskCount:     getInt(m, "long_task_count"),
+		BlockingTimeMs:    getFloat64(m, "blocking_time_ms"),
+	}
+	if end := getTime(m, "end_time"); !end.IsZero() {
+		route.DurationMs = end.Sub(route.StartTime).Milliseconds()
+	} else {
+		route.Current = true
+	}
+
+	if errors, ok := m["errors"].([]interface{}); ok {
+		seen := make(map[string]bool)
+		for _, e := range errors {
+			em, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			msg := getString(em, "message")
+			if len(msg) > 200 {
+				msg = msg[:197] + "..."
+			}
+			if msg == "" || seen[msg] {
+				continue
+			}
+			seen[msg] = true
+			route.Errors = append(route.Errors, msg)
+			if len(route.Errors) >= 3 {
+				break
+			}
+		}
+	}
+
+	if perf, ok := m["performance"].(map[string]interface{}); ok {
+		route.LoadTimeMs = getInt64(perf, "load_event_end")
+		if custom, ok := perf["custom"].(map[string]interface{}); ok {
+			route.SettleMs = getInt64(custom, "settle_ms")
+		}
+	}
+	return route
+}
+
+// convertToWebVitals extracts the web vitals of a page session or route.
+func convertToWebVitals(m map[string]interface{}) map[string]CompactWebVital {
+	raw, ok := m["web_vitals"].(map[string]interface{})
+	if !ok || len(raw) == 0 {
+		return nil
+	}
+	vitals := make(map[string]CompactWebVital, len(raw))
+	for name, v := range raw {
+		if vm, ok := v.(map[string]interface{}); ok {
+			vitals[name] = CompactWebVital{
+				Value:   getFloat64(vm, "value"),
+				Rating:  getString(vm, "rating"),
+				Element: getString(vm, "element"),
+			}
+		}
+	}
+	return vitals
+}
+
+// convertToCompactConsole converts a console message to compact form.
+func convertToCompactConsole(m map[string]interface{}) CompactConsole {
+	compact := CompactConsole{
+		Level:   getString(m, "level"),
+		Message: getString(m, "message"),
+		URL:     getString(m, "url"),
+	}
+	if len(compact.Message) > 500 {
+		compact.Message = compact.Message[:497] + "..."
+	}
+	for _, line := range strings.Split(getString(m, "stack"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if len(line) > 120 {
+				line = line[:117] + "..."
+			}
+			compact.Location = line
+			break
+		}
+	}
+	if ts, ok := m["timestamp"].(string); ok {
+		compact.Timestamp = ts
+	}
+	return compact
+}
+
+// convertRejectionToCompactError converts an unhandled promise rejection to
+// the compact error form; the type is the error class or the reason's type.
+func convertRejectionToCompactError(m map[string]interface{}) CompactError {
+	errType := getString(m, "name")
+	if errType == "" {
+		errType = getString(m, "reason_type")
+	}
+	return convertToCompactError(map[string]interface{}{
+		"message":        getString(m, "message"),
+		"type":           errType,
+		"url":            getString(m, "url"),
+		"stack":          getString(m, "stack"),
+		"resolved_stack": getString(m, "resolved_stack"),
+		"frames":         m["frames"],
+		"timestamp":      m["timestamp"],
+	})
+}
+
+// summarizeWebVitals aggregates web vital measurements by name.
+func summarizeWebVitals(vitals []map[string]interface{}) map[string]WebVitalSummary {
+	values := make(map[string][]float64)
+	summaries := make(map[string]WebVitalSummary)
+	for _, v := range vitals {
+		name := getString(v, "name")
+		value := getFloat64(v, "value")
+		values[name] = append(values[name], value)
+
+		s := summaries[name]
+		s.Count++
+		if getString(v, "rating") == "poor" {
+			s.Poor++
+		}
+		if s.Count == 1 || value > s.Worst {
+			s.Worst = value
+			s.WorstElement = getString(v, "element")
+			s.WorstURL = getString(v, "url")
+		}
+		summaries[name] = s
+	}
+
+	for name, s := range summaries {
+		sorted := values[name]
+		sort.Float64s(sorted)
+		s.P75 = sorted[(len(sorted)*3+3)/4-1]
+		s.Rating = proxy.RateWebVital(name, s.P75)
+		summaries[name] = s
+	}
+	return summaries
+}
+
 // categorizeResource determines the type of resource from its URL.
 func categorizeResource(url string) string {
 	lower := strings.ToLower(url)
@@ -1770,8 +2388,23 @@ func convertToCompactError(em map[string]interface{}) CompactError {
 		}
 	}
 
-	// Truncate stack trace to first 3 lines (most relevant)
+	// Prefer the stack resolved with source maps; the bundle location of
+	// the top frame stays visible
 	stack := getString(em, "stack")
+	if resolved := getString(em, "resolved_stack"); resolved != "" {
+		stack = resolved
+		compact.RawLocation = compact.Location
+		if frames, ok := em["frames"].([]interface{}); ok && len(frames) > 0 {
+			if fm, ok := frames[0].(map[string]interface{}); ok {
+				compact.Location = fmt.Sprintf("%s:%d:%d", getString(fm, "file"), getInt(fm, "line"), getInt(fm, "column"))
+				if generated := getString(fm, "generated"); generated != "" {
+					compact.RawLocation = generated
+				}
+			}
+		}
+	}
+
+	// Truncate stack trace to first 3 lines (most relevant)
 	if stack != "" {
 		lines := strings.Split(stack, "\n")
 		maxLines := 3
@@ -1817,6 +2450,8 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 		ErrorsByType:       make(map[string]int),
 		HTTPByStatus:       make(map[string]int),
 		HTTPByMethod:       make(map[string]int),
+		GraphQLByOperation: make(map[string]int),
+		GraphQLErrors:      make(map[string]int),
 		InteractionsByType: make(map[string]int),
 		MutationsByType:    make(map[string]int),
 		OtherTypes:         make(map[string]int),
@@ -1826,13 +2461,18 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 	var detailSections []string
 	var errors []map[string]interface{}
 	var httpRequests []map[string]interface{}
+	var httpEntries []proxy.LogEntry
 	var performance []map[string]interface{}
 	var interactions []map[string]interface{}
 	var mutations []map[string]interface{}
+	var consoleMessages []map[string]interface{}
+	var rejections []map[string]interface{}
+	var webVitals []map[string]interface{}
 	var other []map[string]interface{}
 
 	var firstTime, lastTime time.Time
 	errorCounts := make(map[string]*ErrorSummary) // For deduplication
+	consoleCounts := make(map[string]*ErrorSummary)
 	var totalLoadTime int64
 	var perfCount int
 
@@ -1905,6 +2545,19 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 				if method != "" {
 					summary.HTTPByMethod[method]++
 				}
+
+				// Aggregate GraphQL requests by operation
+				if gql := graphQLFromData(data); gql != nil {
+					label := gql.Label()
+					summary.GraphQLByOperation[label]++
+					if gql.ErrorCount > 0 {
+						summary.GraphQLErrors[label]++
+					}
+				}
+
+				if entry := httpEntryFromData(data); entry != nil {
+					httpEntries = append(httpEntries, proxy.LogEntry{Type: proxy.LogTypeHTTP, HTTP: entry})
+				}
 			}
 
 		case "performance":
@@ -1944,6 +2597,46 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 				}
 			}
 
+		case "console":
+			summary.ConsoleCount++
+			if data != nil {
+				consoleMessages = append(consoleMessages, data)
+
+				level := getString(data, "level")
+				if summary.ConsoleByLevel == nil {
+					summary.ConsoleByLevel = make(map[string]int)
+				}
+				summary.ConsoleByLevel[level]++
+
+				msg := getString(data, "message")
+				key := level + ":" + msg
+				if len(key) > 100 {
+					key = key[:100]
+				}
+				if existing, ok := consoleCounts[key]; ok {
+					existing.Count++
+				} else {
+					consoleCounts[key] = &ErrorSummary{Message: msg, Type: level, Count: 1}
+				}
+			}
+
+		case "rejection":
+			summary.RejectionCount++
+			if data != nil {
+				rejections = append(rejections, data)
+			}
+
+		case "web_vital":
+			if data == nil {
+				continue
+			}
+			if getString(data, "name") == "long_task" {
+				summary.LongTaskCount++
+				summary.BlockingTimeMs += max(getFloat64(data, "value")-50, 0)
+			} else {
+				webVitals = append(webVitals, data)
+			}
+
 		default:
 			summary.OtherCount++
 			summary.OtherTypes[logType]++
@@ -2001,6 +2694,55 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 		}
 	}
 
+	// Build unique console messages (top 10, most frequent first)
+	for _, cs := range consoleCounts {
+		summary.UniqueConsole = append(summary.UniqueConsole, *cs)
+	}
+	sort.SliceStable(summary.UniqueConsole, func(i, j int) bool {
+		return summary.UniqueConsole[i].Count > summary.UniqueConsole[j].Count
+	})
+	if len(summary.UniqueConsole) > 10 {
+		summary.UniqueConsole = summary.UniqueConsole[:10]
+	}
+	if detailSet["console"] {
+		detailSections = append(detailSections, "console")
+		start := len(consoleMessages) - limit
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < len(consoleMessages); i++ {
+			summary.Console = append(summary.Console, convertToCompactConsole(consoleMessages[i]))
+		}
+	}
+
+	// Process rejections
+	if detailSet["rejections"] {
+		detailSections = append(detailSections, "rejections")
+		start := len(rejections) - limit
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < len(rejections); i++ {
+			summary.Rejections = append(summary.Rejections, convertRejectionToCompactError(rejections[i]))
+		}
+	} else if summary.RejectionCount > 0 {
+		recentLimit := 5
+		if limit < recentLimit {
+			recentLimit = limit
+		}
+		start := len(rejections) - recentLimit
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < len(rejections); i++ {
+			summary.RecentRejections = append(summary.RecentRejections, convertRejectionToCompactError(rejections[i]))
+		}
+	}
+
+	if len(webVitals) > 0 {
+		summary.WebVitals = summarizeWebVitals(webVitals)
+	}
+
 	// Process HTTP requests
 	if detailSet["http"] {
 		detailSections = append(detailSections, "http")
@@ -2029,6 +2771,18 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 		}
 	}
 
+	// Latency per endpoint, slowest first
+	if len(httpEntries) > 0 {
+		endpoints := proxy.AggregateHTTP(httpEntries, []string{proxy.GroupByEndpoint})
+		sort.SliceStable(endpoints, func(i, j int) bool {
+			return endpoints[i].P95Ms > endpoints[j].P95Ms
+		})
+		if len(endpoints) > 10 {
+			endpoints = endpoints[:10]
+		}
+		summary.HTTPEndpoints = endpoints
+	}
+
 	// Process performance
 	if detailSet["performance"] {
 		detailSections = append(detailSections, "performance")
@@ -2138,6 +2892,36 @@ func buildProxyLogSummary(entries []interface{}, detailSet map[string]bool, limi
 
 // Helper converters for compact log types
 
+// graphQLFromData decodes the GraphQL details of an HTTP log entry map.
+func graphQLFromData(data map[string]interface{}) *proxy.GraphQLInfo {
+	raw, ok := data["graphql"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var gql proxy.GraphQLInfo
+	if json.Unmarshal(b, &gql) != nil || len(gql.Operations) == 0 {
+		return nil
+	}
+	return &gql
+}
+
+// httpEntryFromData decodes an HTTP log entry map.
+func httpEntryFromData(data map[string]interface{}) *proxy.HTTPLogEntry {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var entry proxy.HTTPLogEntry
+	if json.Unmarshal(b, &entry) != nil {
+		return nil
+	}
+	return &entry
+}
+
 func convertToCompactHTTP(data map[string]interface{}) CompactHTTPRequest {
 	compact := CompactHTTPRequest{
 		Method:     getString(data, "method"),
@@ -2146,6 +2930,13 @@ func convertToCompactHTTP(data map[string]interface{}) CompactHTTPRequest {
 		Duration:   getInt64(data, "duration") / 1000000, // Convert ns to ms
 		Error:      getString(data, "error"),
 	}
+	if gql := graphQLFromData(data); gql != nil {
+		compact.Operation = gql.Label()
+	}
+	if timing, ok := data["timing"].(map[string]interface{}); ok {
+		compact.UpstreamMs = getInt64(timing, "upstream") / 1000000
+		compact.ChaosMs = getInt64(timing, "chaos") / 1000000
+	}
 
 	if ts, ok := data["timestamp"].(string); ok {
 		if t, err := time.Parse(time.RFC3339, ts); err == nil {
@@ -2338,6 +3129,18 @@ func isWindows() bool {
 	return os.PathSeparator == '\\'
 }
 
+// decodeResult decodes a daemon response into its pkg/api type.
+func decodeResult(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode result: %w", err)
+	}
+	return nil
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if v, ok := m[key].(string); ok {
 		return v
@@ -2373,6 +3176,20 @@ func getBool(m map[string]interface{}, key string) bool {
 	return false
 }
 
+func getStringSlice(m map[string]interface{}, key string) []string {
+	raw, ok := m[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 func getTime(m map[string]interface{}, key string) time.Time {
 	if v, ok := m[key].(string); ok {
 		if t, err := time.Parse(time.RFC3339, v); err == nil {
@@ -2453,6 +3270,9 @@ func convertToPageSessionOutput(m map[string]interface{}) PageSessionOutput {
 		ErrorCount:       getInt(m, "error_count"),
 		HasPerformance:   getBool(m, "has_performance"),
 		LoadTime:         getInt64(m, "load_time_ms"),
+		RouteCount:       getInt(m, "route_count"),
+		ConsoleCount:     getInt(m, "console_count"),
+		RejectionCount:   getInt(m, "rejection_count"),
 		InteractionCount: getInt(m, "interaction_count"),
 		MutationCount:    getInt(m, "mutation_count"),
 	}
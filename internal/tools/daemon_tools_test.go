@@ -1,11 +1,13 @@
 package tools
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/standardbeagle/agnt/internal/daemon"
 )
@@ -337,3 +339,73 @@ func dummyAutoStartConfig() daemon.AutoStartConfig {
 		SocketPath: "/tmp/test.sock",
 	}
 }
+
+func TestDetectedCommands(t *testing.T) {
+	result := map[string]interface{}{
+		"commands": []interface{}{
+			map[string]interface{}{"name": "test", "command": "gotestsum", "args": []interface{}{"--", "./..."}, "source": "global"},
+			map[string]interface{}{"name": "build", "command": "go", "source": "built-in"},
+		},
+	}
+	commands := detectedCommands(result)
+	if len(commands) != 2 {
+		t.Fatalf("expected 2 commands, got %v", commands)
+	}
+	if commands[0].Command != "gotestsum" || commands[0].Source != "global" || len(commands[0].Args) != 2 {
+		t.Errorf("unexpected command %+v", commands[0])
+	}
+	if detectedCommands(map[string]interface{}{}) != nil {
+		t.Error("expected no commands without a commands key")
+	}
+
+	env := commandEnv([]string{"PATH=/bin"}, map[string]string{"B": "2", "A": "1"})
+	if strings.Join(env, " ") != "PATH=/bin A=1 B=2" {
+		t.Errorf("unexpected env %v", env)
+	}
+}
+
+// TestDaemonTools_CloseConnected closes a client connected to a running daemon.
+func TestDaemonTools_CloseConnected(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "test.sock")
+
+	d := daemon.New(daemon.DaemonConfig{
+		SocketPath:   sockPath,
+		MaxClients:   10,
+		WriteTimeout: 5 * time.Second,
+		AuditDir:     filepath.Join(tmpDir, "audit"),
+	})
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start daemon: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		d.Stop(ctx)
+	}()
+
+	dt := NewDaemonTools(daemon.AutoStartConfig{SocketPath: sockPath}, daemon.Version)
+	dt.SetNoAutoAttach(true)
+	if err := dt.ensureConnected(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- dt.Close() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	if dt.daemonClient() != nil {
+		t.Error("Expected no client after Close")
+	}
+	if err := dt.Close(); err != nil {
+		t.Errorf("Second Close returned %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -162,6 +163,7 @@ func makeRunHandler(pm *process.ProcessManager) func(context.Context, *mcp.CallT
 
 		var cmd string
 		var args []string
+		var env []string
 
 		if input.Raw {
 			// Raw mode: use command and args directly
@@ -176,7 +178,7 @@ func makeRunHandler(pm *process.ProcessManager) func(context.Context, *mcp.CallT
 				return errorResult("script_name required (or use raw=true with command)"), RunOutput{}, nil
 			}
 
-			proj, err := project.Detect(path)
+			proj, err := detectProject(path)
 			if err != nil {
 				return errorResult(fmt.Sprintf("failed to detect project: %v", err)), RunOutput{}, nil
 			}
@@ -189,6 +191,9 @@ func makeRunHandler(pm *process.ProcessManager) func(context.Context, *mcp.CallT
 
 			cmd = cmdDef.Command
 			args = append(cmdDef.Args, input.Args...)
+			if len(cmdDef.Env) > 0 {
+				env = commandEnv(os.Environ(), cmdDef.Env)
+			}
 		}
 
 		// Generate ID if not provided
@@ -220,6 +225,7 @@ func makeRunHandler(pm *process.ProcessManager) func(context.Context, *mcp.CallT
 			ProjectPath: path,
 			Command:     cmd,
 			Args:        args,
+			Env:         env,
 		})
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to start: %v", err)), RunOutput{}, nil
@@ -3,7 +3,9 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 
+	"github.com/standardbeagle/agnt/internal/config"
 	"github.com/standardbeagle/agnt/internal/project"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -21,6 +23,17 @@ type DetectOutput struct {
 	Scripts        []string          `json:"scripts"`
 	PackageManager string            `json:"package_manager,omitempty"`
 	Metadata       map[string]string `json:"metadata,omitempty"`
+	// Commands are the scripts with their definition and source: built-in,
+	// global (config.kdl) or project (.agnt.kdl)
+	Commands []project.CommandDef `json:"commands,omitempty"`
+	// Config is the check of the project's .agnt.kdl, if it has one
+	Config *ConfigCheckOutput `json:"config,omitempty"`
+}
+
+// ConfigCheckOutput lists the problems found in an .agnt.kdl file.
+type ConfigCheckOutput struct {
+	Path   string         `json:"path"`
+	Issues []config.Issue `json:"issues"`
 }
 
 // RegisterProjectTools adds project-related MCP tools to the server.
@@ -28,6 +41,8 @@ func RegisterProjectTools(server *mcp.Server) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "detect",
 		Description: `Detect project type and available scripts.
+Languages and commands can be customised in the global config.kdl and the project's .agnt.kdl;
+each command's source (built-in, global, project) is in commands.
 Example: detect {path: "."} → {type: "go", scripts: ["test", "build", "lint"]}`,
 	}, handleDetect)
 }
@@ -38,7 +53,7 @@ func handleDetect(ctx context.Context, req *mcp.CallToolRequest, input DetectInp
 		path = "."
 	}
 
-	proj, err := project.Detect(path)
+	proj, err := detectProject(path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to detect: %v", err)), DetectOutput{}, nil
 	}
@@ -54,5 +69,33 @@ func handleDetect(ctx context.Context, req *mcp.CallToolRequest, input DetectInp
 		Scripts:        scripts,
 		PackageManager: proj.PackageManager,
 		Metadata:       proj.Metadata,
+		Commands:       proj.Commands,
 	}, nil
 }
+
+// detectProject detects the project at path with the languages of the
+// global config.kdl, falling back to the defaults if it can't be loaded,
+// and the project's .agnt.kdl.
+func detectProject(path string) (*project.Project, error) {
+	cfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	return cfg.DetectProject(path)
+}
+
+// commandEnv returns base with the environment variables of a command added,
+// sorted by name.
+func commandEnv(base []string, env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := append([]string{}, base...)
+	for _, name := range names {
+		result = append(result, name+"="+env[name])
+	}
+	return result
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"runtime"
 	"time"
 
+	"github.com/standardbeagle/agnt/internal/certs"
+	"github.com/standardbeagle/agnt/internal/protocol"
 	"github.com/standardbeagle/agnt/internal/proxy"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -13,28 +16,37 @@ import (
 
 // ProxyInput defines input for the proxy tool.
 type ProxyInput struct {
-	Action        string `json:"action" jsonschema:"Action: start, stop, status, list, exec, toast, chaos"`
-	ID            string `json:"id,omitempty" jsonschema:"Proxy ID (required for start/stop/status/exec/toast/chaos)"`
-	TargetURL     string `json:"target_url,omitempty" jsonschema:"Target URL to proxy (required for start)"`
-	Port          int    `json:"port,omitempty" jsonschema:"Listen port (default: stable hash of target URL). Only specify if you need a specific port."`
-	MaxLogSize    int    `json:"max_log_size,omitempty" jsonschema:"Maximum log entries (default: 1000)"`
-	BindAddress   string `json:"bind_address,omitempty" jsonschema:"Bind address: '127.0.0.1' (default, localhost only) or '0.0.0.0' (all interfaces for tunnel/mobile testing)"`
-	PublicURL     string `json:"public_url,omitempty" jsonschema:"Public URL for tunnel services (e.g. 'https://abc123.trycloudflare.com'). Used for URL rewriting when behind a tunnel."`
-	VerifyTLS     bool   `json:"verify_tls,omitempty" jsonschema:"Verify TLS certificates (default: false, accepts self-signed/expired certs for dev). Set to true for strict validation."`
-	Code          string `json:"code,omitempty" jsonschema:"JavaScript code to execute (required for exec)"`
-	Global        bool   `json:"global,omitempty" jsonschema:"For list: include proxies from all directories (default: false)"`
-	Help          bool   `json:"help,omitempty" jsonschema:"For exec: show __devtool API overview instead of executing code"`
-	Describe      string `json:"describe,omitempty" jsonschema:"For exec: show detailed docs for a specific function (e.g. 'screenshot', 'interactions.getLastClick')"`
-	ToastType     string `json:"toast_type,omitempty" jsonschema:"For toast: notification type (success, error, warning, info). Default: info"`
-	ToastTitle    string `json:"toast_title,omitempty" jsonschema:"For toast: notification title (optional)"`
-	ToastMessage  string `json:"toast_message,omitempty" jsonschema:"For toast: notification message (required for toast)"`
-	ToastDuration int    `json:"toast_duration,omitempty" jsonschema:"For toast: duration in milliseconds (0 for default)"`
+	Action      string `json:"action" jsonschema:"Action: start, stop, status, list, exec, toast, chaos, share, unshare, ca"`
+	ID          string `json:"id,omitempty" jsonschema:"Proxy ID (required for start/stop/status/exec/toast/chaos/share/unshare)"`
+	TargetURL   string `json:"target_url,omitempty" jsonschema:"Target URL to proxy (required for start)"`
+	Port        int    `json:"port,omitempty" jsonschema:"Listen port (default: stable hash of target URL). Only specify if you need a specific port."`
+	MaxLogSize  int    `json:"max_log_size,omitempty" jsonschema:"Maximum log entries (default: 1000)"`
+	BindAddress string `json:"bind_address,omitempty" jsonschema:"Bind address: '127.0.0.1' (default, localhost only) or '0.0.0.0' (all interfaces for tunnel/mobile testing)"`
+	PublicURL   string `json:"public_url,omitempty" jsonschema:"Public URL for tunnel services (e.g. 'https://abc123.trycloudflare.com'). Used for URL rewriting when behind a tunnel."`
+	VerifyTLS   bool   `json:"verify_tls,omitempty" jsonschema:"Verify TLS certificates (default: false, accepts self-signed/expired certs for dev). Set to true for strict validation."`
+	HTTPS       bool   `json:"https,omitempty" jsonschema:"For start: also serve HTTPS (HTTP/2) with a certificate from the local agnt CA, for secure cookies, service workers and OAuth redirects"`
+	HTTPSPort   int    `json:"https_port,omitempty" jsonschema:"For start: HTTPS listen port (default: stable port derived from the target URL)"`
+	// Body capture limits (for start action)
+	BodyInlineLimit int    `json:"body_inline_limit,omitempty" jsonschema:"For start: bytes of each request/response body previewed in logs (default: 10240). Larger bodies are kept for proxylog action body."`
+	BodyMaxSize     int64  `json:"body_max_size,omitempty" jsonschema:"For start: most bytes captured per body (default: 10MB, -1 disables body capture)"`
+	Code            string `json:"code,omitempty" jsonschema:"JavaScript code to execute (required for exec)"`
+	Global          bool   `json:"global,omitempty" jsonschema:"For list: include proxies from all directories (default: false)"`
+	Help            bool   `json:"help,omitempty" jsonschema:"For exec: show __devtool API overview instead of executing code"`
+	Describe        string `json:"describe,omitempty" jsonschema:"For exec: show detailed docs for a specific function (e.g. 'screenshot', 'interactions.getLastClick')"`
+	ToastType       string `json:"toast_type,omitempty" jsonschema:"For toast: notification type (success, error, warning, info). Default: info"`
+	ToastTitle      string `json:"toast_title,omitempty" jsonschema:"For toast: notification title (optional)"`
+	ToastMessage    string `json:"toast_message,omitempty" jsonschema:"For toast: notification message (required for toast)"`
+	ToastDuration   int    `json:"toast_duration,omitempty" jsonschema:"For toast: duration in milliseconds (0 for default)"`
+	ShareHTTPS      bool   `json:"share_https,omitempty" jsonschema:"For share: serve over HTTPS with a certificate from the local agnt CA (needed for camera, service workers, clipboard on devices)"`
+	SharePort       int    `json:"share_port,omitempty" jsonschema:"For share: LAN listener port (default: auto-assigned)"`
 	// Tunnel configuration (for start action)
 	Tunnel        string   `json:"tunnel,omitempty" jsonschema:"Tunnel provider: ngrok, cloudflared, tailscale, or custom. Creates public URL for the proxy."`
 	TunnelArgs    []string `json:"tunnel_args,omitempty" jsonschema:"Additional arguments for tunnel command"`
 	TunnelToken   string   `json:"tunnel_token,omitempty" jsonschema:"Authentication token for tunnel (e.g., ngrok authtoken)"`
 	TunnelRegion  string   `json:"tunnel_region,omitempty" jsonschema:"Tunnel region (optional)"`
 	TunnelCommand string   `json:"tunnel_command,omitempty" jsonschema:"Custom tunnel command (when tunnel is 'custom'). Use {{PORT}} as placeholder."`
+	// Route table (for start action)
+	Routes []RouteInput `json:"routes,omitempty" jsonschema:"For start: send matching paths to other upstreams, checked in order. Unmatched requests go to target_url."`
 
 	// Chaos-related fields
 	ChaosOperation string            `json:"chaos_operation,omitempty" jsonschema:"For chaos: enable, disable, status, set, preset, add_rule, remove_rule, list_rules, stats, clear"`
@@ -45,6 +57,16 @@ type ProxyInput struct {
 	ChaosConfig    *ChaosConfigInput `json:"chaos_config,omitempty" jsonschema:"For chaos set: full chaos configuration"`
 }
 
+// RouteInput defines a path-based route to another upstream.
+type RouteInput struct {
+	Name        string `json:"name,omitempty" jsonschema:"Route name for logs and chaos rules (default: path or pattern)"`
+	Path        string `json:"path,omitempty" jsonschema:"Path prefix to match, e.g. '/api' (matches /api and /api/users, not /apidocs)"`
+	Pattern     string `json:"pattern,omitempty" jsonschema:"Regular expression matched against the path (instead of path)"`
+	Target      string `json:"target" jsonschema:"Upstream URL, e.g. 'http://localhost:8080'"`
+	StripPrefix bool   `json:"strip_prefix,omitempty" jsonschema:"Remove the matched prefix before forwarding"`
+	HostHeader  string `json:"host_header,omitempty" jsonschema:"Host header sent upstream (default: target host)"`
+}
+
 // ChaosRuleInput defines input for a single chaos rule.
 type ChaosRuleInput struct {
 	ID          string   `json:"id"`
@@ -54,6 +76,11 @@ type ChaosRuleInput struct {
 	URLPattern  string   `json:"url_pattern,omitempty"`
 	Methods     []string `json:"methods,omitempty"`
 	Probability float64  `json:"probability,omitempty"` // 0.0-1.0, default 1.0
+	Upstream    string   `json:"upstream,omitempty" jsonschema:"Only affect requests to this route name, upstream URL or host"`
+
+	// GraphQL matching
+	Operation     string `json:"operation,omitempty" jsonschema:"Only affect GraphQL requests whose operation name matches this regex"`
+	OperationType string `json:"operation_type,omitempty" jsonschema:"Only affect GraphQL operations of this type: query, mutation, subscription"`
 
 	// Latency config
 	MinLatencyMs int `json:"min_latency_ms,omitempty"`
@@ -69,8 +96,13 @@ type ChaosRuleInput struct {
 	DropAfterBytes   int64   `json:"drop_after_bytes,omitempty"`
 
 	// Error injection config
-	ErrorCodes   []int  `json:"error_codes,omitempty"`
-	ErrorMessage string `json:"error_message,omitempty"`
+	ErrorCodes       []int  `json:"error_codes,omitempty"`
+	ErrorMessage     string `json:"error_message,omitempty"`
+	GraphQLErrorCode string `json:"graphql_error_code,omitempty" jsonschema:"For graphql_error: extensions.code of the injected error (default: INTERNAL_SERVER_ERROR)"`
+
+	// Stream event config
+	EventPattern     string  `json:"event_pattern,omitempty" jsonschema:"For sse_delay/sse_drop: only affect stream events whose name or data matches this regex"`
+	EventProbability float64 `json:"event_probability,omitempty" jsonschema:"For sse_delay/sse_drop: chance per event, 0.0-1.0 (default 1.0)"`
 
 	// Truncation config
 	TruncatePercent float64 `json:"truncate_percent,omitempty"`
@@ -97,7 +129,7 @@ type CurrentPageInput struct {
 	ProxyID   string   `json:"proxy_id" jsonschema:"Proxy ID to query pages from"`
 	Action    string   `json:"action,omitempty" jsonschema:"Action: list, get, summary, clear (default: list)"`
 	SessionID string   `json:"session_id,omitempty" jsonschema:"Specific session ID (required for get/summary action)"`
-	Detail    []string `json:"detail,omitempty" jsonschema:"For summary: sections to include full detail for (interactions, mutations, errors, resources)"`
+	Detail    []string `json:"detail,omitempty" jsonschema:"For summary: sections to include full detail for (interactions, mutations, errors, resources, console, routes)"`
 	Limit     int      `json:"limit,omitempty" jsonschema:"For summary: max items per detailed section (default: 5, max: 100)"`
 }
 
@@ -156,6 +188,24 @@ type PageSummaryOutput struct {
 	RecentMutations []map[string]interface{} `json:"recent_mutations,omitempty"`  // Last N (default 5)
 	Mutations       []map[string]interface{} `json:"mutations,omitempty"`         // Full list when detail=["mutations"]
 
+	// Console warnings/errors and unhandled promise rejections
+	ConsoleCount   int              `json:"console_count,omitempty"`
+	ConsoleByLevel map[string]int   `json:"console_by_level,omitempty"` // e.g., {"warn": 3, "error": 1}
+	RecentConsole  []CompactConsole `json:"recent_console,omitempty"`   // Last N (default 5)
+	Console        []CompactConsole `json:"console,omitempty"`          // Full list when detail=["console"]
+	RejectionCount int              `json:"rejection_count,omitempty"`
+	Rejections     []CompactError   `json:"rejections,omitempty"` // Last N (default 5)
+
+	// Web vitals (latest value of each metric) and main thread blocking
+	WebVitals      map[string]CompactWebVital `json:"web_vitals,omitempty"` // By name: LCP, CLS, INP, FCP, TTFB
+	LongTaskCount  int                        `json:"long_task_count,omitempty"`
+	BlockingTimeMs float64                    `json:"blocking_time_ms,omitempty"` // Long task time over 50ms
+
+	// Routes (document loads and client-side navigations), most recent last
+	RouteCount    int            `json:"route_count,omitempty"`
+	Routes        []RouteSummary `json:"routes,omitempty"`         // Last N (default 5)
+	ServiceWorker bool           `json:"service_worker,omitempty"` // Page is controlled by a service worker
+
 	// Page dimensions (if available from client)
 	PageHeight     int `json:"page_height,omitempty"`
 	PageWidth      int `json:"page_width,omitempty"`
@@ -167,6 +217,58 @@ type PageSummaryOutput struct {
 	DetailLimit    int      `json:"detail_limit,omitempty"`    // Limit applied to detailed sections
 }
 
+// RouteSummary summarizes one route of a page session.
+type RouteSummary struct {
+	URL              string    `json:"url"`
+	Title            string    `json:"title,omitempty"`
+	Source           string    `json:"source"` // document, service_worker, cache, push, pop or hash
+	StartTime        time.Time `json:"start_time"`
+	DurationMs       int64     `json:"duration_ms,omitempty"` // Time on the route; unset for the current one
+	Current          bool      `json:"current,omitempty"`
+	ErrorCount       int       `json:"error_count"`
+	Errors           []string  `json:"errors,omitempty"` // Unique messages (first 3)
+	InteractionCount int       `json:"interaction_count"`
+	MutationCount    int       `json:"mutation_count"`
+	ResourceCount    int       `json:"resource_count,omitempty"`
+	CachedFetchCount int       `json:"cached_fetch_count,omitempty"` // Served by a service worker or cache
+	LoadTimeMs       int64     `json:"load_time_ms,omitempty"`       // Document load event
+	SettleMs         int64     `json:"settle_ms,omitempty"`          // Client-side render until the DOM was quiet
+
+	ConsoleErrorCount int                        `json:"console_error_count,omitempty"`
+	ConsoleWarnCount  int                        `json:"console_warn_count,omitempty"`
+	RejectionCount    int                        `json:"rejection_count,omitempty"`
+	WebVitals         map[string]CompactWebVital `json:"web_vitals,omitempty"`
+	LongTaskCount     int                        `json:"long_task_count,omitempty"`
+	BlockingTimeMs    float64                    `json:"blocking_time_ms,omitempty"`
+}
+
+// CompactConsole represents a console message with truncated verbose fields.
+type CompactConsole struct {
+	Level     string `json:"level"` // warn or error
+	Message   string `json:"message"`
+	Location  string `json:"location,omitempty"` // First frame of the call stack
+	URL       string `json:"url,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// CompactWebVital is a web vital measurement of a page or route.
+type CompactWebVital struct {
+	Value   float64 `json:"value"`             // Milliseconds; unitless for CLS
+	Rating  string  `json:"rating,omitempty"`  // good, needs-improvement or poor
+	Element string  `json:"element,omitempty"` // Selector of the attributed element
+}
+
+// WebVitalSummary aggregates the measurements of one web vital across pages.
+type WebVitalSummary struct {
+	Count        int     `json:"count"`
+	P75          float64 `json:"p75"`    // The value web vitals are assessed at
+	Rating       string  `json:"rating"` // Rating of the p75 value
+	Poor         int     `json:"poor,omitempty"`
+	Worst        float64 `json:"worst"`
+	WorstElement string  `json:"worst_element,omitempty"`
+	WorstURL     string  `json:"worst_url,omitempty"`
+}
+
 // ErrorSummary represents a deduplicated error with occurrence count.
 type ErrorSummary struct {
 	Message string `json:"message"`
@@ -180,7 +282,8 @@ type CompactError struct {
 	Message      string `json:"message"`
 	Type         string `json:"type,omitempty"`
 	URL          string `json:"url,omitempty"`
-	Location     string `json:"location,omitempty"`      // "file.js:123:45" format
+	Location     string `json:"location,omitempty"`      // "file.js:123:45" format; original source when resolved
+	RawLocation  string `json:"raw_location,omitempty"`  // Bundle location when resolved with a source map
 	StackPreview string `json:"stack_preview,omitempty"` // First 3 lines of stack trace
 	Timestamp    string `json:"timestamp,omitempty"`
 }
@@ -199,11 +302,16 @@ type ProxyLogSummary struct {
 	RecentErrors []CompactError `json:"recent_errors,omitempty"`  // Last 5 errors (when detail not specified)
 
 	// HTTP summary
-	HTTPCount    int                  `json:"http_count"`
-	HTTPByStatus map[string]int       `json:"http_by_status,omitempty"` // e.g., {"2xx": 100, "4xx": 5}
-	HTTPByMethod map[string]int       `json:"http_by_method,omitempty"` // e.g., {"GET": 80, "POST": 20}
-	HTTPRequests []CompactHTTPRequest `json:"http_requests,omitempty"`  // Full list when detail includes "http"
-	RecentHTTP   []CompactHTTPRequest `json:"recent_http,omitempty"`    // Last 5 requests (when detail not specified)
+	HTTPCount    int            `json:"http_count"`
+	HTTPByStatus map[string]int `json:"http_by_status,omitempty"` // e.g., {"2xx": 100, "4xx": 5}
+	HTTPByMethod map[string]int `json:"http_by_method,omitempty"` // e.g., {"GET": 80, "POST": 20}
+
+	// GraphQL summary (requests recognised as GraphQL)
+	GraphQLByOperation map[string]int       `json:"graphql_by_operation,omitempty"` // e.g., {"query GetViewer": 12}
+	GraphQLErrors      map[string]int       `json:"graphql_errors,omitempty"`       // Operation -> responses with errors
+	HTTPEndpoints      []proxy.LogGroup     `json:"http_endpoints,omitempty"`       // Slowest endpoints by p95, with upstream and chaos time
+	HTTPRequests       []CompactHTTPRequest `json:"http_requests,omitempty"`        // Full list when detail includes "http"
+	RecentHTTP         []CompactHTTPRequest `json:"recent_http,omitempty"`          // Last 5 requests (when detail not specified)
 
 	// Performance summary
 	PerformanceCount  int                  `json:"performance_count"`
@@ -223,6 +331,22 @@ type ProxyLogSummary struct {
 	Mutations       []CompactMutation `json:"mutations,omitempty"`         // Full list when detail includes "mutations"
 	RecentMutations []CompactMutation `json:"recent_mutations,omitempty"`  // Last 5 (when detail not specified)
 
+	// Console summary (console.warn, console.error and failed assertions)
+	ConsoleCount   int              `json:"console_count,omitempty"`
+	ConsoleByLevel map[string]int   `json:"console_by_level,omitempty"` // e.g., {"warn": 30, "error": 2}
+	UniqueConsole  []ErrorSummary   `json:"unique_console,omitempty"`   // Top 10 deduplicated messages; type is the level
+	Console        []CompactConsole `json:"console,omitempty"`          // Full list when detail includes "console"
+
+	// Unhandled promise rejections
+	RejectionCount   int            `json:"rejection_count,omitempty"`
+	Rejections       []CompactError `json:"rejections,omitempty"`        // Full list when detail includes "rejections"
+	RecentRejections []CompactError `json:"recent_rejections,omitempty"` // Last 5 (when detail not specified)
+
+	// Web vitals by name, and long tasks
+	WebVitals      map[string]WebVitalSummary `json:"web_vitals,omitempty"`
+	LongTaskCount  int                        `json:"long_task_count,omitempty"`
+	BlockingTimeMs float64                    `json:"blocking_time_ms,omitempty"` // Long task time over 50ms
+
 	// Other log types (custom, panel_message, sketch, etc.)
 	OtherCount int               `json:"other_count,omitempty"`
 	OtherTypes map[string]int    `json:"other_types,omitempty"` // Counts for custom, panel_message, sketch, etc.
@@ -247,6 +371,9 @@ type CompactHTTPRequest struct {
 	Duration   int64     `json:"duration_ms"`
 	Timestamp  time.Time `json:"timestamp,omitempty"`
 	Error      string    `json:"error,omitempty"`
+	Operation  string    `json:"operation,omitempty"`   // GraphQL operations
+	UpstreamMs int64     `json:"upstream_ms,omitempty"` // Time spent at the upstream
+	ChaosMs    int64     `json:"chaos_ms,omitempty"`    // Delay injected by chaos rules
 }
 
 // CompactPerformance represents compact performance metrics.
@@ -292,6 +419,9 @@ type PageSessionOutput struct {
 	ErrorCount     int                      `json:"error_count"`
 	HasPerformance bool                     `json:"has_performance"`
 	LoadTime       int64                    `json:"load_time_ms,omitempty"`
+	RouteCount     int                      `json:"route_count,omitempty"`
+	ConsoleCount   int                      `json:"console_count,omitempty"`
+	RejectionCount int                      `json:"rejection_count,omitempty"`
 	Resources      []string                 `json:"resources,omitempty"` // URLs of resources
 	Errors         []map[string]interface{} `json:"errors,omitempty"`
 
@@ -333,6 +463,18 @@ type ProxyOutput struct {
 	Message     string `json:"message,omitempty"`
 	ExecutionID string `json:"execution_id,omitempty"` // For exec action
 
+	// For share
+	ShareURLs  []string `json:"share_urls,omitempty"`
+	QRCode     string   `json:"qr_code,omitempty"` // Terminal-rendered QR code for the first share URL
+	CACertPath string   `json:"ca_cert_path,omitempty"`
+	CAURL      string   `json:"ca_url,omitempty"`
+
+	// For start with https
+	HTTPSURL string `json:"https_url,omitempty"`
+
+	// For ca
+	CAInstructions string `json:"ca_instructions,omitempty"`
+
 	// For chaos
 	ChaosEnabled bool              `json:"chaos_enabled,omitempty"`
 	ChaosStats   *ChaosStatsOutput `json:"chaos_stats,omitempty"`
@@ -349,6 +491,8 @@ type ChaosStatsOutput struct {
 	DropsInjected   int64            `json:"drops_injected"`
 	TruncatedCount  int64            `json:"truncated_count"`
 	ReorderedCount  int64            `json:"reordered_count"`
+	EventsDelayed   int64            `json:"events_delayed,omitempty"`
+	EventsDropped   int64            `json:"events_dropped,omitempty"`
 	RuleStats       map[string]int64 `json:"rule_stats,omitempty"`
 }
 
@@ -359,6 +503,7 @@ type ChaosRuleOutput struct {
 	Type         string   `json:"type"`
 	Enabled      bool     `json:"enabled"`
 	URLPattern   string   `json:"url_pattern,omitempty"`
+	Operation    string   `json:"operation,omitempty"`
 	Methods      []string `json:"methods,omitempty"`
 	Probability  float64  `json:"probability"`
 	TimesApplied int64    `json:"times_applied"`
@@ -396,15 +541,27 @@ type LogStatsOutput struct {
 // ProxyLogInput defines input for the proxylog tool.
 type ProxyLogInput struct {
 	ProxyID     string   `json:"proxy_id" jsonschema:"Proxy ID to query logs from"`
-	Action      string   `json:"action,omitempty" jsonschema:"Action: query, summary, clear, stats (default: query)"`
-	Types       []string `json:"types,omitempty" jsonschema:"Filter by type: http, error, performance"`
+	Action      string   `json:"action,omitempty" jsonschema:"Action: query, summary, aggregate, clear, stats, body (default: query)"`
+	Types       []string `json:"types,omitempty" jsonschema:"Filter by type: http, error, performance, console, rejection, web_vital, ..."`
 	Methods     []string `json:"methods,omitempty" jsonschema:"Filter by HTTP method: GET, POST, etc."`
 	URLPattern  string   `json:"url_pattern,omitempty" jsonschema:"URL substring to match"`
 	StatusCodes []int    `json:"status_codes,omitempty" jsonschema:"Filter by HTTP status code"`
 	Since       string   `json:"since,omitempty" jsonschema:"Start time (RFC3339 or duration like '5m')"`
 	Until       string   `json:"until,omitempty" jsonschema:"End time (RFC3339)"`
 	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum results (default: 100)"`
-	Detail      []string `json:"detail,omitempty" jsonschema:"For summary: sections to include full detail for (errors, http, performance, interactions, mutations)"`
+	Detail      []string `json:"detail,omitempty" jsonschema:"For summary: sections to include full detail for (errors, http, performance, interactions, mutations, console, rejections)"`
+	RequestID   string   `json:"request_id,omitempty" jsonschema:"For body: ID of the HTTP log entry (e.g. req-42)"`
+	Part        string   `json:"part,omitempty" jsonschema:"For body: request or response (default: response)"`
+	// Structured filters (HTTP entries only, except error_pattern)
+	RequestHeaders  map[string]string `json:"request_headers,omitempty" jsonschema:"Filter by request header: name to case-insensitive substring (empty string: header present)"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty" jsonschema:"Filter by response header: name to case-insensitive substring (empty string: header present)"`
+	MinDuration     string            `json:"min_duration,omitempty" jsonschema:"Only requests at least this slow (e.g. 500ms, 2s)"`
+	MaxDuration     string            `json:"max_duration,omitempty" jsonschema:"Only requests at most this slow"`
+	BodyPath        string            `json:"body_path,omitempty" jsonschema:"JSONPath predicate on the JSON response body, e.g. $.errors[0].code == \"UNAUTHENTICATED\" (operators: == != < <= > >= =~; no operator: path exists)"`
+	RequestBodyPath string            `json:"request_body_path,omitempty" jsonschema:"JSONPath predicate on the JSON request body, e.g. $.variables.id"`
+	ErrorPattern    string            `json:"error_pattern,omitempty" jsonschema:"Regex matched against error messages (frontend errors, failed requests, error-level custom logs)"`
+	Operation       string            `json:"operation,omitempty" jsonschema:"GraphQL operation name substring (or persisted query hash prefix)"`
+	GroupBy         []string          `json:"group_by,omitempty" jsonschema:"For aggregate: endpoint, path, method, status, status_class, upstream, operation (default: endpoint)"`
 }
 
 // ProxyLogOutput defines output for proxylog tool.
@@ -419,6 +576,12 @@ type ProxyLogOutput struct {
 	// For stats
 	Stats *LogStatsOutput `json:"stats,omitempty"`
 
+	// For body
+	Body *proxy.BodyContent `json:"body,omitempty"`
+
+	// For aggregate
+	Groups []proxy.LogGroup `json:"groups,omitempty"`
+
 	// For clear
 	Success bool   `json:"success,omitempty"`
 	Message string `json:"message,omitempty"`
@@ -471,6 +634,9 @@ Actions:
 
 Examples:
   proxy {action: "start", id: "dev", target_url: "http://localhost:3000"}
+  proxy {action: "start", id: "app", target_url: "http://localhost:3000", routes: [
+    {path: "/api", target: "http://localhost:8080", strip_prefix: true},
+    {path: "/auth", target: "http://localhost:9000", host_header: "auth.local"}]}
   proxy {action: "status", id: "dev"}
   proxy {action: "list"}
   proxy {action: "exec", id: "dev", code: "document.title"}
@@ -484,6 +650,13 @@ The proxy automatically:
   - Provides WebSocket endpoint for metrics
   - Injects __devtool API with 50+ diagnostic functions
 
+With https: true the proxy also listens on HTTPS (HTTP/2) using a certificate
+from a local agnt CA; proxy {action: "ca"} shows how to trust it.
+
+Routes serve a frontend and its backends from one origin (no CORS). Traffic to
+every upstream is logged (http entries carry an 'upstream' field), grouped into
+the same page sessions, and can be targeted by chaos rules with 'upstream'.
+
 Port selection:
   - Default: A stable port derived from target URL hash (range 10000-60000)
   - Only specify 'port' if you need a specific port number
@@ -511,8 +684,10 @@ Each proxy has separate log storage and WebSocket connections.`,
 
 Actions:
   query: Search logs with filters (default)
+  aggregate: Group HTTP entries with count, duration percentiles and statuses
   clear: Clear all logs for a proxy
   stats: Get log statistics
+  body: Fetch the full request or response body of an HTTP entry
 
 Log Types:
   http: HTTP request/response pairs
@@ -522,6 +697,10 @@ Log Types:
   screenshot: Screenshots captured via __devtool.screenshot()
   execution: Results of executed JavaScript code
   response: JavaScript execution responses returned to MCP client
+  stream_event: One event of an SSE or NDJSON response (request_id links the http entry)
+  console: console.warn/console.error calls and failed console.assert
+  rejection: Unhandled promise rejections with stacks
+  web_vital: LCP, CLS, INP, FCP, TTFB and long tasks, rated and attributed to an element
 
 Examples:
   proxylog {proxy_id: "dev", types: ["http"], methods: ["GET"]}
@@ -535,6 +714,33 @@ Examples:
   proxylog {proxy_id: "dev", since: "5m", limit: 50}
   proxylog {proxy_id: "dev", action: "stats"}
   proxylog {proxy_id: "dev", action: "clear"}
+  proxylog {proxy_id: "dev", action: "body", request_id: "req-42"}
+  proxylog {proxy_id: "dev", action: "body", request_id: "req-42", part: "request"}
+
+Structured filters: request_headers / response_headers ({name: substring},
+"" = present), min_duration / max_duration ("500ms"), body_path /
+request_body_path (JSONPath predicate such as $.errors[0].code ==
+"UNAUTHENTICATED"; operators == != < <= > >= =~) and error_pattern (regex).
+Header, duration and body filters only match HTTP entries.
+  proxylog {proxy_id: "dev", body_path: "$.errors[0].code == \"UNAUTHENTICATED\""}
+  proxylog {proxy_id: "dev", min_duration: "1s"}
+
+GraphQL requests carry 'graphql' (operations, variables, persisted query
+hash, response errors); filter them with operation: "GetViewer".
+
+Aggregate groups by endpoint (method and path template, default), path,
+method, status, status_class, upstream or operation, reporting count, errors,
+avg/p50/p95/max duration and status counts:
+  proxylog {proxy_id: "dev", action: "aggregate", group_by: ["endpoint"]}
+  proxylog {proxy_id: "dev", action: "aggregate", group_by: ["upstream", "status"]}
+
+http entries carry 'timing': chaos delay, dns, connect, tls, ttfb, transfer and
+upstream time (nanoseconds), plus the upstream's Server-Timing metrics.
+
+HTTP bodies are decoded (gzip, deflate) and previewed by type: JSON is
+pretty-printed, forms and multipart uploads are listed field by field, and
+binary bodies show their type and size. Bodies larger than the preview are
+stored on disk; use action "body" to fetch them (binary content as base64).
 
 Each proxy maintains its own separate log storage.`,
 	}, makeProxyLogHandler(pm))
@@ -553,8 +759,10 @@ func makeProxyHandler(pm *proxy.ProxyManager) func(context.Context, *mcp.CallToo
 			return handleProxyList(pm)
 		case "exec":
 			return handleProxyExec(pm, input)
+		case "ca":
+			return handleProxyCA()
 		default:
-			return errorResult(fmt.Sprintf("unknown action %q. Use: start, stop, status, list, exec", input.Action)), ProxyOutput{}, nil
+			return errorResult(fmt.Sprintf("unknown action %q. Use: start, stop, status, list, exec, ca", input.Action)), ProxyOutput{}, nil
 		}
 	}
 }
@@ -583,6 +791,10 @@ func handleProxyStart(ctx context.Context, pm *proxy.ProxyManager, input ProxyIn
 		MaxLogSize:  input.MaxLogSize,
 		AutoRestart: true, // Enable auto-restart for development tool
 		VerifyTLS:   input.VerifyTLS,
+		Routes:      routeInputsToProtocol(input.Routes),
+		HTTPS:       input.HTTPS,
+		HTTPSPort:   httpsPortInput(input.HTTPSPort),
+		BodyCapture: bodyCaptureInput(input),
 	}
 
 	// Use background context - proxy should outlive the MCP tool call
@@ -591,14 +803,74 @@ func handleProxyStart(ctx context.Context, pm *proxy.ProxyManager, input ProxyIn
 		return errorResult(fmt.Sprintf("failed to start proxy: %v", err)), ProxyOutput{}, nil
 	}
 
+	message := fmt.Sprintf("Proxy started. Access at http://localhost%s", proxyServer.ListenAddr)
+	if httpsURL := proxyServer.HTTPSURL(); httpsURL != "" {
+		message += " or " + httpsURL
+	}
+
 	return nil, ProxyOutput{
 		ID:         proxyServer.ID,
 		TargetURL:  proxyServer.TargetURL.String(),
 		ListenAddr: proxyServer.ListenAddr,
-		Message:    fmt.Sprintf("Proxy started. Access at http://localhost%s", proxyServer.ListenAddr),
+		HTTPSURL:   proxyServer.HTTPSURL(),
+		CACertPath: proxyServer.CACertPath(),
+		Message:    message,
 	}, nil
 }
 
+// httpsPortInput maps an unset https_port to the stable default port.
+func httpsPortInput(port int) int {
+	if port <= 0 {
+		return -1
+	}
+	return port
+}
+
+// bodyCaptureInput maps the body capture inputs, or nil for the defaults.
+func bodyCaptureInput(input ProxyInput) *protocol.BodyCaptureConfig {
+	if input.BodyInlineLimit == 0 && input.BodyMaxSize == 0 {
+		return nil
+	}
+	return &protocol.BodyCaptureConfig{
+		InlineLimit: input.BodyInlineLimit,
+		MaxSize:     input.BodyMaxSize,
+	}
+}
+
+// handleProxyCA returns the local agnt CA and how to trust it. The CA is
+// created on first use.
+func handleProxyCA() (*mcp.CallToolResult, ProxyOutput, error) {
+	ca, err := certs.LoadOrCreate("")
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to load local CA: %v", err)), ProxyOutput{}, nil
+	}
+	return nil, ProxyOutput{
+		Success:        true,
+		CACertPath:     ca.CertPath(),
+		CAInstructions: certs.InstallInstructions(ca.CertPath(), runtime.GOOS),
+		Message:        "Trust the agnt CA to open HTTPS proxies and HTTPS LAN shares without warnings",
+	}, nil
+}
+
+// routeInputsToProtocol converts tool route input to proxy routes.
+func routeInputsToProtocol(routes []RouteInput) []protocol.ProxyRoute {
+	if len(routes) == 0 {
+		return nil
+	}
+	result := make([]protocol.ProxyRoute, len(routes))
+	for i, r := range routes {
+		result[i] = protocol.ProxyRoute{
+			Name:        r.Name,
+			Path:        r.Path,
+			Pattern:     r.Pattern,
+			Target:      r.Target,
+			StripPrefix: r.StripPrefix,
+			HostHeader:  r.HostHeader,
+		}
+	}
+	return result
+}
+
 func handleProxyStop(ctx context.Context, pm *proxy.ProxyManager, input ProxyInput) (*mcp.CallToolResult, ProxyOutput, error) {
 	if input.ID == "" {
 		return errorResult("id required for stop"), ProxyOutput{}, nil
@@ -796,43 +1068,21 @@ func makeProxyLogHandler(pm *proxy.ProxyManager) func(context.Context, *mcp.Call
 			return handleProxyLogClear(proxyServer, input)
 		case "stats":
 			return handleProxyLogStats(proxyServer, input)
+		case "body":
+			return handleProxyLogBody(proxyServer, input)
+		case "aggregate":
+			return handleProxyLogAggregate(proxyServer, input)
 		default:
-			return errorResult(fmt.Sprintf("unknown action %q. Use: query, clear, stats", action)), ProxyLogOutput{}, nil
+			return errorResult(fmt.Sprintf("unknown action %q. Use: query, aggregate, clear, stats, body", action)), ProxyLogOutput{}, nil
 		}
 	}
 }
 
 func handleProxyLogQuery(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
 	// Build filter
-	filter := proxy.LogFilter{
-		Methods:     input.Methods,
-		URLPattern:  input.URLPattern,
-		StatusCodes: input.StatusCodes,
-		Limit:       input.Limit,
-	}
-
-	// Parse types
-	if len(input.Types) > 0 {
-		for _, t := range input.Types {
-			filter.Types = append(filter.Types, proxy.LogEntryType(t))
-		}
-	}
-
-	// Parse time range
-	if input.Since != "" {
-		since, err := parseTimeOrDuration(input.Since)
-		if err != nil {
-			return errorResult(fmt.Sprintf("invalid since: %v", err)), ProxyLogOutput{}, nil
-		}
-		filter.Since = &since
-	}
-
-	if input.Until != "" {
-		until, err := parseTime(input.Until)
-		if err != nil {
-			return errorResult(fmt.Sprintf("invalid until: %v", err)), ProxyLogOutput{}, nil
-		}
-		filter.Until = &until
+	filter, err := proxy.FilterFromQuery(logQueryFilter(input))
+	if err != nil {
+		return errorResult(err.Error()), ProxyLogOutput{}, nil
 	}
 
 	// Default limit
@@ -873,6 +1123,21 @@ func handleProxyLogQuery(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*
 				if entry.HTTP.Error != "" {
 					data["error"] = entry.HTTP.Error
 				}
+				if entry.HTTP.RequestBodyInfo != nil {
+					data["request_body_info"] = entry.HTTP.RequestBodyInfo
+				}
+				if entry.HTTP.ResponseBodyInfo != nil {
+					data["response_body_info"] = entry.HTTP.ResponseBodyInfo
+				}
+				if entry.HTTP.GraphQL != nil {
+					data["graphql"] = entry.HTTP.GraphQL
+				}
+				if entry.HTTP.Stream != nil {
+					data["stream"] = entry.HTTP.Stream
+				}
+				if entry.HTTP.Timing != nil {
+					data["timing"] = entry.HTTP.Timing
+				}
 			}
 			output[i] = LogEntryOutput{
 				Type:      string(entry.Type),
@@ -891,6 +1156,10 @@ func handleProxyLogQuery(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*
 				if entry.Error.Stack != "" {
 					data["stack"] = entry.Error.Stack
 				}
+				if entry.Error.ResolvedStack != "" {
+					data["resolved_stack"] = entry.Error.ResolvedStack
+					data["frames"] = entry.Error.Frames
+				}
 			}
 			output[i] = LogEntryOutput{
 				Type:      string(entry.Type),
@@ -982,6 +1251,78 @@ func handleProxyLogQuery(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*
 				Timestamp: entry.Response.Timestamp,
 				Data:      marshalData(data),
 			}
+
+		case proxy.LogTypeStreamEvent:
+			if entry.StreamEvent != nil {
+				data["id"] = entry.StreamEvent.ID
+				data["request_id"] = entry.StreamEvent.RequestID
+				data["url"] = entry.StreamEvent.URL
+				data["seq"] = entry.StreamEvent.Seq
+				data["event"] = entry.StreamEvent.Event
+				data["data"] = entry.StreamEvent.Data
+				data["offset_ms"] = entry.StreamEvent.Offset.Milliseconds()
+				if entry.StreamEvent.EventID != "" {
+					data["event_id"] = entry.StreamEvent.EventID
+				}
+				if entry.StreamEvent.Chaos != "" {
+					data["chaos"] = entry.StreamEvent.Chaos
+				}
+			}
+			output[i] = LogEntryOutput{
+				Type:      string(entry.Type),
+				Timestamp: entry.StreamEvent.Timestamp,
+				Data:      marshalData(data),
+			}
+
+		case proxy.LogTypeConsole:
+			if entry.Console != nil {
+				data["id"] = entry.Console.ID
+				data["level"] = entry.Console.Level
+				data["message"] = entry.Console.Message
+				data["args"] = entry.Console.Args
+				data["stack"] = entry.Console.Stack
+				data["url"] = entry.Console.URL
+			}
+			output[i] = LogEntryOutput{
+				Type:      string(entry.Type),
+				Timestamp: entry.Console.Timestamp,
+				Data:      marshalData(data),
+			}
+
+		case proxy.LogTypeRejection:
+			if entry.Rejection != nil {
+				data["id"] = entry.Rejection.ID
+				data["message"] = entry.Rejection.Message
+				data["name"] = entry.Rejection.Name
+				data["reason_type"] = entry.Rejection.ReasonType
+				data["stack"] = entry.Rejection.Stack
+				data["url"] = entry.Rejection.URL
+				if entry.Rejection.ResolvedStack != "" {
+					data["resolved_stack"] = entry.Rejection.ResolvedStack
+					data["frames"] = entry.Rejection.Frames
+				}
+			}
+			output[i] = LogEntryOutput{
+				Type:      string(entry.Type),
+				Timestamp: entry.Rejection.Timestamp,
+				Data:      marshalData(data),
+			}
+
+		case proxy.LogTypeWebVital:
+			if entry.WebVital != nil {
+				data["id"] = entry.WebVital.ID
+				data["name"] = entry.WebVital.Name
+				data["value"] = entry.WebVital.Value
+				data["rating"] = entry.WebVital.Rating
+				data["element"] = entry.WebVital.Element
+				data["details"] = entry.WebVital.Details
+				data["url"] = entry.WebVital.URL
+			}
+			output[i] = LogEntryOutput{
+				Type:      string(entry.Type),
+				Timestamp: entry.WebVital.Timestamp,
+				Data:      marshalData(data),
+			}
 		}
 	}
 
@@ -1013,20 +1354,61 @@ func handleProxyLogStats(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*
 	}, nil
 }
 
-// Helper functions
+func handleProxyLogBody(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
+	if input.RequestID == "" {
+		return errorResult("request_id required for body"), ProxyLogOutput{}, nil
+	}
 
-func parseTimeOrDuration(s string) (time.Time, error) {
-	// Try parsing as duration first (e.g., "5m", "1h")
-	if d, err := time.ParseDuration(s); err == nil {
-		return time.Now().Add(-d), nil
+	body, err := proxyServer.HTTPBody(input.RequestID, input.Part)
+	if err != nil {
+		return errorResult(err.Error()), ProxyLogOutput{}, nil
 	}
 
-	// Try parsing as RFC3339 timestamp
-	return time.Parse(time.RFC3339, s)
+	return nil, ProxyLogOutput{Body: body}, nil
 }
 
-func parseTime(s string) (time.Time, error) {
-	return time.Parse(time.RFC3339, s)
+func handleProxyLogAggregate(proxyServer *proxy.ProxyServer, input ProxyLogInput) (*mcp.CallToolResult, ProxyLogOutput, error) {
+	query := logQueryFilter(input)
+	if len(query.GroupBy) == 0 {
+		query.GroupBy = []string{proxy.GroupByEndpoint}
+	}
+	if err := proxy.ValidateGroupBy(query.GroupBy); err != nil {
+		return errorResult(err.Error()), ProxyLogOutput{}, nil
+	}
+	filter, err := proxy.FilterFromQuery(query)
+	if err != nil {
+		return errorResult(err.Error()), ProxyLogOutput{}, nil
+	}
+
+	entries := proxyServer.Logger().Query(filter)
+	return nil, ProxyLogOutput{
+		Groups: proxy.AggregateHTTP(entries, query.GroupBy),
+		Count:  len(entries),
+	}, nil
+}
+
+// Helper functions
+
+// logQueryFilter builds the wire query filter from proxylog input.
+func logQueryFilter(input ProxyLogInput) protocol.LogQueryFilter {
+	return protocol.LogQueryFilter{
+		Types:           input.Types,
+		Methods:         input.Methods,
+		URLPattern:      input.URLPattern,
+		StatusCodes:     input.StatusCodes,
+		Since:           input.Since,
+		Until:           input.Until,
+		Limit:           input.Limit,
+		RequestHeaders:  input.RequestHeaders,
+		ResponseHeaders: input.ResponseHeaders,
+		MinDuration:     input.MinDuration,
+		MaxDuration:     input.MaxDuration,
+		BodyPath:        input.BodyPath,
+		RequestBodyPath: input.RequestBodyPath,
+		ErrorPattern:    input.ErrorPattern,
+		Operation:       input.Operation,
+		GroupBy:         input.GroupBy,
+	}
 }
 
 // makeCurrentPageHandler creates the handler for the currentpage tool.
@@ -1111,6 +1493,9 @@ func convertPageSession(session *proxy.PageSession, includeDetails bool) PageSes
 		ResourceCount:  len(session.Resources),
 		ErrorCount:     len(session.Errors),
 		HasPerformance: session.Performance != nil,
+		RouteCount:     session.RouteCount,
+		ConsoleCount:   session.ConsoleCount,
+		RejectionCount: session.RejectionCount,
 	}
 
 	if session.Performance != nil {
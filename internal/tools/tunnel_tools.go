@@ -11,39 +11,50 @@ import (
 
 // TunnelInput represents input for the tunnel tool.
 type TunnelInput struct {
-	Action     string `json:"action" jsonschema:"Action: start, stop, status, list"`
-	ID         string `json:"id,omitempty" jsonschema:"Tunnel ID (required for start/stop/status)"`
-	Provider   string `json:"provider,omitempty" jsonschema:"Tunnel provider: 'cloudflare' or 'ngrok' (required for start)"`
-	LocalPort  int    `json:"local_port,omitempty" jsonschema:"Local port to tunnel (required for start)"`
-	LocalHost  string `json:"local_host,omitempty" jsonschema:"Local host (default: localhost)"`
-	BinaryPath string `json:"binary_path,omitempty" jsonschema:"Optional path to tunnel binary"`
-	ProxyID    string `json:"proxy_id,omitempty" jsonschema:"Optional proxy ID to auto-configure with the tunnel's public URL"`
-	Global     bool   `json:"global,omitempty" jsonschema:"For list: include tunnels from all directories (default: false)"`
+	Action         string   `json:"action" jsonschema:"Action: start, stop, status, list"`
+	ID             string   `json:"id,omitempty" jsonschema:"Tunnel ID (required for start/stop/status)"`
+	Provider       string   `json:"provider,omitempty" jsonschema:"Tunnel provider: 'cloudflare', 'ngrok', 'tailscale', 'ssh' (localhost.run), 'serveo' or 'custom' (required for start)"`
+	LocalPort      int      `json:"local_port,omitempty" jsonschema:"Local port to tunnel (required for start)"`
+	LocalHost      string   `json:"local_host,omitempty" jsonschema:"Local host (default: localhost)"`
+	BinaryPath     string   `json:"binary_path,omitempty" jsonschema:"Optional path to tunnel binary"`
+	ProxyID        string   `json:"proxy_id,omitempty" jsonschema:"Optional proxy ID to auto-configure with the tunnel's public URL"`
+	Command        string   `json:"command,omitempty" jsonschema:"Command to run for the custom provider ({{PORT}} is replaced with local_port)"`
+	Args           []string `json:"args,omitempty" jsonschema:"Extra arguments for the tunnel command ({{PORT}} is replaced with local_port)"`
+	AuthToken      string   `json:"auth_token,omitempty" jsonschema:"ngrok auth token"`
+	Region         string   `json:"region,omitempty" jsonschema:"ngrok region"`
+	SSHHost        string   `json:"ssh_host,omitempty" jsonschema:"SSH host for the ssh provider (default: localhost.run)"`
+	AutoRestart    *bool    `json:"auto_restart,omitempty" jsonschema:"Restart the tunnel when it dies or fails health checks (default: true)"`
+	HealthInterval int      `json:"health_interval,omitempty" jsonschema:"Seconds between health checks of the public URL (default: 30, negative disables)"`
+	Global         bool     `json:"global,omitempty" jsonschema:"For list: include tunnels from all directories (default: false)"`
 }
 
 // TunnelOutput represents output from the tunnel tool.
 type TunnelOutput struct {
-	ID        string        `json:"id,omitempty"`
-	Provider  string        `json:"provider,omitempty"`
-	State     string        `json:"state,omitempty"`
-	PublicURL string        `json:"public_url,omitempty"`
-	LocalAddr string        `json:"local_addr,omitempty"`
-	Error     string        `json:"error,omitempty"`
-	Success   bool          `json:"success,omitempty"`
-	Message   string        `json:"message,omitempty"`
-	Count     int           `json:"count,omitempty"`
-	Tunnels   []TunnelEntry `json:"tunnels,omitempty"`
+	ID          string        `json:"id,omitempty"`
+	Provider    string        `json:"provider,omitempty"`
+	State       string        `json:"state,omitempty"`
+	PublicURL   string        `json:"public_url,omitempty"`
+	LocalAddr   string        `json:"local_addr,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Restarts    int           `json:"restarts,omitempty"`
+	HealthError string        `json:"health_error,omitempty"`
+	Success     bool          `json:"success,omitempty"`
+	Message     string        `json:"message,omitempty"`
+	Count       int           `json:"count,omitempty"`
+	Tunnels     []TunnelEntry `json:"tunnels,omitempty"`
 }
 
 // TunnelEntry represents a tunnel in a list response.
 type TunnelEntry struct {
-	ID        string `json:"id,omitempty"`
-	Provider  string `json:"provider"`
-	State     string `json:"state"`
-	PublicURL string `json:"public_url,omitempty"`
-	LocalAddr string `json:"local_addr"`
-	Path      string `json:"path,omitempty"`
-	Error     string `json:"error,omitempty"`
+	ID          string `json:"id,omitempty"`
+	Provider    string `json:"provider"`
+	State       string `json:"state"`
+	PublicURL   string `json:"public_url,omitempty"`
+	LocalAddr   string `json:"local_addr"`
+	Path        string `json:"path,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Restarts    int    `json:"restarts,omitempty"`
+	HealthError string `json:"health_error,omitempty"`
 }
 
 // RegisterTunnelTool registers the tunnel MCP tool with the server.
@@ -60,11 +71,16 @@ Actions:
 
 Providers:
   cloudflare: Uses cloudflared for Cloudflare Quick Tunnels (trycloudflare.com)
-  ngrok: Uses ngrok for tunneling
+  ngrok: Uses ngrok for tunneling (auth_token and region optional)
+  tailscale: Uses 'tailscale funnel' to expose the port on your tailnet's ts.net domain
+  ssh: Uses ssh -R to localhost.run (or ssh_host); 'serveo' selects serveo.net
+  custom: Runs command with args; the first https URL printed is used
 
 Examples:
   tunnel {action: "start", id: "dev", provider: "cloudflare", local_port: 8080}
   tunnel {action: "start", id: "dev", provider: "cloudflare", local_port: 12345, proxy_id: "dev"}
+  tunnel {action: "start", id: "dev", provider: "ssh", local_port: 8080}
+  tunnel {action: "start", id: "dev", provider: "custom", local_port: 8080, command: "lt", args: ["--port", "{{PORT}}"]}
   tunnel {action: "status", id: "dev"}
   tunnel {action: "list"}
   tunnel {action: "stop", id: "dev"}
@@ -72,9 +88,16 @@ Examples:
 The tunnel automatically configures the proxy's public_url when proxy_id is specified,
 enabling proper URL rewriting for mobile device testing through the tunnel.
 
+The public URL is health-checked periodically. When the tunnel process dies or
+fails repeated health checks it is restarted (auto_restart: false disables this);
+a restart may produce a new URL, which is pushed to the linked proxy and its
+connected browsers. status reports restarts and the last health error.
+
 Requirements:
   - cloudflare provider: 'cloudflared' binary must be installed and in PATH
-  - ngrok provider: 'ngrok' binary must be installed and in PATH`,
+  - ngrok provider: 'ngrok' binary must be installed and in PATH
+  - tailscale provider: 'tailscale' installed, logged in, with Funnel enabled
+  - ssh provider: 'ssh' binary in PATH`,
 	}, dt.makeTunnelHandler())
 }
 
@@ -109,19 +132,26 @@ func (dt *DaemonTools) handleTunnelStart(input TunnelInput) (*mcp.CallToolResult
 		return errorResult("id required"), emptyOutput, nil
 	}
 	if input.Provider == "" {
-		return errorResult("provider required (cloudflare or ngrok)"), emptyOutput, nil
+		return errorResult("provider required (cloudflare, ngrok, tailscale, ssh or custom)"), emptyOutput, nil
 	}
 	if input.LocalPort <= 0 {
 		return errorResult("local_port required"), emptyOutput, nil
 	}
 
 	config := protocol.TunnelStartConfig{
-		ID:         input.ID,
-		Provider:   input.Provider,
-		LocalPort:  input.LocalPort,
-		LocalHost:  input.LocalHost,
-		BinaryPath: input.BinaryPath,
-		ProxyID:    input.ProxyID,
+		ID:             input.ID,
+		Provider:       input.Provider,
+		LocalPort:      input.LocalPort,
+		LocalHost:      input.LocalHost,
+		BinaryPath:     input.BinaryPath,
+		ProxyID:        input.ProxyID,
+		Command:        input.Command,
+		Args:           input.Args,
+		AuthToken:      input.AuthToken,
+		Region:         input.Region,
+		SSHHost:        input.SSHHost,
+		AutoRestart:    input.AutoRestart,
+		HealthInterval: input.HealthInterval,
 	}
 
 	result, err := dt.client.TunnelStart(config)
@@ -176,13 +206,15 @@ func (dt *DaemonTools) handleTunnelStatus(input TunnelInput) (*mcp.CallToolResul
 	}
 
 	output := TunnelOutput{
-		ID:        getString(result, "id"),
-		Provider:  getString(result, "provider"),
-		State:     getString(result, "state"),
-		PublicURL: getString(result, "public_url"),
-		LocalAddr: getString(result, "local_addr"),
-		Error:     getString(result, "error"),
-		Tunnels:   []TunnelEntry{},
+		ID:          getString(result, "id"),
+		Provider:    getString(result, "provider"),
+		State:       getString(result, "state"),
+		PublicURL:   getString(result, "public_url"),
+		LocalAddr:   getString(result, "local_addr"),
+		Error:       getString(result, "error"),
+		Restarts:    getInt(result, "restarts"),
+		HealthError: getString(result, "health_error"),
+		Tunnels:     []TunnelEntry{},
 	}
 
 	return nil, output, nil
@@ -205,13 +237,15 @@ func (dt *DaemonTools) handleTunnelList(input TunnelInput) (*mcp.CallToolResult,
 	for _, t := range tunnelsRaw {
 		if tm, ok := t.(map[string]interface{}); ok {
 			tunnels = append(tunnels, TunnelEntry{
-				ID:        getString(tm, "id"),
-				Provider:  getString(tm, "provider"),
-				State:     getString(tm, "state"),
-				PublicURL: getString(tm, "public_url"),
-				LocalAddr: getString(tm, "local_addr"),
-				Path:      getString(tm, "path"),
-				Error:     getString(tm, "error"),
+				ID:          getString(tm, "id"),
+				Provider:    getString(tm, "provider"),
+				State:       getString(tm, "state"),
+				PublicURL:   getString(tm, "public_url"),
+				LocalAddr:   getString(tm, "local_addr"),
+				Path:        getString(tm, "path"),
+				Error:       getString(tm, "error"),
+				Restarts:    getInt(tm, "restarts"),
+				HealthError: getString(tm, "health_error"),
 			})
 		}
 	}
@@ -0,0 +1,113 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// monitorHealth periodically probes the public URL while cmd is running.
+// After MaxFailures consecutive failures the process is killed so that
+// supervise can restart it with a fresh tunnel.
+func (t *Tunnel) monitorHealth(ctx context.Context, cmd *exec.Cmd) {
+	if t.config.HealthInterval < 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		publicURL := t.PublicURL()
+		if publicURL == "" || t.State() != StateConnected {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, t.config.HealthTimeout)
+		err := t.probe(probeCtx, publicURL)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		t.healthMu.Lock()
+		t.health.lastCheck = time.Now()
+		if err == nil {
+			t.health.failures = 0
+			t.health.lastError = ""
+		} else {
+			t.health.failures++
+			t.health.lastError = err.Error()
+		}
+		failures := t.health.failures
+		t.healthMu.Unlock()
+
+		if failures >= t.config.MaxFailures && t.config.AutoRestart {
+			if cmd.Process != nil {
+				_ = cmd.Process.Kill()
+			}
+			return
+		}
+	}
+}
+
+// probe runs the configured health check, or the default HTTP probe.
+func (t *Tunnel) probe(ctx context.Context, publicURL string) error {
+	if t.config.HealthCheck != nil {
+		return t.config.HealthCheck(ctx, publicURL)
+	}
+	return ProbeURL(ctx, strings.TrimRight(publicURL, "/")+healthPath(t.config.HealthPath))
+}
+
+func healthPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(p, "/") {
+		return "/" + p
+	}
+	return p
+}
+
+// ProbeURL checks that a request through a tunnel's public URL reaches the
+// local service. Any response from the local service counts as healthy;
+// network errors and the gateway errors tunnel providers return when the
+// tunnel is down count as failures.
+func ProbeURL(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "agnt-tunnel-health")
+
+	client := &http.Client{
+		// Redirects are answered by the local service, which is healthy enough
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("probe failed: %w", err)
+	}
+	resp.Body.Close()
+
+	// ngrok marks its own error pages (e.g. endpoint offline)
+	if code := resp.Header.Get("Ngrok-Error-Code"); code != "" {
+		return fmt.Errorf("tunnel error %s", code)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		530: // Cloudflare: origin unreachable / tunnel not connected
+		return fmt.Errorf("tunnel returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package tunnel
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseProvider converts a provider name to a Provider.
+// Names are case-insensitive and common aliases are accepted
+// (cloudflared, tailscale-funnel, localhost.run, serveo).
+func ParseProvider(name string) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "cloudflare", "cloudflared":
+		return ProviderCloudflare, nil
+	case "ngrok":
+		return ProviderNgrok, nil
+	case "tailscale", "tailscale-funnel", "funnel":
+		return ProviderTailscale, nil
+	case "ssh", "localhost.run", "serveo", "serveo.net":
+		return ProviderSSH, nil
+	case "custom":
+		return ProviderCustom, nil
+	default:
+		return "", fmt.Errorf("unknown tunnel provider: %s", name)
+	}
+}
+
+// defaultSSHHost returns the SSH host implied by a provider alias.
+func defaultSSHHost(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "serveo", "serveo.net":
+		return "serveo.net"
+	default:
+		return "localhost.run"
+	}
+}
+
+// BuildCommand returns the executable and arguments used to start a tunnel
+// for the given configuration. The {{PORT}} placeholder is expanded in
+// custom commands and in extra args for every provider.
+func BuildCommand(config Config) (string, []string, error) {
+	provider, err := ParseProvider(string(config.Provider))
+	if err != nil {
+		return "", nil, err
+	}
+
+	host := config.LocalHost
+	if host == "" {
+		host = "localhost"
+	}
+	port := strconv.Itoa(config.LocalPort)
+	extra := expandPort(config.Args, port)
+
+	var binary string
+	var args []string
+	switch provider {
+	case ProviderCloudflare:
+		binary = "cloudflared"
+		args = []string{"tunnel", "--url", fmt.Sprintf("http://%s:%s", host, port)}
+
+	case ProviderNgrok:
+		binary = "ngrok"
+		args = []string{"http", port}
+		if config.AuthToken != "" {
+			args = append(args, "--authtoken", config.AuthToken)
+		}
+		if config.Region != "" {
+			args = append(args, "--region", config.Region)
+		}
+
+	case ProviderTailscale:
+		// tailscale funnel exposes a local port at https://<machine>.<tailnet>.ts.net
+		binary = "tailscale"
+		args = []string{"funnel", port}
+
+	case ProviderSSH:
+		// ssh -R tunnels to localhost.run / serveo-style services print the public URL
+		binary = "ssh"
+		sshHost := config.SSHHost
+		if sshHost == "" {
+			sshHost = defaultSSHHost(string(config.Provider))
+		}
+		if !strings.Contains(sshHost, "@") {
+			sshHost = "nokey@" + sshHost
+		}
+		args = []string{
+			"-o", "StrictHostKeyChecking=accept-new",
+			"-o", "ServerAliveInterval=30",
+			"-o", "ExitOnForwardFailure=yes",
+			"-R", fmt.Sprintf("80:%s:%s", host, port),
+			sshHost,
+		}
+
+	case ProviderCustom:
+		if config.Command == "" {
+			return "", nil, fmt.Errorf("custom tunnel requires command to be set")
+		}
+		return strings.ReplaceAll(config.Command, "{{PORT}}", port), extra, nil
+	}
+
+	if config.BinaryPath != "" {
+		binary = config.BinaryPath
+	}
+	return binary, append(args, extra...), nil
+}
+
+// expandPort replaces {{PORT}} in each argument.
+func expandPort(args []string, port string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = strings.ReplaceAll(arg, "{{PORT}}", port)
+	}
+	return out
+}
+
+// Provider-specific output patterns
+var (
+	// Matches: https://something-something.trycloudflare.com
+	cloudflareURLPattern = regexp.MustCompile(`https://[a-z0-9-]+\.trycloudflare\.com`)
+
+	// Matches ngrok URLs like https://abc123.ngrok.io, https://abc123.ngrok-free.app
+	// or https://abc123.ngrok-free.dev
+	ngrokURLPattern = regexp.MustCompile(`https://[a-z0-9-]+\.ngrok(?:-free)?\.(?:io|app|dev)`)
+
+	// Matches tailscale funnel URLs like https://machine.tailnet.ts.net
+	tailscaleURLPattern = regexp.MustCompile(`https://[a-zA-Z0-9-]+\.[a-zA-Z0-9-]+\.ts\.net[^\s]*`)
+
+	// Matches localhost.run (lhr.life) and serveo URLs
+	sshURLPattern = regexp.MustCompile(`https://[a-z0-9-]+\.(?:lhr\.life|localhost\.run|serveo\.net|serveousercontent\.com)`)
+)
+
+// genericURLPatterns extract public URLs from the output of custom tunnel commands.
+var genericURLPatterns = []*regexp.Regexp{
+	// ngrok patterns
+	regexp.MustCompile(`Forwarding\s+(https?://[^\s]+)\s+->`),
+	regexp.MustCompile(`url=(https?://[^\s]+)`),
+	regexp.MustCompile(`https?://[a-zA-Z0-9-]+\.ngrok(?:-free)?\.(?:app|io|dev)[^\s]*`),
+	// cloudflared patterns
+	regexp.MustCompile(`https?://[a-zA-Z0-9-]+\.trycloudflare\.com[^\s]*`),
+	regexp.MustCompile(`\|\s+(https?://[^\s|]+)`),
+	// tailscale funnel patterns (https://machine.tailnet.ts.net)
+	regexp.MustCompile(`(https://[a-zA-Z0-9-]+\.[a-zA-Z0-9-]+\.ts\.net[^\s]*)`),
+	// localtunnel patterns
+	regexp.MustCompile(`your url is:\s*(https?://[^\s]+)`),
+	// Generic https URL pattern (fallback)
+	regexp.MustCompile(`(https://[a-zA-Z0-9][a-zA-Z0-9-]*\.[a-zA-Z0-9.-]+[^\s]*)`),
+}
+
+// ExtractURL extracts the public URL from a line of tunnel output.
+// Known providers only match their own domains, so links to documentation
+// or dashboards in their output are ignored.
+func ExtractURL(provider Provider, line string) string {
+	var pattern *regexp.Regexp
+	switch provider {
+	case ProviderCloudflare:
+		pattern = cloudflareURLPattern
+	case ProviderNgrok:
+		if found := ngrokURLPattern.FindString(line); found != "" {
+			return found
+		}
+		// Custom and reserved domains: take the forwarding URL, but not
+		// links to ngrok's own dashboard or docs
+		if found := extractGenericURL(line); !isNgrokSite(found) {
+			return found
+		}
+		return ""
+	case ProviderTailscale:
+		pattern = tailscaleURLPattern
+	case ProviderSSH:
+		pattern = sshURLPattern
+	default:
+		return extractGenericURL(line)
+	}
+	return pattern.FindString(line)
+}
+
+// isNgrokSite reports whether rawURL is on ngrok.com.
+func isNgrokSite(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "ngrok.com" || strings.HasSuffix(host, ".ngrok.com")
+}
+
+// extractGenericURL tries each generic pattern in order.
+func extractGenericURL(line string) string {
+	for _, pattern := range genericURLPatterns {
+		if matches := pattern.FindStringSubmatch(line); len(matches) > 0 {
+			// Return the captured group if present, otherwise the full match
+			if len(matches) > 1 {
+				return matches[1]
+			}
+			return matches[0]
+		}
+	}
+	return ""
+}
@@ -1,13 +1,16 @@
-// Package tunnel provides management for tunnel services like Cloudflare and ngrok.
+// Package tunnel provides management for tunnel services like Cloudflare, ngrok,
+// Tailscale Funnel, SSH reverse tunnels and custom commands.
 package tunnel
 
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
-	"regexp"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +24,12 @@ const (
 	ProviderCloudflare Provider = "cloudflare"
 	// ProviderNgrok uses ngrok for tunneling.
 	ProviderNgrok Provider = "ngrok"
+	// ProviderTailscale uses tailscale funnel.
+	ProviderTailscale Provider = "tailscale"
+	// ProviderSSH uses an ssh -R reverse tunnel (localhost.run, serveo).
+	ProviderSSH Provider = "ssh"
+	// ProviderCustom runs a user-supplied command.
+	ProviderCustom Provider = "custom"
 )
 
 // State represents the tunnel state.
@@ -32,6 +41,7 @@ const (
 	StateConnected
 	StateFailed
 	StateStopped
+	StateRestarting
 )
 
 func (s State) String() string {
@@ -46,11 +56,23 @@ func (s State) String() string {
 		return "failed"
 	case StateStopped:
 		return "stopped"
+	case StateRestarting:
+		return "restarting"
 	default:
 		return "unknown"
 	}
 }
 
+// Health monitoring and restart defaults
+const (
+	DefaultHealthInterval = 30 * time.Second
+	DefaultHealthTimeout  = 10 * time.Second
+	DefaultMaxFailures    = 3
+	DefaultMaxRestarts    = 5
+	maxRestartBackoff     = 30 * time.Second
+	maxOutputLines        = 100
+)
+
 // Config holds tunnel configuration.
 type Config struct {
 	Provider   Provider
@@ -59,6 +81,30 @@ type Config struct {
 	BinaryPath string // optional: path to tunnel binary, otherwise uses PATH
 	ID         string // tunnel identifier
 	Path       string // project path for session scoping
+
+	Command   string   // command for the custom provider ({{PORT}} is expanded)
+	Args      []string // extra arguments appended to the provider command
+	AuthToken string   // ngrok auth token
+	Region    string   // ngrok region
+	SSHHost   string   // ssh provider host (default localhost.run)
+
+	// AutoRestart restarts the tunnel when its process exits or health checks fail.
+	// A restarted tunnel usually gets a new public URL, reported through OnURL.
+	AutoRestart bool
+	// MaxRestarts limits consecutive restarts that fail to produce a URL (default 5).
+	MaxRestarts int
+
+	// HealthInterval is the time between probes through the public URL
+	// (default 30s, negative disables health checks).
+	HealthInterval time.Duration
+	// HealthTimeout bounds a single probe (default 10s).
+	HealthTimeout time.Duration
+	// HealthPath is requested on the public URL (default "/").
+	HealthPath string
+	// MaxFailures is the number of consecutive failed probes before restart (default 3).
+	MaxFailures int
+	// HealthCheck overrides the default HTTP probe.
+	HealthCheck func(ctx context.Context, publicURL string) error
 }
 
 // Tunnel represents a running tunnel instance.
@@ -67,15 +113,31 @@ type Tunnel struct {
 	state     atomic.Uint32
 	publicURL atomic.Pointer[string]
 	cmd       *exec.Cmd
+	cmdMu     sync.Mutex
 	cancel    context.CancelFunc
 	done      chan struct{}
 	err       error
 	errMu     sync.RWMutex
 
+	restarts   atomic.Int32 // total automatic restarts
+	attempts   atomic.Int32 // consecutive restarts without a public URL
+	health     healthStatus
+	healthMu   sync.RWMutex
+	output     []string // recent output lines for debugging
+	outputMu   sync.RWMutex
+	callbackMu sync.RWMutex
+
 	// Callbacks
 	onURL func(url string)
 }
 
+// healthStatus records the result of the most recent health probe.
+type healthStatus struct {
+	lastCheck time.Time
+	failures  int
+	lastError string
+}
+
 // TunnelInfo contains information about a tunnel.
 type TunnelInfo struct {
 	ID        string   `json:"id"`
@@ -85,22 +147,50 @@ type TunnelInfo struct {
 	LocalAddr string   `json:"local_addr"`
 	Path      string   `json:"path,omitempty"`
 	Error     string   `json:"error,omitempty"`
+
+	Restarts        int        `json:"restarts,omitempty"`
+	LastHealthCheck *time.Time `json:"last_health_check,omitempty"`
+	HealthFailures  int        `json:"health_failures,omitempty"`
+	HealthError     string     `json:"health_error,omitempty"`
 }
 
 // New creates a new tunnel with the given configuration.
+// Provider aliases are normalized; an unknown provider fails in Start.
 func New(config Config) *Tunnel {
 	if config.LocalHost == "" {
 		config.LocalHost = "localhost"
 	}
+	if p, err := ParseProvider(string(config.Provider)); err == nil {
+		if p == ProviderSSH && config.SSHHost == "" {
+			config.SSHHost = defaultSSHHost(string(config.Provider))
+		}
+		config.Provider = p
+	}
+	if config.MaxRestarts == 0 {
+		config.MaxRestarts = DefaultMaxRestarts
+	}
+	if config.HealthInterval == 0 {
+		config.HealthInterval = DefaultHealthInterval
+	}
+	if config.HealthTimeout == 0 {
+		config.HealthTimeout = DefaultHealthTimeout
+	}
+	if config.MaxFailures == 0 {
+		config.MaxFailures = DefaultMaxFailures
+	}
 	return &Tunnel{
 		config: config,
 		done:   make(chan struct{}),
+		output: make([]string, 0, maxOutputLines),
 	}
 }
 
-// OnURL sets a callback that's invoked when the public URL is discovered.
+// OnURL sets a callback that's invoked whenever a public URL is discovered,
+// including the new URL after an automatic restart.
 func (t *Tunnel) OnURL(fn func(url string)) {
+	t.callbackMu.Lock()
 	t.onURL = fn
+	t.callbackMu.Unlock()
 }
 
 // Start starts the tunnel and returns immediately.
@@ -113,15 +203,16 @@ func (t *Tunnel) Start(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	t.cancel = cancel
 
-	switch t.config.Provider {
-	case ProviderCloudflare:
-		return t.startCloudflare(ctx)
-	case ProviderNgrok:
-		return t.startNgrok(ctx)
-	default:
+	cmd, err := t.startProcess(ctx)
+	if err != nil {
 		t.setState(StateFailed)
-		return fmt.Errorf("unsupported tunnel provider: %s", t.config.Provider)
+		t.setError(err)
+		close(t.done)
+		return err
 	}
+
+	go t.supervise(ctx, cmd)
+	return nil
 }
 
 // Stop stops the tunnel.
@@ -130,9 +221,11 @@ func (t *Tunnel) Stop(ctx context.Context) error {
 		t.cancel()
 	}
 
-	if t.cmd != nil && t.cmd.Process != nil {
-		// Send interrupt first for graceful shutdown
-		if err := t.cmd.Process.Kill(); err != nil {
+	t.cmdMu.Lock()
+	cmd := t.cmd
+	t.cmdMu.Unlock()
+	if cmd != nil && cmd.Process != nil {
+		if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
 			return fmt.Errorf("failed to kill tunnel process: %w", err)
 		}
 	}
@@ -161,6 +254,11 @@ func (t *Tunnel) PublicURL() string {
 	return ""
 }
 
+// Restarts returns the number of automatic restarts performed.
+func (t *Tunnel) Restarts() int {
+	return int(t.restarts.Load())
+}
+
 // Info returns information about the tunnel.
 func (t *Tunnel) Info() TunnelInfo {
 	info := TunnelInfo{
@@ -170,6 +268,7 @@ func (t *Tunnel) Info() TunnelInfo {
 		PublicURL: t.PublicURL(),
 		LocalAddr: fmt.Sprintf("%s:%d", t.config.LocalHost, t.config.LocalPort),
 		Path:      t.config.Path,
+		Restarts:  t.Restarts(),
 	}
 
 	t.errMu.RLock()
@@ -178,6 +277,15 @@ func (t *Tunnel) Info() TunnelInfo {
 	}
 	t.errMu.RUnlock()
 
+	t.healthMu.RLock()
+	if !t.health.lastCheck.IsZero() {
+		last := t.health.lastCheck
+		info.LastHealthCheck = &last
+	}
+	info.HealthFailures = t.health.failures
+	info.HealthError = t.health.lastError
+	t.healthMu.RUnlock()
+
 	return info
 }
 
@@ -191,6 +299,15 @@ func (t *Tunnel) ID() string {
 	return t.config.ID
 }
 
+// RecentOutput returns recent output lines from the tunnel process for debugging.
+func (t *Tunnel) RecentOutput() []string {
+	t.outputMu.RLock()
+	defer t.outputMu.RUnlock()
+	result := make([]string, len(t.output))
+	copy(result, t.output)
+	return result
+}
+
 // WaitForURL waits for the public URL to be available or timeout.
 func (t *Tunnel) WaitForURL(ctx context.Context) (string, error) {
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -220,6 +337,7 @@ func (t *Tunnel) WaitForURL(ctx context.Context) (string, error) {
 }
 
 // Done returns a channel that's closed when the tunnel exits.
+// With AutoRestart this happens only after Stop or when restarts are exhausted.
 func (t *Tunnel) Done() <-chan struct{} {
 	return t.done
 }
@@ -239,141 +357,153 @@ func (t *Tunnel) setError(err error) {
 }
 
 func (t *Tunnel) setPublicURL(url string) {
+	if t.PublicURL() == url {
+		return
+	}
 	t.publicURL.Store(&url)
-	if t.onURL != nil {
-		t.onURL(url)
+
+	t.callbackMu.RLock()
+	onURL := t.onURL
+	t.callbackMu.RUnlock()
+	if onURL != nil {
+		onURL(url)
 	}
 }
 
-// cloudflared output patterns
-var (
-	// Matches: https://something-something.trycloudflare.com
-	cloudflareURLPattern = regexp.MustCompile(`https://[a-z0-9-]+\.trycloudflare\.com`)
-)
-
-func (t *Tunnel) startCloudflare(ctx context.Context) error {
-	binary := t.config.BinaryPath
-	if binary == "" {
-		binary = "cloudflared"
+// startProcess launches the tunnel command and begins parsing its output.
+func (t *Tunnel) startProcess(ctx context.Context) (*exec.Cmd, error) {
+	binary, args, err := BuildCommand(t.config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if binary exists
 	if _, err := exec.LookPath(binary); err != nil {
-		t.setState(StateFailed)
-		t.setError(fmt.Errorf("cloudflared not found in PATH: %w", err))
-		close(t.done)
-		return t.err
+		return nil, fmt.Errorf("%s not found in PATH: %w", binary, err)
 	}
 
-	localURL := fmt.Sprintf("http://%s:%d", t.config.LocalHost, t.config.LocalPort)
-	t.cmd = exec.CommandContext(ctx, binary, "tunnel", "--url", localURL)
+	cmd := exec.CommandContext(ctx, binary, args...)
 
-	// Capture stderr (cloudflared logs to stderr)
-	stderr, err := t.cmd.StderrPipe()
+	// Providers differ in which stream carries the URL (cloudflared logs to stderr)
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		t.setState(StateFailed)
-		t.setError(fmt.Errorf("failed to create stderr pipe: %w", err))
-		close(t.done)
-		return t.err
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
-	if err := t.cmd.Start(); err != nil {
-		t.setState(StateFailed)
-		t.setError(fmt.Errorf("failed to start cloudflared: %w", err))
-		close(t.done)
-		return t.err
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", binary, err)
 	}
 
-	// Parse output in goroutine
-	go t.parseCloudflareOutput(stderr)
+	t.cmdMu.Lock()
+	t.cmd = cmd
+	t.cmdMu.Unlock()
 
-	// Wait for process in goroutine
-	go func() {
-		defer close(t.done)
-		if err := t.cmd.Wait(); err != nil {
-			if ctx.Err() == nil { // Not cancelled
-				t.setError(fmt.Errorf("cloudflared exited: %w", err))
-				t.setState(StateFailed)
-			}
-		}
-	}()
+	go t.parseOutput(stdout)
+	go t.parseOutput(stderr)
 
-	return nil
+	return cmd, nil
 }
 
-func (t *Tunnel) parseCloudflareOutput(r io.Reader) {
+// parseOutput records output lines and extracts the public URL.
+func (t *Tunnel) parseOutput(r io.Reader) {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if match := cloudflareURLPattern.FindString(line); match != "" {
-			t.setPublicURL(match)
+
+		t.outputMu.Lock()
+		t.output = append(t.output, line)
+		if len(t.output) > maxOutputLines {
+			t.output = t.output[1:]
+		}
+		t.outputMu.Unlock()
+
+		if url := t.extractURL(line); url != "" && t.State() != StateConnected {
+			t.attempts.Store(0)
+			t.setPublicURL(url)
 			t.setState(StateConnected)
 		}
 	}
 }
 
-// ngrok output patterns
-var (
-	// Matches ngrok URLs like https://abc123.ngrok.io or https://abc123.ngrok-free.app
-	ngrokURLPattern = regexp.MustCompile(`https://[a-z0-9-]+\.ngrok(?:-free)?\.(?:io|app)`)
-)
-
-func (t *Tunnel) startNgrok(ctx context.Context) error {
-	binary := t.config.BinaryPath
-	if binary == "" {
-		binary = "ngrok"
+// extractURL extracts a public URL using the provider's pattern.
+// SSH tunnels to hosts other than the well-known services use generic patterns.
+func (t *Tunnel) extractURL(line string) string {
+	if t.config.Provider == ProviderSSH && !isKnownSSHHost(t.config.SSHHost) {
+		return extractGenericURL(line)
 	}
+	return ExtractURL(t.config.Provider, line)
+}
 
-	// Check if binary exists
-	if _, err := exec.LookPath(binary); err != nil {
-		t.setState(StateFailed)
-		t.setError(fmt.Errorf("ngrok not found in PATH: %w", err))
-		close(t.done)
-		return t.err
+// isKnownSSHHost returns true for SSH tunnel services with dedicated URL patterns.
+func isKnownSSHHost(host string) bool {
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
 	}
+	return host == "" || host == "localhost.run" || host == "serveo.net"
+}
 
-	t.cmd = exec.CommandContext(ctx, binary, "http", fmt.Sprintf("%d", t.config.LocalPort))
+// supervise waits for the tunnel process, runs health checks while it is up,
+// and restarts it when it dies if AutoRestart is enabled.
+func (t *Tunnel) supervise(ctx context.Context, cmd *exec.Cmd) {
+	defer close(t.done)
 
-	// ngrok outputs to stdout
-	stdout, err := t.cmd.StdoutPipe()
-	if err != nil {
-		t.setState(StateFailed)
-		t.setError(fmt.Errorf("failed to create stdout pipe: %w", err))
-		close(t.done)
-		return t.err
-	}
+	for {
+		probeCtx, stopProbe := context.WithCancel(ctx)
+		go t.monitorHealth(probeCtx, cmd)
+		waitErr := cmd.Wait()
+		stopProbe()
 
-	if err := t.cmd.Start(); err != nil {
-		t.setState(StateFailed)
-		t.setError(fmt.Errorf("failed to start ngrok: %w", err))
-		close(t.done)
-		return t.err
-	}
+		if ctx.Err() != nil { // Stopped
+			return
+		}
 
-	// Parse output in goroutine
-	go t.parseNgrokOutput(stdout)
+		exitErr := fmt.Errorf("%s exited", t.config.Provider)
+		if waitErr != nil {
+			exitErr = fmt.Errorf("%s exited: %w", t.config.Provider, waitErr)
+		}
+		t.healthMu.RLock()
+		if t.health.failures >= t.config.MaxFailures {
+			exitErr = fmt.Errorf("%s restarted after %d failed health checks: %s",
+				t.config.Provider, t.health.failures, t.health.lastError)
+		}
+		t.healthMu.RUnlock()
+		t.setError(exitErr)
 
-	// Wait for process in goroutine
-	go func() {
-		defer close(t.done)
-		if err := t.cmd.Wait(); err != nil {
-			if ctx.Err() == nil { // Not cancelled
-				t.setError(fmt.Errorf("ngrok exited: %w", err))
-				t.setState(StateFailed)
-			}
+		attempts := int(t.attempts.Load())
+		if !t.config.AutoRestart || attempts >= t.config.MaxRestarts {
+			t.setState(StateFailed)
+			return
 		}
-	}()
 
-	return nil
-}
+		// Back off before restarting; the old URL is no longer valid
+		t.setState(StateRestarting)
+		t.publicURL.Store(nil)
+		backoff := time.Duration(attempts+1) * time.Second
+		if backoff > maxRestartBackoff {
+			backoff = maxRestartBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
 
-func (t *Tunnel) parseNgrokOutput(r io.Reader) {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if match := ngrokURLPattern.FindString(line); match != "" {
-			t.setPublicURL(match)
-			t.setState(StateConnected)
+		t.attempts.Add(1)
+		t.restarts.Add(1)
+		t.healthMu.Lock()
+		t.health = healthStatus{}
+		t.healthMu.Unlock()
+
+		next, err := t.startProcess(ctx)
+		if err != nil {
+			t.setError(err)
+			t.setState(StateFailed)
+			return
 		}
+		cmd = next
 	}
 }
@@ -1,7 +1,15 @@
 package tunnel
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestCloudflareURLPattern(t *testing.T) {
@@ -80,6 +88,7 @@ func TestTunnelState(t *testing.T) {
 		{StateConnected, "connected"},
 		{StateFailed, "failed"},
 		{StateStopped, "stopped"},
+		{StateRestarting, "restarting"},
 		{State(99), "unknown"},
 	}
 
@@ -134,3 +143,273 @@ func TestTunnelInfo(t *testing.T) {
 		t.Errorf("expected 127.0.0.1:3000, got %s", info.LocalAddr)
 	}
 }
+
+func TestParseProvider(t *testing.T) {
+	tests := map[string]Provider{
+		"cloudflare":    ProviderCloudflare,
+		"CloudFlared":   ProviderCloudflare,
+		"NGROK":         ProviderNgrok,
+		"Tailscale":     ProviderTailscale,
+		"funnel":        ProviderTailscale,
+		"ssh":           ProviderSSH,
+		"localhost.run": ProviderSSH,
+		"serveo":        ProviderSSH,
+		"custom":        ProviderCustom,
+	}
+	for name, want := range tests {
+		if got, err := ParseProvider(name); err != nil || got != want {
+			t.Errorf("ParseProvider(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseProvider("unknown-provider"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestBuildCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		wantCmd  string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			name:     "ngrok",
+			config:   Config{Provider: ProviderNgrok, LocalPort: 8080},
+			wantCmd:  "ngrok",
+			wantArgs: []string{"http", "8080"},
+		},
+		{
+			name:     "ngrok with auth token and region",
+			config:   Config{Provider: ProviderNgrok, LocalPort: 3000, AuthToken: "secret123", Region: "eu"},
+			wantCmd:  "ngrok",
+			wantArgs: []string{"http", "3000", "--authtoken", "secret123", "--region", "eu"},
+		},
+		{
+			name:     "ngrok with extra args",
+			config:   Config{Provider: ProviderNgrok, LocalPort: 8080, Args: []string{"--hostname", "myapp.ngrok.io"}},
+			wantCmd:  "ngrok",
+			wantArgs: []string{"http", "8080", "--hostname", "myapp.ngrok.io"},
+		},
+		{
+			name:     "cloudflared alias",
+			config:   Config{Provider: "cloudflared", LocalPort: 9000},
+			wantCmd:  "cloudflared",
+			wantArgs: []string{"tunnel", "--url", "http://localhost:9000"},
+		},
+		{
+			name:     "cloudflare with binary path",
+			config:   Config{Provider: ProviderCloudflare, LocalPort: 9000, LocalHost: "127.0.0.1", BinaryPath: "/opt/cloudflared"},
+			wantCmd:  "/opt/cloudflared",
+			wantArgs: []string{"tunnel", "--url", "http://127.0.0.1:9000"},
+		},
+		{
+			name:     "tailscale",
+			config:   Config{Provider: ProviderTailscale, LocalPort: 4000},
+			wantCmd:  "tailscale",
+			wantArgs: []string{"funnel", "4000"},
+		},
+		{
+			name:    "ssh localhost.run",
+			config:  Config{Provider: "localhost.run", LocalPort: 5000},
+			wantCmd: "ssh",
+			wantArgs: []string{"-o", "StrictHostKeyChecking=accept-new", "-o", "ServerAliveInterval=30",
+				"-o", "ExitOnForwardFailure=yes", "-R", "80:localhost:5000", "nokey@localhost.run"},
+		},
+		{
+			name:    "ssh serveo with user",
+			config:  Config{Provider: ProviderSSH, LocalPort: 5000, SSHHost: "me@serveo.net"},
+			wantCmd: "ssh",
+			wantArgs: []string{"-o", "StrictHostKeyChecking=accept-new", "-o", "ServerAliveInterval=30",
+				"-o", "ExitOnForwardFailure=yes", "-R", "80:localhost:5000", "me@serveo.net"},
+		},
+		{
+			name:     "custom with port placeholder",
+			config:   Config{Provider: ProviderCustom, LocalPort: 7777, Command: "expose-{{PORT}}", Args: []string{"--port", "{{PORT}}"}},
+			wantCmd:  "expose-7777",
+			wantArgs: []string{"--port", "7777"},
+		},
+		{
+			name:    "custom without command",
+			config:  Config{Provider: ProviderCustom, LocalPort: 8080},
+			wantErr: true,
+		},
+		{
+			name:    "unknown provider",
+			config:  Config{Provider: "unknown-provider", LocalPort: 8080},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args, err := BuildCommand(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cmd != tt.wantCmd || strings.Join(args, " ") != strings.Join(tt.wantArgs, " ") {
+				t.Errorf("got %s %v, want %s %v", cmd, args, tt.wantCmd, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestExtractURL(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		line     string
+		expected string
+	}{
+		{ProviderCloudflare, "INF | https://random-name.trycloudflare.com |", "https://random-name.trycloudflare.com"},
+		{ProviderCloudflare, "INF Terms: https://www.cloudflare.com/website-terms/", ""},
+		{ProviderNgrok, "Forwarding https://abc123.ngrok.io -> http://localhost:8080", "https://abc123.ngrok.io"},
+		{ProviderNgrok, "Forwarding https://abcd-1234.ngrok-free.dev -> http://localhost:8080", "https://abcd-1234.ngrok-free.dev"},
+		{ProviderNgrok, "Forwarding https://preview.example.com -> http://localhost:8080", "https://preview.example.com"},
+		{ProviderNgrok, `t=2026-10-18 lvl=info msg="started tunnel" url=https://app.example.com`, "https://app.example.com"},
+		{ProviderNgrok, "Sign up at https://dashboard.ngrok.com/signup", ""},
+		{ProviderTailscale, "https://mybox.tail12345.ts.net", "https://mybox.tail12345.ts.net"},
+		{ProviderSSH, "a1b2c3d4e5.lhr.life tunneled with tls termination, https://a1b2c3d4e5.lhr.life", "https://a1b2c3d4e5.lhr.life"},
+		{ProviderSSH, "to learn more see https://localhost.run/docs/", ""},
+		{ProviderSSH, "Forwarding HTTP traffic from https://abc.serveo.net", "https://abc.serveo.net"},
+
+		// Custom commands use the generic patterns
+		{ProviderCustom, "Forwarding https://abc123.ngrok.io -> http://localhost:8080", "https://abc123.ngrok.io"},
+		{ProviderCustom, "url=https://def456.ngrok.io", "https://def456.ngrok.io"},
+		{ProviderCustom, "INF | https://random-name.trycloudflare.com |", "https://random-name.trycloudflare.com"},
+		{ProviderCustom, "Serving https://mybox.tailnet.ts.net:443", "https://mybox.tailnet.ts.net:443"},
+		{ProviderCustom, "your url is: https://warm-phones-lie.loca.lt", "https://warm-phones-lie.loca.lt"},
+		{ProviderCustom, "Tunnel ready at https://example.tunnel.dev", "https://example.tunnel.dev"},
+		{ProviderCustom, "Starting tunnel...", ""},
+		{ProviderCustom, "local server at http://localhost:8080", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.provider)+"/"+tt.line, func(t *testing.T) {
+			if got := ExtractURL(tt.provider, tt.line); got != tt.expected {
+				t.Errorf("ExtractURL(%s, %q) = %q, want %q", tt.provider, tt.line, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProbeURL(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ngrok" {
+			w.Header().Set("Ngrok-Error-Code", "ERR_NGROK_3200")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	for _, code := range []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError} {
+		status = code
+		if err := ProbeURL(ctx, srv.URL); err != nil {
+			t.Errorf("status %d should be healthy: %v", code, err)
+		}
+	}
+	for _, code := range []int{http.StatusBadGateway, 530} {
+		status = code
+		if err := ProbeURL(ctx, srv.URL); err == nil {
+			t.Errorf("status %d should be unhealthy", code)
+		}
+	}
+	if err := ProbeURL(ctx, srv.URL+"/ngrok"); err == nil {
+		t.Error("ngrok error page should be unhealthy")
+	}
+}
+
+// urlScript prints a fresh public URL on each run and keeps running.
+const urlScript = `echo "tunnel ready at https://t-$$.example.dev"; sleep 30`
+
+func TestTunnel_RestartsAfterFailedHealthChecks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var mu sync.Mutex
+	var urls []string
+	tun := New(Config{
+		Provider:       ProviderCustom,
+		Command:        "sh",
+		Args:           []string{"-c", urlScript},
+		LocalPort:      8080,
+		AutoRestart:    true,
+		HealthInterval: 20 * time.Millisecond,
+		MaxFailures:    2,
+		HealthCheck: func(ctx context.Context, publicURL string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(urls) == 1 {
+				return errors.New("tunnel down")
+			}
+			return nil
+		},
+	})
+	tun.OnURL(func(url string) {
+		mu.Lock()
+		urls = append(urls, url)
+		mu.Unlock()
+	})
+
+	if err := tun.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer tun.Stop(context.Background())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(urls)
+		mu.Unlock()
+		if n >= 2 && tun.State() == StateConnected {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(urls) < 2 || urls[0] == urls[1] {
+		t.Fatalf("expected a new URL after restart, got %v (output %v)", urls, tun.RecentOutput())
+	}
+	if tun.Restarts() != 1 || tun.PublicURL() != urls[1] {
+		t.Errorf("Restarts = %d, PublicURL = %q", tun.Restarts(), tun.PublicURL())
+	}
+	if info := tun.Info(); !strings.Contains(info.Error, "failed health checks") {
+		t.Errorf("Info.Error = %q", info.Error)
+	}
+}
+
+func TestTunnel_ExitWithoutRestart(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	tun := New(Config{Provider: ProviderCustom, Command: "sh", Args: []string{"-c", "exit 3"}, LocalPort: 8080})
+	if err := tun.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	select {
+	case <-tun.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("tunnel did not exit")
+	}
+	if tun.State() != StateFailed {
+		t.Errorf("State = %s, want failed", tun.State())
+	}
+	if _, err := tun.WaitForURL(context.Background()); err == nil {
+		t.Error("WaitForURL should fail when the tunnel exits without a URL")
+	}
+}
@@ -0,0 +1,377 @@
+// Package agntclient is a Go client for the agnt daemon. It talks to the
+// daemon over its socket, like the MCP server does, and returns the typed
+// responses of pkg/api.
+//
+//	c := agntclient.New()
+//	defer c.Close()
+//	p, err := c.ProxyStart(ctx, "app", "http://localhost:3000", 8080, api.ProxyStartOptions{})
+//
+// Every call takes a context. A call whose context is done returns ctx.Err()
+// and drops the connection, since the daemon's response would otherwise be
+// read by the next call; the next call reconnects.
+package agntclient
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/standardbeagle/agnt/pkg/api"
+	goclient "github.com/standardbeagle/go-cli-server/client"
+	hubprotocol "github.com/standardbeagle/go-cli-server/protocol"
+	"github.com/standardbeagle/go-cli-server/socket"
+)
+
+// Errors returned by the client.
+var (
+	// ErrNotConnected is returned when the daemon can't be reached.
+	ErrNotConnected = goclient.ErrNotConnected
+	// ErrServerError wraps errors reported by the daemon.
+	ErrServerError = goclient.ErrServerError
+)
+
+// Client is a client of the agnt daemon. It is safe for concurrent use;
+// calls are sent one at a time.
+type Client struct {
+	mu   sync.Mutex
+	conn *goclient.Conn
+}
+
+type options struct {
+	socketPath string
+	timeout    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+// WithSocketPath sets the socket of the daemon. It defaults to the socket
+// the agnt daemon listens on.
+func WithSocketPath(path string) Option {
+	return func(o *options) {
+		o.socketPath = path
+	}
+}
+
+// WithTimeout sets how long a call may take when its context has no
+// deadline. It defaults to 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// DefaultSocketPath returns the socket the agnt daemon listens on.
+func DefaultSocketPath() string {
+	return socket.DefaultSocketPath(api.SocketName)
+}
+
+// New creates a client. It connects on the first call.
+func New(opts ...Option) *Client {
+	o := &options{
+		socketPath: DefaultSocketPath(),
+		timeout:    30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Client{
+		conn: goclient.NewConn(
+			goclient.WithSocketPath(o.socketPath),
+			goclient.WithTimeout(o.timeout),
+		),
+	}
+}
+
+// Dial creates a client and connects to the daemon.
+func Dial(ctx context.Context, opts ...Option) (*Client, error) {
+	c := New(opts...)
+	if err := c.Ping(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SocketPath returns the socket of the daemon.
+func (c *Client) SocketPath() string {
+	return c.conn.SocketPath()
+}
+
+// do sends a request with the connection, giving up when ctx is done.
+func (c *Client) do(ctx context.Context, req func(conn *goclient.Conn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		if err := c.conn.EnsureConnected(); err != nil {
+			done <- err
+			return
+		}
+		done <- req(c.conn)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// The response would be read by the next request
+		c.conn.Close()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Ping checks that the daemon responds.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Ping()
+	})
+}
+
+// Info returns the status of the daemon.
+func (c *Client) Info(ctx context.Context) (*api.DaemonInfo, error) {
+	var info api.DaemonInfo
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbStatus).JSONInto(&info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Detect detects the project at path, or at the daemon's directory if path
+// is empty.
+func (c *Client) Detect(ctx context.Context, path string) (*api.DetectResult, error) {
+	var args []string
+	if path != "" {
+		args = append(args, path)
+	}
+	var result api.DetectResult
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbDetect, args...).JSONInto(&result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Run runs a script or command of a project.
+func (c *Client) Run(ctx context.Context, config api.RunConfig) (*api.RunResult, error) {
+	req := hubprotocol.RunConfig{
+		ID:         config.ID,
+		Path:       config.Path,
+		ScriptName: config.ScriptName,
+		Raw:        config.Raw,
+		Command:    config.Command,
+		Args:       config.Args,
+		Mode:       config.Mode,
+		Env:        config.Env,
+	}
+	var result api.RunResult
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(hubprotocol.VerbRunJSON).WithJSON(req).JSONInto(&result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ProcStatus returns the status of a process.
+func (c *Client) ProcStatus(ctx context.Context, processID string) (*api.Process, error) {
+	var proc api.Process
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(hubprotocol.VerbProc, hubprotocol.SubVerbStatus, processID).JSONInto(&proc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proc, nil
+}
+
+// ProcOutput returns the output of a process.
+func (c *Client) ProcOutput(ctx context.Context, processID string, filter api.OutputFilter) (string, error) {
+	args := []string{hubprotocol.SubVerbOutput, processID}
+	if filter.Stream != "" && filter.Stream != "combined" {
+		args = append(args, "stream="+filter.Stream)
+	}
+	if filter.Tail > 0 {
+		args = append(args, "tail="+strconv.Itoa(filter.Tail))
+	}
+	if filter.Head > 0 {
+		args = append(args, "head="+strconv.Itoa(filter.Head))
+	}
+	if filter.Grep != "" {
+		args = append(args, "grep="+filter.Grep)
+	}
+	if filter.GrepV {
+		args = append(args, "grep_v")
+	}
+
+	var output string
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		var err error
+		output, err = conn.Request(hubprotocol.VerbProc, args...).String()
+		return err
+	})
+	return output, err
+}
+
+// ProcStop stops a process, killing it if force is set.
+func (c *Client) ProcStop(ctx context.Context, processID string, force bool) error {
+	args := []string{hubprotocol.SubVerbStop, processID}
+	if force {
+		args = append(args, "force")
+	}
+	return c.do(ctx, func(conn *goclient.Conn) error {
+		_, err := conn.Request(hubprotocol.VerbProc, args...).JSON()
+		return err
+	})
+}
+
+// ProcList lists the processes of the project or session in filter.
+func (c *Client) ProcList(ctx context.Context, filter api.DirectoryFilter) (*api.ProcessList, error) {
+	var list api.ProcessList
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		req := conn.Request(hubprotocol.VerbProc, hubprotocol.SubVerbList)
+		if filter.Directory != "" || filter.Global {
+			req = req.WithJSON(filter)
+		}
+		return req.JSONInto(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ProxyStart starts a reverse proxy to targetURL listening on port.
+func (c *Client) ProxyStart(ctx context.Context, id, targetURL string, port int, opts api.ProxyStartOptions) (*api.Proxy, error) {
+	args := []string{hubprotocol.SubVerbStart, id, targetURL, strconv.Itoa(port)}
+	var p api.Proxy
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbProxy, args...).WithJSON(opts).JSONInto(&p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ProxyStop stops a reverse proxy.
+func (c *Client) ProxyStop(ctx context.Context, id string) error {
+	return c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbProxy, hubprotocol.SubVerbStop, id).OK()
+	})
+}
+
+// ProxyStatus returns the status and statistics of a reverse proxy.
+func (c *Client) ProxyStatus(ctx context.Context, id string) (*api.Proxy, error) {
+	var p api.Proxy
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbProxy, hubprotocol.SubVerbStatus, id).JSONInto(&p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ProxyList lists the reverse proxies of the project or session in filter.
+func (c *Client) ProxyList(ctx context.Context, filter api.DirectoryFilter) (*api.ProxyList, error) {
+	var list api.ProxyList
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		req := conn.Request(api.VerbProxy, hubprotocol.SubVerbList)
+		if filter.Directory != "" || filter.Global {
+			req = req.WithJSON(filter)
+		}
+		return req.JSONInto(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ProxyExec runs JavaScript in the browsers connected to a proxy.
+func (c *Client) ProxyExec(ctx context.Context, id, code string) (*api.ExecResult, error) {
+	var result api.ExecResult
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbProxy, api.SubVerbExec, id).WithData([]byte(code)).JSONInto(&result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ProxyLogQuery returns the log entries of a proxy matching filter.
+func (c *Client) ProxyLogQuery(ctx context.Context, id string, filter api.LogQueryFilter) (*api.LogQueryResult, error) {
+	var result api.LogQueryResult
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbProxyLog, api.SubVerbQuery, id).WithJSON(filter).JSONInto(&result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ProxyLogClear clears the log of a proxy.
+func (c *Client) ProxyLogClear(ctx context.Context, id string) error {
+	return c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbProxyLog, hubprotocol.SubVerbClear, id).OK()
+	})
+}
+
+// CurrentPageList lists the browser tabs seen through a proxy.
+func (c *Client) CurrentPageList(ctx context.Context, proxyID string) (*api.PageSessionList, error) {
+	var list api.PageSessionList
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbCurrentPage, hubprotocol.SubVerbList, proxyID).JSONInto(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CurrentPageGet returns a browser tab seen through a proxy.
+func (c *Client) CurrentPageGet(ctx context.Context, proxyID, sessionID string) (*api.PageSession, error) {
+	var session api.PageSession
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbCurrentPage, hubprotocol.SubVerbGet, proxyID, sessionID).JSONInto(&session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// AuditQuery returns the actions MCP clients performed in a project, from
+// its audit log.
+func (c *Client) AuditQuery(ctx context.Context, q api.AuditQuery) (*api.AuditResult, error) {
+	var result api.AuditResult
+	err := c.do(ctx, func(conn *goclient.Conn) error {
+		return conn.Request(api.VerbAudit, api.SubVerbQuery).WithJSON(q).JSONInto(&result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,227 @@
+//go:build unix
+
+package agntclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/standardbeagle/agnt/internal/daemon"
+	"github.com/standardbeagle/agnt/pkg/api"
+)
+
+// startDaemon runs a daemon on a socket in a temp directory and returns a
+// client of it.
+func startDaemon(t *testing.T) *Client {
+	t.Helper()
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "test.sock")
+
+	d := daemon.New(daemon.DaemonConfig{
+		SocketPath:   sockPath,
+		MaxClients:   10,
+		WriteTimeout: 5 * time.Second,
+		AuditDir:     filepath.Join(tmpDir, "audit"),
+	})
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		d.Stop(ctx)
+	})
+
+	c := New(WithSocketPath(sockPath), WithTimeout(10*time.Second))
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// A call whose context ends drops the connection, so its response is not
+// read by the next call, which reconnects.
+func TestClient_CancelledCallReconnects(t *testing.T) {
+	c := startDaemon(t)
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Run(short, api.RunConfig{
+		ID:      "slow",
+		Path:    t.TempDir(),
+		Raw:     true,
+		Command: "sleep",
+		Args:    []string{"2"},
+		Mode:    "foreground",
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled call took %v", elapsed)
+	}
+
+	// The RUN response must not be read as the response of INFO
+	info, err := c.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info after cancel: %v", err)
+	}
+	if info.Version != daemon.Version {
+		t.Errorf("version = %q, want %q", info.Version, daemon.Version)
+	}
+
+	done, cancelDone := context.WithCancel(ctx)
+	cancelDone()
+	if err := c.Ping(done); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled context to fail before sending, got %v", err)
+	}
+}
+
+// The typed methods decode the daemon's responses.
+func TestClient_TypedResponses(t *testing.T) {
+	c := startDaemon(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	detected, err := c.Detect(ctx, dir)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if detected.Type != "go" || len(detected.Scripts) == 0 || len(detected.Commands) == 0 {
+		t.Errorf("unexpected detect result %+v", detected)
+	}
+
+	run, err := c.Run(ctx, api.RunConfig{
+		ID:      "echo",
+		Path:    dir,
+		Raw:     true,
+		Command: "echo",
+		Args:    []string{"hello"},
+		Mode:    "foreground-raw",
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if run.ExitCode != 0 || !strings.Contains(run.Stdout, "hello") {
+		t.Errorf("unexpected run result %+v", run)
+	}
+
+	bg, err := c.Run(ctx, api.RunConfig{
+		ID:      "sleeper",
+		Path:    dir,
+		Raw:     true,
+		Command: "sleep",
+		Args:    []string{"30"},
+		Mode:    "background",
+	})
+	if err != nil {
+		t.Fatalf("Run in background: %v", err)
+	}
+	defer c.ProcStop(ctx, bg.ProcessID, true)
+	if bg.ProcessID == "" || bg.PID == 0 {
+		t.Errorf("unexpected background run result %+v", bg)
+	}
+
+	proc, err := c.ProcStatus(ctx, bg.ProcessID)
+	if err != nil {
+		t.Fatalf("ProcStatus: %v", err)
+	}
+	if proc.ID != bg.ProcessID || proc.Command != "sleep" || proc.ProjectPath == "" {
+		t.Errorf("unexpected process %+v", proc)
+	}
+	procs, err := c.ProcList(ctx, api.DirectoryFilter{Global: true})
+	if err != nil {
+		t.Fatalf("ProcList: %v", err)
+	}
+	if procs.Count != len(procs.Processes) || procs.Count == 0 {
+		t.Errorf("unexpected process list %+v", procs)
+	}
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer target.Close()
+
+	p, err := c.ProxyStart(ctx, "app", target.URL, 0, api.ProxyStartOptions{Path: dir})
+	if err != nil {
+		t.Fatalf("ProxyStart: %v", err)
+	}
+	defer c.ProxyStop(ctx, "app")
+	if p.ID != "app" || p.ListenAddr == "" || p.TargetURL != target.URL {
+		t.Errorf("unexpected proxy %+v", p)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api", p.ListenAddr))
+	if err != nil {
+		t.Fatalf("request through proxy: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	status, err := c.ProxyStatus(ctx, "app")
+	if err != nil {
+		t.Fatalf("ProxyStatus: %v", err)
+	}
+	if status.Stats == nil || !status.Stats.Running {
+		t.Errorf("unexpected proxy status %+v", status)
+	}
+	proxies, err := c.ProxyList(ctx, api.DirectoryFilter{Global: true})
+	if err != nil {
+		t.Fatalf("ProxyList: %v", err)
+	}
+	if proxies.Count != 1 || proxies.Proxies[0].ID != "app" {
+		t.Errorf("unexpected proxy list %+v", proxies)
+	}
+
+	var logs *api.LogQueryResult
+	for i := 0; i < 20; i++ {
+		logs, err = c.ProxyLogQuery(ctx, "app", api.LogQueryFilter{Types: []string{"http"}})
+		if err != nil {
+			t.Fatalf("ProxyLogQuery: %v", err)
+		}
+		if logs.Count > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if logs.Count != 1 || len(logs.Logs) != 1 {
+		t.Fatalf("unexpected log query result %+v", logs)
+	}
+	if h := logs.Logs[0].HTTP; h == nil || h.Method != "GET" || h.StatusCode != 200 || !strings.HasSuffix(h.URL, "/api") {
+		t.Errorf("unexpected HTTP entry %+v", logs.Logs[0].HTTP)
+	}
+
+	groups, err := c.ProxyLogQuery(ctx, "app", api.LogQueryFilter{GroupBy: []string{"method"}})
+	if err != nil {
+		t.Fatalf("ProxyLogQuery group_by: %v", err)
+	}
+	if len(groups.Groups) != 1 || groups.Groups[0].Count != 1 || groups.Groups[0].Key["method"] != "GET" {
+		t.Errorf("unexpected log groups %+v", groups.Groups)
+	}
+
+	pages, err := c.CurrentPageList(ctx, "app")
+	if err != nil {
+		t.Fatalf("CurrentPageList: %v", err)
+	}
+	if pages.Count != len(pages.Sessions) {
+		t.Errorf("unexpected page session list %+v", pages)
+	}
+}
@@ -0,0 +1,179 @@
+package agntclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/standardbeagle/agnt/pkg/api"
+	hubprotocol "github.com/standardbeagle/go-cli-server/protocol"
+	"github.com/standardbeagle/go-cli-server/socket"
+)
+
+// ErrStreamEnded is the error of a subscription the daemon ended, which it
+// does when it stops.
+var ErrStreamEnded = errors.New("event stream ended by the daemon")
+
+// Subscription is a stream of daemon events, opened with Client.Subscribe.
+type Subscription struct {
+	events chan api.Event
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	conn net.Conn
+	last uint64
+	err  error
+}
+
+// Subscribe streams the daemon events matching req. The stream uses its own
+// connection and runs until ctx is done or Close is called. A subscription
+// the daemon drops for falling behind is resumed from the last event
+// received; events the daemon no longer keeps arrive as a gap event.
+// Heartbeats are not delivered.
+func (c *Client) Subscribe(ctx context.Context, req api.SubscribeRequest) (*Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Subscription{
+		events: make(chan api.Event, 64),
+		cancel: cancel,
+		last:   req.Since,
+	}
+
+	conn, err := s.open(c.SocketPath(), req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.closeConn()
+	}()
+	go s.run(ctx, c.SocketPath(), req, conn)
+	return s, nil
+}
+
+// Events returns the events of the subscription. It is closed when the
+// subscription ends; Err then tells why.
+func (s *Subscription) Events() <-chan api.Event {
+	return s.events
+}
+
+// Err returns why the subscription ended, or nil if it was closed.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// LastSeq returns the sequence number of the last event received, to
+// subscribe again from with SubscribeRequest.Since.
+func (s *Subscription) LastSeq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() error {
+	s.cancel()
+	return nil
+}
+
+// open connects to the daemon and sends SUBSCRIBE.
+func (s *Subscription) open(socketPath string, req api.SubscribeRequest) (net.Conn, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := socket.Connect(socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotConnected, err)
+	}
+	if _, err := conn.Write(hubprotocol.FormatCommand(&hubprotocol.Command{Verb: api.VerbSubscribe, Data: data})); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	return conn, nil
+}
+
+func (s *Subscription) closeConn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// run reads the stream, resuming it when the daemon drops the subscriber
+// for falling behind.
+func (s *Subscription) run(ctx context.Context, socketPath string, req api.SubscribeRequest, conn net.Conn) {
+	defer close(s.events)
+
+	for {
+		lagged, err := s.read(ctx, conn)
+		if ctx.Err() != nil {
+			return
+		}
+		if lagged {
+			req.Since = s.LastSeq()
+			if conn, err = s.open(socketPath, req); err == nil {
+				continue
+			}
+		}
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		return
+	}
+}
+
+// read delivers the events of a connection until it ends. lagged reports
+// that the daemon dropped the subscriber for falling behind.
+func (s *Subscription) read(ctx context.Context, conn net.Conn) (lagged bool, err error) {
+	defer conn.Close()
+
+	parser := hubprotocol.NewParser(conn)
+	for {
+		resp, err := parser.ParseResponse()
+		if err != nil {
+			return false, err
+		}
+
+		switch resp.Type {
+		case hubprotocol.ResponseChunk:
+			var ev api.Event
+			if err := json.Unmarshal(resp.Data, &ev); err != nil {
+				return false, fmt.Errorf("invalid event: %w", err)
+			}
+			if ev.Type == api.EventHeartbeat {
+				continue
+			}
+			if ev.Seq > 0 {
+				s.mu.Lock()
+				s.last = ev.Seq
+				s.mu.Unlock()
+			}
+			select {
+			case s.events <- ev:
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		case hubprotocol.ResponseEnd:
+			return false, ErrStreamEnded
+		case hubprotocol.ResponseErr:
+			if resp.Code == string(hubprotocol.ErrInvalidState) {
+				return true, nil
+			}
+			return false, fmt.Errorf("%w: %s: %s", ErrServerError, resp.Code, resp.Message)
+		default:
+			return false, fmt.Errorf("unexpected %v response to SUBSCRIBE", resp.Type)
+		}
+	}
+}
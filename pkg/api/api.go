@@ -0,0 +1,416 @@
+// Package api defines the requests and responses of the agnt daemon
+// protocol. The daemon builds its responses from these types and
+// pkg/agntclient decodes them, so both sides agree on the JSON.
+//
+// The types are independent of the daemon's own: the daemon converts its
+// types to the ones defined here, and the client converts requests to the
+// daemon's.
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Command is a runnable project command, with where it is defined.
+type Command struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args,omitempty"`
+	// Timeout is in seconds (0 = no timeout)
+	Timeout int `json:"timeout,omitempty"`
+	// Persistent is set for long-running processes such as dev servers
+	Persistent bool              `json:"persistent,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	// Source is built-in, global (config.kdl) or project (.agnt.kdl)
+	Source string `json:"source,omitempty"`
+}
+
+// ConfigIssue is a problem found in an .agnt.kdl file.
+type ConfigIssue struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Severity is error or warning
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// RunConfig is the request of RUN: a script of the project at Path, or a
+// raw command.
+type RunConfig struct {
+	ID         string   `json:"id,omitempty"`
+	Path       string   `json:"path"`
+	ScriptName string   `json:"script_name,omitempty"`
+	Raw        bool     `json:"raw,omitempty"`
+	Command    string   `json:"command,omitempty"`
+	Args       []string `json:"args,omitempty"`
+	// Mode is background (default), foreground or foreground-raw
+	Mode string `json:"mode,omitempty"`
+	// Env is the environment of the process, as KEY=value
+	Env []string `json:"env,omitempty"`
+}
+
+// OutputFilter selects the lines of PROC OUTPUT.
+type OutputFilter struct {
+	// Stream is stdout, stderr or combined (default)
+	Stream string `json:"stream,omitempty"`
+	Tail   int    `json:"tail,omitempty"`
+	Head   int    `json:"head,omitempty"`
+	Grep   string `json:"grep,omitempty"`
+	// GrepV inverts Grep
+	GrepV bool `json:"grep_v,omitempty"`
+}
+
+// BodyCaptureConfig sets how much of each proxied body is kept.
+type BodyCaptureConfig struct {
+	// InlineLimit is the size of the preview stored in the log entry (default 10KB)
+	InlineLimit int `json:"inline_limit,omitempty"`
+	// MaxSize is the most bytes captured per body (default 10MB); negative disables capture
+	MaxSize int64 `json:"max_size,omitempty"`
+	// StoreLimit caps the bytes of full bodies kept on disk per proxy (default 256MB)
+	StoreLimit int64 `json:"store_limit,omitempty"`
+	// Dir is where full bodies are written (default: a temp directory per proxy)
+	Dir string `json:"dir,omitempty"`
+}
+
+// TunnelConfig configures a tunnel started alongside a proxy.
+type TunnelConfig struct {
+	// Provider is ngrok, cloudflared, tailscale, ssh, localhost.run, serveo
+	// or custom
+	Provider string `json:"provider"`
+	// Command is the command run by the custom provider
+	Command   string   `json:"command,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	AuthToken string   `json:"auth_token,omitempty"`
+	Region    string   `json:"region,omitempty"`
+	// SSHHost is the host of the ssh provider (default: localhost.run)
+	SSHHost string `json:"ssh_host,omitempty"`
+}
+
+// LogQueryFilter selects proxy log entries. Durations are Go durations such
+// as "500ms"; Since is an RFC 3339 time or a duration before now, Until an
+// RFC 3339 time.
+type LogQueryFilter struct {
+	Types       []string `json:"types,omitempty"`
+	Methods     []string `json:"methods,omitempty"`
+	URLPattern  string   `json:"url_pattern,omitempty"`
+	StatusCodes []int    `json:"status_codes,omitempty"`
+	Since       string   `json:"since,omitempty"`
+	Until       string   `json:"until,omitempty"`
+	Limit       int      `json:"limit,omitempty"`
+
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`   // Header name → substring ("" = present)
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`  // Header name → substring ("" = present)
+	MinDuration     string            `json:"min_duration,omitempty"`      // Slower than
+	MaxDuration     string            `json:"max_duration,omitempty"`      // Faster than
+	BodyPath        string            `json:"body_path,omitempty"`         // JSONPath predicate on the response body
+	RequestBodyPath string            `json:"request_body_path,omitempty"` // JSONPath predicate on the request body
+	ErrorPattern    string            `json:"error_pattern,omitempty"`     // Regex on error messages
+	Operation       string            `json:"operation,omitempty"`         // GraphQL operation name substring
+	GroupBy         []string          `json:"group_by,omitempty"`          // Aggregate HTTP entries instead of listing them
+}
+
+// LogEntry is a proxy log entry. Type says which field is set: HTTP for
+// proxied traffic, the others for what the browser reported. Entries other
+// than HTTP are kept as the JSON the daemon logs them in.
+type LogEntry struct {
+	Type string        `json:"type"`
+	HTTP *HTTPLogEntry `json:"http,omitempty"`
+
+	Error             json.RawMessage `json:"error,omitempty"`
+	Performance       json.RawMessage `json:"performance,omitempty"`
+	Custom            json.RawMessage `json:"custom,omitempty"`
+	Screenshot        json.RawMessage `json:"screenshot,omitempty"`
+	Execution         json.RawMessage `json:"execution,omitempty"`
+	Response          json.RawMessage `json:"response,omitempty"`
+	Interaction       json.RawMessage `json:"interaction,omitempty"`
+	Mutation          json.RawMessage `json:"mutation,omitempty"`
+	PanelMessage      json.RawMessage `json:"panel_message,omitempty"`
+	Sketch            json.RawMessage `json:"sketch,omitempty"`
+	ScreenshotCapture json.RawMessage `json:"screenshot_capture,omitempty"`
+	ElementCapture    json.RawMessage `json:"element_capture,omitempty"`
+	SketchCapture     json.RawMessage `json:"sketch_capture,omitempty"`
+	DesignState       json.RawMessage `json:"design_state,omitempty"`
+	DesignRequest     json.RawMessage `json:"design_request,omitempty"`
+	DesignChat        json.RawMessage `json:"design_chat,omitempty"`
+	StreamEvent       json.RawMessage `json:"stream_event,omitempty"`
+	Console           json.RawMessage `json:"console,omitempty"`
+	Rejection         json.RawMessage `json:"rejection,omitempty"`
+	WebVital          json.RawMessage `json:"web_vital,omitempty"`
+}
+
+// HTTPLogEntry is a request made through a proxy and its response.
+type HTTPLogEntry struct {
+	ID              string            `json:"id"`
+	Timestamp       time.Time         `json:"timestamp"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body,omitempty"` // Preview; see RequestBodyInfo for the full body
+	StatusCode      int               `json:"status_code"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    string            `json:"response_body,omitempty"` // Preview; see ResponseBodyInfo for the full body
+	Duration        time.Duration     `json:"duration"`
+	Error           string            `json:"error,omitempty"`
+	Upstream        string            `json:"upstream,omitempty"` // Route that served the request (empty for the proxy target)
+
+	RequestBodyInfo  *BodyInfo `json:"request_body_info,omitempty"`
+	ResponseBodyInfo *BodyInfo `json:"response_body_info,omitempty"`
+
+	// GraphQL operations, streaming and upstream timing, as the daemon logs them
+	GraphQL json.RawMessage `json:"graphql,omitempty"`
+	Stream  json.RawMessage `json:"stream,omitempty"`
+	Timing  json.RawMessage `json:"timing,omitempty"`
+}
+
+// BodyInfo describes a captured request or response body.
+type BodyInfo struct {
+	Size        int64  `json:"size"` // Bytes on the wire, before decoding
+	Kind        string `json:"kind"` // json, form, multipart, text or binary
+	ContentType string `json:"content_type,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`  // Content-Encoding of the captured bytes
+	File        string `json:"file,omitempty"`      // Full body on disk, as sent (still encoded)
+	Truncated   bool   `json:"truncated,omitempty"` // Body exceeded the capture limit
+}
+
+// LogGroup is a group of HTTP log entries aggregated by a query.
+type LogGroup struct {
+	Key      map[string]string `json:"key"`
+	Count    int               `json:"count"`
+	Errors   int               `json:"errors,omitempty"` // 5xx responses, failed requests and GraphQL errors
+	AvgMs    float64           `json:"avg_ms"`
+	P50Ms    float64           `json:"p50_ms"`
+	P95Ms    float64           `json:"p95_ms"`
+	MaxMs    float64           `json:"max_ms"`
+	Statuses map[string]int    `json:"statuses,omitempty"` // Status code → count
+
+	// Upstream time (connection to last byte) and injected chaos delay, for
+	// requests that reached the upstream
+	UpstreamP50Ms float64 `json:"upstream_p50_ms,omitempty"`
+	UpstreamP95Ms float64 `json:"upstream_p95_ms,omitempty"`
+	ChaosAvgMs    float64 `json:"chaos_avg_ms,omitempty"`
+}
+
+// DirectoryFilter scopes list requests to a project or session.
+type DirectoryFilter struct {
+	Directory   string `json:"directory,omitempty"`
+	SessionCode string `json:"session_code,omitempty"`
+	// Global lists the entries of every project
+	Global bool `json:"global,omitempty"`
+}
+
+// DetectResult is the response of DETECT.
+type DetectResult struct {
+	Type           string    `json:"type"`
+	Path           string    `json:"path"`
+	PackageManager string    `json:"package_manager"`
+	Scripts        []string  `json:"scripts"`
+	Commands       []Command `json:"commands"`
+	// Config is the check of the project's .agnt.kdl, if it has one
+	Config *ConfigCheck `json:"config,omitempty"`
+}
+
+// ConfigCheck lists the problems found in an .agnt.kdl file.
+type ConfigCheck struct {
+	Path   string        `json:"path"`
+	Issues []ConfigIssue `json:"issues"`
+}
+
+// Process is the response of PROC STATUS and an entry of PROC LIST.
+type Process struct {
+	ID          string   `json:"id"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args,omitempty"`
+	State       string   `json:"state"`
+	Summary     string   `json:"summary"`
+	Runtime     string   `json:"runtime"`
+	RuntimeMs   int64    `json:"runtime_ms"`
+	ProjectPath string   `json:"project_path"`
+	PID         int      `json:"pid,omitempty"`
+	// ExitCode is set once the process has stopped or failed
+	ExitCode *int `json:"exit_code,omitempty"`
+	// URLs are the URLs detected in the process output
+	URLs []string `json:"urls,omitempty"`
+}
+
+// ProcessList is the response of PROC LIST.
+type ProcessList struct {
+	Count         int       `json:"count"`
+	Processes     []Process `json:"processes"`
+	Global        bool      `json:"global"`
+	TotalInDaemon int       `json:"total_in_daemon"`
+	ProjectPath   string    `json:"project_path,omitempty"`
+	SessionCode   string    `json:"session_code,omitempty"`
+}
+
+// ProxyRoute sends requests matching a path prefix or pattern to a different
+// upstream than the proxy target.
+type ProxyRoute struct {
+	Name string `json:"name,omitempty"`
+	// Path is a path prefix, matched on segment boundaries
+	Path string `json:"path,omitempty"`
+	// Pattern is a regular expression matched against the path instead of Path
+	Pattern     string `json:"pattern,omitempty"`
+	Target      string `json:"target"`
+	StripPrefix bool   `json:"strip_prefix,omitempty"`
+	HostHeader  string `json:"host_header,omitempty"`
+}
+
+// ProxyStartOptions is the JSON data of PROXY START, besides the id, target
+// URL, port and log size arguments.
+type ProxyStartOptions struct {
+	Path        string        `json:"path,omitempty"`
+	BindAddress string        `json:"bind_address,omitempty"`
+	PublicURL   string        `json:"public_url,omitempty"`
+	VerifyTLS   bool          `json:"verify_tls,omitempty"`
+	Tunnel      *TunnelConfig `json:"tunnel,omitempty"`
+	Routes      []ProxyRoute  `json:"routes,omitempty"`
+	HTTPS       bool          `json:"https,omitempty"`
+	HTTPSPort   int           `json:"https_port,omitempty"`
+
+	BodyCapture *BodyCaptureConfig `json:"body_capture,omitempty"`
+}
+
+// Proxy is the response of PROXY START and STATUS and an entry of PROXY
+// LIST.
+type Proxy struct {
+	ID          string       `json:"id"`
+	ListenAddr  string       `json:"listen_addr"`
+	TargetURL   string       `json:"target_url"`
+	Status      string       `json:"status"`
+	Running     bool         `json:"running,omitempty"`
+	Path        string       `json:"path,omitempty"`
+	BindAddress string       `json:"bind_address,omitempty"`
+	HTTPSURL    string       `json:"https_url,omitempty"`
+	CACertPath  string       `json:"ca_cert_path,omitempty"`
+	Routes      []ProxyRoute `json:"routes,omitempty"`
+	// Stats are only in the response of PROXY STATUS
+	Stats *ProxyStats `json:"stats,omitempty"`
+}
+
+// ProxyStats are the statistics of a running proxy.
+type ProxyStats struct {
+	ID            string        `json:"id"`
+	TargetURL     string        `json:"target_url"`
+	ListenAddr    string        `json:"listen_addr"`
+	HTTPSURL      string        `json:"https_url,omitempty"`
+	Path          string        `json:"path,omitempty"`
+	BindAddress   string        `json:"bind_address,omitempty"`
+	PublicURL     string        `json:"public_url,omitempty"`
+	Running       bool          `json:"running"`
+	Uptime        time.Duration `json:"uptime"`
+	TotalRequests int64         `json:"total_requests"`
+	LoggerStats   LoggerStats   `json:"logger_stats"`
+	// LastError is set if the proxy crashed
+	LastError    string `json:"last_error,omitempty"`
+	RestartCount int    `json:"restart_count"`
+	AutoRestart  bool   `json:"auto_restart"`
+	// ShareURLs are the LAN URLs of a proxy shared on the local network
+	ShareURLs []string `json:"share_urls,omitempty"`
+}
+
+// LoggerStats are the statistics of a proxy's log.
+type LoggerStats struct {
+	TotalEntries     int64 `json:"total_entries"`
+	AvailableEntries int64 `json:"available_entries"`
+	MaxSize          int64 `json:"max_size"`
+	Dropped          int64 `json:"dropped"`
+}
+
+// ProxyList is the response of PROXY LIST.
+type ProxyList struct {
+	Proxies []Proxy `json:"proxies"`
+	Count   int     `json:"count"`
+}
+
+// ExecResult is the response of PROXY EXEC.
+type ExecResult struct {
+	ExecutionID string `json:"execution_id"`
+	Success     bool   `json:"success"`
+	Result      string `json:"result"`
+	Error       string `json:"error"`
+	Duration    string `json:"duration"`
+	// FilePath is where a result too large for the response was written
+	FilePath string `json:"file_path,omitempty"`
+}
+
+// LogQueryResult is the response of PROXYLOG QUERY: the matching entries,
+// or their groups when the filter sets GroupBy.
+type LogQueryResult struct {
+	Logs   []LogEntry `json:"logs"`
+	Groups []LogGroup `json:"groups,omitempty"`
+	// Count is the number of matching entries
+	Count int `json:"count"`
+}
+
+// PageSessionList is the response of CURRENTPAGE LIST.
+type PageSessionList struct {
+	Sessions []*PageSession `json:"sessions"`
+	Count    int            `json:"count"`
+}
+
+// PageSession is a browser tab seen through a proxy and the response of
+// CURRENTPAGE GET. The recorded traffic, errors and events are kept as the
+// JSON the daemon logs them in.
+type PageSession struct {
+	ID             string    `json:"id"`
+	URL            string    `json:"url"`
+	BrowserSession string    `json:"browser_session,omitempty"`
+	PageTitle      string    `json:"page_title,omitempty"`
+	StartTime      time.Time `json:"start_time"`
+	LastActivity   time.Time `json:"last_activity"`
+	Active         bool      `json:"active"`
+
+	Navigations     json.RawMessage `json:"navigations,omitempty"`
+	DocumentRequest json.RawMessage `json:"document_request,omitempty"`
+	Resources       json.RawMessage `json:"resources"`
+	Errors          json.RawMessage `json:"errors,omitempty"`
+	Performance     json.RawMessage `json:"performance,omitempty"`
+
+	// The counts are totals and may exceed the events kept
+	Interactions     json.RawMessage `json:"interactions,omitempty"`
+	InteractionCount int             `json:"interaction_count"`
+	Mutations        json.RawMessage `json:"mutations,omitempty"`
+	MutationCount    int             `json:"mutation_count"`
+	Routes           json.RawMessage `json:"routes,omitempty"`
+	RouteCount       int             `json:"route_count"`
+	ServiceWorker    bool            `json:"service_worker,omitempty"`
+	Console          json.RawMessage `json:"console,omitempty"`
+	ConsoleCount     int             `json:"console_count"`
+	Rejections       json.RawMessage `json:"rejections,omitempty"`
+	RejectionCount   int             `json:"rejection_count"`
+
+	// WebVitals are the latest value of each metric by name (LCP, CLS, INP,
+	// FCP, TTFB)
+	WebVitals      map[string]WebVital `json:"web_vitals,omitempty"`
+	LongTaskCount  int                 `json:"long_task_count,omitempty"`
+	BlockingTimeMs float64             `json:"blocking_time_ms,omitempty"`
+}
+
+// WebVital is a web vitals measurement of a page.
+type WebVital struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
+	// Value is in milliseconds, or unitless for CLS
+	Value float64 `json:"value"`
+	// Rating is good, needs-improvement or poor
+	Rating  string                 `json:"rating,omitempty"`
+	Element string                 `json:"element,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	URL     string                 `json:"url"`
+}
+
+// RunResult is the response of RUN. Output is only set for foreground runs.
+type RunResult struct {
+	ProcessID string `json:"process_id"`
+	PID       int    `json:"pid"`
+	Command   string `json:"command"`
+	ExitCode  int    `json:"exit_code"`
+	State     string `json:"state"`
+	Runtime   string `json:"runtime"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+}
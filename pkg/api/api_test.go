@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// jsonKeys returns the top-level keys v is encoded with.
+func jsonKeys(t *testing.T, v interface{}) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The keys include those the daemon sent before its responses were typed,
+// which existing clients read.
+func TestResponseKeys(t *testing.T) {
+	exitCode := 1
+	tests := []struct {
+		name string
+		v    interface{}
+		want []string
+	}{
+		{"detect", DetectResult{Config: &ConfigCheck{}}, []string{"commands", "config", "package_manager", "path", "scripts", "type"}},
+		{"process", Process{}, []string{"command", "id", "project_path", "runtime", "runtime_ms", "state", "summary"}},
+		{"stopped process", Process{Args: []string{"test"}, PID: 42, ExitCode: &exitCode, URLs: []string{"http://localhost:3000"}},
+			[]string{"args", "command", "exit_code", "id", "pid", "project_path", "runtime", "runtime_ms", "state", "summary", "urls"}},
+		{"process list", ProcessList{}, []string{"count", "global", "processes", "total_in_daemon"}},
+		{"proxy", Proxy{}, []string{"id", "listen_addr", "status", "target_url"}},
+		{"proxy list", ProxyList{}, []string{"count", "proxies"}},
+		{"exec", ExecResult{}, []string{"duration", "error", "execution_id", "result", "success"}},
+		{"log query", LogQueryResult{Logs: []LogEntry{}}, []string{"count", "logs"}},
+		{"log query groups", LogQueryResult{Groups: []LogGroup{{}}, Count: 3}, []string{"count", "groups", "logs"}},
+		{"page sessions", PageSessionList{}, []string{"count", "sessions"}},
+		{"page session", PageSession{}, []string{"active", "console_count", "id", "interaction_count", "last_activity",
+			"mutation_count", "rejection_count", "resources", "route_count", "start_time", "url"}},
+		{"proxy stats", ProxyStats{}, []string{"auto_restart", "id", "listen_addr", "logger_stats", "restart_count",
+			"running", "target_url", "total_requests", "uptime"}},
+		{"daemon info", DaemonInfo{}, []string{"client_count", "process_info", "proxy_info", "scheduler_info",
+			"session_info", "socket_path", "tunnel_info", "uptime", "version"}},
+		{"run", RunResult{}, []string{"command", "exit_code", "pid", "process_id", "runtime", "state", "stderr", "stdout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jsonKeys(t, tt.v)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Responses of daemons from before the responses were typed still decode.
+func TestDecodeLegacyResponses(t *testing.T) {
+	var proc Process
+	if err := json.Unmarshal([]byte(`{
+		"id": "dev", "command": "npm", "args": ["run", "dev"], "state": "stopped",
+		"summary": "exited", "runtime": "1.5s", "runtime_ms": 1500,
+		"project_path": "/app", "pid": 42, "exit_code": 0, "urls": ["http://localhost:5173"]
+	}`), &proc); err != nil {
+		t.Fatal(err)
+	}
+	if proc.ID != "dev" || proc.RuntimeMs != 1500 || proc.PID != 42 || len(proc.URLs) != 1 {
+		t.Errorf("unexpected process %+v", proc)
+	}
+	if proc.ExitCode == nil || *proc.ExitCode != 0 {
+		t.Errorf("exit code 0 should be set, got %v", proc.ExitCode)
+	}
+
+	var p Proxy
+	if err := json.Unmarshal([]byte(`{
+		"id": "app", "listen_addr": "127.0.0.1:45849", "target_url": "http://localhost:3000",
+		"status": "running", "https_url": "https://127.0.0.1:45850",
+		"routes": [{"path": "/api", "target": "http://localhost:8080"}],
+		"stats": {"total_requests": 3}
+	}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ListenAddr != "127.0.0.1:45849" || p.HTTPSURL == "" || len(p.Routes) != 1 || p.Stats == nil {
+		t.Errorf("unexpected proxy %+v", p)
+	}
+
+	var session PageSession
+	if err := json.Unmarshal([]byte(`{
+		"id": "page-1", "url": "http://localhost:3000/", "start_time": "2026-10-18T12:00:00Z",
+		"last_activity": "2026-10-18T12:01:00Z", "active": true,
+		"navigations": [{"id": "req-1", "method": "GET", "url": "http://localhost:3000/"}],
+		"resources": [], "errors": [{"id": "err-1", "message": "boom"}], "interaction_count": 4,
+		"web_vitals": {"LCP": {"name": "LCP", "value": 1200, "rating": "good"}}, "long_task_count": 1
+	}`), &session); err != nil {
+		t.Fatal(err)
+	}
+	if session.ID != "page-1" || !session.Active || session.InteractionCount != 4 || session.LongTaskCount != 1 {
+		t.Errorf("unexpected page session %+v", session)
+	}
+	if session.WebVitals["LCP"].Value != 1200 || len(session.Navigations) == 0 || len(session.Errors) == 0 {
+		t.Errorf("page session details not decoded: %+v", session)
+	}
+
+	var logs LogQueryResult
+	if err := json.Unmarshal([]byte(`{
+		"logs": [
+			{"type": "http", "http": {"id": "req-1", "method": "GET", "url": "http://localhost:3000/api",
+				"status_code": 200, "duration": 120000000, "response_body_info": {"size": 2, "kind": "json"},
+				"timing": {"ttfb": 100000000, "transfer": 0}}},
+			{"type": "error", "error": {"id": "err-1", "message": "boom"}}
+		],
+		"count": 2
+	}`), &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.Logs) != 2 || logs.Logs[0].HTTP == nil || logs.Logs[0].HTTP.Duration != 120*time.Millisecond {
+		t.Fatalf("unexpected log entries %+v", logs.Logs)
+	}
+	if logs.Logs[0].HTTP.ResponseBodyInfo.Kind != "json" || len(logs.Logs[0].HTTP.Timing) == 0 {
+		t.Errorf("HTTP entry details not decoded: %+v", logs.Logs[0].HTTP)
+	}
+	if logs.Logs[1].Type != "error" || len(logs.Logs[1].Error) == 0 {
+		t.Errorf("unexpected error entry %+v", logs.Logs[1])
+	}
+
+	var detected DetectResult
+	if err := json.Unmarshal([]byte(`{
+		"type": "go", "path": "/app", "package_manager": "", "scripts": ["test", "build"]
+	}`), &detected); err != nil {
+		t.Fatal(err)
+	}
+	if detected.Type != "go" || len(detected.Scripts) != 2 || detected.Commands != nil || detected.Config != nil {
+		t.Errorf("unexpected detect result %+v", detected)
+	}
+}
+
+// The options of PROXY START keep the keys the daemon decodes.
+func TestProxyStartOptionsKeys(t *testing.T) {
+	opts := ProxyStartOptions{
+		Path:        "/app",
+		BindAddress: "0.0.0.0",
+		PublicURL:   "https://app.example.com",
+		VerifyTLS:   true,
+		Tunnel:      &TunnelConfig{},
+		Routes:      []ProxyRoute{{}},
+		HTTPS:       true,
+		HTTPSPort:   8443,
+		BodyCapture: &BodyCaptureConfig{},
+	}
+	want := []string{"bind_address", "body_capture", "https", "https_port", "path", "public_url", "routes", "tunnel", "verify_tls"}
+	if got := jsonKeys(t, opts); !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	if got := jsonKeys(t, ProxyStartOptions{}); len(got) != 0 {
+		t.Errorf("empty options should encode as {}, got %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit statuses of a tool call.
+const (
+	AuditStatusOK    = "ok"
+	AuditStatusError = "error"
+)
+
+// AuditEntry is an action an MCP client performed through agnt, as kept in
+// the audit log of its project and as the JSON data of AUDIT RECORD.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// ProjectPath is the project the action was performed in; the daemon
+	// uses the project of the session when there is one
+	ProjectPath string `json:"project_path,omitempty"`
+	SessionCode string `json:"session_code,omitempty"`
+	// Client is the MCP client's name and version, e.g. "claude-code 2.0.1"
+	Client string `json:"client,omitempty"`
+	Tool   string `json:"tool"`
+	Action string `json:"action,omitempty"`
+	// Args are the tool's arguments with secrets redacted
+	Args json.RawMessage `json:"args,omitempty"`
+	// Status is AuditStatusOK or AuditStatusError
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// AuditQuery is the JSON data of AUDIT QUERY. Empty fields match all
+// entries.
+type AuditQuery struct {
+	// ProjectPath is the project whose log is read; empty uses the project of
+	// the connection's session
+	ProjectPath string    `json:"project_path,omitempty"`
+	SessionCode string    `json:"session_code,omitempty"`
+	Tool        string    `json:"tool,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Since       time.Time `json:"since,omitempty"`
+	// Limit returns the most recent entries (0 = 100, negative = all)
+	Limit int `json:"limit,omitempty"`
+}
+
+// Matches reports whether e is one the query selects, not counting Limit.
+func (q AuditQuery) Matches(e AuditEntry) bool {
+	return (q.SessionCode == "" || e.SessionCode == q.SessionCode) &&
+		(q.Tool == "" || e.Tool == q.Tool) &&
+		(q.Status == "" || e.Status == q.Status) &&
+		(q.Since.IsZero() || !e.Time.Before(q.Since))
+}
+
+// AuditResult is the response of AUDIT QUERY: the matching entries, oldest
+// first.
+type AuditResult struct {
+	ProjectPath string       `json:"project_path,omitempty"`
+	File        string       `json:"file"`
+	Entries     []AuditEntry `json:"entries"`
+	Count       int          `json:"count"`
+	// Total counts the matching entries before the limit
+	Total int `json:"total"`
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"strings"
+	"time"
+)
+
+// EventType is the type of a daemon event. Its topic is the part before
+// the dot: "process", "proxy", "tunnel" or "task".
+type EventType string
+
+const (
+	// EventProcessStarted is sent when a process starts running.
+	EventProcessStarted EventType = "process.started"
+	// EventProcessExited is sent when a process exits, with its exit code
+	// when known.
+	EventProcessExited EventType = "process.exited"
+	// EventProcessURL is sent when a URL is detected in a process's output.
+	EventProcessURL EventType = "process.url"
+
+	// EventProxyCreated is sent when a proxy starts.
+	EventProxyCreated EventType = "proxy.created"
+	// EventProxyStopped is sent when a proxy is stopped.
+	EventProxyStopped EventType = "proxy.stopped"
+	// EventProxyCrashed is sent when a proxy's server fails, with the error.
+	EventProxyCrashed EventType = "proxy.crashed"
+	// EventProxyRestarted is sent when a crashed proxy has restarted.
+	EventProxyRestarted EventType = "proxy.restarted"
+	// EventProxyError is sent for each frontend error or unhandled promise
+	// rejection, with its log entry.
+	EventProxyError EventType = "proxy.error"
+	// EventPanelMessage is sent for each message from the browser panel,
+	// with its log entry.
+	EventPanelMessage EventType = "proxy.panel_message"
+
+	// EventTunnelURL is sent when a tunnel gets a public URL, including the
+	// new URL after a restart.
+	EventTunnelURL EventType = "tunnel.url"
+
+	// EventTaskDelivered is sent when a scheduled task is delivered to its
+	// session.
+	EventTaskDelivered EventType = "task.delivered"
+
+	// EventGap is sent first when events after Since are no longer kept;
+	// Missed is how many were lost, or 0 when the daemon restarted.
+	EventGap EventType = "stream.gap"
+	// EventHeartbeat is sent while no other event is, so either side
+	// notices a broken connection.
+	EventHeartbeat EventType = "stream.heartbeat"
+)
+
+// Topic returns the topic of the event type.
+func (t EventType) Topic() string {
+	topic, _, _ := strings.Cut(string(t), ".")
+	return topic
+}
+
+// Event is an event of the SUBSCRIBE stream. Which fields are set depends
+// on Type.
+type Event struct {
+	// Seq orders the events of a daemon; stream events have none. Sequence
+	// numbers restart with the daemon.
+	Seq         uint64    `json:"seq,omitempty"`
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	ProjectPath string    `json:"project_path,omitempty"`
+
+	ProcessID   string `json:"process_id,omitempty"`
+	ProxyID     string `json:"proxy_id,omitempty"`
+	TunnelID    string `json:"tunnel_id,omitempty"`
+	TaskID      string `json:"task_id,omitempty"`
+	SessionCode string `json:"session_code,omitempty"`
+
+	// Command is the command of a started process
+	Command string `json:"command,omitempty"`
+	// ExitCode is the exit code of an exited process, when known
+	ExitCode *int `json:"exit_code,omitempty"`
+	// URL is the detected process URL or the tunnel's public URL
+	URL string `json:"url,omitempty"`
+	// Error is why a proxy crashed
+	Error string `json:"error,omitempty"`
+	// Message is the message of a delivered task
+	Message string `json:"message,omitempty"`
+	// Entry is the log entry of a proxy error or panel message
+	Entry *LogEntry `json:"entry,omitempty"`
+	// Missed is the number of events lost before a gap, 0 if unknown
+	Missed uint64 `json:"missed,omitempty"`
+}
+
+// SubscribeRequest is the JSON data of SUBSCRIBE.
+type SubscribeRequest struct {
+	// Topics are topics ("process") or event types ("process.exited") to
+	// receive; empty receives all
+	Topics []string `json:"topics,omitempty"`
+	// ProjectPath only receives the events of a project; empty receives the
+	// events of all projects
+	ProjectPath string `json:"project_path,omitempty"`
+	// Since replays the events after this sequence number that the daemon
+	// still keeps, so a client can resume where it stopped
+	Since uint64 `json:"since,omitempty"`
+}
+
+// Matches reports whether ev is one the subscription receives. Stream
+// events always are.
+func (r SubscribeRequest) Matches(ev Event) bool {
+	if ev.Type.Topic() == "stream" {
+		return true
+	}
+	if r.ProjectPath != "" && ev.ProjectPath != r.ProjectPath {
+		return false
+	}
+	if len(r.Topics) == 0 {
+		return true
+	}
+	for _, topic := range r.Topics {
+		if topic == string(ev.Type) || topic == ev.Type.Topic() {
+			return true
+		}
+	}
+	return false
+}
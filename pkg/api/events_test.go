@@ -0,0 +1,39 @@
+package api
+
+import "testing"
+
+func TestSubscribeRequestMatches(t *testing.T) {
+	exited := Event{Type: EventProcessExited, ProjectPath: "/app"}
+	tests := []struct {
+		name string
+		req  SubscribeRequest
+		ev   Event
+		want bool
+	}{
+		{"everything", SubscribeRequest{}, exited, true},
+		{"topic", SubscribeRequest{Topics: []string{"process"}}, exited, true},
+		{"event type", SubscribeRequest{Topics: []string{"process.exited"}}, exited, true},
+		{"other event type", SubscribeRequest{Topics: []string{"process.started"}}, exited, false},
+		{"other topic", SubscribeRequest{Topics: []string{"proxy", "task"}}, exited, false},
+		{"project", SubscribeRequest{ProjectPath: "/app"}, exited, true},
+		{"other project", SubscribeRequest{ProjectPath: "/web"}, exited, false},
+		{"no project", SubscribeRequest{ProjectPath: "/app"}, Event{Type: EventProcessExited}, false},
+		{"stream events", SubscribeRequest{Topics: []string{"task"}, ProjectPath: "/web"}, Event{Type: EventGap}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.Matches(tt.ev); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventTypeTopic(t *testing.T) {
+	if got := EventPanelMessage.Topic(); got != "proxy" {
+		t.Errorf("Topic() = %q, want proxy", got)
+	}
+	if got := EventType("custom").Topic(); got != "custom" {
+		t.Errorf("Topic() = %q, want custom", got)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import "time"
+
+// DaemonInfo is the response of STATUS.
+type DaemonInfo struct {
+	Version       string        `json:"version"`
+	BuildTime     string        `json:"build_time,omitempty"`
+	GitCommit     string        `json:"git_commit,omitempty"`
+	SocketPath    string        `json:"socket_path"`
+	Uptime        time.Duration `json:"uptime"`
+	ClientCount   int64         `json:"client_count"`
+	ProcessInfo   ProcessInfo   `json:"process_info"`
+	ProxyInfo     ProxyInfo     `json:"proxy_info"`
+	TunnelInfo    TunnelInfo    `json:"tunnel_info"`
+	SessionInfo   SessionInfo   `json:"session_info"`
+	SchedulerInfo SchedulerInfo `json:"scheduler_info"`
+	// UpdateInfo is set once the daemon has checked for updates
+	UpdateInfo *UpdateInfo `json:"update_info,omitempty"`
+}
+
+// ProcessInfo are the statistics of the daemon's processes.
+type ProcessInfo struct {
+	Active       int64 `json:"active"`
+	TotalStarted int64 `json:"total_started"`
+	TotalFailed  int64 `json:"total_failed"`
+}
+
+// ProxyInfo are the statistics of the daemon's proxies.
+type ProxyInfo struct {
+	Active       int64 `json:"active"`
+	TotalStarted int64 `json:"total_started"`
+}
+
+// TunnelInfo are the statistics of the daemon's tunnels.
+type TunnelInfo struct {
+	Active int64 `json:"active"`
+}
+
+// SessionInfo are the statistics of the sessions registered with the daemon.
+type SessionInfo struct {
+	ActiveCount       int64 `json:"active_count"`
+	TotalRegistered   int64 `json:"total_registered"`
+	TotalUnregistered int64 `json:"total_unregistered"`
+}
+
+// SchedulerInfo are the statistics of the messages scheduled for sessions.
+type SchedulerInfo struct {
+	TotalScheduled int64 `json:"total_scheduled"`
+	TotalDelivered int64 `json:"total_delivered"`
+	TotalFailed    int64 `json:"total_failed"`
+	TotalCancelled int64 `json:"total_cancelled"`
+	PendingCount   int64 `json:"pending_count"`
+}
+
+// UpdateInfo tells whether a newer agnt release is available.
+type UpdateInfo struct {
+	Available      bool      `json:"available"`
+	LatestVersion  string    `json:"latest_version,omitempty"`
+	CurrentVersion string    `json:"current_version"`
+	ReleaseURL     string    `json:"release_url,omitempty"`
+	ReleaseNotes   string    `json:"release_notes,omitempty"`
+	LastChecked    time.Time `json:"last_checked"`
+	CheckError     string    `json:"check_error,omitempty"`
+}
@@ -0,0 +1,24 @@
+package api
+
+// SocketName is the name of the socket the agnt daemon listens on; the
+// path is derived from it by go-cli-server/socket.
+const SocketName = "devtool-mcp"
+
+// Agnt command verbs of the daemon protocol, beyond the ones of
+// go-cli-server/protocol.
+const (
+	VerbProxy       = "PROXY"
+	VerbProxyLog    = "PROXYLOG"
+	VerbCurrentPage = "CURRENTPAGE"
+	VerbDetect      = "DETECT"
+	VerbStatus      = "STATUS"
+	VerbSubscribe   = "SUBSCRIBE"
+	VerbAudit       = "AUDIT"
+)
+
+// Agnt sub-verbs of the daemon protocol, beyond the ones of
+// go-cli-server/protocol.
+const (
+	SubVerbExec  = "EXEC"
+	SubVerbQuery = "QUERY"
+)